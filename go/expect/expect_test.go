@@ -20,7 +20,7 @@ func TestMarker(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	const expectNotes = 13
+	const expectNotes = 14
 	expectMarkers := map[string]string{
 		"αSimpleMarker": "α",
 		"OffsetMarker":  "β",
@@ -31,6 +31,7 @@ func TestMarker(t *testing.T) {
 		"Declared":      "η",
 		"Comment":       "ι",
 		"LineComment":   "someFunc",
+		"AtComment":     "κ",
 		"NonIdentifier": "+",
 		"StringMarker":  "\"hello\"",
 	}
@@ -65,15 +65,14 @@ func Extract(fset *token.FileSet, file *ast.File) ([]*Note, error) {
 			// "// //@mark()" is valid.
 			// "// @mark()" is not valid.
 			// "// /*@mark()*/" is not valid.
+			// The text before the note may itself contain @, as in
+			// "//go:generate go run pkg@latest //@mark()".
 			var adjust int
-			if i := strings.Index(text, commentStart); i > 2 {
-				// Get the text before the commentStart.
-				pre := text[i-2 : i]
-				if pre != "//" {
-					continue
+			if !strings.HasPrefix(text, commentStart) {
+				if i := strings.Index(text, "//"+commentStart); i > 0 {
+					text = text[i+2:]
+					adjust = i + 2
 				}
-				text = text[i:]
-				adjust = i
 			}
 			if !strings.HasPrefix(text, commentStart) {
 				continue
@@ -22,6 +22,7 @@ const (
 var x = "hello"                 //@mark(StringMarker, `"hello"`)
 
 // someFunc is a function. //@mark(LineComment, "someFunc")
+// See κ@latest. //@mark(AtComment, "κ")
 func someFunc(a, b int) int {
 	// The line below must be the first occurrence of the plus operator
 	return a + b + 1 //@mark(NonIdentifier, re`\+[^\+]*`)
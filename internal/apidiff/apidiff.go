@@ -26,10 +26,10 @@ func Changes(old, new *types.Package) Report {
 	d.checkPackage()
 	r := Report{}
 	for _, m := range d.incompatibles.collect() {
-		r.Changes = append(r.Changes, Change{Message: m, Compatible: false})
+		r.Changes = append(r.Changes, Change{Message: m.text, Compatible: false, Object: m.obj})
 	}
 	for _, m := range d.compatibles.collect() {
-		r.Changes = append(r.Changes, Change{Message: m, Compatible: true})
+		r.Changes = append(r.Changes, Change{Message: m.text, Compatible: true, Object: m.obj})
 	}
 	return r
 }
@@ -34,8 +34,14 @@ func (m messageSet) add(obj types.Object, part, msg string) {
 	s[part] = msg
 }
 
-func (m messageSet) collect() []string {
-	var s []string
+// A message is a message of a messageSet along with its object.
+type message struct {
+	obj  types.Object
+	text string
+}
+
+func (m messageSet) collect() []message {
+	var s []message
 	for obj, parts := range m {
 		// Format each object name relative to its own package.
 		objstring := objectString(obj)
@@ -47,10 +53,10 @@ func (m messageSet) collect() []string {
 			} else {
 				p = dotjoin(objstring, part)
 			}
-			s = append(s, p+": "+msg)
+			s = append(s, message{obj: obj, text: p + ": " + msg})
 		}
 	}
-	sort.Strings(s)
+	sort.Slice(s, func(i, j int) bool { return s[i].text < s[j].text })
 	return s
 }
 
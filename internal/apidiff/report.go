@@ -3,6 +3,7 @@ package apidiff
 import (
 	"bytes"
 	"fmt"
+	"go/types"
 	"io"
 )
 
@@ -15,6 +16,11 @@ type Report struct {
 type Change struct {
 	Message    string
 	Compatible bool
+
+	// Object is the object of the old or the new package that the change is
+	// about. For a change to a field or an interface method, it is the
+	// object of the type.
+	Object types.Object
 }
 
 func (r Report) messages(compatible bool) []string {
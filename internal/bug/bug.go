@@ -0,0 +1,114 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bug reports the internal bugs of gopls: the states that its code
+// does not expect to reach, but that are not worth a crash. A reported bug
+// is recorded, so that it can be attached to an issue, and passed to the
+// handlers that want to know about it, such as a server that offers its
+// user to report it.
+package bug
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Bug is an internal bug reported by Reportf or Errorf.
+type Bug struct {
+	// File and Line are the location of the report.
+	File string `json:"file"`
+	Line int    `json:"line"`
+
+	Description string    `json:"description"`
+	AtTime      time.Time `json:"atTime"`
+}
+
+func (b Bug) String() string {
+	return fmt.Sprintf("%s:%d: %s", b.File, b.Line, b.Description)
+}
+
+var (
+	mu sync.Mutex
+
+	// exemplars holds the first bug reported at each location.
+	exemplars = make(map[string]Bug)
+
+	handlers []*handler
+)
+
+type handler struct {
+	f func(Bug)
+}
+
+// Reportf reports an internal bug with a description formatted as by
+// fmt.Sprintf. Only the first bug reported at each location is recorded and
+// passed to the handlers.
+func Reportf(format string, args ...interface{}) {
+	report(fmt.Sprintf(format, args...))
+}
+
+// Errorf reports an internal bug like Reportf, and returns its description
+// as an error.
+func Errorf(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	report(err.Error())
+	return err
+}
+
+func report(description string) {
+	b := Bug{Description: description, AtTime: time.Now()}
+	// Skip report and its exported caller.
+	if _, file, line, ok := runtime.Caller(2); ok {
+		b.File, b.Line = file, line
+	}
+	key := fmt.Sprintf("%s:%d", b.File, b.Line)
+
+	mu.Lock()
+	if _, ok := exemplars[key]; ok {
+		mu.Unlock()
+		return
+	}
+	exemplars[key] = b
+	hs := append([]*handler(nil), handlers...)
+	mu.Unlock()
+
+	for _, h := range hs {
+		h.f(b)
+	}
+}
+
+// Handle registers f to be called with each bug recorded from now on, and
+// returns a function that unregisters it. f is called by the goroutine that
+// reports the bug, so it must not block.
+func Handle(f func(Bug)) (unregister func()) {
+	h := &handler{f: f}
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = append(handlers, h)
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for i, existing := range handlers {
+			if existing == h {
+				handlers = append(handlers[:i], handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// List returns the bugs recorded so far, oldest first.
+func List() []Bug {
+	mu.Lock()
+	defer mu.Unlock()
+	result := make([]Bug, 0, len(exemplars))
+	for _, b := range exemplars {
+		result = append(result, b)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].AtTime.Before(result[j].AtTime) })
+	return result
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bug
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReportf(t *testing.T) {
+	var handled []Bug
+	unregister := Handle(func(b Bug) { handled = append(handled, b) })
+
+	for i := 0; i < 3; i++ {
+		Reportf("unexpected %d", i) // only the first of these is recorded
+	}
+	err := Errorf("unexpected %s", "error")
+	unregister()
+	Reportf("after unregister")
+
+	if err == nil || err.Error() != "unexpected error" {
+		t.Errorf("Errorf returned %v, want unexpected error", err)
+	}
+	if len(handled) != 2 {
+		t.Fatalf("handled %v, want 2 bugs", handled)
+	}
+	if got := handled[0].Description; got != "unexpected 0" {
+		t.Errorf("first bug is %q, want %q", got, "unexpected 0")
+	}
+	if filepath.Base(handled[0].File) != "bug_test.go" || handled[0].Line == 0 {
+		t.Errorf("bug reported at %s:%d, want bug_test.go", handled[0].File, handled[0].Line)
+	}
+	var descriptions []string
+	for _, b := range List() {
+		descriptions = append(descriptions, b.Description)
+	}
+	if got, want := strings.Join(descriptions, ", "), "unexpected 0, unexpected error, after unregister"; got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+}
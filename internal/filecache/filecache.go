@@ -0,0 +1,282 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package filecache provides a cache on disk of the values computed by
+// gopls, such as the export data and cross references of packages, which
+// outlives a gopls process and is shared by the processes of a user.
+//
+// Values are stored by content: each key of a kind of value refers to the
+// hash of its value, and each distinct value is stored once, whatever the
+// number of keys that refer to it. The packages of two checkouts of the same
+// repository, for example, have different keys but mostly identical values.
+//
+// The cache directory contains an index directory, with a file for each
+// kind and key holding the hash of its value, and a cas directory, with a
+// file for each value named by its hash. The file of a value starts with a
+// byte that records its format, which is either the value itself or, for
+// large values, the value compressed with DEFLATE.
+//
+// Several processes may use the same cache. Each file is written to a
+// temporary file first and then renamed, so a reader sees either the whole
+// file or none of it, and the writers of the same value write the same
+// content. A value whose file does not match its hash, because it was
+// damaged on disk, is deleted and reported as not found, so that it is
+// computed and stored again, and the damage is reported as a bug.
+package filecache
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/tools/internal/bug"
+	"golang.org/x/tools/internal/telemetry/log"
+	"golang.org/x/tools/internal/xcontext"
+)
+
+// ErrNotFound is returned by Get for a key that has no value in the cache.
+var ErrNotFound = errors.New("not found in the file cache")
+
+// Cache is a cache of values in a directory.
+type Cache struct {
+	dir string
+
+	mu       sync.Mutex
+	compress bool
+	remote   *Remote
+}
+
+// New returns the cache in dir, which is created when the first value is
+// stored in it. The large values stored in it are compressed.
+func New(dir string) *Cache {
+	return &Cache{dir: dir, compress: true}
+}
+
+// SetCompression sets whether the large values stored in c from now on are
+// compressed, which saves space on disk at the cost of the time spent
+// compressing and decompressing them. The values already stored are read
+// whatever their format.
+func (c *Cache) SetCompression(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.compress = enabled
+}
+
+// SetRemote sets the remote cache that c falls back to for the values that
+// it does not have, or removes it if remote is nil. The values found in the
+// remote cache are stored in c.
+func (c *Cache) SetRemote(remote *Remote) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remote = remote
+}
+
+func (c *Cache) getRemote() *Remote {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.remote
+}
+
+// The formats of the files of values, recorded in their first byte.
+const (
+	formatRaw   = 0
+	formatFlate = 1
+)
+
+// compressThreshold is the size of the smallest value that is compressed.
+// Smaller values gain little from it.
+const compressThreshold = 4096
+
+// DefaultDir returns the directory of the cache shared by the gopls
+// processes of the user.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gopls", "filecache-v2"), nil
+}
+
+// Get returns the value of key of the given kind, such as "export" or
+// "xrefs", or ErrNotFound if there is none. The errors of the remote cache
+// are logged, and the keys it fails to return are reported as not found.
+// The remote cache is not asked while it is down.
+func (c *Cache) Get(ctx context.Context, kind string, key [32]byte) ([]byte, error) {
+	value, err := c.getLocal(kind, key)
+	if err != ErrNotFound {
+		return value, err
+	}
+	remote := c.getRemote()
+	if remote == nil {
+		return nil, ErrNotFound
+	}
+	value, err = remote.Get(ctx, kind, key)
+	if err != nil {
+		if err != ErrNotFound && err != errRemoteDown {
+			log.Error(ctx, "cannot download from the remote file cache", err)
+		}
+		return nil, ErrNotFound
+	}
+	if err := c.setLocal(kind, key, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (c *Cache) getLocal(kind string, key [32]byte) ([]byte, error) {
+	ref, err := ioutil.ReadFile(c.indexFile(kind, key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(ref) != sha256.Size {
+		c.heal(c.indexFile(kind, key))
+		return nil, ErrNotFound
+	}
+	var hash [32]byte
+	copy(hash[:], ref)
+	data, err := ioutil.ReadFile(c.casFile(hash))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	value, err := decode(data)
+	if err != nil || sha256.Sum256(value) != hash {
+		c.heal(c.indexFile(kind, key), c.casFile(hash))
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+// heal deletes the files of a damaged entry, so that it is stored again, and
+// reports the damage as a bug.
+func (c *Cache) heal(filenames ...string) {
+	bug.Reportf("damaged file cache entry %s", filenames[0])
+	for _, filename := range filenames {
+		os.Remove(filename)
+	}
+}
+
+// Set sets the value of key of the given kind to value. The value is stored
+// only if no other key refers to the same value. It is also uploaded in the
+// background to the remote cache of c, if it uploads values, on a best effort
+// basis: the errors of the upload are logged.
+func (c *Cache) Set(ctx context.Context, kind string, key [32]byte, value []byte) error {
+	if err := c.setLocal(kind, key, value); err != nil {
+		return err
+	}
+	if remote := c.getRemote(); remote != nil && remote.upload {
+		// The upload outlives the request that stores the value.
+		remote.setAsync(xcontext.Detach(ctx), kind, key, value)
+	}
+	return nil
+}
+
+func (c *Cache) setLocal(kind string, key [32]byte, value []byte) error {
+	hash := sha256.Sum256(value)
+	casFile := c.casFile(hash)
+	if _, err := os.Stat(casFile); os.IsNotExist(err) {
+		c.mu.Lock()
+		compress := c.compress
+		c.mu.Unlock()
+		data, err := encode(value, compress)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(casFile, data); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	return writeFile(c.indexFile(kind, key), hash[:])
+}
+
+// encode returns the content of the file of value, which is compressed if
+// compress is set and the value is large.
+func encode(value []byte, compress bool) ([]byte, error) {
+	if !compress || len(value) < compressThreshold {
+		return append([]byte{formatRaw}, value...), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(formatFlate)
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(value); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decode returns the value of the content of its file.
+func decode(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty file cache entry")
+	}
+	switch data[0] {
+	case formatRaw:
+		return data[1:], nil
+	case formatFlate:
+		return ioutil.ReadAll(flate.NewReader(bytes.NewReader(data[1:])))
+	default:
+		return nil, fmt.Errorf("unknown file cache format %d", data[0])
+	}
+}
+
+func (c *Cache) indexFile(kind string, key [32]byte) string {
+	return filepath.Join(c.dir, "index", kind, hashPath(key))
+}
+
+func (c *Cache) casFile(hash [32]byte) string {
+	return filepath.Join(c.dir, "cas", hashPath(hash))
+}
+
+// hashPath returns the relative path of the file of hash, in a
+// subdirectory named by its first byte so that no directory gets too large.
+func hashPath(hash [32]byte) string {
+	s := hex.EncodeToString(hash[:])
+	return filepath.Join(s[:2], s)
+}
+
+// writeFile writes data to filename, creating its directory if needed. It
+// writes a temporary file in the same directory and renames it, so that
+// filename never holds part of data.
+func writeFile(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package filecache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/tools/internal/bug"
+)
+
+// newTestCache returns a cache in a new temporary directory, and a function
+// that removes it.
+func newTestCache(t testing.TB) (*Cache, func()) {
+	dir, err := ioutil.TempDir("", "filecache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(dir), func() { os.RemoveAll(dir) }
+}
+
+func TestGetSet(t *testing.T) {
+	ctx := context.Background()
+	c, cleanup := newTestCache(t)
+	defer cleanup()
+
+	a, b := sha256.Sum256([]byte("a")), sha256.Sum256([]byte("b"))
+	if _, err := c.Get(ctx, "export", a); err != ErrNotFound {
+		t.Fatalf("Get of a missing key returned %v, want ErrNotFound", err)
+	}
+	value := []byte("export data")
+	for _, key := range [][32]byte{a, b} {
+		if err := c.Set(ctx, "export", key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range [][32]byte{a, b} {
+		got, err := c.Get(ctx, "export", key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, value) {
+			t.Errorf("Get(%x) = %q, want %q", key, got, value)
+		}
+	}
+	if _, err := c.Get(ctx, "xrefs", a); err != ErrNotFound {
+		t.Errorf("Get of another kind returned %v, want ErrNotFound", err)
+	}
+
+	// The value of both keys is stored once.
+	values, err := filepath.Glob(filepath.Join(c.dir, "cas", "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 {
+		t.Errorf("the cache holds %d values, want 1", len(values))
+	}
+}
+
+func TestCorruption(t *testing.T) {
+	ctx := context.Background()
+	c, cleanup := newTestCache(t)
+	defer cleanup()
+
+	key := sha256.Sum256([]byte("key"))
+	value := []byte("xrefs")
+	if err := c.Set(ctx, "xrefs", key, value); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(c.casFile(sha256.Sum256(value)), []byte{formatRaw, 'x'}, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "xrefs", key); err != ErrNotFound {
+		t.Fatalf("Get of a damaged value returned %v, want ErrNotFound", err)
+	}
+	reported := false
+	for _, b := range bug.List() {
+		if strings.HasPrefix(b.Description, "damaged file cache entry") {
+			reported = true
+		}
+	}
+	if !reported {
+		t.Errorf("the damaged value was not reported as a bug")
+	}
+
+	// The damaged value is stored again.
+	if err := c.Set(ctx, "xrefs", key, value); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.Get(ctx, "xrefs", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, value) {
+		t.Errorf("Get = %q, want %q", got, value)
+	}
+}
+
+func TestConcurrentSet(t *testing.T) {
+	ctx := context.Background()
+	c, cleanup := newTestCache(t)
+	defer cleanup()
+
+	// Writers of the same value, as in different processes, must not
+	// leave a partial file for the readers.
+	value := largeValue()
+	key := sha256.Sum256([]byte("key"))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := New(c.dir).Set(ctx, "export", key, value); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	got, err := c.Get(ctx, "export", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, value) {
+		t.Errorf("Get returned a different value")
+	}
+	for _, pattern := range []string{"cas/*/*.tmp*", "index/*/*/*.tmp*"} {
+		if tmps, _ := filepath.Glob(filepath.Join(c.dir, filepath.FromSlash(pattern))); len(tmps) != 0 {
+			t.Errorf("temporary files were left: %v", tmps)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	ctx := context.Background()
+	c, cleanup := newTestCache(t)
+	defer cleanup()
+
+	value := largeValue()
+	for _, compress := range []bool{true, false} {
+		c.SetCompression(compress)
+		key := sha256.Sum256([]byte(fmt.Sprint(compress)))
+		// Store a value that differs from the others, so that it is not
+		// shared with them.
+		v := append([]byte(fmt.Sprint(compress)), value...)
+		if err := c.Set(ctx, "export", key, v); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(c.casFile(sha256.Sum256(v)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if compressed := len(data) < len(v); compressed != compress {
+			t.Errorf("with compression %v, the file of %d bytes holds %d bytes", compress, len(v), len(data))
+		}
+		// Values are read whatever the current setting.
+		c.SetCompression(!compress)
+		got, err := c.Get(ctx, "export", key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, v) {
+			t.Errorf("with compression %v, Get returned a different value", compress)
+		}
+	}
+}
+
+// largeValue returns a value that resembles the export data of a large
+// package.
+func largeValue() []byte {
+	var buf bytes.Buffer
+	for i := 0; buf.Len() < 4<<20; i++ {
+		fmt.Fprintf(&buf, "func F%d(ctx context.Context, x%d int) (*T%d, error)\n", i, i%7, i%13)
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkGetCompressed(b *testing.B) {
+	benchmarkGet(b, true)
+}
+
+func BenchmarkGetUncompressed(b *testing.B) {
+	benchmarkGet(b, false)
+}
+
+func benchmarkGet(b *testing.B, compress bool) {
+	ctx := context.Background()
+	c, cleanup := newTestCache(b)
+	defer cleanup()
+	c.SetCompression(compress)
+
+	value := largeValue()
+	key := sha256.Sum256(value)
+	if err := c.Set(ctx, "export", key, value); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(value)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Get(ctx, "export", key); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package filecache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/internal/telemetry/log"
+)
+
+// Remote is an experimental cache of values on an HTTP server, which lets
+// the values computed once, for example by a continuous integration job,
+// be downloaded by the gopls processes of other machines instead of being
+// computed again.
+//
+// The value of a key of a kind is at the URL of the server followed by
+// /<kind>/<key in hex>. The server stores it for a PUT request, along with
+// the Digest header of the request, which holds the SHA-256 hash of the
+// value, and returns both for a GET request, with the status 404 if it has
+// none. The values whose hash does not match are rejected.
+//
+// A value missing locally is computed again when the server is slow or
+// down, which is faster than waiting for it: the requests have a short
+// timeout, the server is not asked for a while once several requests in a row
+// failed, and the values are uploaded in the background.
+type Remote struct {
+	url    string
+	token  string
+	upload bool
+	client *http.Client
+
+	mu        sync.Mutex
+	failures  int       // the number of requests in a row that failed
+	downUntil time.Time // the server is not asked before then
+
+	uploads     sync.WaitGroup
+	uploadSlots chan struct{} // holds a token for each value uploading
+}
+
+var (
+	// remoteTimeout is the time that a request to the server has.
+	remoteTimeout = 5 * time.Second
+
+	// remoteMaxFailures is the number of requests in a row that fail before
+	// the server is not asked for remoteBackoff.
+	remoteMaxFailures = 3
+	remoteBackoff     = time.Minute
+)
+
+// maxUploads is the number of values that are uploaded at the same time.
+// The values stored while as many are uploading are not uploaded.
+const maxUploads = 4
+
+// errRemoteDown is returned for the requests to a server that is not asked
+// after too many failures.
+var errRemoteDown = errors.New("the remote file cache is down")
+
+// NewRemote returns the remote cache at url. If token is not empty, it is
+// sent as a bearer token in the Authorization header of each request. If
+// upload is set, the values stored in a local cache that uses the remote
+// cache are also stored on the server.
+func NewRemote(url, token string, upload bool) *Remote {
+	return &Remote{
+		url:    strings.TrimSuffix(url, "/"),
+		token:  token,
+		upload: upload,
+		client: &http.Client{Timeout: remoteTimeout},
+
+		uploadSlots: make(chan struct{}, maxUploads),
+	}
+}
+
+// Get returns the value of key of the given kind on the server, or
+// ErrNotFound if it has none.
+func (r *Remote) Get(ctx context.Context, kind string, key [32]byte) ([]byte, error) {
+	resp, err := r.do(ctx, "GET", kind, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		value, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Header.Get("Digest") != digest(value) {
+			return nil, fmt.Errorf("GET %s: the value does not match its digest", resp.Request.URL)
+		}
+		return value, nil
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("GET %s: %s", resp.Request.URL, resp.Status)
+	}
+}
+
+// Set stores value as the value of key of the given kind on the server.
+func (r *Remote) Set(ctx context.Context, kind string, key [32]byte, value []byte) error {
+	resp, err := r.do(ctx, "PUT", kind, key, value)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("PUT %s: %s", resp.Request.URL, resp.Status)
+	}
+	return nil
+}
+
+// setAsync uploads value as the value of key of the given kind in the
+// background, on a best effort basis: the errors of the upload are logged,
+// and the value is not uploaded if maxUploads values already are.
+func (r *Remote) setAsync(ctx context.Context, kind string, key [32]byte, value []byte) {
+	select {
+	case r.uploadSlots <- struct{}{}:
+	default:
+		return
+	}
+	r.uploads.Add(1)
+	go func() {
+		defer r.uploads.Done()
+		defer func() { <-r.uploadSlots }()
+		if err := r.Set(ctx, kind, key, value); err != nil && err != errRemoteDown {
+			log.Error(ctx, "cannot upload to the remote file cache", err)
+		}
+	}()
+}
+
+func (r *Remote) do(ctx context.Context, method, kind string, key [32]byte, body []byte) (*http.Response, error) {
+	url := r.url + "/" + kind + "/" + hex.EncodeToString(key[:])
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Digest", digest(body))
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	if !r.available() {
+		return nil, errRemoteDown
+	}
+	resp, err := r.client.Do(req)
+	r.record(err == nil && resp.StatusCode < 500)
+	return resp, err
+}
+
+// available reports whether the server may be asked.
+func (r *Remote) available() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Now().After(r.downUntil)
+}
+
+// record records whether a request to the server succeeded, which is whether
+// the server answered it without an error of its own. The server is not asked
+// for remoteBackoff once remoteMaxFailures requests in a row failed.
+func (r *Remote) record(ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ok {
+		r.failures = 0
+		return
+	}
+	r.failures++
+	if r.failures >= remoteMaxFailures {
+		r.failures = 0
+		r.downUntil = time.Now().Add(remoteBackoff)
+	}
+}
+
+// digest returns the value of the Digest header of value.
+func digest(value []byte) string {
+	hash := sha256.Sum256(value)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(hash[:])
+}
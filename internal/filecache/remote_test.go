@@ -0,0 +1,199 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package filecache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// remoteServer is a remote cache server that keeps its values, and their
+// digests, in memory.
+type remoteServer struct {
+	token string
+
+	mu      sync.Mutex
+	values  map[string][]byte
+	digests map[string]string
+}
+
+func newRemoteServer(token string) *remoteServer {
+	return &remoteServer{
+		token:   token,
+		values:  make(map[string][]byte),
+		digests: make(map[string]string),
+	}
+}
+
+func (s *remoteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+s.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case "GET":
+		value, ok := s.values[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Digest", s.digests[r.URL.Path])
+		w.Write(value)
+	case "PUT":
+		value, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.values[r.URL.Path] = value
+		s.digests[r.URL.Path] = r.Header.Get("Digest")
+	}
+}
+
+func TestRemote(t *testing.T) {
+	ctx := context.Background()
+	server := newRemoteServer("secret")
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// A continuous integration job uploads the values it computes.
+	ci, cleanup := newTestCache(t)
+	defer cleanup()
+	ci.SetRemote(NewRemote(ts.URL, "secret", true))
+	key := sha256.Sum256([]byte("key"))
+	value := []byte("export data")
+	if err := ci.Set(ctx, "export", key, value); err != nil {
+		t.Fatal(err)
+	}
+	// The values are uploaded in the background.
+	ci.getRemote().uploads.Wait()
+
+	// A developer machine downloads them.
+	dev, cleanup := newTestCache(t)
+	defer cleanup()
+	dev.SetRemote(NewRemote(ts.URL+"/", "secret", false))
+	got, err := dev.Get(ctx, "export", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, value) {
+		t.Errorf("Get = %q, want %q", got, value)
+	}
+	if _, err := dev.Get(ctx, "export", sha256.Sum256([]byte("other"))); err != ErrNotFound {
+		t.Errorf("Get of a missing key returned %v, want ErrNotFound", err)
+	}
+
+	// The downloaded value is stored locally.
+	dev.SetRemote(nil)
+	if _, err := dev.Get(ctx, "export", key); err != nil {
+		t.Errorf("Get of a downloaded value without the remote cache: %v", err)
+	}
+
+	// Requests without the token are rejected.
+	if _, err := NewRemote(ts.URL, "", false).Get(ctx, "export", key); err == nil {
+		t.Errorf("Get without a token succeeded")
+	}
+}
+
+func TestRemoteErrors(t *testing.T) {
+	ctx := context.Background()
+	server := newRemoteServer("secret")
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	c, cleanup := newTestCache(t)
+	defer cleanup()
+	key := sha256.Sum256([]byte("key"))
+	value := []byte("export data")
+
+	// The values damaged on the server are not found, and not stored.
+	c.SetRemote(NewRemote(ts.URL, "secret", false))
+	if err := NewRemote(ts.URL, "secret", false).Set(ctx, "export", key, value); err != nil {
+		t.Fatal(err)
+	}
+	server.mu.Lock()
+	for path := range server.values {
+		server.values[path] = []byte("damaged")
+	}
+	server.mu.Unlock()
+	if _, err := c.Get(ctx, "export", key); err != ErrNotFound {
+		t.Errorf("Get of a damaged value returned %v, want ErrNotFound", err)
+	}
+	if _, err := c.getLocal("export", key); err != ErrNotFound {
+		t.Errorf("the damaged value was stored locally")
+	}
+
+	// The errors of the server are reported as misses.
+	c.SetRemote(NewRemote(ts.URL, "wrong", true))
+	if _, err := c.Get(ctx, "export", key); err != ErrNotFound {
+		t.Errorf("Get with a failing server returned %v, want ErrNotFound", err)
+	}
+
+	// A failed upload does not fail Set.
+	if err := c.Set(ctx, "export", key, value); err != nil {
+		t.Errorf("Set with a failing server: %v", err)
+	}
+	c.getRemote().uploads.Wait()
+	if _, err := c.getLocal("export", key); err != nil {
+		t.Errorf("the value was not stored locally: %v", err)
+	}
+}
+
+func TestRemoteDown(t *testing.T) {
+	defer func(timeout, backoff time.Duration) {
+		remoteTimeout, remoteBackoff = timeout, backoff
+	}(remoteTimeout, remoteBackoff)
+	remoteTimeout, remoteBackoff = 50*time.Millisecond, time.Hour
+
+	ctx := context.Background()
+	var requests int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	c, cleanup := newTestCache(t)
+	defer cleanup()
+	remote := NewRemote(ts.URL, "", false)
+	c.SetRemote(remote)
+	key := sha256.Sum256([]byte("key"))
+
+	// The requests to a server that hangs time out, and once enough of them
+	// did, the server is not asked anymore.
+	for i := 0; i < 2*remoteMaxFailures; i++ {
+		start := time.Now()
+		if _, err := c.Get(ctx, "export", key); err != ErrNotFound {
+			t.Fatalf("Get with a hanging server returned %v, want ErrNotFound", err)
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Fatalf("Get with a hanging server took %v", d)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != int32(remoteMaxFailures) {
+		t.Errorf("the server got %d requests, want %d", n, remoteMaxFailures)
+	}
+
+	// It is asked again after the backoff.
+	remote.mu.Lock()
+	remote.downUntil = time.Time{}
+	remote.mu.Unlock()
+	c.Get(ctx, "export", key)
+	if n := atomic.LoadInt32(&requests); n != int32(remoteMaxFailures)+1 {
+		t.Errorf("the server got %d requests after the backoff, want %d", n, remoteMaxFailures+1)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package deadbranch defines an Analyzer that checks for branches of if
+// statements that are never taken because their condition is constant.
+package deadbranch
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const Doc = `check for branches made unreachable by a constant condition
+
+The deadbranch analyzer finds the branches of if statements that are never
+taken because the condition of the statement is a compile-time constant,
+such as
+
+	const debug = false
+
+	if debug {
+		log.Print("unreachable")
+	}
+
+and offers to remove them. A condition that depends on a constant whose
+value depends on the build configuration, such as runtime.GOOS or a constant
+declared in a file with build constraints, is reported as constant for the
+current build configuration only, and no removal is offered.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "deadbranch",
+	Doc:      Doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	nodeFilter := []ast.Node{
+		(*ast.IfStmt)(nil),
+	}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		stmt := n.(*ast.IfStmt)
+		tv, ok := pass.TypesInfo.Types[stmt.Cond]
+		if !ok || tv.Value == nil || tv.Value.Kind() != constant.Bool {
+			return
+		}
+		value := constant.BoolVal(tv.Value)
+
+		// The dead branch is the body when the condition is false, and the
+		// else branch, if any, when it is true.
+		var dead ast.Node = stmt.Body
+		if value {
+			if stmt.Else == nil {
+				return
+			}
+			dead = stmt.Else
+		}
+
+		diag := analysis.Diagnostic{
+			Pos:     dead.Pos(),
+			End:     dead.End(),
+			Message: fmt.Sprintf("unreachable branch: the condition is always %v", value),
+		}
+		if buildDependent(pass, stmt.Cond) {
+			diag.Message += " in this build configuration"
+			pass.Report(diag)
+			return
+		}
+		// Removing the statement of an if statement with an init statement
+		// would change the scope of the variables it declares.
+		if stmt.Init == nil {
+			diag.SuggestedFixes = []analysis.SuggestedFix{{
+				Message:   "Remove the unreachable branch",
+				TextEdits: removeBranch(stmt, value),
+			}}
+		}
+		pass.Report(diag)
+	})
+	return nil, nil
+}
+
+// removeBranch returns the edits that remove the dead branch of stmt, whose
+// condition has the given value. The branch that is taken is kept as a block,
+// so that the scope of the variables it declares is unchanged.
+func removeBranch(stmt *ast.IfStmt, value bool) []analysis.TextEdit {
+	if value {
+		// if true { A } else { B } => { A }
+		return []analysis.TextEdit{
+			{Pos: stmt.Pos(), End: stmt.Body.Lbrace},
+			{Pos: stmt.Body.End(), End: stmt.Else.End()},
+		}
+	}
+	if stmt.Else == nil {
+		// if false { A } =>
+		return []analysis.TextEdit{{Pos: stmt.Pos(), End: stmt.End()}}
+	}
+	// if false { A } else { B } => { B }
+	// if false { A } else if c { B } => if c { B }
+	return []analysis.TextEdit{{Pos: stmt.Pos(), End: stmt.Else.Pos()}}
+}
+
+// buildDependent reports whether the value of the constant expression e may
+// depend on the build configuration: the target operating system or
+// architecture, or the build tags.
+func buildDependent(pass *analysis.Pass, e ast.Expr) bool {
+	dependent := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		if dependent {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.Ident:
+			if c, ok := pass.TypesInfo.Uses[n].(*types.Const); ok && buildDependentConst(pass, c) {
+				dependent = true
+			}
+		case *ast.CallExpr:
+			// The results of unsafe.Sizeof, Alignof and Offsetof depend on
+			// the architecture.
+			if fun, ok := n.Fun.(*ast.SelectorExpr); ok {
+				if b, ok := pass.TypesInfo.Uses[fun.Sel].(*types.Builtin); ok {
+					switch b.Name() {
+					case "Sizeof", "Alignof", "Offsetof":
+						dependent = true
+					}
+				}
+			}
+		}
+		return !dependent
+	})
+	return dependent
+}
+
+// platformConsts are constants of the standard library whose values depend on
+// the target platform.
+var platformConsts = map[string]bool{
+	"runtime.GOOS":     true,
+	"runtime.GOARCH":   true,
+	"runtime.Compiler": true,
+	"strconv.IntSize":  true,
+	"math.MaxInt":      true,
+	"math.MinInt":      true,
+	"math.MaxUint":     true,
+}
+
+func buildDependentConst(pass *analysis.Pass, c *types.Const) bool {
+	if c.Pkg() == nil {
+		return false // true, false or iota
+	}
+	if platformConsts[c.Pkg().Path()+"."+c.Name()] {
+		return true
+	}
+	filename := pass.Fset.Position(c.Pos()).Filename
+	if filename == "" {
+		return false
+	}
+	if platformFileName(filepath.Base(filename)) {
+		return true
+	}
+	if c.Pkg() != pass.Pkg {
+		return false
+	}
+	for _, f := range pass.Files {
+		if f.Pos() <= c.Pos() && c.Pos() < f.End() {
+			return hasBuildConstraints(f)
+		}
+	}
+	return false
+}
+
+// hasBuildConstraints reports whether f has a +build or go:build comment
+// before its package clause.
+func hasBuildConstraints(f *ast.File) bool {
+	for _, group := range f.Comments {
+		if group.Pos() >= f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, "// +build ") || strings.HasPrefix(c.Text, "//go:build ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// platformFileName reports whether a Go file with the given name is only
+// built for some operating systems or architectures, as a file named
+// name_$GOOS.go, name_$GOARCH.go or name_$GOOS_$GOARCH.go is.
+func platformFileName(name string) bool {
+	name = strings.TrimSuffix(name, ".go")
+	name = strings.TrimSuffix(name, "_test")
+	parts := strings.Split(name, "_")
+	if len(parts) < 2 {
+		return false
+	}
+	last := parts[len(parts)-1]
+	return knownOS[last] || knownArch[last]
+}
+
+var knownOS = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true,
+	"freebsd": true, "hurd": true, "illumos": true, "ios": true, "js": true,
+	"linux": true, "nacl": true, "netbsd": true, "openbsd": true,
+	"plan9": true, "solaris": true, "wasip1": true, "windows": true,
+	"zos": true,
+}
+
+var knownArch = map[string]bool{
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true,
+	"arm64": true, "arm64be": true, "loong64": true, "mips": true,
+	"mipsle": true, "mips64": true, "mips64le": true, "mips64p32": true,
+	"mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true,
+	"riscv": true, "riscv64": true, "s390": true, "s390x": true,
+	"sparc": true, "sparc64": true, "wasm": true,
+}
@@ -0,0 +1,35 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package deadbranch_test
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/internal/lsp/analysis/deadbranch"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, deadbranch.Analyzer, "a")
+
+	// Only the branches whose removal does not depend on the build
+	// configuration or change the scope of a variable can be removed.
+	var fixes int
+	for _, result := range results {
+		for _, diag := range result.Diagnostics {
+			if len(diag.SuggestedFixes) > 0 {
+				fixes++
+				if strings.Contains(diag.Message, "build configuration") {
+					t.Errorf("got a fix for %q", diag.Message)
+				}
+			}
+		}
+	}
+	if fixes != 3 {
+		t.Errorf("got %d fixes, want 3", fixes)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+import "unsafe"
+
+const debug = false
+
+func f(x int) int {
+	if debug { // want "unreachable branch: the condition is always false"
+		println(x)
+	}
+
+	if !debug {
+		x++
+	} else { // want "unreachable branch: the condition is always true"
+		x--
+	}
+
+	if debug || !true { // want "unreachable branch: the condition is always false"
+		return 0
+	} else if x > 1 {
+		return 1
+	}
+
+	if y := x; debug { // want "unreachable branch: the condition is always false"
+		return y
+	}
+
+	if x > 0 {
+		return x
+	}
+
+	if true {
+		x++
+	}
+
+	if unsafe.Sizeof(x) == 1 { // want "unreachable branch: the condition is always false in this build configuration"
+		return 3
+	}
+
+	if !tagged { // want "unreachable branch: the condition is always false in this build configuration"
+		return 4
+	}
+	return x
+}
@@ -0,0 +1,218 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package doccomment defines an Analyzer that checks that the doc comments
+// of exported declarations start with the name of what they declare.
+package doccomment
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/internal/lsp/analysis/internal/comment"
+)
+
+const Doc = `check that doc comments start with the name of the declaration
+
+The doccomment analyzer reports the doc comments of exported declarations
+whose first word is not the name of what they declare, as go doc expects:
+
+	// Reads the named file.
+	func ReadFile(name string) ([]byte, error)
+
+The comment of a type may also start with "A", "An" or "The" followed by
+its name. The fix replaces the first word with the name when it looks like
+a stale name, such as after the declaration was renamed, and otherwise
+inserts the name before it.`
+
+var Analyzer = &analysis.Analyzer{
+	Name: "doccomment",
+	Doc:  Doc,
+	Run:  run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if !decl.Name.IsExported() {
+					continue
+				}
+				kind := "function"
+				if decl.Recv != nil {
+					if len(decl.Recv.List) == 0 || !exportedRecv(decl.Recv.List[0].Type) {
+						continue
+					}
+					kind = "method"
+				}
+				check(pass, decl.Doc, decl.Name.Name, kind)
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					// The doc comment of a group of declarations is that of
+					// the declaration if it is the only one of the group.
+					doc := decl.Doc
+					if decl.Lparen.IsValid() && len(decl.Specs) > 1 {
+						doc = nil
+					}
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if spec.Doc != nil {
+							doc = spec.Doc
+						}
+						if spec.Name.IsExported() {
+							check(pass, doc, spec.Name.Name, "type")
+						}
+					case *ast.ValueSpec:
+						if spec.Doc != nil {
+							doc = spec.Doc
+						}
+						if len(spec.Names) == 1 && spec.Names[0].IsExported() {
+							check(pass, doc, spec.Names[0].Name, decl.Tok.String())
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// exportedRecv reports whether the type of a receiver is exported.
+func exportedRecv(typ ast.Expr) bool {
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+		case *ast.ParenExpr:
+			typ = t.X
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.Ident:
+			return t.IsExported()
+		default:
+			return false
+		}
+	}
+}
+
+// check reports doc if it does not start with name, the name of a
+// declaration of the given kind.
+func check(pass *analysis.Pass, doc *ast.CommentGroup, name, kind string) {
+	if doc == nil {
+		return
+	}
+	// Directives, such as //go:generate, are not part of the text.
+	var text []*ast.Comment
+	for _, c := range doc.List {
+		if !comment.IsDirective(c.Text) {
+			text = append(text, c)
+		}
+	}
+	words := strings.Fields((&ast.CommentGroup{List: text}).Text())
+	if len(words) == 0 || startsWithName(words, name, kind) || strings.HasPrefix(words[0], "Deprecated:") {
+		return
+	}
+	diag := analysis.Diagnostic{
+		Pos:     text[0].Pos(),
+		End:     doc.End(),
+		Message: fmt.Sprintf("comment on exported %s %s should be of the form %q", kind, name, name+" ..."),
+	}
+	if edit, ok := fixFirstWord(text[0], name); ok {
+		diag.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   fmt.Sprintf("Start the comment with %s", name),
+			TextEdits: []analysis.TextEdit{edit},
+		}}
+	}
+	pass.Report(diag)
+}
+
+// startsWithName reports whether the words of a doc comment start with name,
+// possibly followed by punctuation, as in "Name's", or, for a type, with an
+// article followed by name.
+func startsWithName(words []string, name, kind string) bool {
+	if kind == "type" && len(words) > 1 {
+		switch words[0] {
+		case "A", "An", "The":
+			words = words[1:]
+		}
+	}
+	if !strings.HasPrefix(words[0], name) {
+		return false
+	}
+	rest := words[0][len(name):]
+	if rest == "" {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return !isIdentRune(r)
+}
+
+// fixFirstWord returns the edit that makes the first line c of a doc comment
+// start with name, if it is a line comment with a first word.
+func fixFirstWord(c *ast.Comment, name string) (analysis.TextEdit, bool) {
+	if !strings.HasPrefix(c.Text, "//") {
+		return analysis.TextEdit{}, false
+	}
+	text := c.Text[len("//"):]
+	start := len(text) - len(strings.TrimLeftFunc(text, unicode.IsSpace))
+	if start == len(text) {
+		return analysis.TextEdit{}, false
+	}
+	end := start
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !isIdentRune(r) {
+			break
+		}
+		end += size
+	}
+	pos := c.Slash + token.Pos(len("//")+start)
+	word := text[start:end]
+	if staleName(word, name) {
+		return analysis.TextEdit{Pos: pos, End: pos + token.Pos(len(word)), NewText: []byte(name)}, true
+	}
+	// Otherwise the comment starts with a sentence, such as "Reads the
+	// file", which the name becomes the subject of.
+	first, size := utf8.DecodeRuneInString(text[start:])
+	if second, _ := utf8.DecodeRuneInString(text[start+size:]); unicode.IsUpper(first) && !unicode.IsUpper(second) {
+		return analysis.TextEdit{Pos: pos, End: pos + token.Pos(size), NewText: []byte(name + " " + string(unicode.ToLower(first)))}, true
+	}
+	return analysis.TextEdit{Pos: pos, End: pos, NewText: []byte(name + " ")}, true
+}
+
+// staleName reports whether the first word of a doc comment is likely the
+// old name of the declaration named name: it is the name with another case,
+// or it is not a word of a sentence, as a mixed case identifier, or one with
+// a digit or an underscore, is not.
+func staleName(word, name string) bool {
+	if word == "" {
+		return false
+	}
+	if strings.EqualFold(word, name) {
+		return true
+	}
+	var lower, innerUpper bool
+	for i, r := range word {
+		switch {
+		case unicode.IsDigit(r) || r == '_':
+			return true
+		case unicode.IsLower(r):
+			lower = true
+		case i > 0 && unicode.IsUpper(r):
+			innerUpper = true
+		}
+	}
+	// An acronym, such as HTTP, may start a sentence.
+	return lower && innerUpper
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package doccomment_test
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/internal/lsp/analysis/doccomment"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, doccomment.Analyzer, "a")
+
+	// Each fix either replaces a stale name or makes the name the subject
+	// of the sentence.
+	var got []string
+	for _, result := range results {
+		for _, diag := range result.Diagnostics {
+			if len(diag.SuggestedFixes) != 1 || len(diag.SuggestedFixes[0].TextEdits) != 1 {
+				t.Errorf("got fixes %v for %q, want a single edit", diag.SuggestedFixes, diag.Message)
+				continue
+			}
+			edit := diag.SuggestedFixes[0].TextEdits[0]
+			file := result.Pass.Fset.File(edit.Pos)
+			content, err := ioutil.ReadFile(file.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			start, end := file.Offset(edit.Pos), file.Offset(edit.End)
+			fixed := string(content[:start]) + string(edit.NewText) + string(content[end:])
+			line := fixed[strings.LastIndex(fixed[:start], "\n")+1:]
+			line = line[:strings.Index(line, " // want")]
+			got = append(got, strings.TrimSpace(line))
+		}
+	}
+	want := []string{
+		"// ReadAll reads the named file.",
+		"// WriteFile writes the named file.",
+		"// Reset closes the reader.",
+		"// Send HTTP requests are sent by Send.",
+		"// Max old is renamed.",
+		"// Limit is stale.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got fixed comments\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
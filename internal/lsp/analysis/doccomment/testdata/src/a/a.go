@@ -0,0 +1,53 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+// ReadFile reads the named file.
+func ReadFile(name string) ([]byte, error) { return nil, nil }
+
+// Reads the named file. // want `comment on exported function ReadAll should be of the form "ReadAll ..."`
+func ReadAll(name string) ([]byte, error) { return nil, nil }
+
+// writeFile writes the named file. // want `comment on exported function WriteFile should be of the form "WriteFile ..."`
+func WriteFile(name string, data []byte) error { return nil }
+
+// A Reader reads.
+type Reader struct{}
+
+// Close's result is always nil.
+func (r *Reader) Close() error { return nil }
+
+// closes the reader. // want `comment on exported method Reset should be of the form "Reset ..."`
+func (r Reader) Reset() {}
+
+// Writer writes.
+type writer struct{}
+
+// HTTP is not the name.
+func (w *writer) Flush() {}
+
+//go:noinline
+// HTTP requests are sent by Send. // want `comment on exported function Send should be of the form "Send ..."`
+func Send() {}
+
+// Deprecated: use Send.
+func Post() {}
+
+const (
+	// Old is renamed. // want `comment on exported const Max should be of the form "Max ..."`
+	Max = 10
+
+	// Min is the minimum.
+	Min = 0
+)
+
+// Options of the package.
+var (
+	Verbose bool
+	Debug   bool
+)
+
+// Limit2 is stale. // want `comment on exported var Limit should be of the form "Limit ..."`
+var Limit = 3
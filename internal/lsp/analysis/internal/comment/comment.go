@@ -0,0 +1,26 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package comment provides helpers shared by the analyzers that check the
+// text of comments.
+package comment
+
+import (
+	"strings"
+	"unicode"
+)
+
+// IsDirective reports whether the text of a comment is a directive, such as
+// //go:generate, //lint:ignore or //export, rather than prose.
+func IsDirective(text string) bool {
+	if !strings.HasPrefix(text, "//") {
+		return false
+	}
+	text = text[len("//"):]
+	if strings.HasPrefix(text, "export ") || strings.HasPrefix(text, "line ") {
+		return true
+	}
+	word := strings.Fields(text + " ")
+	return len(word) > 0 && !unicode.IsSpace(rune(text[0])) && strings.Contains(word[0], ":")
+}
@@ -0,0 +1,292 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package spellcheck defines an Analyzer that checks for common misspellings
+// in comments, string literals and the names of declarations.
+package spellcheck
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/internal/lsp/analysis/internal/comment"
+)
+
+const Doc = `check for common misspellings in comments, strings and names
+
+The spellcheck analyzer reports the words of comments and string literals,
+and the words of the names of declarations, such as the "recieve" of
+recieveMessage, that are common misspellings. The fix of a word of a comment
+or a string replaces it, and that of a name renames the declaration.
+
+Words that are deliberately spelled so can be listed, one per line, in a
+file named .spelling in the directory of a package or any of its parents,
+such as the root of the workspace. Lines starting with # are comments.`
+
+var Analyzer = &analysis.Analyzer{
+	Name: "spellcheck",
+	Doc:  Doc,
+	Run:  run,
+}
+
+// AllowlistFile is the name of the file that lists the words that are not
+// reported in the files of its directory and its subdirectories.
+const AllowlistFile = ".spelling"
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	allowlists := make(map[string]map[string]bool)
+	for _, file := range pass.Files {
+		tok := pass.Fset.File(file.Pos())
+		if tok == nil {
+			continue
+		}
+		c := &checker{
+			pass:    pass,
+			allowed: allowlist(filepath.Dir(tok.Name()), allowlists),
+		}
+		for _, group := range file.Comments {
+			for _, com := range group.List {
+				if !comment.IsDirective(com.Text) {
+					c.text(com.Slash, com.Text, false)
+				}
+			}
+		}
+		// The paths of imports and struct tags are not prose, and the
+		// values of tags are part of encodings that cannot be changed.
+		skip := make(map[*ast.BasicLit]bool)
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ImportSpec:
+				return false
+			case *ast.Field:
+				if n.Tag != nil {
+					skip[n.Tag] = true
+				}
+			case *ast.BasicLit:
+				if n.Kind == token.STRING && !skip[n] {
+					c.text(n.ValuePos, n.Value, n.Value[0] == '"')
+				}
+			case *ast.Ident:
+				if n.Name != "_" && pass.TypesInfo.Defs[n] != nil {
+					c.ident(n)
+				}
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+type checker struct {
+	pass    *analysis.Pass
+	allowed map[string]bool
+}
+
+// text reports the misspelled words of text, the source of a comment or of a
+// string literal at pos. Escape sequences separate words if escapes is set.
+func (c *checker) text(pos token.Pos, text string, escapes bool) {
+	words(text, escapes, func(offset int, word string) {
+		fix, ok := c.correct(word)
+		if !ok {
+			return
+		}
+		start := pos + token.Pos(offset)
+		end := start + token.Pos(len(word))
+		c.pass.Report(analysis.Diagnostic{
+			Pos:     start,
+			End:     end,
+			Message: fmt.Sprintf("%q is a misspelling of %q", word, fix),
+			SuggestedFixes: []analysis.SuggestedFix{{
+				Message:   fmt.Sprintf("Replace with %q", fix),
+				TextEdits: []analysis.TextEdit{{Pos: start, End: end, NewText: []byte(fix)}},
+			}},
+		})
+	})
+}
+
+// ident reports the declaration of id if a word of its name is misspelled.
+// The fix corrects all of the misspelled words of the name.
+func (c *checker) ident(id *ast.Ident) {
+	var (
+		b          strings.Builder
+		word, fix  string
+		last       int
+		misspelled bool
+	)
+	for _, w := range nameWords(id.Name) {
+		f, ok := c.correct(id.Name[w[0]:w[1]])
+		if !ok {
+			continue
+		}
+		if !misspelled {
+			word, fix, misspelled = id.Name[w[0]:w[1]], f, true
+		}
+		b.WriteString(id.Name[last:w[0]])
+		b.WriteString(f)
+		last = w[1]
+	}
+	if !misspelled {
+		return
+	}
+	b.WriteString(id.Name[last:])
+	name := b.String()
+	c.pass.Report(analysis.Diagnostic{
+		Pos:     id.Pos(),
+		End:     id.End(),
+		Message: fmt.Sprintf("%q is a misspelling of %q in %s", word, fix, id.Name),
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   fmt.Sprintf("Rename %s to %s", id.Name, name),
+			TextEdits: []analysis.TextEdit{{Pos: id.Pos(), End: id.End(), NewText: []byte(name)}},
+		}},
+	})
+}
+
+// correct returns the correct spelling of word, with the same case, if it is
+// a misspelling that is not allowed. Only words that are lower case, upper
+// case, or capitalized are checked.
+func (c *checker) correct(word string) (string, bool) {
+	lower := strings.ToLower(word)
+	fix, ok := misspellings[lower]
+	if !ok || c.allowed[lower] {
+		return "", false
+	}
+	switch word {
+	case lower:
+		return fix, true
+	case strings.ToUpper(lower):
+		return strings.ToUpper(fix), true
+	}
+	r, size := utf8.DecodeRuneInString(word)
+	if unicode.IsUpper(r) && word[size:] == lower[size:] {
+		r, size := utf8.DecodeRuneInString(fix)
+		return string(unicode.ToUpper(r)) + fix[size:], true
+	}
+	return "", false
+}
+
+// words calls f with each word of text and its offset. The words are the
+// runs of letters that are not part of an identifier, such as foo_bar or
+// x2, nor of a path or a URL.
+func words(text string, escapes bool, f func(offset int, word string)) {
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if escapes && r == '\\' {
+			i += size + 1
+			continue
+		}
+		if !isWordRune(r) {
+			i += size
+			continue
+		}
+		start, letters := i, true
+		for i < len(text) {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if !isWordRune(r) {
+				break
+			}
+			letters = letters && unicode.IsLetter(r)
+			i += size
+		}
+		if letters && !inPath(text, start, i) {
+			f(start, text[start:i])
+		}
+	}
+}
+
+// inPath reports whether the word from start to end of text is an element
+// of a path, a host name or an address.
+func inPath(text string, start, end int) bool {
+	if start > 0 {
+		switch text[start-1] {
+		case '/', '.', '@':
+			return true
+		}
+	}
+	if end < len(text) {
+		switch text[end] {
+		case '/', '@':
+			return true
+		case '.':
+			r, _ := utf8.DecodeRuneInString(text[end+1:])
+			return unicode.IsLetter(r)
+		}
+	}
+	return false
+}
+
+// nameWords returns the start and end of the words of a mixed case or
+// underscore separated name. An upper case word followed by a capitalized
+// one, as in HTTPServer, ends before its last letter.
+func nameWords(name string) [][2]int {
+	var (
+		result [][2]int
+		start  = -1
+		prev   rune
+	)
+	for i, r := range name {
+		switch {
+		case !unicode.IsLetter(r):
+			if start >= 0 {
+				result = append(result, [2]int{start, i})
+			}
+			start = -1
+		case start < 0:
+			start = i
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			result = append(result, [2]int{start, i})
+			start = i
+		case unicode.IsLower(r) && unicode.IsUpper(prev) && i-utf8.RuneLen(prev) > start:
+			result = append(result, [2]int{start, i - utf8.RuneLen(prev)})
+			start = i - utf8.RuneLen(prev)
+		}
+		prev = r
+	}
+	if start >= 0 {
+		result = append(result, [2]int{start, len(name)})
+	}
+	return result
+}
+
+// allowlist returns the words of the allowlist file of dir, or of its
+// closest parent that has one. The allowlists of directories are cached in
+// cache.
+func allowlist(dir string, cache map[string]map[string]bool) map[string]bool {
+	if words, ok := cache[dir]; ok {
+		return words
+	}
+	var words map[string]bool
+	if data, err := ioutil.ReadFile(filepath.Join(dir, AllowlistFile)); err == nil {
+		words = parseAllowlist(data)
+	} else if parent := filepath.Dir(dir); parent != dir {
+		words = allowlist(parent, cache)
+	}
+	cache[dir] = words
+	return words
+}
+
+func parseAllowlist(data []byte) map[string]bool {
+	words := make(map[string]bool)
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words[strings.ToLower(line)] = true
+	}
+	return words
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,39 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spellcheck_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/internal/lsp/analysis/spellcheck"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, spellcheck.Analyzer, "a")
+
+	// The misspelled names of declarations are renamed, and all of their
+	// misspelled words corrected.
+	var got []string
+	for _, result := range results {
+		for _, diag := range result.Diagnostics {
+			for _, fix := range diag.SuggestedFixes {
+				if strings.HasPrefix(fix.Message, "Rename") {
+					got = append(got, fix.Message)
+				}
+			}
+		}
+	}
+	want := []string{
+		"Rename recieveMessage to receiveMessage",
+		"Rename HTTPReciever to HTTPReceiver",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got renames %q, want %q", got, want)
+	}
+}
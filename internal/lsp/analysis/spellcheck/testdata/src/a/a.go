@@ -0,0 +1,25 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+import "strings"
+
+// split returns teh fields of s. // want `misspelling of "the"`
+func split(s string) []string {
+	return strings.Fields(s)
+}
+
+func recieveMessage() string { // want `misspelling of "receive" in`
+	return "Recieved the MESAGE" // want `misspelling of "Received"` `misspelling of "MESSAGE"`
+}
+
+type HTTPReciever struct { // want `misspelling of "Receiver" in`
+	Address string `json:"adress"`
+}
+
+// The words of paths, such as https://example.com/recieve, of names,
+// such as recieve_all or recieve2, and of allowed words, such as wich,
+// are not checked.
+var _ = "line\nteh end" // want `misspelling of "the"`
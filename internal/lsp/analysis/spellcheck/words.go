@@ -0,0 +1,161 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spellcheck
+
+// misspellings maps common misspellings of English words, and of words
+// frequently used in programs, to their correct spelling. Only misspellings
+// that are not themselves words are listed, so that the analyzer has few
+// false positives.
+var misspellings = map[string]string{
+	"accesible":        "accessible",
+	"accomodate":       "accommodate",
+	"accross":          "across",
+	"acheive":          "achieve",
+	"adress":           "address",
+	"aggresive":        "aggressive",
+	"agressive":        "aggressive",
+	"alignement":       "alignment",
+	"allready":         "already",
+	"alloted":          "allotted",
+	"amoung":           "among",
+	"arguement":        "argument",
+	"arguements":       "arguments",
+	"asynchonous":      "asynchronous",
+	"asyncronous":      "asynchronous",
+	"attatch":          "attach",
+	"authentification": "authentication",
+	"avaiable":         "available",
+	"availabe":         "available",
+	"availible":        "available",
+	"becasue":          "because",
+	"becuase":          "because",
+	"begining":         "beginning",
+	"beleive":          "believe",
+	"bounday":          "boundary",
+	"buisness":         "business",
+	"capabilites":      "capabilities",
+	"charachter":       "character",
+	"charater":         "character",
+	"commited":         "committed",
+	"comming":          "coming",
+	"compatability":    "compatibility",
+	"compatable":       "compatible",
+	"compatiblity":     "compatibility",
+	"completly":        "completely",
+	"concurency":       "concurrency",
+	"conditon":         "condition",
+	"configuraton":     "configuration",
+	"connnection":      "connection",
+	"consistant":       "consistent",
+	"containg":         "containing",
+	"contruct":         "construct",
+	"corresponing":     "corresponding",
+	"currenty":         "currently",
+	"decripted":        "decrypted",
+	"defintion":        "definition",
+	"deleteing":        "deleting",
+	"dependancy":       "dependency",
+	"dependecy":        "dependency",
+	"depricated":       "deprecated",
+	"desciption":       "description",
+	"destory":          "destroy",
+	"diffrent":         "different",
+	"directoy":         "directory",
+	"enviroment":       "environment",
+	"environement":     "environment",
+	"equivalant":       "equivalent",
+	"exection":         "execution",
+	"existance":        "existence",
+	"existant":         "existent",
+	"explicitely":      "explicitly",
+	"fucntion":         "function",
+	"funciton":         "function",
+	"functionallity":   "functionality",
+	"garantee":         "guarantee",
+	"guarentee":        "guarantee",
+	"heirarchy":        "hierarchy",
+	"identifer":        "identifier",
+	"immediatly":       "immediately",
+	"implemenation":    "implementation",
+	"implemention":     "implementation",
+	"implmentation":    "implementation",
+	"independant":      "independent",
+	"infomation":       "information",
+	"initalize":        "initialize",
+	"intial":           "initial",
+	"intialize":        "initialize",
+	"lenght":           "length",
+	"maintainance":     "maintenance",
+	"managment":        "management",
+	"mesage":           "message",
+	"messsage":         "message",
+	"neccessary":       "necessary",
+	"necesary":         "necessary",
+	"nessecary":        "necessary",
+	"noticable":        "noticeable",
+	"occured":          "occurred",
+	"occurence":        "occurrence",
+	"occurrance":       "occurrence",
+	"ommit":            "omit",
+	"ommited":          "omitted",
+	"paramater":        "parameter",
+	"paramenter":       "parameter",
+	"paramter":         "parameter",
+	"paramters":        "parameters",
+	"parrallel":        "parallel",
+	"particularily":    "particularly",
+	"perfomance":       "performance",
+	"permision":        "permission",
+	"persistant":       "persistent",
+	"posible":          "possible",
+	"preceeding":       "preceding",
+	"prefered":         "preferred",
+	"presense":         "presence",
+	"previos":          "previous",
+	"priviledge":       "privilege",
+	"proccess":         "process",
+	"properies":        "properties",
+	"propery":          "property",
+	"recieve":          "receive",
+	"recieved":         "received",
+	"reciever":         "receiver",
+	"recomend":         "recommend",
+	"recursivly":       "recursively",
+	"refered":          "referred",
+	"registery":        "registry",
+	"relevent":         "relevant",
+	"repositry":        "repository",
+	"reponse":          "response",
+	"requried":         "required",
+	"resouce":          "resource",
+	"responce":         "response",
+	"retreive":         "retrieve",
+	"retrived":         "retrieved",
+	"seperate":         "separate",
+	"seperated":        "separated",
+	"seperator":        "separator",
+	"sepcify":          "specify",
+	"sucessful":        "successful",
+	"succesful":        "successful",
+	"successfull":      "successful",
+	"suport":           "support",
+	"supress":          "suppress",
+	"suppport":         "support",
+	"syncronous":       "synchronous",
+	"teh":              "the",
+	"threshhold":       "threshold",
+	"tranform":         "transform",
+	"transfered":       "transferred",
+	"truely":           "truly",
+	"unecessary":       "unnecessary",
+	"unneccessary":     "unnecessary",
+	"untill":           "until",
+	"usefull":          "useful",
+	"varaible":         "variable",
+	"variabel":         "variable",
+	"verison":          "version",
+	"wich":             "which",
+	"writting":         "writing",
+}
@@ -0,0 +1,265 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tagname defines an Analyzer that checks the names of the json
+// struct tags of the fields of a struct against each other and against the
+// names of the fields.
+package tagname
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+const Doc = `check that json tag names match their fields
+
+The tagname analyzer reports the json names of struct fields that are the
+names of other fields of the struct, which is usually the result of copying
+the tag of a field to another one:
+
+	type User struct {
+		ID   int    ` + "`json:\"id\"`" + `
+		Name string ` + "`json:\"id\"`" + `
+	}
+
+It also reports the json names that do not follow the case, such as
+camelCase or snake_case, of most of the other json names of the struct. The
+fixes rename the json name after the field, in the case of the others.`
+
+var Analyzer = &analysis.Analyzer{
+	Name: "tagname",
+	Doc:  Doc,
+	Run:  run,
+}
+
+// The cases of the names of struct tags.
+const (
+	CamelCase  = "camelCase"
+	SnakeCase  = "snake_case"
+	KebabCase  = "kebab-case"
+	PascalCase = "PascalCase"
+)
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if st, ok := n.(*ast.StructType); ok {
+				checkStruct(pass, st)
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// jsonField is a field of a struct with a json name.
+type jsonField struct {
+	field     *ast.Field
+	name      string    // the json name
+	pos, end  token.Pos // of the json name, or of the tag if it is not raw
+	editable  bool      // the name can be replaced from pos to end
+	fieldName string
+}
+
+func checkStruct(pass *analysis.Pass, st *ast.StructType) {
+	fieldNames := make(map[string]string) // by normalized name
+	var fields []jsonField
+	for _, field := range st.Fields.List {
+		for _, name := range field.Names {
+			fieldNames[normalize(name.Name)] = name.Name
+		}
+		if len(field.Names) != 1 || field.Tag == nil {
+			continue
+		}
+		if f, ok := jsonName(field); ok {
+			fields = append(fields, f)
+		}
+	}
+
+	// The case of most of the names, if there is one.
+	counts := make(map[string]int)
+	for _, f := range fields {
+		if c := caseOf(f.name); c != "" {
+			counts[c]++
+		}
+	}
+	majority, most, tie := "", 1, false
+	for c, n := range counts {
+		switch {
+		case n > most:
+			majority, most, tie = c, n, false
+		case n == most:
+			tie = true
+		}
+	}
+	if tie {
+		majority = ""
+	}
+
+	for _, f := range fields {
+		name := normalize(f.name)
+		if other, ok := fieldNames[name]; ok && name != normalize(f.fieldName) {
+			casing := majority
+			if casing == "" {
+				casing = CamelCase
+			}
+			report(pass, f, Convert(f.fieldName, casing),
+				"json name %q of field %s is the name of field %s", f.name, f.fieldName, other)
+			continue
+		}
+		if c := caseOf(f.name); majority != "" && c != "" && c != majority {
+			report(pass, f, Convert(f.fieldName, majority),
+				"json name %q is not in the %s of the other json names of the struct", f.name, majority)
+		}
+	}
+}
+
+func report(pass *analysis.Pass, f jsonField, fix, format string, args ...interface{}) {
+	diag := analysis.Diagnostic{
+		Pos:     f.pos,
+		End:     f.end,
+		Message: fmt.Sprintf(format, args...),
+	}
+	if f.editable && fix != f.name {
+		diag.SuggestedFixes = []analysis.SuggestedFix{{
+			Message:   fmt.Sprintf("Rename to %q", fix),
+			TextEdits: []analysis.TextEdit{{Pos: f.pos, End: f.end, NewText: []byte(fix)}},
+		}}
+	}
+	pass.Report(diag)
+}
+
+// jsonName returns the json name of field, which has a single name and a
+// tag. Fields whose json name is empty or "-" have none.
+func jsonName(field *ast.Field) (jsonField, bool) {
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return jsonField{}, false
+	}
+	value, ok := reflect.StructTag(tag).Lookup("json")
+	if !ok {
+		return jsonField{}, false
+	}
+	name := value
+	if i := strings.IndexByte(name, ','); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" || name == "-" {
+		return jsonField{}, false
+	}
+	f := jsonField{
+		field:     field,
+		name:      name,
+		pos:       field.Tag.Pos(),
+		end:       field.Tag.End(),
+		fieldName: field.Names[0].Name,
+	}
+	// The offsets of the name in a raw string are those in the source.
+	if field.Tag.Value[0] == '`' {
+		if i := strings.Index(tag, `json:"`+name); i >= 0 {
+			f.pos = field.Tag.Pos() + token.Pos(1+i+len(`json:"`))
+			f.end = f.pos + token.Pos(len(name))
+			f.editable = true
+		}
+	}
+	return f, true
+}
+
+// normalize returns name in lower case without its underscores and dashes.
+func normalize(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
+
+// caseOf returns the case of the name of a tag, or "" if it could be in
+// several cases, as a single lower case word is.
+func caseOf(name string) string {
+	hasUpper := strings.IndexFunc(name, unicode.IsUpper) >= 0
+	switch {
+	case strings.Contains(name, "_"):
+		if !hasUpper && !strings.Contains(name, "-") {
+			return SnakeCase
+		}
+	case strings.Contains(name, "-"):
+		if !hasUpper {
+			return KebabCase
+		}
+	case hasUpper:
+		r, _ := utf8.DecodeRuneInString(name)
+		if unicode.IsUpper(r) {
+			return PascalCase
+		}
+		return CamelCase
+	}
+	return ""
+}
+
+// Convert returns the name of a tag for the Go name of a field in the given
+// case, such as userID for UserID in camelCase, or user_id in snake_case.
+// The name of the field is returned as is in PascalCase.
+func Convert(name, casing string) string {
+	words := Words(name)
+	if len(words) == 0 {
+		return name
+	}
+	switch casing {
+	case SnakeCase, KebabCase:
+		sep := "_"
+		if casing == KebabCase {
+			sep = "-"
+		}
+		for i, w := range words {
+			words[i] = strings.ToLower(w)
+		}
+		return strings.Join(words, sep)
+	case CamelCase:
+		words[0] = strings.ToLower(words[0])
+		for i, w := range words[1:] {
+			r, size := utf8.DecodeRuneInString(w)
+			words[i+1] = string(unicode.ToUpper(r)) + w[size:]
+		}
+		return strings.Join(words, "")
+	}
+	return name
+}
+
+// Words returns the words of a mixed case or underscore separated name. An
+// upper case word followed by a capitalized one, as in HTTPServer, ends before
+// its last letter.
+func Words(name string) []string {
+	var (
+		words []string
+		start = -1
+		prev  rune
+	)
+	for i, r := range name {
+		switch {
+		case r == '_' || r == '-':
+			if start >= 0 {
+				words = append(words, name[start:i])
+			}
+			start = -1
+		case start < 0:
+			start = i
+		case unicode.IsUpper(r) && !unicode.IsUpper(prev):
+			words = append(words, name[start:i])
+			start = i
+		case unicode.IsLower(r) && unicode.IsUpper(prev) && i-utf8.RuneLen(prev) > start:
+			words = append(words, name[start:i-utf8.RuneLen(prev)])
+			start = i - utf8.RuneLen(prev)
+		}
+		prev = r
+	}
+	if start >= 0 {
+		words = append(words, name[start:])
+	}
+	return words
+}
@@ -0,0 +1,39 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tagname_test
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/internal/lsp/analysis/tagname"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, tagname.Analyzer, "a")
+}
+
+func TestConvert(t *testing.T) {
+	for _, test := range []struct {
+		name, casing, want string
+	}{
+		{"UserID", tagname.CamelCase, "userID"},
+		{"UserID", tagname.SnakeCase, "user_id"},
+		{"HTTPServer", tagname.KebabCase, "http-server"},
+		{"HTTPServer", tagname.CamelCase, "httpServer"},
+		{"Name", tagname.SnakeCase, "name"},
+		{"Name", tagname.PascalCase, "Name"},
+		{"first_name", tagname.CamelCase, "firstName"},
+	} {
+		if got := tagname.Convert(test.name, test.casing); got != test.want {
+			t.Errorf("Convert(%q, %q) = %q, want %q", test.name, test.casing, got, test.want)
+		}
+	}
+	if got, want := tagname.Words("XMLHTTPRequest2"), []string{"XMLHTTP", "Request2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Words = %q, want %q", got, want)
+	}
+}
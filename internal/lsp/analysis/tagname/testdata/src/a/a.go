@@ -0,0 +1,34 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+type Copied struct {
+	ID   int    `json:"id"`
+	Name string `json:"id"` // want `json name "id" of field Name is the name of field ID`
+}
+
+type Mixed struct {
+	UserID    int    `json:"user_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"lastName,omitempty"` // want `json name "lastName" is not in the snake_case of the other json names of the struct`
+	Age       int    `json:"age"`
+}
+
+type Tie struct {
+	UserID    int    `json:"user_id"`
+	FirstName string `json:"firstName"`
+}
+
+type Ignored struct {
+	A, B   int
+	Secret string `json:"-"`
+	Empty  string `json:",omitempty"`
+	Other  string `yaml:"a"`
+}
+
+type Renamed struct {
+	HTTPServer string `json:"server"`
+	Port       int    `json:"port"`
+}
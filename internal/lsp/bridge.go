@@ -0,0 +1,345 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/internal/jsonrpc2"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/log"
+	"golang.org/x/tools/internal/telemetry/tag"
+	"golang.org/x/tools/internal/xcontext"
+	errors "golang.org/x/xerrors"
+)
+
+// The requests for files that are not Go files, such as the .proto files
+// that a //go:generate directive compiles, may be handed off to another
+// language server, set for their extension by the externalLanguageServers
+// setting. gopls starts the server when the first such file is opened, and
+// forwards to it the notifications and requests of the files, so that an
+// editor that only talks to gopls still gets the features of the other
+// server. The diagnostics and messages that the other server sends are
+// forwarded to the client, and the workspace symbols of all the servers are
+// added to those of gopls.
+
+// bridge holds the external language servers that gopls forwards requests
+// to.
+type bridge struct {
+	mu     sync.Mutex
+	starts map[string]*externalStart // by command line
+
+	// capabilities are those of the client, which the external servers are
+	// initialized with.
+	capabilities protocol.ClientCapabilities
+
+	// dial connects to the external server that the command starts. It is
+	// replaced by tests.
+	dial func(ctx context.Context, command []string) (jsonrpc2.Stream, io.Closer, error)
+}
+
+// externalServer is a language server that gopls forwards the requests for
+// the files of some extensions to.
+type externalServer struct {
+	protocol.Server
+
+	command []string
+	conn    *jsonrpc2.Conn
+	closer  io.Closer
+}
+
+// externalStart is the start of an external language server, which the
+// requests for its files wait for. done is closed once the server is
+// started, or failed to start at the time failed.
+type externalStart struct {
+	done   chan struct{}
+	es     *externalServer
+	err    error
+	failed time.Time
+}
+
+var (
+	// externalStartTimeout is the time that an external language server
+	// has to initialize.
+	externalStartTimeout = 30 * time.Second
+
+	// externalRetryDelay is the time after which an external language
+	// server that failed to start is started again, rather than the
+	// requests for its files failing with the same error.
+	externalRetryDelay = time.Minute
+)
+
+// external returns the external language server that handles the file with
+// the given URI, starting it if needed. It returns nil if the file is handled
+// by gopls.
+func (s *Server) external(ctx context.Context, uri protocol.DocumentURI) (*externalServer, error) {
+	ext := filepath.Ext(span.NewURI(uri).Filename())
+	command := s.session.Options().ExternalLanguageServers[ext]
+	if len(command) == 0 {
+		return nil, nil
+	}
+	key := strings.Join(command, " ")
+
+	// The server is started outside of the lock, so that a server that is
+	// slow to start does not hold up the requests for the other servers.
+	b := &s.bridge
+	b.mu.Lock()
+	start, ok := b.starts[key]
+	if ok && !start.failed.IsZero() && time.Since(start.failed) >= externalRetryDelay {
+		ok = false
+	}
+	if !ok {
+		start = &externalStart{done: make(chan struct{})}
+		if b.starts == nil {
+			b.starts = make(map[string]*externalStart)
+		}
+		b.starts[key] = start
+		// The server outlives the request that starts it.
+		go s.startExternal(xcontext.Detach(ctx), ext, command, start)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-start.done:
+		return start.es, start.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startExternal starts and initializes the external language server of the
+// command, and records it, or the error starting it, in start.
+func (s *Server) startExternal(ctx context.Context, ext string, command []string, start *externalStart) {
+	es, err := s.dialExternal(ctx, ext, command)
+
+	b := &s.bridge
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		start.err, start.failed = err, time.Now()
+	} else if b.starts[strings.Join(command, " ")] != start {
+		// The external servers were shut down in the meantime.
+		go es.closer.Close()
+		start.err = errors.Errorf("language server for %s files was shut down", ext)
+	} else {
+		start.es = es
+	}
+	close(start.done)
+}
+
+// dialExternal starts and initializes the external language server of the
+// command, which has externalStartTimeout to initialize.
+func (s *Server) dialExternal(ctx context.Context, ext string, command []string) (*externalServer, error) {
+	dial := s.bridge.dial
+	if dial == nil {
+		dial = startCommand
+	}
+	stream, closer, err := dial(ctx, command)
+	if err != nil {
+		return nil, errors.Errorf("starting language server for %s files: %w", ext, err)
+	}
+	es := &externalServer{command: command, closer: closer}
+	ctx, es.conn, es.Server = protocol.NewClient(ctx, stream, &bridgeClient{server: s})
+	go func() {
+		if err := es.conn.Run(ctx); err != nil {
+			log.Error(ctx, "external language server stopped", err, tag.Of("Command", strings.Join(command, " ")))
+		}
+	}()
+
+	params := &protocol.ParamInitia{}
+	params.ProcessID = float64(os.Getpid())
+	params.Capabilities = s.bridge.capabilities
+	for _, view := range s.session.Views() {
+		params.WorkspaceFolders = append(params.WorkspaceFolders, protocol.WorkspaceFolder{
+			URI:  protocol.NewURI(view.Folder()),
+			Name: view.Name(),
+		})
+	}
+	if len(params.WorkspaceFolders) > 0 {
+		params.RootURI = params.WorkspaceFolders[0].URI
+	}
+	initCtx, cancel := context.WithTimeout(ctx, externalStartTimeout)
+	defer cancel()
+	if _, err := es.Initialize(initCtx, params); err != nil {
+		go closer.Close()
+		return nil, errors.Errorf("initializing language server for %s files: %w", ext, err)
+	}
+	if err := es.Initialized(initCtx, &protocol.InitializedParams{}); err != nil {
+		go closer.Close()
+		return nil, err
+	}
+	return es, nil
+}
+
+// externalServers returns the external language servers that are running.
+func (s *Server) externalServers() []*externalServer {
+	s.bridge.mu.Lock()
+	defer s.bridge.mu.Unlock()
+	return s.bridge.startedLocked()
+}
+
+// startedLocked returns the external language servers that are started.
+// b.mu must be held.
+func (b *bridge) startedLocked() []*externalServer {
+	var servers []*externalServer
+	for _, start := range b.starts {
+		select {
+		case <-start.done:
+			if start.es != nil {
+				servers = append(servers, start.es)
+			}
+		default:
+		}
+	}
+	return servers
+}
+
+// shutdownExternal shuts down the external language servers. The servers
+// that are still starting are closed once they are started.
+func (s *Server) shutdownExternal(ctx context.Context) {
+	s.bridge.mu.Lock()
+	servers := s.bridge.startedLocked()
+	s.bridge.starts = nil
+	s.bridge.mu.Unlock()
+
+	for _, es := range servers {
+		key := strings.Join(es.command, " ")
+		if err := es.Shutdown(ctx); err != nil {
+			log.Error(ctx, "shutting down external language server", err, tag.Of("Command", key))
+		}
+		// The server exits once it is notified, so the error of the
+		// notification is of no interest.
+		es.Exit(ctx)
+		es.closer.Close()
+	}
+}
+
+// externalSymbols returns the workspace symbols of the external language
+// servers that match the query, which are added to those of gopls.
+func (s *Server) externalSymbols(ctx context.Context, params *protocol.WorkspaceSymbolParams) []protocol.SymbolInformation {
+	var symbols []protocol.SymbolInformation
+	for _, es := range s.externalServers() {
+		result, err := es.Symbol(ctx, params)
+		if err != nil {
+			log.Error(ctx, "workspace symbols of external language server", err, tag.Of("Command", strings.Join(es.command, " ")))
+			continue
+		}
+		symbols = append(symbols, result...)
+	}
+	return symbols
+}
+
+// startCommand starts the language server command, and returns a stream
+// connected to its standard input and output.
+func startCommand(ctx context.Context, command []string) (jsonrpc2.Stream, io.Closer, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+	return jsonrpc2.NewHeaderStream(stdout, stdin), commandCloser{cmd, stdin}, nil
+}
+
+// commandCloser closes the input of a language server command, and waits
+// for it to exit.
+type commandCloser struct {
+	cmd   *exec.Cmd
+	stdin io.Closer
+}
+
+// externalExitTimeout is the time that a language server command has to
+// exit once its input is closed, before it is killed.
+const externalExitTimeout = 5 * time.Second
+
+func (c commandCloser) Close() error {
+	c.stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- c.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(externalExitTimeout):
+		c.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+// bridgeClient is the client of an external language server. It forwards the
+// diagnostics, messages and edits of the server to the client of gopls.
+type bridgeClient struct {
+	server *Server
+}
+
+func (c *bridgeClient) ShowMessage(ctx context.Context, params *protocol.ShowMessageParams) error {
+	return c.server.client.ShowMessage(ctx, params)
+}
+
+func (c *bridgeClient) LogMessage(ctx context.Context, params *protocol.LogMessageParams) error {
+	return c.server.client.LogMessage(ctx, params)
+}
+
+func (c *bridgeClient) Event(ctx context.Context, event *interface{}) error {
+	return nil
+}
+
+func (c *bridgeClient) PublishDiagnostics(ctx context.Context, params *protocol.PublishDiagnosticsParams) error {
+	return c.server.client.PublishDiagnostics(ctx, params)
+}
+
+func (c *bridgeClient) Progress(ctx context.Context, params *protocol.ProgressParams) error {
+	return c.server.client.Progress(ctx, params)
+}
+
+func (c *bridgeClient) WorkspaceFolders(ctx context.Context) ([]protocol.WorkspaceFolder, error) {
+	var folders []protocol.WorkspaceFolder
+	for _, view := range c.server.session.Views() {
+		folders = append(folders, protocol.WorkspaceFolder{
+			URI:  protocol.NewURI(view.Folder()),
+			Name: view.Name(),
+		})
+	}
+	return folders, nil
+}
+
+func (c *bridgeClient) Configuration(ctx context.Context, params *protocol.ParamConfig) ([]interface{}, error) {
+	// The external server has no settings of its own.
+	return make([]interface{}, len(params.Items)), nil
+}
+
+func (c *bridgeClient) RegisterCapability(ctx context.Context, params *protocol.RegistrationParams) error {
+	return nil
+}
+
+func (c *bridgeClient) UnregisterCapability(ctx context.Context, params *protocol.UnregistrationParams) error {
+	return nil
+}
+
+func (c *bridgeClient) ShowMessageRequest(ctx context.Context, params *protocol.ShowMessageRequestParams) (*protocol.MessageActionItem, error) {
+	return c.server.client.ShowMessageRequest(ctx, params)
+}
+
+func (c *bridgeClient) ApplyEdit(ctx context.Context, params *protocol.ApplyWorkspaceEditParams) (*protocol.ApplyWorkspaceEditResponse, error) {
+	return c.server.client.ApplyEdit(ctx, params)
+}
+
+func (c *bridgeClient) WorkDoneProgressCreate(ctx context.Context, params *protocol.WorkDoneProgressCreateParams) error {
+	return c.server.client.WorkDoneProgressCreate(ctx, params)
+}
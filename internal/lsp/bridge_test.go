@@ -0,0 +1,255 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/internal/jsonrpc2"
+	"golang.org/x/tools/internal/lsp/cache"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/tests"
+	"golang.org/x/tools/internal/span"
+)
+
+// fakeExternalServer is an external language server that records the files
+// opened in it, and defines every symbol at the start of its file. Its other
+// methods are unimplemented.
+type fakeExternalServer struct {
+	protocol.Server
+	client protocol.Client
+
+	mu       sync.Mutex
+	opened   []string
+	shutdown bool
+}
+
+func (s *fakeExternalServer) Initialize(context.Context, *protocol.ParamInitia) (*protocol.InitializeResult, error) {
+	return &protocol.InitializeResult{}, nil
+}
+
+func (s *fakeExternalServer) Initialized(context.Context, *protocol.InitializedParams) error {
+	return nil
+}
+
+func (s *fakeExternalServer) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
+	s.mu.Lock()
+	s.opened = append(s.opened, params.TextDocument.URI)
+	s.mu.Unlock()
+	return s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+		URI:         params.TextDocument.URI,
+		Diagnostics: []protocol.Diagnostic{{Message: "external", Source: "fake"}},
+	})
+}
+
+func (s *fakeExternalServer) Definition(ctx context.Context, params *protocol.DefinitionParams) ([]protocol.Location, error) {
+	return []protocol.Location{{URI: params.TextDocument.URI}}, nil
+}
+
+func (s *fakeExternalServer) Shutdown(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdown = true
+	return nil
+}
+
+func (s *fakeExternalServer) Exit(context.Context) error {
+	return nil
+}
+
+// newBridgeServer returns a server for a module in a new directory, which
+// hands off the .proto files to the external language server fakels.
+func newBridgeServer(t *testing.T) (s *Server, client *fakeClient, dir string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "bridge_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"go.mod":      "module example.com/a\n",
+		"a.go":        "package a\n\n//go:generate protoc a.proto\n",
+		"a.proto":     "syntax = \"proto3\";\n",
+		"other.proto": "syntax = \"proto3\";\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := tests.Context(t)
+	session := cache.New(nil).NewSession(ctx)
+	options := tests.DefaultOptions()
+	options.ExternalLanguageServers = map[string][]string{".proto": {"fakels"}}
+	session.SetOptions(options)
+	session.NewView(ctx, "bridge_test", span.FileURI(dir), options)
+	client = newFakeClient()
+	s = &Server{
+		client:      client,
+		session:     session,
+		undelivered: make(map[span.URI][]source.Diagnostic),
+	}
+	return s, client, dir
+}
+
+// pipeExternal connects to the external server over a pipe, as the dial of
+// a bridge does, and returns the client of the server too.
+func pipeExternal(ctx context.Context, external protocol.Server) (jsonrpc2.Stream, io.Closer, protocol.Client) {
+	cr, sw := net.Pipe()
+	sr, cw := net.Pipe()
+	ctx, conn, c := protocol.NewServer(ctx, jsonrpc2.NewHeaderStream(sr, sw), external)
+	go conn.Run(ctx)
+	return jsonrpc2.NewHeaderStream(cr, cw), cr, c
+}
+
+func TestExternalLanguageServer(t *testing.T) {
+	s, client, dir := newBridgeServer(t)
+	defer os.RemoveAll(dir)
+	ctx := tests.Context(t)
+	external := &fakeExternalServer{}
+	dials := 0
+	s.bridge.dial = func(ctx context.Context, command []string) (jsonrpc2.Stream, io.Closer, error) {
+		dials++
+		if !reflect.DeepEqual(command, []string{"fakels"}) {
+			t.Errorf("got command %q, want fakels", command)
+		}
+		stream, closer, c := pipeExternal(ctx, external)
+		external.client = c
+		return stream, closer, nil
+	}
+
+	open := func(name string) protocol.DocumentURI {
+		uri := protocol.NewURI(span.FileURI(filepath.Join(dir, name)))
+		if err := s.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{URI: uri, Text: "syntax = \"proto3\";\n"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return uri
+	}
+	a := open("a.proto")
+	other := open("other.proto")
+	if dials != 1 {
+		t.Errorf("started the external server %d times, want 1", dials)
+	}
+	// The diagnostics of the external server are sent to the client, in the
+	// background.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		client.mu.Lock()
+		diagnostics := client.diagnostics[span.NewURI(a)]
+		client.mu.Unlock()
+		if len(diagnostics) == 1 && diagnostics[0].Message == "external" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got diagnostics %v for %s, want the external one", diagnostics, a)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	params := &protocol.DefinitionParams{}
+	params.TextDocument.URI = a
+	locations, err := s.Definition(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 1 || locations[0].URI != a {
+		t.Errorf("got definitions %v, want the start of %s", locations, a)
+	}
+
+	// The external server handles its requests in order, so it has handled
+	// the notifications sent before the definition request.
+	external.mu.Lock()
+	if want := []string{a, other}; !reflect.DeepEqual(external.opened, want) {
+		t.Errorf("got opened files %q, want %q", external.opened, want)
+	}
+	external.mu.Unlock()
+
+	s.shutdownExternal(ctx)
+	external.mu.Lock()
+	defer external.mu.Unlock()
+	if !external.shutdown {
+		t.Error("the external server was not shut down")
+	}
+}
+
+// hangingExternalServer is an external language server that never finishes
+// initializing.
+type hangingExternalServer struct {
+	fakeExternalServer
+}
+
+func (s *hangingExternalServer) Initialize(ctx context.Context, _ *protocol.ParamInitia) (*protocol.InitializeResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestExternalLanguageServerStartFailure(t *testing.T) {
+	defer func(d time.Duration) { externalRetryDelay = d }(externalRetryDelay)
+	externalRetryDelay = time.Hour
+
+	s, _, dir := newBridgeServer(t)
+	defer os.RemoveAll(dir)
+	ctx := tests.Context(t)
+	var mu sync.Mutex
+	dials := 0
+	s.bridge.dial = func(context.Context, []string) (jsonrpc2.Stream, io.Closer, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		dials++
+		return nil, nil, errors.New("no such command")
+	}
+	uri := protocol.NewURI(span.FileURI(filepath.Join(dir, "a.proto")))
+	start := func() int {
+		t.Helper()
+		if es, err := s.external(ctx, uri); err == nil || es != nil {
+			t.Fatalf("got server %v and error %v, want the start to fail", es, err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return dials
+	}
+
+	// The failure is remembered, rather than the server being started again
+	// for every request.
+	start()
+	if got := start(); got != 1 {
+		t.Errorf("started the external server %d times, want 1", got)
+	}
+
+	// It is started again once the retry delay has passed.
+	externalRetryDelay = 0
+	if got := start(); got != 2 {
+		t.Errorf("started the external server %d times, want 2", got)
+	}
+}
+
+func TestExternalLanguageServerStartTimeout(t *testing.T) {
+	defer func(d time.Duration) { externalStartTimeout = d }(externalStartTimeout)
+	externalStartTimeout = 100 * time.Millisecond
+
+	s, _, dir := newBridgeServer(t)
+	defer os.RemoveAll(dir)
+	ctx := tests.Context(t)
+	s.bridge.dial = func(ctx context.Context, _ []string) (jsonrpc2.Stream, io.Closer, error) {
+		stream, closer, _ := pipeExternal(ctx, &hangingExternalServer{})
+		return stream, closer, nil
+	}
+	uri := protocol.NewURI(span.FileURI(filepath.Join(dir, "a.proto")))
+	if es, err := s.external(ctx, uri); err == nil || es != nil {
+		t.Errorf("got server %v and error %v, want the initialization to time out", es, err)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/internal/bug"
+	"golang.org/x/tools/internal/lsp/debug"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/telemetry/log"
+)
+
+// createBugReport is the action of the message that offers the user to
+// create a bug report bundle.
+const createBugReport = "Create bug report"
+
+// handleBugs registers a handler of the internal bugs that offers the user,
+// for the first bug reported while the server runs, to create a bug report
+// bundle. It returns a function that unregisters the handler.
+func (s *Server) handleBugs(ctx context.Context) (unregister func()) {
+	var once sync.Once
+	return bug.Handle(func(b bug.Bug) {
+		once.Do(func() {
+			go s.offerBugReport(ctx, b)
+		})
+	})
+}
+
+func (s *Server) offerBugReport(ctx context.Context, b bug.Bug) {
+	action, err := s.client.ShowMessageRequest(ctx, &protocol.ShowMessageRequestParams{
+		Type:    protocol.Error,
+		Message: fmt.Sprintf("gopls found an internal bug: %s. Create a bug report bundle to attach to an issue? It does not contain your source code.", b.Description),
+		Actions: []protocol.MessageActionItem{{Title: createBugReport}},
+	})
+	if err != nil || action == nil || action.Title != createBugReport {
+		return
+	}
+	f, err := ioutil.TempFile("", "gopls-bugreport-*.zip")
+	if err != nil {
+		log.Error(ctx, "creating bug report", err)
+		return
+	}
+	err = WriteBugReport(f, s.session.Cache())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Error(ctx, "writing bug report", err)
+		return
+	}
+	s.client.ShowMessage(ctx, &protocol.ShowMessageParams{
+		Type:    protocol.Info,
+		Message: fmt.Sprintf("The bug report bundle is at %s. Please attach it to an issue at https://github.com/golang/go/issues/new.", f.Name()),
+	})
+}
+
+// WriteBugReport writes a zip file to w that holds the information on the
+// state of cache needed to investigate a bug: the version of gopls, the
+// internal bugs reported so far, the settings of the views of its sessions,
+// their statistics, and the stacks of the goroutines of the process.
+//
+// The bundle does not contain any source code or environment variable, and
+// the directories of the views and the home directory of the user are
+// replaced by placeholders.
+func WriteBugReport(w io.Writer, cache source.Cache) error {
+	var replacements []string
+	var settings bytes.Buffer
+	for _, session := range cache.Sessions() {
+		for _, view := range session.Views() {
+			folder := view.Folder().Filename()
+			placeholder := fmt.Sprintf("$FOLDER%d", len(replacements)/2+1)
+			replacements = append(replacements, folder, placeholder)
+			fmt.Fprintf(&settings, "session %s, view %s:\n", session.ID(), placeholder)
+			writeSettings(&settings, view.Options())
+		}
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		replacements = append(replacements, home, "$HOME")
+	}
+	redact := strings.NewReplacer(replacements...)
+
+	var version bytes.Buffer
+	debug.PrintVersionInfo(&version, true, debug.PlainText)
+	bugs, err := json.MarshalIndent(bug.List(), "", "\t")
+	if err != nil {
+		return err
+	}
+	stats, err := json.MarshalIndent(source.Stats(cache), "", "\t")
+	if err != nil {
+		return err
+	}
+	var goroutines bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&goroutines, 2); err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	for _, file := range []struct {
+		name    string
+		content []byte
+	}{
+		{"version.txt", version.Bytes()},
+		{"bugs.json", bugs},
+		{"settings.txt", settings.Bytes()},
+		{"stats.json", stats},
+		{"goroutines.txt", goroutines.Bytes()},
+	} {
+		fw, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := redact.WriteString(fw, string(file.content)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeSettings writes the options of a view to w, one per line, omitting
+// the environment and the options that are functions.
+func writeSettings(w io.Writer, options source.Options) {
+	v := reflect.ValueOf(options)
+	var lines []string
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Name == "Env" || field.PkgPath != "" {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.Func, reflect.Chan, reflect.Interface, reflect.Ptr:
+			continue
+		}
+		if field.Type.Kind() == reflect.Map {
+			switch field.Type.Elem().Kind() {
+			case reflect.Func, reflect.Ptr:
+				continue
+			}
+		}
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() != reflect.String {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("\t%s: %v\n", field.Name, v.Field(i).Interface()))
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		io.WriteString(w, line)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/bug"
+	"golang.org/x/tools/internal/lsp/cache"
+)
+
+func TestWriteBugReport(t *testing.T) {
+	bug.Reportf("bug report test")
+
+	var buf bytes.Buffer
+	if err := WriteBugReport(&buf, cache.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(content)
+	}
+	for _, name := range []string{"version.txt", "bugs.json", "settings.txt", "stats.json", "goroutines.txt"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("the bundle has no %s", name)
+		}
+	}
+	if !strings.Contains(files["bugs.json"], "bug report test") {
+		t.Errorf("bugs.json does not contain the reported bug:\n%s", files["bugs.json"])
+	}
+	if !strings.Contains(files["goroutines.txt"], "TestWriteBugReport") {
+		t.Errorf("goroutines.txt does not contain the stack of the test")
+	}
+}
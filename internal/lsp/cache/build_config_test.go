@@ -0,0 +1,64 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+func TestSetBuildConfiguration(t *testing.T) {
+	view, shutdown := newTestView(t, os.TempDir(), nil)
+	defer shutdown()
+	ctx := context.Background()
+
+	uri := span.FileURI(filepath.Join(os.TempDir(), "a.go"))
+	s := view.getSnapshot()
+	s.setMetadata(&metadata{id: "a", pkgPath: "a"})
+	s.addID(uri, "a")
+
+	view.SetBuildConfiguration(ctx, source.BuildConfiguration{GOOS: "windows", GOARCH: "arm64", Tags: []string{"integration"}})
+	if s := view.getSnapshot(); s.getMetadata("a") != nil || len(s.getIDs(uri)) != 0 {
+		t.Errorf("the metadata of a survived the change of build configuration")
+	}
+
+	cfg := view.Config(ctx)
+	env := cfg.Env[len(cfg.Env)-2:]
+	if env[0] != "GOOS=windows" || env[1] != "GOARCH=arm64" {
+		t.Errorf("got environment %q, want it to end with GOOS=windows GOARCH=arm64", cfg.Env)
+	}
+	if flags := cfg.BuildFlags; len(flags) == 0 || flags[len(flags)-1] != "-tags=integration" {
+		t.Errorf("got build flags %q, want them to end with -tags=integration", flags)
+	}
+}
+
+func TestSetBuildConfigurationConcurrently(t *testing.T) {
+	view, shutdown := newTestView(t, os.TempDir(), nil)
+	defer shutdown()
+	ctx := context.Background()
+
+	// The options are read while the build configuration changes, which
+	// the race detector checks.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, goos := range []string{"linux", "windows", "darwin"} {
+			view.SetBuildConfiguration(ctx, source.BuildConfiguration{GOOS: goos})
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		view.Options()
+		view.Config(ctx)
+	}
+	<-done
+	if got := view.Options().BuildConfiguration.GOOS; got != "darwin" {
+		t.Errorf("got GOOS %q, want darwin", got)
+	}
+}
@@ -41,7 +41,7 @@ func (v *view) buildBuiltinPackage(ctx context.Context) error {
 	files := make(map[string]*ast.File)
 	for _, filename := range pkg.GoFiles {
 		fh := v.session.GetFile(span.FileURI(filename), source.Go)
-		ph := v.session.cache.ParseGoHandle(fh, source.ParseFull)
+		ph := v.session.ParseGoHandle(fh, source.ParseFull)
 		v.builtin.files = append(v.builtin.files, ph)
 		file, _, _, err := ph.Parse(ctx)
 		if err != nil {
@@ -10,8 +10,10 @@ import (
 	"fmt"
 	"go/token"
 	"strconv"
+	"sync"
 	"sync/atomic"
 
+	"golang.org/x/tools/internal/filecache"
 	"golang.org/x/tools/internal/lsp/debug"
 	"golang.org/x/tools/internal/lsp/source"
 	"golang.org/x/tools/internal/memoize"
@@ -21,10 +23,11 @@ import (
 func New(options func(*source.Options)) source.Cache {
 	index := atomic.AddInt64(&cacheIndex, 1)
 	c := &cache{
-		fs:      &nativeFileSystem{},
-		id:      strconv.FormatInt(index, 10),
-		fset:    token.NewFileSet(),
-		options: options,
+		fs:         &nativeFileSystem{},
+		id:         strconv.FormatInt(index, 10),
+		fset:       token.NewFileSet(),
+		options:    options,
+		parseCache: newParseCache(source.DefaultOptions.ParseCacheSize),
 	}
 	debug.AddCache(debugCache{c})
 	return c
@@ -37,6 +40,22 @@ type cache struct {
 	options func(*source.Options)
 
 	store memoize.Store
+
+	sessionMu sync.Mutex
+	sessions  []*session
+
+	// watchingMemory reports whether the memory watchdog is running.
+	watchingMemory int32
+
+	parseCache *parseCache
+
+	// fileCache is the cache on disk of the indexes of Go files, or nil if
+	// it is disabled.
+	fileCacheMu sync.Mutex
+	fileCache   *filecache.Cache
+
+	modulesMu sync.Mutex
+	modules   map[string]*source.ModuleMetadata
 }
 
 type fileKey struct {
@@ -82,6 +101,7 @@ func (c *cache) NewSession(ctx context.Context) source.Session {
 		overlays:      make(map[span.URI]*overlay),
 		filesWatchMap: NewWatchMap(),
 	}
+	c.addSession(s)
 	debug.AddSession(debugSession{s})
 	return s
 }
@@ -90,6 +110,21 @@ func (c *cache) FileSet() *token.FileSet {
 	return c.fset
 }
 
+func (c *cache) ModuleMetadata(dir string, read func() *source.ModuleMetadata) *source.ModuleMetadata {
+	c.modulesMu.Lock()
+	defer c.modulesMu.Unlock()
+
+	m, ok := c.modules[dir]
+	if !ok {
+		if c.modules == nil {
+			c.modules = make(map[string]*source.ModuleMetadata)
+		}
+		m = read()
+		c.modules[dir] = m
+	}
+	return m
+}
+
 func (h *fileHandle) FileSystem() source.FileSystem {
 	return h.cache
 }
@@ -0,0 +1,107 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestBuildCallGraph(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	const src = `package a
+
+type Shape interface{ Area() int }
+
+type Square struct{ s int }
+
+func (q Square) Area() int { return q.s * q.s }
+
+type Circle struct{ r int }
+
+func (c *Circle) Area() int { return 3 * c.r * c.r }
+
+func Total(shapes []Shape) int {
+	t := 0
+	for _, s := range shapes {
+		t += s.Area()
+	}
+	return t
+}
+
+func Squares() int {
+	return Total([]Shape{Square{1}})
+}
+
+func Circles() int {
+	return Total([]Shape{&Circle{1}})
+}
+`
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"a.go":   src,
+		"go.mod": "module example.com/a\n",
+	}, nil)
+	defer cleanup()
+	filename := filepath.Join(dir, "a.go")
+	ctx := context.Background()
+	f, err := view.GetFile(ctx, span.FileURI(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		function  string
+		algorithm source.CallGraphAlgorithm
+		want      []string
+	}{
+		{"", source.CHA, []string{
+			"Circles -> Total",
+			"Squares -> Total",
+			"Total -> (*Circle).Area dynamic",
+			"Total -> (Square).Area dynamic",
+		}},
+		{"Squares", source.CHA, []string{
+			"Squares -> Total",
+			"Total -> (*Circle).Area dynamic",
+			"Total -> (Square).Area dynamic",
+		}},
+		{"Squares", source.RTA, []string{
+			"Squares -> Total",
+			"Total -> (Square).Area dynamic",
+		}},
+	} {
+		graph, err := source.BuildCallGraph(ctx, view, f, test.function, test.algorithm)
+		if err != nil {
+			t.Fatalf("%s %s: %v", test.function, test.algorithm, err)
+		}
+		var got []string
+		for _, e := range graph.Edges {
+			s := fmt.Sprintf("%s -> %s", graph.Nodes[e.Caller].Name, graph.Nodes[e.Callee].Name)
+			if e.Dynamic {
+				s += " dynamic"
+			}
+			if e.Site == nil {
+				t.Errorf("%s %s: no site for %s", test.function, test.algorithm, s)
+			}
+			got = append(got, s)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s %s: got edges %q, want %q", test.function, test.algorithm, got, test.want)
+		}
+	}
+	if _, err := source.BuildCallGraph(ctx, view, f, "Missing", source.CHA); err == nil {
+		t.Errorf("got a call graph of a missing function")
+	}
+}
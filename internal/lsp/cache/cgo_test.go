@@ -0,0 +1,85 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"go/build"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestCgoPositions(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+	if !build.Default.CgoEnabled {
+		t.Skip("cgo is not enabled")
+	}
+
+	const src = `package m
+
+// int add(int a, int b) { return a + b; }
+import "C"
+
+func Add(x, y int) int {
+	return int(C.add(C.int(x), C.int(y)))
+}
+
+func use() int { return Add(1, 2) + undefined }
+`
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"x.go":   src,
+	}, nil)
+	defer cleanup()
+	ctx := context.Background()
+	uri := span.FileURI(filepath.Join(dir, "x.go"))
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The type error is reported in x.go, and not in the file that cgo
+	// generated from it.
+	reports, _, err := source.Diagnostics(ctx, view, f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := protocol.Range{
+		Start: protocol.Position{Line: 9, Character: 36},
+		End:   protocol.Position{Line: 9, Character: 45},
+	}
+	if diags := reports[uri]; len(diags) != 1 || diags[0].Range != want {
+		t.Errorf("got diagnostics %v for x.go, want one at %v", diags, want)
+	}
+
+	// The references to Add are found from x.go, and reported in x.go.
+	ident, err := source.Identifier(ctx, view, f, protocol.Position{Line: 9, Character: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := ident.References(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []protocol.Position
+	for _, ref := range refs {
+		rng, err := ref.Range()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ref.URI() != uri {
+			t.Errorf("got reference in %s, want %s", ref.URI(), uri)
+		}
+		got = append(got, rng.Start)
+	}
+	if len(got) != 2 || got[0] != (protocol.Position{Line: 5, Character: 5}) || got[1] != (protocol.Position{Line: 9, Character: 24}) {
+		t.Errorf("got references at %v, want 5:5 and 9:24", got)
+	}
+}
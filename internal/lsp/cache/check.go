@@ -10,10 +10,13 @@ import (
 	"fmt"
 	"go/ast"
 	"go/types"
+	"runtime"
 	"sort"
 	"sync"
+	"time"
 
 	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/source"
 	"golang.org/x/tools/internal/lsp/telemetry"
 	"golang.org/x/tools/internal/memoize"
@@ -38,10 +41,18 @@ type importer struct {
 
 	// parentCheckPackageHandle is the check package handle that imports the current package.
 	parentCheckPackageHandle *checkPackageHandle
+
+	// importTime is the time spent waiting for the imports of parentPkg.
+	importTime time.Duration
 }
 
 // checkPackageHandle implements source.CheckPackageHandle.
 type checkPackageHandle struct {
+	// lastUsed is the time, in nanoseconds since the Unix epoch, at which
+	// the handle was last retrieved from a snapshot. It is accessed
+	// atomically, so it must stay the first field for alignment.
+	lastUsed int64
+
 	handle *memoize.Handle
 
 	// files are the ParseGoHandles that compose the package.
@@ -75,85 +86,165 @@ type checkPackageData struct {
 	err error
 }
 
+// Limits the number of CheckPackageHandles whose files are hashed in parallel.
+var handleLimit = newScheduler(runtime.GOMAXPROCS(0))
+
+// handleNode is a package whose CheckPackageHandle is built by a call to
+// checkPackageHandle. The handle is available once done is closed.
+type handleNode struct {
+	id   packageID
+	mode source.ParseMode
+	m    *metadata
+
+	// depIDs are the IDs of the package's dependencies, sorted, and deps
+	// their nodes. The node of a dependency that would close an import
+	// cycle is nil.
+	depIDs []packageID
+	deps   []*handleNode
+
+	// visiting is set while the dependencies of the node are being added.
+	visiting bool
+
+	done chan struct{}
+	cph  *checkPackageHandle
+	err  error
+}
+
 // checkPackageHandle returns a source.CheckPackageHandle for a given package and config.
+//
+// The handles of the package's dependencies, which its own handle's key is
+// computed from, are built first. The import graph is walked to find the
+// packages whose handles are not cached in the snapshot, and then their
+// handles are built concurrently, each waiting only for the handles of its
+// own dependencies. A dependency shared by several packages is built once.
 func (imp *importer) checkPackageHandle(ctx context.Context, id packageID) (*checkPackageHandle, error) {
-	// Determine the mode that the files should be parsed in.
-	mode := imp.mode(id)
+	nodes := make(map[packageID]*handleNode)
+	root := imp.addHandleNode(id, nodes)
+	for _, n := range nodes {
+		if n.cph == nil && n.err == nil {
+			go imp.buildHandle(ctx, n)
+		}
+	}
+	<-root.done
+	return root.cph, root.err
+}
 
-	// Check if we already have this CheckPackageHandle cached.
-	if cph := imp.snapshot.getPackage(id, mode); cph != nil {
-		return cph, nil
+// addHandleNode adds the node of the package with the given ID, and of its
+// dependencies whose handles are not cached, to nodes.
+func (imp *importer) addHandleNode(id packageID, nodes map[packageID]*handleNode) *handleNode {
+	if n, ok := nodes[id]; ok {
+		if n.visiting {
+			return nil
+		}
+		return n
+	}
+	n := &handleNode{
+		id:   id,
+		mode: imp.mode(id),
+		done: make(chan struct{}),
 	}
+	nodes[id] = n
 
-	// Build the CheckPackageHandle for this ID and its dependencies.
-	cph, err := imp.buildKey(ctx, id, mode)
-	if err != nil {
-		return nil, err
+	// Check if we already have this CheckPackageHandle cached.
+	if n.cph = imp.snapshot.getPackage(id, n.mode); n.cph != nil {
+		close(n.done)
+		return n
+	}
+	if n.m = imp.snapshot.getMetadata(id); n.m == nil {
+		n.err = errors.Errorf("no metadata for %s", id)
+		close(n.done)
+		return n
 	}
 
-	h := imp.snapshot.view.session.cache.store.Bind(string(cph.key), func(ctx context.Context) interface{} {
-		data := &checkPackageData{}
-		data.pkg, data.err = imp.typeCheck(ctx, cph)
-		return data
+	// Make sure all of the deps are sorted.
+	n.depIDs = append([]packageID{}, n.m.deps...)
+	sort.Slice(n.depIDs, func(i, j int) bool {
+		return n.depIDs[i] < n.depIDs[j]
 	})
-	cph.handle = h
-
-	// Cache the CheckPackageHandle in the snapshot.
-	imp.snapshot.addPackage(cph)
-
-	return cph, nil
+	n.visiting = true
+	for _, dep := range n.depIDs {
+		n.deps = append(n.deps, imp.addHandleNode(dep, nodes))
+	}
+	n.visiting = false
+	return n
 }
 
-// buildKey computes the checkPackageKey for a given checkPackageHandle.
-func (imp *importer) buildKey(ctx context.Context, id packageID, mode source.ParseMode) (*checkPackageHandle, error) {
-	m := imp.snapshot.getMetadata(id)
-	if m == nil {
-		return nil, errors.Errorf("no metadata for %s", id)
+// buildHandle builds the CheckPackageHandle of n, once the handles of its
+// dependencies are built.
+func (imp *importer) buildHandle(ctx context.Context, n *handleNode) {
+	defer close(n.done)
+
+	// Hash the files of the package while its dependencies are built.
+	release, err := handleLimit.acquire(ctx)
+	if err != nil {
+		n.err = err
+		return
 	}
-	phs, err := imp.parseGoHandles(ctx, m, mode)
+	phs, err := imp.parseGoHandles(ctx, n.m, n.mode)
+	var filesKey string
+	if err == nil {
+		// Packages parsed in full are keyed on the content of their files.
+		// The packages they depend on are parsed in exported mode, so they
+		// are keyed on their exported syntax instead. As a dependency's key
+		// then depends only on the exported syntax of it and its own
+		// dependencies, an edit that does not change it leaves the keys of
+		// the packages that import it unchanged, and they need not be
+		// type-checked again. The package then reused for the dependency
+		// holds the syntax it was checked from, in which the exported
+		// declarations are at the same positions.
+		filesKey = hashParseKeys(phs)
+		if n.mode == source.ParseExported {
+			filesKey, err = hashExportedSyntax(ctx, imp.snapshot.view.session.cache.FileSet(), phs)
+		}
+	}
+	release()
 	if err != nil {
-		return nil, err
+		n.err = err
+		return
 	}
 	cph := &checkPackageHandle{
-		m:       m,
+		m:       n.m,
 		files:   phs,
 		imports: make(map[packagePath]packageID),
-		mode:    mode,
+		mode:    n.mode,
 	}
 
-	// Make sure all of the deps are sorted.
-	deps := append([]packageID{}, m.deps...)
-	sort.Slice(deps, func(i, j int) bool {
-		return deps[i] < deps[j]
-	})
-
-	// Create the dep importer for use on the dependency handles.
-	depImporter := &importer{
-		snapshot:          imp.snapshot,
-		topLevelPackageID: imp.topLevelPackageID,
-	}
-	// Begin computing the key by getting the depKeys for all dependencies.
+	// Compute the key from the keys of all of the dependencies.
 	var depKeys [][]byte
-	for _, dep := range deps {
-		depHandle, err := depImporter.checkPackageHandle(ctx, dep)
+	for i, dep := range n.deps {
+		var err error
+		if dep != nil {
+			<-dep.done
+			err = dep.err
+		} else {
+			err = errors.Errorf("import cycle through %s", n.depIDs[i])
+		}
 		if err != nil {
-			log.Error(ctx, "no dep handle", err, telemetry.Package.Of(dep))
+			log.Error(ctx, "no dep handle", err, telemetry.Package.Of(n.depIDs[i]))
 
 			// One bad dependency should not prevent us from checking the entire package.
 			// Add a special key to mark a bad dependency.
-			depKeys = append(depKeys, []byte(fmt.Sprintf("%s import not found", id)))
+			depKeys = append(depKeys, []byte(fmt.Sprintf("%s import not found", n.id)))
 			continue
 		}
-		cph.imports[depHandle.m.pkgPath] = depHandle.m.id
-		depKeys = append(depKeys, depHandle.key)
+		cph.imports[dep.cph.m.pkgPath] = dep.cph.m.id
+		depKeys = append(depKeys, dep.cph.key)
 	}
-	cph.key = checkPackageKey(cph.m.id, cph.files, m.config, depKeys)
+	cph.key = checkPackageKey(cph.m.id, filesKey, n.m.config, depKeys)
+
+	cph.handle = imp.snapshot.view.session.cache.store.Bind(string(cph.key), func(ctx context.Context) interface{} {
+		data := &checkPackageData{}
+		data.pkg, data.err = imp.typeCheck(ctx, cph)
+		return data
+	})
 
-	return cph, nil
+	// Cache the CheckPackageHandle in the snapshot.
+	imp.snapshot.addPackage(cph)
+	n.cph = cph
 }
 
-func checkPackageKey(id packageID, phs []source.ParseGoHandle, cfg *packages.Config, deps [][]byte) []byte {
-	return []byte(hashContents([]byte(fmt.Sprintf("%s%s%s%s", id, hashParseKeys(phs), hashConfig(cfg), hashContents(bytes.Join(deps, nil))))))
+func checkPackageKey(id packageID, filesKey string, cfg *packages.Config, deps [][]byte) []byte {
+	return []byte(hashContents([]byte(fmt.Sprintf("%s%s%s%s", id, filesKey, hashConfig(cfg), hashContents(bytes.Join(deps, nil))))))
 }
 
 // hashConfig returns the hash for the *packages.Config.
@@ -223,7 +314,7 @@ func (imp *importer) parseGoHandles(ctx context.Context, m *metadata, mode sourc
 			return nil, err
 		}
 		fh := imp.snapshot.Handle(ctx, f)
-		phs = append(phs, imp.snapshot.view.session.cache.ParseGoHandle(fh, mode))
+		phs = append(phs, imp.snapshot.view.session.ParseGoHandle(fh, mode))
 	}
 	return phs, nil
 }
@@ -252,7 +343,9 @@ func (imp *importer) Import(pkgPath string) (*types.Package, error) {
 	if cph == nil {
 		return nil, errors.Errorf("no cached package for %s", id)
 	}
+	start := time.Now()
 	pkg, err := cph.check(ctx)
+	imp.importTime += time.Since(start)
 	if err != nil {
 		return nil, err
 	}
@@ -261,9 +354,15 @@ func (imp *importer) Import(pkgPath string) (*types.Package, error) {
 }
 
 func (imp *importer) typeCheck(ctx context.Context, cph *checkPackageHandle) (*pkg, error) {
-	ctx, done := trace.StartSpan(ctx, "cache.importer.typeCheck", telemetry.Package.Of(cph.m.id))
+	ctx, done := trace.StartSpan(ctx, "cache.importer.typeCheck",
+		telemetry.Package.Of(cph.m.id),
+		telemetry.ParseMode.Of(parseModeString(cph.mode)),
+		telemetry.FileCount.Of(len(cph.files)),
+	)
 	defer done()
 
+	start := time.Now()
+
 	var rawErrors []error
 	for _, err := range cph.m.errors {
 		rawErrors = append(rawErrors, err)
@@ -288,6 +387,7 @@ func (imp *importer) typeCheck(ctx context.Context, cph *checkPackageHandle) (*p
 	}
 	var (
 		files       = make([]*ast.File, len(pkg.files))
+		mappers     = make([]*protocol.ColumnMapper, len(pkg.files))
 		parseErrors = make([]error, len(pkg.files))
 		wg          sync.WaitGroup
 	)
@@ -296,16 +396,25 @@ func (imp *importer) typeCheck(ctx context.Context, cph *checkPackageHandle) (*p
 		go func(i int, ph source.ParseGoHandle) {
 			defer wg.Done()
 
-			files[i], _, parseErrors[i], _ = ph.Parse(ctx)
+			files[i], mappers[i], parseErrors[i], _ = ph.Parse(ctx)
 		}(i, ph)
 	}
 	wg.Wait()
+	for _, m := range mappers {
+		if m != nil {
+			pkg.sourceSize += int64(len(m.Content))
+		}
+	}
 
 	for _, e := range parseErrors {
 		if e != nil {
 			rawErrors = append(rawErrors, e)
 		}
 	}
+	var stream *errorStream
+	if cph.mode == source.ParseFull && source.StreamingDiagnostics(ctx) {
+		stream = newErrorStream(ctx, pkg, parseErrors)
+	}
 
 	var i int
 	for _, f := range files {
@@ -325,17 +434,25 @@ func (imp *importer) typeCheck(ctx context.Context, cph *checkPackageHandle) (*p
 		pkg.types = types.NewPackage(string(cph.m.pkgPath), cph.m.name)
 	}
 
+	depImporter := imp.depImporter(ctx, cph, pkg)
 	cfg := &types.Config{
 		Error: func(e error) {
 			rawErrors = append(rawErrors, e)
+			if stream != nil {
+				stream.typeError(e)
+			}
 		},
-		Importer: imp.depImporter(ctx, cph, pkg),
+		Importer: depImporter,
 	}
 	check := types.NewChecker(cfg, imp.snapshot.view.session.cache.FileSet(), pkg.types, pkg.typesInfo)
 
 	// Type checking errors are handled via the config, so ignore them here.
 	_ = check.Files(files)
 
+	// Dependencies are type-checked on demand by the importer, so leave
+	// out the time spent on them, which is recorded separately.
+	imp.snapshot.view.stats.recordTypeCheck(time.Since(start) - depImporter.importTime)
+
 	// We don't care about a package's errors unless we have parsed it in full.
 	if cph.mode == source.ParseFull {
 		for _, e := range rawErrors {
@@ -0,0 +1,141 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestDependencyKeyPruning(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"go.mod": "module example.com/a\n",
+		"a.go":   "package a\n\nimport \"example.com/a/b\"\n\nvar A = b.B()\n",
+		"b/b.go": "package b\n\nfunc B() int { return 1 }\n",
+	}, nil)
+	defer cleanup()
+	ctx := context.Background()
+	aURI := span.FileURI(filepath.Join(dir, "a.go"))
+	bURI := span.FileURI(filepath.Join(dir, "b", "b.go"))
+
+	// key returns the key of the package of a.go, after checking it.
+	key := func() []byte {
+		f, err := view.GetFile(ctx, aURI)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, cphs, err := view.CheckPackageHandles(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cphs) != 1 {
+			t.Fatalf("got %d packages for a.go, want 1", len(cphs))
+		}
+		if _, err := cphs[0].Check(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return cphs[0].(*checkPackageHandle).key
+	}
+	before := key()
+
+	// Changing the body of B leaves the exported syntax of b unchanged.
+	if _, err := view.SetContent(ctx, bURI, []byte("package b\n\nfunc B() int { return 2 }\n")); err != nil {
+		t.Fatal(err)
+	}
+	if after := key(); !bytes.Equal(before, after) {
+		t.Errorf("the key of a changed after a change to the body of a function of b")
+	}
+
+	// Changing the signature of B changes it.
+	if _, err := view.SetContent(ctx, bURI, []byte("package b\n\nfunc B() string { return \"\" }\n")); err != nil {
+		t.Fatal(err)
+	}
+	if after := key(); bytes.Equal(before, after) {
+		t.Errorf("the key of a is unchanged after a change to the signature of a function of b")
+	}
+}
+
+func TestCheckPackageHandleGraph(t *testing.T) {
+	view, shutdown := newTestView(t, os.TempDir(), nil)
+	defer shutdown()
+	ctx := context.Background()
+	s := view.getSnapshot()
+
+	// a imports b and c, which both import d, and d imports a back.
+	cfg := &packages.Config{}
+	for id, deps := range map[packageID][]packageID{
+		"a": {"b", "c"},
+		"b": {"d"},
+		"c": {"d"},
+		"d": {"a"},
+	} {
+		s.setMetadata(&metadata{id: id, pkgPath: packagePath(id), deps: deps, config: cfg})
+	}
+	imp := &importer{snapshot: s, topLevelPackageID: "a"}
+	cph, err := imp.checkPackageHandle(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(cph.imports); got != 2 {
+		t.Errorf("got %d imports of a, want 2", got)
+	}
+	for _, id := range []packageID{"b", "c", "d"} {
+		if s.getPackage(id, source.ParseExported) == nil {
+			t.Errorf("no handle for %s", id)
+		}
+	}
+	if s.getPackage("a", source.ParseFull) != cph {
+		t.Errorf("the handle of a is not cached")
+	}
+}
+
+func TestPartialDiagnostics(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"go.mod": "module example.com/a\n",
+		"a.go":   "package a\n\nvar _ int = \"a\"\n",
+		"b.go":   "package a\n\nvar _ int = \"b\"\n",
+		"c.go":   "package a\n\nvar _ int = \n",
+	}, nil)
+	defer cleanup()
+	ctx := context.Background()
+
+	published := make(map[span.URI][]source.Diagnostic)
+	ctx = source.WithPartialDiagnostics(ctx, func(uri span.URI, diagnostics []source.Diagnostic) {
+		published[uri] = diagnostics
+	})
+	f, err := view.GetFile(ctx, span.FileURI(filepath.Join(dir, "a.go")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, cphs, err := view.CheckPackageHandles(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cphs[0].Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The parse error of c.go is published once it is parsed, and the type
+	// error of a.go once the type checker moves on to b.go. The type errors
+	// of b.go, the last file checked, are left to the final diagnostics.
+	for name, src := range map[string]string{"a.go": "compiler", "c.go": "syntax"} {
+		diags := published[span.FileURI(filepath.Join(dir, name))]
+		if len(diags) != 1 || diags[0].Source != src {
+			t.Errorf("got %v for %s, want a single %s diagnostic", diags, name, src)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// fileDecls holds the declarations of a version of a file of a dependency.
+type fileDecls struct {
+	identity source.FileIdentity
+	symbols  []source.Symbol
+}
+
+// ImportedDeclarations returns the package imported with the given path by
+// the package of the file uri, as the metadata of the snapshot knows it,
+// without type-checking either of them. It returns nil if the metadata of
+// the file has not been loaded, or if the package does not import the path.
+//
+// The declarations of a file are indexed from the syntax that the type
+// checker uses for dependencies, and are kept by the view until the file
+// changes, which the files of the module cache never do.
+func (s *snapshot) ImportedDeclarations(ctx context.Context, uri span.URI, importPath string) (*source.PackageDeclarations, error) {
+	ctx, done := trace.StartSpan(ctx, "cache.snapshot.ImportedDeclarations", telemetry.URI.Of(uri))
+	defer done()
+
+	var dep *metadata
+	for _, m := range s.getMetadataForURI(uri) {
+		for _, id := range m.deps {
+			d := s.getMetadata(id)
+			if d == nil {
+				continue
+			}
+			if p := string(d.pkgPath); p == importPath || strings.HasSuffix(p, "/vendor/"+importPath) {
+				dep = d
+			}
+		}
+	}
+	if dep == nil {
+		return nil, nil
+	}
+	result := &source.PackageDeclarations{
+		Name:  dep.name,
+		Decls: make(map[string]protocol.Location),
+	}
+	for _, fileURI := range dep.files {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		decls, err := s.view.fileDecls(ctx, s.view.session.GetFile(fileURI, source.Go))
+		if err != nil {
+			return nil, err
+		}
+		for _, sym := range decls.symbols {
+			name := sym.Name
+			if sym.Container != dep.name {
+				name = strings.TrimPrefix(sym.Container, dep.name+".") + "." + sym.Name
+			}
+			result.Decls[name] = sym.Location
+		}
+	}
+	return result, nil
+}
+
+// fileDecls returns the declarations of the file fh, computing them if the
+// view has none for its version.
+func (v *view) fileDecls(ctx context.Context, fh source.FileHandle) (*fileDecls, error) {
+	uri := fh.Identity().URI
+	v.declsMu.Lock()
+	decls, ok := v.decls[uri]
+	v.declsMu.Unlock()
+	if ok && decls.identity == fh.Identity() {
+		return decls, nil
+	}
+
+	file, m, _, err := v.session.ParseGoHandle(fh, source.ParseExported).Parse(ctx)
+	if file == nil {
+		return nil, err
+	}
+	decls = &fileDecls{
+		identity: fh.Identity(),
+		symbols:  fileSymbols(v.session.cache.FileSet(), file, m),
+	}
+	v.declsMu.Lock()
+	if v.decls == nil {
+		v.decls = make(map[span.URI]*fileDecls)
+	}
+	v.decls[uri] = decls
+	v.declsMu.Unlock()
+	return decls, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestImportedDeclarations(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"a/a.go": `package a
+
+import (
+	"example.com/m/b"
+	other "example.com/m/c"
+)
+
+var _ = b.Hello() + other.Name + b.Buffer{}.Text
+
+func f(b *b.Buffer) string { return b.Text }
+`,
+		"b/b.go": `package b
+
+func Hello() string { return "hello" }
+`,
+		"b/buffer.go": `package b
+
+type Buffer struct {
+	Text string
+}
+`,
+		"c/c.go": `package c
+
+const Name = "c"
+`,
+	}, nil)
+	defer cleanup()
+	ctx := context.Background()
+
+	uri := span.FileURI(filepath.Join(dir, "a", "a.go"))
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Load the metadata of the file, as opening it would.
+	if _, _, err := view.CheckPackageHandles(ctx, f); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		line, column float64
+		file         string // of the definition, if any
+		defLine      float64
+	}{
+		{7, 11, "b/b.go", 2},      // b.Hello
+		{7, 29, "c/c.go", 2},      // other.Name
+		{7, 35, "b/buffer.go", 2}, // b.Buffer
+		{7, 44, "", 0},            // the field Text of a value
+		{9, 12, "b/buffer.go", 2}, // b.Buffer
+		{9, 39, "", 0},            // the parameter b shadows the import
+	} {
+		got, err := source.IndexedDefinition(ctx, view, f, protocol.Position{Line: test.line, Character: test.column})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.file == "" {
+			if got != nil {
+				t.Errorf("%v:%v: got %v, want none", test.line, test.column, got)
+			}
+			continue
+		}
+		want := protocol.NewURI(span.FileURI(filepath.Join(dir, filepath.FromSlash(test.file))))
+		if len(got) != 1 || got[0].URI != want || got[0].Range.Start.Line != test.defLine {
+			t.Errorf("%v:%v: got %v, want %s:%v", test.line, test.column, got, test.file, test.defLine)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestDynamicTypes(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	const src = `package a
+
+type Shape interface{ Area() int }
+
+type Square struct{ s int }
+
+func (q Square) Area() int { return q.s * q.s }
+
+type Circle struct{ r int }
+
+func (c *Circle) Area() int { return 3 * c.r * c.r }
+
+type Triangle struct{ b, h int }
+
+func (t Triangle) Area() int { return t.b * t.h / 2 }
+
+func Total(shapes ...Shape) int {
+	t := 0
+	for _, s := range shapes {
+		t += s.Area()
+	}
+	return t
+}
+
+func Sum() int {
+	return Total(Square{1}, &Circle{1}) + Triangle{}.Area()
+}
+`
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"a.go":   src,
+		"go.mod": "module example.com/a\n",
+	}, nil)
+	defer cleanup()
+	filename := filepath.Join(dir, "a.go")
+	ctx := context.Background()
+	f, err := view.GetFile(ctx, span.FileURI(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The s of "for _, s := range shapes".
+	locs, err := source.DynamicTypes(ctx, view, f, protocol.Position{Line: 18, Character: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []float64
+	for _, loc := range locs {
+		lines = append(lines, loc.Range.Start.Line)
+	}
+	// Circle and Square, but not Triangle, which is not converted to an
+	// interface.
+	if len(lines) != 2 || lines[0] != 8 || lines[1] != 4 {
+		t.Errorf("got dynamic types at lines %v, want 8 and 4", lines)
+	}
+
+	// The t of "t := 0" is not of an interface type.
+	if _, err := source.DynamicTypes(ctx, view, f, protocol.Position{Line: 17, Character: 1}); err == nil {
+		t.Errorf("got dynamic types of an int variable")
+	}
+}
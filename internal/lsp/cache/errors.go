@@ -67,7 +67,7 @@ func sourceError(ctx context.Context, pkg *pkg, e interface{}) (*source.Error, e
 		}
 
 	case *analysis.Diagnostic:
-		spn, err = span.NewRange(fset, e.Pos, e.End).Span()
+		spn, err = posSpan(fset, e.Pos, e.End)
 		if err != nil {
 			return nil, err
 		}
@@ -103,7 +103,7 @@ func suggestedFixes(ctx context.Context, fset *token.FileSet, pkg *pkg, diag *an
 	for _, fix := range diag.SuggestedFixes {
 		edits := make(map[span.URI][]protocol.TextEdit)
 		for _, e := range fix.TextEdits {
-			spn, err := span.NewRange(fset, e.Pos, e.End).Span()
+			spn, err := posSpan(fset, e.Pos, e.End)
 			if err != nil {
 				return nil, err
 			}
@@ -127,7 +127,7 @@ func suggestedFixes(ctx context.Context, fset *token.FileSet, pkg *pkg, diag *an
 func relatedInformation(ctx context.Context, fset *token.FileSet, pkg *pkg, diag *analysis.Diagnostic) ([]source.RelatedInformation, error) {
 	var out []source.RelatedInformation
 	for _, related := range diag.Related {
-		spn, err := span.NewRange(fset, related.Pos, related.End).Span()
+		spn, err := posSpan(fset, related.Pos, related.End)
 		if err != nil {
 			return nil, err
 		}
@@ -158,11 +158,11 @@ func toSourceErrorKind(kind packages.ErrorKind) source.ErrorKind {
 }
 
 func typeErrorRange(ctx context.Context, fset *token.FileSet, pkg *pkg, pos token.Pos) (span.Span, error) {
-	spn, err := span.NewRange(fset, pos, pos).Span()
+	spn, err := posSpan(fset, pos, pos)
 	if err != nil {
 		return span.Span{}, err
 	}
-	posn := fset.Position(pos)
+	posn := fset.PositionFor(pos, false)
 	ph, _, err := pkg.FindFile(ctx, span.FileURI(posn.Filename))
 	if err != nil {
 		return spn, nil // ignore errors
@@ -173,10 +173,13 @@ func typeErrorRange(ctx context.Context, fset *token.FileSet, pkg *pkg, pos toke
 	}
 	path, _ := astutil.PathEnclosingInterval(file, pos, pos)
 	if len(path) > 0 {
-		if spn, err := span.NewRange(fset, path[0].Pos(), path[0].End()).Span(); err == nil {
+		if spn, err := posSpan(fset, path[0].Pos(), path[0].End()); err == nil {
 			return spn, nil
 		}
 	}
+	if spn.URI() != ph.File().Identity().URI {
+		return spn, nil // the position was mapped to the original of a cgo file
+	}
 	s, err := spn.WithOffset(m.Converter)
 	if err != nil {
 		return spn, nil // ignore errors
@@ -221,12 +224,26 @@ func scannerErrorRange(ctx context.Context, fset *token.FileSet, pkg *pkg, posn
 	return span.NewRange(fset, pos, pos).Span()
 }
 
+// posSpan returns the span of the positions pos and end, in the original file
+// if they are in a file generated by cgo.
+func posSpan(fset *token.FileSet, pos, end token.Pos) (span.Span, error) {
+	if spn, ok := source.CgoOriginalSpan(fset, pos, end); ok {
+		return spn, nil
+	}
+	return span.NewRange(fset, pos, end).Span()
+}
+
 // spanToRange converts a span.Span to a protocol.Range,
 // assuming that the span belongs to the package whose diagnostics are being computed.
 func spanToRange(ctx context.Context, pkg *pkg, spn span.Span) (protocol.Range, error) {
 	ph, _, err := pkg.FindFile(ctx, spn.URI())
 	if err != nil {
-		return protocol.Range{}, err
+		// The original files of the files generated by cgo are not parsed.
+		m, cgoErr := source.CgoOriginalMapper(ctx, pkg.view, spn.URI())
+		if cgoErr != nil {
+			return protocol.Range{}, err
+		}
+		return m.Range(spn)
 	}
 	_, m, _, err := ph.Cached()
 	if err != nil {
@@ -251,3 +268,59 @@ func parseGoListError(input string) span.Span {
 	}
 	return span.Parse(input[:msgIndex])
 }
+
+// errorStream reports the errors found in the files of a package while it is
+// type-checked, for source.WithPartialDiagnostics.
+type errorStream struct {
+	ctx context.Context
+	pkg *pkg
+
+	// parseErrors is the set of files with parse errors, whose type errors
+	// are not reported.
+	parseErrors map[span.URI]bool
+
+	// uri is the file of the last type error, and typeErrors the type errors
+	// found in each file so far.
+	uri        span.URI
+	typeErrors map[span.URI][]*source.Error
+}
+
+// newErrorStream reports the parse errors of the files of pkg, as returned
+// by parsing each of them, and returns a stream for its type errors.
+func newErrorStream(ctx context.Context, pkg *pkg, parseErrors []error) *errorStream {
+	s := &errorStream{
+		ctx:         ctx,
+		pkg:         pkg,
+		parseErrors: make(map[span.URI]bool),
+		typeErrors:  make(map[span.URI][]*source.Error),
+	}
+	for i, e := range parseErrors {
+		if e == nil {
+			continue
+		}
+		srcErr, err := sourceError(ctx, pkg, e)
+		if err != nil {
+			continue
+		}
+		uri := pkg.files[i].File().Identity().URI
+		s.parseErrors[uri] = true
+		source.ReportPartialErrors(ctx, uri, []*source.Error{srcErr})
+	}
+	return s
+}
+
+// typeError adds a type error to the stream. The type errors of a file are
+// reported when the type checker moves on to another file.
+func (s *errorStream) typeError(e error) {
+	srcErr, err := sourceError(s.ctx, s.pkg, e)
+	if err != nil || s.parseErrors[srcErr.URI] {
+		return
+	}
+	if srcErr.URI != s.uri {
+		if errs := s.typeErrors[s.uri]; len(errs) > 0 {
+			source.ReportPartialErrors(s.ctx, s.uri, errs)
+		}
+		s.uri = srcErr.URI
+	}
+	s.typeErrors[s.uri] = append(s.typeErrors[s.uri], srcErr)
+}
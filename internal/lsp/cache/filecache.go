@@ -0,0 +1,184 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"os"
+
+	"golang.org/x/tools/internal/filecache"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/telemetry/log"
+)
+
+// xrefsKind is the kind of the indexes of Go files in the file cache. It
+// changes whenever their encoding, or the way in which they are computed,
+// changes.
+const xrefsKind = "xrefs-v1"
+
+// remoteFileCacheTokenEnv is the environment variable that holds the token
+// sent to the remote file cache, which is kept out of the settings.
+const remoteFileCacheTokenEnv = "GOPLS_REMOTE_FILE_CACHE_TOKEN"
+
+// setFileCache enables or disables the cache on disk of the indexes of Go
+// files, sets whether it compresses them, and sets its remote cache, as set
+// by the options of a view. It is shared by all views, so the most recently
+// set values apply.
+func (c *cache) setFileCache(ctx context.Context, options source.Options) {
+	c.fileCacheMu.Lock()
+	defer c.fileCacheMu.Unlock()
+	if !options.FileCache {
+		c.fileCache = nil
+		return
+	}
+	if c.fileCache == nil {
+		dir, err := filecache.DefaultDir()
+		if err != nil {
+			log.Error(ctx, "cannot locate the file cache", err)
+			return
+		}
+		c.fileCache = filecache.New(dir)
+	}
+	c.fileCache.SetCompression(options.FileCacheCompression)
+	if options.RemoteFileCache == "" {
+		c.fileCache.SetRemote(nil)
+	} else {
+		token := os.Getenv(remoteFileCacheTokenEnv)
+		c.fileCache.SetRemote(filecache.NewRemote(options.RemoteFileCache, token, options.RemoteFileCacheUpload))
+	}
+}
+
+func (c *cache) getFileCache() *filecache.Cache {
+	c.fileCacheMu.Lock()
+	defer c.fileCacheMu.Unlock()
+	return c.fileCache
+}
+
+// xrefsData is the encoding of a fileXrefs in the file cache. The index of a
+// file depends only on its content, so its locations are stored without the
+// URI of the file, and the index is shared by the copies of the file, such as
+// those of two checkouts of a repository.
+type xrefsData struct {
+	Imports   []string
+	Refs      map[source.Xref]source.XrefUse
+	Linknames map[source.Xref][]protocol.Range
+	Symbols   []source.Symbol
+	Methods   *methodsData
+}
+
+type methodsData struct {
+	Pkg   string
+	Types []typeData
+	Recvs map[string][]string
+}
+
+type typeData struct {
+	Name     string
+	Iface    bool
+	Methods  []string
+	Embeds   []string
+	Invalid  bool
+	Location protocol.Range
+}
+
+// xrefsKey returns the key in the file cache of the index of the file whose
+// content has the given hash.
+func xrefsKey(hash string) [32]byte {
+	return sha256.Sum256([]byte(xrefsKind + " " + hash))
+}
+
+// readFileXrefs returns the index of the file fh stored under key in fc, or
+// nil if there is none.
+func readFileXrefs(ctx context.Context, fc *filecache.Cache, key [32]byte, fh source.FileHandle) *fileXrefs {
+	value, err := fc.Get(ctx, xrefsKind, key)
+	if err != nil {
+		return nil
+	}
+	var data xrefsData
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&data); err != nil {
+		return nil
+	}
+	uri := protocol.NewURI(fh.Identity().URI)
+	xrefs := &fileXrefs{
+		identity: fh.Identity(),
+		imports:  data.Imports,
+		refs:     data.Refs,
+		symbols:  data.Symbols,
+	}
+	if xrefs.refs == nil {
+		xrefs.refs = make(map[source.Xref]source.XrefUse)
+	}
+	for ref, ranges := range data.Linknames {
+		if xrefs.linknames == nil {
+			xrefs.linknames = make(map[source.Xref][]protocol.Location)
+		}
+		for _, rng := range ranges {
+			xrefs.linknames[ref] = append(xrefs.linknames[ref], protocol.Location{URI: uri, Range: rng})
+		}
+	}
+	for i := range xrefs.symbols {
+		xrefs.symbols[i].Location.URI = uri
+	}
+	if m := data.Methods; m != nil {
+		xrefs.methods = &fileMethods{pkg: m.Pkg, recvs: m.Recvs}
+		for _, t := range m.Types {
+			xrefs.methods.types = append(xrefs.methods.types, &typeMethods{
+				name:     t.Name,
+				iface:    t.Iface,
+				methods:  t.Methods,
+				embeds:   t.Embeds,
+				invalid:  t.Invalid,
+				location: protocol.Location{URI: uri, Range: t.Location},
+			})
+		}
+	}
+	return xrefs
+}
+
+// writeFileXrefs stores xrefs under key in fc.
+func writeFileXrefs(ctx context.Context, fc *filecache.Cache, key [32]byte, xrefs *fileXrefs) {
+	data := xrefsData{
+		Imports: xrefs.imports,
+		Refs:    xrefs.refs,
+	}
+	for ref, locations := range xrefs.linknames {
+		if data.Linknames == nil {
+			data.Linknames = make(map[source.Xref][]protocol.Range)
+		}
+		for _, location := range locations {
+			data.Linknames[ref] = append(data.Linknames[ref], location.Range)
+		}
+	}
+	for _, symbol := range xrefs.symbols {
+		symbol.Location.URI = ""
+		data.Symbols = append(data.Symbols, symbol)
+	}
+	if m := xrefs.methods; m != nil {
+		data.Methods = &methodsData{Pkg: m.pkg, Recvs: m.recvs}
+		for _, t := range m.types {
+			data.Methods.Types = append(data.Methods.Types, typeData{
+				Name:     t.name,
+				Iface:    t.iface,
+				Methods:  t.methods,
+				Embeds:   t.embeds,
+				Invalid:  t.invalid,
+				Location: t.location.Range,
+			})
+		}
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&data); err != nil {
+		log.Error(ctx, "cannot encode the index of a file", err, telemetry.URI.Of(xrefs.identity.URI))
+		return
+	}
+	if err := fc.Set(ctx, xrefsKind, key, buf.Bytes()); err != nil {
+		log.Error(ctx, "cannot store the index of a file", err, telemetry.URI.Of(xrefs.identity.URI))
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/internal/filecache"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+func TestFileXrefsCache(t *testing.T) {
+	const src = `package b
+
+import (
+	"fmt"
+	_ "unsafe"
+)
+
+//go:linkname local example.com/m/a.target
+func local()
+
+type I interface {
+	fmt.Stringer
+	M(int) error
+}
+
+type T struct{ F int }
+
+func (T) String() string { return fmt.Sprint(0) }
+`
+	// The same file in two checkouts.
+	dir, v, cleanup := newTestModule(t, map[string]string{
+		"one/b.go": src,
+		"two/b.go": src,
+	}, func(options *source.Options) {
+		options.FileCache = false
+	})
+	defer cleanup()
+	uris := []span.URI{
+		span.FileURI(filepath.Join(dir, "one", "b.go")),
+		span.FileURI(filepath.Join(dir, "two", "b.go")),
+	}
+
+	ctx := context.Background()
+	fc := filecache.New(filepath.Join(dir, "filecache"))
+
+	xrefs := func(uri span.URI) *fileXrefs {
+		t.Helper()
+		v.xrefsMu.Lock()
+		v.xrefs = nil
+		v.xrefsMu.Unlock()
+		xrefs, err := v.fileXrefs(ctx, v.session.GetFile(uri, source.Go))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return xrefs
+	}
+	want := xrefs(uris[1])
+
+	v.session.cache.fileCache = fc
+	xrefs(uris[0])
+
+	// The index of the copy of the file is read from the file cache, with
+	// the locations of the copy.
+	fh := v.session.GetFile(uris[1], source.Go)
+	_, hash, err := fh.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readFileXrefs(ctx, fc, xrefsKey(hash), fh)
+	if got == nil {
+		t.Fatal("the index of the file is not in the file cache")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("the index read from the file cache is\n%#v\nwant\n%#v", got, want)
+	}
+}
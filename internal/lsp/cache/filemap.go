@@ -0,0 +1,107 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+// filesMap maps file URIs to their FileHandles. Its entries are sharded by
+// the directory of their URI, and the shards are shared by the maps cloned
+// from each other until one of them changes the shard, so that cloning the
+// files of a snapshot copies the shards of the directories whose files
+// changed rather than every entry.
+type filesMap struct {
+	shards map[string]map[span.URI]source.FileHandle
+
+	// owned records the shards that are not shared with another map, and so
+	// may be changed in place.
+	owned map[string]bool
+}
+
+func newFilesMap() *filesMap {
+	return &filesMap{
+		shards: make(map[string]map[span.URI]source.FileHandle),
+		owned:  make(map[string]bool),
+	}
+}
+
+// shardKey returns the key of the shard of uri, which is its URI up to the
+// last slash.
+func shardKey(uri span.URI) string {
+	s := string(uri)
+	if i := strings.LastIndexByte(s, '/'); i >= 0 {
+		return s[:i]
+	}
+	return ""
+}
+
+func (m *filesMap) get(uri span.URI) source.FileHandle {
+	return m.shards[shardKey(uri)][uri]
+}
+
+func (m *filesMap) set(uri span.URI, fh source.FileHandle) {
+	m.writable(shardKey(uri))[uri] = fh
+}
+
+func (m *filesMap) delete(uri span.URI) {
+	key := shardKey(uri)
+	if _, ok := m.shards[key][uri]; !ok {
+		return
+	}
+	shard := m.writable(key)
+	delete(shard, uri)
+	if len(shard) == 0 {
+		delete(m.shards, key)
+		delete(m.owned, key)
+	}
+}
+
+// writable returns the shard of key, which it copies first if it is shared.
+func (m *filesMap) writable(key string) map[span.URI]source.FileHandle {
+	shard := m.shards[key]
+	if m.owned[key] {
+		return shard
+	}
+	copied := make(map[span.URI]source.FileHandle, len(shard)+1)
+	for k, v := range shard {
+		copied[k] = v
+	}
+	m.shards[key] = copied
+	m.owned[key] = true
+	return copied
+}
+
+// clone returns a copy of m, which shares all of its shards with m.
+func (m *filesMap) clone() *filesMap {
+	result := &filesMap{
+		shards: make(map[string]map[span.URI]source.FileHandle, len(m.shards)),
+		owned:  make(map[string]bool),
+	}
+	for k, v := range m.shards {
+		result.shards[k] = v
+	}
+	// The shards are now shared, so neither map may change them in place.
+	m.owned = make(map[string]bool)
+	return result
+}
+
+// shardCount returns the number of shards of m, which is the number of
+// entries copied by clone.
+func (m *filesMap) shardCount() int {
+	return len(m.shards)
+}
+
+// forEach calls f with each URI of m and its FileHandle.
+func (m *filesMap) forEach(f func(uri span.URI, fh source.FileHandle)) {
+	for _, shard := range m.shards {
+		for uri, fh := range shard {
+			f(uri, fh)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+func TestFilesMapClone(t *testing.T) {
+	var (
+		a1 = span.URI("file:///a/1.go")
+		a2 = span.URI("file:///a/2.go")
+		b1 = span.URI("file:///b/1.go")
+	)
+	fh := func(uri span.URI) source.FileHandle {
+		return &nativeFileHandle{identity: source.FileIdentity{URI: uri}}
+	}
+	m := newFilesMap()
+	m.set(a1, fh(a1))
+	m.set(b1, fh(b1))
+
+	clone := m.clone()
+	clone.set(a2, fh(a2))
+	clone.delete(b1)
+	m.set(b1, fh(a1))
+
+	if got := m.get(a2); got != nil {
+		t.Errorf("original map has %v after it was set in the clone", got.Identity().URI)
+	}
+	if got := m.get(b1); got == nil || got.Identity().URI != a1 {
+		t.Errorf("original map lost the change of %s", b1)
+	}
+	if got := clone.get(a2); got == nil {
+		t.Errorf("clone does not have %s", a2)
+	}
+	if got := clone.get(b1); got != nil {
+		t.Errorf("clone has %s after it was deleted", b1)
+	}
+	if got := clone.get(a1); got == nil {
+		t.Errorf("clone does not have %s", a1)
+	}
+	if clone.shardCount() != 1 {
+		t.Errorf("clone has %d shards, want 1", clone.shardCount())
+	}
+}
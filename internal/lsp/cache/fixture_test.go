@@ -0,0 +1,94 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+// writeFiles writes files, keyed by their slash-separated paths, to a new
+// temporary directory, and returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "gopls-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// newTestView returns a view of folder in a new session, along with a
+// function that shuts the session down. The view has the default options
+// without analyzers, as changed by setOptions if it is not nil.
+func newTestView(t *testing.T, folder string, setOptions func(*source.Options)) (*view, func()) {
+	ctx := context.Background()
+	session := New(nil).NewSession(ctx)
+	options := source.DefaultOptions
+	options.Analyzers = nil
+	if setOptions != nil {
+		setOptions(&options)
+	}
+	v := session.NewView(ctx, t.Name(), span.FileURI(folder), options).(*view)
+	return v, func() { session.Shutdown(ctx) }
+}
+
+// newTestModule writes files to a new temporary directory, as writeFiles
+// does, and returns the directory and a view of it, as newTestView does,
+// along with a function that shuts the session down and removes the
+// directory.
+func newTestModule(t *testing.T, files map[string]string, setOptions func(*source.Options)) (string, *view, func()) {
+	t.Helper()
+	dir := writeFiles(t, files)
+	v, shutdown := newTestView(t, dir, setOptions)
+	return dir, v, func() {
+		shutdown()
+		os.RemoveAll(dir)
+	}
+}
+
+const moduleSource = "package a\n\nfunc A() {}\n"
+
+// writeModule writes a module with a single package to a new temporary
+// directory, and returns the directory.
+func writeModule(t *testing.T) string {
+	return writeFiles(t, map[string]string{
+		"go.mod": "module example.com/a\n",
+		"a.go":   moduleSource,
+	})
+}
+
+// checkModule type-checks the package of the module written by writeModule
+// in view.
+func checkModule(ctx context.Context, t *testing.T, view source.View, dir string) {
+	f, err := view.GetFile(ctx, span.FileURI(filepath.Join(dir, "a.go")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, cphs, err := view.CheckPackageHandles(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cph := range cphs {
+		if _, err := cph.Check(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -71,6 +71,10 @@ func (s *snapshot) CheckPackageHandles(ctx context.Context, f source.File) ([]so
 	return cphs, nil
 }
 
+func (s *snapshot) CachedCheckPackageHandles(f source.File) []source.CheckPackageHandle {
+	return s.getPackages(f.URI(), source.ParseFull)
+}
+
 func (s *snapshot) shouldCheck(fh source.FileHandle) (m []*metadata, cphs []source.CheckPackageHandle, load, check bool) {
 	// Get the metadata for the given file.
 	m = s.getMetadataForURI(fh.Identity().URI)
@@ -0,0 +1,88 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+// goSumProblems returns the go.sum problems that the error of
+// go/packages.Load, or the errors of the packages it loaded, report.
+func goSumProblems(err error, pkgs []*packages.Package) []source.GoSumProblem {
+	var msgs []string
+	if err != nil {
+		msgs = append(msgs, err.Error())
+	}
+	for _, pkg := range pkgs {
+		for _, e := range pkg.Errors {
+			msgs = append(msgs, e.Msg)
+		}
+	}
+	return source.ParseGoSumProblems(strings.Join(msgs, "\n"))
+}
+
+// goSumError returns the error of go/packages.Load, which loaded no package
+// in dir, as a *source.GoSumError if it reports go.sum problems, and nil
+// otherwise.
+func goSumError(dir string, err error, problems []source.GoSumProblem) *source.GoSumError {
+	if err == nil || len(problems) == 0 {
+		return nil
+	}
+	return &source.GoSumError{
+		Dir:      dir,
+		Problems: problems,
+		Msg:      goCommandOutput(err.Error(), "go: ", "verifying "),
+	}
+}
+
+func (s *snapshot) setGoSumProblems(uri span.URI, problems []source.GoSumProblem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(problems) == 0 {
+		delete(s.goSumProblems, uri)
+		return
+	}
+	s.goSumProblems[uri] = problems
+}
+
+func (s *snapshot) GoSumProblems(gomod span.URI) ([]source.GoSumProblem, []span.URI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Dir(gomod.Filename()) + string(filepath.Separator)
+	var (
+		result []source.GoSumProblem
+		uris   []span.URI
+	)
+	seen := make(map[source.GoSumProblem]bool)
+	for uri, problems := range s.goSumProblems {
+		if !strings.HasPrefix(uri.Filename(), dir) {
+			continue
+		}
+		uris = append(uris, uri)
+		for _, p := range problems {
+			if !seen[p] {
+				seen[p] = true
+				result = append(result, p)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		x, y := result[i], result[j]
+		if x.Module != y.Module {
+			return x.Module < y.Module
+		}
+		return x.Package < y.Package
+	})
+	sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+	return result, uris
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	errors "golang.org/x/xerrors"
+)
+
+func TestGoSumProblems(t *testing.T) {
+	// The module requires the version of golang.org/x/sync that x/tools
+	// requires, so that it is in the module cache, without a go.sum file.
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"go.mod": "module example.com/m\n\nrequire golang.org/x/sync v0.0.0-20190423024810-112230192c58\n",
+		"a.go":   "package m\n\nimport _ \"golang.org/x/sync/errgroup\"\n",
+	}, func(options *source.Options) {
+		options.Env = append(os.Environ(), "GOFLAGS=-mod=readonly", "GOPROXY=off")
+	})
+	defer cleanup()
+	ctx := context.Background()
+	f, err := view.GetFile(ctx, span.FileURI(filepath.Join(dir, "a.go")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = view.CheckPackageHandles(ctx, f)
+	var sumErr *source.GoSumError
+	if !errors.As(err, &sumErr) {
+		t.Fatalf("got error %v, want a go.sum error", err)
+	}
+	gomod := span.FileURI(filepath.Join(dir, "go.mod"))
+	problems, uris := view.Snapshot().GoSumProblems(gomod)
+	if want := []source.GoSumProblem{{Module: "golang.org/x/sync", Version: "v0.0.0-20190423024810-112230192c58"}}; !reflect.DeepEqual(problems, want) {
+		t.Fatalf("got problems %+v, want %+v", problems, want)
+	}
+	if want := []span.URI{f.URI()}; !reflect.DeepEqual(uris, want) {
+		t.Errorf("got files %v, want %v", uris, want)
+	}
+
+	reports, err := source.ModDiagnostics(ctx, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports[gomod]) != 1 {
+		t.Fatalf("got diagnostics %v, want 1 for golang.org/x/sync", reports[gomod])
+	}
+	diag := reports[gomod][0]
+	// The module path on the require line.
+	want := protocol.Range{
+		Start: protocol.Position{Line: 2, Character: 8},
+		End:   protocol.Position{Line: 2, Character: 8 + float64(len("golang.org/x/sync"))},
+	}
+	if diag.Source != source.GoSumSource || diag.Range != want {
+		t.Errorf("got diagnostic %+v, want one from %s at %v", diag, source.GoSumSource, want)
+	}
+	modFile, err := view.GetFile(ctx, gomod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands, err := source.GoSumCommands(ctx, view, modFile, protocol.Diagnostic{Range: diag.Range, Message: diag.Message})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, c := range commands {
+		titles = append(titles, c.Title)
+	}
+	if want := []string{"Run go mod tidy", "Run go mod download golang.org/x/sync"}; !reflect.DeepEqual(titles, want) {
+		t.Fatalf("got commands %q, want %q", titles, want)
+	}
+
+	// Downloading the module adds its go.sum entries, after which the
+	// package loads and the problems are gone.
+	if err := source.ModDownload(ctx, view, commands[1].Arguments[0].(source.DownloadArgs)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := view.CheckPackageHandles(ctx, f); err != nil {
+		t.Fatal(err)
+	}
+	if problems, _ := view.Snapshot().GoSumProblems(gomod); len(problems) != 0 {
+		t.Errorf("got problems %+v after go mod download", problems)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// PackageImporters returns the import path of the package of the file uri
+// and the files that import it: those of the packages that the metadata of
+// the snapshot knows to import it, along with the Go files in the folder of
+// the view whose imports name its path, as indexed for WorkspaceXrefs. If
+// subpackages is set, the files that import the packages of the
+// subdirectories of its directory are also returned.
+func (s *snapshot) PackageImporters(ctx context.Context, uri span.URI, subpackages bool) (string, []span.URI, error) {
+	ctx, done := trace.StartSpan(ctx, "cache.snapshot.PackageImporters", telemetry.URI.Of(uri))
+	defer done()
+
+	metadata := s.getMetadataForURI(uri)
+	if len(metadata) == 0 {
+		return "", nil, errors.Errorf("no metadata for %s", uri)
+	}
+	// The test variants of a package have the path of the package.
+	pkgPath := string(metadata[0].pkgPath)
+	for _, m := range metadata {
+		if string(m.id) == string(m.pkgPath) {
+			pkgPath = string(m.pkgPath)
+		}
+	}
+
+	seen := make(map[span.URI]bool)
+	var result []span.URI
+	add := func(uri span.URI) {
+		if !seen[uri] {
+			seen[uri] = true
+			result = append(result, uri)
+		}
+	}
+	for _, m := range metadata {
+		for _, id := range s.getImportedBy(m.id) {
+			if importer := s.getMetadata(id); importer != nil {
+				for _, uri := range importer.files {
+					add(uri)
+				}
+			}
+		}
+	}
+	files, err := s.workspaceFileXrefs(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, xrefs := range files {
+		for _, importPath := range xrefs.imports {
+			if importPath == pkgPath || subpackages && strings.HasPrefix(importPath, pkgPath+"/") {
+				add(xrefs.identity.URI)
+				break
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return pkgPath, result, nil
+}
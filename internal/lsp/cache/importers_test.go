@@ -0,0 +1,123 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/diff"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestRenamePackage(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	files := map[string]string{
+		"go.mod": "module example.com/m\n",
+		"util/util.go": `package util
+
+func Do() {}
+`,
+		"util/util_test.go": `package util_test
+
+import "example.com/m/util"
+
+var _ = util.Do
+`,
+		"util/sub/sub.go": `package sub
+
+const X = 1
+`,
+		"a/a.go": `package a
+
+import (
+	"example.com/m/util"
+	"example.com/m/util/sub"
+)
+
+var _ = sub.X
+
+func f() { util.Do() }
+`,
+		"b/b.go": `package b
+
+import u "example.com/m/util"
+
+var _ = u.Do
+`,
+	}
+	dir, view, cleanup := newTestModule(t, files, func(options *source.Options) {
+		options.RenamePackageDirectory = true
+	})
+	defer cleanup()
+	ctx := context.Background()
+
+	f, err := view.GetFile(ctx, span.FileURI(filepath.Join(dir, "util", "util.go")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item, err := source.PrepareRenamePackage(ctx, view, f, protocol.Position{Line: 0, Character: 9}); err != nil || item == nil || item.Text != "util" {
+		t.Errorf("PrepareRenamePackage = %v, %v, want util", item, err)
+	}
+	if edits, err := source.RenamePackage(ctx, view, f, protocol.Position{Line: 2, Character: 5}, "helper"); err != nil || edits != nil {
+		t.Errorf("RenamePackage outside of the package clause = %v, %v, want none", edits, err)
+	}
+	edits, err := source.RenamePackage(ctx, view, f, protocol.Position{Line: 0, Character: 9}, "helper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"util/util.go": `package helper
+
+func Do() {}
+`,
+		"util/util_test.go": `package helper_test
+
+import "example.com/m/helper"
+
+var _ = helper.Do
+`,
+		"a/a.go": `package a
+
+import (
+	"example.com/m/helper"
+	"example.com/m/helper/sub"
+)
+
+var _ = sub.X
+
+func f() { helper.Do() }
+`,
+		"b/b.go": `package b
+
+import u "example.com/m/helper"
+
+var _ = u.Do
+`,
+	}
+	if len(edits) != len(want) {
+		t.Errorf("got edits of %d files, want %d", len(edits), len(want))
+	}
+	for name, content := range want {
+		uri := span.FileURI(filepath.Join(dir, filepath.FromSlash(name)))
+		m := &protocol.ColumnMapper{
+			URI:       uri,
+			Converter: span.NewContentConverter(uri.Filename(), []byte(files[name])),
+			Content:   []byte(files[name]),
+		}
+		fileEdits, err := source.FromProtocolEdits(m, edits[uri])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := diff.ApplyEdits(files[name], fileEdits); got != content {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", name, got, content)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"go/ast"
+	"go/scanner"
+	"go/token"
+)
+
+// fixIncompleteExprs repairs the expressions that go/parser builds from code
+// in the middle of being typed, so that they are type-checked as the user
+// means them, and completion has their types:
+//
+//   - A selector chain that ends in a dangling "." takes the identifier that
+//     starts the next line as its selector, such as y in
+//
+//     x := foo.bar.
+//     y := 2
+//
+//     Its selector is replaced with a phantom "_" right after the ".".
+//
+//   - A composite literal that is not closed yet takes the statements that
+//     follow it as its elements, up to the closing brace of the block. The
+//     elements from the first line whose tokens the parser had to skip are
+//     dropped, and the literal is closed at the end of the line before.
+//
+//   - A dangling comma in a composite literal, as in T{A: 1, ,}, leaves a bad
+//     expression among its elements, which is dropped.
+//
+// Only the expressions that parseError, the error of go/parser, reports
+// errors in or right after are repaired, as the same syntax can be valid,
+// such as a selector chain broken across lines after a ".".
+func fixIncompleteExprs(n ast.Node, tok *token.File, src []byte, parseError error) {
+	list, ok := parseError.(scanner.ErrorList)
+	if !ok || len(list) == 0 {
+		return
+	}
+	errOffsets := make(map[int]bool)
+	for _, e := range list {
+		errOffsets[e.Pos.Offset] = true
+	}
+	// The selectors are fixed first, as a dangling selector hides the end of
+	// the element of a composite literal.
+	ast.Inspect(n, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			fixDanglingSelector(sel, tok, src, errOffsets)
+		}
+		return true
+	})
+	ast.Inspect(n, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.CompositeLit); ok {
+			fixCompositeLit(lit, tok, src)
+		}
+		return true
+	})
+}
+
+// fixDanglingSelector replaces the selector of sel with a phantom "_" if it
+// is on a line after the "." and go/parser reported an error right after it.
+func fixDanglingSelector(sel *ast.SelectorExpr, tok *token.File, src []byte, errOffsets map[int]bool) {
+	if sel.Sel == nil || sel.Sel.Name == "_" || !validRange(tok, sel.X.End(), sel.Sel.End()) {
+		return
+	}
+	dot := skipSpace(src, tok.Offset(sel.X.End()))
+	if dot >= len(src) || src[dot] != '.' {
+		return
+	}
+	if tok.Line(sel.Sel.Pos()) <= tok.Line(tok.Pos(dot)) {
+		return
+	}
+	if next := skipSpace(src, tok.Offset(sel.Sel.End())); !errOffsets[next] {
+		return
+	}
+	sel.Sel = &ast.Ident{
+		Name:    "_",
+		NamePos: tok.Pos(dot + 1),
+	}
+}
+
+// fixCompositeLit drops the bad elements of lit, and closes it before the
+// statements that follow it if it is not closed.
+func fixCompositeLit(lit *ast.CompositeLit, tok *token.File, src []byte) {
+	var elts []ast.Expr
+	for _, elt := range lit.Elts {
+		if _, bad := elt.(*ast.BadExpr); !bad {
+			elts = append(elts, elt)
+		}
+	}
+	lit.Elts = elts
+
+	for i := 0; i+1 < len(elts); i++ {
+		skipped := skippedToken(tok, src, elts[i].End(), elts[i+1].Pos())
+		if !skipped.IsValid() {
+			continue
+		}
+		// The literal ends with the last line before the skipped tokens.
+		line := tok.Line(skipped)
+		keep := 0
+		for keep < len(elts) && tok.Line(elts[keep].End()-1) < line {
+			keep++
+		}
+		end := lit.Lbrace
+		if keep > 0 {
+			end = elts[keep-1].End()
+		}
+		lit.Elts = elts[:keep]
+		lit.Rbrace = endOfLine(tok, src, end)
+		return
+	}
+}
+
+// skippedToken returns the position of the first token between two elements
+// of a composite literal from from to to, if go/parser skipped it, which it
+// does for all the tokens but a separating comma and comments.
+func skippedToken(tok *token.File, src []byte, from, to token.Pos) token.Pos {
+	if !validRange(tok, from, to) {
+		return token.NoPos
+	}
+	start := tok.Offset(from)
+	gap := src[start:tok.Offset(to)]
+	fset := token.NewFileSet()
+	var s scanner.Scanner
+	s.Init(fset.AddFile("", -1, len(gap)), gap, nil, scanner.ScanComments)
+	for {
+		pos, t, lit := s.Scan()
+		switch {
+		case t == token.EOF, t == token.COMMA:
+			return token.NoPos
+		case t == token.COMMENT, t == token.SEMICOLON && lit == "\n":
+			continue
+		}
+		return tok.Pos(start + fset.Position(pos).Offset)
+	}
+}
+
+// endOfLine returns the position of the newline that ends the line of pos,
+// or of the end of the file.
+func endOfLine(tok *token.File, src []byte, pos token.Pos) token.Pos {
+	offset := tok.Offset(pos)
+	for offset < len(src) && src[offset] != '\n' {
+		offset++
+	}
+	if offset >= tok.Size() {
+		offset = tok.Size() - 1
+	}
+	return tok.Pos(offset)
+}
+
+// skipSpace returns the offset of the first byte of src from offset that is
+// not a space, tab or newline.
+func skipSpace(src []byte, offset int) int {
+	for offset < len(src) && (src[offset] == ' ' || src[offset] == '\t' || src[offset] == '\r' || src[offset] == '\n') {
+		offset++
+	}
+	return offset
+}
+
+// validRange reports whether from and to are positions of tok, with from
+// before to.
+func validRange(tok *token.File, from, to token.Pos) bool {
+	base := token.Pos(tok.Base())
+	return from.IsValid() && to.IsValid() && from >= base && to <= base+token.Pos(tok.Size()) && from <= to
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestFixIncompleteExprs(t *testing.T) {
+	for _, tt := range []struct {
+		name, src string
+		want      []string // the printed expressions assigned to x
+	}{
+		{
+			name: "unclosed literal",
+			src: `x := T{A: 1, B: 2
+	y := 2`,
+			want: []string{"T{A: 1, B: 2}"},
+		},
+		{
+			name: "unclosed literal with dangling selector",
+			src: `x := T{A: 1, B: foo.
+	y := 2`,
+			want: []string{"T{A: 1, B: foo._}"},
+		},
+		{
+			name: "dangling comma",
+			src:  `x := T{A: 1, ,}`,
+			want: []string{"T{A: 1}"},
+		},
+		{
+			name: "dangling selector",
+			src: `x := foo.bar.
+	y := 2`,
+			want: []string{"foo.bar._"},
+		},
+		{
+			name: "selector across lines",
+			src: `x := foo.
+		Bar()`,
+			want: []string{"foo.Bar()"},
+		},
+	} {
+		src := "package p\n\nfunc _() {\n\t" + tt.src + "\n}\n"
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "p.go", src, parser.AllErrors)
+		if file == nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		fixIncompleteExprs(file, fset.File(file.Pos()), []byte(src), err)
+
+		var got []string
+		ast.Inspect(file, func(n ast.Node) bool {
+			assign, ok := n.(*ast.AssignStmt)
+			if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+				return true
+			}
+			if id, ok := assign.Lhs[0].(*ast.Ident); !ok || id.Name != "x" {
+				return true
+			}
+			var buf bytes.Buffer
+			printer.Fprint(&buf, token.NewFileSet(), assign.Rhs[0])
+			got = append(got, strings.Join(strings.Fields(buf.String()), " "))
+			return true
+		})
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/tools/internal/lsp/debug"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	errors "golang.org/x/xerrors"
+)
+
+// maxInvalidations is the number of snapshots of a view for which the view
+// remembers what was invalidated.
+const maxInvalidations = 100
+
+// invalidation records why a snapshot was created from the snapshot before
+// it, what was discarded when it was, and the work later discarded or
+// redone in it.
+type invalidation struct {
+	snapshot uint64
+	uri      span.URI
+	kind     source.FileKind
+	change   protocol.FileChangeType
+
+	// metadataChanged is set when the package name or imports of the file
+	// changed, so that the metadata of its packages had to be reloaded.
+	metadataChanged bool
+
+	// types and metadata are the packages whose type information and
+	// metadata were not copied into the new snapshot.
+	types    []packageID
+	metadata []packageID
+
+	// reloads and evicted are added to while the snapshot is current, and
+	// are guarded by the view's invalidationMu.
+	reloads []reload
+	evicted []packageKey
+}
+
+// reload is a go/packages load done in a snapshot.
+type reload struct {
+	uri span.URI
+	ids []packageID
+}
+
+// recordInvalidation adds inv to the invalidations remembered by the view.
+func (v *view) recordInvalidation(inv *invalidation) {
+	v.invalidationMu.Lock()
+	defer v.invalidationMu.Unlock()
+	v.invalidations = append(v.invalidations, inv)
+	if n := len(v.invalidations) - maxInvalidations; n > 0 {
+		v.invalidations = append(v.invalidations[:0], v.invalidations[n:]...)
+	}
+}
+
+// recordReload records that the given metadata was loaded for uri in the
+// snapshot s.
+func (s *snapshot) recordReload(uri span.URI, metadata []*metadata) {
+	ids := make([]packageID, 0, len(metadata))
+	for _, m := range metadata {
+		ids = append(ids, m.id)
+	}
+	s.view.invalidationMu.Lock()
+	defer s.view.invalidationMu.Unlock()
+	if inv := s.invalidation; inv != nil {
+		inv.reloads = append(inv.reloads, reload{uri: uri, ids: ids})
+	}
+}
+
+// recordEviction records that the package with the given key was evicted from
+// the snapshot s.
+func (s *snapshot) recordEviction(key packageKey) {
+	s.view.invalidationMu.Lock()
+	defer s.view.invalidationMu.Unlock()
+	if inv := s.invalidation; inv != nil {
+		inv.evicted = append(inv.evicted, key)
+	}
+}
+
+// snapshotDiff explains what was invalidated, and why, in the snapshot with
+// ID to, which must have been created from the snapshot with ID from.
+func (v *view) snapshotDiff(from, to uint64) (*debug.SnapshotDiff, error) {
+	if to != from+1 {
+		return nil, errors.Errorf("snapshots %d and %d are not consecutive", from, to)
+	}
+	v.invalidationMu.Lock()
+	defer v.invalidationMu.Unlock()
+	for _, inv := range v.invalidations {
+		if inv.snapshot == to {
+			return inv.diff(), nil
+		}
+	}
+	return nil, errors.Errorf("no record of snapshot %d of view %s", to, v.name)
+}
+
+// snapshotDiffs returns the diffs of the snapshots the view remembers, most
+// recent first.
+func (v *view) snapshotDiffs() []*debug.SnapshotDiff {
+	v.invalidationMu.Lock()
+	defer v.invalidationMu.Unlock()
+	result := make([]*debug.SnapshotDiff, 0, len(v.invalidations))
+	for i := len(v.invalidations) - 1; i >= 0; i-- {
+		result = append(result, v.invalidations[i].diff())
+	}
+	return result
+}
+
+// diff converts inv to its debug representation. It must be called with the
+// view's invalidationMu held.
+func (inv *invalidation) diff() *debug.SnapshotDiff {
+	d := &debug.SnapshotDiff{
+		From:                inv.snapshot - 1,
+		To:                  inv.snapshot,
+		Cause:               "file change",
+		URI:                 inv.uri,
+		Change:              changeTypeString(inv.change),
+		MetadataInvalidated: inv.metadataChanged,
+		Types:               packageIDStrings(inv.types),
+		Metadata:            packageIDStrings(inv.metadata),
+	}
+	if inv.kind == source.Mod {
+		d.Cause = "go.mod change"
+	}
+	for _, r := range inv.reloads {
+		d.Reloads = append(d.Reloads, debug.SnapshotReload{
+			URI:      r.uri,
+			Packages: packageIDStrings(r.ids),
+		})
+	}
+	for _, key := range inv.evicted {
+		d.Evicted = append(d.Evicted, fmt.Sprintf("%s (%s)", key.id, parseModeString(key.mode)))
+	}
+	return d
+}
+
+func packageIDStrings(ids []packageID) []string {
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, string(id))
+	}
+	sort.Strings(result)
+	return result
+}
+
+func changeTypeString(change protocol.FileChangeType) string {
+	switch change {
+	case protocol.Created:
+		return "created"
+	case protocol.Changed:
+		return "changed"
+	case protocol.Deleted:
+		return "deleted"
+	}
+	return fmt.Sprintf("change %v", change)
+}
+
+// parseModeString describes the syntax a package is type-checked from.
+func parseModeString(mode source.ParseMode) string {
+	if mode == source.ParseFull {
+		return "full"
+	}
+	return "exported"
+}
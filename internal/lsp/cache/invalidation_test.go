@@ -0,0 +1,68 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	dir := writeModule(t)
+	defer os.RemoveAll(dir)
+	view, shutdown := newTestView(t, dir, nil)
+	defer shutdown()
+	ctx := context.Background()
+	checkModule(ctx, t, view, dir)
+	uri := span.FileURI(filepath.Join(dir, "a.go"))
+
+	// A change to the imports of the file invalidates the metadata of its
+	// package, which is reloaded when the package is next checked.
+	if _, err := view.SetContent(ctx, uri, []byte("package a\n\nimport _ \"unsafe\"\n\nfunc A() {}\n")); err != nil {
+		t.Fatal(err)
+	}
+	checkModule(ctx, t, view, dir)
+	diff, err := view.snapshotDiff(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Cause != "file change" || diff.URI != uri || diff.Change != "changed" || !diff.MetadataInvalidated {
+		t.Errorf("got diff %+v, want a change to the imports of %s", diff, uri)
+	}
+	want := []string{"example.com/a"}
+	if !reflect.DeepEqual(diff.Types, want) || !reflect.DeepEqual(diff.Metadata, want) {
+		t.Errorf("got types %v and metadata %v discarded, want %v", diff.Types, diff.Metadata, want)
+	}
+	if len(diff.Reloads) != 1 || diff.Reloads[0].URI != uri {
+		t.Errorf("got reloads %+v, want one for %s", diff.Reloads, uri)
+	}
+
+	// A change to the body of a function keeps the metadata.
+	if _, err := view.SetContent(ctx, uri, []byte("package a\n\nimport _ \"unsafe\"\n\nfunc A() { _ = 1 }\n")); err != nil {
+		t.Fatal(err)
+	}
+	diff, err = view.snapshotDiff(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.MetadataInvalidated || len(diff.Metadata) != 0 || !reflect.DeepEqual(diff.Types, want) {
+		t.Errorf("got diff %+v, want only the types of %v discarded", diff, want)
+	}
+
+	if _, err := view.snapshotDiff(0, 2); err == nil {
+		t.Errorf("got a diff of snapshots that are not consecutive")
+	}
+	if got := len(view.snapshotDiffs()); got != 2 {
+		t.Errorf("got %d snapshot diffs, want 2", got)
+	}
+}
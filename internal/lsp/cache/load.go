@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"go/types"
+	"time"
 
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/internal/lsp/source"
@@ -37,8 +38,20 @@ func (s *snapshot) load(ctx context.Context, uri span.URI) ([]*metadata, error)
 	ctx, done := trace.StartSpan(ctx, "cache.view.load", telemetry.URI.Of(uri))
 	defer done()
 
+	if s.view.DirectoryFilter().ExcludedFile(uri.Filename()) {
+		return nil, errors.Errorf("no metadata for %s: its directory is excluded by the directory filters of the view", uri.Filename())
+	}
 	cfg := s.view.Config(ctx)
+	standalone := s.view.Standalone(uri)
+	if standalone {
+		cfg = standaloneConfig(cfg, uri.Filename())
+	}
+	start := time.Now()
 	pkgs, err := packages.Load(cfg, fmt.Sprintf("file=%s", uri.Filename()))
+	s.view.stats.recordLoad(time.Since(start))
+	if standalone {
+		renameAdHocPackages(pkgs, uri.Filename())
+	}
 
 	// If the context was canceled, return early.
 	// Otherwise, we might be type-checking an incomplete result.
@@ -47,7 +60,15 @@ func (s *snapshot) load(ctx context.Context, uri span.URI) ([]*metadata, error)
 	}
 
 	log.Print(ctx, "go/packages.Load", tag.Of("packages", len(pkgs)))
+	problems := goSumProblems(err, pkgs)
+	s.setGoSumProblems(uri, problems)
 	if len(pkgs) == 0 {
+		if vendorErr := inconsistentVendoring(err); vendorErr != nil {
+			return nil, vendorErr
+		}
+		if sumErr := goSumError(cfg.Dir, err, problems); sumErr != nil {
+			return nil, sumErr
+		}
 		if err == nil {
 			err = errors.Errorf("go/packages.Load: no packages found for %s", uri)
 		}
@@ -58,6 +79,7 @@ func (s *snapshot) load(ctx context.Context, uri span.URI) ([]*metadata, error)
 	if err != nil {
 		return nil, err
 	}
+	s.recordReload(uri, m)
 	meta, err := validateMetadata(ctx, m, prevMissingImports)
 	if err != nil {
 		return nil, err
@@ -183,6 +205,11 @@ func (s *snapshot) updateImports(ctx context.Context, pkgPath packagePath, pkg *
 
 		s.addID(uri, m.id)
 	}
+	// The files that use cgo are type-checked from the files that cgo
+	// generates from them, but belong to the package too.
+	for _, filename := range pkg.GoFiles {
+		s.addID(span.FileURI(filename), m.id)
+	}
 
 	// Add the metadata to the cache.
 	s.setMetadata(m)
@@ -0,0 +1,54 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+
+	"golang.org/x/tools/internal/lsp/source"
+)
+
+// MatrixViews returns the views that load the files of the view under each
+// configuration of its build matrix. They are created on first use, and are
+// not views of the session: they only serve to type-check the packages of
+// files for their diagnostics.
+func (v *view) MatrixViews() []source.View {
+	options := v.Options()
+	configs := options.BuildMatrix
+	if len(configs) == 0 {
+		return nil
+	}
+	v.matrixMu.Lock()
+	defer v.matrixMu.Unlock()
+
+	if v.matrix == nil {
+		v.matrix = make(map[string]*view)
+	}
+	views := make([]source.View, 0, len(configs))
+	for _, c := range configs {
+		key := c.String()
+		mv, ok := v.matrix[key]
+		if !ok {
+			mvOptions := c.Apply(options)
+			mvOptions.BuildMatrix = nil
+			mvOptions.BuildConfiguration = source.BuildConfiguration{}
+			mv = v.session.newView(v.baseCtx, v.name+" ["+key+"]", v.folder, mvOptions)
+			v.matrix[key] = mv
+		}
+		views = append(views, mv)
+	}
+	return views
+}
+
+// dropMatrix shuts down the views of the build matrix of the view, which no
+// longer match its options.
+func (v *view) dropMatrix(ctx context.Context) {
+	v.matrixMu.Lock()
+	defer v.matrixMu.Unlock()
+	for _, mv := range v.matrix {
+		mv.shutdown(ctx)
+	}
+	v.matrix = nil
+}
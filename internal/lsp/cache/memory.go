@@ -0,0 +1,148 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/telemetry/log"
+	"golang.org/x/tools/internal/telemetry/tag"
+)
+
+// memoryCheckInterval is how often the memory watchdog compares the heap
+// size with the memory limit.
+var memoryCheckInterval = 5 * time.Second
+
+func (cph *checkPackageHandle) touch() {
+	atomic.StoreInt64(&cph.lastUsed, time.Now().UnixNano())
+}
+
+// watchMemory starts the memory watchdog of the cache, unless it is already
+// running. The watchdog stops once no view has a memory limit.
+func (c *cache) watchMemory() {
+	if !atomic.CompareAndSwapInt32(&c.watchingMemory, 0, 1) {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(memoryCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if c.memoryLimit() == 0 {
+				atomic.StoreInt32(&c.watchingMemory, 0)
+				return
+			}
+			c.checkMemory(context.Background())
+		}
+	}()
+}
+
+// memoryLimit returns the smallest memory limit of the views of the cache,
+// or 0 if none of them has one.
+func (c *cache) memoryLimit() int64 {
+	var limit int64
+	for _, v := range c.views() {
+		if l := v.Options().MemoryLimit; l > 0 && (limit == 0 || l < limit) {
+			limit = l
+		}
+	}
+	return limit
+}
+
+// checkMemory evicts packages if the heap is larger than the memory limit,
+// and returns the number of packages evicted.
+func (c *cache) checkMemory(ctx context.Context) int {
+	limit := c.memoryLimit()
+	if limit == 0 {
+		return 0
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	if int64(mem.HeapAlloc) <= limit {
+		return 0
+	}
+	evicted := c.evictActivePackages()
+	log.Print(ctx, "memory limit exceeded", tag.Of("heapAlloc", mem.HeapAlloc), tag.Of("limit", limit), tag.Of("evicted", evicted))
+	// Collect the evicted packages now, so that the next check sees the
+	// effect of the eviction rather than evicting more.
+	runtime.GC()
+	return evicted
+}
+
+// activePackage is a package type-checked in full in a view's snapshot.
+type activePackage struct {
+	snapshot *snapshot
+	key      packageKey
+	cph      *checkPackageHandle
+}
+
+// evictActivePackages removes the least recently used quarter, and at least
+// one, of the packages that the views of the cache have type-checked in
+// full. An evicted package is type-checked again the next time it is needed;
+// meanwhile, the packages that import it use its exported declarations, which
+// are held separately. It returns the number of packages evicted.
+func (c *cache) evictActivePackages() int {
+	var active []activePackage
+	for _, v := range c.views() {
+		s := v.getSnapshot()
+		s.mu.Lock()
+		for key, cph := range s.packages {
+			if key.mode != source.ParseFull || cph.handle.Cached() == nil {
+				continue
+			}
+			active = append(active, activePackage{s, key, cph})
+		}
+		s.mu.Unlock()
+	}
+	if len(active) == 0 {
+		return 0
+	}
+	sort.Slice(active, func(i, j int) bool {
+		return atomic.LoadInt64(&active[i].cph.lastUsed) < atomic.LoadInt64(&active[j].cph.lastUsed)
+	})
+	n := len(active) / 4
+	if n == 0 {
+		n = 1
+	}
+	for _, p := range active[:n] {
+		p.snapshot.evictPackage(p.key, p.cph)
+	}
+	return n
+}
+
+// evictPackage removes the given package, and the analyses of it, from the
+// snapshot, unless it has already been replaced.
+func (s *snapshot) evictPackage(key packageKey, cph *checkPackageHandle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.packages[key] != cph {
+		return
+	}
+	delete(s.packages, key)
+	s.recordEviction(key)
+	for k := range s.actions {
+		if k.pkg == key {
+			delete(s.actions, k)
+		}
+	}
+}
+
+// views returns the views of all the sessions of the cache.
+func (c *cache) views() []*view {
+	var result []*view
+	c.sessionMu.Lock()
+	sessions := append([]*session(nil), c.sessions...)
+	c.sessionMu.Unlock()
+	for _, s := range sessions {
+		s.viewMu.Lock()
+		result = append(result, s.views...)
+		s.viewMu.Unlock()
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestEvictActivePackages(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	dir := writeModule(t)
+	defer os.RemoveAll(dir)
+	view, shutdown := newTestView(t, dir, nil)
+	defer shutdown()
+	ctx := context.Background()
+	c := view.session.cache
+	options := view.Options()
+	checkModule(ctx, t, view, dir)
+
+	// Without a limit, nothing is evicted.
+	if n := c.checkMemory(ctx); n != 0 {
+		t.Errorf("evicted %d packages without a memory limit", n)
+	}
+
+	// Any heap exceeds a limit of a single byte.
+	options.MemoryLimit = 1
+	view.SetOptions(options)
+	if n := c.checkMemory(ctx); n != 1 {
+		t.Fatalf("evicted %d packages, want 1", n)
+	}
+	if got := view.Stats().Packages; got != 0 {
+		t.Errorf("got %d type-checked packages after eviction, want 0", got)
+	}
+
+	// The package is type-checked again when it is needed.
+	checkModule(ctx, t, view, dir)
+	if got := view.Stats().Packages; got != 1 {
+		t.Errorf("got %d type-checked packages after checking again, want 1", got)
+	}
+}
+
+func TestMemoryLimitConcurrently(t *testing.T) {
+	view, shutdown := newTestView(t, os.TempDir(), nil)
+	defer shutdown()
+	c := view.session.cache
+	options := view.Options()
+
+	// The watchdog reads the limit while the options change, which the race
+	// detector checks.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for limit := int64(1 << 30); limit <= 4<<30; limit += 1 << 30 {
+			options.MemoryLimit = limit
+			view.SetOptions(options)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		c.memoryLimit()
+	}
+	<-done
+	if got, want := c.memoryLimit(), int64(4<<30); got != want {
+		t.Errorf("got memory limit %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"sort"
+
+	"golang.org/x/tools/internal/lsp/source"
+)
+
+// MetadataGraph returns the metadata of the packages that the snapshot has
+// loaded and of their dependencies, sorted by ID.
+func (s *snapshot) MetadataGraph() []source.PackageMetadata {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]source.PackageMetadata, 0, len(s.metadata))
+	for _, m := range s.metadata {
+		pm := source.PackageMetadata{
+			ID:      string(m.id),
+			PkgPath: string(m.pkgPath),
+			Files:   append(m.files[:0:0], m.files...),
+		}
+		for _, dep := range m.deps {
+			pm.Deps = append(pm.Deps, string(dep))
+		}
+		result = append(result, pm)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
@@ -0,0 +1,216 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// fileMethods holds the types that a Go file declares, and the methods it
+// declares on the types of its package.
+type fileMethods struct {
+	pkg   string
+	types []*typeMethods
+	recvs map[string][]string // method fingerprints by receiver type name
+}
+
+// typeMethods is a type declared by a Go file.
+type typeMethods struct {
+	name     string
+	iface    bool
+	methods  []string // of an interface
+	embeds   []string // the names of the interfaces embedded in an interface
+	invalid  bool     // an interface whose methods cannot be known syntactically
+	location protocol.Location
+}
+
+// WorkspaceMethodSets returns the method sets of the named types declared by
+// the Go files in the folder of the view. They are indexed along with the
+// references of WorkspaceXrefs, so only the files that changed since the last
+// request are parsed again.
+func (s *snapshot) WorkspaceMethodSets(ctx context.Context) ([]source.MethodSet, error) {
+	ctx, done := trace.StartSpan(ctx, "cache.snapshot.WorkspaceMethodSets", telemetry.URI.Of(s.view.folder))
+	defer done()
+
+	files, err := s.workspaceFileXrefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The files of a package are those of a directory with the same
+	// package name.
+	type pkgKey struct{ dir, name string }
+	type pkgMethods struct {
+		types map[string]*typeMethods
+		recvs map[string][]string
+	}
+	pkgs := make(map[pkgKey]*pkgMethods)
+	var keys []pkgKey
+	for _, xrefs := range files {
+		m := xrefs.methods
+		if m == nil {
+			continue
+		}
+		key := pkgKey{filepath.Dir(xrefs.identity.URI.Filename()), m.pkg}
+		p, ok := pkgs[key]
+		if !ok {
+			p = &pkgMethods{types: make(map[string]*typeMethods), recvs: make(map[string][]string)}
+			pkgs[key] = p
+			keys = append(keys, key)
+		}
+		for _, t := range m.types {
+			p.types[t.name] = t
+		}
+		for recv, methods := range m.recvs {
+			p.recvs[recv] = append(p.recvs[recv], methods...)
+		}
+	}
+
+	var result []source.MethodSet
+	for _, key := range keys {
+		p := pkgs[key]
+		for _, t := range p.types {
+			set := source.MethodSet{
+				Type:      key.name + "." + t.name,
+				Dir:       key.dir,
+				Interface: t.iface,
+				Location:  t.location,
+			}
+			if t.iface {
+				methods, ok := interfaceMethods(p.types, t, make(map[string]bool))
+				if !ok {
+					continue
+				}
+				set.Methods = methods
+			} else {
+				set.Methods = append(set.Methods, p.recvs[t.name]...)
+			}
+			sort.Strings(set.Methods)
+			result = append(result, set)
+		}
+	}
+	return result, nil
+}
+
+// interfaceMethods returns the methods of the interface t of a package, with
+// those of the interfaces it embeds, and whether they are all known.
+func interfaceMethods(types map[string]*typeMethods, t *typeMethods, seen map[string]bool) ([]string, bool) {
+	if t.invalid || seen[t.name] {
+		return nil, !t.invalid
+	}
+	seen[t.name] = true
+	methods := append([]string(nil), t.methods...)
+	for _, name := range t.embeds {
+		embedded, ok := types[name]
+		switch {
+		case ok && embedded.iface:
+			more, ok := interfaceMethods(types, embedded, seen)
+			if !ok {
+				return nil, false
+			}
+			methods = append(methods, more...)
+		case ok:
+			return nil, false
+		case name == "error":
+			methods = append(methods, source.MethodFingerprint("Error", 0, 1))
+		case name != "any" && name != "comparable":
+			return nil, false
+		}
+	}
+	return methods, true
+}
+
+// methodSets returns the types that file declares and the methods it
+// declares on them, or nil if it declares neither.
+func methodSets(fset *token.FileSet, file *ast.File, m *protocol.ColumnMapper) *fileMethods {
+	var (
+		result fileMethods
+		ranges []span.Range
+	)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil || len(decl.Recv.List) == 0 {
+				continue
+			}
+			recv := receiverName(decl.Recv.List[0].Type)
+			if recv == "" {
+				continue
+			}
+			if result.recvs == nil {
+				result.recvs = make(map[string][]string)
+			}
+			result.recvs[recv] = append(result.recvs[recv], funcFingerprint(decl.Name.Name, decl.Type))
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				spec, ok := spec.(*ast.TypeSpec)
+				if !ok || spec.Name.Name == "_" {
+					continue
+				}
+				t := &typeMethods{name: spec.Name.Name}
+				if iface, ok := spec.Type.(*ast.InterfaceType); ok {
+					t.iface = true
+					for _, field := range iface.Methods.List {
+						switch typ := field.Type.(type) {
+						case *ast.FuncType:
+							for _, name := range field.Names {
+								t.methods = append(t.methods, funcFingerprint(name.Name, typ))
+							}
+						case *ast.Ident:
+							t.embeds = append(t.embeds, typ.Name)
+						default:
+							// Interfaces of other packages and the
+							// elements of constraints.
+							t.invalid = true
+						}
+					}
+				}
+				result.types = append(result.types, t)
+				ranges = append(ranges, span.NewRange(fset, spec.Name.Pos(), spec.Name.End()))
+			}
+		}
+	}
+	if len(result.types) == 0 && len(result.recvs) == 0 {
+		return nil
+	}
+	protocolRanges, err := m.RangesToUTF16(ranges)
+	if err != nil {
+		return nil
+	}
+	for i, t := range result.types {
+		t.location = protocol.Location{URI: protocol.NewURI(m.URI), Range: protocolRanges[i]}
+	}
+	result.pkg = file.Name.Name
+	return &result
+}
+
+// funcFingerprint returns the fingerprint of the method name of type typ.
+func funcFingerprint(name string, typ *ast.FuncType) string {
+	return source.MethodFingerprint(name, fieldCount(typ.Params), fieldCount(typ.Results))
+}
+
+// fieldCount returns the number of parameters or results of list.
+func fieldCount(list *ast.FieldList) int {
+	if list == nil {
+		return 0
+	}
+	n := 0
+	for _, field := range list.List {
+		if len(field.Names) == 0 {
+			n++
+		}
+		n += len(field.Names)
+	}
+	return n
+}
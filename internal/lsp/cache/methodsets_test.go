@@ -0,0 +1,124 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestWorkspaceMethodSets(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"a/a.go": `package a
+
+import "io"
+
+type Reader interface {
+	Read(p []byte) (n int, err error)
+}
+
+type ReadCloser interface {
+	Reader
+	Close() error
+}
+
+type Foreign interface {
+	io.Reader
+}
+
+type Failer interface {
+	error
+	Fail()
+}
+
+type File struct{}
+
+func (f *File) Read(p []byte) (int, error) { return 0, nil }
+`,
+		"a/b.go": `package a
+
+func (f *File) Close() error { return nil }
+
+type Empty int
+`,
+		"b/b.go": `package b
+
+type Buffer struct{}
+
+func (b *Buffer) Read(p []byte) (int, error) { return 0, nil }
+`,
+	}, func(options *source.Options) {
+		options.HoverImplements = true
+	})
+	defer cleanup()
+	ctx := context.Background()
+
+	sets, err := view.Snapshot().WorkspaceMethodSets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, set := range sets {
+		got = append(got, set.Type+" "+strings.Join(set.Methods, ","))
+	}
+	sort.Strings(got)
+	want := []string{
+		"a.Empty ",
+		"a.Failer Error/0/1,Fail/0/0",
+		"a.File Close/0/1,Read/1/2",
+		"a.ReadCloser Close/0/1,Read/1/2",
+		"a.Reader Read/1/2",
+		"b.Buffer Read/1/2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got method sets %q, want %q", got, want)
+	}
+
+	uri := span.FileURI(filepath.Join(dir, "a", "a.go"))
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		line, column float64
+		interfaces   bool
+		want         []string
+	}{
+		{4, 5, true, []string{"a.File", "b.Buffer"}},
+		{22, 5, false, []string{"a.ReadCloser", "a.Reader"}},
+	} {
+		ident, err := source.Identifier(ctx, view, f, protocol.Position{Line: test.line, Character: test.column})
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := ident.Hover(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Implements == nil {
+			t.Errorf("%s: no implementations", ident.Name)
+			continue
+		}
+		var got []string
+		for _, l := range h.Implements.Types {
+			got = append(got, l.Name)
+		}
+		if h.Implements.Interface != test.interfaces || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v %q, want %v %q", ident.Name, h.Implements.Interface, got, test.interfaces, test.want)
+		}
+	}
+}
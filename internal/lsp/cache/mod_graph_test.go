@@ -0,0 +1,51 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+func TestModGraphCache(t *testing.T) {
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"go.mod": "module example.com/a\n",
+	}, nil)
+	defer cleanup()
+	gomod := filepath.Join(dir, "go.mod")
+	ctx := context.Background()
+
+	fetches := 0
+	fetch := func(context.Context) (*source.ModGraph, error) {
+		fetches++
+		return &source.ModGraph{Main: "example.com/a"}, nil
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := view.Snapshot().ModGraph(ctx, span.FileURI(gomod), fetch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("got %d fetches of the graph of an unchanged go.mod file, want 1", fetches)
+	}
+
+	// The version of a file on disk is its modification time.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(gomod, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := view.Snapshot().ModGraph(ctx, span.FileURI(gomod), fetch); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 2 {
+		t.Errorf("got %d fetches after the go.mod file changed, want 2", fetches)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+func TestReplaceDirectives(t *testing.T) {
+	const gomod = `module example.com/m
+
+require (
+	example.com/b v1.0.0
+	example.com/c v1.0.0
+)
+
+replace example.com/c => ../missing
+`
+	dir := writeFiles(t, map[string]string{
+		"m/go.mod": gomod,
+		"b/go.mod": "module example.com/b\n",
+	})
+	defer os.RemoveAll(dir)
+	modDir := filepath.Join(dir, "m")
+	filename := filepath.Join(modDir, "go.mod")
+	view, shutdown := newTestView(t, modDir, nil)
+	defer shutdown()
+	ctx := context.Background()
+	uri := span.FileURI(filename)
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := source.ModDiagnostics(ctx, view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports[uri]) != 1 {
+		t.Fatalf("got diagnostics %v, want 1 for the replacement by ../missing", reports[uri])
+	}
+	diag := reports[uri][0]
+	if want := "replacement directory ../missing does not exist"; diag.Message != want {
+		t.Errorf("got message %q, want %q", diag.Message, want)
+	}
+	fixes, err := source.ReplaceFixes(ctx, view, f, protocol.Diagnostic{Range: diag.Range, Message: diag.Message})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := applyFixes(t, gomod, uri, fixes, source.DropReplaceTitle); got != gomod[:len(gomod)-len("replace example.com/c => ../missing\n")] {
+		t.Errorf("dropping the replace directive gave:\n%s", got)
+	}
+
+	// The requirement of example.com/b, on line 3.
+	line := protocol.Range{Start: protocol.Position{Line: 3}, End: protocol.Position{Line: 3}}
+	fixes, err = source.ReplaceActions(ctx, view, f, line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, fix := range fixes {
+		titles = append(titles, fix.Title)
+	}
+	if want := []string{"Replace with local directory ../b"}; !reflect.DeepEqual(titles, want) {
+		t.Fatalf("got actions %q, want %q", titles, want)
+	}
+	if got, want := applyFixes(t, gomod, uri, fixes, titles[0]), gomod+"replace example.com/b => ../b\n"; got != want {
+		t.Errorf("replacing example.com/b gave:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The replace directive, on line 7.
+	line = protocol.Range{Start: protocol.Position{Line: 7}, End: protocol.Position{Line: 7}}
+	fixes, err = source.ReplaceActions(ctx, view, f, line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixes) != 1 || fixes[0].Title != source.DropReplaceTitle {
+		t.Errorf("got actions %v on the replace directive, want %q", fixes, source.DropReplaceTitle)
+	}
+}
+
+// applyFixes returns the content of the file with the given URI after the
+// edits of the fix with the given title.
+func applyFixes(t *testing.T, content string, uri span.URI, fixes []source.SuggestedFix, title string) string {
+	t.Helper()
+	for _, fix := range fixes {
+		if fix.Title != title {
+			continue
+		}
+		m := &protocol.ColumnMapper{
+			URI:       uri,
+			Converter: span.NewContentConverter(uri.Filename(), []byte(content)),
+			Content:   []byte(content),
+		}
+		edits := fix.Edits[uri]
+		for i := len(edits) - 1; i >= 0; i-- {
+			spn, err := m.RangeSpan(edits[i].Range)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content = content[:spn.Start().Offset()] + edits[i].NewText + content[spn.End().Offset():]
+		}
+		return content
+	}
+	t.Fatalf("no fix %q in %v", title, fixes)
+	return ""
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/internal/span"
+)
+
+// nestedModuleRoot returns the root directory of the module that contains the
+// file with the given URI, if that module is nested in the folder of the view
+// v: the go command, run in the folder, would report the file to be outside
+// of the main module. It returns "" if the file is not in a nested module, or
+// if a go.work file in the folder already brings the modules it contains
+// into the workspace.
+func nestedModuleRoot(v *view, uri span.URI) string {
+	folder := v.folder.Filename()
+	if _, err := os.Stat(filepath.Join(folder, "go.work")); err == nil {
+		return ""
+	}
+	for dir := filepath.Dir(uri.Filename()); ; {
+		rel, err := filepath.Rel(folder, dir)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return ""
+		}
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// createNestedView creates the view of the module rooted at root, which is
+// nested in the folder of the view parent. The view is named after its
+// directory relative to the workspace folder, and shares the options of the
+// view of the workspace folder, which owns it: it is shut down along with it.
+// viewMu must be held when calling this method.
+func (s *session) createNestedView(parent *view, root string) *view {
+	if parent.parent != nil {
+		parent = parent.parent
+	}
+	name := parent.name
+	if rel, err := filepath.Rel(parent.folder.Filename(), root); err == nil {
+		name += "/" + filepath.ToSlash(rel)
+	}
+	v := s.createView(parent.baseCtx, name, span.FileURI(root), parent.Options())
+	v.parent = parent
+	return v
+}
+
+// obsoleteNestedViews returns the views of nested modules that the change of
+// the file with the given URI makes obsolete: the deletion of the go.mod file
+// of their module, or the creation of a go.work file in their workspace
+// folder.
+// viewMu must be held when calling this method.
+func (s *session) obsoleteNestedViews(uri span.URI, deleted bool) []*view {
+	filename := uri.Filename()
+	dir := span.FileURI(filepath.Dir(filename))
+	var obsolete []*view
+	for _, v := range s.views {
+		if v.parent == nil {
+			continue
+		}
+		switch filepath.Base(filename) {
+		case "go.mod":
+			if deleted && v.folder == dir {
+				obsolete = append(obsolete, v)
+			}
+		case "go.work":
+			if !deleted && v.parent.folder == dir {
+				obsolete = append(obsolete, v)
+			}
+		}
+	}
+	return obsolete
+}
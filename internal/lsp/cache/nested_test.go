@@ -0,0 +1,63 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+func TestNestedModuleViews(t *testing.T) {
+	dir, root, cleanup := newTestModule(t, map[string]string{
+		"a/go.mod":   "module example.com/a\n",
+		"a/a.go":     "package a\n",
+		"b/go.mod":   "module example.com/b\n",
+		"b/c/c.go":   "package c\n",
+		"tools/t.go": "package tools\n",
+	}, nil)
+	defer cleanup()
+	ctx := context.Background()
+	session := root.session
+
+	open := func(name string) source.View {
+		uri := span.FileURI(filepath.Join(dir, filepath.FromSlash(name)))
+		if err := session.DidOpen(ctx, uri, source.Go, nil); err != nil {
+			t.Fatal(err)
+		}
+		return session.ViewOf(uri)
+	}
+	// The views of the modules are named after that of the folder.
+	for _, tt := range []struct {
+		file, view string
+	}{
+		{"a/a.go", root.Name() + "/a"},
+		{"b/c/c.go", root.Name() + "/b"},
+		{"tools/t.go", root.Name()},
+	} {
+		if got := open(tt.file).Name(); got != tt.view {
+			t.Errorf("view of %s = %q, want %q", tt.file, got, tt.view)
+		}
+	}
+	if got := len(session.Views()); got != 3 {
+		t.Errorf("got %d views after opening files, want 3", got)
+	}
+
+	// Deleting the go.mod file of a nested module removes its view.
+	session.DidChangeOutOfBand(ctx, span.FileURI(filepath.Join(dir, "b", "go.mod")), protocol.Deleted)
+	if session.View(root.Name()+"/b") != nil {
+		t.Errorf("the view of b survived the deletion of its go.mod file")
+	}
+
+	// Shutting down the view of the folder shuts down those of its modules.
+	root.Shutdown(ctx)
+	if got := len(session.Views()); got != 0 {
+		t.Errorf("got %d views after shutting down the folder, want 0", got)
+	}
+}
@@ -7,11 +7,13 @@ package cache
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/scanner"
 	"go/token"
 	"reflect"
+	"strings"
 
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/source"
@@ -24,7 +26,7 @@ import (
 )
 
 // Limits the number of parallel parser calls per process.
-var parseLimit = make(chan struct{}, 20)
+var parseLimit = newScheduler(20)
 
 // parseKey uniquely identifies a parsed Go file.
 type parseKey struct {
@@ -33,9 +35,15 @@ type parseKey struct {
 }
 
 type parseGoHandle struct {
+	cache  *cache
 	handle *memoize.Handle
 	file   source.FileHandle
 	mode   source.ParseMode
+
+	// encoding is the position encoding of the mappers returned by the
+	// handle. The parsed files are shared by the sessions of the cache,
+	// whose clients may have negotiated different encodings.
+	encoding protocol.PositionEncodingKind
 }
 
 type parseGoData struct {
@@ -45,6 +53,10 @@ type parseGoData struct {
 	parseError error // errors associated with parsing the file
 	mapper     *protocol.ColumnMapper
 	err        error
+
+	// constraintEnd is the end of the build constraint lines of the file,
+	// if any.
+	constraintEnd token.Pos
 }
 
 func (c *cache) ParseGoHandle(fh source.FileHandle, mode source.ParseMode) source.ParseGoHandle {
@@ -55,9 +67,13 @@ func (c *cache) ParseGoHandle(fh source.FileHandle, mode source.ParseMode) sourc
 	h := c.store.Bind(key, func(ctx context.Context) interface{} {
 		data := &parseGoData{}
 		data.ast, data.mapper, data.parseError, data.err = parseGo(ctx, c, fh, mode)
+		if data.ast != nil {
+			data.constraintEnd = buildConstraintEnd(data.ast)
+		}
 		return data
 	})
 	return &parseGoHandle{
+		cache:  c,
 		handle: h,
 		file:   fh,
 		mode:   mode,
@@ -73,12 +89,40 @@ func (h *parseGoHandle) Mode() source.ParseMode {
 }
 
 func (h *parseGoHandle) Parse(ctx context.Context) (*ast.File, *protocol.ColumnMapper, error, error) {
+	hit := h.handle.Cached() != nil
 	v := h.handle.Get(ctx)
 	if v == nil {
 		return nil, nil, nil, errors.Errorf("no parsed file for %s", h.File().Identity().URI)
 	}
+	h.cache.parseCache.used(ctx, h.handle, hit)
 	data := v.(*parseGoData)
-	return data.ast, data.mapper, data.parseError, data.err
+	return data.ast, h.mapper(data.mapper), data.parseError, data.err
+}
+
+func (h *parseGoHandle) BuildConstraintEnd(ctx context.Context) (token.Pos, error) {
+	v := h.handle.Get(ctx)
+	if v == nil {
+		return token.NoPos, errors.Errorf("no parsed file for %s", h.File().Identity().URI)
+	}
+	return v.(*parseGoData).constraintEnd, nil
+}
+
+// buildConstraintEnd returns the end of the last build constraint line of
+// file, "//go:build" or "// +build", before its package clause, or
+// token.NoPos if it has none.
+func buildConstraintEnd(file *ast.File) token.Pos {
+	var end token.Pos
+	for _, cg := range file.Comments {
+		if cg.Pos() >= file.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if strings.HasPrefix(c.Text, "//go:build") || strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(c.Text, "//")), "+build") {
+				end = c.End()
+			}
+		}
+	}
+	return end
 }
 
 func (h *parseGoHandle) Cached() (*ast.File, *protocol.ColumnMapper, error, error) {
@@ -87,13 +131,29 @@ func (h *parseGoHandle) Cached() (*ast.File, *protocol.ColumnMapper, error, erro
 		return nil, nil, nil, errors.Errorf("no cached AST for %s", h.file.Identity().URI)
 	}
 	data := v.(*parseGoData)
-	return data.ast, data.mapper, data.parseError, data.err
+	return data.ast, h.mapper(data.mapper), data.parseError, data.err
+}
+
+// mapper returns a copy of the mapper m of the parsed file with the position
+// encoding of h.
+func (h *parseGoHandle) mapper(m *protocol.ColumnMapper) *protocol.ColumnMapper {
+	if m == nil || m.Encoding == h.encoding {
+		return m
+	}
+	encoded := *m
+	encoded.Encoding = h.encoding
+	return &encoded
 }
 
 func hashParseKey(ph source.ParseGoHandle) string {
 	b := bytes.NewBuffer(nil)
 	b.WriteString(ph.File().Identity().String())
 	b.WriteString(string(ph.Mode()))
+	// The packages type-checked from the files hold their handles, so they
+	// are not shared by sessions with different position encodings.
+	if ph, ok := ph.(*parseGoHandle); ok {
+		b.WriteString(string(ph.encoding))
+	}
 	return hashContents(b.Bytes())
 }
 
@@ -105,6 +165,52 @@ func hashParseKeys(phs []source.ParseGoHandle) string {
 	return hashContents(b.Bytes())
 }
 
+// hashExportedSyntax returns a hash of the syntax of the files of phs, which
+// must have been parsed in ParseExported mode, and so hold only what can
+// affect the exported API of their package. Edits that leave that syntax,
+// and the line and column of each of its nodes, unchanged, such as edits to
+// function bodies that keep the number of lines, leave the hash unchanged.
+// Files that cannot be parsed are hashed by their identity instead.
+func hashExportedSyntax(ctx context.Context, fset *token.FileSet, phs []source.ParseGoHandle) (string, error) {
+	b := bytes.NewBuffer(nil)
+	for _, ph := range phs {
+		file, _, _, err := ph.Parse(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			b.WriteString(hashParseKey(ph))
+			continue
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n == nil {
+				return false
+			}
+			pos := fset.Position(n.Pos())
+			fmt.Fprintf(b, "%T %d:%d ", n, pos.Line, pos.Column)
+			switch n := n.(type) {
+			case *ast.Ident:
+				b.WriteString(n.Name)
+			case *ast.BasicLit:
+				b.WriteString(n.Value)
+			case *ast.Comment:
+				b.WriteString(n.Text)
+			case *ast.BinaryExpr:
+				b.WriteString(n.Op.String())
+			case *ast.UnaryExpr:
+				b.WriteString(n.Op.String())
+			case *ast.ChanType:
+				fmt.Fprint(b, n.Dir)
+			case *ast.GenDecl:
+				b.WriteString(n.Tok.String())
+			}
+			b.WriteByte('\n')
+			return true
+		})
+	}
+	return hashContents(b.Bytes()), nil
+}
+
 func parseGo(ctx context.Context, c *cache, fh source.FileHandle, mode source.ParseMode) (file *ast.File, mapper *protocol.ColumnMapper, parseError error, err error) {
 	ctx, done := trace.StartSpan(ctx, "cache.parseGo", telemetry.File.Of(fh.Identity().URI.Filename()))
 	defer done()
@@ -113,8 +219,11 @@ func parseGo(ctx context.Context, c *cache, fh source.FileHandle, mode source.Pa
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	parseLimit <- struct{}{}
-	defer func() { <-parseLimit }()
+	release, err := parseLimit.acquire(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer release()
 	parserMode := parser.AllErrors | parser.ParseComments
 	if mode == source.ParseHeader {
 		parserMode = parser.ImportsOnly | parser.ParseComments
@@ -129,6 +238,7 @@ func parseGo(ctx context.Context, c *cache, fh source.FileHandle, mode source.Pa
 		if err := fix(ctx, file, tok, buf); err != nil {
 			log.Error(ctx, "failed to fix AST", err)
 		}
+		fixIncompleteExprs(file, tok, buf, parseError)
 	}
 
 	if file == nil {
@@ -0,0 +1,114 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"golang.org/x/tools/internal/lsp/debug"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/memoize"
+)
+
+// parseCache holds on to the most recently used parsed files.
+//
+// The memoize store only remembers a parsed file while a handle to it is
+// reachable, which for most files means while a snapshot refers to it. The
+// parse cache keeps the handles of recently used files reachable too, so
+// that they are not parsed again when they are needed after their snapshot
+// has been replaced.
+type parseCache struct {
+	mu       sync.Mutex
+	capacity int
+
+	// lru holds the retained handles, most recently used first, and elems
+	// maps each of them to its element.
+	lru   *list.List
+	elems map[*memoize.Handle]*list.Element
+
+	hits, misses, evictions int64
+}
+
+func newParseCache(capacity int) *parseCache {
+	return &parseCache{
+		capacity: capacity,
+		lru:      list.New(),
+		elems:    make(map[*memoize.Handle]*list.Element),
+	}
+}
+
+// used records a use of the parsed file of h, which was a hit if the file
+// had already been parsed.
+func (c *parseCache) used(ctx context.Context, h *memoize.Handle, hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hit {
+		c.hits++
+		telemetry.ParseCacheHits.Record(ctx, 1)
+	} else {
+		c.misses++
+		telemetry.ParseCacheMisses.Record(ctx, 1)
+	}
+	if e, ok := c.elems[h]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.elems[h] = c.lru.PushFront(h)
+	c.evict(ctx)
+}
+
+// setCapacity changes the number of parsed files retained by the cache. A
+// negative capacity is treated as 0.
+func (c *parseCache) setCapacity(ctx context.Context, capacity int) {
+	if capacity < 0 {
+		capacity = 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.capacity = capacity
+	c.evict(ctx)
+}
+
+// evict drops the least recently used handles until the cache is within its
+// capacity. It must be called with c.mu held.
+func (c *parseCache) evict(ctx context.Context) {
+	for c.lru.Len() > c.capacity {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.elems, e.Value.(*memoize.Handle))
+		c.evictions++
+		telemetry.ParseCacheEvictions.Record(ctx, 1)
+	}
+}
+
+func (c *parseCache) stats() source.ParseCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return source.ParseCacheStats{
+		Capacity:  c.capacity,
+		Size:      c.lru.Len(),
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+func (c *cache) ParseCacheStats() source.ParseCacheStats {
+	return c.parseCache.stats()
+}
+
+func (c debugCache) ParseCache() debug.ParseCache {
+	s := c.parseCache.stats()
+	return debug.ParseCache{
+		Capacity:  s.Capacity,
+		Size:      s.Size,
+		Hits:      s.Hits,
+		Misses:    s.Misses,
+		Evictions: s.Evictions,
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/memoize"
+)
+
+func TestParseCache(t *testing.T) {
+	ctx := context.Background()
+	var store memoize.Store
+	handle := func(key string) *memoize.Handle {
+		return store.Bind(key, func(context.Context) interface{} { return new(int) })
+	}
+	a, b, c := handle("a"), handle("b"), handle("c")
+
+	pc := newParseCache(2)
+	pc.used(ctx, a, false)
+	pc.used(ctx, b, false)
+	pc.used(ctx, a, true) // a is now more recently used than b
+	pc.used(ctx, c, false)
+	if _, ok := pc.elems[b]; ok {
+		t.Errorf("least recently used handle was not evicted")
+	}
+	want := source.ParseCacheStats{Capacity: 2, Size: 2, Hits: 1, Misses: 3, Evictions: 1}
+	if got := pc.stats(); got != want {
+		t.Errorf("got stats %+v, want %+v", got, want)
+	}
+
+	pc.setCapacity(ctx, 0)
+	want = source.ParseCacheStats{Capacity: 0, Size: 0, Hits: 1, Misses: 3, Evictions: 3}
+	if got := pc.stats(); got != want {
+		t.Errorf("after shrinking, got stats %+v, want %+v", got, want)
+	}
+
+	// A negative capacity retains nothing.
+	pc.used(ctx, a, true)
+	pc.setCapacity(ctx, -1)
+	want = source.ParseCacheStats{Capacity: 0, Size: 0, Hits: 2, Misses: 3, Evictions: 4}
+	if got := pc.stats(); got != want {
+		t.Errorf("after a negative capacity, got stats %+v, want %+v", got, want)
+	}
+}
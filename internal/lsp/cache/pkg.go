@@ -30,6 +30,9 @@ type pkg struct {
 	types      *types.Package
 	typesInfo  *types.Info
 	typesSizes types.Sizes
+
+	// sourceSize is the total size of the source of the package's files.
+	sourceSize int64
 }
 
 // Declare explicit types for package paths and IDs to ensure that we never use
@@ -0,0 +1,87 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/diff"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+	errors "golang.org/x/xerrors"
+)
+
+func TestRenameConflicts(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	const src = `package a
+
+import "fmt"
+
+func f() {
+	msg := "hello"
+	fmt.Println(msg)
+}
+`
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"a.go":   src,
+		"go.mod": "module example.com/a\n",
+	}, nil)
+	defer cleanup()
+	filename := filepath.Join(dir, "a.go")
+	ctx := context.Background()
+
+	uri := span.FileURI(filename)
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ident, err := source.Identifier(ctx, view, f, protocol.Position{Line: 5, Character: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ident.Rename(ctx, view, "fmt")
+	var conflictErr *source.RenameConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("got error %v, want a conflict", err)
+	}
+	var lines []int
+	for _, c := range conflictErr.Conflicts {
+		if c.URI != uri {
+			t.Errorf("got a conflict in %s, want %s", c.URI, uri)
+		}
+		lines = append(lines, c.Line)
+	}
+	// The declaration, the reference it would shadow, and the import.
+	if len(lines) != 3 || lines[0] != 6 || lines[1] != 7 || lines[2] != 3 {
+		t.Errorf("got conflicts at lines %v, want 6, 7 and 3", lines)
+	}
+
+	m := &protocol.ColumnMapper{
+		URI:       uri,
+		Converter: span.NewContentConverter(filename, []byte(src)),
+		Content:   []byte(src),
+	}
+	edits, err := source.FromProtocolEdits(m, conflictErr.Edits[uri])
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `package a
+
+import "fmt"
+
+func f() {
+	fmt := "hello"
+	fmt.Println(fmt)
+}
+`
+	if got := diff.ApplyEdits(src, edits); got != want {
+		t.Errorf("got edits to:\n%s\nwant:\n%s", got, want)
+	}
+}
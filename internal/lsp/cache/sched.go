@@ -0,0 +1,137 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/tools/internal/lsp/source"
+)
+
+// scheduler limits the number of goroutines doing some kind of work at once,
+// giving priority to the work of the requests of the user over background
+// work, as told by source.BackgroundPriority.
+//
+// The free slots go to the waiting foreground work first, in the order it
+// arrived, and then to the waiting background work. Unless there is a
+// single slot, background work never holds all of them, so that a request
+// of the user does not wait for a whole batch of diagnostics to be done.
+type scheduler struct {
+	capacity           int
+	backgroundCapacity int
+
+	mu                sync.Mutex
+	running           int
+	runningBackground int
+
+	// foreground and background are closed to hand a slot to the work
+	// waiting for it.
+	foreground, background []chan struct{}
+}
+
+func newScheduler(capacity int) *scheduler {
+	backgroundCapacity := capacity - 1
+	if backgroundCapacity < 1 {
+		backgroundCapacity = 1
+	}
+	return &scheduler{
+		capacity:           capacity,
+		backgroundCapacity: backgroundCapacity,
+	}
+}
+
+// acquire waits for a slot for the work done for ctx, and returns the
+// function that releases it. It returns the error of ctx if ctx is done
+// before a slot is free.
+func (s *scheduler) acquire(ctx context.Context) (release func(), err error) {
+	background := source.BackgroundPriority(ctx)
+	release = func() { s.release(background) }
+
+	s.mu.Lock()
+	queued := len(s.foreground) > 0
+	if background {
+		queued = queued || len(s.background) > 0
+	}
+	if !queued && s.canRunLocked(background) {
+		s.startLocked(background)
+		s.mu.Unlock()
+		return release, nil
+	}
+	ready := make(chan struct{})
+	if background {
+		s.background = append(s.background, ready)
+	} else {
+		s.foreground = append(s.foreground, ready)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return release, nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	removed := s.dequeueLocked(ready, background)
+	s.mu.Unlock()
+	if !removed {
+		// The slot was handed over as ctx was done.
+		release()
+	}
+	return nil, ctx.Err()
+}
+
+// dequeueLocked removes ready from the queue of the foreground or background
+// work, and reports whether it was still there.
+func (s *scheduler) dequeueLocked(ready chan struct{}, background bool) bool {
+	queue := &s.foreground
+	if background {
+		queue = &s.background
+	}
+	for i, c := range *queue {
+		if c == ready {
+			*queue = append((*queue)[:i], (*queue)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *scheduler) release(background bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	if background {
+		s.runningBackground--
+	}
+	for {
+		switch {
+		case len(s.foreground) > 0 && s.canRunLocked(false):
+			s.startLocked(false)
+			close(s.foreground[0])
+			s.foreground = s.foreground[1:]
+		case len(s.background) > 0 && s.canRunLocked(true):
+			s.startLocked(true)
+			close(s.background[0])
+			s.background = s.background[1:]
+		default:
+			return
+		}
+	}
+}
+
+func (s *scheduler) canRunLocked(background bool) bool {
+	if s.running >= s.capacity {
+		return false
+	}
+	return !background || s.runningBackground < s.backgroundCapacity
+}
+
+func (s *scheduler) startLocked(background bool) {
+	s.running++
+	if background {
+		s.runningBackground++
+	}
+}
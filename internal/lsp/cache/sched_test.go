@@ -0,0 +1,102 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/source"
+)
+
+func TestScheduler(t *testing.T) {
+	fg := context.Background()
+	bg := source.WithBackgroundPriority(fg)
+	s := newScheduler(2)
+
+	// Background work gets one of the two slots, and foreground work the
+	// other one.
+	releaseBG := mustAcquire(t, s, bg)
+	acquired := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+	acquire := func(ctx context.Context, name string) {
+		go func() {
+			release, err := s.acquire(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			acquired <- name
+			<-done
+		}()
+	}
+	acquire(bg, "background 1")
+	waitQueued(s, 0, 1)
+	releaseFG := mustAcquire(t, s, fg)
+
+	// Once a slot is released, the waiting foreground work runs before the
+	// background work that waited longer, which still has to wait for the
+	// background slot.
+	acquire(fg, "foreground 1")
+	waitQueued(s, 1, 1)
+	releaseFG()
+	if name := <-acquired; name != "foreground 1" {
+		t.Errorf("%s acquired the released slot, want foreground 1", name)
+	}
+	waitQueued(s, 0, 1)
+	releaseBG()
+	if name := <-acquired; name != "background 1" {
+		t.Errorf("%s acquired the released background slot, want background 1", name)
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := newScheduler(1)
+	release := mustAcquire(t, s, context.Background())
+
+	// Work whose context is done stops waiting for a slot.
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error)
+	go func() {
+		_, err := s.acquire(ctx)
+		errc <- err
+	}()
+	waitQueued(s, 1, 0)
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	waitQueued(s, 0, 0)
+
+	// The slot is free once released.
+	release()
+	mustAcquire(t, s, context.Background())()
+}
+
+func mustAcquire(t *testing.T, s *scheduler, ctx context.Context) func() {
+	t.Helper()
+	release, err := s.acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return release
+}
+
+// waitQueued waits until the given numbers of foreground and background
+// work are waiting for a slot of s.
+func waitQueued(s *scheduler, foreground, background int) {
+	for {
+		s.mu.Lock()
+		f, b := len(s.foreground), len(s.background)
+		s.mu.Unlock()
+		if f == foreground && b == background {
+			return
+		}
+		runtime.Gosched()
+	}
+}
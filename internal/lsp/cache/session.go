@@ -56,6 +56,10 @@ type overlay struct {
 	unchanged bool
 }
 
+func (s *session) ID() string {
+	return s.id
+}
+
 func (s *session) Options() source.Options {
 	return s.options
 }
@@ -64,6 +68,12 @@ func (s *session) SetOptions(options source.Options) {
 	s.options = options
 }
 
+func (s *session) ParseGoHandle(fh source.FileHandle, mode source.ParseMode) source.ParseGoHandle {
+	ph := s.cache.ParseGoHandle(fh, mode).(*parseGoHandle)
+	ph.encoding = s.options.PositionEncoding
+	return ph
+}
+
 func (s *session) Shutdown(ctx context.Context) {
 	s.viewMu.Lock()
 	defer s.viewMu.Unlock()
@@ -72,6 +82,7 @@ func (s *session) Shutdown(ctx context.Context) {
 	}
 	s.views = nil
 	s.viewMap = nil
+	s.cache.removeSession(s)
 	debug.DropSession(debugSession{s})
 }
 
@@ -80,9 +91,25 @@ func (s *session) Cache() source.Cache {
 }
 
 func (s *session) NewView(ctx context.Context, name string, folder span.URI, options source.Options) source.View {
-	index := atomic.AddInt64(&viewIndex, 1)
 	s.viewMu.Lock()
 	defer s.viewMu.Unlock()
+	return s.createView(ctx, name, folder, options)
+}
+
+// createView creates a view and adds it to the session.
+// viewMu must be held when calling this method.
+func (s *session) createView(ctx context.Context, name string, folder span.URI, options source.Options) *view {
+	v := s.newView(ctx, name, folder, options)
+	s.views = append(s.views, v)
+	// we always need to drop the view map
+	s.viewMap = make(map[span.URI]source.View)
+	debug.AddView(debugView{v})
+	return v
+}
+
+// newView creates a view without adding it to the session.
+func (s *session) newView(ctx context.Context, name string, folder span.URI, options source.Options) *view {
+	index := atomic.AddInt64(&viewIndex, 1)
 	// We want a true background context and not a detached context here
 	// the spans need to be unrelated and no tag values should pollute it.
 	baseCtx := trace.Detach(xcontext.Detach(ctx))
@@ -99,12 +126,17 @@ func (s *session) NewView(ctx context.Context, name string, folder span.URI, opt
 		filesByURI:    make(map[span.URI]viewFile),
 		filesByBase:   make(map[string][]viewFile),
 		snapshot: &snapshot{
-			packages:   make(map[packageKey]*checkPackageHandle),
-			ids:        make(map[span.URI][]packageID),
-			metadata:   make(map[packageID]*metadata),
-			files:      make(map[span.URI]source.FileHandle),
-			importedBy: make(map[packageID][]packageID),
-			actions:    make(map[actionKey]*actionHandle),
+			packages:      make(map[packageKey]*checkPackageHandle),
+			ids:           make(map[span.URI][]packageID),
+			metadata:      make(map[packageID]*metadata),
+			files:         newFilesMap(),
+			importedBy:    make(map[packageID][]packageID),
+			actions:       make(map[actionKey]*actionHandle),
+			coverage:      make(map[span.URI]*source.FileCoverage),
+			modules:       make(map[string]*moduleInfoEntry),
+			modGraphs:     make(map[span.URI]*modGraphEntry),
+			tidyDiffs:     make(map[span.URI]*tidyDiffEntry),
+			goSumProblems: make(map[span.URI][]source.GoSumProblem),
 		},
 		ignoredURIs: make(map[span.URI]struct{}),
 		builtin:     &builtinPkg{},
@@ -114,15 +146,16 @@ func (s *session) NewView(ctx context.Context, name string, folder span.URI, opt
 	if v.session.cache.options != nil {
 		v.session.cache.options(&v.options)
 	}
+	v.dirFilter = v.newDirectoryFilter(ctx, v.options)
+	if v.options.MemoryLimit > 0 {
+		s.cache.watchMemory()
+	}
+	s.cache.parseCache.setCapacity(ctx, v.options.ParseCacheSize)
+	s.cache.setFileCache(ctx, v.options)
 
 	// Preemptively build the builtin package,
 	// so we immediately add builtin.go to the list of ignored files.
 	v.buildBuiltinPackage(ctx)
-
-	s.views = append(s.views, v)
-	// we always need to drop the view map
-	s.viewMap = make(map[span.URI]source.View)
-	debug.AddView(debugView{v})
 	return v
 }
 
@@ -202,18 +235,26 @@ func (s *session) removeView(ctx context.Context, view *view) error {
 	defer s.viewMu.Unlock()
 	// we always need to drop the view map
 	s.viewMap = make(map[span.URI]source.View)
-	for i, v := range s.views {
-		if view == v {
-			// delete this view... we don't care about order but we do want to make
-			// sure we can garbage collect the view
-			s.views[i] = s.views[len(s.views)-1]
-			s.views[len(s.views)-1] = nil
-			s.views = s.views[:len(s.views)-1]
-			v.shutdown(ctx)
-			return nil
+	found := false
+	for i := 0; i < len(s.views); {
+		v := s.views[i]
+		// The views of the modules nested in the folder of the view go with it.
+		if view != v && view != v.parent {
+			i++
+			continue
 		}
+		found = found || view == v
+		// delete this view... we don't care about order but we do want to make
+		// sure we can garbage collect the view
+		s.views[i] = s.views[len(s.views)-1]
+		s.views[len(s.views)-1] = nil
+		s.views = s.views[:len(s.views)-1]
+		v.shutdown(ctx)
 	}
-	return errors.Errorf("view %s for %v not found", view.Name(), view.Folder())
+	if !found {
+		return errors.Errorf("view %s for %v not found", view.Name(), view.Folder())
+	}
+	return nil
 }
 
 // TODO: Propagate the language ID through to the view.
@@ -230,9 +271,17 @@ func (s *session) DidOpen(ctx context.Context, uri span.URI, kind source.FileKin
 		return nil
 	}
 
+	// Open files in modules nested in the folder of their view get a view of
+	// their own, rather than being outside of its main module.
+	s.viewMu.Lock()
+	v := s.bestView(uri).(*view)
+	if root := nestedModuleRoot(v, uri); root != "" {
+		v = s.createNestedView(v, root)
+	}
+	s.viewMu.Unlock()
+
 	// Make sure that the file gets added to the session's file watch map.
-	view := s.bestView(uri)
-	if _, err := view.GetFile(ctx, uri); err != nil {
+	if _, err := v.GetFile(ctx, uri); err != nil {
 		return err
 	}
 
@@ -356,6 +405,14 @@ func (s *session) buildOverlay() map[string][]byte {
 }
 
 func (s *session) DidChangeOutOfBand(ctx context.Context, uri span.URI, changeType protocol.FileChangeType) bool {
+	s.viewMu.Lock()
+	obsolete := s.obsoleteNestedViews(uri, changeType == protocol.Deleted)
+	s.viewMu.Unlock()
+	for _, v := range obsolete {
+		if err := s.removeView(ctx, v); err != nil {
+			log.Error(ctx, "failed to remove nested module view", err, telemetry.File)
+		}
+	}
 	return s.filesWatchMap.Notify(uri, changeType)
 }
 
@@ -376,7 +433,6 @@ func (o *overlay) Read(ctx context.Context) ([]byte, string, error) {
 
 type debugSession struct{ *session }
 
-func (s debugSession) ID() string         { return s.id }
 func (s debugSession) Cache() debug.Cache { return debugCache{s.cache} }
 func (s debugSession) Files() []*debug.File {
 	var files []*debug.File
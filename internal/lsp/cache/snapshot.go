@@ -3,12 +3,16 @@ package cache
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/source"
 	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/xcontext"
+	errors "golang.org/x/xerrors"
 )
 
 type snapshot struct {
@@ -30,7 +34,7 @@ type snapshot struct {
 
 	// files maps file URIs to their corresponding FileHandles.
 	// It may invalidated when a file's content changes.
-	files map[span.URI]source.FileHandle
+	files *filesMap
 
 	// packages maps a packageKey to a set of CheckPackageHandles to which that file belongs.
 	// It may be invalidated when a file's content changes.
@@ -38,8 +42,79 @@ type snapshot struct {
 
 	// actions maps an actionkey to its actionHandle.
 	actions map[actionKey]*actionHandle
+
+	// invalidation records why the snapshot was created from the previous
+	// snapshot of the view. It is nil for the first snapshot.
+	invalidation *invalidation
+
+	// coverage maps file URIs to the test coverage most recently computed
+	// for them. It is not invalidated when a file's content changes, since
+	// the coverage records the content that it was computed for.
+	coverage map[span.URI]*source.FileCoverage
+
+	// apiChanges maps package paths to the incompatible changes to their
+	// API most recently found by the "apidiff" command. Like the coverage,
+	// they are kept until the command is run again.
+	apiChanges map[string][]source.APIChange
+
+	// modules maps module paths to the information of the module proxy on
+	// them. It is not copied to the next snapshot, so that the information
+	// is fetched again, but at most once per snapshot.
+	modules map[string]*moduleInfoEntry
+
+	// modGraphs maps the URIs of go.mod files to their module requirement
+	// graphs. They are copied to the next snapshot, and computed again when
+	// the go.mod file has changed.
+	modGraphs map[span.URI]*modGraphEntry
+
+	// tidyDiffs maps the URIs of go.mod files to the differences that
+	// "go mod tidy" would make to them. They are copied to the next snapshot
+	// unless the imports of its packages have changed, and are computed
+	// again when the go.mod or go.sum file has changed.
+	tidyDiffs map[span.URI]*tidyDiffEntry
+
+	// goSumProblems maps the URIs of files to the go.sum problems found when
+	// the packages of the files were last loaded. As such files are loaded
+	// again until their packages are complete, the problems are copied to
+	// the next snapshot.
+	goSumProblems map[span.URI][]source.GoSumProblem
+}
+
+// moduleInfoEntry is the information of the module proxy on a module, or the
+// error fetching it.
+type moduleInfoEntry struct {
+	once sync.Once
+	info *source.ModuleInfo
+	err  error
+}
+
+// modGraphEntry is the module requirement graph of a version of a go.mod
+// file, or the error computing it.
+type modGraphEntry struct {
+	once  sync.Once
+	file  source.FileIdentity
+	graph *source.ModGraph
+	err   error
+}
+
+// tidyDiffEntry is the difference that "go mod tidy" would make to versions
+// of a go.mod file and its go.sum file, or the error computing it. It is
+// computed in the background once the files have not changed for tidyDelay,
+// or as soon as a request waits for it, and done is closed once it is.
+type tidyDiffEntry struct {
+	mod, sum source.FileIdentity
+	now      chan struct{} // closed by the first request that waits
+	nowOnce  sync.Once
+	done     chan struct{}
+	diff     *source.TidyDiff
+	err      error
 }
 
+// tidyDelay is the time for which go.mod and go.sum files must not change
+// before the difference that "go mod tidy" would make to them is computed, so
+// that it is not computed for each edit of the user.
+var tidyDelay = 500 * time.Millisecond
+
 type packageKey struct {
 	mode source.ParseMode
 	id   packageID
@@ -76,6 +151,7 @@ func (s *snapshot) addPackage(cph *checkPackageHandle) {
 	if _, ok := s.packages[cph.packageKey()]; ok {
 		return
 	}
+	cph.touch()
 	s.packages[cph.packageKey()] = cph
 }
 
@@ -91,6 +167,7 @@ func (s *snapshot) getPackages(uri span.URI, m source.ParseMode) (cphs []source.
 			}
 			cph, ok := s.packages[key]
 			if ok {
+				cph.touch()
 				cphs = append(cphs, cph)
 			}
 		}
@@ -106,7 +183,11 @@ func (s *snapshot) getPackage(id packageID, m source.ParseMode) *checkPackageHan
 		id:   id,
 		mode: m,
 	}
-	return s.packages[key]
+	cph := s.packages[key]
+	if cph != nil {
+		cph.touch()
+	}
+	return cph
 }
 
 func (s *snapshot) getActionHandles(id packageID, m source.ParseMode) []*actionHandle {
@@ -211,39 +292,184 @@ func (s *snapshot) getFile(uri span.URI) source.FileHandle {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.files[uri]
+	return s.files.get(uri)
 }
 
 func (s *snapshot) Handle(ctx context.Context, f source.File) source.FileHandle {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.files[f.URI()]; !ok {
-		s.files[f.URI()] = s.view.session.GetFile(f.URI(), f.Kind())
+	fh := s.files.get(f.URI())
+	if fh == nil {
+		fh = s.view.session.GetFile(f.URI(), f.Kind())
+		s.files.set(f.URI(), fh)
+	}
+	return fh
+}
+
+func (s *snapshot) StoreCoverage(coverage []*source.FileCoverage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, fc := range coverage {
+		s.coverage[fc.URI] = fc
+	}
+}
+
+func (s *snapshot) Coverage(uri span.URI) *source.FileCoverage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.coverage[uri]
+}
+
+func (s *snapshot) StoreAPIChanges(changes map[string][]source.APIChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apiChanges = changes
+}
+
+func (s *snapshot) APIChanges(pkgPath string) []source.APIChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.apiChanges[pkgPath]
+}
+
+func (s *snapshot) ModuleInfo(ctx context.Context, path string, fetch func(context.Context) (*source.ModuleInfo, error)) (*source.ModuleInfo, error) {
+	s.mu.Lock()
+	e, ok := s.modules[path]
+	if !ok {
+		e = &moduleInfoEntry{}
+		s.modules[path] = e
+	}
+	s.mu.Unlock()
+
+	e.once.Do(func() {
+		// The result is shared by the later requests, so it must not be
+		// the error of a canceled request.
+		e.info, e.err = fetch(xcontext.Detach(ctx))
+	})
+	return e.info, e.err
+}
+
+func (s *snapshot) ModGraph(ctx context.Context, gomod span.URI, fetch func(context.Context) (*source.ModGraph, error)) (*source.ModGraph, error) {
+	file := s.view.session.GetFile(gomod, source.Mod).Identity()
+
+	s.mu.Lock()
+	e, ok := s.modGraphs[gomod]
+	if !ok || e.file != file {
+		e = &modGraphEntry{file: file}
+		s.modGraphs[gomod] = e
+	}
+	s.mu.Unlock()
+
+	e.once.Do(func() {
+		e.graph, e.err = fetch(xcontext.Detach(ctx))
+	})
+	return e.graph, e.err
+}
+
+func (s *snapshot) TidyDiff(ctx context.Context, gomod span.URI, wait bool, fetch func(context.Context) (*source.TidyDiff, error)) (*source.TidyDiff, error) {
+	mod := s.view.session.GetFile(gomod, source.Mod).Identity()
+	sum := s.view.session.GetFile(span.FileURI(filepath.Join(filepath.Dir(gomod.Filename()), "go.sum")), source.Mod).Identity()
+
+	s.mu.Lock()
+	e, ok := s.tidyDiffs[gomod]
+	if !ok || e.mod != mod || e.sum != sum {
+		e = &tidyDiffEntry{mod: mod, sum: sum, now: make(chan struct{}), done: make(chan struct{})}
+		s.tidyDiffs[gomod] = e
+		// The difference is shared by the later requests, so it must not
+		// be canceled with this one.
+		go s.computeTidyDiff(xcontext.Detach(ctx), gomod, e, fetch)
+	}
+	s.mu.Unlock()
+
+	if wait {
+		e.nowOnce.Do(func() { close(e.now) })
+	}
+	select {
+	case <-e.done:
+		return e.diff, e.err
+	case <-ctx.Done():
+		if !wait {
+			return nil, nil
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// computeTidyDiff computes the difference of e with fetch, once its files
+// have not changed for tidyDelay or a request waits for it.
+func (s *snapshot) computeTidyDiff(ctx context.Context, gomod span.URI, e *tidyDiffEntry, fetch func(context.Context) (*source.TidyDiff, error)) {
+	defer close(e.done)
+
+	timer := time.NewTimer(tidyDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		// No request needs the difference of files that have changed since.
+		if s.view.session.GetFile(gomod, source.Mod).Identity() != e.mod ||
+			s.view.session.GetFile(e.sum.URI, source.Mod).Identity() != e.sum {
+			e.err = errors.Errorf("%s changed before go mod tidy ran", gomod.Filename())
+			return
+		}
+	case <-e.now:
 	}
-	return s.files[f.URI()]
+	e.diff, e.err = fetch(ctx)
 }
 
-func (s *snapshot) clone(ctx context.Context, withoutURI *span.URI, withoutTypes, withoutMetadata map[span.URI]struct{}) *snapshot {
+// clone returns a copy of the snapshot without the file handle of withoutURI,
+// and without the type information and metadata of the packages of the files
+// in withoutTypes and withoutMetadata. The packages that are not copied are
+// recorded in inv, which explains why the new snapshot was created.
+func (s *snapshot) clone(ctx context.Context, withoutURI *span.URI, withoutTypes, withoutMetadata map[span.URI]struct{}, inv *invalidation) *snapshot {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	start := time.Now()
+	inv.snapshot = s.id + 1
 	result := &snapshot{
-		id:         s.id + 1,
-		view:       s.view,
-		ids:        make(map[span.URI][]packageID),
-		importedBy: make(map[packageID][]packageID),
-		metadata:   make(map[packageID]*metadata),
-		packages:   make(map[packageKey]*checkPackageHandle),
-		actions:    make(map[actionKey]*actionHandle),
-		files:      make(map[span.URI]source.FileHandle),
-	}
-	// Copy all of the FileHandles except for the one that was invalidated.
-	for k, v := range s.files {
-		if withoutURI != nil && k == *withoutURI {
-			continue
+		id:            s.id + 1,
+		view:          s.view,
+		invalidation:  inv,
+		ids:           make(map[span.URI][]packageID),
+		importedBy:    make(map[packageID][]packageID),
+		metadata:      make(map[packageID]*metadata),
+		packages:      make(map[packageKey]*checkPackageHandle),
+		actions:       make(map[actionKey]*actionHandle),
+		files:         s.files.clone(),
+		coverage:      make(map[span.URI]*source.FileCoverage),
+		apiChanges:    s.apiChanges,
+		modules:       make(map[string]*moduleInfoEntry),
+		modGraphs:     make(map[span.URI]*modGraphEntry),
+		tidyDiffs:     make(map[span.URI]*tidyDiffEntry),
+		goSumProblems: make(map[span.URI][]source.GoSumProblem),
+	}
+	for k, v := range s.goSumProblems {
+		result.goSumProblems[k] = v
+	}
+	// Copy the module graphs, which are keyed by the versions of their
+	// go.mod files.
+	for k, v := range s.modGraphs {
+		result.modGraphs[k] = v
+	}
+	// Copy the differences of go mod tidy, unless the imports of the
+	// packages may have changed.
+	if len(withoutMetadata) == 0 {
+		for k, v := range s.tidyDiffs {
+			result.tidyDiffs[k] = v
 		}
-		result.files[k] = v
+	}
+	// Share the FileHandles, except for the one that was invalidated.
+	// Only the shard of its directory is copied.
+	if withoutURI != nil {
+		result.files.delete(*withoutURI)
+	}
+	// Copy all of the test coverage.
+	for k, v := range s.coverage {
+		result.coverage[k] = v
 	}
 	// Collect the IDs for the packages associated with the excluded URIs.
 	withoutMetadataIDs := make(map[packageID]struct{})
@@ -268,15 +494,21 @@ func (s *snapshot) clone(ctx context.Context, withoutURI *span.URI, withoutTypes
 		result.ids[k] = ids
 	}
 	// Copy the package type information.
+	dropped := make(map[packageID]struct{})
 	for k, v := range s.packages {
 		if _, ok := withoutTypesIDs[k.id]; ok {
+			dropped[k.id] = struct{}{}
 			continue
 		}
 		if _, ok := withoutMetadataIDs[k.id]; ok {
+			dropped[k.id] = struct{}{}
 			continue
 		}
 		result.packages[k] = v
 	}
+	for id := range dropped {
+		inv.types = append(inv.types, id)
+	}
 	// Copy the package analysis information.
 	for k, v := range s.actions {
 		if _, ok := withoutTypesIDs[k.pkg.id]; ok {
@@ -290,12 +522,16 @@ func (s *snapshot) clone(ctx context.Context, withoutURI *span.URI, withoutTypes
 	// Copy the package metadata.
 	for k, v := range s.metadata {
 		if _, ok := withoutMetadataIDs[k]; ok {
+			inv.metadata = append(inv.metadata, k)
 			continue
 		}
 		result.metadata[k] = v
 	}
 	// Don't bother copying the importedBy graph,
 	// as it changes each time we update metadata.
+
+	copied := result.files.shardCount() + len(s.coverage) + len(s.ids) + len(s.packages) + len(s.actions) + len(s.metadata)
+	s.view.stats.recordClone(time.Since(start), copied)
 	return result
 }
 
@@ -324,7 +560,7 @@ func (v *view) invalidateContent(ctx context.Context, f source.File, kind source
 		// of all of the files in the same directory as this one.
 		// TODO(rstambler): Speed this up by mapping directories to filenames.
 		if dirStat, err := os.Stat(dir(f.URI().Filename())); err == nil {
-			for uri := range v.snapshot.files {
+			v.snapshot.files.forEach(func(uri span.URI, _ source.FileHandle) {
 				if fdirStat, err := os.Stat(dir(uri.Filename())); err == nil {
 					if os.SameFile(dirStat, fdirStat) {
 						for _, id := range v.snapshot.ids[uri] {
@@ -332,7 +568,7 @@ func (v *view) invalidateContent(ctx context.Context, f source.File, kind source
 						}
 					}
 				}
-			}
+			})
 		}
 	}
 
@@ -358,14 +594,21 @@ func (v *view) invalidateContent(ctx context.Context, f source.File, kind source
 
 	// Check if the file's package name or imports have changed,
 	// and if so, invalidate metadata.
+	inv := &invalidation{
+		uri:    f.URI(),
+		kind:   kind,
+		change: changeType,
+	}
 	if v.session.cache.shouldLoad(ctx, v.snapshot, originalFH, currentFH) {
 		withoutMetadata = withoutTypes
+		inv.metadataChanged = true
 
 		// TODO: If a package's name has changed,
 		// we should invalidate the metadata for the new package name (if it exists).
 	}
 	uri := f.URI()
-	v.snapshot = v.snapshot.clone(ctx, &uri, withoutTypes, withoutMetadata)
+	v.snapshot = v.snapshot.clone(ctx, &uri, withoutTypes, withoutMetadata, inv)
+	v.recordInvalidation(inv)
 	return true
 }
 
@@ -0,0 +1,78 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/internal/span"
+)
+
+// adHocPackageID is the ID that go list gives the package of the files named
+// on its command line.
+const adHocPackageID = "command-line-arguments"
+
+// Standalone reports whether the Go file with the given URI is a standalone
+// file: one that is neither in a module, nor in a GOPATH, nor in GOROOT.
+// Such a file is loaded on its own, as an ad hoc package whose imports are
+// resolved in GOPATH mode, which finds those of the standard library.
+func (v *view) Standalone(uri span.URI) bool {
+	filename := uri.Filename()
+	if !strings.HasSuffix(filename, ".go") {
+		return false
+	}
+	for dir := filepath.Dir(filename); ; {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	env := v.Options().Env
+	getenv := func(key, fallback string) string {
+		for i := len(env) - 1; i >= 0; i-- {
+			if kv := env[i]; strings.HasPrefix(kv, key+"=") {
+				return kv[len(key)+1:]
+			}
+		}
+		return fallback
+	}
+	roots := []string{getenv("GOROOT", build.Default.GOROOT)}
+	for _, dir := range filepath.SplitList(getenv("GOPATH", build.Default.GOPATH)) {
+		roots = append(roots, filepath.Join(dir, "src"))
+	}
+	for _, root := range roots {
+		if root != "" && strings.HasPrefix(filename, root+string(filepath.Separator)) {
+			return false
+		}
+	}
+	return true
+}
+
+// standaloneConfig returns the configuration that loads the standalone file
+// filename: it runs the go command in the directory of the file, in GOPATH
+// mode.
+func standaloneConfig(cfg *packages.Config, filename string) *packages.Config {
+	standalone := *cfg
+	standalone.Dir = filepath.Dir(filename)
+	standalone.Env = append(append([]string{}, cfg.Env...), "GO111MODULE=off")
+	return &standalone
+}
+
+// renameAdHocPackages gives the ad hoc packages of the standalone file
+// filename, and their test variants, IDs of their own, as the ad hoc packages
+// of all standalone files have the same ID.
+func renameAdHocPackages(pkgs []*packages.Package, filename string) {
+	for _, pkg := range pkgs {
+		pkg.ID = strings.Replace(pkg.ID, adHocPackageID, adHocPackageID+"@"+filename, -1)
+	}
+}
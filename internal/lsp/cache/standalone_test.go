@@ -0,0 +1,44 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+func TestStandalone(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"mod/go.mod": "module example.com/mod\n",
+	})
+	defer os.RemoveAll(dir)
+
+	gopath := filepath.Join(dir, "gopath")
+	mod := filepath.Join(dir, "mod")
+
+	v := &view{options: source.Options{
+		Env: []string{"GOPATH=" + gopath, "GOROOT=" + filepath.Join(dir, "goroot")},
+	}}
+	for _, tt := range []struct {
+		filename string
+		want     bool
+	}{
+		{filepath.Join(dir, "main.go"), true},
+		{filepath.Join(dir, "other", "main.go"), true},
+		{filepath.Join(dir, "go.mod"), false},
+		{filepath.Join(mod, "main.go"), false},
+		{filepath.Join(mod, "sub", "main.go"), false},
+		{filepath.Join(gopath, "src", "example.com", "main.go"), false},
+		{filepath.Join(dir, "goroot", "src", "fmt", "print.go"), false},
+	} {
+		if got := v.Standalone(span.FileURI(tt.filename)); got != tt.want {
+			t.Errorf("Standalone(%s) = %v, want %v", tt.filename, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/source"
+)
+
+// viewStats accumulates the work done on behalf of a view.
+// Its fields are updated atomically.
+type viewStats struct {
+	loads         int64
+	loadTime      int64
+	typeChecks    int64
+	typeCheckTime int64
+	clones        int64
+	cloneTime     int64
+	clonedEntries int64
+}
+
+func (s *viewStats) recordLoad(d time.Duration) {
+	atomic.AddInt64(&s.loads, 1)
+	atomic.AddInt64(&s.loadTime, int64(d))
+}
+
+func (s *viewStats) recordTypeCheck(d time.Duration) {
+	atomic.AddInt64(&s.typeChecks, 1)
+	atomic.AddInt64(&s.typeCheckTime, int64(d))
+}
+
+func (s *viewStats) recordClone(d time.Duration, entries int) {
+	atomic.AddInt64(&s.clones, 1)
+	atomic.AddInt64(&s.cloneTime, int64(d))
+	atomic.AddInt64(&s.clonedEntries, int64(entries))
+}
+
+func (v *view) Stats() source.ViewStats {
+	result := source.ViewStats{
+		Name:          v.name,
+		Folder:        v.folder,
+		Loads:         atomic.LoadInt64(&v.stats.loads),
+		LoadTime:      time.Duration(atomic.LoadInt64(&v.stats.loadTime)),
+		TypeChecks:    atomic.LoadInt64(&v.stats.typeChecks),
+		TypeCheckTime: time.Duration(atomic.LoadInt64(&v.stats.typeCheckTime)),
+		Clones:        atomic.LoadInt64(&v.stats.clones),
+		CloneTime:     time.Duration(atomic.LoadInt64(&v.stats.cloneTime)),
+		ClonedEntries: atomic.LoadInt64(&v.stats.clonedEntries),
+	}
+	s := v.getSnapshot()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cph := range s.packages {
+		// Only count the packages that have been type-checked, without
+		// forcing the others to be.
+		pkg, err := cph.cached()
+		if err != nil || pkg == nil {
+			continue
+		}
+		result.Packages++
+		result.SourceBytes += pkg.sourceSize
+	}
+	return result
+}
+
+// Sessions returns the sessions of the cache that have not been shut down.
+func (c *cache) Sessions() []source.Session {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	result := make([]source.Session, len(c.sessions))
+	for i, s := range c.sessions {
+		result[i] = s
+	}
+	return result
+}
+
+func (c *cache) addSession(s *session) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	c.sessions = append(c.sessions, s)
+}
+
+func (c *cache) removeSession(s *session) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	for i, existing := range c.sessions {
+		if existing == s {
+			c.sessions = append(c.sessions[:i], c.sessions[i+1:]...)
+			return
+		}
+	}
+}
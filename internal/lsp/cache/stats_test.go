@@ -0,0 +1,44 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestViewStats(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	dir := writeModule(t)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	c := New(nil)
+	session := c.NewSession(ctx)
+	view := session.NewView(ctx, "stats_test", span.FileURI(dir), source.DefaultOptions)
+	checkModule(ctx, t, view, dir)
+
+	stats := source.Stats(c)
+	if len(stats.Sessions) != 1 || stats.Sessions[0].ID != session.ID() || len(stats.Sessions[0].Views) != 1 {
+		t.Fatalf("got stats %+v, want one session with one view", stats)
+	}
+	vs := stats.Sessions[0].Views[0]
+	if vs.Loads == 0 || vs.TypeChecks == 0 {
+		t.Errorf("got %d loads and %d type checks, want at least one of each", vs.Loads, vs.TypeChecks)
+	}
+	if vs.Packages != 1 || vs.SourceBytes != int64(len(moduleSource)) {
+		t.Errorf("got %d packages of %d bytes, want 1 of %d", vs.Packages, vs.SourceBytes, len(moduleSource))
+	}
+
+	session.Shutdown(ctx)
+	if sessions := c.Sessions(); len(sessions) != 0 {
+		t.Errorf("got %d sessions after shutdown, want none", len(sessions))
+	}
+}
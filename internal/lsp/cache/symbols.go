@@ -0,0 +1,132 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// WorkspaceSymbols returns the symbols declared by the Go files in the folder
+// of the view. They are indexed along with the references of WorkspaceXrefs,
+// so only the files that changed since the last request are parsed again.
+func (s *snapshot) WorkspaceSymbols(ctx context.Context) ([]source.Symbol, error) {
+	ctx, done := trace.StartSpan(ctx, "cache.snapshot.WorkspaceSymbols", telemetry.URI.Of(s.view.folder))
+	defer done()
+
+	files, err := s.workspaceFileXrefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var result []source.Symbol
+	for _, xrefs := range files {
+		result = append(result, xrefs.symbols...)
+	}
+	return result, nil
+}
+
+// fileSymbols returns the symbols declared by file: its package-level
+// declarations, the methods of its types, and the fields and methods of its
+// struct and interface types.
+func fileSymbols(fset *token.FileSet, file *ast.File, m *protocol.ColumnMapper) []source.Symbol {
+	var (
+		symbols []source.Symbol
+		ranges  []span.Range
+	)
+	pkg := file.Name.Name
+	add := func(name *ast.Ident, container string, kind protocol.SymbolKind) {
+		if name == nil || name.Name == "_" {
+			return
+		}
+		symbols = append(symbols, source.Symbol{
+			Name:      name.Name,
+			Container: container,
+			Kind:      kind,
+		})
+		ranges = append(ranges, span.NewRange(fset, name.Pos(), name.End()))
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil || len(decl.Recv.List) == 0 {
+				add(decl.Name, pkg, protocol.Function)
+				break
+			}
+			container := pkg
+			if recv := receiverName(decl.Recv.List[0].Type); recv != "" {
+				container = pkg + "." + recv
+			}
+			add(decl.Name, container, protocol.Method)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					container := pkg + "." + spec.Name.Name
+					switch t := spec.Type.(type) {
+					case *ast.StructType:
+						add(spec.Name, pkg, protocol.Struct)
+						for _, field := range t.Fields.List {
+							for _, name := range field.Names {
+								add(name, container, protocol.Field)
+							}
+						}
+					case *ast.InterfaceType:
+						add(spec.Name, pkg, protocol.Interface)
+						for _, method := range t.Methods.List {
+							for _, name := range method.Names {
+								add(name, container, protocol.Method)
+							}
+						}
+					default:
+						add(spec.Name, pkg, protocol.Class)
+					}
+				case *ast.ValueSpec:
+					kind := protocol.Variable
+					if decl.Tok == token.CONST {
+						kind = protocol.Constant
+					}
+					for _, name := range spec.Names {
+						add(name, pkg, kind)
+					}
+				}
+			}
+		}
+	}
+	// A file declares many symbols, so their ranges are converted at once.
+	protocolRanges, err := m.RangesToUTF16(ranges)
+	if err != nil {
+		return nil
+	}
+	for i := range symbols {
+		symbols[i].Location = protocol.Location{URI: protocol.NewURI(m.URI), Range: protocolRanges[i]}
+	}
+	return symbols
+}
+
+// receiverName returns the name of the type of a method receiver, such as T
+// for *T or T[P], or "" if it has none.
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+func TestTidyDiff(t *testing.T) {
+	// The module requires versions that x/tools requires, so that they are in
+	// the module cache, but does not import golang.org/x/xerrors.
+	const gomod = `module example.com/m
+
+go 1.12
+
+require (
+	golang.org/x/sync v0.0.0-20190423024810-112230192c58
+	golang.org/x/xerrors v0.0.0-20190717185122-a985d3407aa7
+)
+`
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"go.mod": gomod,
+		"a.go":   "package m\n\nimport _ \"golang.org/x/sync/errgroup\"\n",
+	}, func(options *source.Options) {
+		options.Env = append(os.Environ(), "GOFLAGS=-mod=readonly", "GOPROXY=off")
+	})
+	defer cleanup()
+	ctx := context.Background()
+	uri := span.FileURI(filepath.Join(dir, "go.mod"))
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The lens waits for go mod tidy, which runs in the background.
+	lenses, err := source.ModCodeLens(ctx, view, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lenses) != 2 {
+		t.Fatalf("got lenses %v, want 2", lenses)
+	}
+	if got, want := lenses[1].Command.Title, "go mod tidy would change go.mod and go.sum: apply"; got != want {
+		t.Errorf("got lens %q, want %q", got, want)
+	}
+	diff, err := source.ModTidyDiff(ctx, view, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fix := []source.SuggestedFix{{Title: source.ApplyTidyTitle, Edits: diff.Edits}}
+	tidy := applyFixes(t, gomod, uri, fix, source.ApplyTidyTitle)
+	if strings.Contains(tidy, "xerrors") || !strings.Contains(tidy, "golang.org/x/sync") {
+		t.Errorf("go mod tidy would change go.mod to:\n%s", tidy)
+	}
+	// The go.sum file does not exist, so it is written as a whole.
+	sum := span.FileURI(filepath.Join(dir, "go.sum"))
+	edits := diff.Edits[sum]
+	if len(edits) != 1 || edits[0].Range != (protocol.Range{}) || !strings.HasPrefix(edits[0].NewText, "golang.org/x/sync v0.0.0-20190423024810-112230192c58 h1:") {
+		t.Fatalf("got go.sum edits %v", edits)
+	}
+	// The files themselves are left untouched.
+	if _, err := os.Stat(sum.Filename()); !os.IsNotExist(err) {
+		t.Errorf("go.sum was written: %v", err)
+	}
+
+	// Once the edits are saved, the module is tidy.
+	for filename, content := range map[string]string{uri.Filename(): tidy, sum.Filename(): edits[0].NewText} {
+		if err := ioutil.WriteFile(filename, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lenses, err = source.ModCodeLens(ctx, view, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lenses) != 2 || lenses[1].Command.Title != "go.mod and go.sum are tidy" || lenses[1].Command.Command != "" {
+		t.Errorf("got lenses %v for the tidy module", lenses)
+	}
+}
+
+func TestTidyDiffBackground(t *testing.T) {
+	defer func(delay time.Duration) { tidyDelay = delay }(tidyDelay)
+	tidyDelay = time.Hour
+
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"go.mod": "module example.com/m\n",
+	}, func(options *source.Options) {
+		options.FileCache = false
+	})
+	defer cleanup()
+	uri := span.FileURI(filepath.Join(dir, "go.mod"))
+	ctx := context.Background()
+
+	var fetches int32
+	want := &source.TidyDiff{}
+	fetch := func(context.Context) (*source.TidyDiff, error) {
+		atomic.AddInt32(&fetches, 1)
+		return want, nil
+	}
+	// Until a request waits for it, the difference is pending.
+	for i := 0; i < 2; i++ {
+		pendingCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		diff, err := view.Snapshot().TidyDiff(pendingCtx, uri, false, fetch)
+		cancel()
+		if diff != nil || err != nil {
+			t.Fatalf("got %v, %v for a pending difference, want nil", diff, err)
+		}
+	}
+	if diff, err := view.Snapshot().TidyDiff(ctx, uri, true, fetch); diff != want || err != nil {
+		t.Fatalf("got %v, %v, want the difference", diff, err)
+	}
+	if diff, err := view.Snapshot().TidyDiff(ctx, uri, false, fetch); diff != want || err != nil {
+		t.Fatalf("got %v, %v once the difference is computed, want it", diff, err)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("the difference was computed %d times, want 1", n)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/source"
+)
+
+// inconsistentVendoringRegexp matches the error of the go command about a
+// vendor/modules.txt file that disagrees with go.mod, capturing the
+// directory of the module.
+var inconsistentVendoringRegexp = regexp.MustCompile(`inconsistent vendoring in (.+):\n`)
+
+// inconsistentVendoring returns the error of go/packages.Load as an
+// *source.InconsistentVendoringError if it reports that the vendor directory
+// of a module is out of sync with its go.mod file, and nil otherwise.
+func inconsistentVendoring(err error) *source.InconsistentVendoringError {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	matches := inconsistentVendoringRegexp.FindStringSubmatch(msg)
+	if matches == nil {
+		return nil
+	}
+	return &source.InconsistentVendoringError{
+		Dir: matches[1],
+		Msg: goCommandOutput(msg, "go: inconsistent vendoring"),
+	}
+}
+
+// goCommandOutput returns the output of the go command in msg, the text of an
+// error of go/packages.Load, starting at the first of prefixes that it
+// contains. go/packages prefixes the output with the command line, which
+// isn't useful to users.
+func goCommandOutput(msg string, prefixes ...string) string {
+	for _, prefix := range prefixes {
+		if i := strings.Index(msg, prefix); i >= 0 {
+			msg = msg[i:]
+			break
+		}
+	}
+	return strings.TrimSpace(msg)
+}
+
+// modFlagRegexp matches the -mod flag of the go command, capturing its value.
+var modFlagRegexp = regexp.MustCompile(`(?:^|\s)-mod[ =](\w+)`)
+
+// withModFlag returns goflags, the value of GOFLAGS, with the -mod flag of
+// buildFlags, if any, so that the modules of packages found by goimports, such
+// as the vendor directory in -mod=vendor mode, are those of the packages that
+// go/packages loads. An explicit -mod flag in GOFLAGS is left alone.
+func withModFlag(goflags string, buildFlags []string) string {
+	if modFlagRegexp.MatchString(goflags) {
+		return goflags
+	}
+	matches := modFlagRegexp.FindStringSubmatch(strings.Join(buildFlags, " "))
+	if matches == nil {
+		return goflags
+	}
+	return strings.TrimSpace(goflags + " -mod=" + matches[1])
+}
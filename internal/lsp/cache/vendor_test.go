@@ -0,0 +1,48 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInconsistentVendoring(t *testing.T) {
+	err := errors.New("go [list -e -json -compiled=true -test=true -export=false -deps=true -find=false -- file=/m/a.go]: exit status 1: go: inconsistent vendoring in /m:\n" +
+		"\tgolang.org/x/text@v0.3.2: is explicitly required in go.mod, but not marked as explicit in vendor/modules.txt\n\n" +
+		"\trun 'go mod vendor' to sync, or use -mod=mod or -mod=readonly to ignore the vendor directory\n")
+	vendorErr := inconsistentVendoring(err)
+	if vendorErr == nil {
+		t.Fatalf("inconsistentVendoring(%q) = nil", err)
+	}
+	if vendorErr.Dir != "/m" {
+		t.Errorf("got directory %q, want /m", vendorErr.Dir)
+	}
+	if want := "go: inconsistent vendoring in /m:"; vendorErr.Msg[:len(want)] != want {
+		t.Errorf("got message %q, want prefix %q", vendorErr.Msg, want)
+	}
+
+	if vendorErr := inconsistentVendoring(errors.New("go: cannot find main module")); vendorErr != nil {
+		t.Errorf("inconsistentVendoring reported %v for an unrelated error", vendorErr)
+	}
+}
+
+func TestWithModFlag(t *testing.T) {
+	for _, tt := range []struct {
+		goflags    string
+		buildFlags []string
+		want       string
+	}{
+		{"", nil, ""},
+		{"", []string{"-tags=foo", "-mod=vendor"}, "-mod=vendor"},
+		{"-tags=bar", []string{"-mod", "vendor"}, "-tags=bar -mod=vendor"},
+		{"-mod=mod", []string{"-mod=vendor"}, "-mod=mod"},
+		{"", []string{"-modfile=alt.mod"}, ""},
+	} {
+		if got := withModFlag(tt.goflags, tt.buildFlags); got != tt.want {
+			t.Errorf("withModFlag(%q, %q) = %q, want %q", tt.goflags, tt.buildFlags, got, tt.want)
+		}
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"go/token"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -20,6 +22,7 @@ import (
 	"golang.org/x/tools/internal/lsp/debug"
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/telemetry"
 	"golang.org/x/tools/internal/span"
 	"golang.org/x/tools/internal/telemetry/log"
 	"golang.org/x/tools/internal/xcontext"
@@ -30,7 +33,13 @@ type view struct {
 	session *session
 	id      string
 
-	options source.Options
+	// optionsMu guards options and dirFilter. It is not mu, which is held
+	// while running code that reads the options, as in RunProcessEnvFunc.
+	optionsMu sync.Mutex
+	options   source.Options
+
+	// dirFilter is the directory filter of the folder for options.
+	dirFilter *source.DirectoryFilter
 
 	// mu protects all mutable state of the view.
 	mu sync.Mutex
@@ -53,6 +62,10 @@ type view struct {
 	// Folder is the root of this view.
 	folder span.URI
 
+	// parent is the view of the workspace folder in which the module of this
+	// view is nested, or nil if this is the view of a workspace folder.
+	parent *view
+
 	// process is the process env for this view.
 	// Note: this contains cached module and filesystem state.
 	//
@@ -74,12 +87,34 @@ type view struct {
 	snapshotMu sync.Mutex
 	snapshot   *snapshot
 
+	// invalidations records why the most recent snapshots were created.
+	invalidationMu sync.Mutex
+	invalidations  []*invalidation
+
+	// xrefs caches the references of the Go files in the folder of the view
+	// to the packages they import.
+	xrefsMu sync.Mutex
+	xrefs   map[span.URI]*fileXrefs
+
+	// decls caches the declarations of the files of the dependencies of
+	// the packages of the view, as indexed for definitions.
+	declsMu sync.Mutex
+	decls   map[span.URI]*fileDecls
+
 	// builtin is used to resolve builtin types.
 	builtin *builtinPkg
 
 	// ignoredURIs is the set of URIs of files that we ignore.
 	ignoredURIsMu sync.Mutex
 	ignoredURIs   map[span.URI]struct{}
+
+	// stats accumulates the work done on behalf of the view.
+	stats viewStats
+
+	// matrix holds the views of the build matrix of the view, by build
+	// configuration.
+	matrixMu sync.Mutex
+	matrix   map[string]*view
 }
 
 func (v *view) Session() source.Session {
@@ -97,22 +132,70 @@ func (v *view) Folder() span.URI {
 }
 
 func (v *view) Options() source.Options {
+	v.optionsMu.Lock()
+	defer v.optionsMu.Unlock()
 	return v.options
 }
 
 func (v *view) SetOptions(options source.Options) {
+	filter := v.newDirectoryFilter(v.baseCtx, options)
+	v.optionsMu.Lock()
 	v.options = options
+	v.dirFilter = filter
+	v.optionsMu.Unlock()
+	v.dropMatrix(v.baseCtx)
+	if options.MemoryLimit > 0 {
+		v.session.cache.watchMemory()
+	}
+	v.session.cache.parseCache.setCapacity(v.baseCtx, options.ParseCacheSize)
+	v.session.cache.setFileCache(v.baseCtx, options)
+}
+
+// SetBuildConfiguration changes the build configuration of the view, and
+// discards all of the metadata and type information of its snapshot, which
+// depend on it. It returns the URIs of the open files whose packages must be
+// loaded and checked again.
+func (v *view) SetBuildConfiguration(ctx context.Context, c source.BuildConfiguration) []span.URI {
+	v.optionsMu.Lock()
+	v.options.BuildConfiguration = c
+	v.optionsMu.Unlock()
+	v.mu.Lock()
+	// The process env caches the state of the previous configuration.
+	v.processEnv = nil
+	v.mu.Unlock()
+	v.dropMatrix(ctx)
+
+	v.snapshotMu.Lock()
+	defer v.snapshotMu.Unlock()
+
+	var open []span.URI
+	all := make(map[span.URI]struct{})
+	v.snapshot.mu.Lock()
+	for uri := range v.snapshot.ids {
+		all[uri] = struct{}{}
+		if v.session.IsOpen(uri) {
+			open = append(open, uri)
+		}
+	}
+	v.snapshot.mu.Unlock()
+
+	inv := &invalidation{metadataChanged: true}
+	v.snapshot = v.snapshot.clone(ctx, nil, all, all, inv)
+	v.recordInvalidation(inv)
+	return open
 }
 
 // Config returns the configuration used for the view's interaction with the
 // go/packages API. It is shared across all views.
 func (v *view) Config(ctx context.Context) *packages.Config {
 	// TODO: Should we cache the config and/or overlay somewhere?
+	options := v.Options()
+	options = options.BuildConfiguration.Apply(options)
 	return &packages.Config{
 		Dir:        v.folder.Filename(),
 		Context:    ctx,
-		Env:        v.options.Env,
-		BuildFlags: v.options.BuildFlags,
+		Env:        options.Env,
+		BuildFlags: options.BuildFlags,
 		Mode: packages.NeedName |
 			packages.NeedFiles |
 			packages.NeedCompiledGoFiles |
@@ -193,6 +276,8 @@ func (v *view) buildProcessEnv(ctx context.Context) (*imports.ProcessEnv, error)
 		}
 	}
 
+	env.GOFLAGS = withModFlag(env.GOFLAGS, cfg.BuildFlags)
+
 	if env.GOPATH == "" {
 		cmd := exec.CommandContext(ctx, "go", "env", "GOPATH")
 		cmd.Env = cfg.Env
@@ -244,7 +329,8 @@ func (v *view) Shutdown(ctx context.Context) {
 	v.session.removeView(ctx, v)
 }
 
-func (v *view) shutdown(context.Context) {
+func (v *view) shutdown(ctx context.Context) {
+	v.dropMatrix(ctx)
 	v.mu.Lock()
 	defer v.mu.Unlock()
 	if v.cancel != nil {
@@ -254,6 +340,22 @@ func (v *view) shutdown(context.Context) {
 	debug.DropView(debugView{v})
 }
 
+func (v *view) DirectoryFilter() *source.DirectoryFilter {
+	v.optionsMu.Lock()
+	defer v.optionsMu.Unlock()
+	return v.dirFilter
+}
+
+// newDirectoryFilter returns the directory filter of the folder of the view
+// for options. An invalid filter is logged, and the others still apply.
+func (v *view) newDirectoryFilter(ctx context.Context, options source.Options) *source.DirectoryFilter {
+	filter, err := source.NewDirectoryFilter(v.folder.Filename(), options)
+	if err != nil {
+		log.Error(ctx, "invalid directory filters", err, telemetry.URI.Of(v.folder))
+	}
+	return filter
+}
+
 // Ignore checks if the given URI is a URI we ignore.
 // As of right now, we only ignore files in the "builtin" package.
 func (v *view) Ignore(uri span.URI) bool {
@@ -310,7 +412,7 @@ func (v *view) SetContent(ctx context.Context, uri span.URI, content []byte) (bo
 		return false, nil
 	}
 
-	kind := source.DetectLanguage("", uri.Filename())
+	kind := v.Options().DetectLanguage("", uri.Filename())
 	return v.session.SetOverlay(uri, kind, content), nil
 }
 
@@ -332,7 +434,7 @@ func (v *view) GetFile(ctx context.Context, uri span.URI) (source.File, error) {
 	defer v.mu.Unlock()
 
 	// TODO(rstambler): Should there be a version that provides a kind explicitly?
-	kind := source.DetectLanguage("", uri.Filename())
+	kind := v.Options().DetectLanguage("", uri.Filename())
 	return v.getFile(ctx, uri, kind)
 }
 
@@ -422,3 +524,40 @@ type debugView struct{ *view }
 
 func (v debugView) ID() string             { return v.id }
 func (v debugView) Session() debug.Session { return debugSession{v.session} }
+func (v debugView) SnapshotDiffs() []*debug.SnapshotDiff {
+	return v.snapshotDiffs()
+}
+func (v debugView) SnapshotDiff(from, to uint64) (*debug.SnapshotDiff, error) {
+	return v.snapshotDiff(from, to)
+}
+
+// ModGraph returns the module requirement graph of the go.mod file of the
+// folder of the view, with the requirement paths to module if it is not empty.
+// If module is not required, the graph is returned with the error.
+func (v debugView) ModGraph(ctx context.Context, module string) (*debug.ModGraph, error) {
+	gomod := span.FileURI(filepath.Join(v.folder.Filename(), "go.mod"))
+	graph, err := source.ModuleGraph(ctx, v.view, gomod, "")
+	if err != nil {
+		return nil, err
+	}
+	result := &debug.ModGraph{GoMod: gomod, Main: graph.Main, Module: module}
+	for m, reqs := range graph.Requires {
+		result.Modules = append(result.Modules, debug.ModRequirements{Module: m, Requires: reqs})
+	}
+	sort.Slice(result.Modules, func(i, j int) bool {
+		// The main module comes first.
+		x, y := result.Modules[i].Module, result.Modules[j].Module
+		if (x == graph.Main) != (y == graph.Main) {
+			return x == graph.Main
+		}
+		return x < y
+	})
+	if module != "" {
+		why, err := source.ModuleGraph(ctx, v.view, gomod, module)
+		if err != nil {
+			return result, err
+		}
+		result.Why = why.Why
+	}
+	return result, nil
+}
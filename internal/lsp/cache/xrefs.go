@@ -0,0 +1,299 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// fileXrefs holds the references that a version of a file makes to the
+// package-level objects of the packages it imports, the objects that its
+// //go:linkname directives refer to, the symbols it declares, the methods of
+// the types of its package that it declares, and the paths it imports.
+type fileXrefs struct {
+	identity  source.FileIdentity
+	imports   []string
+	refs      map[source.Xref]source.XrefUse
+	linknames map[source.Xref][]protocol.Location
+	symbols   []source.Symbol
+	methods   *fileMethods
+}
+
+// WorkspaceXrefs returns the package-level objects referred to by the Go
+// files in the folder of the view, from outside of their own packages, with
+// the ways in which the files use them.
+//
+// The references of a file are found syntactically, as the selectors whose
+// operand is the name of an import, and are cached by the view until the file
+// changes, so that only the files that changed are parsed again.
+func (s *snapshot) WorkspaceXrefs(ctx context.Context) (map[source.Xref]source.XrefUse, error) {
+	ctx, done := trace.StartSpan(ctx, "cache.snapshot.WorkspaceXrefs", telemetry.URI.Of(s.view.folder))
+	defer done()
+
+	files, err := s.workspaceFileXrefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[source.Xref]source.XrefUse)
+	for _, xrefs := range files {
+		for ref, use := range xrefs.refs {
+			result[ref] |= use
+		}
+	}
+	return result, nil
+}
+
+// WorkspaceLinknames returns the locations of the //go:linkname directives
+// of the Go files in the folder of the view, by the object they refer to.
+// The directives are indexed along with the references of WorkspaceXrefs.
+func (s *snapshot) WorkspaceLinknames(ctx context.Context) (map[source.Xref][]protocol.Location, error) {
+	ctx, done := trace.StartSpan(ctx, "cache.snapshot.WorkspaceLinknames", telemetry.URI.Of(s.view.folder))
+	defer done()
+
+	files, err := s.workspaceFileXrefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[source.Xref][]protocol.Location)
+	for _, xrefs := range files {
+		for ref, locations := range xrefs.linknames {
+			result[ref] = append(result[ref], locations...)
+		}
+	}
+	return result, nil
+}
+
+// workspaceFileXrefs returns the references of each Go file in the folder of
+// the view.
+func (s *snapshot) workspaceFileXrefs(ctx context.Context) ([]*fileXrefs, error) {
+	var uris []span.URI
+	err := filepath.Walk(s.view.folder.Filename(), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != s.view.folder.Filename() && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".go") {
+			uris = append(uris, span.FileURI(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*fileXrefs
+	seen := make(map[span.URI]struct{}, len(uris))
+	for _, uri := range uris {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		seen[uri] = struct{}{}
+		xrefs, err := s.view.fileXrefs(ctx, s.view.session.GetFile(uri, source.Go))
+		if err != nil {
+			// Files that cannot be parsed refer to nothing.
+			continue
+		}
+		result = append(result, xrefs)
+	}
+
+	// Forget the files that no longer exist.
+	s.view.xrefsMu.Lock()
+	for uri := range s.view.xrefs {
+		if _, ok := seen[uri]; !ok {
+			delete(s.view.xrefs, uri)
+		}
+	}
+	s.view.xrefsMu.Unlock()
+
+	return result, nil
+}
+
+// fileXrefs returns the references of the file fh, computing them if the
+// view has none for its version and the file cache has none for its content.
+func (v *view) fileXrefs(ctx context.Context, fh source.FileHandle) (*fileXrefs, error) {
+	uri := fh.Identity().URI
+	v.xrefsMu.Lock()
+	xrefs, ok := v.xrefs[uri]
+	v.xrefsMu.Unlock()
+	if ok && xrefs.identity == fh.Identity() {
+		return xrefs, nil
+	}
+
+	// The indexes of the files that have not changed since the last
+	// session are in the file cache, if it is enabled.
+	fc := v.session.cache.getFileCache()
+	var key [32]byte
+	if fc != nil {
+		if _, hash, err := fh.Read(ctx); err == nil {
+			key = xrefsKey(hash)
+			xrefs = readFileXrefs(ctx, fc, key, fh)
+		} else {
+			fc = nil
+		}
+	}
+	if xrefs == nil {
+		file, m, _, err := v.session.ParseGoHandle(fh, source.ParseFull).Parse(ctx)
+		if file == nil {
+			return nil, err
+		}
+		xrefs = &fileXrefs{
+			identity:  fh.Identity(),
+			imports:   importPaths(file),
+			refs:      importedReferences(file),
+			linknames: linknames(v.session.cache.FileSet(), file, m),
+			symbols:   fileSymbols(v.session.cache.FileSet(), file, m),
+			methods:   methodSets(v.session.cache.FileSet(), file, m),
+		}
+		if fc != nil {
+			writeFileXrefs(ctx, fc, key, xrefs)
+		}
+	}
+	v.xrefsMu.Lock()
+	if v.xrefs == nil {
+		v.xrefs = make(map[span.URI]*fileXrefs)
+	}
+	v.xrefs[uri] = xrefs
+	v.xrefsMu.Unlock()
+	return xrefs, nil
+}
+
+// importedReferences returns the references of file to the package-level
+// objects of the packages it imports, with the ways in which file uses them.
+// Names that are shadowed locally are not told apart from the imports.
+func importedReferences(file *ast.File) map[source.Xref]source.XrefUse {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := assumedPackageName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		imports[name] = importPath
+	}
+	refs := make(map[source.Xref]source.XrefUse)
+	if len(imports) == 0 {
+		return refs
+	}
+	// The stack holds the ancestors of the current node, outermost first.
+	var stack []ast.Node
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok {
+				if importPath, ok := imports[x.Name]; ok {
+					parents := []ast.Node{sel}
+					for i := len(stack) - 1; i >= 0; i-- {
+						parents = append(parents, stack[i])
+					}
+					ref := source.Xref{PkgPath: importPath, Name: sel.Sel.Name}
+					refs[ref] |= xrefUse(source.ClassifyReference(sel.Sel, parents))
+				}
+			}
+		}
+		stack = append(stack, n)
+		return true
+	})
+	return refs
+}
+
+// xrefUse returns the use of an Xref by a reference of the given kind.
+func xrefUse(kind source.ReferenceKind) source.XrefUse {
+	switch kind {
+	case source.ReferenceWrite, source.ReferenceDeclaration:
+		return source.XrefWrite
+	case source.ReferenceAddress:
+		return source.XrefAddress
+	}
+	return source.XrefRead
+}
+
+// importPaths returns the paths of the imports of file.
+func importPaths(file *ast.File) []string {
+	var paths []string
+	for _, spec := range file.Imports {
+		if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+			paths = append(paths, importPath)
+		}
+	}
+	return paths
+}
+
+// linknames returns the locations of the targets of the //go:linkname
+// directives of file, by the object they refer to.
+func linknames(fset *token.FileSet, file *ast.File, m *protocol.ColumnMapper) map[source.Xref][]protocol.Location {
+	var result map[source.Xref][]protocol.Location
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			target, pos, end, ok := source.LinknameTarget(c)
+			if !ok {
+				continue
+			}
+			spn, err := span.NewRange(fset, pos, end).Span()
+			if err != nil {
+				continue
+			}
+			location, err := m.Location(spn)
+			if err != nil {
+				continue
+			}
+			if result == nil {
+				result = make(map[source.Xref][]protocol.Location)
+			}
+			result[target] = append(result[target], location)
+		}
+	}
+	return result
+}
+
+// assumedPackageName returns the name of the package with the given import
+// path, as guessed from the path alone: the last element of the path that does
+// not look like a major version, without a "go-" prefix and up to its first
+// character that cannot be part of an identifier.
+func assumedPackageName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			if dir := path.Dir(importPath); dir != "." {
+				base = path.Base(dir)
+			}
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
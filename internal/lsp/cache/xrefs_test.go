@@ -0,0 +1,208 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+)
+
+func TestUnusedExports(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	dir, view, cleanup := newTestModule(t, map[string]string{
+		"go.mod": "module example.com/m\n",
+		"a/a.go": `package a
+
+// Used is used by b.
+func Used() {}
+
+// Unused is not used.
+func Unused() {}
+
+func local() { Local() }
+
+// Local is used within a.
+func Local() {}
+
+type (
+	T int
+	S int
+)
+
+func Ignored() {} //nolint:unusedexports
+`,
+		"b/b.go": "package b\n\nimport \"example.com/m/a\"\n\nvar _ = a.Used\n\nvar _ a.S\n",
+	}, func(options *source.Options) {
+		options.UnusedExports = true
+	})
+	defer cleanup()
+	ctx := context.Background()
+	uri := span.FileURI(filepath.Join(dir, "a", "a.go"))
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, _, err := source.Diagnostics(ctx, view, f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, diag := range reports[uri] {
+		if diag.Source != source.UnusedExportsSource {
+			t.Errorf("unexpected diagnostic %q from %s", diag.Message, diag.Source)
+			continue
+		}
+		got = append(got, diag.Message)
+		if len(diag.SuggestedFixes) != 1 {
+			t.Errorf("got %d fixes for %q, want 1", len(diag.SuggestedFixes), diag.Message)
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		"exported function Unused is unused in the workspace",
+		"exported type T is unused in the workspace",
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got diagnostics %q, want %q", got, want)
+	}
+}
+
+func TestWorkspaceLinknames(t *testing.T) {
+	const src = `package b
+
+import _ "unsafe"
+
+//go:linkname local example.com/m/a.target
+func local()
+
+//go:linkname method example.com/m/a.(*T).m
+func method()
+
+//go:linkname exported
+`
+	_, view, cleanup := newTestModule(t, map[string]string{
+		"b.go": src,
+	}, nil)
+	defer cleanup()
+	ctx := context.Background()
+	linknames, err := view.Snapshot().WorkspaceLinknames(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []struct {
+		target source.Xref
+		line   float64
+		text   string
+	}{
+		{source.Xref{PkgPath: "example.com/m/a", Name: "target"}, 4, "example.com/m/a.target"},
+		{source.Xref{PkgPath: "example.com/m/a", Name: "(*T).m"}, 7, "example.com/m/a.(*T).m"},
+	} {
+		locations := linknames[want.target]
+		if len(locations) != 1 {
+			t.Errorf("got %d locations for %v, want 1", len(locations), want.target)
+			continue
+		}
+		rng := locations[0].Range
+		if rng.Start.Line != want.line || rng.End.Line != want.line || int(rng.End.Character-rng.Start.Character) != len(want.text) {
+			t.Errorf("got range %v for %v, want %q on line %v", rng, want.target, want.text, want.line)
+		}
+	}
+	if len(linknames) != 2 {
+		t.Errorf("got linknames %v, want 2", linknames)
+	}
+}
+
+func TestWorkspaceXrefUses(t *testing.T) {
+	const src = `package b
+
+import "example.com/m/a"
+
+func f() {
+	a.Counter++
+	a.Read(a.Value)
+	_ = &a.Config.Name
+	a.Buffer = a.Value
+}
+`
+	_, view, cleanup := newTestModule(t, map[string]string{
+		"b.go": src,
+	}, nil)
+	defer cleanup()
+	ctx := context.Background()
+	xrefs, err := view.Snapshot().WorkspaceXrefs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[source.Xref]source.XrefUse{
+		{PkgPath: "example.com/m/a", Name: "Counter"}: source.XrefWrite,
+		{PkgPath: "example.com/m/a", Name: "Read"}:    source.XrefRead,
+		{PkgPath: "example.com/m/a", Name: "Value"}:   source.XrefRead,
+		{PkgPath: "example.com/m/a", Name: "Config"}:  source.XrefAddress,
+		{PkgPath: "example.com/m/a", Name: "Buffer"}:  source.XrefWrite,
+	}
+	if !reflect.DeepEqual(xrefs, want) {
+		t.Errorf("got xrefs %v, want %v", xrefs, want)
+	}
+}
+
+func TestWorkspaceSymbols(t *testing.T) {
+	const src = `package b
+
+type T struct {
+	F, _ int
+}
+
+func (*T) M() {}
+
+type I interface {
+	N()
+}
+
+type S = string
+
+const C = 1
+
+var V, _ = 1, 2
+
+func Fn() {}
+`
+	_, view, cleanup := newTestModule(t, map[string]string{
+		"b.go": src,
+	}, nil)
+	defer cleanup()
+	ctx := context.Background()
+	symbols, err := view.Snapshot().WorkspaceSymbols(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, sym := range symbols {
+		got = append(got, fmt.Sprintf("%s.%s %v %v", sym.Container, sym.Name, sym.Kind, sym.Location.Range.Start.Line))
+	}
+	want := []string{
+		"b.T Struct 2",
+		"b.T.F Field 3",
+		"b.T.M Method 6",
+		"b.I Interface 8",
+		"b.I.N Method 9",
+		"b.S Class 12",
+		"b.C Constant 14",
+		"b.V Variable 16",
+		"b.Fn Function 18",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbols\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+
+	"golang.org/x/tools/internal/jsonrpc2"
+	"golang.org/x/tools/internal/lsp"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/tool"
+)
+
+// bugReport implements the bugreport command.
+type bugReport struct {
+	Output string `flag:"o" help:"the file to write the bundle to"`
+
+	app *Application
+}
+
+func (b *bugReport) Name() string  { return "bugreport" }
+func (b *bugReport) Usage() string { return "" }
+func (b *bugReport) ShortHelp() string {
+	return "create a bug report bundle to attach to an issue"
+}
+func (b *bugReport) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Writes a zip file with the information needed to investigate a bug in
+gopls: its version, the internal bugs it has reported, the settings and
+statistics of its views, and the stacks of its goroutines. The bundle does
+not contain source code or environment variables, and the directories of
+the views and the home directory are replaced by placeholders.
+
+With the -remote flag, the bundle describes the daemon at that address,
+which is a server started with "gopls serve -listen". Otherwise it
+describes this process, which only has the version information.
+
+Example:
+
+  $ gopls -remote=localhost:4389 bugreport -o bug.zip
+
+bugreport flags are:
+`)
+	f.PrintDefaults()
+}
+
+func (b *bugReport) Run(ctx context.Context, args ...string) error {
+	if len(args) > 0 {
+		return tool.CommandLineErrorf("bugreport does not take arguments, got %v", args)
+	}
+	output := b.Output
+	if output == "" {
+		output = "gopls-bugreport.zip"
+	}
+	var bundle []byte
+	switch remote := b.app.Remote; remote {
+	case "", "internal":
+		var buf bytes.Buffer
+		if err := lsp.WriteBugReport(&buf, b.app.cache); err != nil {
+			return err
+		}
+		bundle = buf.Bytes()
+	default:
+		conn, err := net.Dial("tcp", remote)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		// Like the stats request, the bug report request does not need the
+		// connection to be initialized.
+		ctx, jc, _ := protocol.NewClient(ctx, jsonrpc2.NewHeaderStream(conn, conn), newConnection(b.app).Client)
+		go jc.Run(ctx)
+		if err := jc.Call(ctx, "gopls/bugReport", nil, &bundle); err != nil {
+			return err
+		}
+	}
+	if err := ioutil.WriteFile(output, bundle, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the bug report bundle to %s. Please attach it to an issue at https://github.com/golang/go/issues/new.\n", output)
+	return nil
+}
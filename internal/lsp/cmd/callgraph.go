@@ -0,0 +1,103 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/tool"
+)
+
+// callgraph implements the callgraph verb for gopls.
+type callgraph struct {
+	Algorithm string `flag:"algo" help:"call graph algorithm: cha or rta"`
+	JSON      bool   `flag:"json" help:"emit output in JSON format instead of DOT"`
+
+	app *Application
+}
+
+func (c *callgraph) Name() string      { return "callgraph" }
+func (c *callgraph) Usage() string     { return "<file> [function]" }
+func (c *callgraph) ShortHelp() string { return "print the static call graph of a package" }
+func (c *callgraph) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Prints the static call graph of the package of the file, or of the calls
+reachable from one of its functions, in the DOT format of Graphviz or in JSON.
+Dynamic calls are resolved by class hierarchy analysis (cha), or by rapid type
+analysis (rta) from the function, or from all the functions of the package.
+
+Example:
+
+  $ gopls callgraph internal/lsp/server.go
+  $ gopls callgraph -algo=rta -json internal/lsp/server.go Server.Initialize
+
+	gopls callgraph flags are:
+`)
+	f.PrintDefaults()
+}
+
+// Run prints the call graph of the package of the file args[0], or of the
+// function args[1] in it.
+func (c *callgraph) Run(ctx context.Context, args ...string) error {
+	if len(args) != 1 && len(args) != 2 {
+		return tool.CommandLineErrorf("callgraph expects 1 or 2 arguments (file and function)")
+	}
+	conn, err := c.app.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.terminate(ctx)
+
+	uri := span.FileURI(args[0])
+	if file := conn.AddFile(ctx, uri); file.err != nil {
+		return file.err
+	}
+	params := &source.CallGraphParams{
+		URI:       protocol.NewURI(uri),
+		Algorithm: source.CallGraphAlgorithm(c.Algorithm),
+	}
+	if len(args) == 2 {
+		params.Function = args[1]
+	}
+	result, err := conn.NonstandardRequest(ctx, "gopls/callGraph", params)
+	if err != nil {
+		return err
+	}
+	// A remote server replies with the decoded JSON of the graph.
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	var graph source.CallGraph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		return err
+	}
+
+	if c.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		return enc.Encode(graph)
+	}
+	fmt.Println("digraph callgraph {")
+	for _, n := range graph.Nodes {
+		fmt.Printf("\tn%d [label=%q];\n", n.ID, n.Name)
+	}
+	for _, e := range graph.Edges {
+		if e.Dynamic {
+			fmt.Printf("\tn%d -> n%d [style=dashed];\n", e.Caller, e.Callee)
+		} else {
+			fmt.Printf("\tn%d -> n%d;\n", e.Caller, e.Callee)
+		}
+	}
+	fmt.Println("}")
+	return nil
+}
@@ -27,6 +27,7 @@ import (
 	"golang.org/x/tools/internal/span"
 	"golang.org/x/tools/internal/telemetry/export"
 	"golang.org/x/tools/internal/telemetry/export/ocagent"
+	"golang.org/x/tools/internal/telemetry/export/otlp"
 	"golang.org/x/tools/internal/tool"
 	"golang.org/x/tools/internal/xcontext"
 	errors "golang.org/x/xerrors"
@@ -57,6 +58,10 @@ type Application struct {
 	// The environment variables to use.
 	env []string
 
+	// The settings that the server is configured with, in addition to the
+	// environment, as set by the verbs that need them.
+	settings map[string]interface{}
+
 	// Support for remote lsp server
 	Remote string `flag:"remote" help:"*EXPERIMENTAL* - forward all commands to a remote lsp"`
 
@@ -66,6 +71,9 @@ type Application struct {
 	// Control ocagent export of telemetry
 	OCAgent string `flag:"ocagent" help:"The address of the ocagent, or off"`
 
+	// Control OTLP export of telemetry
+	OTLP string `flag:"otlp" help:"The URL of an OpenTelemetry collector, or off; defaults to $OTEL_EXPORTER_OTLP_ENDPOINT"`
+
 	// PrepareOptions is called to update the options when a new view is built.
 	// It is primarily to allow the behavior of gopls to be modified by hooks.
 	PrepareOptions func(*source.Options)
@@ -121,6 +129,11 @@ func (app *Application) Run(ctx context.Context, args ...string) error {
 	//TODO: we should not need to adjust the discovered configuration
 	ocConfig.Address = app.OCAgent
 	export.AddExporters(ocagent.Connect(ocConfig))
+	otlpConfig := otlp.Discover()
+	if app.OTLP != "" {
+		otlpConfig.Endpoint = app.OTLP
+	}
+	export.AddExporters(otlp.Connect(otlpConfig))
 	app.Serve.app = app
 	if len(args) == 0 {
 		return tool.Run(ctx, &app.Serve, args)
@@ -141,10 +154,18 @@ func (app *Application) commands() []tool.Application {
 	return []tool.Application{
 		&app.Serve,
 		&bug{},
+		&bugReport{app: app},
+		&callgraph{app: app},
 		&check{app: app},
+		&daemon{app: app},
 		&format{app: app},
+		&metrics{app: app},
+		&mod{app: app},
 		&query{app: app},
+		&references{app: app},
 		&rename{app: app},
+		&replay{app: app},
+		&workspaceStats{app: app},
 		&version{app: app},
 	}
 }
@@ -302,9 +323,13 @@ func (c *cmdClient) Configuration(ctx context.Context, p *protocol.ParamConfig)
 			}
 			env[l[0]] = l[1]
 		}
-		results[i] = map[string]interface{}{
+		settings := map[string]interface{}{
 			"env": env,
 		}
+		for name, value := range c.app.settings {
+			settings[name] = value
+		}
+		results[i] = settings
 	}
 	return results, nil
 }
@@ -313,6 +338,14 @@ func (c *cmdClient) ApplyEdit(ctx context.Context, p *protocol.ApplyWorkspaceEdi
 	return &protocol.ApplyWorkspaceEditResponse{Applied: false, FailureReason: "not implemented"}, nil
 }
 
+func (c *cmdClient) Progress(ctx context.Context, p *protocol.ProgressParams) error {
+	return nil
+}
+
+func (c *cmdClient) WorkDoneProgressCreate(ctx context.Context, p *protocol.WorkDoneProgressCreateParams) error {
+	return nil
+}
+
 func (c *cmdClient) PublishDiagnostics(ctx context.Context, p *protocol.PublishDiagnosticsParams) error {
 	c.filesMu.Lock()
 	defer c.filesMu.Unlock()
@@ -350,10 +383,13 @@ func (c *cmdClient) getFile(ctx context.Context, uri span.URI) *cmdFile {
 		f := c.fset.AddFile(fname, -1, len(content))
 		f.SetLinesForContent(content)
 		converter := span.NewContentConverter(fname, content)
+		// The file may have changed since the positions were computed, so
+		// those beyond the ends of lines are clamped rather than rejected.
 		file.mapper = &protocol.ColumnMapper{
 			URI:       uri,
 			Converter: converter,
 			Content:   content,
+			Clamp:     true,
 		}
 	}
 	return file
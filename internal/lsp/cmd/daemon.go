@@ -0,0 +1,142 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"text/tabwriter"
+
+	"golang.org/x/tools/internal/jsonrpc2"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/tool"
+)
+
+// daemon implements the daemon command.
+type daemon struct {
+	app *Application
+}
+
+func (d *daemon) Name() string  { return "daemon" }
+func (d *daemon) Usage() string { return "<mode>" }
+func (d *daemon) ShortHelp() string {
+	return "inspect a shared gopls daemon"
+}
+func (d *daemon) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Inspects the gopls daemon given by the -remote flag, which is a server
+started with "gopls serve -listen" and shared by several clients.
+
+The mode argument determines the operation to perform:
+`)
+	for _, m := range d.modes() {
+		fmt.Fprintf(f.Output(), "  %s : %v\n", m.Name(), m.ShortHelp())
+	}
+	fmt.Fprint(f.Output(), `
+daemon flags are:
+`)
+	f.PrintDefaults()
+}
+
+// Run takes the args after command flag processing, and invokes the correct
+// daemon mode as specified by the first argument.
+func (d *daemon) Run(ctx context.Context, args ...string) error {
+	if len(args) == 0 {
+		return tool.CommandLineErrorf("daemon must be supplied a mode")
+	}
+	mode, args := args[0], args[1:]
+	for _, m := range d.modes() {
+		if m.Name() == mode {
+			return tool.Run(ctx, m, args)
+		}
+	}
+	return tool.CommandLineErrorf("unknown command %v", mode)
+}
+
+// modes returns the set of modes supported by the daemon command.
+func (d *daemon) modes() []tool.Application {
+	return []tool.Application{
+		&daemonStats{daemon: d},
+	}
+}
+
+// daemonStats implements the daemon stats command.
+type daemonStats struct {
+	JSON bool `flag:"json" help:"emit output in JSON format"`
+
+	daemon *daemon
+}
+
+func (s *daemonStats) Name() string  { return "stats" }
+func (s *daemonStats) Usage() string { return "" }
+func (s *daemonStats) ShortHelp() string {
+	return "print the resources used by each client and view of the daemon"
+}
+func (s *daemonStats) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Prints the use of the daemon's parse cache and, for each client connected
+to the daemon, the views it has created and the work done on their behalf:
+the number of packages they hold and the size of their source, and the time
+spent loading and type-checking packages. Work shared between views through
+the cache is counted for the view that first did it.
+
+Example:
+
+  $ gopls -remote=localhost:4389 daemon stats
+
+daemon stats flags are:
+`)
+	f.PrintDefaults()
+}
+
+func (s *daemonStats) Run(ctx context.Context, args ...string) error {
+	if len(args) > 0 {
+		return tool.CommandLineErrorf("daemon stats does not take arguments, got %v", args)
+	}
+	remote := s.daemon.app.Remote
+	if remote == "" || remote == "internal" {
+		return tool.CommandLineErrorf("daemon stats requires the address of a daemon in the -remote flag")
+	}
+	conn, err := net.Dial("tcp", remote)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// The stats request does not need the connection to be initialized, so
+	// this connection does not add a view of its own to the stats.
+	ctx, jc, _ := protocol.NewClient(ctx, jsonrpc2.NewHeaderStream(conn, conn), newConnection(s.daemon.app).Client)
+	go jc.Run(ctx)
+	stats := &source.DaemonStats{}
+	if err := jc.Call(ctx, "gopls/daemonStats", nil, stats); err != nil {
+		return err
+	}
+	if s.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		return enc.Encode(stats)
+	}
+	printDaemonStats(os.Stdout, stats)
+	return nil
+}
+
+func printDaemonStats(w io.Writer, stats *source.DaemonStats) {
+	fmt.Fprintf(w, "heap in use: %d bytes\n", stats.HeapAlloc)
+	pc := stats.ParseCache
+	fmt.Fprintf(w, "parse cache: %d/%d files, %d hits, %d misses, %d evictions\n", pc.Size, pc.Capacity, pc.Hits, pc.Misses, pc.Evictions)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "client\tview\tpackages\tsource bytes\tloads\tload time\ttype checks\ttype check time\tclones\tclone time")
+	for _, session := range stats.Sessions {
+		for _, v := range session.Views {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%v\t%d\t%v\t%d\t%v\n", session.ID, v.Folder.Filename(), v.Packages, v.SourceBytes, v.Loads, v.LoadTime, v.TypeChecks, v.TypeCheckTime, v.Clones, v.CloneTime)
+		}
+	}
+	tw.Flush()
+}
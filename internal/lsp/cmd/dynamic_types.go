@@ -0,0 +1,103 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/tool"
+	errors "golang.org/x/xerrors"
+)
+
+// dynamicTypes implements the dynamictypes noun for the query command.
+type dynamicTypes struct {
+	query *query
+}
+
+func (d *dynamicTypes) Name() string  { return "dynamictypes" }
+func (d *dynamicTypes) Usage() string { return "<position>" }
+func (d *dynamicTypes) ShortHelp() string {
+	return "list the likely dynamic types of an interface-typed variable (experimental)"
+}
+func (d *dynamicTypes) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Lists the declarations of the types that implement the interface of the
+variable at the position and that the functions of its package, or those they
+call, convert to interfaces, as found by rapid type analysis.
+
+Example:
+
+  $ gopls query dynamictypes internal/lsp/source/view.go:#1234
+
+	gopls query dynamictypes flags are:
+`)
+	f.PrintDefaults()
+}
+
+// Run prints the spans of the declarations of the dynamic types of the
+// variable at the position args[0].
+func (d *dynamicTypes) Run(ctx context.Context, args ...string) error {
+	if len(args) != 1 {
+		return tool.CommandLineErrorf("dynamictypes expects 1 argument (position)")
+	}
+	conn, err := d.query.app.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.terminate(ctx)
+	from := span.Parse(args[0])
+	file := conn.AddFile(ctx, from.URI())
+	if file.err != nil {
+		return file.err
+	}
+	loc, err := file.mapper.Location(from)
+	if err != nil {
+		return err
+	}
+	result, err := conn.NonstandardRequest(ctx, "gopls/dynamicTypes", &protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: loc.URI},
+		Position:     loc.Range.Start,
+	})
+	if err != nil {
+		return errors.Errorf("%v: %v", from, err)
+	}
+	// A remote server replies with the decoded JSON of the locations.
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	var locs []protocol.Location
+	if err := json.Unmarshal(data, &locs); err != nil {
+		return err
+	}
+
+	spans := []span.Span{}
+	for _, loc := range locs {
+		file := conn.AddFile(ctx, span.NewURI(loc.URI))
+		if file.err != nil {
+			return file.err
+		}
+		spn, err := file.mapper.Span(loc)
+		if err != nil {
+			return err
+		}
+		spans = append(spans, spn)
+	}
+	if d.query.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		return enc.Encode(spans)
+	}
+	for _, spn := range spans {
+		fmt.Println(spn)
+	}
+	return nil
+}
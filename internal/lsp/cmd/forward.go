@@ -0,0 +1,282 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/internal/jsonrpc2"
+	"golang.org/x/tools/internal/lsp/journal"
+)
+
+// forwarder relays the messages between the editor and a gopls daemon.
+//
+// The daemon records the files that the editor has open in a journal named
+// by the ID of the forwarder. If the connection to the daemon is lost, as
+// when it crashes, the forwarder fails the requests that were in flight,
+// connects to the daemon again once it is restarted, and initializes a new
+// session as the editor did. The new daemon then opens the files of the
+// journal again, so that its results are not silently computed from the
+// content of the files on disk. The messages of the editor received in the
+// meantime are sent to the new daemon once the session is initialized.
+type forwarder struct {
+	remote   string
+	clientID string
+	editor   jsonrpc2.Stream
+
+	// reconnectTimeout is how long the forwarder tries to connect to the
+	// daemon again after losing it.
+	reconnectTimeout time.Duration
+
+	mu     sync.Mutex
+	daemon jsonrpc2.Stream
+	conn   net.Conn
+
+	// connected is cleared while the connection to the daemon is restored,
+	// during which the messages of the editor are queued.
+	connected bool
+	queued    [][]byte
+
+	// initialize and initialized are the messages with which the editor
+	// initialized the session, replayed to a restarted daemon.
+	initialize, initialized *jsonrpc2.WireRequest
+
+	// pending holds the IDs of the requests of the editor that the daemon
+	// has not answered yet, by their string form.
+	pending map[string]*jsonrpc2.ID
+
+	// shutdown is set once the editor shuts the session down, after which
+	// a lost connection is not restored.
+	shutdown bool
+
+	// lastID is the number of the last request sent by the forwarder itself.
+	lastID int64
+}
+
+// forwarderIDPrefix prefixes the IDs of the requests sent by the forwarder,
+// whose responses are not relayed to the editor.
+const forwarderIDPrefix = "gopls-forwarder/"
+
+// message holds the fields of a JSON-RPC message that tell its kind.
+type message struct {
+	Method string       `json:"method"`
+	ID     *jsonrpc2.ID `json:"id"`
+}
+
+func newForwarder(remote string, editor jsonrpc2.Stream) *forwarder {
+	return &forwarder{
+		remote:           remote,
+		clientID:         fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano()),
+		editor:           editor,
+		reconnectTimeout: time.Minute,
+		pending:          make(map[string]*jsonrpc2.ID),
+	}
+}
+
+// run relays messages until the editor or, after the editor shuts the
+// session down, the daemon closes its stream.
+func (f *forwarder) run(ctx context.Context) error {
+	if err := f.connect(ctx); err != nil {
+		return err
+	}
+	f.connected = true
+	errc := make(chan error, 2)
+	go func() { errc <- f.relayEditor(ctx) }()
+	go func() { errc <- f.relayDaemon(ctx) }()
+	err := <-errc
+	f.mu.Lock()
+	f.conn.Close()
+	f.mu.Unlock()
+	return err
+}
+
+// connect connects to the daemon and tells it the ID of the journal of the
+// client.
+func (f *forwarder) connect(ctx context.Context) error {
+	conn, err := net.Dial("tcp", f.remote)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.conn = conn
+	f.daemon = jsonrpc2.NewHeaderStream(conn, conn)
+	data, err := json.Marshal(&journal.Params{ClientID: f.clientID})
+	if err != nil {
+		return err
+	}
+	params := json.RawMessage(data)
+	return f.replayLocked(ctx, &jsonrpc2.WireRequest{
+		Method: "gopls/journal",
+		Params: &params,
+		ID:     &jsonrpc2.ID{},
+	})
+}
+
+// replayLocked sends a request or notification of the forwarder itself to
+// the daemon. A request is sent with an ID of the forwarder, so that its
+// response is not relayed to the editor.
+func (f *forwarder) replayLocked(ctx context.Context, req *jsonrpc2.WireRequest) error {
+	r := *req
+	if r.ID != nil {
+		f.lastID++
+		r.ID = &jsonrpc2.ID{Name: fmt.Sprintf("%s%d", forwarderIDPrefix, f.lastID)}
+	}
+	data, err := json.Marshal(&r)
+	if err != nil {
+		return err
+	}
+	_, err = f.daemon.Write(ctx, data)
+	return err
+}
+
+// relayEditor relays the messages of the editor to the daemon.
+func (f *forwarder) relayEditor(ctx context.Context) error {
+	for {
+		data, _, err := f.editor.Read(ctx)
+		if err != nil {
+			return err
+		}
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return err
+		}
+		f.mu.Lock()
+		switch msg.Method {
+		case "":
+			// A response to a request of the daemon.
+		case "initialize", "initialized":
+			var req jsonrpc2.WireRequest
+			if err := json.Unmarshal(data, &req); err == nil {
+				if msg.Method == "initialize" {
+					f.initialize = &req
+				} else {
+					f.initialized = &req
+				}
+			}
+		case "shutdown", "exit":
+			f.shutdown = true
+		}
+		if !f.connected {
+			f.queued = append(f.queued, data)
+			f.mu.Unlock()
+			continue
+		}
+		if msg.Method != "" && msg.ID != nil {
+			f.pending[msg.ID.String()] = msg.ID
+		}
+		// If the daemon is lost, a request is failed when the connection
+		// is restored.
+		f.daemon.Write(ctx, data)
+		f.mu.Unlock()
+	}
+}
+
+// relayDaemon relays the messages of the daemon to the editor, and restores
+// the connection to the daemon when it is lost.
+func (f *forwarder) relayDaemon(ctx context.Context) error {
+	for {
+		f.mu.Lock()
+		daemon := f.daemon
+		f.mu.Unlock()
+		data, _, err := daemon.Read(ctx)
+		if err != nil {
+			f.mu.Lock()
+			shutdown := f.shutdown
+			f.mu.Unlock()
+			if shutdown {
+				return nil
+			}
+			log.Printf("lost the connection to the gopls daemon: %v", err)
+			if err := f.reconnect(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return err
+		}
+		if msg.Method == "" && msg.ID != nil {
+			if strings.HasPrefix(msg.ID.Name, forwarderIDPrefix) {
+				continue
+			}
+			f.mu.Lock()
+			delete(f.pending, msg.ID.String())
+			f.mu.Unlock()
+		}
+		if _, err := f.editor.Write(ctx, data); err != nil {
+			return err
+		}
+	}
+}
+
+// reconnect fails the pending requests of the editor, connects to the
+// daemon again, and replays the initialization of the session.
+func (f *forwarder) reconnect(ctx context.Context) error {
+	f.mu.Lock()
+	f.conn.Close()
+	f.connected = false
+	pending := f.pending
+	f.pending = make(map[string]*jsonrpc2.ID)
+	f.mu.Unlock()
+	for _, id := range pending {
+		resp := &jsonrpc2.WireResponse{
+			ID:    id,
+			Error: jsonrpc2.NewErrorf(jsonrpc2.CodeInternalError, "the connection to the gopls daemon was lost"),
+		}
+		if data, err := json.Marshal(resp); err == nil {
+			f.editor.Write(ctx, data)
+		}
+	}
+
+	deadline := time.Now().Add(f.reconnectTimeout)
+	delay := 100 * time.Millisecond
+	for {
+		err := f.connect(ctx)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("reconnecting to the gopls daemon: %v", err)
+		}
+		time.Sleep(delay)
+		if delay < 5*time.Second {
+			delay *= 2
+		}
+	}
+	log.Printf("reconnected to the gopls daemon")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, req := range []*jsonrpc2.WireRequest{f.initialize, f.initialized} {
+		if req == nil {
+			continue
+		}
+		if err := f.replayLocked(ctx, req); err != nil {
+			return err
+		}
+	}
+	for _, data := range f.queued {
+		var msg message
+		if err := json.Unmarshal(data, &msg); err == nil && msg.Method != "" && msg.ID != nil {
+			f.pending[msg.ID.String()] = msg.ID
+		}
+		if _, err := f.daemon.Write(ctx, data); err != nil {
+			return err
+		}
+	}
+	f.queued = nil
+	f.connected = true
+	return nil
+}
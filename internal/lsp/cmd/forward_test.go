@@ -0,0 +1,93 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/internal/jsonrpc2"
+)
+
+// readMessage reads a message from stream, and returns its method and ID.
+func readMessage(t *testing.T, ctx context.Context, stream jsonrpc2.Stream) (string, string) {
+	t.Helper()
+	data, _, err := stream.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg.Method, msg.ID.String()
+}
+
+func sendMessage(t *testing.T, ctx context.Context, stream jsonrpc2.Stream, msg string) {
+	t.Helper()
+	if _, err := stream.Write(ctx, []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForwarderReconnect(t *testing.T) {
+	ctx := context.Background()
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accept := func() (net.Conn, jsonrpc2.Stream) {
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn, jsonrpc2.NewHeaderStream(conn, conn)
+	}
+
+	editorConn, forwarderConn := net.Pipe()
+	defer editorConn.Close()
+	editor := jsonrpc2.NewHeaderStream(editorConn, editorConn)
+	f := newForwarder(ln.Addr().String(), jsonrpc2.NewHeaderStream(forwarderConn, forwarderConn))
+	f.reconnectTimeout = 10 * time.Second
+	go f.run(ctx)
+
+	conn, daemon := accept()
+	if method, id := readMessage(t, ctx, daemon); method != "gopls/journal" || !strings.Contains(id, forwarderIDPrefix) {
+		t.Fatalf("first message is %s %s, want gopls/journal from the forwarder", method, id)
+	}
+	sendMessage(t, ctx, editor, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	readMessage(t, ctx, daemon)
+	sendMessage(t, ctx, daemon, `{"jsonrpc":"2.0","id":1,"result":{}}`)
+	readMessage(t, ctx, editor)
+	sendMessage(t, ctx, editor, `{"jsonrpc":"2.0","method":"initialized","params":{}}`)
+	readMessage(t, ctx, daemon)
+	sendMessage(t, ctx, editor, `{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{}}`)
+	readMessage(t, ctx, daemon)
+
+	// The daemon crashes before answering the hover request.
+	conn.Close()
+	if method, id := readMessage(t, ctx, editor); method != "" || id != "#2" {
+		t.Errorf("editor got %q %s, want the failed response to request 2", method, id)
+	}
+	sendMessage(t, ctx, editor, `{"jsonrpc":"2.0","method":"textDocument/didChange","params":{}}`)
+
+	// The restarted daemon gets the journal ID, the initialization of the
+	// session, and the messages sent while it was down.
+	_, daemon = accept()
+	for _, want := range []string{"gopls/journal", "initialize", "initialized", "textDocument/didChange"} {
+		method, id := readMessage(t, ctx, daemon)
+		if method != want {
+			t.Errorf("restarted daemon got %s, want %s", method, want)
+		}
+		if method == "initialize" && !strings.Contains(id, forwarderIDPrefix) {
+			t.Errorf("initialize was replayed with the ID %s of the editor", id)
+		}
+	}
+}
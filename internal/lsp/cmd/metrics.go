@@ -0,0 +1,122 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/tool"
+)
+
+// metrics implements the metrics command.
+type metrics struct {
+	JSON bool `flag:"json" help:"emit output in JSON format"`
+
+	app *Application
+}
+
+func (m *metrics) Name() string  { return "metrics" }
+func (m *metrics) Usage() string { return "[packages]" }
+func (m *metrics) ShortHelp() string {
+	return "print metrics of the packages of the workspace and their imports"
+}
+func (m *metrics) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Loads the packages that match the patterns, ./... by default, as an editor
+opening them would, and prints for each package its number of files and
+lines, the number of packages it imports and depends on, the number of
+matching packages that import it, and the length of the longest chain of
+imports among the matching packages that starts with it. It also prints the
+import cycles and the longest chains of imports among the matching packages.
+
+With -json, the metrics are printed in JSON, for dashboards.
+
+Example:
+
+  $ gopls metrics -json ./internal/...
+
+metrics flags are:
+`)
+	f.PrintDefaults()
+}
+
+func (m *metrics) Run(ctx context.Context, args ...string) error {
+	if m.app.Remote != "" {
+		return tool.CommandLineErrorf("metrics loads the workspace itself and does not use the -remote flag")
+	}
+	if len(args) == 0 {
+		args = []string{"./..."}
+	}
+	session := m.app.cache.NewSession(ctx)
+	defer session.Shutdown(ctx)
+	options := session.Options()
+	options.Env = m.app.env
+	if m.app.PrepareOptions != nil {
+		m.app.PrepareOptions(&options)
+	}
+	view := session.NewView(ctx, "metrics", span.FileURI(m.app.wd), options)
+
+	cfg := view.Config(ctx)
+	cfg.Mode = packages.NeedName | packages.NeedFiles
+	pkgs, err := packages.Load(cfg, args...)
+	if err != nil {
+		return err
+	}
+	// Load the metadata of each package, and of its dependencies, into the
+	// snapshot of the view.
+	var workspace []string
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) == 0 {
+			continue
+		}
+		f, err := view.GetFile(ctx, span.FileURI(pkg.GoFiles[0]))
+		if err != nil {
+			return err
+		}
+		if _, _, err := view.CheckPackageHandles(ctx, f); err != nil {
+			return err
+		}
+		workspace = append(workspace, pkg.ID)
+	}
+	result, err := source.WorkspaceMetrics(ctx, session, view.Snapshot().MetadataGraph(), workspace)
+	if err != nil {
+		return err
+	}
+	if m.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		return enc.Encode(result)
+	}
+	printMetrics(os.Stdout, result)
+	return nil
+}
+
+func printMetrics(w io.Writer, r *source.Metrics) {
+	for _, p := range r.Packages {
+		fmt.Fprintf(w, "%s: %d files, %d lines, %d imports, %d deps, %d importers, depth %d\n",
+			p.Path, p.Files, p.Lines, p.Imports, p.Deps, p.Importers, p.Depth)
+	}
+	if len(r.Cycles) > 0 {
+		fmt.Fprintf(w, "import cycles:\n")
+		for _, cycle := range r.Cycles {
+			fmt.Fprintf(w, "  %s\n", strings.Join(cycle, ", "))
+		}
+	}
+	if len(r.Chains) > 0 {
+		fmt.Fprintf(w, "longest import chains:\n")
+		for _, chain := range r.Chains {
+			fmt.Fprintf(w, "  %s\n", strings.Join(chain, " -> "))
+		}
+	}
+}
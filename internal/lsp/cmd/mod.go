@@ -0,0 +1,179 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/tool"
+	errors "golang.org/x/xerrors"
+)
+
+// mod implements the mod command.
+type mod struct {
+	app *Application
+}
+
+func (m *mod) Name() string  { return "mod" }
+func (m *mod) Usage() string { return "<mode> <mode args>" }
+func (m *mod) ShortHelp() string {
+	return "answer queries about the modules of the workspace"
+}
+func (m *mod) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+The mode argument determines the query to perform:
+`)
+	for _, mode := range m.modes() {
+		fmt.Fprintf(f.Output(), "  %s : %v\n", mode.Name(), mode.ShortHelp())
+	}
+	fmt.Fprint(f.Output(), `
+mod flags are:
+`)
+	f.PrintDefaults()
+}
+
+// Run invokes the mode of the mod command given by the first argument.
+func (m *mod) Run(ctx context.Context, args ...string) error {
+	if len(args) == 0 {
+		return tool.CommandLineErrorf("mod must be supplied a mode")
+	}
+	mode, args := args[0], args[1:]
+	for _, a := range m.modes() {
+		if a.Name() == mode {
+			return tool.Run(ctx, a, args)
+		}
+	}
+	return tool.CommandLineErrorf("unknown command %v", mode)
+}
+
+// modes returns the set of modes supported by the mod command.
+func (m *mod) modes() []tool.Application {
+	return []tool.Application{
+		&modGraph{mod: m},
+	}
+}
+
+// modGraph implements the graph mode of the mod command.
+type modGraph struct {
+	Why  string `flag:"why" help:"print the shortest requirement paths from the main module to the module with this path or path@version"`
+	JSON bool   `flag:"json" help:"emit output in JSON format"`
+
+	mod *mod
+}
+
+func (g *modGraph) Name() string  { return "graph" }
+func (g *modGraph) Usage() string { return "[directory]" }
+func (g *modGraph) ShortHelp() string {
+	return "print the module requirement graph of the main module"
+}
+func (g *modGraph) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Prints the module requirement graph of the main module of the directory, or
+of the current directory, as "go mod graph" does: one line per module and
+requirement. With -why, prints instead the shortest requirement paths from
+the main module to the versions of a module. The graph is cached by the
+server until the go.mod file changes.
+
+Example:
+
+  $ gopls mod graph
+  $ gopls mod graph -why golang.org/x/sync
+
+	gopls mod graph flags are:
+`)
+	f.PrintDefaults()
+}
+
+// Run prints the module requirement graph of the go.mod file of the directory
+// args[0], or of the working directory.
+func (g *modGraph) Run(ctx context.Context, args ...string) error {
+	if len(args) > 1 {
+		return tool.CommandLineErrorf("graph expects at most 1 argument (directory)")
+	}
+	dir := g.mod.app.wd
+	if len(args) == 1 {
+		dir = args[0]
+	}
+	gomod, err := findGoMod(dir)
+	if err != nil {
+		return err
+	}
+	conn, err := g.mod.app.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.terminate(ctx)
+
+	result, err := conn.NonstandardRequest(ctx, "gopls/modGraph", &source.ModGraphParams{
+		URI:    protocol.NewURI(span.FileURI(gomod)),
+		Module: g.Why,
+	})
+	if err != nil {
+		return err
+	}
+	// A remote server replies with the decoded JSON of the graph.
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	var graph source.ModGraph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		return err
+	}
+
+	if g.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		return enc.Encode(graph)
+	}
+	if g.Why != "" {
+		for _, path := range graph.Why {
+			fmt.Println(strings.Join(path, " -> "))
+		}
+		return nil
+	}
+	var modules []string
+	for m := range graph.Requires {
+		if m != graph.Main {
+			modules = append(modules, m)
+		}
+	}
+	sort.Strings(modules)
+	for _, m := range append([]string{graph.Main}, modules...) {
+		for _, req := range graph.Requires[m] {
+			fmt.Println(m, req)
+		}
+	}
+	return nil
+}
+
+// findGoMod returns the go.mod file of the module that contains dir.
+func findGoMod(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for d := dir; ; {
+		gomod := filepath.Join(d, "go.mod")
+		if _, err := os.Stat(gomod); err == nil {
+			return gomod, nil
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return "", errors.Errorf("%s is not in a module", dir)
+		}
+		d = parent
+	}
+}
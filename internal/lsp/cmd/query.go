@@ -66,5 +66,6 @@ func (q *query) Run(ctx context.Context, args ...string) error {
 func (q *query) modes() []tool.Application {
 	return []tool.Application{
 		&definition{query: q},
+		&dynamicTypes{query: q},
 	}
 }
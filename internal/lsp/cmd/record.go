@@ -0,0 +1,219 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/internal/jsonrpc2"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	errors "golang.org/x/xerrors"
+)
+
+// The files of a recording directory.
+const (
+	recordedMessagesFile = "messages.jsonl"
+	recordedFilesFile    = "files.jsonl"
+	recordedFilesDir     = "files"
+)
+
+// recordedMessage is a single message of a recorded session.
+type recordedMessage struct {
+	// Elapsed is the time since the start of the session.
+	Elapsed time.Duration `json:"elapsed"`
+
+	// FromClient reports whether the message was sent by the client.
+	FromClient bool `json:"fromClient"`
+
+	Message json.RawMessage `json:"message"`
+}
+
+// recordedFile is a snapshot of the content on disk of a file that the
+// client referred to, taken when it was first referred to.
+type recordedFile struct {
+	URI string `json:"uri"`
+
+	// Path is the path of the snapshot, relative to the recording
+	// directory. It is empty if the file did not exist.
+	Path string `json:"path,omitempty"`
+}
+
+// recordingStream is a jsonrpc2.Stream that records the messages that pass
+// through it, along with snapshots of the files they refer to, in a
+// directory that can be replayed with "gopls replay".
+type recordingStream struct {
+	stream jsonrpc2.Stream
+	dir    string
+	start  time.Time
+
+	mu       sync.Mutex
+	messages *os.File
+	files    *os.File
+	seen     map[string]bool
+}
+
+// newRecordingStream returns a stream that records the messages of stream
+// to dir, which is created if necessary. The stream must be closed once
+// the session is over.
+func newRecordingStream(stream jsonrpc2.Stream, dir string) (*recordingStream, error) {
+	if err := os.MkdirAll(filepath.Join(dir, recordedFilesDir), 0777); err != nil {
+		return nil, err
+	}
+	messages, err := os.Create(filepath.Join(dir, recordedMessagesFile))
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.Create(filepath.Join(dir, recordedFilesFile))
+	if err != nil {
+		messages.Close()
+		return nil, err
+	}
+	return &recordingStream{
+		stream:   stream,
+		dir:      dir,
+		start:    time.Now(),
+		messages: messages,
+		files:    files,
+		seen:     make(map[string]bool),
+	}, nil
+}
+
+func (s *recordingStream) Read(ctx context.Context) ([]byte, int64, error) {
+	data, count, err := s.stream.Read(ctx)
+	if err == nil {
+		s.record(data, true)
+	}
+	return data, count, err
+}
+
+func (s *recordingStream) Write(ctx context.Context, data []byte) (int64, error) {
+	s.record(data, false)
+	return s.stream.Write(ctx, data)
+}
+
+func (s *recordingStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.messages.Close()
+	if ferr := s.files.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// record records a message. Recording is best effort: failures are only
+// logged, so that they do not disrupt the session.
+func (s *recordingStream) record(data []byte, fromClient bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg := recordedMessage{
+		Elapsed:    time.Since(s.start),
+		FromClient: fromClient,
+		Message:    data,
+	}
+	if err := writeJSONLine(s.messages, msg); err != nil {
+		fmt.Fprintf(os.Stderr, "recording message: %v\n", err)
+	}
+	if fromClient {
+		if err := s.snapshotFile(data); err != nil {
+			fmt.Fprintf(os.Stderr, "recording file: %v\n", err)
+		}
+	}
+}
+
+// snapshotFile records the content of the text document referred to by the
+// given message from the client, if it has not been recorded already.
+func (s *recordingStream) snapshotFile(data []byte) error {
+	var msg protocol.Combined
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Params == nil {
+		return err
+	}
+	var params struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+	}
+	if err := json.Unmarshal(*msg.Params, &params); err != nil {
+		// Not all params are objects.
+		return nil
+	}
+	uri := params.TextDocument.URI
+	if uri == "" || s.seen[uri] {
+		return nil
+	}
+	s.seen[uri] = true
+	rec := recordedFile{URI: uri}
+	content, err := ioutil.ReadFile(span.NewURI(uri).Filename())
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return err
+	default:
+		rec.Path = filepath.Join(recordedFilesDir, fmt.Sprint(len(s.seen)))
+		if err := ioutil.WriteFile(filepath.Join(s.dir, rec.Path), content, 0666); err != nil {
+			return err
+		}
+	}
+	return writeJSONLine(s.files, rec)
+}
+
+func writeJSONLine(f *os.File, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// readRecording reads the messages and file snapshots recorded in dir.
+func readRecording(dir string) ([]recordedMessage, []recordedFile, error) {
+	var messages []recordedMessage
+	if err := readJSONLines(filepath.Join(dir, recordedMessagesFile), func(dec *json.Decoder) error {
+		var msg recordedMessage
+		if err := dec.Decode(&msg); err != nil {
+			return err
+		}
+		messages = append(messages, msg)
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+	var files []recordedFile
+	if err := readJSONLines(filepath.Join(dir, recordedFilesFile), func(dec *json.Decoder) error {
+		var f recordedFile
+		if err := dec.Decode(&f); err != nil {
+			return err
+		}
+		files = append(files, f)
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+	return messages, files, nil
+}
+
+func readJSONLines(filename string, decode func(*json.Decoder) error) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		if err := decode(dec); err != nil {
+			return errors.Errorf("reading %s: %w", filename, err)
+		}
+	}
+	return nil
+}
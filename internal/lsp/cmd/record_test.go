@@ -0,0 +1,128 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/internal/jsonrpc2"
+	"golang.org/x/tools/internal/lsp"
+	"golang.org/x/tools/internal/lsp/cache"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+)
+
+const recordedSource = `package a
+
+func A() {}
+`
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopls-record-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "a.go")
+	unopened := filepath.Join(dir, "b.go")
+	for name, content := range map[string]string{
+		"go.mod": "module example.com/a\n",
+		"a.go":   recordedSource,
+		"b.go":   "package a\n\nfunc B() {}\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recording := filepath.Join(dir, "recording")
+
+	// Record a short session.
+	ctx := context.Background()
+	clientConn, serverConn := net.Pipe()
+	rs, err := newRecordingStream(jsonrpc2.NewHeaderStream(serverConn, serverConn), recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		ctx, srv := lsp.NewServer(ctx, cache.New(nil), rs)
+		srv.Run(ctx)
+	}()
+	app := New("gopls-test", dir, nil, nil)
+	conn := newConnection(app)
+	ctx, jc, server := protocol.NewClient(ctx, jsonrpc2.NewHeaderStream(clientConn, clientConn), conn.Client)
+	go jc.Run(ctx)
+	conn.Server = server
+	if err := conn.initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	uri := protocol.NewURI(span.FileURI(filename))
+	if err := server.DidOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{
+			URI:        uri,
+			LanguageID: "go",
+			Version:    1,
+			Text:       recordedSource,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// The symbols of a file that is not open are those of its content on
+	// disk.
+	for _, uri := range []protocol.DocumentURI{uri, protocol.NewURI(span.FileURI(unopened))} {
+		if _, err := server.DocumentSymbol(ctx, &protocol.DocumentSymbolParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	clientConn.Close()
+	if err := rs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, files, err := readRecording(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].URI != string(uri) || files[0].Path == "" {
+		t.Errorf("got recorded files %v, want a snapshot of %s and b.go", files, uri)
+	}
+
+	// Replay it against a new server, with the file as it was recorded
+	// rather than as it is now.
+	changed := "package a\n\nfunc B() {}\n\nfunc C() {}\n"
+	if err := ioutil.WriteFile(unopened, []byte(changed), 0666); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := replaySession(context.Background(), cache.New(nil), recording, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for method, count := range map[string]int{
+		"initialize":                  1,
+		"textDocument/documentSymbol": 2,
+		"shutdown":                    1,
+	} {
+		s := stats[method]
+		if s == nil || s.count != count {
+			t.Errorf("%s: got stats %+v, want %d requests", method, s, count)
+			continue
+		}
+		if s.differ != 0 {
+			t.Errorf("%s: replayed result differs from the recording", method)
+		}
+	}
+	if content, err := ioutil.ReadFile(unopened); err != nil || string(content) != changed {
+		t.Errorf("the replay changed %s: %q, %v", unopened, content, err)
+	}
+}
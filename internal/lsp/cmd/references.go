@@ -0,0 +1,158 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/tool"
+	errors "golang.org/x/xerrors"
+)
+
+// A Reference is a result of a 'references' query.
+type Reference struct {
+	Span     span.Span            `json:"span"`               // span of the reference
+	Kind     source.ReferenceKind `json:"kind"`               // how the reference uses the object
+	Function string               `json:"function,omitempty"` // enclosing function declaration, such as T.M
+}
+
+// references implements the references verb for gopls.
+type references struct {
+	JSON bool `flag:"json" help:"emit output in JSON format"`
+
+	app *Application
+}
+
+func (r *references) Name() string      { return "references" }
+func (r *references) Usage() string     { return "<position or symbol>" }
+func (r *references) ShortHelp() string { return "list the references to an object" }
+func (r *references) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Lists the declaration of the object at the position or with the qualified
+name, and its references in the workspace, with the function that encloses
+each reference and how it uses the object: declaration, read, write, address
+(taken), call, or other, for the references that are not identifiers.
+
+Example:
+
+  $ # 1-based location (:line:column or :#position) of the identifier
+  $ gopls references helper/helper.go:8:6
+  $ # a symbol of the workspace, qualified by its package or type
+  $ gopls references -json helper.Buffer.Write
+
+	gopls references flags are:
+`)
+	f.PrintDefaults()
+}
+
+// Run lists the references to the object at the position or with the name of
+// args, sorted by span.
+func (r *references) Run(ctx context.Context, args ...string) error {
+	if len(args) != 1 {
+		return tool.CommandLineErrorf("references expects 1 argument (position or symbol)")
+	}
+	conn, err := r.app.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.terminate(ctx)
+
+	loc, err := referencedLocation(ctx, conn, args[0])
+	if err != nil {
+		return err
+	}
+	locs, err := conn.References(ctx, &protocol.ReferenceParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: loc.URI},
+			Position:     loc.Range.Start,
+		},
+		Context: protocol.ReferenceContext{IncludeDeclaration: true},
+	})
+	if err != nil {
+		return errors.Errorf("%v: %v", args[0], err)
+	}
+
+	var result []Reference
+	fset := token.NewFileSet()
+	for _, loc := range locs {
+		file := conn.AddFile(ctx, span.NewURI(loc.URI))
+		if file.err != nil {
+			return file.err
+		}
+		spn, err := file.mapper.Span(loc)
+		if err != nil {
+			return err
+		}
+		ref := Reference{Span: spn, Kind: source.ReferenceOther}
+		if f, _ := parser.ParseFile(fset, spn.URI().Filename(), file.mapper.Content, 0); f != nil {
+			tok := fset.File(f.Pos())
+			if offset := spn.Start().Offset(); offset < tok.Size() {
+				ref.Kind, ref.Function = source.DescribeReference(f, tok.Pos(offset))
+			}
+		}
+		result = append(result, ref)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return span.Compare(result[i].Span, result[j].Span) < 0
+	})
+
+	if r.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		return enc.Encode(result)
+	}
+	for _, ref := range result {
+		fmt.Printf("%v\t%s\t%s\n", ref.Span, ref.Kind, ref.Function)
+	}
+	return nil
+}
+
+// referencedLocation returns the location of the position arg, or of the
+// declaration of the workspace symbol named arg, such as pkg.T.M or T.M.
+func referencedLocation(ctx context.Context, conn *connection, arg string) (protocol.Location, error) {
+	// Positions have a :line:column or :#offset suffix, and symbols no colons.
+	if strings.Contains(arg, ":") {
+		from := span.Parse(arg)
+		file := conn.AddFile(ctx, from.URI())
+		if file.err != nil {
+			return protocol.Location{}, file.err
+		}
+		return file.mapper.Location(from)
+	}
+	name := arg[strings.LastIndex(arg, ".")+1:]
+	symbols, err := conn.Symbol(ctx, &protocol.WorkspaceSymbolParams{Query: "^" + name})
+	if err != nil {
+		return protocol.Location{}, err
+	}
+	var matches []protocol.SymbolInformation
+	for _, sym := range symbols {
+		qualified := sym.ContainerName + "." + sym.Name
+		if sym.Name == arg || qualified == arg || strings.HasSuffix(qualified, "."+arg) {
+			matches = append(matches, sym)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return protocol.Location{}, errors.Errorf("no symbol %s in the workspace", arg)
+	case 1:
+		return matches[0].Location, nil
+	}
+	var names []string
+	for _, sym := range matches {
+		names = append(names, sym.ContainerName+"."+sym.Name)
+	}
+	return protocol.Location{}, errors.Errorf("ambiguous symbol %s: %s", arg, strings.Join(names, ", "))
+}
@@ -8,6 +8,8 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"go/parser"
+	"go/token"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -25,6 +27,7 @@ import (
 type rename struct {
 	Diff  bool `flag:"d" help:"display diffs instead of rewriting files"`
 	Write bool `flag:"w" help:"write result to (source) file instead of stdout"`
+	Dir   bool `flag:"dir" help:"when renaming a package, also rename its directory if it has the name of the package"`
 
 	app *Application
 }
@@ -39,6 +42,8 @@ Example:
   $ # 1-based location (:line:column or :#position) of the thing to change
   $ gopls rename helper/helper.go:8:6
   $ gopls rename helper/helper.go:#53
+  $ # rename the package of the package clause and, with -w, its directory
+  $ gopls rename -dir -w helper/helper.go:1:9 util
 
 	gopls rename flags are:
 `)
@@ -49,10 +54,15 @@ Example:
 // - if -w is specified, updates the file(s) in place;
 // - if -d is specified, prints out unified diffs of the changes; or
 // - otherwise, prints the new versions to stdout.
+// If -dir and -w are specified and a package is renamed, its directory is
+// renamed too.
 func (r *rename) Run(ctx context.Context, args ...string) error {
 	if len(args) != 2 {
 		return tool.CommandLineErrorf("definition expects 2 arguments (position, new name)")
 	}
+	if r.Dir {
+		r.app.settings = map[string]interface{}{"renamePackageDirectory": true}
+	}
 	conn, err := r.app.connect(ctx)
 	if err != nil {
 		return err
@@ -79,6 +89,13 @@ func (r *rename) Run(ctx context.Context, args ...string) error {
 	if err != nil {
 		return err
 	}
+	var dir string
+	if r.Dir && r.Write {
+		dir, err = renamedDirectory(file, loc.Range.Start)
+		if err != nil {
+			return err
+		}
+	}
 
 	// Make output order predictable
 	var keys []string
@@ -124,5 +141,33 @@ func (r *rename) Run(ctx context.Context, args ...string) error {
 			changeCount -= 1
 		}
 	}
+	if dir != "" {
+		newDir := filepath.Join(filepath.Dir(dir), args[1])
+		fmt.Fprintln(os.Stderr, newDir)
+		if err := os.Rename(dir, newDir); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// renamedDirectory returns the directory of file if pos is in the name of its
+// package clause and the directory has that name, or "" if it is not.
+func renamedDirectory(file *cmdFile, pos protocol.Position) (string, error) {
+	spn, err := file.mapper.PointSpan(pos)
+	if err != nil {
+		return "", err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file.uri.Filename(), file.mapper.Content, parser.PackageClauseOnly)
+	if err != nil {
+		return "", err
+	}
+	offset := spn.Start().Offset()
+	start, end := fset.Position(f.Name.Pos()).Offset, fset.Position(f.Name.End()).Offset
+	dir := filepath.Dir(file.uri.Filename())
+	if offset < start || offset > end || filepath.Base(dir) != f.Name.Name {
+		return "", nil
+	}
+	return dir, nil
+}
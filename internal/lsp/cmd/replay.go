@@ -0,0 +1,442 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/tools/internal/jsonrpc2"
+	"golang.org/x/tools/internal/lsp"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/tool"
+	errors "golang.org/x/xerrors"
+)
+
+// replay implements the replay command.
+type replay struct {
+	Diff bool `flag:"diff" help:"print the recorded and replayed results of requests whose results differ"`
+
+	app *Application
+}
+
+func (r *replay) Name() string      { return "replay" }
+func (r *replay) Usage() string     { return "<directory>" }
+func (r *replay) ShortHelp() string { return "replay a session recorded by serve -record" }
+func (r *replay) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Replays the client messages of a session recorded with "gopls serve -record"
+against an in-process server, and reports the latency of each kind of request
+in the recording and in the replay, along with the number of requests whose
+results differ. The session is replayed on a copy of its workspace folders,
+in a temporary directory, with the files it refers to as they were recorded.
+
+Example:
+
+  $ gopls serve -record=/tmp/session
+  $ gopls replay -diff /tmp/session
+
+gopls replay flags are:
+`)
+	f.PrintDefaults()
+}
+
+// replayStats are the statistics of the requests of a single method.
+type replayStats struct {
+	count    int
+	recorded time.Duration
+	replayed time.Duration
+	differ   int
+}
+
+// Run replays the recording in the directory given by args.
+func (r *replay) Run(ctx context.Context, args ...string) error {
+	if len(args) != 1 {
+		return tool.CommandLineErrorf("replay expects 1 argument (directory)")
+	}
+	var diff io.Writer
+	if r.Diff {
+		diff = os.Stdout
+	}
+	stats, err := replaySession(ctx, r.app.cache, args[0], diff)
+	if err != nil {
+		return err
+	}
+	printReplayStats(os.Stdout, stats)
+	return nil
+}
+
+// replaySession replays the session recorded in dir against a new server
+// using cache, and returns the statistics of its requests by method. If diff
+// is not nil, the results of requests that differ are written to it.
+func replaySession(ctx context.Context, cache source.Cache, dir string, diff io.Writer) (map[string]*replayStats, error) {
+	messages, files, err := readRecording(dir)
+	if err != nil {
+		return nil, err
+	}
+	workspace, err := ioutil.TempDir("", "gopls-replay-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workspace)
+	toReplay, toRecorded, err := restoreRecordedFiles(dir, workspace, messages, files)
+	if err != nil {
+		return nil, err
+	}
+	// The client messages refer to the copies of the files, and the results
+	// of the server to the originals again, to compare them.
+	for i, m := range messages {
+		if m.FromClient {
+			messages[i].Message = json.RawMessage(toReplay.Replace(string(m.Message)))
+		}
+	}
+
+	// Index the recorded messages that the replay needs: the results and
+	// latencies of client requests, and the client's responses to server
+	// requests, by method.
+	var (
+		requestTimes    = make(map[string]time.Duration)
+		recordedResults = make(map[string]*protocol.Combined)
+		latencies       = make(map[string]time.Duration)
+		serverRequests  = make(map[string]string)
+		clientResponses = make(map[string][]*protocol.Combined)
+	)
+	for _, m := range messages {
+		msg, err := decodeRecordedMessage(m)
+		if err != nil {
+			return nil, err
+		}
+		if msg.ID == nil {
+			continue
+		}
+		id := msg.ID.String()
+		switch {
+		case m.FromClient && msg.Method != "":
+			requestTimes[id] = m.Elapsed
+		case m.FromClient:
+			if method, ok := serverRequests[id]; ok {
+				clientResponses[method] = append(clientResponses[method], msg)
+			}
+		case msg.Method != "":
+			serverRequests[id] = msg.Method
+		default:
+			recordedResults[id] = msg
+			latencies[id] = m.Elapsed - requestTimes[id]
+		}
+	}
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go func() {
+		ctx, srv := lsp.NewServer(ctx, cache, jsonrpc2.NewHeaderStream(serverConn, serverConn))
+		srv.Run(ctx)
+	}()
+	stream := jsonrpc2.NewHeaderStream(clientConn, clientConn)
+
+	// Read the messages from the server, answering its requests with the
+	// responses of the recorded client and passing on the responses to the
+	// replayed requests.
+	responses := make(chan *protocol.Combined)
+	go func() {
+		defer close(responses)
+		for {
+			data, _, err := stream.Read(ctx)
+			if err != nil {
+				return
+			}
+			data = []byte(toRecorded.Replace(string(data)))
+			msg := &protocol.Combined{}
+			if err := json.Unmarshal(data, msg); err != nil || msg.ID == nil {
+				continue
+			}
+			if msg.Method == "" {
+				responses <- msg
+				continue
+			}
+			reply := &protocol.Combined{VersionTag: msg.VersionTag, ID: msg.ID}
+			if queue := clientResponses[msg.Method]; len(queue) > 0 {
+				reply.Result, reply.Error = queue[0].Result, queue[0].Error
+				clientResponses[msg.Method] = queue[1:]
+			}
+			if reply.Result == nil && reply.Error == nil {
+				null := json.RawMessage("null")
+				reply.Result = &null
+			}
+			if err := writeMessage(ctx, stream, reply); err != nil {
+				return
+			}
+		}
+	}()
+
+	stats := make(map[string]*replayStats)
+	for _, m := range messages {
+		if !m.FromClient {
+			continue
+		}
+		msg, err := decodeRecordedMessage(m)
+		if err != nil {
+			return nil, err
+		}
+		// Responses to server requests are sent when the server asks, and
+		// exit would terminate this process.
+		if msg.Method == "" || msg.Method == "exit" {
+			continue
+		}
+		start := time.Now()
+		if _, err := stream.Write(ctx, m.Message); err != nil {
+			return nil, err
+		}
+		if msg.ID == nil {
+			continue
+		}
+		id := msg.ID.String()
+		var result *protocol.Combined
+		for resp := range responses {
+			if resp.ID.String() == id {
+				result = resp
+				break
+			}
+		}
+		if result == nil {
+			return nil, errors.Errorf("server closed the connection before responding to %s %s", msg.Method, id)
+		}
+		s := stats[msg.Method]
+		if s == nil {
+			s = &replayStats{}
+			stats[msg.Method] = s
+		}
+		s.count++
+		s.recorded += latencies[id]
+		s.replayed += time.Since(start)
+		if recorded := recordedResults[id]; recorded != nil && !sameResult(recorded, result) {
+			s.differ++
+			if diff != nil {
+				fmt.Fprintf(diff, "%s %s differs:\nrecorded: %s\nreplayed: %s\n", msg.Method, id, resultString(recorded), resultString(result))
+			}
+		}
+	}
+	return stats, nil
+}
+
+func decodeRecordedMessage(m recordedMessage) (*protocol.Combined, error) {
+	msg := &protocol.Combined{}
+	if err := json.Unmarshal(m.Message, msg); err != nil {
+		return nil, errors.Errorf("decoding recorded message: %w", err)
+	}
+	return msg, nil
+}
+
+func writeMessage(ctx context.Context, stream jsonrpc2.Stream, msg *protocol.Combined) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = stream.Write(ctx, data)
+	return err
+}
+
+// restoreRecordedFiles copies the workspace folders of the session recorded
+// in dir into workspace, and restores there the content that the files of
+// the session had when it was recorded. It returns the replacers of the URIs
+// of the folders by those of their copies, and back.
+//
+// The files outside of the folders, such as those of the module cache, are
+// not copied, and a warning is printed for those that have changed since the
+// session was recorded, as the results of the replay will differ for them.
+func restoreRecordedFiles(dir, workspace string, messages []recordedMessage, files []recordedFile) (toReplay, toRecorded *strings.Replacer, err error) {
+	folders, err := recordedFolders(messages)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The nested folders are replaced first.
+	sort.Slice(folders, func(i, j int) bool { return len(folders[i]) > len(folders[j]) })
+	copies := make([]string, len(folders))
+	var pairs, reverse []string
+	for i, folder := range folders {
+		copies[i] = filepath.Join(workspace, fmt.Sprint(i), filepath.Base(folder))
+		if err := copyDir(folder, copies[i]); err != nil {
+			return nil, nil, errors.Errorf("copying the workspace folder %s: %w", folder, err)
+		}
+		uri, copyURI := string(span.FileURI(folder)), string(span.FileURI(copies[i]))
+		pairs = append(pairs, uri, copyURI)
+		reverse = append(reverse, copyURI, uri)
+	}
+
+	for _, f := range files {
+		filename := span.NewURI(f.URI).Filename()
+		target := ""
+		for i, folder := range folders {
+			if rel, err := filepath.Rel(folder, filename); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				target = filepath.Join(copies[i], rel)
+				break
+			}
+		}
+		if target == "" {
+			checkRecordedFile(dir, filename, f)
+			continue
+		}
+		if f.Path == "" {
+			os.Remove(target)
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, f.Path))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0777); err != nil {
+			return nil, nil, err
+		}
+		if err := ioutil.WriteFile(target, content, 0666); err != nil {
+			return nil, nil, err
+		}
+	}
+	return strings.NewReplacer(pairs...), strings.NewReplacer(reverse...), nil
+}
+
+// recordedFolders returns the directories of the workspace folders of the
+// initialize request of a recorded session.
+func recordedFolders(messages []recordedMessage) ([]string, error) {
+	for _, m := range messages {
+		if !m.FromClient {
+			continue
+		}
+		msg, err := decodeRecordedMessage(m)
+		if err != nil {
+			return nil, err
+		}
+		if msg.Method != "initialize" || msg.Params == nil {
+			continue
+		}
+		var params protocol.ParamInitia
+		if err := json.Unmarshal(*msg.Params, &params); err != nil {
+			return nil, errors.Errorf("decoding the recorded initialize request: %w", err)
+		}
+		var folders []string
+		for _, folder := range params.WorkspaceFolders {
+			folders = append(folders, span.NewURI(folder.URI).Filename())
+		}
+		if len(folders) == 0 && params.RootURI != "" {
+			folders = append(folders, span.NewURI(params.RootURI).Filename())
+		}
+		return folders, nil
+	}
+	return nil, errors.New("the recorded session has no initialize request")
+}
+
+// copyDir copies the files of the directory src to dst, which is created,
+// except those of version control.
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			if info.Name() == ".git" || info.Name() == ".hg" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0777)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(target, content, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+// checkRecordedFile warns about the file f, with the given name, if its
+// content on disk is not the content it had when the session was recorded.
+func checkRecordedFile(dir, filename string, f recordedFile) {
+	content, err := ioutil.ReadFile(filename)
+	if f.Path == "" {
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "warning: %s did not exist when the session was recorded\n", filename)
+		}
+		return
+	}
+	recorded, rerr := ioutil.ReadFile(filepath.Join(dir, f.Path))
+	if err != nil || rerr != nil || !bytes.Equal(content, recorded) {
+		fmt.Fprintf(os.Stderr, "warning: %s has changed since the session was recorded\n", filename)
+	}
+}
+
+// sameResult reports whether two responses have equivalent results,
+// ignoring the formatting of their JSON.
+func sameResult(a, b *protocol.Combined) bool {
+	if (a.Error == nil) != (b.Error == nil) {
+		return false
+	}
+	if a.Error != nil {
+		return a.Error.Code == b.Error.Code
+	}
+	var av, bv interface{}
+	if a.Result != nil {
+		if err := json.Unmarshal(*a.Result, &av); err != nil {
+			return false
+		}
+	}
+	if b.Result != nil {
+		if err := json.Unmarshal(*b.Result, &bv); err != nil {
+			return false
+		}
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+func resultString(msg *protocol.Combined) string {
+	switch {
+	case msg.Error != nil:
+		return msg.Error.Error()
+	case msg.Result != nil:
+		return string(*msg.Result)
+	default:
+		return "null"
+	}
+}
+
+func printReplayStats(w io.Writer, stats map[string]*replayStats) {
+	methods := make([]string, 0, len(stats))
+	for method := range stats {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "method\tcount\trecorded\treplayed\tdiffer")
+	for _, method := range methods {
+		s := stats[method]
+		n := time.Duration(s.count)
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%d\n", method, s.count, s.recorded/n, s.replayed/n, s.differ)
+	}
+	tw.Flush()
+}
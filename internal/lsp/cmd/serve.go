@@ -11,7 +11,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"os"
 	"path/filepath"
 	"time"
@@ -26,6 +25,10 @@ import (
 	errors "golang.org/x/xerrors"
 )
 
+// defaultSlowThresholds are the latencies above which requests are logged as
+// slow, unless the -slow flag says otherwise.
+const defaultSlowThresholds = "1s,textDocument/completion=500ms"
+
 // Serve is a struct that exposes the configurable parts of the LSP server as
 // flags, in the right form for tool.Main to consume.
 type Serve struct {
@@ -35,6 +38,8 @@ type Serve struct {
 	Address string `flag:"listen" help:"address on which to listen for remote connections"`
 	Trace   bool   `flag:"rpc.trace" help:"Print the full rpc trace in lsp inspector format"`
 	Debug   string `flag:"debug" help:"Serve debug information on the supplied address"`
+	Record  string `flag:"record" help:"record all LSP traffic and the files it refers to in the given directory, for use with the replay command"`
+	Slow    string `flag:"slow" help:"log the requests that take longer than the given thresholds, such as 1s,textDocument/completion=200ms, or off"`
 
 	app *Application
 }
@@ -60,6 +65,14 @@ func (s *Serve) Run(ctx context.Context, args ...string) error {
 	if len(args) > 0 {
 		return tool.CommandLineErrorf("server does not take arguments, got %v", args)
 	}
+	slow := s.Slow
+	if slow == "" {
+		slow = defaultSlowThresholds
+	}
+	thresholds, err := debug.ParseSlowThresholds(slow)
+	if err != nil {
+		return tool.CommandLineErrorf("%v", err)
+	}
 	out := os.Stderr
 	if s.Logfile != "" {
 		filename := s.Logfile
@@ -75,10 +88,11 @@ func (s *Serve) Run(ctx context.Context, args ...string) error {
 		out = f
 	}
 
+	debug.TrackSlowRequests(thresholds)
 	debug.Serve(ctx, s.Debug)
 
 	if s.app.Remote != "" {
-		return s.forward()
+		return newForwarder(s.app.Remote, jsonrpc2.NewHeaderStream(os.Stdin, os.Stdout)).run(ctx)
 	}
 
 	// For debugging purposes only.
@@ -95,30 +109,18 @@ func (s *Serve) Run(ctx context.Context, args ...string) error {
 	if s.Trace {
 		stream = protocol.LoggingStream(stream, out)
 	}
+	if s.Record != "" {
+		rs, err := newRecordingStream(stream, s.Record)
+		if err != nil {
+			return errors.Errorf("Unable to record session: %v", err)
+		}
+		defer rs.Close()
+		stream = rs
+	}
 	ctx, srv := lsp.NewServer(ctx, s.app.cache, stream)
 	return srv.Run(ctx)
 }
 
-func (s *Serve) forward() error {
-	conn, err := net.Dial("tcp", s.app.Remote)
-	if err != nil {
-		return err
-	}
-	errc := make(chan error)
-
-	go func(conn net.Conn) {
-		_, err := io.Copy(conn, os.Stdin)
-		errc <- err
-	}(conn)
-
-	go func(conn net.Conn) {
-		_, err := io.Copy(os.Stdout, conn)
-		errc <- err
-	}(conn)
-
-	return <-errc
-}
-
 type handler struct {
 	out io.Writer
 }
@@ -189,6 +191,9 @@ func (h *handler) Done(ctx context.Context, err error) {
 	latencyMillis := float64(elapsedTime) / float64(time.Millisecond)
 	telemetry.Latency.Record(ctx, latencyMillis)
 	stats.close()
+	if stats.direction == jsonrpc2.Receive {
+		debug.LogSlowRequest(ctx)
+	}
 }
 
 func (h *handler) Read(ctx context.Context, bytes int64) context.Context {
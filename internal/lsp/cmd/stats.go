@@ -0,0 +1,211 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/internal/lsp/debug"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/tool"
+)
+
+// workspaceStats implements the stats command.
+type workspaceStats struct {
+	Anon bool `flag:"anon" help:"omit the names of the workspace, its packages and its files, so that the report can be shared"`
+	JSON bool `flag:"json" help:"emit output in JSON format"`
+
+	app *Application
+}
+
+func (s *workspaceStats) Name() string  { return "stats" }
+func (s *workspaceStats) Usage() string { return "" }
+func (s *workspaceStats) ShortHelp() string {
+	return "print statistics about the workspace of the current directory"
+}
+func (s *workspaceStats) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Loads and type-checks the packages of the current directory and below, as
+an editor opening them would, and prints the shape of the workspace: the
+number of packages, files and lines, the largest packages, the time spent
+loading and type-checking, the use of the parse cache, and the memory in
+use afterwards.
+
+With -anon, the report omits the names of the workspace and its packages,
+so that it can be attached to a report of a performance problem.
+
+Example:
+
+  $ gopls stats -anon
+
+stats flags are:
+`)
+	f.PrintDefaults()
+}
+
+// statsReport is the output of the stats command.
+type statsReport struct {
+	GoVersion string `json:"goVersion"`
+	GOOS      string `json:"goos"`
+	GOARCH    string `json:"goarch"`
+	Folder    string `json:"folder,omitempty"`
+
+	Packages int `json:"packages"`
+	Files    int `json:"files"`
+	Lines    int `json:"lines"`
+
+	// Largest lists the packages with the most lines, largest first.
+	Largest []packageSize `json:"largest"`
+
+	LoadTime      time.Duration          `json:"loadTime"`
+	TypeCheckTime time.Duration          `json:"typeCheckTime"`
+	ParseCache    source.ParseCacheStats `json:"parseCache"`
+
+	// HeapAlloc is the heap in use once the workspace is type-checked,
+	// after a garbage collection.
+	HeapAlloc uint64 `json:"heapAlloc"`
+}
+
+// packageSize is the size of a package of the workspace.
+type packageSize struct {
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	Lines int    `json:"lines"`
+}
+
+// largestPackages is the number of packages listed in the Largest field of
+// a statsReport.
+const largestPackages = 10
+
+func (s *workspaceStats) Run(ctx context.Context, args ...string) error {
+	if len(args) > 0 {
+		return tool.CommandLineErrorf("stats does not take arguments, got %v", args)
+	}
+	if s.app.Remote != "" {
+		return tool.CommandLineErrorf("stats loads the workspace itself and does not use the -remote flag")
+	}
+	session := s.app.cache.NewSession(ctx)
+	defer session.Shutdown(ctx)
+	options := session.Options()
+	options.Env = s.app.env
+	if s.app.PrepareOptions != nil {
+		s.app.PrepareOptions(&options)
+	}
+	view := session.NewView(ctx, "stats", span.FileURI(s.app.wd), options)
+
+	report := &statsReport{
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		Folder:    s.app.wd,
+	}
+	start := time.Now()
+	cfg := view.Config(ctx)
+	cfg.Mode = packages.NeedName | packages.NeedFiles
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return err
+	}
+	report.LoadTime = time.Since(start)
+
+	var sizes []packageSize
+	for _, pkg := range pkgs {
+		size := packageSize{Path: pkg.PkgPath, Files: len(pkg.GoFiles)}
+		for _, filename := range pkg.GoFiles {
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+			size.Lines += bytes.Count(data, []byte("\n"))
+		}
+		report.Packages++
+		report.Files += size.Files
+		report.Lines += size.Lines
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i].Lines > sizes[j].Lines })
+	if len(sizes) > largestPackages {
+		sizes = sizes[:largestPackages]
+	}
+	report.Largest = sizes
+
+	// Type-check each package through the view, so that the cache is used
+	// as it is for an editor.
+	start = time.Now()
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) == 0 {
+			continue
+		}
+		f, err := view.GetFile(ctx, span.FileURI(pkg.GoFiles[0]))
+		if err != nil {
+			return err
+		}
+		_, cphs, err := view.CheckPackageHandles(ctx, f)
+		if err != nil {
+			return err
+		}
+		for _, cph := range cphs {
+			if _, err := cph.Check(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	report.TypeCheckTime = time.Since(start)
+	report.ParseCache = s.app.cache.ParseCacheStats()
+
+	runtime.GC()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	report.HeapAlloc = mem.HeapAlloc
+
+	if s.Anon {
+		report.anonymize()
+	}
+	if s.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		return enc.Encode(report)
+	}
+	printStatsReport(os.Stdout, report)
+	return nil
+}
+
+// anonymize removes the names of the workspace and its packages from r.
+func (r *statsReport) anonymize() {
+	r.Folder = ""
+	for i := range r.Largest {
+		r.Largest[i].Path = fmt.Sprintf("package%d", i+1)
+	}
+}
+
+func printStatsReport(w io.Writer, r *statsReport) {
+	fmt.Fprintf(w, "gopls %s, %s %s/%s\n", debug.Version, r.GoVersion, r.GOOS, r.GOARCH)
+	if r.Folder != "" {
+		fmt.Fprintf(w, "workspace: %s\n", r.Folder)
+	}
+	fmt.Fprintf(w, "%d packages, %d files, %d lines\n", r.Packages, r.Files, r.Lines)
+	fmt.Fprintf(w, "load time: %v, type check time: %v\n", r.LoadTime, r.TypeCheckTime)
+	pc := r.ParseCache
+	fmt.Fprintf(w, "parse cache: %d/%d files, %d hits, %d misses, %d evictions\n", pc.Size, pc.Capacity, pc.Hits, pc.Misses, pc.Evictions)
+	fmt.Fprintf(w, "heap in use: %d bytes\n", r.HeapAlloc)
+	if len(r.Largest) > 0 {
+		fmt.Fprintf(w, "largest packages:\n")
+		for _, p := range r.Largest {
+			fmt.Fprintf(w, "  %s: %d files, %d lines\n", p.Path, p.Files, p.Lines)
+		}
+	}
+}
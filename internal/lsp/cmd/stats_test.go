@@ -0,0 +1,36 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStatsReportAnon(t *testing.T) {
+	r := &statsReport{
+		Folder:   "/home/user/secret",
+		Packages: 2,
+		Files:    3,
+		Lines:    120,
+		Largest: []packageSize{
+			{Path: "example.com/secret/a", Files: 2, Lines: 100},
+			{Path: "example.com/secret/b", Files: 1, Lines: 20},
+		},
+	}
+	r.anonymize()
+	var buf bytes.Buffer
+	printStatsReport(&buf, r)
+	out := buf.String()
+	if strings.Contains(out, "secret") {
+		t.Errorf("anonymized report contains a name of the workspace:\n%s", out)
+	}
+	for _, want := range []string{"2 packages, 3 files, 120 lines", "package1: 2 files, 100 lines", "package2: 1 files, 20 lines"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report does not contain %q:\n%s", want, out)
+		}
+	}
+}
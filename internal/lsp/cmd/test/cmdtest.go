@@ -70,6 +70,10 @@ func (r *runner) FoldingRange(t *testing.T, spn span.Span) {
 	//TODO: add command line folding range tests when it works
 }
 
+func (r *runner) SelectionRange(t *testing.T, spn span.Span) {
+	//TODO: add command line selection range tests when it works
+}
+
 func (r *runner) Highlight(t *testing.T, name string, locations []span.Span) {
 	//TODO: add command line highlight tests when it works
 }
@@ -94,6 +98,14 @@ func (r *runner) Link(t *testing.T, uri span.URI, wantLinks []tests.Link) {
 	//TODO: add command line link tests when it works
 }
 
+func (r *runner) CodeLens(t *testing.T, uri span.URI, want []protocol.CodeLens) {
+	//TODO: add command line code lens tests when it works
+}
+
+func (r *runner) PackageTests(t *testing.T, spn span.Span) {
+	//TODO: add command line package tests tests when it works
+}
+
 func (r *runner) Import(t *testing.T, spn span.Span) {
 	//TODO: add command line imports tests when it works
 }
@@ -7,6 +7,7 @@ package lsp
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"sort"
 	"strings"
 
@@ -54,23 +55,62 @@ func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionPara
 	var codeActions []protocol.CodeAction
 	switch fileKind {
 	case source.Mod:
-		if !wanted[protocol.SourceOrganizeImports] {
-			return nil, nil
+		if wanted[protocol.SourceOrganizeImports] {
+			codeActions = append(codeActions, protocol.CodeAction{
+				Title: "Tidy",
+				Kind:  protocol.SourceOrganizeImports,
+				Command: &protocol.Command{
+					Title:   "Tidy",
+					Command: "tidy",
+					Arguments: []interface{}{
+						f.URI(),
+					},
+				},
+			})
 		}
-		codeActions = append(codeActions, protocol.CodeAction{
-			Title: "Tidy",
-			Kind:  protocol.SourceOrganizeImports,
-			Command: &protocol.Command{
-				Title:   "Tidy",
-				Command: "tidy",
-				Arguments: []interface{}{
-					f.URI(),
+		// Offer to bring the modules nested in a workspace folder into a
+		// go.work file, rather than working on each of them in its own view.
+		if folder := s.workspaceFolderView(uri); wanted[protocol.Source] && folder != nil && !hasWorkFile(folder.Folder()) &&
+			span.FileURI(filepath.Dir(uri.Filename())) != folder.Folder() {
+			codeActions = append(codeActions, protocol.CodeAction{
+				Title: source.CreateWorkTitle,
+				Kind:  protocol.Source,
+				Command: &protocol.Command{
+					Title:   source.CreateWorkTitle,
+					Command: "creatework",
+					Arguments: []interface{}{
+						folder.Folder(),
+					},
 				},
-			},
-		})
+			})
+		}
+		if diagnostics := params.Context.Diagnostics; wanted[protocol.QuickFix] && len(diagnostics) > 0 {
+			codeActions = append(codeActions, replaceQuickFixes(ctx, view, f, diagnostics)...)
+			codeActions = append(codeActions, goSumQuickFixes(ctx, view, f, diagnostics)...)
+		}
+		if wanted[protocol.RefactorRewrite] {
+			fixes, err := source.ReplaceActions(ctx, view, f, params.Range)
+			if err != nil {
+				log.Error(ctx, "failed to compute replace actions", err, telemetry.File.Of(uri))
+			}
+			for _, fix := range fixes {
+				codeActions = append(codeActions, fixAction(fix, protocol.RefactorRewrite, nil))
+			}
+		}
 	case source.Go:
 		edits, editsPerFix, err := source.AllImportsFixes(ctx, view, f)
 		if err != nil {
+			// No package of the module can be loaded until its vendor
+			// directory is in sync with go.mod, which is the only fix.
+			var vendorErr *source.InconsistentVendoringError
+			if errors.As(err, &vendorErr) && wanted[protocol.QuickFix] {
+				return vendorActions(f, params.Context.Diagnostics), nil
+			}
+			// The fixes of go.sum problems are offered on go.mod.
+			var sumErr *source.GoSumError
+			if errors.As(err, &sumErr) {
+				return nil, nil
+			}
 			return nil, err
 		}
 		if diagnostics := params.Context.Diagnostics; wanted[protocol.QuickFix] && len(diagnostics) > 0 {
@@ -81,6 +121,12 @@ func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionPara
 			}
 			codeActions = append(codeActions, qf...)
 
+			// Offer to install the missing tools of //go:generate directives.
+			codeActions = append(codeActions, generateActions(ctx, view, f, diagnostics)...)
+
+			// Offer to suppress the diagnostics of analyzers with a comment.
+			codeActions = append(codeActions, ignoreDiagnosticsActions(ctx, view, f, diagnostics)...)
+
 			// If we also have diagnostics for missing imports, we can associate them with quick fixes.
 			if findImportErrors(diagnostics) {
 				// Separate this into a set of codeActions per diagnostic, where
@@ -102,6 +148,56 @@ func (s *Server) codeAction(ctx context.Context, params *protocol.CodeActionPara
 				}
 			}
 		}
+		if wanted[protocol.RefactorRewrite] {
+			fix, err := source.AddDocComment(ctx, view, f, params.Range)
+			if err != nil {
+				log.Error(ctx, "failed to add doc comment", err, telemetry.File.Of(uri))
+			} else if fix != nil {
+				changes := make(map[string][]protocol.TextEdit)
+				for uri, e := range fix.Edits {
+					changes[protocol.NewURI(uri)] = e
+				}
+				codeActions = append(codeActions, protocol.CodeAction{
+					Title: fix.Title,
+					Kind:  protocol.RefactorRewrite,
+					Edit: &protocol.WorkspaceEdit{
+						Changes: &changes,
+					},
+				})
+			}
+			fix, err = source.AddStructTags(ctx, view, f, params.Range)
+			if err != nil {
+				log.Error(ctx, "failed to add struct tags", err, telemetry.File.Of(uri))
+			} else if fix != nil {
+				changes := make(map[string][]protocol.TextEdit)
+				for uri, e := range fix.Edits {
+					changes[protocol.NewURI(uri)] = e
+				}
+				codeActions = append(codeActions, protocol.CodeAction{
+					Title: fix.Title,
+					Kind:  protocol.RefactorRewrite,
+					Edit: &protocol.WorkspaceEdit{
+						Changes: &changes,
+					},
+				})
+			}
+			fix, err = source.GenerateTypeFromLiteral(ctx, view, f, params.Range)
+			if err != nil {
+				log.Error(ctx, "failed to generate type", err, telemetry.File.Of(uri))
+			} else if fix != nil {
+				changes := make(map[string][]protocol.TextEdit)
+				for uri, e := range fix.Edits {
+					changes[protocol.NewURI(uri)] = e
+				}
+				codeActions = append(codeActions, protocol.CodeAction{
+					Title: fix.Title,
+					Kind:  protocol.RefactorRewrite,
+					Edit: &protocol.WorkspaceEdit{
+						Changes: &changes,
+					},
+				})
+			}
+		}
 		if wanted[protocol.SourceOrganizeImports] && len(edits) > 0 {
 			codeActions = append(codeActions, protocol.CodeAction{
 				Title: "Organize Imports",
@@ -205,6 +301,158 @@ func importDiagnostics(fix *imports.ImportFix, diagnostics []protocol.Diagnostic
 	return results
 }
 
+// generateActions returns the code actions that install the missing tool of
+// a //go:generate directive, and then run the directive.
+func generateActions(ctx context.Context, view source.View, f source.File, diagnostics []protocol.Diagnostic) []protocol.CodeAction {
+	var codeActions []protocol.CodeAction
+	for _, diag := range diagnostics {
+		if diag.Source != source.GenerateSource {
+			continue
+		}
+		title, args, err := source.GenerateFix(ctx, view, f, diag)
+		if err != nil {
+			continue
+		}
+		codeActions = append(codeActions, protocol.CodeAction{
+			Title:       title,
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			Command: &protocol.Command{
+				Title:     title,
+				Command:   "generate",
+				Arguments: []interface{}{args},
+			},
+		})
+	}
+	return codeActions
+}
+
+// replaceQuickFixes returns the fixes of the diagnostics of the replace
+// directives of a go.mod file.
+func replaceQuickFixes(ctx context.Context, view source.View, f source.File, diagnostics []protocol.Diagnostic) []protocol.CodeAction {
+	var codeActions []protocol.CodeAction
+	for _, diag := range diagnostics {
+		if diag.Source != source.ReplaceSource {
+			continue
+		}
+		fixes, err := source.ReplaceFixes(ctx, view, f, diag)
+		if err != nil {
+			continue
+		}
+		for _, fix := range fixes {
+			codeActions = append(codeActions, fixAction(fix, protocol.QuickFix, []protocol.Diagnostic{diag}))
+		}
+	}
+	return codeActions
+}
+
+// goSumQuickFixes returns the code actions that run the commands that fix the
+// go.sum problems of the given diagnostics of the go.mod file f.
+func goSumQuickFixes(ctx context.Context, view source.View, f source.File, diagnostics []protocol.Diagnostic) []protocol.CodeAction {
+	var codeActions []protocol.CodeAction
+	for _, diag := range diagnostics {
+		if diag.Source != source.GoSumSource {
+			continue
+		}
+		commands, err := source.GoSumCommands(ctx, view, f, diag)
+		if err != nil {
+			continue
+		}
+		for i := range commands {
+			codeActions = append(codeActions, protocol.CodeAction{
+				Title:       commands[i].Title,
+				Kind:        protocol.QuickFix,
+				Diagnostics: []protocol.Diagnostic{diag},
+				Command:     &commands[i],
+			})
+		}
+	}
+	return codeActions
+}
+
+// fixAction returns the code action of the given kind that applies fix.
+func fixAction(fix source.SuggestedFix, kind protocol.CodeActionKind, diagnostics []protocol.Diagnostic) protocol.CodeAction {
+	edits := make(map[string][]protocol.TextEdit)
+	for uri, e := range fix.Edits {
+		edits[protocol.NewURI(uri)] = e
+	}
+	return protocol.CodeAction{
+		Title:       fix.Title,
+		Kind:        kind,
+		Diagnostics: diagnostics,
+		Edit: &protocol.WorkspaceEdit{
+			Changes: &edits,
+		},
+	}
+}
+
+// vendorActions returns the quick fixes that run "go mod vendor" for the
+// diagnostics of inconsistent vendoring.
+func vendorActions(f source.File, diagnostics []protocol.Diagnostic) []protocol.CodeAction {
+	var codeActions []protocol.CodeAction
+	for _, diag := range diagnostics {
+		if diag.Source != source.VendorSource {
+			continue
+		}
+		codeActions = append(codeActions, protocol.CodeAction{
+			Title:       source.VendorTitle,
+			Kind:        protocol.QuickFix,
+			Diagnostics: []protocol.Diagnostic{diag},
+			Command: &protocol.Command{
+				Title:     source.VendorTitle,
+				Command:   "vendor",
+				Arguments: []interface{}{f.URI()},
+			},
+		})
+	}
+	return codeActions
+}
+
+// ignoreDiagnosticsActions returns the code actions that suppress the given
+// diagnostics. The diagnostics of the same check on the same line are grouped
+// into a single action, as one comment suppresses all of them.
+func ignoreDiagnosticsActions(ctx context.Context, view source.View, f source.File, diagnostics []protocol.Diagnostic) []protocol.CodeAction {
+	type group struct {
+		line   float64
+		source string
+	}
+	var (
+		order  []group
+		groups = make(map[group][]protocol.Diagnostic)
+	)
+	for _, diag := range diagnostics {
+		if !source.Suppressible(diag.Source) {
+			continue
+		}
+		g := group{line: diag.Range.Start.Line, source: diag.Source}
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+		groups[g] = append(groups[g], diag)
+	}
+	var codeActions []protocol.CodeAction
+	for _, g := range order {
+		fix, err := source.IgnoreDiagnostics(ctx, view, f, int(g.line), g.source)
+		if err != nil {
+			log.Error(ctx, "failed to ignore diagnostics", err, telemetry.File.Of(f.URI()))
+			continue
+		}
+		edits := make(map[string][]protocol.TextEdit)
+		for uri, e := range fix.Edits {
+			edits[protocol.NewURI(uri)] = e
+		}
+		codeActions = append(codeActions, protocol.CodeAction{
+			Title:       fix.Title,
+			Kind:        protocol.QuickFix,
+			Diagnostics: groups[g],
+			Edit: &protocol.WorkspaceEdit{
+				Changes: &edits,
+			},
+		})
+	}
+	return codeActions
+}
+
 func quickFixes(ctx context.Context, s source.Snapshot, f source.File, diagnostics []protocol.Diagnostic) ([]protocol.CodeAction, error) {
 	var codeActions []protocol.CodeAction
 	cphs, err := s.CheckPackageHandles(ctx, f)
@@ -218,11 +466,31 @@ func quickFixes(ctx context.Context, s source.Snapshot, f source.File, diagnosti
 		return nil, err
 	}
 	for _, diag := range diagnostics {
-		srcErr, err := s.FindAnalysisError(ctx, cph.ID(), diag)
-		if err != nil {
-			continue
+		var fixes []source.SuggestedFix
+		switch diag.Source {
+		case source.UnusedExportsSource:
+			fixes, err = source.UnusedExportFixes(ctx, s, f, diag)
+			if err != nil {
+				continue
+			}
+		case source.GoVersionSource:
+			fixes, err = source.GoVersionFixes(ctx, s, f, diag)
+			if err != nil {
+				continue
+			}
+		case source.SpellCheckSource:
+			fixes, err = source.SpellCheckFixes(ctx, s, f, diag)
+			if err != nil {
+				continue
+			}
+		default:
+			srcErr, err := s.FindAnalysisError(ctx, cph.ID(), diag)
+			if err != nil {
+				continue
+			}
+			fixes = srcErr.SuggestedFixes
 		}
-		for _, fix := range srcErr.SuggestedFixes {
+		for _, fix := range fixes {
 			edits := make(map[string][]protocol.TextEdit)
 			for uri, e := range fix.Edits {
 				edits[protocol.NewURI(uri)] = e
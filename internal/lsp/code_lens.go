@@ -0,0 +1,32 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"context"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+func (s *Server) codeLens(ctx context.Context, params *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
+	uri := span.NewURI(params.TextDocument.URI)
+	view := s.session.ViewOf(uri)
+	if s.isLargeFile(ctx, view, uri) {
+		return nil, nil
+	}
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	switch view.Snapshot().Handle(ctx, f).Identity().Kind {
+	case source.Go:
+		return source.CodeLens(ctx, view, f)
+	case source.Mod:
+		return source.ModCodeLens(ctx, view, f)
+	}
+	return nil, nil
+}
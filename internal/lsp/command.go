@@ -1,11 +1,18 @@
 package lsp
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/telemetry"
 	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/log"
+	"golang.org/x/tools/internal/xcontext"
 	errors "golang.org/x/xerrors"
 )
 
@@ -30,6 +37,299 @@ func (s *Server) executeCommand(ctx context.Context, params *protocol.ExecuteCom
 		if err := source.ModTidy(ctx, view); err != nil {
 			return nil, err
 		}
+		go s.diagnoseGoSum(view, uri)
+	case "applytidy":
+		if len(params.Arguments) != 1 {
+			return nil, errors.Errorf("expected one go.mod URI for call to apply `go mod tidy`, got %v", params.Arguments)
+		}
+		uri := span.NewURI(params.Arguments[0].(string))
+		view := s.session.ViewOf(uri)
+		diff, err := source.ModTidyDiff(ctx, view, uri)
+		if err != nil {
+			return nil, err
+		}
+		if len(diff.Edits) == 0 {
+			return nil, nil
+		}
+		// The client applies the edits, so that they can be undone.
+		if err := s.applyFix(ctx, &source.SuggestedFix{Title: source.ApplyTidyTitle, Edits: diff.Edits}); err != nil {
+			return nil, err
+		}
+	case "download":
+		var args source.DownloadArgs
+		if err := decodeArgs(params.Arguments, &args); err != nil {
+			return nil, err
+		}
+		uri := span.NewURI(args.URI)
+		view := s.session.ViewOf(uri)
+		if err := source.ModDownload(ctx, view, args); err != nil {
+			return nil, err
+		}
+		go s.diagnoseGoSum(view, uri)
+	case "vendor":
+		if len(params.Arguments) != 1 {
+			return nil, errors.Errorf("expected one file URI for call to `go mod vendor`, got %v", params.Arguments)
+		}
+		uri := span.NewURI(params.Arguments[0].(string))
+		view := s.session.ViewOf(uri)
+		if err := source.ModVendor(ctx, view, uri); err != nil {
+			return nil, err
+		}
+		// The package of the file could not be loaded before, so it is
+		// loaded again along with its diagnostics.
+		go s.diagnostics(view, uri)
+	case "gopls.SetBuildConfiguration":
+		var args source.BuildConfigurationArgs
+		if err := decodeArgs(params.Arguments, &args); err != nil {
+			return nil, err
+		}
+		view := s.session.ViewOf(span.NewURI(args.URI))
+		for _, uri := range view.SetBuildConfiguration(ctx, args.BuildConfiguration) {
+			go s.diagnostics(view, uri)
+		}
+	case "creatework":
+		if len(params.Arguments) != 1 {
+			return nil, errors.Errorf("expected one folder URI for call to create go.work, got %v", params.Arguments)
+		}
+		if err := s.createWorkFile(ctx, span.NewURI(params.Arguments[0].(string))); err != nil {
+			return nil, err
+		}
+	case "test":
+		var args source.TestArgs
+		if err := decodeArgs(params.Arguments, &args); err != nil {
+			return nil, err
+		}
+		s.runTests(ctx, args)
+	case "coverage":
+		var args source.TestArgs
+		if err := decodeArgs(params.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.runCoverage(ctx, args)
+	case "apidiff":
+		var args source.APIDiffArgs
+		if err := decodeArgs(params.Arguments, &args); err != nil {
+			return nil, err
+		}
+		s.runAPIDiff(ctx, args)
+	case "generate":
+		var args source.GenerateArgs
+		if err := decodeArgs(params.Arguments, &args); err != nil {
+			return nil, err
+		}
+		s.runGenerate(ctx, args)
+	case source.GenerateTypeCommand:
+		var args source.GenerateTypeArgs
+		if err := decodeArgs(params.Arguments, &args); err != nil {
+			return nil, err
+		}
+		uri := span.NewURI(args.URI)
+		view := s.session.ViewOf(uri)
+		f, err := view.GetFile(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		fix, err := source.GenerateType(ctx, view, f, args)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.applyFix(ctx, fix); err != nil {
+			return nil, err
+		}
 	}
 	return nil, nil
 }
+
+// applyFix asks the client to apply the edits of fix.
+func (s *Server) applyFix(ctx context.Context, fix *source.SuggestedFix) error {
+	changes := make(map[string][]protocol.TextEdit)
+	for uri, e := range fix.Edits {
+		changes[protocol.NewURI(uri)] = e
+	}
+	resp, err := s.client.ApplyEdit(ctx, &protocol.ApplyWorkspaceEditParams{
+		Label: fix.Title,
+		Edit:  protocol.WorkspaceEdit{Changes: &changes},
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Applied {
+		return errors.Errorf("%s: edit not applied: %s", fix.Title, resp.FailureReason)
+	}
+	return nil
+}
+
+// diagnoseGoSum computes again the diagnostics of the open files whose
+// packages could not be loaded because of the go.sum file of the module of
+// the go.mod file gomod, which loads them again, and then those of gomod.
+func (s *Server) diagnoseGoSum(view source.View, gomod span.URI) {
+	_, uris := view.Snapshot().GoSumProblems(gomod)
+	for _, uri := range uris {
+		if s.session.IsOpen(uri) {
+			s.diagnostics(view, uri)
+		}
+	}
+	s.diagnostics(view, gomod)
+}
+
+// decodeArgs decodes the single JSON object argument of a command into v.
+func decodeArgs(args []interface{}, v interface{}) error {
+	if len(args) != 1 {
+		return errors.Errorf("expected one argument, got %v", args)
+	}
+	return decodeParams(args[0], v)
+}
+
+// decodeParams decodes params, which was decoded from JSON into an untyped
+// value, into v.
+func decodeParams(params interface{}, v interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// runTests runs the tests described by args in the background, reporting
+// progress to the client. The tests may run for a long time, as is the case
+// for fuzz targets, so they can be cancelled by the client. The output of the
+// tests is streamed to the client as progress reports if it supports them,
+// and is otherwise included in the final message.
+func (s *Server) runTests(ctx context.Context, args source.TestArgs) {
+	uri := span.NewURI(args.URI)
+	view := s.session.ViewOf(uri)
+
+	// Progress is reported using a context that outlives both the request
+	// and the tests, so that the end of the work is always reported.
+	ctx = xcontext.Detach(ctx)
+	runCtx, cancel := context.WithCancel(ctx)
+	title := "running tests"
+	if args.Fuzz != "" {
+		title = fmt.Sprintf("fuzzing %s", args.Fuzz)
+	}
+	wd := s.startWork(ctx, title, uri.Filename(), cancel)
+	go func() {
+		defer cancel()
+		out := &bytes.Buffer{}
+		pw := &progressWriter{ctx: ctx, wd: wd}
+		err := source.RunTests(runCtx, view, args, io.MultiWriter(out, pw))
+		pw.flush()
+		switch {
+		case err == context.Canceled:
+			wd.end(ctx, fmt.Sprintf("%s: cancelled", title))
+		case err != nil:
+			log.Error(ctx, title, err, telemetry.URI.Of(uri))
+			if wd.token != "" {
+				// The output has already been reported as progress.
+				wd.end(ctx, fmt.Sprintf("%s: failed", title))
+			} else {
+				wd.end(ctx, fmt.Sprintf("%s: failed\n%s", title, out))
+			}
+		default:
+			wd.end(ctx, fmt.Sprintf("%s: passed", title))
+		}
+	}()
+}
+
+// runGenerate installs the tool of args, if any, and runs the //go:generate
+// directives of args in the background, reporting progress to the client as
+// runTests does.
+func (s *Server) runGenerate(ctx context.Context, args source.GenerateArgs) {
+	uri := span.NewURI(args.URI)
+	view := s.session.ViewOf(uri)
+
+	ctx = xcontext.Detach(ctx)
+	runCtx, cancel := context.WithCancel(ctx)
+	title := "running go generate"
+	if args.Install != "" {
+		title = fmt.Sprintf("installing %s and running go generate", args.Install)
+	}
+	wd := s.startWork(ctx, title, uri.Filename(), cancel)
+	go func() {
+		defer cancel()
+		out := &bytes.Buffer{}
+		pw := &progressWriter{ctx: ctx, wd: wd}
+		err := source.RunGenerate(runCtx, view, args, io.MultiWriter(out, pw))
+		pw.flush()
+		switch {
+		case err == context.Canceled:
+			wd.end(ctx, fmt.Sprintf("%s: cancelled", title))
+		case err != nil:
+			log.Error(ctx, title, err, telemetry.URI.Of(uri))
+			if wd.token != "" {
+				wd.end(ctx, fmt.Sprintf("%s: failed", title))
+			} else {
+				wd.end(ctx, fmt.Sprintf("%s: failed\n%s", title, out))
+			}
+		default:
+			wd.end(ctx, fmt.Sprintf("%s: done", title))
+		}
+	}()
+}
+
+// runCoverage runs the tests described by args with coverage enabled, and
+// returns the coverage ranges of each file in the package.
+func (s *Server) runCoverage(ctx context.Context, args source.TestArgs) ([]*source.CoverageRanges, error) {
+	uri := span.NewURI(args.URI)
+	view := s.session.ViewOf(uri)
+	coverage, err := source.RunCoverage(ctx, view, args)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*source.CoverageRanges, 0, len(coverage))
+	for _, fc := range coverage {
+		f, err := view.GetFile(ctx, fc.URI)
+		if err != nil {
+			return nil, err
+		}
+		ranges, err := source.Coverage(ctx, view, f)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ranges)
+	}
+	return result, nil
+}
+
+// runAPIDiff compares the API of the module of args with a released version in
+// the background, reporting progress to the client as runTests does, and then
+// updates the diagnostics of the open files of the module.
+func (s *Server) runAPIDiff(ctx context.Context, args source.APIDiffArgs) {
+	uri := span.NewURI(args.URI)
+	view := s.session.ViewOf(uri)
+
+	ctx = xcontext.Detach(ctx)
+	runCtx, cancel := context.WithCancel(ctx)
+	title := "checking API"
+	if args.Version != "" {
+		title = fmt.Sprintf("checking API against %s", args.Version)
+	}
+	wd := s.startWork(ctx, title, uri.Filename(), cancel)
+	go func() {
+		defer cancel()
+		result, err := source.RunAPIDiff(runCtx, view, args)
+		switch {
+		case err == context.Canceled:
+			wd.end(ctx, fmt.Sprintf("%s: cancelled", title))
+			return
+		case err != nil:
+			log.Error(ctx, title, err, telemetry.URI.Of(uri))
+			wd.end(ctx, fmt.Sprintf("%s: failed\n%v", title, err))
+			return
+		}
+		n := 0
+		for _, changes := range result.Changes {
+			n += len(changes)
+		}
+		msg := fmt.Sprintf("%d incompatible changes since %s", n, result.Version)
+		for _, path := range result.Removed {
+			msg += fmt.Sprintf("\n%s: removed", path)
+		}
+		wd.end(ctx, msg)
+		for _, uri := range result.Files {
+			if s.session.IsOpen(uri) {
+				go s.diagnostics(view, uri)
+			}
+		}
+	}()
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/source"
@@ -25,7 +26,27 @@ func (s *Server) completion(ctx context.Context, params *protocol.CompletionPara
 		return nil, err
 	}
 	options.Completion.FullDocumentation = options.HoverKind == source.FullDocumentation
-	candidates, surrounding, err := source.Completion(ctx, view, f, params.Position, options.Completion)
+	// Deep completion stops searching once the client no longer needs the
+	// result, which is then incomplete.
+	deadline, hasDeadline := protocol.SoftDeadline(ctx)
+	if hasDeadline {
+		budget := time.Until(deadline)
+		if budget < time.Millisecond {
+			budget = time.Millisecond
+		}
+		if budget < options.Completion.Budget {
+			options.Completion.Budget = budget
+		}
+	}
+	var (
+		candidates  []source.CompletionItem
+		surrounding *source.Selection
+	)
+	if options.DetectLanguage("", uri.Filename()) == source.Mod {
+		candidates, surrounding, err = source.ModCompletion(ctx, view, f, params.Position, options.Completion)
+	} else {
+		candidates, surrounding, err = source.Completion(ctx, view, f, params.Position, options.Completion)
+	}
 	if err != nil {
 		log.Print(ctx, "no completions found", tag.Of("At", params.Position), tag.Of("Failure", err))
 	}
@@ -46,7 +67,7 @@ func (s *Server) completion(ctx context.Context, params *protocol.CompletionPara
 
 	// When using deep completions/fuzzy matching, report results as incomplete so
 	// client fetches updated completions after every key stroke.
-	incompleteResults := options.Completion.Deep || options.Completion.FuzzyMatching
+	incompleteResults := options.Completion.Deep || options.Completion.FuzzyMatching || hasDeadline
 
 	items := toProtocolCompletionItems(candidates, rng, options)
 
@@ -46,4 +46,19 @@ var (
 		Description: "Count of RPCs completed by method and status.",
 		Keys:        []interface{}{telemetry.RPCDirection, telemetry.Method, telemetry.StatusCode},
 	}.CountFloat64(telemetry.Latency)
+
+	parseCacheHits = metric.Scalar{
+		Name:        "parse_cache_hits",
+		Description: "Count of parsed files found in the parse cache.",
+	}.CountInt64(telemetry.ParseCacheHits)
+
+	parseCacheMisses = metric.Scalar{
+		Name:        "parse_cache_misses",
+		Description: "Count of files parsed because they were not in the parse cache.",
+	}.CountInt64(telemetry.ParseCacheMisses)
+
+	parseCacheEvictions = metric.Scalar{
+		Name:        "parse_cache_evictions",
+		Description: "Count of parsed files evicted from the parse cache.",
+	}.CountInt64(telemetry.ParseCacheEvictions)
 )
@@ -7,6 +7,7 @@ package debug
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"go/token"
 	"html/template"
 	"net"
@@ -28,6 +29,16 @@ import (
 type Cache interface {
 	ID() string
 	FileSet() *token.FileSet
+	ParseCache() ParseCache
+}
+
+// ParseCache describes the use of the parse cache of a Cache.
+type ParseCache struct {
+	Capacity  int
+	Size      int
+	Hits      int64
+	Misses    int64
+	Evictions int64
 }
 
 type Session interface {
@@ -42,6 +53,57 @@ type View interface {
 	Name() string
 	Folder() span.URI
 	Session() Session
+	SnapshotDiffs() []*SnapshotDiff
+	SnapshotDiff(from, to uint64) (*SnapshotDiff, error)
+	ModGraph(ctx context.Context, module string) (*ModGraph, error)
+}
+
+// ModGraph is the module requirement graph of the main module of a view, as
+// printed by "go mod graph".
+type ModGraph struct {
+	GoMod span.URI
+	Main  string
+	// Modules are the modules of the graph and their requirements, sorted.
+	Modules []ModRequirements
+	// Module is the module selected on the page, and Why the shortest
+	// requirement paths from the main module to its versions.
+	Module string
+	Why    [][]string
+}
+
+// ModRequirements are the modules that a module requires.
+type ModRequirements struct {
+	Module   string
+	Requires []string
+}
+
+// SnapshotDiff explains what was invalidated, and why, when a snapshot of a
+// view was created from the snapshot before it.
+type SnapshotDiff struct {
+	From, To uint64
+	// Cause is the kind of event that created the new snapshot, and URI and
+	// Change the file that changed and how.
+	Cause  string
+	URI    span.URI
+	Change string
+	// MetadataInvalidated is set if the package name or imports of the file
+	// changed, so that the metadata of its packages must be reloaded.
+	MetadataInvalidated bool
+	// Types and Metadata are the packages whose type information and
+	// metadata were discarded. They are recomputed when next needed.
+	Types    []string
+	Metadata []string
+	// Reloads are the go/packages loads done in the new snapshot.
+	Reloads []SnapshotReload
+	// Evicted are the type-checked packages later discarded from the new
+	// snapshot to reduce memory use.
+	Evicted []string
+}
+
+// SnapshotReload is a go/packages load done for a file in a snapshot.
+type SnapshotReload struct {
+	URI      span.URI
+	Packages []string
 }
 
 type File struct {
@@ -152,6 +214,50 @@ func getView(r *http.Request) interface{} {
 	return findView(id)
 }
 
+// getSnapshotDiff serves /snapshot/<view>/<from>/<to>.
+func getSnapshotDiff(r *http.Request) interface{} {
+	result := struct {
+		View View
+		Diff *SnapshotDiff
+		Err  error
+	}{}
+	to, err := strconv.ParseUint(path.Base(r.URL.Path), 10, 64)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	rest := path.Dir(r.URL.Path)
+	from, err := strconv.ParseUint(path.Base(rest), 10, 64)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.View = findView(path.Base(path.Dir(rest)))
+	if result.View == nil {
+		result.Err = fmt.Errorf("no view %s", path.Base(path.Dir(rest)))
+		return result
+	}
+	result.Diff, result.Err = result.View.SnapshotDiff(from, to)
+	return result
+}
+
+// getModGraph serves /modgraph/<view>?module=<module>.
+func getModGraph(r *http.Request) interface{} {
+	result := struct {
+		View  View
+		Graph *ModGraph
+		Err   error
+	}{}
+	id := path.Base(r.URL.Path)
+	result.View = findView(id)
+	if result.View == nil {
+		result.Err = fmt.Errorf("no view %s", id)
+		return result
+	}
+	result.Graph, result.Err = result.View.ModGraph(r.Context(), r.URL.Query().Get("module"))
+	return result
+}
+
 func getFile(r *http.Request) interface{} {
 	mu.Lock()
 	defer mu.Unlock()
@@ -218,7 +324,8 @@ func Serve(ctx context.Context, addr string) error {
 	prometheus := prometheus.New()
 	rpcs := &rpcs{}
 	traces := &traces{}
-	export.AddExporters(prometheus, rpcs, traces)
+	typeChecks := &typeChecks{}
+	export.AddExporters(prometheus, rpcs, traces, typeChecks)
 	go func() {
 		mux := http.NewServeMux()
 		mux.HandleFunc("/", Render(mainTmpl, func(*http.Request) interface{} { return data }))
@@ -231,10 +338,14 @@ func Serve(ctx context.Context, addr string) error {
 		mux.HandleFunc("/metrics/", prometheus.Serve)
 		mux.HandleFunc("/rpc/", Render(rpcTmpl, rpcs.getData))
 		mux.HandleFunc("/trace/", Render(traceTmpl, traces.getData))
+		mux.HandleFunc("/typecheck", Render(typeCheckTmpl, typeChecks.getData))
+		mux.HandleFunc("/slow", Render(slowTmpl, slow.getData))
 		mux.HandleFunc("/cache/", Render(cacheTmpl, getCache))
 		mux.HandleFunc("/session/", Render(sessionTmpl, getSession))
 		mux.HandleFunc("/view/", Render(viewTmpl, getView))
 		mux.HandleFunc("/file/", Render(fileTmpl, getFile))
+		mux.HandleFunc("/modgraph/", Render(modGraphTmpl, getModGraph))
+		mux.HandleFunc("/snapshot/", Render(snapshotTmpl, getSnapshotDiff))
 		mux.HandleFunc("/info", Render(infoTmpl, getInfo))
 		mux.HandleFunc("/memory", Render(memoryTmpl, getMemory))
 		if err := http.Serve(listener, mux); err != nil {
@@ -291,6 +402,13 @@ td.value {
 ul.events {
 	list-style-type: none;
 }
+td.bar {
+	width: 30rem;
+}
+td.bar div {
+	height: 1em;
+	background: #6a9;
+}
 
 </style>
 {{block "head" .}}{{end}}
@@ -302,6 +420,8 @@ ul.events {
 <a href="/metrics">Metrics</a>
 <a href="/rpc">RPC</a>
 <a href="/trace">Trace</a>
+<a href="/typecheck">Type checking</a>
+<a href="/slow">Slow requests</a>
 <hr>
 <h1>{{template "title" .}}</h1>
 {{block "body" .}}
@@ -378,6 +498,16 @@ var debugTmpl = template.Must(template.Must(BaseTemplate.Clone()).Parse(`
 var cacheTmpl = template.Must(template.Must(BaseTemplate.Clone()).Parse(`
 {{define "title"}}Cache {{.ID}}{{end}}
 {{define "body"}}
+<h2>Parse cache</h2>
+{{with .ParseCache}}
+<table>
+<tr><td class="label">Capacity</td><td class="value">{{.Capacity}}</td></tr>
+<tr><td class="label">Size</td><td class="value">{{.Size}}</td></tr>
+<tr><td class="label">Hits</td><td class="value">{{.Hits}}</td></tr>
+<tr><td class="label">Misses</td><td class="value">{{.Misses}}</td></tr>
+<tr><td class="label">Evictions</td><td class="value">{{.Evictions}}</td></tr>
+</table>
+{{end}}
 <h2>Sessions</h2>
 <ul>{{range .Sessions}}<li>{{template "sessionlink" .ID}}</li>{{end}}</ul>
 {{end}}
@@ -402,6 +532,52 @@ Folder: <b>{{.Folder}}</b><br>
 From: <b>{{template "sessionlink" .Session.ID}}</b><br>
 <h2>Environment</h2>
 <ul>{{range .Env}}<li>{{.}}</li>{{end}}</ul>
+<a href="/modgraph/{{.ID}}">Module graph</a><br>
+<h2>Recent snapshots</h2>
+<ul>{{$view := .ID}}{{range .SnapshotDiffs}}<li><a href="/snapshot/{{$view}}/{{.From}}/{{.To}}">Snapshot {{.To}}</a>: {{.Cause}}, {{.URI}} {{.Change}}, {{len .Types}} packages to type-check again</li>{{end}}</ul>
+{{end}}
+`))
+
+var snapshotTmpl = template.Must(template.Must(BaseTemplate.Clone()).Parse(`
+{{define "title"}}Snapshot diff{{end}}
+{{define "body"}}
+{{with .Err}}Error: <b>{{.}}</b>{{end}}
+{{with .Diff}}
+From: <b>{{template "viewlink" $.View.ID}}</b>, snapshot {{.From}} to {{.To}}<br>
+Cause: <b>{{.Cause}}</b>, {{.URI}} {{.Change}}<br>
+{{if .MetadataInvalidated}}The package name or imports of the file changed, so the metadata of its packages must be reloaded.<br>{{end}}
+<h2>Type information discarded</h2>
+<p>These packages, the packages of the file and those that depend on them, are type-checked again when next needed.</p>
+<ul>{{range .Types}}<li>{{.}}</li>{{end}}</ul>
+<h2>Metadata discarded</h2>
+<ul>{{range .Metadata}}<li>{{.}}</li>{{end}}</ul>
+<h2>Metadata reloads</h2>
+<ul>{{range .Reloads}}<li>{{.URI}}: {{range .Packages}}{{.}} {{end}}</li>{{end}}</ul>
+<h2>Evicted under memory pressure</h2>
+<ul>{{range .Evicted}}<li>{{.}}</li>{{end}}</ul>
+{{end}}
+{{end}}
+`))
+
+var modGraphTmpl = template.Must(template.Must(BaseTemplate.Clone()).Parse(`
+{{define "title"}}Module graph{{end}}
+{{define "body"}}
+{{with .View}}From: <b>{{template "viewlink" .ID}}</b><br>{{end}}
+{{with .Err}}Error: <b>{{.}}</b><br>{{end}}
+{{with .Graph}}
+go.mod: <b>{{.GoMod}}</b><br>
+Main module: <b>{{.Main}}</b><br>
+{{if .Module}}
+<h2>Why {{.Module}}</h2>
+<p>The shortest requirement paths from the main module to the versions of the module.</p>
+<ul>{{range .Why}}<li>{{range $i, $m := .}}{{if $i}} &rarr; {{end}}{{$m}}{{end}}</li>{{end}}</ul>
+{{end}}
+<h2>Requirements</h2>
+<p>Select a module to show why the main module requires it.</p>
+{{$view := $.View.ID}}
+<ul>{{range .Modules}}<li><a href="/modgraph/{{$view}}?module={{.Module}}">{{.Module}}</a> requires
+<ul>{{range .Requires}}<li><a href="/modgraph/{{$view}}?module={{.}}">{{.}}</a></li>{{end}}</ul></li>{{end}}</ul>
+{{end}}
 {{end}}
 `))
 
@@ -0,0 +1,381 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/telemetry"
+	tele "golang.org/x/tools/internal/telemetry"
+	"golang.org/x/tools/internal/telemetry/export"
+	"golang.org/x/tools/internal/telemetry/log"
+	errors "golang.org/x/xerrors"
+)
+
+// The names of the spans of the work that requests wait for, besides those
+// of type checking.
+const (
+	queuedSpan  = "queued"
+	loadSpan    = "cache.view.load"
+	analyzeSpan = "cache.snapshot.Analyze"
+)
+
+// The kinds of work a request may be blocked on, in the order in which they
+// are reported.
+var blockedOn = []string{"queued", "load", "type-check", "analysis"}
+
+// maxSlowRequests is the number of slow requests kept for the debug page.
+const maxSlowRequests = 50
+
+var slowTmpl = template.Must(template.Must(BaseTemplate.Clone()).Parse(`
+{{define "title"}}Slow requests{{end}}
+{{define "body"}}
+{{if .Tracking}}
+<p>Requests are slow when they take longer than {{.Thresholds}}. The time they spent blocked on each kind of work is that of the outermost spans of that kind, so concurrent work may add up to more than the latency of the request.</p>
+<h2>In progress</h2>
+{{template "slowRequests" .InProgress}}
+<p>The kinds of work marked with * are those the requests are blocked on now, whose time is counted once done.</p>
+<h2>Most recent</h2>
+{{template "slowRequests" .Recent}}
+{{else}}
+Slow requests are not tracked.
+{{end}}
+{{end}}
+{{define "slowRequests"}}
+{{if .}}
+<table>
+<tr><th>Method</th><th>ID</th><th>Started</th><th>Latency</th><th>Queued</th><th>Load</th><th>Type check</th><th>Analysis</th><th>Other</th></tr>
+{{range .}}
+<tr>
+<td>{{.Method}}</td>
+<td>{{.ID}}</td>
+<td>{{.Start.Format "15:04:05.000"}}</td>
+<td class="value">{{.Duration}}</td>
+{{range .Blocked}}<td class="value">{{.Duration}}{{if .Active}} *{{end}}</td>{{end}}
+<td class="value">{{.Other}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+None.
+{{end}}
+{{end}}
+`))
+
+// SlowThresholds are the latencies above which requests from the client are
+// slow.
+type SlowThresholds struct {
+	// Default is the threshold of the methods not in Methods. Zero means
+	// that they are never slow.
+	Default time.Duration
+	Methods map[string]time.Duration
+}
+
+// ParseSlowThresholds parses thresholds such as
+// "1s,textDocument/completion=200ms", where the durations without a method
+// are the default threshold. "off" disables the tracking of slow requests.
+func ParseSlowThresholds(s string) (SlowThresholds, error) {
+	var t SlowThresholds
+	if s == "off" {
+		return t, nil
+	}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		method, value := "", field
+		if i := strings.LastIndex(field, "="); i >= 0 {
+			method, value = field[:i], field[i+1:]
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return SlowThresholds{}, errors.Errorf("invalid slow request threshold %q: %v", field, err)
+		}
+		if method == "" {
+			t.Default = d
+			continue
+		}
+		if t.Methods == nil {
+			t.Methods = make(map[string]time.Duration)
+		}
+		t.Methods[method] = d
+	}
+	return t, nil
+}
+
+func (t SlowThresholds) threshold(method string) time.Duration {
+	if d, ok := t.Methods[method]; ok {
+		return d
+	}
+	return t.Default
+}
+
+func (t SlowThresholds) String() string {
+	var methods []string
+	for method, d := range t.Methods {
+		methods = append(methods, fmt.Sprintf("%v for %s", d, method))
+	}
+	sort.Strings(methods)
+	if t.Default > 0 {
+		methods = append([]string{t.Default.String()}, methods...)
+	}
+	return strings.Join(methods, ", ")
+}
+
+// slowRequest is the breakdown of the time a request spent blocked on the
+// work it waited for.
+type slowRequest struct {
+	Method    string
+	ID        string
+	Start     time.Time
+	Duration  time.Duration
+	Threshold time.Duration
+	Blocked   []blockedTime
+	Other     time.Duration
+
+	span   tele.SpanContext
+	logged bool
+}
+
+type blockedTime struct {
+	On       string
+	Duration time.Duration
+	Active   bool
+}
+
+func (r *slowRequest) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "slow request %s", r.Method)
+	if r.ID != "" {
+		fmt.Fprintf(&b, " %s", r.ID)
+	}
+	fmt.Fprintf(&b, " took %v, over %v:", r.Duration, r.Threshold)
+	for _, blocked := range r.Blocked {
+		fmt.Fprintf(&b, " %s %v,", blocked.On, blocked.Duration)
+	}
+	fmt.Fprintf(&b, " other %v", r.Other)
+	return b.String()
+}
+
+// requestNode is an inbound request that has not finished yet.
+type requestNode struct {
+	method string
+	id     string
+	start  time.Time
+
+	blocked map[string]time.Duration
+	active  map[string]int
+}
+
+// workNode is a span started on behalf of a request.
+type workNode struct {
+	request *requestNode
+	start   time.Time
+
+	// blockedOn is the kind of work of the outermost span of a kind among
+	// the span and its ancestors, if any, and outermost reports whether it
+	// is the span itself.
+	blockedOn string
+	outermost bool
+}
+
+// slowRequests is an exporter that attributes the time spent by the
+// requests from the client to the work they wait for, and keeps those that
+// are slow.
+type slowRequests struct {
+	register sync.Once
+
+	mu         sync.Mutex
+	thresholds SlowThresholds
+	requests   map[tele.SpanContext]*requestNode
+	work       map[tele.SpanContext]*workNode
+	recent     []*slowRequest
+}
+
+var slow = &slowRequests{}
+
+// TrackSlowRequests starts the tracking of the requests from the client
+// that take longer than thresholds, which LogSlowRequest logs and the debug
+// server shows.
+func TrackSlowRequests(thresholds SlowThresholds) {
+	slow.mu.Lock()
+	slow.thresholds = thresholds
+	slow.mu.Unlock()
+	slow.register.Do(func() {
+		export.AddExporters(slow)
+	})
+}
+
+// LogSlowRequest logs the request of ctx, once finished, if it was slow,
+// with the time it spent blocked on each kind of work.
+func LogSlowRequest(ctx context.Context) {
+	span := tele.GetSpan(ctx)
+	if span == nil {
+		return
+	}
+	slow.mu.Lock()
+	var found *slowRequest
+	for _, r := range slow.recent {
+		if r.span == span.ID && !r.logged {
+			r.logged = true
+			found = r
+		}
+	}
+	slow.mu.Unlock()
+	if found != nil {
+		log.Print(ctx, found.String())
+	}
+}
+
+func (s *slowRequests) StartSpan(ctx context.Context, span *tele.Span) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.requests == nil {
+		s.requests = make(map[tele.SpanContext]*requestNode)
+		s.work = make(map[tele.SpanContext]*workNode)
+	}
+	parentID := tele.SpanContext{TraceID: span.ID.TraceID, SpanID: span.ParentID}
+	var node *workNode
+	if request, ok := s.requests[parentID]; ok {
+		node = &workNode{request: request}
+	} else if parent, ok := s.work[parentID]; ok {
+		node = &workNode{request: parent.request, blockedOn: parent.blockedOn}
+	} else {
+		if method, ok := tagValue(span.Tags, telemetry.Method).(string); ok && tagValue(span.Tags, telemetry.RPCDirection) == telemetry.Inbound {
+			s.requests[span.ID] = &requestNode{
+				method:  method,
+				id:      fmt.Sprint(tagValue(span.Tags, telemetry.RPCID)),
+				start:   span.Start,
+				blocked: make(map[string]time.Duration),
+				active:  make(map[string]int),
+			}
+		}
+		return
+	}
+	if kind := workKind(span.Name); kind != "" && node.blockedOn == "" {
+		node.blockedOn = kind
+		node.outermost = true
+		node.start = span.Start
+		node.request.active[kind]++
+	}
+	s.work[span.ID] = node
+}
+
+// workKind returns the kind of work of the span named name, if it is one
+// requests may be blocked on.
+func workKind(name string) string {
+	switch name {
+	case queuedSpan:
+		return "queued"
+	case loadSpan:
+		return "load"
+	case typeCheckSpan, importSpan, parseSpan:
+		return "type-check"
+	case analyzeSpan:
+		return "analysis"
+	}
+	return ""
+}
+
+func (s *slowRequests) FinishSpan(ctx context.Context, span *tele.Span) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if node, ok := s.work[span.ID]; ok {
+		delete(s.work, span.ID)
+		if node.outermost {
+			node.request.blocked[node.blockedOn] += span.Finish.Sub(node.start)
+			node.request.active[node.blockedOn]--
+		}
+		return
+	}
+	request, ok := s.requests[span.ID]
+	if !ok {
+		return
+	}
+	delete(s.requests, span.ID)
+	threshold := s.thresholds.threshold(request.method)
+	if threshold <= 0 || span.Finish.Sub(span.Start) < threshold {
+		return
+	}
+	r := request.breakdown(span.Finish, threshold)
+	r.span = span.ID
+	s.recent = append(s.recent, r)
+	if len(s.recent) > maxSlowRequests {
+		s.recent = s.recent[len(s.recent)-maxSlowRequests:]
+	}
+}
+
+// breakdown returns the time spent by the request until now, and blocked on
+// each kind of work.
+func (n *requestNode) breakdown(now time.Time, threshold time.Duration) *slowRequest {
+	r := &slowRequest{
+		Method:    n.method,
+		ID:        n.id,
+		Start:     n.start,
+		Duration:  now.Sub(n.start),
+		Threshold: threshold,
+	}
+	r.Other = r.Duration
+	for _, kind := range blockedOn {
+		b := blockedTime{
+			On:       kind,
+			Duration: n.blocked[kind],
+			Active:   n.active[kind] > 0,
+		}
+		r.Other -= b.Duration
+		r.Blocked = append(r.Blocked, b)
+	}
+	if r.Other < 0 {
+		r.Other = 0
+	}
+	return r
+}
+
+func (s *slowRequests) Log(ctx context.Context, event tele.Event) {}
+
+func (s *slowRequests) Metric(ctx context.Context, data tele.MetricData) {}
+
+func (s *slowRequests) Flush() {}
+
+type slowRequestsData struct {
+	Tracking   bool
+	Thresholds SlowThresholds
+	InProgress []*slowRequest
+	Recent     []*slowRequest
+}
+
+func (s *slowRequests) getData(req *http.Request) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := slowRequestsData{
+		Tracking:   s.thresholds.Default > 0 || len(s.thresholds.Methods) > 0,
+		Thresholds: s.thresholds,
+	}
+	// The requests in progress are shown once they have run for longer
+	// than their threshold, with the work they are blocked on now marked.
+	now := time.Now()
+	for _, request := range s.requests {
+		threshold := s.thresholds.threshold(request.method)
+		if threshold <= 0 || now.Sub(request.start) < threshold {
+			continue
+		}
+		data.InProgress = append(data.InProgress, request.breakdown(now, threshold))
+	}
+	sort.Slice(data.InProgress, func(i, j int) bool {
+		return data.InProgress[i].Start.Before(data.InProgress[j].Start)
+	})
+	for i := len(s.recent) - 1; i >= 0; i-- {
+		data.Recent = append(data.Recent, s.recent[i])
+	}
+	return data
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/telemetry"
+	tele "golang.org/x/tools/internal/telemetry"
+)
+
+func TestParseSlowThresholds(t *testing.T) {
+	got, err := ParseSlowThresholds("1s, textDocument/completion=200ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.threshold("textDocument/hover") != time.Second {
+		t.Errorf("got default threshold %v, want 1s", got.threshold("textDocument/hover"))
+	}
+	if got.threshold("textDocument/completion") != 200*time.Millisecond {
+		t.Errorf("got completion threshold %v, want 200ms", got.threshold("textDocument/completion"))
+	}
+	if off, err := ParseSlowThresholds("off"); err != nil || off.threshold("textDocument/hover") != 0 {
+		t.Errorf("got %v, %v for off, want no threshold", off, err)
+	}
+	if _, err := ParseSlowThresholds("textDocument/completion=soon"); err == nil {
+		t.Errorf("no error for an invalid duration")
+	}
+}
+
+func TestSlowRequests(t *testing.T) {
+	ctx := context.Background()
+	s := &slowRequests{
+		thresholds: SlowThresholds{Default: 5 * time.Millisecond},
+	}
+	trace := tele.NewTraceID()
+	base := time.Now()
+	ms := func(n int) time.Time { return base.Add(time.Duration(n) * time.Millisecond) }
+
+	start := func(name string, parent *tele.Span, at time.Time, tags ...tele.Tag) *tele.Span {
+		span := &tele.Span{
+			Name:  name,
+			ID:    tele.SpanContext{TraceID: trace, SpanID: tele.NewSpanID()},
+			Start: at,
+			Tags:  tags,
+		}
+		if parent != nil {
+			span.ParentID = parent.ID.SpanID
+		}
+		s.StartSpan(ctx, span)
+		return span
+	}
+	finish := func(span *tele.Span, at time.Time) {
+		span.Finish = at
+		s.FinishSpan(ctx, span)
+	}
+	request := func(method string, at time.Time) *tele.Span {
+		return start(method, nil, at, telemetry.Method.Of(method), telemetry.RPCDirection.Of(telemetry.Inbound))
+	}
+
+	// The hover waits 1ms in the queue, 3ms for the load, and 4ms for the
+	// type check of its package, which imports another one.
+	hover := request("textDocument/hover", ms(0))
+	finish(start(queuedSpan, hover, ms(0)), ms(1))
+	finish(start(loadSpan, hover, ms(1)), ms(4))
+	check := start(typeCheckSpan, hover, ms(4))
+	imp := start(importSpan, check, ms(5))
+	finish(start(typeCheckSpan, imp, ms(5)), ms(7))
+	finish(imp, ms(7))
+	finish(check, ms(8))
+	finish(hover, ms(10))
+
+	// The definition is fast.
+	definition := request("textDocument/definition", ms(10))
+	finish(start(loadSpan, definition, ms(10)), ms(12))
+	finish(definition, ms(12))
+
+	if len(s.recent) != 1 {
+		t.Fatalf("got %d slow requests, want 1", len(s.recent))
+	}
+	got := s.recent[0]
+	if got.Method != "textDocument/hover" || got.Duration != 10*time.Millisecond {
+		t.Errorf("got slow request %s taking %v, want textDocument/hover taking 10ms", got.Method, got.Duration)
+	}
+	want := map[string]time.Duration{
+		"queued":     1 * time.Millisecond,
+		"load":       3 * time.Millisecond,
+		"type-check": 4 * time.Millisecond,
+		"analysis":   0,
+	}
+	for _, b := range got.Blocked {
+		if b.Duration != want[b.On] {
+			t.Errorf("got %v blocked on %s, want %v", b.Duration, b.On, want[b.On])
+		}
+	}
+	if got.Other != 2*time.Millisecond {
+		t.Errorf("got %v of other work, want 2ms", got.Other)
+	}
+	if len(s.requests) != 0 || len(s.work) != 0 {
+		t.Errorf("%d requests and %d spans left unfinished", len(s.requests), len(s.work))
+	}
+}
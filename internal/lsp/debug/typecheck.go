@@ -0,0 +1,238 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/telemetry"
+	tele "golang.org/x/tools/internal/telemetry"
+)
+
+// The names of the spans emitted by the cache while type-checking.
+const (
+	typeCheckSpan = "cache.importer.typeCheck"
+	importSpan    = "cache.importer.Import"
+	parseSpan     = "cache.parseGo"
+)
+
+var typeCheckTmpl = template.Must(template.Must(BaseTemplate.Clone()).Parse(`
+{{define "title"}}Type checking{{end}}
+{{define "body"}}
+{{with .}}
+<p>The last batch of packages type-checked for a single request, started {{.Start.Format "15:04:05.000"}}, took {{.Duration}}.</p>
+<table>
+<tr><td class="label">Packages type-checked</td><td class="value">{{.Checked}}</td></tr>
+<tr><td class="label">Imports found in the cache</td><td class="value">{{.ImportHits}} of {{.Imports}}</td></tr>
+<tr><td class="label">Files found already parsed</td><td class="value">{{.ParseHits}} of {{.Files}}</td></tr>
+</table>
+<h2>Packages</h2>
+<table>
+<tr><th>Package</th><th>Syntax</th><th>Parse</th><th>Check</th><th>Total</th><th></th></tr>
+{{range .Packages}}
+<tr>
+<td style="padding-left:{{.Depth}}em">{{.ID}}</td>
+<td>{{.Mode}}</td>
+<td class="value">{{.Parse}} ({{.Parsed}}/{{.Files}} files)</td>
+<td class="value">{{.Check}}</td>
+<td class="value">{{.Duration}}</td>
+<td class="bar"><div style="{{.Bar}}"></div></td>
+</tr>
+{{end}}
+</table>
+<p>Syntax is "full" for the packages of the requested files, and "exported" for their dependencies, which are checked using only their exported declarations. Parse and check times exclude the time spent on dependencies.</p>
+{{else}}
+No packages have been type-checked yet.
+{{end}}
+{{end}}
+`))
+
+// typeCheckBatch is the breakdown of the type-checking of a package and the
+// dependencies that were type-checked on its behalf.
+type typeCheckBatch struct {
+	Start    time.Time
+	Duration time.Duration
+	Packages []*typeCheckTiming
+
+	Checked    int
+	Imports    int
+	ImportHits int
+	Files      int
+	ParseHits  int
+}
+
+// typeCheckTiming is the time spent type-checking a single package.
+type typeCheckTiming struct {
+	ID       string
+	Mode     string
+	Depth    int
+	Duration time.Duration
+	Parse    time.Duration
+	Check    time.Duration
+	Files    int
+	Parsed   int
+	Bar      template.CSS
+
+	start time.Time
+}
+
+// typeCheckNode is a span of interest to the type-checking breakdown.
+type typeCheckNode struct {
+	name     string
+	tags     tele.TagList
+	start    time.Time
+	duration time.Duration
+	children []*typeCheckNode
+}
+
+// typeChecks is an exporter that builds a breakdown of the most recent batch
+// of type checks from the spans emitted by the cache.
+type typeChecks struct {
+	mu         sync.Mutex
+	unfinished map[tele.SpanContext]*typeCheckNode
+	last       *typeCheckBatch
+}
+
+func (t *typeChecks) StartSpan(ctx context.Context, span *tele.Span) {
+	switch span.Name {
+	case typeCheckSpan, importSpan, parseSpan:
+	default:
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.unfinished == nil {
+		t.unfinished = make(map[tele.SpanContext]*typeCheckNode)
+	}
+	node := &typeCheckNode{
+		name:  span.Name,
+		tags:  span.Tags,
+		start: span.Start,
+	}
+	parent := t.unfinished[tele.SpanContext{TraceID: span.ID.TraceID, SpanID: span.ParentID}]
+	switch {
+	case parent != nil:
+		parent.children = append(parent.children, node)
+	case span.Name != typeCheckSpan:
+		// Only type checks start a batch.
+		return
+	}
+	t.unfinished[span.ID] = node
+}
+
+func (t *typeChecks) FinishSpan(ctx context.Context, span *tele.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node, ok := t.unfinished[span.ID]
+	if !ok {
+		return
+	}
+	delete(t.unfinished, span.ID)
+	node.duration = span.Finish.Sub(span.Start)
+	if _, hasParent := t.unfinished[tele.SpanContext{TraceID: span.ID.TraceID, SpanID: span.ParentID}]; hasParent {
+		return
+	}
+	batch := &typeCheckBatch{
+		Start:    node.start,
+		Duration: node.duration,
+	}
+	batch.add(node, 0)
+	// Each package's bar spans the time from its start to its end, relative
+	// to the batch, so that the bars of dependencies nest within the bars
+	// of the packages that import them.
+	for _, p := range batch.Packages {
+		left, width := 0.0, 100.0
+		if batch.Duration > 0 {
+			left = 100 * float64(p.start.Sub(batch.Start)) / float64(batch.Duration)
+			width = 100 * float64(p.Duration) / float64(batch.Duration)
+		}
+		p.Bar = template.CSS(fmt.Sprintf("margin-left:%.1f%%;width:%.1f%%", left, width))
+	}
+	t.last = batch
+}
+
+// add adds the timing of the type check of node, and of the dependencies
+// type-checked during it, to the batch.
+func (b *typeCheckBatch) add(node *typeCheckNode, depth int) {
+	timing := &typeCheckTiming{
+		ID:       fmt.Sprint(tagValue(node.tags, telemetry.Package)),
+		Mode:     fmt.Sprint(tagValue(node.tags, telemetry.ParseMode)),
+		Depth:    depth,
+		Duration: node.duration,
+		start:    node.start,
+	}
+	if n, ok := tagValue(node.tags, telemetry.FileCount).(int); ok {
+		timing.Files = n
+	}
+	b.Packages = append(b.Packages, timing)
+	b.Checked++
+	b.Files += timing.Files
+	var (
+		imports              time.Duration
+		parseStart, parseEnd time.Time
+	)
+	for _, child := range node.children {
+		switch child.name {
+		case parseSpan:
+			// Files are parsed concurrently, so the parse time is the time
+			// from the first parse to the end of the last.
+			if parseStart.IsZero() || child.start.Before(parseStart) {
+				parseStart = child.start
+			}
+			if end := child.start.Add(child.duration); end.After(parseEnd) {
+				parseEnd = end
+			}
+			timing.Parsed++
+		case importSpan:
+			imports += child.duration
+			b.Imports++
+			checked := false
+			for _, grandchild := range child.children {
+				if grandchild.name == typeCheckSpan {
+					checked = true
+					b.add(grandchild, depth+1)
+				}
+			}
+			if !checked {
+				b.ImportHits++
+			}
+		}
+	}
+	timing.Parse = parseEnd.Sub(parseStart)
+	b.ParseHits += timing.Files - timing.Parsed
+	timing.Check = node.duration - imports - timing.Parse
+	if timing.Check < 0 {
+		timing.Check = 0
+	}
+}
+
+func tagValue(tags tele.TagList, key interface{}) interface{} {
+	for _, t := range tags {
+		if t.Key == key {
+			return t.Value
+		}
+	}
+	return nil
+}
+
+func (t *typeChecks) Log(ctx context.Context, event tele.Event) {}
+
+func (t *typeChecks) Metric(ctx context.Context, data tele.MetricData) {}
+
+func (t *typeChecks) Flush() {}
+
+func (t *typeChecks) getData(req *http.Request) interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.last == nil {
+		return nil
+	}
+	return t.last
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/telemetry"
+	tele "golang.org/x/tools/internal/telemetry"
+)
+
+func TestTypeCheckBatch(t *testing.T) {
+	ctx := context.Background()
+	tc := &typeChecks{}
+	trace := tele.NewTraceID()
+	base := time.Now()
+	ms := func(n int) time.Time { return base.Add(time.Duration(n) * time.Millisecond) }
+
+	start := func(name string, parent *tele.Span, at time.Time, tags ...tele.Tag) *tele.Span {
+		span := &tele.Span{
+			Name:  name,
+			ID:    tele.SpanContext{TraceID: trace, SpanID: tele.NewSpanID()},
+			Start: at,
+			Tags:  tags,
+		}
+		if parent != nil {
+			span.ParentID = parent.ID.SpanID
+		}
+		tc.StartSpan(ctx, span)
+		return span
+	}
+	finish := func(span *tele.Span, at time.Time) {
+		span.Finish = at
+		tc.FinishSpan(ctx, span)
+	}
+
+	// a imports b, which is type-checked on its behalf, and c, which has
+	// already been type-checked. One of the two files of a is parsed, and the
+	// file of b is parsed.
+	a := start(typeCheckSpan, nil, ms(0), telemetry.Package.Of("a"), telemetry.ParseMode.Of("full"), telemetry.FileCount.Of(2))
+	finish(start(parseSpan, a, ms(0)), ms(2))
+	importB := start(importSpan, a, ms(2))
+	b := start(typeCheckSpan, importB, ms(2), telemetry.Package.Of("b"), telemetry.ParseMode.Of("exported"), telemetry.FileCount.Of(1))
+	finish(start(parseSpan, b, ms(2)), ms(3))
+	finish(b, ms(6))
+	finish(importB, ms(6))
+	finish(start(importSpan, a, ms(6)), ms(6))
+	finish(a, ms(10))
+
+	batch := tc.last
+	if batch == nil {
+		t.Fatal("no batch recorded")
+	}
+	if batch.Duration != 10*time.Millisecond {
+		t.Errorf("got batch duration %v, want 10ms", batch.Duration)
+	}
+	if batch.Checked != 2 || batch.Imports != 2 || batch.ImportHits != 1 || batch.Files != 3 || batch.ParseHits != 1 {
+		t.Errorf("got batch %+v, want 2 packages checked, 1 of 2 imports cached, 1 of 3 files parsed", batch)
+	}
+	want := []typeCheckTiming{
+		{ID: "a", Mode: "full", Depth: 0, Duration: 10 * time.Millisecond, Parse: 2 * time.Millisecond, Check: 4 * time.Millisecond, Files: 2, Parsed: 1},
+		{ID: "b", Mode: "exported", Depth: 1, Duration: 4 * time.Millisecond, Parse: 1 * time.Millisecond, Check: 3 * time.Millisecond, Files: 1, Parsed: 1},
+	}
+	if len(batch.Packages) != len(want) {
+		t.Fatalf("got %d packages, want %d", len(batch.Packages), len(want))
+	}
+	for i, got := range batch.Packages {
+		w := want[i]
+		w.Bar, w.start = got.Bar, got.start
+		if *got != w {
+			t.Errorf("package %d: got %+v, want %+v", i, *got, w)
+		}
+	}
+	if len(tc.unfinished) != 0 {
+		t.Errorf("%d spans left unfinished", len(tc.unfinished))
+	}
+	if err := typeCheckTmpl.Execute(ioutil.Discard, batch); err != nil {
+		t.Errorf("rendering the batch: %v", err)
+	}
+}
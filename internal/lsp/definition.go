@@ -10,6 +10,8 @@ import (
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/source"
 	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/log"
+	"golang.org/x/tools/internal/telemetry/tag"
 )
 
 func (s *Server) definition(ctx context.Context, params *protocol.DefinitionParams) ([]protocol.Location, error) {
@@ -19,20 +21,67 @@ func (s *Server) definition(ctx context.Context, params *protocol.DefinitionPara
 	if err != nil {
 		return nil, err
 	}
+	// The symbols of assembly files are defined by Go declarations.
+	switch view.Options().DetectLanguage("", uri.Filename()) {
+	case source.Asm:
+		return source.AsmDefinition(ctx, view, f, params.Position)
+	case source.Tmpl:
+		// The fields of templates are defined by the type of their data.
+		return source.TemplateDefinition(ctx, view, f, params.Position)
+	}
+	// The definitions of the patterns of //go:embed directives are the files
+	// they match.
+	locations, err := source.EmbedDefinition(ctx, view, f, params.Position)
+	if err != nil {
+		return nil, err
+	}
+	if locations != nil {
+		return locations, nil
+	}
+	// The definition of the target of a //go:linkname directive is the
+	// object it refers to.
+	locations, err = source.LinknameDefinition(ctx, view, f, params.Position)
+	if err != nil {
+		return nil, err
+	}
+	if locations != nil {
+		return locations, nil
+	}
+	// The declarations of imported packages are found from their syntax,
+	// without type-checking the packages, if possible.
+	locations, err = source.IndexedDefinition(ctx, view, f, params.Position)
+	if err != nil {
+		log.Error(ctx, "indexed definition failed", err, tag.Of("File", uri))
+	}
+	if locations != nil {
+		return locations, nil
+	}
 	ident, err := source.Identifier(ctx, view, f, params.Position)
 	if err != nil {
 		return nil, err
 	}
-	decRange, err := ident.Declaration.Range()
+	locations, err = ident.Definitions(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return []protocol.Location{
-		{
-			URI:   protocol.NewURI(ident.Declaration.URI()),
-			Range: decRange,
-		},
-	}, nil
+	// On the declaration of an object, also return the //go:linkname
+	// directives that refer to it, which have no declaration of their own.
+	identSpan, err := ident.Span()
+	if err != nil {
+		return nil, err
+	}
+	decSpan, err := ident.Declaration.Span()
+	if err != nil {
+		return nil, err
+	}
+	if span.Compare(identSpan, decSpan) == 0 {
+		linknames, err := ident.Linknames(ctx)
+		if err != nil {
+			log.Error(ctx, "no linknames", err, tag.Of("Identifier", ident.Name))
+		}
+		locations = append(locations, linknames...)
+	}
+	return locations, nil
 }
 
 func (s *Server) typeDefinition(ctx context.Context, params *protocol.TypeDefinitionParams) ([]protocol.Location, error) {
@@ -6,6 +6,9 @@ package lsp
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	"golang.org/x/tools/internal/lsp/protocol"
@@ -17,17 +20,54 @@ import (
 )
 
 func (s *Server) diagnostics(view source.View, uri span.URI) error {
-	ctx := view.BackgroundContext()
+	// The requests of the user are served before the diagnostics.
+	ctx := source.WithBackgroundPriority(view.BackgroundContext())
 	ctx, done := trace.StartSpan(ctx, "lsp:background-worker")
 	defer done()
 
 	ctx = telemetry.File.With(ctx, uri)
 
+	// Clients that pull diagnostics request them when they need them.
+	if view.Options().PullDiagnosticsSupported {
+		return nil
+	}
+
 	f, err := view.GetFile(ctx, uri)
 	if err != nil {
 		return err
 	}
-	reports, warningMsg, err := source.Diagnostics(ctx, view, f, view.Options().DisabledAnalyses)
+	// The diagnostics of an assembly file are those of its Go package,
+	// whose functions it may implement.
+	if view.Options().DetectLanguage("", uri.Filename()) == source.Asm {
+		if f, err = source.AsmPackageFile(ctx, view, f); err != nil {
+			return err
+		}
+	}
+	if view.Options().StreamDiagnostics {
+		requestCtx := ctx
+		ctx = source.WithPartialDiagnostics(ctx, func(uri span.URI, diagnostics []source.Diagnostic) {
+			// The type-checking may outlive this request, and must not
+			// publish diagnostics once newer ones may have been computed.
+			if requestCtx.Err() != nil || view.Ignore(uri) {
+				return
+			}
+			s.publishDiagnostics(requestCtx, uri, diagnostics)
+		})
+	}
+	var (
+		reports    map[span.URI][]source.Diagnostic
+		warningMsg string
+	)
+	switch view.Options().DetectLanguage("", uri.Filename()) {
+	case source.Tmpl:
+		reports, err = source.TemplateDiagnostics(ctx, view, f)
+	case source.Mod:
+		// All of the go.mod files of the workspace are checked, as a
+		// change to one module may break the replacements of the others.
+		reports, err = source.ModDiagnostics(ctx, view)
+	default:
+		reports, warningMsg, err = source.Diagnostics(ctx, view, f, view.Options().DisabledAnalyses)
+	}
 	if err != nil {
 		return err
 	}
@@ -67,6 +107,71 @@ func (s *Server) diagnostics(view source.View, uri span.URI) error {
 	return nil
 }
 
+// diagnostic answers a textDocument/diagnostic request. The diagnostics are
+// those of the package type-checked for the file, which are only recomputed
+// after a change to the package or its dependencies. The result ID is a hash
+// of the diagnostics, so that a client that already has them is told they are
+// unchanged rather than sent them again.
+func (s *Server) diagnostic(ctx context.Context, params *protocol.DocumentDiagnosticParams) (*protocol.DocumentDiagnosticReport, error) {
+	uri := span.NewURI(params.TextDocument.URI)
+	// The diagnostics of a large file changed since it was saved are those
+	// computed when it was saved.
+	if params.PreviousResultID != "" && s.isStaleLargeFile(uri) {
+		return &protocol.DocumentDiagnosticReport{
+			Kind:     protocol.DiagnosticUnchanged,
+			ResultID: params.PreviousResultID,
+		}, nil
+	}
+	view := s.session.ViewOf(uri)
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	var reports map[span.URI][]source.Diagnostic
+	switch view.Options().DetectLanguage("", uri.Filename()) {
+	case source.Asm:
+		if f, err = source.AsmPackageFile(ctx, view, f); err != nil {
+			return nil, err
+		}
+		reports, _, err = source.Diagnostics(ctx, view, f, view.Options().DisabledAnalyses)
+	case source.Tmpl:
+		reports, err = source.TemplateDiagnostics(ctx, view, f)
+	case source.Mod:
+		reports, err = source.ModDiagnostics(ctx, view)
+	default:
+		reports, _, err = source.Diagnostics(ctx, view, f, view.Options().DisabledAnalyses)
+	}
+	if err != nil {
+		return nil, err
+	}
+	items := toProtocolDiagnostics(ctx, reports[uri])
+	resultID, err := diagnosticsResultID(items)
+	if err != nil {
+		return nil, err
+	}
+	if resultID == params.PreviousResultID {
+		return &protocol.DocumentDiagnosticReport{
+			Kind:     protocol.DiagnosticUnchanged,
+			ResultID: resultID,
+		}, nil
+	}
+	return &protocol.DocumentDiagnosticReport{
+		Kind:     protocol.DiagnosticFull,
+		ResultID: resultID,
+		Items:    items,
+	}, nil
+}
+
+// diagnosticsResultID returns an identifier of the given diagnostics that
+// does not depend on when or by which server they were computed.
+func diagnosticsResultID(diagnostics []protocol.Diagnostic) (string, error) {
+	data, err := json.Marshal(diagnostics)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
 func (s *Server) publishDiagnostics(ctx context.Context, uri span.URI, diagnostics []source.Diagnostic) error {
 	s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
 		Diagnostics: toProtocolDiagnostics(ctx, diagnostics),
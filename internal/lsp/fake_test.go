@@ -0,0 +1,149 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+)
+
+// fakeClient is a client that supports work done progress and records the
+// progress and diagnostics reported to it. Its other methods are
+// unimplemented.
+type fakeClient struct {
+	protocol.Client
+	progress chan *protocol.ProgressParams
+
+	mu          sync.Mutex
+	diagnostics map[span.URI][]protocol.Diagnostic
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		progress:    make(chan *protocol.ProgressParams, 100),
+		diagnostics: make(map[span.URI][]protocol.Diagnostic),
+	}
+}
+
+func (c *fakeClient) WorkDoneProgressCreate(context.Context, *protocol.WorkDoneProgressCreateParams) error {
+	return nil
+}
+
+func (c *fakeClient) Progress(ctx context.Context, params *protocol.ProgressParams) error {
+	c.progress <- params
+	return nil
+}
+
+func (c *fakeClient) ShowMessage(context.Context, *protocol.ShowMessageParams) error {
+	return nil
+}
+
+func (c *fakeClient) LogMessage(context.Context, *protocol.LogMessageParams) error {
+	return nil
+}
+
+func (c *fakeClient) PublishDiagnostics(ctx context.Context, params *protocol.PublishDiagnosticsParams) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.diagnostics[span.NewURI(params.URI)] = params.Diagnostics
+	return nil
+}
+
+// awaitProgress waits for the first progress notification accepted by
+// match, and fails the test if none is reported in time.
+func (c *fakeClient) awaitProgress(t *testing.T, timeout time.Duration, match func(*protocol.ProgressParams) bool) *protocol.ProgressParams {
+	t.Helper()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case p := <-c.progress:
+			if match(p) {
+				return p
+			}
+		case <-deadline:
+			t.Fatalf("no matching progress reported after %v", timeout)
+		}
+	}
+}
+
+// fakeWatcher simulates the file watcher of a client. It changes files in
+// dir on disk, and notifies the server of the changes with
+// workspace/didChangeWatchedFiles. Like real file watchers, it may deliver
+// notifications late or not at all.
+type fakeWatcher struct {
+	server *Server
+	dir    string
+
+	// Delay is how long notifications are delayed.
+	Delay time.Duration
+
+	// Drop reports whether to drop the notification of a change to the
+	// file with the given name, relative to dir.
+	Drop func(name string) bool
+
+	pending sync.WaitGroup
+}
+
+// WriteFile writes the file with the given name, relative to w.dir.
+func (w *fakeWatcher) WriteFile(ctx context.Context, t *testing.T, name, content string) {
+	t.Helper()
+	filename := filepath.Join(w.dir, name)
+	changeType := protocol.Changed
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		changeType = protocol.Created
+	}
+	if err := ioutil.WriteFile(filename, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	w.notify(ctx, t, name, changeType)
+}
+
+// RemoveFile removes the file with the given name, relative to w.dir.
+func (w *fakeWatcher) RemoveFile(ctx context.Context, t *testing.T, name string) {
+	t.Helper()
+	if err := os.Remove(filepath.Join(w.dir, name)); err != nil {
+		t.Fatal(err)
+	}
+	w.notify(ctx, t, name, protocol.Deleted)
+}
+
+func (w *fakeWatcher) notify(ctx context.Context, t *testing.T, name string, changeType protocol.FileChangeType) {
+	t.Helper()
+	if w.Drop != nil && w.Drop(name) {
+		return
+	}
+	params := &protocol.DidChangeWatchedFilesParams{
+		Changes: []protocol.FileEvent{{
+			URI:  protocol.NewURI(span.FileURI(filepath.Join(w.dir, name))),
+			Type: changeType,
+		}},
+	}
+	if w.Delay == 0 {
+		if err := w.server.DidChangeWatchedFiles(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	w.pending.Add(1)
+	time.AfterFunc(w.Delay, func() {
+		defer w.pending.Done()
+		if err := w.server.DidChangeWatchedFiles(ctx, params); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// Await waits for all delayed notifications to be delivered.
+func (w *fakeWatcher) Await() {
+	w.pending.Wait()
+}
@@ -21,3 +21,29 @@ func (s *Server) formatting(ctx context.Context, params *protocol.DocumentFormat
 	}
 	return source.Format(ctx, view, f)
 }
+
+func (s *Server) rangeFormatting(ctx context.Context, params *protocol.DocumentRangeFormattingParams) ([]protocol.TextEdit, error) {
+	uri := span.NewURI(params.TextDocument.URI)
+	view := s.session.ViewOf(uri)
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if view.Snapshot().Handle(ctx, f).Identity().Kind != source.Go {
+		return nil, nil
+	}
+	return source.RangeFormat(ctx, view, f, params.Range)
+}
+
+func (s *Server) onTypeFormatting(ctx context.Context, params *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
+	uri := span.NewURI(params.TextDocument.URI)
+	view := s.session.ViewOf(uri)
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if view.Snapshot().Handle(ctx, f).Identity().Kind != source.Go {
+		return nil, nil
+	}
+	return source.OnTypeFormat(ctx, view, f, params.Position, params.Ch)
+}
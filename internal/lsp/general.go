@@ -17,6 +17,7 @@ import (
 	"golang.org/x/tools/internal/lsp/source"
 	"golang.org/x/tools/internal/span"
 	"golang.org/x/tools/internal/telemetry/log"
+	"golang.org/x/tools/internal/xcontext"
 	errors "golang.org/x/xerrors"
 )
 
@@ -37,6 +38,7 @@ func (s *Server) initialize(ctx context.Context, params *protocol.ParamInitia) (
 	// TODO: Handle results here.
 	source.SetOptions(&options, params.InitializationOptions)
 	options.ForClientCapabilities(params.Capabilities)
+	s.bridge.capabilities = params.Capabilities
 
 	s.pendingFolders = params.WorkspaceFolders
 	if len(s.pendingFolders) == 0 {
@@ -75,22 +77,34 @@ func (s *Server) initialize(ctx context.Context, params *protocol.ParamInitia) (
 	}
 	return &protocol.InitializeResult{
 		Capabilities: protocol.ServerCapabilities{
+			PositionEncoding:   options.PositionEncoding,
 			CodeActionProvider: codeActionProvider,
+			CodeLensProvider:   &protocol.CodeLensOptions{},
 			CompletionProvider: &protocol.CompletionOptions{
 				TriggerCharacters: []string{"."},
 			},
-			DefinitionProvider:         true,
-			DocumentFormattingProvider: true,
-			DocumentSymbolProvider:     true,
+			DefinitionProvider: true,
+			DiagnosticProvider: &protocol.DiagnosticOptions{
+				InterFileDependencies: true,
+			},
+			DocumentFormattingProvider:      true,
+			DocumentRangeFormattingProvider: true,
+			DocumentOnTypeFormattingProvider: &protocol.DocumentOnTypeFormattingOptions{
+				FirstTriggerCharacter: source.OnTypeTriggers[0],
+				MoreTriggerCharacter:  source.OnTypeTriggers[1:],
+			},
+			DocumentSymbolProvider: true,
 			ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
 				Commands: options.SupportedCommands,
 			},
 			FoldingRangeProvider:      true,
 			HoverProvider:             true,
+			InlayHintProvider:         true,
 			DocumentHighlightProvider: true,
 			DocumentLinkProvider:      &protocol.DocumentLinkOptions{},
 			ReferencesProvider:        true,
 			RenameProvider:            renameOpts,
+			SelectionRangeProvider:    true,
 			SignatureHelpProvider: &protocol.SignatureHelpOptions{
 				TriggerCharacters: []string{"(", ","},
 			},
@@ -101,7 +115,8 @@ func (s *Server) initialize(ctx context.Context, params *protocol.ParamInitia) (
 					IncludeText: false,
 				},
 			},
-			TypeDefinitionProvider: true,
+			TypeDefinitionProvider:  true,
+			WorkspaceSymbolProvider: true,
 			Workspace: &struct {
 				WorkspaceFolders *struct {
 					Supported           bool   "json:\"supported,omitempty\""
@@ -123,6 +138,7 @@ func (s *Server) initialize(ctx context.Context, params *protocol.ParamInitia) (
 func (s *Server) initialized(ctx context.Context, params *protocol.InitializedParams) error {
 	s.stateMu.Lock()
 	s.state = serverInitialized
+	s.unregisterBugs = s.handleBugs(xcontext.Detach(ctx))
 	s.stateMu.Unlock()
 
 	options := s.session.Options()
@@ -142,19 +158,6 @@ func (s *Server) initialized(ctx context.Context, params *protocol.InitializedPa
 		)
 	}
 
-	if options.WatchFileChanges && options.DynamicWatchedFilesSupported {
-		registrations = append(registrations, protocol.Registration{
-			ID:     "workspace/didChangeWatchedFiles",
-			Method: "workspace/didChangeWatchedFiles",
-			RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{
-				Watchers: []protocol.FileSystemWatcher{{
-					GlobPattern: "**/*.go",
-					Kind:        float64(protocol.WatchChange + protocol.WatchDelete + protocol.WatchCreate),
-				}},
-			},
-		})
-	}
-
 	if len(registrations) > 0 {
 		s.client.RegisterCapability(ctx, &protocol.RegistrationParams{
 			Registrations: registrations,
@@ -171,6 +174,11 @@ func (s *Server) initialized(ctx context.Context, params *protocol.InitializedPa
 		}
 	}
 	s.pendingFolders = nil
+	// The file watchers depend on the folders of the views.
+	if err := s.updateWatchedFiles(ctx); err != nil {
+		log.Error(ctx, "registering file watchers", err)
+	}
+	s.restoreJournal(ctx)
 
 	return nil
 }
@@ -230,8 +238,17 @@ func (s *Server) shutdown(ctx context.Context) error {
 	if s.state < serverInitialized {
 		return jsonrpc2.NewErrorf(jsonrpc2.CodeInvalidRequest, "server not initialized")
 	}
+	if s.unregisterBugs != nil {
+		s.unregisterBugs()
+	}
+	if j := s.getJournal(); j != nil {
+		if err := j.Remove(); err != nil {
+			log.Error(ctx, "removing journal", err)
+		}
+	}
 	// drop all the active views
 	s.session.Shutdown(ctx)
+	s.shutdownExternal(ctx)
 	s.state = serverShutDown
 	return nil
 }
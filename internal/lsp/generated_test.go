@@ -0,0 +1,132 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/cache"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/tests"
+	"golang.org/x/tools/internal/span"
+)
+
+// TestGeneratedFiles checks that gopls warns about the edits to generated
+// files, and does not edit them itself unless told to.
+func TestGeneratedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopls-generated-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	const gen = "// Code generated by hand. DO NOT EDIT.\n\npackage a\n\nfunc G() int { return F() }\n"
+	for name, content := range map[string]string{
+		"go.mod": "module example.com/a\n",
+		"a.go":   "package a\n\nfunc F() int { return 1 }\n\nvar _ = G()\n",
+		"gen.go": gen,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := tests.Context(t)
+	session := cache.New(nil).NewSession(ctx)
+	options := tests.DefaultOptions()
+	session.SetOptions(options)
+	view := session.NewView(ctx, "generated_test", span.FileURI(dir), options)
+	s := &Server{
+		client:      newFakeClient(),
+		session:     session,
+		undelivered: make(map[span.URI][]source.Diagnostic),
+	}
+	aURI := protocol.NewURI(span.FileURI(filepath.Join(dir, "a.go")))
+	genURI := span.FileURI(filepath.Join(dir, "gen.go"))
+
+	rename := func(uri protocol.DocumentURI, line, char float64) ([]string, error) {
+		params := &protocol.RenameParams{NewName: "H"}
+		params.TextDocument.URI = uri
+		params.Position = protocol.Position{Line: line, Character: char}
+		edit, err := s.rename(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		var files []string
+		for uri := range *edit.Changes {
+			files = append(files, filepath.Base(span.NewURI(uri).Filename()))
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+	files, err := rename(aURI, 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(files, " "); got != "a.go" {
+		t.Errorf("renaming F edits %s, want a.go", got)
+	}
+	if _, err := rename(aURI, 4, 8); err == nil || !strings.Contains(err.Error(), "generated file") {
+		t.Errorf("renaming G: got error %v, want an error about the generated file", err)
+	}
+
+	// The edits to the generated file are reported.
+	warnings := func() []source.Diagnostic {
+		f, err := view.GetFile(ctx, genURI)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports, _, err := source.Diagnostics(ctx, view, f, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var warnings []source.Diagnostic
+		for _, d := range reports[genURI] {
+			if d.Source == source.GeneratedSource {
+				warnings = append(warnings, d)
+			}
+		}
+		return warnings
+	}
+	if err := s.didOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: protocol.NewURI(genURI), Text: gen},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if w := warnings(); len(w) != 0 {
+		t.Errorf("got warnings %v before the generated file is edited", w)
+	}
+	if err := s.didChange(ctx, &protocol.DidChangeTextDocumentParams{
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: gen + "\nvar x = 1\n"}},
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: protocol.NewURI(genURI)},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if w := warnings(); len(w) != 1 || w[0].Range.Start.Line != 0 {
+		t.Errorf("got warnings %v after the generated file is edited, want one on its first line", w)
+	}
+
+	// With editGeneratedFiles, gopls edits generated files, and does not warn
+	// about them.
+	options.EditGeneratedFiles = true
+	view.SetOptions(options)
+	if w := warnings(); len(w) != 0 {
+		t.Errorf("got warnings %v with editGeneratedFiles", w)
+	}
+	files, err = rename(aURI, 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(files, " "); got != "a.go gen.go" {
+		t.Errorf("renaming F with editGeneratedFiles edits %s, want a.go gen.go", got)
+	}
+}
@@ -17,21 +17,12 @@ import (
 func (s *Server) documentHighlight(ctx context.Context, params *protocol.DocumentHighlightParams) ([]protocol.DocumentHighlight, error) {
 	uri := span.NewURI(params.TextDocument.URI)
 	view := s.session.ViewOf(uri)
-	rngs, err := source.Highlight(ctx, view, uri, params.Position)
+	highlights, err := source.Highlight(ctx, view, uri, params.Position)
 	if err != nil {
 		log.Error(ctx, "no highlight", err, telemetry.URI.Of(uri))
 	}
-	return toProtocolHighlight(rngs), nil
-}
-
-func toProtocolHighlight(rngs []protocol.Range) []protocol.DocumentHighlight {
-	result := make([]protocol.DocumentHighlight, 0, len(rngs))
-	kind := protocol.Text
-	for _, rng := range rngs {
-		result = append(result, protocol.DocumentHighlight{
-			Kind:  &kind,
-			Range: rng,
-		})
+	if highlights == nil {
+		highlights = []protocol.DocumentHighlight{}
 	}
-	return result
+	return highlights, nil
 }
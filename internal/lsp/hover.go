@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/source"
@@ -22,6 +23,9 @@ func (s *Server) hover(ctx context.Context, params *protocol.HoverParams) (*prot
 	if err != nil {
 		return nil, err
 	}
+	if view.Options().DetectLanguage("", uri.Filename()) == source.Mod {
+		return source.ModHover(ctx, view, f, params.Position, view.Options().PreferredContentFormat)
+	}
 	ident, err := source.Identifier(ctx, view, f, params.Position)
 	if err != nil {
 		return nil, nil
@@ -86,5 +90,60 @@ func (s *Server) toProtocolHoverContents(ctx context.Context, h *source.HoverInf
 		}
 		content.Value = string(b)
 	}
+	switch options.HoverKind {
+	case source.SynopsisDocumentation, source.FullDocumentation:
+		if h.Module != nil {
+			content.Value += "\n" + moduleHoverText(h.Module, content.Kind == protocol.Markdown)
+		}
+		// Links cannot be put in the code block of the signature, so they
+		// follow the documentation.
+		if content.Kind == protocol.Markdown && len(h.Links) > 0 {
+			var links []string
+			for _, l := range h.Links {
+				links = append(links, fmt.Sprintf("[`%s`](%s)", l.Name, l.Target))
+			}
+			content.Value += "\n\n" + strings.Join(links, ", ")
+		}
+		if h.Implements != nil {
+			content.Value += "\n\n" + implementsHoverText(h.Implements, content.Kind == protocol.Markdown)
+		}
+	}
 	return content
 }
+
+// implementsHoverText returns the line of the hover of a type that lists the
+// interfaces it implements, or the types that implement an interface.
+func implementsHoverText(impls *source.HoverImplements, markdown bool) string {
+	var names []string
+	for _, l := range impls.Types {
+		if markdown {
+			names = append(names, fmt.Sprintf("[`%s`](%s)", l.Name, l.Target))
+		} else {
+			names = append(names, l.Name)
+		}
+	}
+	list := strings.Join(names, ", ")
+	if more := impls.Count - len(impls.Types); more > 0 {
+		list += fmt.Sprintf(" and %d more", more)
+	}
+	if impls.Interface {
+		if impls.Count == 1 {
+			return "1 implementation: " + list
+		}
+		return fmt.Sprintf("%d implementations: %s", impls.Count, list)
+	}
+	return "Implements " + list
+}
+
+// moduleHoverText returns the line of the hover of an import spec that tells
+// the module of the imported package, its version, and its license.
+func moduleHoverText(m *source.ModuleMetadata, markdown bool) string {
+	text := fmt.Sprintf("module %s %s", m.Path, m.Version)
+	if markdown {
+		text = fmt.Sprintf("\nmodule `%s` `%s`", m.Path, m.Version)
+	}
+	if m.License != "" {
+		text += fmt.Sprintf(", license %s", m.License)
+	}
+	return text
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"context"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+func (s *Server) inlayHint(ctx context.Context, params *protocol.InlayHintParams) ([]protocol.InlayHint, error) {
+	uri := span.NewURI(params.TextDocument.URI)
+	view := s.session.ViewOf(uri)
+	if s.isLargeFile(ctx, view, uri) {
+		return nil, nil
+	}
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	hints, err := source.InlayHints(ctx, view, f, params.Range)
+	if err != nil {
+		return nil, err
+	}
+	return toProtocolInlayHints(hints)
+}
+
+func toProtocolInlayHints(hints []*source.InlayHintInfo) ([]protocol.InlayHint, error) {
+	result := make([]protocol.InlayHint, 0, len(hints))
+	for _, info := range hints {
+		rng, err := info.Range()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, protocol.InlayHint{
+			Position:     rng.Start,
+			Label:        info.Label,
+			Kind:         info.Kind,
+			Tooltip:      info.Tooltip,
+			PaddingLeft:  info.PaddingLeft,
+			PaddingRight: info.PaddingRight,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"context"
+
+	"golang.org/x/tools/internal/lsp/journal"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/telemetry/log"
+)
+
+// startJournal handles the gopls/journal request, which the forwarder of a
+// client sends before any other message. It opens the journal of the
+// client, which holds the files that the client had open in the previous
+// daemon if this one was restarted after a crash.
+func (s *Server) startJournal(ctx context.Context, params *journal.Params) (interface{}, error) {
+	dir, err := journal.Dir()
+	if err != nil {
+		return nil, err
+	}
+	j, err := journal.Open(dir, params.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	s.journalMu.Lock()
+	s.journal = j
+	s.journalMu.Unlock()
+	return nil, nil
+}
+
+func (s *Server) getJournal() *journal.Journal {
+	s.journalMu.Lock()
+	defer s.journalMu.Unlock()
+	return s.journal
+}
+
+// restoreJournal opens the files recorded in the journal of the client
+// again, with their content in the editor, once the views are created.
+func (s *Server) restoreJournal(ctx context.Context) {
+	j := s.getJournal()
+	if j == nil {
+		return
+	}
+	for _, f := range j.Files() {
+		params := &protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{
+				URI:        f.URI,
+				LanguageID: f.LanguageID,
+				Version:    f.Version,
+				Text:       f.Text,
+			},
+		}
+		if err := s.didOpen(ctx, params); err != nil {
+			log.Error(ctx, "restoring "+f.URI, err)
+		}
+	}
+}
+
+// The following functions record the changes of the open files in the
+// journal of the client, if it has one.
+
+func (s *Server) journalOpened(ctx context.Context, item protocol.TextDocumentItem) {
+	if j := s.getJournal(); j != nil {
+		f := journal.File{URI: item.URI, LanguageID: item.LanguageID, Version: item.Version, Text: item.Text}
+		if err := j.Opened(f); err != nil {
+			log.Error(ctx, "writing journal", err)
+		}
+	}
+}
+
+func (s *Server) journalChanged(ctx context.Context, uri string, version float64, text string) {
+	if j := s.getJournal(); j != nil {
+		if err := j.Changed(uri, version, text); err != nil {
+			log.Error(ctx, "writing journal", err)
+		}
+	}
+}
+
+func (s *Server) journalClosed(ctx context.Context, uri string) {
+	if j := s.getJournal(); j != nil {
+		if err := j.Closed(uri); err != nil {
+			log.Error(ctx, "writing journal", err)
+		}
+	}
+}
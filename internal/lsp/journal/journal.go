@@ -0,0 +1,229 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package journal records the files that the client of a gopls daemon has
+// open, with their content in the editor, so that a daemon restarted after
+// a crash can open them again for the client, rather than returning results
+// for their content on disk.
+//
+// The journal of a client is a JSON file named by the ID that its forwarder
+// chose. It is rewritten when a file is opened or closed, and in the
+// background shortly after the content of an open file changes, so that the
+// edits of the user are not slowed down by writing it.
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Params are the parameters of the gopls/journal request, with which the
+// forwarder of a client tells the daemon the ID of the journal of the client.
+type Params struct {
+	ClientID string `json:"clientID"`
+}
+
+// File is an open file recorded in a journal.
+type File struct {
+	URI        string  `json:"uri"`
+	LanguageID string  `json:"languageId"`
+	Version    float64 `json:"version"`
+	Text       string  `json:"text"`
+}
+
+// Journal is the journal of the open files of a client.
+type Journal struct {
+	filename string
+
+	mu    sync.Mutex
+	files map[string]File
+
+	// pending is the timer of the background write of the changes that are
+	// not written yet, if any, and err is the error of the last background
+	// write.
+	pending *time.Timer
+	err     error
+}
+
+// writeDelay is the time after a change of an open file at which the
+// journal is written, so that it is written once for a burst of edits.
+var writeDelay = time.Second
+
+// Dir returns the directory of the journals of the daemons of the user. It
+// is in the cache directory of the user rather than in the shared temporary
+// directory, where another user could create it first and read or plant
+// journals.
+func Dir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gopls", "journal"), nil
+}
+
+// validID matches the IDs of clients, which name their journal files.
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Open returns the journal of the client with the given ID in dir, which
+// holds the files recorded in it by a previous daemon, if any.
+func Open(dir, clientID string) (*Journal, error) {
+	if !validID.MatchString(clientID) {
+		return nil, fmt.Errorf("invalid client ID %q", clientID)
+	}
+	j := &Journal{
+		filename: filepath.Join(dir, clientID+".json"),
+		files:    make(map[string]File),
+	}
+	data, err := ioutil.ReadFile(j.filename)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("reading journal %s: %v", j.filename, err)
+	}
+	for _, f := range files {
+		j.files[f.URI] = f
+	}
+	return j, nil
+}
+
+// Files returns the open files recorded in j, ordered by URI.
+func (j *Journal) Files() []File {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.sortedFiles()
+}
+
+// Opened records that f was opened.
+func (j *Journal) Opened(f File) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.files[f.URI] = f
+	j.stopPending()
+	return j.write()
+}
+
+// Changed records the new version and text of the open file uri. The
+// journal is written in the background, and Changed returns the error of
+// the last background write, if any.
+func (j *Journal) Changed(uri string, version float64, text string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	err := j.err
+	j.err = nil
+	f, ok := j.files[uri]
+	if !ok {
+		return err
+	}
+	f.Version, f.Text = version, text
+	j.files[uri] = f
+	if j.pending == nil {
+		j.pending = time.AfterFunc(writeDelay, j.writePending)
+	}
+	return err
+}
+
+// writePending writes the changes recorded since the last write.
+func (j *Journal) writePending() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.pending == nil {
+		// Written or removed since.
+		return
+	}
+	j.pending = nil
+	j.err = j.write()
+}
+
+// Flush writes the changes that are not written yet.
+func (j *Journal) Flush() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.pending == nil {
+		return nil
+	}
+	j.stopPending()
+	return j.write()
+}
+
+// stopPending cancels the background write, if any. It must be called with
+// j.mu held.
+func (j *Journal) stopPending() {
+	if j.pending != nil {
+		j.pending.Stop()
+		j.pending = nil
+	}
+}
+
+// Closed records that the file uri was closed.
+func (j *Journal) Closed(uri string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.files[uri]; !ok {
+		return nil
+	}
+	delete(j.files, uri)
+	j.stopPending()
+	return j.write()
+}
+
+// Remove removes the journal file, once the client shuts the session down.
+func (j *Journal) Remove() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.files = make(map[string]File)
+	j.stopPending()
+	if err := os.Remove(j.filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (j *Journal) sortedFiles() []File {
+	files := make([]File, 0, len(j.files))
+	for _, f := range j.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, k int) bool { return files[i].URI < files[k].URI })
+	return files
+}
+
+// write writes the files of j to its file. It writes a temporary file and
+// renames it, so that a crash leaves the previous journal intact.
+func (j *Journal) write() error {
+	data, err := json.Marshal(j.sortedFiles())
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(j.filename)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, filepath.Base(j.filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), j.filename)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package journal
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopls-journal-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(delay time.Duration) { writeDelay = delay }(writeDelay)
+	writeDelay = time.Hour
+
+	j, err := Open(dir, "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := File{URI: "file:///a.go", LanguageID: "go", Version: 1, Text: "package a"}
+	b := File{URI: "file:///b.go", LanguageID: "go", Version: 1, Text: "package b"}
+	for _, err := range []error{
+		j.Opened(a),
+		j.Opened(b),
+		j.Changed(a.URI, 2, "package a // edited"),
+		j.Closed(b.URI),
+		j.Changed(b.URI, 2, "package b // closed"),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The changes are written in the background, or once flushed.
+	if err := j.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A journal opened again, as by a restarted daemon, has the open files.
+	restored, err := Open(dir, "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []File{{URI: a.URI, LanguageID: "go", Version: 2, Text: "package a // edited"}}
+	if got := restored.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("restored files = %+v, want %+v", got, want)
+	}
+
+	if err := restored.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	j, err = Open(dir, "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := j.Files(); len(files) != 0 {
+		t.Errorf("journal after Remove has files %+v", files)
+	}
+
+	if _, err := Open(dir, "../client"); err == nil {
+		t.Errorf("Open with an invalid client ID succeeded")
+	}
+}
+
+func TestJournalBackgroundWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopls-journal-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(delay time.Duration) { writeDelay = delay }(writeDelay)
+	writeDelay = time.Hour
+
+	j, err := Open(dir, "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := File{URI: "file:///a.go", LanguageID: "go", Version: 1, Text: "package a"}
+	if err := j.Opened(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Changed(a.URI, 2, "package a // edited"); err != nil {
+		t.Fatal(err)
+	}
+	// The change is not written until the background write.
+	restored, err := Open(dir, "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.Files(); !reflect.DeepEqual(got, []File{a}) {
+		t.Errorf("files before the background write = %+v, want %+v", got, []File{a})
+	}
+	j.mu.Lock()
+	pending := j.pending != nil
+	j.mu.Unlock()
+	if !pending {
+		t.Fatal("no background write is pending after a change")
+	}
+	j.writePending()
+	restored, err = Open(dir, "client-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []File{{URI: a.URI, LanguageID: "go", Version: 2, Text: "package a // edited"}}
+	if got := restored.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("files after the background write = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+// isLargeFile reports whether the file uri is larger than the
+// LargeFileThreshold option of view. The features computed as the user types
+// are disabled for such files, and their diagnostics are only computed when
+// they are opened or saved.
+func (s *Server) isLargeFile(ctx context.Context, view source.View, uri span.URI) bool {
+	threshold := view.Options().LargeFileThreshold
+	if threshold <= 0 {
+		return false
+	}
+	content, _, err := s.session.GetFile(uri, source.UnknownKind).Read(ctx)
+	return err == nil && int64(len(content)) > threshold
+}
+
+// warnLargeFile tells the user that the features computed as they type are
+// disabled for the large file uri.
+func (s *Server) warnLargeFile(ctx context.Context, view source.View, uri span.URI) {
+	s.client.ShowMessage(ctx, &protocol.ShowMessageParams{
+		Type: protocol.Info,
+		Message: fmt.Sprintf("%s is larger than %d bytes, the largeFileThreshold setting: its diagnostics are only updated when it is saved, and it has no inlay hints or code lenses.",
+			uri.Filename(), view.Options().LargeFileThreshold),
+	})
+}
+
+// setStaleLargeFile records whether the diagnostics of the large file uri
+// are stale, and reports whether they were.
+func (s *Server) setStaleLargeFile(uri span.URI, stale bool) bool {
+	s.staleLargeFilesMu.Lock()
+	defer s.staleLargeFilesMu.Unlock()
+	wasStale := s.staleLargeFiles[uri]
+	if !stale {
+		delete(s.staleLargeFiles, uri)
+		return wasStale
+	}
+	if s.staleLargeFiles == nil {
+		s.staleLargeFiles = make(map[span.URI]bool)
+	}
+	s.staleLargeFiles[uri] = true
+	return wasStale
+}
+
+// isStaleLargeFile reports whether the large file uri was changed since it
+// was last saved.
+func (s *Server) isStaleLargeFile(uri span.URI) bool {
+	s.staleLargeFilesMu.Lock()
+	defer s.staleLargeFilesMu.Unlock()
+	return s.staleLargeFiles[uri]
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/cache"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/tests"
+	"golang.org/x/tools/internal/span"
+)
+
+func TestLargeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopls-large-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	small := "package a\n"
+	large := small + "\n// " + strings.Repeat("x", 100) + "\n"
+	for name, content := range map[string]string{
+		"go.mod":   "module example.com/a\n",
+		"small.go": small,
+		"large.go": large,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := tests.Context(t)
+	session := cache.New(nil).NewSession(ctx)
+	options := tests.DefaultOptions()
+	options.LargeFileThreshold = 50
+	session.SetOptions(options)
+	view := session.NewView(ctx, "large_test", span.FileURI(dir), options)
+	s := &Server{
+		client:      newFakeClient(),
+		session:     session,
+		undelivered: make(map[span.URI][]source.Diagnostic),
+	}
+
+	smallURI := span.FileURI(filepath.Join(dir, "small.go"))
+	largeURI := span.FileURI(filepath.Join(dir, "large.go"))
+	if s.isLargeFile(ctx, view, smallURI) {
+		t.Errorf("isLargeFile(small.go) = true, want false")
+	}
+	if !s.isLargeFile(ctx, view, largeURI) {
+		t.Errorf("isLargeFile(large.go) = false, want true")
+	}
+
+	hints, err := s.inlayHint(ctx, &protocol.InlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.NewURI(largeURI)},
+	})
+	if err != nil || hints != nil {
+		t.Errorf("inlayHint(large.go) = %v, %v, want no hints", hints, err)
+	}
+
+	// The diagnostics of a changed large file are stale until it is saved.
+	if err := s.didOpen(ctx, &protocol.DidOpenTextDocumentParams{
+		TextDocument: protocol.TextDocumentItem{URI: protocol.NewURI(largeURI), LanguageID: "go", Version: 1, Text: large},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.didChange(ctx, &protocol.DidChangeTextDocumentParams{
+		TextDocument:   versioned(largeURI, 2),
+		ContentChanges: []protocol.TextDocumentContentChangeEvent{{Text: large + "\n"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !s.isStaleLargeFile(largeURI) {
+		t.Errorf("the diagnostics of large.go are not stale after a change")
+	}
+	report, err := s.diagnostic(ctx, &protocol.DocumentDiagnosticParams{
+		TextDocument:     protocol.TextDocumentIdentifier{URI: protocol.NewURI(largeURI)},
+		PreviousResultID: "previous",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Kind != protocol.DiagnosticUnchanged || report.ResultID != "previous" {
+		t.Errorf("diagnostic(large.go) = %v %q, want an unchanged report", report.Kind, report.ResultID)
+	}
+	if err := s.didSave(ctx, &protocol.DidSaveTextDocumentParams{
+		TextDocument: versioned(largeURI, 2),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if s.isStaleLargeFile(largeURI) {
+		t.Errorf("the diagnostics of large.go are stale after a save")
+	}
+}
+
+func versioned(uri span.URI, version float64) protocol.VersionedTextDocumentIdentifier {
+	return protocol.VersionedTextDocumentIdentifier{
+		Version:                version,
+		TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: protocol.NewURI(uri)},
+	}
+}
@@ -8,8 +8,11 @@ import (
 	"context"
 	"go/ast"
 	"go/token"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 
 	"golang.org/x/tools/internal/lsp/protocol"
@@ -28,10 +31,11 @@ func (s *Server) documentLink(ctx context.Context, params *protocol.DocumentLink
 		return nil, err
 	}
 	fh := view.Snapshot().Handle(ctx, f)
-	file, m, _, err := view.Session().Cache().ParseGoHandle(fh, source.ParseFull).Parse(ctx)
+	file, m, _, err := view.Session().ParseGoHandle(fh, source.ParseFull).Parse(ctx)
 	if err != nil {
 		return nil, err
 	}
+	fset := view.Session().Cache().FileSet()
 	var links []protocol.DocumentLink
 	ast.Inspect(file, func(node ast.Node) bool {
 		switch n := node.(type) {
@@ -49,11 +53,32 @@ func (s *Server) documentLink(ctx context.Context, params *protocol.DocumentLink
 			}
 			links = append(links, l)
 			return false
+		case *ast.Field:
+			if n.Tag == nil {
+				return true
+			}
+			src, err := sourceText(fset, m, n.Tag.Pos(), n.Tag.End())
+			if err != nil {
+				log.Error(ctx, "cannot find links in struct tag", err)
+				return true
+			}
+			l, err := findLinksInStructTag(src, n.Tag.Pos(), view, m)
+			if err != nil {
+				log.Error(ctx, "cannot find links in struct tag", err)
+				return true
+			}
+			links = append(links, l...)
+			return true
 		case *ast.BasicLit:
 			if n.Kind != token.STRING {
 				return false
 			}
-			l, err := findLinksInString(n.Value, n.Pos(), view, m)
+			src, err := sourceText(fset, m, n.Pos(), n.End())
+			if err != nil {
+				log.Error(ctx, "cannot find links in string", err)
+				return false
+			}
+			l, err := findLinksInString(src, n.Pos(), view, m)
 			if err != nil {
 				log.Error(ctx, "cannot find links in string", err)
 				return false
@@ -66,18 +91,43 @@ func (s *Server) documentLink(ctx context.Context, params *protocol.DocumentLink
 
 	for _, commentGroup := range file.Comments {
 		for _, comment := range commentGroup.List {
-			l, err := findLinksInString(comment.Text, comment.Pos(), view, m)
+			src, err := sourceText(fset, m, comment.Pos(), comment.End())
+			if err != nil {
+				log.Error(ctx, "cannot find links in comment", err)
+				continue
+			}
+			l, err := findLinksInString(src, comment.Pos(), view, m)
 			if err != nil {
 				log.Error(ctx, "cannot find links in comment", err)
 				continue
 			}
 			links = append(links, l...)
+			if strings.HasPrefix(src, "//go:generate ") || strings.HasPrefix(src, "//go:generate\t") {
+				l, err := findLinksInGenerate(src, comment.Pos(), view, m, uri.Filename())
+				if err != nil {
+					log.Error(ctx, "cannot find links in go:generate directive", err)
+					continue
+				}
+				links = append(links, l...)
+			}
 		}
 	}
 
 	return links, nil
 }
 
+// sourceText returns the text of the file of mapper from start to end. The
+// scanner removes the carriage returns from the values of raw strings and
+// from the text of comments, so offsets into them are not offsets into the
+// file.
+func sourceText(fset *token.FileSet, mapper *protocol.ColumnMapper, start, end token.Pos) (string, error) {
+	startOffset, endOffset := fset.Position(start).Offset, fset.Position(end).Offset
+	if startOffset < 0 || endOffset > len(mapper.Content) || startOffset > endOffset {
+		return "", errors.Errorf("invalid range %d-%d of file of length %d", startOffset, endOffset, len(mapper.Content))
+	}
+	return string(mapper.Content[startOffset:endOffset]), nil
+}
+
 func findLinksInString(src string, pos token.Pos, view source.View, mapper *protocol.ColumnMapper) ([]protocol.DocumentLink, error) {
 	var links []protocol.DocumentLink
 	re, err := getURLRegexp()
@@ -99,6 +149,95 @@ func findLinksInString(src string, pos token.Pos, view source.View, mapper *prot
 	return links, nil
 }
 
+// findLinksInStructTag returns the links to the documentation of the import
+// paths, such as the go_package option of protocol buffers, that are among
+// the comma-separated items of the values of the struct tag src at pos. An
+// item can also be an option of the form name=path.
+func findLinksInStructTag(src string, pos token.Pos, view source.View, mapper *protocol.ColumnMapper) ([]protocol.DocumentLink, error) {
+	var links []protocol.DocumentLink
+	for _, match := range tagValueRegexp.FindAllStringSubmatchIndex(src, -1) {
+		start, end := match[2], match[3]
+		for start < end {
+			n := strings.IndexByte(src[start:end], ',')
+			if n < 0 {
+				n = end - start
+			}
+			item := src[start : start+n]
+			offset := start
+			if i := strings.IndexByte(item, '='); i >= 0 {
+				item = item[i+1:]
+				offset += i + 1
+			}
+			if importPathRegexp.MatchString(item) {
+				startPos := token.Pos(int(pos) + offset)
+				endPos := token.Pos(int(pos) + offset + len(item))
+				l, err := toProtocolLink(view, mapper, "https://godoc.org/"+item, startPos, endPos)
+				if err != nil {
+					return nil, err
+				}
+				links = append(links, l)
+			}
+			start += n + 1
+		}
+	}
+	return links, nil
+}
+
+// findLinksInGenerate returns the links of the arguments of the go:generate
+// directive src at pos in the file filename that are import paths, such as
+// the package of "go run", or relative paths of existing files, such as the
+// command.
+func findLinksInGenerate(src string, pos token.Pos, view source.View, mapper *protocol.ColumnMapper, filename string) ([]protocol.DocumentLink, error) {
+	var links []protocol.DocumentLink
+	prefix := len("//go:generate")
+	for _, field := range fieldRegexp.FindAllStringIndex(src[prefix:], -1) {
+		start, end := prefix+field[0], prefix+field[1]
+		arg := src[start:end]
+		if strings.HasPrefix(arg, "//") {
+			// The rest of the line is a comment, such as a note.
+			break
+		}
+		var target string
+		switch {
+		case strings.HasPrefix(arg, "./") || strings.HasPrefix(arg, "../"):
+			path := filepath.Join(filepath.Dir(filename), filepath.FromSlash(arg))
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			target = string(span.FileURI(path))
+		default:
+			if i := strings.IndexByte(arg, '@'); i >= 0 {
+				arg = arg[:i]
+			}
+			if !importPathRegexp.MatchString(arg) {
+				continue
+			}
+			target = "https://godoc.org/" + arg
+		}
+		l, err := toProtocolLink(view, mapper, target, token.Pos(int(pos)+start), token.Pos(int(pos)+end))
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, nil
+}
+
+var (
+	// tagValueRegexp matches the values of the key:"value" pairs of a
+	// struct tag, whose quotes are not escaped inside the backquotes of a
+	// raw string.
+	tagValueRegexp = regexp.MustCompile(`\w+:"([^"]*)"`)
+
+	// importPathRegexp matches the import paths whose first element is a
+	// domain name, such as "github.com/user/repo".
+	importPathRegexp = regexp.MustCompile(`^[a-z0-9-]+(\.[a-z0-9-]+)+(/[\w.~-]+)+$`)
+
+	// fieldRegexp matches the space-separated fields of a go:generate
+	// directive.
+	fieldRegexp = regexp.MustCompile(`\S+`)
+)
+
 const urlRegexpString = "(http|ftp|https)://([\\w_-]+(?:(?:\\.[\\w_-]+)+))([\\w.,@?^=%&:/~+#-]*[\\w@?^=%&/~+#-])?"
 
 var (
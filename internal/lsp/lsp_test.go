@@ -81,6 +81,7 @@ func (r *runner) Diagnostics(t *testing.T, uri span.URI, want []source.Diagnosti
 		t.Fatal(err)
 	}
 	got := results[uri]
+	r.pullDiagnostics(t, uri, len(got))
 	// A special case to test that there are no diagnostics for a file.
 	if len(want) == 1 && want[0].Source == "no_diagnostics" {
 		if len(got) != 0 {
@@ -93,6 +94,28 @@ func (r *runner) Diagnostics(t *testing.T, uri span.URI, want []source.Diagnosti
 	}
 }
 
+// pullDiagnostics checks that pulling the diagnostics of uri returns n of
+// them, and that pulling them again reports them as unchanged.
+func (r *runner) pullDiagnostics(t *testing.T, uri span.URI, n int) {
+	params := &protocol.DocumentDiagnosticParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.NewURI(uri)},
+	}
+	report, err := r.server.Diagnostic(r.ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Kind != protocol.DiagnosticFull || len(report.Items) != n {
+		t.Errorf("pulled a %s report of %d diagnostics for %s, want a full report of %d", report.Kind, len(report.Items), uri, n)
+	}
+	params.PreviousResultID = report.ResultID
+	if report, err = r.server.Diagnostic(r.ctx, params); err != nil {
+		t.Fatal(err)
+	}
+	if report.Kind != protocol.DiagnosticUnchanged || report.ResultID != params.PreviousResultID {
+		t.Errorf("pulled a %s report with result ID %q for %s, want it unchanged", report.Kind, report.ResultID, uri)
+	}
+}
+
 func (r *runner) FoldingRange(t *testing.T, spn span.Span) {
 	uri := spn.URI()
 	view := r.server.session.ViewOf(uri)
@@ -126,9 +149,59 @@ func (r *runner) FoldingRange(t *testing.T, spn span.Span) {
 		return
 	}
 	r.foldingRanges(t, "foldingRange-lineFolding", uri, ranges)
+
+	// Test folding ranges with a maximum nesting depth.
+	modified.LineFoldingOnly = false
+	modified.FoldingRangeMaxDepth = 1
+	view.SetOptions(modified)
+	ranges, err = r.server.FoldingRange(r.ctx, &protocol.FoldingRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: protocol.NewURI(uri),
+		},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	r.foldingRanges(t, "foldingRange-maxDepth", uri, ranges)
 	view.SetOptions(original)
 }
 
+func (r *runner) SelectionRange(t *testing.T, spn span.Span) {
+	uri := spn.URI()
+	m, err := r.data.Mapper(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err := m.Location(spn)
+	if err != nil {
+		t.Fatalf("failed for %v: %v", spn, err)
+	}
+	ranges, err := r.server.SelectionRange(r.ctx, &protocol.SelectionRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: protocol.NewURI(uri),
+		},
+		Positions: []protocol.Position{loc.Range.Start},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 {
+		t.Fatalf("got %d selection ranges, expected 1", len(ranges))
+	}
+	got, err := tests.SummarizeSelectionRange(m, &ranges[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := fmt.Sprintf("selectionRange-%d-%d", spn.Start().Line(), spn.Start().Column())
+	want := string(r.data.Golden(tag, uri.Filename(), func() ([]byte, error) {
+		return []byte(got), nil
+	}))
+	if want != got {
+		t.Errorf("%s: selection ranges do not match:\nwant:\n%s\ngot:\n%s", tag, want, got)
+	}
+}
+
 func (r *runner) foldingRanges(t *testing.T, prefix string, uri span.URI, ranges []protocol.FoldingRange) {
 	m, err := r.data.Mapper(uri)
 	if err != nil {
@@ -153,7 +226,7 @@ func (r *runner) foldingRanges(t *testing.T, prefix string, uri span.URI, ranges
 	}
 
 	// Filter by kind.
-	kinds := []protocol.FoldingRangeKind{protocol.Imports, protocol.Comment}
+	kinds := []protocol.FoldingRangeKind{protocol.Imports, protocol.Comment, protocol.Region}
 	for _, kind := range kinds {
 		var kindOnly []protocol.FoldingRange
 		for _, fRng := range ranges {
@@ -449,11 +522,20 @@ func (r *runner) Highlight(t *testing.T, name string, locations []span.Span) {
 	if len(highlights) != len(locations) {
 		t.Fatalf("got %d highlights for %s, expected %d", len(highlights), name, len(locations))
 	}
+	// Highlights are compared in source order, since the first location is
+	// the position of the request rather than the first highlight.
+	want := append([]span.Span{}, locations...)
+	sort.Slice(want, func(i, j int) bool {
+		return span.Compare(want[i], want[j]) < 0
+	})
+	sort.Slice(highlights, func(i, j int) bool {
+		return protocol.CompareRange(highlights[i].Range, highlights[j].Range) < 0
+	})
 	for i := range highlights {
 		if h, err := m.RangeSpan(highlights[i].Range); err != nil {
 			t.Fatalf("failed for %v: %v", highlights[i], err)
-		} else if h != locations[i] {
-			t.Errorf("want %v, got %v\n", locations[i], h)
+		} else if h != want[i] {
+			t.Errorf("want %v, got %v\n", want[i], h)
 		}
 	}
 }
@@ -805,6 +887,37 @@ func (r *runner) Link(t *testing.T, uri span.URI, wantLinks []tests.Link) {
 	}
 }
 
+func (r *runner) CodeLens(t *testing.T, uri span.URI, want []protocol.CodeLens) {
+	got, err := r.server.CodeLens(r.ctx, &protocol.CodeLensParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: protocol.NewURI(uri),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := tests.DiffCodeLens(want, got); diff != "" {
+		t.Errorf("%s: %s", uri, diff)
+	}
+}
+
+func (r *runner) PackageTests(t *testing.T, spn span.Span) {
+	uri := spn.URI()
+	result, err := r.server.NonstandardRequest(r.ctx, "gopls/tests", map[string]interface{}{
+		"uri": protocol.NewURI(uri),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := tests.SummarizePackageTests(result.([]source.TestFunc))
+	want := string(r.data.Golden("packageTests", uri.Filename(), func() ([]byte, error) {
+		return []byte(got), nil
+	}))
+	if want != got {
+		t.Errorf("%s: package tests do not match:\nwant:\n%s\ngot:\n%s", uri, want, got)
+	}
+}
+
 func TestBytesOffset(t *testing.T) {
 	tests := []struct {
 		text string
@@ -0,0 +1,136 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bytes"
+	"context"
+
+	"golang.org/x/tools/internal/lsp/journal"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+	errors "golang.org/x/xerrors"
+)
+
+// nonstandardRequest handles the gopls-specific requests that extend the
+// LSP specification.
+func (s *Server) nonstandardRequest(ctx context.Context, method string, params interface{}) (interface{}, error) {
+	switch method {
+	case "gopls/tests":
+		var p source.PackageTestsParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.packageTests(ctx, &p)
+	case "gopls/coverage":
+		var p source.CoverageParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.coverage(ctx, &p)
+	case "gopls/callGraph":
+		var p source.CallGraphParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.callGraph(ctx, &p)
+	case "gopls/dynamicTypes":
+		var p protocol.TextDocumentPositionParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.dynamicTypes(ctx, &p)
+	case "gopls/modGraph":
+		var p source.ModGraphParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.modGraph(ctx, &p)
+	case "gopls/daemonStats":
+		return source.Stats(s.session.Cache()), nil
+	case "gopls/journal":
+		var p journal.Params
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.startJournal(ctx, &p)
+	case "gopls/bugReport":
+		var buf bytes.Buffer
+		if err := WriteBugReport(&buf, s.session.Cache()); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, notImplemented(method)
+}
+
+func (s *Server) packageTests(ctx context.Context, params *source.PackageTestsParams) ([]source.TestFunc, error) {
+	if params.URI == "" {
+		return nil, errors.Errorf("expected a file URI for gopls/tests")
+	}
+	uri := span.NewURI(params.URI)
+	view := s.session.ViewOf(uri)
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if view.Snapshot().Handle(ctx, f).Identity().Kind != source.Go {
+		return nil, nil
+	}
+	tests, err := source.PackageTests(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	if tests == nil {
+		// Always return a list, so that clients can tell there are no tests.
+		tests = []source.TestFunc{}
+	}
+	return tests, nil
+}
+
+func (s *Server) coverage(ctx context.Context, params *source.CoverageParams) (*source.CoverageRanges, error) {
+	if params.URI == "" {
+		return nil, errors.Errorf("expected a file URI for gopls/coverage")
+	}
+	uri := span.NewURI(params.URI)
+	view := s.session.ViewOf(uri)
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return source.Coverage(ctx, view, f)
+}
+
+func (s *Server) callGraph(ctx context.Context, params *source.CallGraphParams) (*source.CallGraph, error) {
+	if params.URI == "" {
+		return nil, errors.Errorf("expected a file URI for gopls/callGraph")
+	}
+	uri := span.NewURI(params.URI)
+	view := s.session.ViewOf(uri)
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return source.BuildCallGraph(ctx, view, f, params.Function, params.Algorithm)
+}
+
+func (s *Server) dynamicTypes(ctx context.Context, params *protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
+	uri := span.NewURI(params.TextDocument.URI)
+	view := s.session.ViewOf(uri)
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return source.DynamicTypes(ctx, view, f, params.Position)
+}
+
+func (s *Server) modGraph(ctx context.Context, params *source.ModGraphParams) (*source.ModGraph, error) {
+	if params.URI == "" {
+		return nil, errors.Errorf("expected a go.mod URI for gopls/modGraph")
+	}
+	uri := span.NewURI(params.URI)
+	return source.ModuleGraph(ctx, s.session.ViewOf(uri), uri, params.Module)
+}
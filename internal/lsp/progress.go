@@ -0,0 +1,195 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strconv"
+	"sync"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/telemetry/log"
+	errors "golang.org/x/xerrors"
+)
+
+// progressTracker keeps track of the server-initiated work done progress
+// that is in flight, so that it can be cancelled by the client.
+type progressTracker struct {
+	mu         sync.Mutex
+	inProgress map[string]*workDone
+}
+
+// workDone represents a unit of work that reports progress to the client.
+// If the client does not support work done progress, the final message is
+// shown to the user instead.
+type workDone struct {
+	client  protocol.Client
+	token   string // empty if the client does not support work done progress
+	cancel  func()
+	tracker *progressTracker
+}
+
+// startWork begins reporting progress for a new unit of work to the client.
+func (s *Server) startWork(ctx context.Context, title, message string, cancel func()) *workDone {
+	return s.progress.start(ctx, s.client, s.session.Options().WorkDoneProgressSupported, title, message, cancel)
+}
+
+// start begins reporting progress for a new unit of work. If cancel is
+// non-nil, the client is told that the work is cancellable, and cancel is
+// called if the client requests cancellation.
+func (t *progressTracker) start(ctx context.Context, client protocol.Client, supported bool, title, message string, cancel func()) *workDone {
+	wd := &workDone{
+		client:  client,
+		cancel:  cancel,
+		tracker: t,
+	}
+	if client == nil || !supported {
+		return wd
+	}
+	token := strconv.FormatInt(rand.Int63(), 10)
+	if err := client.WorkDoneProgressCreate(ctx, &protocol.WorkDoneProgressCreateParams{
+		Token: token,
+	}); err != nil {
+		log.Error(ctx, "creating work done progress", err)
+		return wd
+	}
+	wd.token = token
+	t.mu.Lock()
+	if t.inProgress == nil {
+		t.inProgress = make(map[string]*workDone)
+	}
+	t.inProgress[token] = wd
+	t.mu.Unlock()
+
+	if err := client.Progress(ctx, &protocol.ProgressParams{
+		Token: token,
+		Value: &protocol.WorkDoneProgressBegin{
+			Kind:        "begin",
+			Title:       title,
+			Message:     message,
+			Cancellable: cancel != nil,
+		},
+	}); err != nil {
+		log.Error(ctx, "starting work done progress", err)
+	}
+	return wd
+}
+
+// cancel cancels the work associated with the given token.
+func (t *progressTracker) cancel(ctx context.Context, token protocol.ProgressToken) error {
+	key, ok := progressTokenKey(token)
+	if !ok {
+		return errors.Errorf("invalid progress token %v", token)
+	}
+	t.mu.Lock()
+	wd, ok := t.inProgress[key]
+	t.mu.Unlock()
+	if !ok {
+		return errors.Errorf("no work in progress for token %q", key)
+	}
+	if wd.cancel == nil {
+		return errors.Errorf("work for token %q is not cancellable", key)
+	}
+	wd.cancel()
+	return nil
+}
+
+// report reports an update on the progress of the work.
+func (wd *workDone) report(ctx context.Context, message string, percentage float64) {
+	if wd.token == "" {
+		return
+	}
+	if err := wd.client.Progress(ctx, &protocol.ProgressParams{
+		Token: wd.token,
+		Value: &protocol.WorkDoneProgressReport{
+			Kind:        "report",
+			Message:     message,
+			Percentage:  percentage,
+			Cancellable: wd.cancel != nil,
+		},
+	}); err != nil {
+		log.Error(ctx, "reporting work done progress", err)
+	}
+}
+
+// end reports that the work is complete, with a final message.
+func (wd *workDone) end(ctx context.Context, message string) {
+	if wd.client == nil {
+		return
+	}
+	if wd.token == "" {
+		if err := wd.client.ShowMessage(ctx, &protocol.ShowMessageParams{
+			Type:    protocol.Info,
+			Message: message,
+		}); err != nil {
+			log.Error(ctx, "showing work done message", err)
+		}
+		return
+	}
+	wd.tracker.mu.Lock()
+	delete(wd.tracker.inProgress, wd.token)
+	wd.tracker.mu.Unlock()
+
+	if err := wd.client.Progress(ctx, &protocol.ProgressParams{
+		Token: wd.token,
+		Value: &protocol.WorkDoneProgressEnd{
+			Kind:    "end",
+			Message: message,
+		},
+	}); err != nil {
+		log.Error(ctx, "ending work done progress", err)
+	}
+}
+
+// progressWriter is an io.Writer that reports each line written to it as
+// progress of the work.
+type progressWriter struct {
+	ctx context.Context
+	wd  *workDone
+
+	mu   sync.Mutex
+	line []byte // the incomplete last line
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.line = append(w.line, p...)
+	for {
+		i := bytes.IndexByte(w.line, '\n')
+		if i < 0 {
+			break
+		}
+		w.wd.report(w.ctx, string(w.line[:i]), 0)
+		w.line = w.line[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush reports the incomplete last line, if any.
+func (w *progressWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.line) > 0 {
+		w.wd.report(w.ctx, string(w.line), 0)
+		w.line = nil
+	}
+}
+
+// progressTokenKey returns the string form of a progress token, which may be
+// either a number or a string.
+func progressTokenKey(token protocol.ProgressToken) (string, bool) {
+	switch token := token.(type) {
+	case string:
+		return token, true
+	case float64:
+		return strconv.FormatInt(int64(token), 10), true
+	case int:
+		return strconv.Itoa(token), true
+	}
+	return "", false
+}
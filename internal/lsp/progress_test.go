@@ -0,0 +1,110 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/cache"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/tests"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/testenv"
+)
+
+const slowTest = `package slow
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSlow(t *testing.T) {
+	if err := ioutil.WriteFile(os.Getenv("SLOW_STARTED"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Minute)
+}
+`
+
+// TestCancelTests checks that cancelling the progress of the test command
+// stops the tests promptly, including the test binary.
+func TestCancelTests(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	dir, err := ioutil.TempDir("", "gopls-progress-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "slow_test.go")
+	for name, content := range map[string]string{
+		"go.mod":       "module example.com/slow\n",
+		"slow_test.go": slowTest,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := tests.Context(t)
+	session := cache.New(nil).NewSession(ctx)
+	options := tests.DefaultOptions()
+	options.WorkDoneProgressSupported = true
+	session.SetOptions(options)
+	session.NewView(ctx, "progress_test", span.FileURI(dir), options)
+	client := newFakeClient()
+	s := &Server{
+		client:      client,
+		session:     session,
+		undelivered: make(map[span.URI][]source.Diagnostic),
+	}
+
+	started := filepath.Join(dir, "started")
+	if _, err := s.executeCommand(ctx, &protocol.ExecuteCommandParams{
+		Command: "test",
+		Arguments: []interface{}{source.TestArgs{
+			URI: protocol.NewURI(span.FileURI(filename)),
+			Env: map[string]string{"SLOW_STARTED": started},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	begin := client.awaitProgress(t, 10*time.Second, func(p *protocol.ProgressParams) bool {
+		_, ok := p.Value.(*protocol.WorkDoneProgressBegin)
+		return ok
+	})
+	// Wait for the test binary to start, so that cancellation has to stop
+	// it rather than just the build.
+	for deadline := time.Now().Add(time.Minute); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("test binary did not start")
+		}
+	}
+	if err := s.WorkDoneProgressCancel(ctx, &protocol.WorkDoneProgressCancelParams{Token: begin.Token}); err != nil {
+		t.Fatal(err)
+	}
+	end := client.awaitProgress(t, 10*time.Second, func(p *protocol.ProgressParams) bool {
+		_, ok := p.Value.(*protocol.WorkDoneProgressEnd)
+		return ok
+	})
+	if msg := end.Value.(*protocol.WorkDoneProgressEnd).Message; !strings.HasSuffix(msg, "cancelled") {
+		t.Errorf("got end message %q, want cancellation", msg)
+	}
+	if err := s.WorkDoneProgressCancel(ctx, &protocol.WorkDoneProgressCancelParams{Token: begin.Token}); err == nil {
+		t.Errorf("cancelling finished work succeeded, want an error")
+	}
+}
@@ -7,6 +7,7 @@ package protocol
 import (
 	"context"
 	"encoding/json"
+	"strings"
 
 	"golang.org/x/tools/internal/jsonrpc2"
 	"golang.org/x/tools/internal/telemetry/log"
@@ -30,7 +31,20 @@ type clientHandler struct {
 
 type serverHandler struct {
 	canceller
-	server Server
+	server   Server
+	workDone *workDoneRequests
+}
+
+func (h serverHandler) Request(ctx context.Context, conn *jsonrpc2.Conn, direction jsonrpc2.Direction, r *jsonrpc2.WireRequest) context.Context {
+	ctx = h.canceller.Request(ctx, conn, direction, r)
+	if direction == jsonrpc2.Receive {
+		ctx = h.workDone.handleHints(ctx, conn, r)
+	}
+	return ctx
+}
+
+func (h serverHandler) Done(ctx context.Context, err error) {
+	h.workDone.done(ctx)
 }
 
 func (canceller) Request(ctx context.Context, conn *jsonrpc2.Conn, direction jsonrpc2.Direction, r *jsonrpc2.WireRequest) context.Context {
@@ -67,7 +81,7 @@ func NewServer(ctx context.Context, stream jsonrpc2.Stream, server Server) (cont
 	conn := jsonrpc2.NewConn(stream)
 	client := &clientDispatcher{Conn: conn}
 	ctx = WithClient(ctx, client)
-	conn.AddHandler(&serverHandler{server: server})
+	conn.AddHandler(&serverHandler{server: server, workDone: &workDoneRequests{}})
 	return ctx, conn, client
 }
 
@@ -79,3 +93,31 @@ func sendParseError(ctx context.Context, req *jsonrpc2.Request, err error) {
 		log.Error(ctx, "", err)
 	}
 }
+
+// deliverNonstandard delivers requests for gopls-specific methods, which are
+// not part of the LSP specification and are prefixed with "gopls/".
+func (h serverHandler) deliverNonstandard(ctx context.Context, r *jsonrpc2.Request) bool {
+	if !strings.HasPrefix(r.Method, "gopls/") || r.IsNotify() {
+		return false
+	}
+	var params interface{}
+	if r.Params != nil {
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+	}
+	resp, err := h.server.NonstandardRequest(ctx, r.Method, params)
+	if err := r.Reply(ctx, resp, err); err != nil {
+		log.Error(ctx, "", err)
+	}
+	return true
+}
+
+func (s *serverDispatcher) NonstandardRequest(ctx context.Context, method string, params interface{}) (interface{}, error) {
+	var result interface{}
+	if err := s.Conn.Call(ctx, method, params, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
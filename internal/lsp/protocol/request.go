@@ -0,0 +1,117 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/internal/jsonrpc2"
+)
+
+type requestKey int
+
+const (
+	softDeadlineKey = requestKey(iota)
+	workDoneTokenKey
+	workDoneRequestsKey
+)
+
+// requestHints are the fields of the parameters of a request that tell how
+// it may be cut short.
+type requestHints struct {
+	// WorkDoneToken is the token with which the client can cancel the
+	// request through window/workDoneProgress/cancel.
+	WorkDoneToken json.RawMessage `json:"workDoneToken"`
+
+	// GoplsDeadline is the number of milliseconds within which the result
+	// of the request is useful to the client, an extension of the LSP.
+	GoplsDeadline float64 `json:"goplsDeadline"`
+}
+
+// workDoneRequests maps the work done tokens of the requests being handled
+// to their IDs, so that the client can cancel them by token.
+type workDoneRequests struct {
+	conn *jsonrpc2.Conn
+
+	mu  sync.Mutex
+	ids map[string]jsonrpc2.ID
+}
+
+// handleHints records the hints of a request received from the client, and
+// returns its context with them.
+func (w *workDoneRequests) handleHints(ctx context.Context, conn *jsonrpc2.Conn, r *jsonrpc2.WireRequest) context.Context {
+	ctx = context.WithValue(ctx, workDoneRequestsKey, w)
+	if r.Params == nil {
+		return ctx
+	}
+	var hints requestHints
+	if err := json.Unmarshal(*r.Params, &hints); err != nil {
+		return ctx
+	}
+	if hints.GoplsDeadline > 0 {
+		deadline := time.Now().Add(time.Duration(hints.GoplsDeadline * float64(time.Millisecond)))
+		ctx = context.WithValue(ctx, softDeadlineKey, deadline)
+	}
+	if r.ID != nil && len(hints.WorkDoneToken) > 0 && string(hints.WorkDoneToken) != "null" {
+		key := string(hints.WorkDoneToken)
+		w.mu.Lock()
+		w.conn = conn
+		if w.ids == nil {
+			w.ids = make(map[string]jsonrpc2.ID)
+		}
+		w.ids[key] = *r.ID
+		w.mu.Unlock()
+		ctx = context.WithValue(ctx, workDoneTokenKey, key)
+	}
+	return ctx
+}
+
+// done forgets the work done token of the request of ctx, once handled.
+func (w *workDoneRequests) done(ctx context.Context) {
+	key, ok := ctx.Value(workDoneTokenKey).(string)
+	if !ok {
+		return
+	}
+	w.mu.Lock()
+	delete(w.ids, key)
+	w.mu.Unlock()
+}
+
+// CancelWorkDone cancels the request being handled whose work done token is
+// token, as the client asks with window/workDoneProgress/cancel, and reports
+// whether there was such a request. ctx must be that of a request from the
+// client.
+func CancelWorkDone(ctx context.Context, token ProgressToken) bool {
+	w, ok := ctx.Value(workDoneRequestsKey).(*workDoneRequests)
+	if !ok {
+		return false
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return false
+	}
+	w.mu.Lock()
+	id, ok := w.ids[string(data)]
+	conn := w.conn
+	w.mu.Unlock()
+	if !ok {
+		return false
+	}
+	conn.Cancel(id)
+	return true
+}
+
+// SoftDeadline returns the time until which the result of the request of
+// ctx is useful to the client, if it told with the goplsDeadline field of
+// the parameters of the request. Unlike the deadline of a context, a soft
+// deadline does not cancel the request: past it, gopls returns the results
+// it has rather than compute better ones.
+func SoftDeadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(softDeadlineKey).(time.Time)
+	return deadline, ok
+}
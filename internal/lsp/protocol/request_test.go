@@ -0,0 +1,58 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protocol
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/internal/jsonrpc2"
+)
+
+func TestRequestHints(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	conn := jsonrpc2.NewConn(jsonrpc2.NewHeaderStream(r, w))
+	requests := &workDoneRequests{}
+
+	params := json.RawMessage(`{"workDoneToken": "t1", "goplsDeadline": 100}`)
+	start := time.Now()
+	ctx := requests.handleHints(context.Background(), conn, &jsonrpc2.WireRequest{
+		Method: "textDocument/completion",
+		Params: &params,
+		ID:     &jsonrpc2.ID{Number: 1},
+	})
+	deadline, ok := SoftDeadline(ctx)
+	if !ok {
+		t.Fatal("no soft deadline")
+	}
+	if d := deadline.Sub(start); d < 100*time.Millisecond || d > time.Second {
+		t.Errorf("soft deadline in %v, want 100ms", d)
+	}
+
+	// The cancellation is requested in the context of another request.
+	other := requests.handleHints(context.Background(), conn, &jsonrpc2.WireRequest{
+		Method: "window/workDoneProgress/cancel",
+	})
+	if _, ok := SoftDeadline(other); ok {
+		t.Errorf("soft deadline for a request without one")
+	}
+	if CancelWorkDone(other, "t2") {
+		t.Errorf("cancelled a request with an unknown token")
+	}
+	if !CancelWorkDone(other, "t1") {
+		t.Errorf("did not cancel the request with token t1")
+	}
+	requests.done(ctx)
+	if CancelWorkDone(other, "t1") {
+		t.Errorf("cancelled a request that was done")
+	}
+	if CancelWorkDone(context.Background(), "t1") {
+		t.Errorf("cancelled a request outside of the context of a request")
+	}
+}
@@ -7,7 +7,10 @@
 package protocol
 
 import (
+	"bytes"
 	"fmt"
+	"sort"
+	"unicode/utf8"
 
 	"golang.org/x/tools/internal/span"
 	errors "golang.org/x/xerrors"
@@ -17,6 +20,16 @@ type ColumnMapper struct {
 	URI       span.URI
 	Converter *span.TokenConverter
 	Content   []byte
+
+	// Encoding is the position encoding negotiated with the client, in
+	// whose code units the characters of protocol positions are counted.
+	// The zero value is UTF16, the default of the protocol.
+	Encoding PositionEncodingKind
+
+	// Clamp converts the positions beyond the end of their line, or of the
+	// file, to the end of the line or of the file, rather than failing.
+	// It suits the callers whose positions may be slightly out of date.
+	Clamp bool
 }
 
 func NewURI(uri span.URI) string {
@@ -35,7 +48,7 @@ func (m *ColumnMapper) Range(s span.Span) (Range, error) {
 	if span.CompareURI(m.URI, s.URI()) != 0 {
 		return Range{}, errors.Errorf("column mapper is for file %q instead of %q", m.URI, s.URI())
 	}
-	s, err := s.WithAll(m.Converter)
+	s, err := s.WithAll(m.converter())
 	if err != nil {
 		return Range{}, err
 	}
@@ -51,7 +64,7 @@ func (m *ColumnMapper) Range(s span.Span) (Range, error) {
 }
 
 func (m *ColumnMapper) Position(p span.Point) (Position, error) {
-	chr, err := span.ToUTF16Column(p, m.Content)
+	chr, err := m.column(p)
 	if err != nil {
 		return Position{}, err
 	}
@@ -74,7 +87,7 @@ func (m *ColumnMapper) RangeSpan(r Range) (span.Span, error) {
 	if err != nil {
 		return span.Span{}, err
 	}
-	return span.New(m.URI, start, end).WithAll(m.Converter)
+	return span.New(m.URI, start, end).WithAll(m.converter())
 }
 
 func (m *ColumnMapper) PointSpan(p Position) (span.Span, error) {
@@ -82,17 +95,261 @@ func (m *ColumnMapper) PointSpan(p Position) (span.Span, error) {
 	if err != nil {
 		return span.Span{}, err
 	}
-	return span.New(m.URI, start, start).WithAll(m.Converter)
+	return span.New(m.URI, start, start).WithAll(m.converter())
 }
 
 func (m *ColumnMapper) Point(p Position) (span.Point, error) {
 	line := int(p.Line) + 1
-	offset, err := m.Converter.ToOffset(line, 1)
+	offset, err := m.converter().ToOffset(line, 1)
 	if err != nil {
 		return span.Point{}, err
 	}
+	if m.Clamp {
+		// The line may be beyond the end of the file, so its number is
+		// that of the offset it was clamped to.
+		l, c, err := m.Converter.ToPosition(offset)
+		if err != nil {
+			return span.Point{}, err
+		}
+		return m.fromColumn(span.NewPoint(l, c, offset), int(p.Character)+1)
+	}
 	lineStart := span.NewPoint(line, 1, offset)
-	return span.FromUTF16Column(lineStart, int(p.Character)+1, m.Content)
+	switch m.Encoding {
+	case UTF8, UTF32:
+		return m.fromColumn(lineStart, int(p.Character)+1)
+	default:
+		return span.FromUTF16Column(lineStart, int(p.Character)+1, m.Content)
+	}
+}
+
+// converter returns the converter of m, which clamps positions if m does.
+func (m *ColumnMapper) converter() *span.TokenConverter {
+	if m.Clamp {
+		return m.Converter.WithClamping()
+	}
+	return m.Converter
+}
+
+// column returns the 1-based column of the point p in the code units of the
+// encoding of m.
+func (m *ColumnMapper) column(p span.Point) (int, error) {
+	switch m.Encoding {
+	case UTF8:
+		// The columns of points are in bytes already.
+		if !p.HasPosition() {
+			return -1, errors.Errorf("point is missing position")
+		}
+		return p.Column(), nil
+	case UTF32:
+		if !p.HasPosition() || !p.HasOffset() {
+			return -1, errors.Errorf("point is missing position or offset")
+		}
+		lineStart := p.Offset() - (p.Column() - 1)
+		if lineStart < 0 || p.Offset() > len(m.Content) {
+			return -1, errors.Errorf("offsets %v-%v outside file contents (%v)", lineStart, p.Offset(), len(m.Content))
+		}
+		return utf8.RuneCount(m.Content[lineStart:p.Offset()]) + 1, nil
+	default:
+		return span.ToUTF16Column(p, m.Content)
+	}
+}
+
+// fromColumn advances the point p at the start of a line by the 1-based
+// column col, in the code units of the encoding of m. A column past the end
+// of the line is the end of the line, and one past the end of the file is
+// an error, unless m clamps positions.
+func (m *ColumnMapper) fromColumn(p span.Point, col int) (span.Point, error) {
+	if !p.HasOffset() {
+		return span.Point{}, errors.Errorf("point is missing offset")
+	}
+	offset := p.Offset()
+	for chr := 1; chr < col; {
+		if offset >= len(m.Content) {
+			if m.Clamp {
+				break
+			}
+			return span.Point{}, errors.Errorf("column %v goes beyond the content", col)
+		}
+		r, w := utf8.DecodeRune(m.Content[offset:])
+		if r == '\n' {
+			break
+		}
+		n := m.units(r, w)
+		if chr+n > col {
+			// Do not advance past a rune that the column is in the
+			// middle of.
+			break
+		}
+		chr += n
+		offset += w
+	}
+	return span.NewPoint(p.Line(), p.Column()+offset-p.Offset(), offset), nil
+}
+
+// units returns the number of code units of the encoding of m in the rune r
+// of w bytes.
+func (m *ColumnMapper) units(r rune, w int) int {
+	switch m.Encoding {
+	case UTF8:
+		return w
+	case UTF32:
+		return 1
+	default:
+		if r >= 0x10000 {
+			return 2
+		}
+		return 1
+	}
+}
+
+// ApplyChanges returns a mapper for the content of m with the incremental
+// changes applied in order. The line table of the converter of m, which
+// must have been created by span.NewContentConverter, is adjusted by each
+// change rather than computed again from the new content.
+func (m *ColumnMapper) ApplyChanges(changes []TextDocumentContentChangeEvent) (*ColumnMapper, error) {
+	for _, change := range changes {
+		if change.Range == nil {
+			return nil, errors.Errorf("content change of %s has no range", m.URI)
+		}
+		spn, err := m.RangeSpan(*change.Range)
+		if err != nil {
+			return nil, err
+		}
+		if !spn.HasOffset() {
+			return nil, errors.Errorf("invalid range for content change")
+		}
+		start, end := spn.Start().Offset(), spn.End().Offset()
+		if end < start {
+			return nil, errors.Errorf("invalid range for content change")
+		}
+		converter, err := m.Converter.Edit(start, end, []byte(change.Text))
+		if err != nil {
+			return nil, err
+		}
+		content := make([]byte, 0, len(m.Content)-(end-start)+len(change.Text))
+		content = append(content, m.Content[:start]...)
+		content = append(content, change.Text...)
+		content = append(content, m.Content[end:]...)
+		m = &ColumnMapper{
+			URI:       m.URI,
+			Converter: converter,
+			Content:   content,
+			Encoding:  m.Encoding,
+		}
+	}
+	return m, nil
+}
+
+// RangesToUTF16 converts the ranges of the file of m to protocol ranges, in
+// the same order, in the encoding of m, which is UTF-16 unless the client
+// negotiated another. Rather than converting each point on its own, which scans
+// its line from the start, it sorts the points and converts them in a single
+// pass over the content, for the callers that convert many of them at once.
+func (m *ColumnMapper) RangesToUTF16(ranges []span.Range) ([]Range, error) {
+	// The start of range i is point 2*i and its end point 2*i+1.
+	offsets := make([]int, 2*len(ranges))
+	for i, r := range ranges {
+		f := r.FileSet.File(r.Start)
+		if f == nil {
+			return nil, errors.Errorf("range %d is not in a file", i)
+		}
+		end := r.End
+		if !end.IsValid() {
+			end = r.Start
+		}
+		if end < r.Start || int(end)-f.Base() > f.Size() {
+			return nil, errors.Errorf("invalid range %d of %s", i, f.Name())
+		}
+		offsets[2*i] = f.Offset(r.Start)
+		offsets[2*i+1] = int(end) - f.Base()
+	}
+	order := make([]int, len(offsets))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return offsets[order[i]] < offsets[order[j]]
+	})
+
+	positions := make([]Position, len(offsets))
+	var line, chr, offset int
+	for _, i := range order {
+		target := offsets[i]
+		if target > len(m.Content) {
+			return nil, errors.Errorf("offset %v is past the end of the content (%v)", target, len(m.Content))
+		}
+		for offset < target {
+			r, w := utf8.DecodeRune(m.Content[offset:])
+			if offset+w > target {
+				// The point is in the middle of a rune.
+				break
+			}
+			offset += w
+			if r == '\n' {
+				line++
+				chr = 0
+			} else {
+				chr += m.units(r, w)
+			}
+		}
+		positions[i] = Position{Line: float64(line), Character: float64(chr)}
+	}
+
+	result := make([]Range, len(ranges))
+	for i := range result {
+		result[i] = Range{Start: positions[2*i], End: positions[2*i+1]}
+	}
+	return result, nil
+}
+
+// PositionsToOffsets converts the protocol positions of the file of m to
+// byte offsets into its content, in the same order, in a single pass over
+// the content. As for Point, a character past the end of a line is the end
+// of the line.
+func (m *ColumnMapper) PositionsToOffsets(positions []Position) ([]int, error) {
+	order := make([]int, len(positions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return ComparePosition(positions[order[i]], positions[order[j]]) < 0
+	})
+
+	offsets := make([]int, len(positions))
+	// lineStart is the offset of the start of line, and chr and offset are
+	// the last position converted on it.
+	var line, lineStart, chr, offset int
+	for _, i := range order {
+		p := positions[i]
+		if p.Line < 0 || p.Character < 0 {
+			return nil, errors.Errorf("invalid position %v:%v", p.Line, p.Character)
+		}
+		for line < int(p.Line) {
+			n := bytes.IndexByte(m.Content[lineStart:], '\n')
+			if n < 0 {
+				return nil, errors.Errorf("line %v is beyond the end of the file", p.Line+1)
+			}
+			line++
+			lineStart += n + 1
+			chr, offset = 0, lineStart
+		}
+		for chr < int(p.Character) && offset < len(m.Content) {
+			r, w := utf8.DecodeRune(m.Content[offset:])
+			if r == '\n' {
+				break
+			}
+			n := m.units(r, w)
+			if chr+n > int(p.Character) {
+				// Do not advance past a rune that the character is in
+				// the middle of.
+				break
+			}
+			chr += n
+			offset += w
+		}
+		offsets[i] = offset
+	}
+	return offsets, nil
 }
 
 func IsPoint(r Range) bool {
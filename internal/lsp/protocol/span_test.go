@@ -0,0 +1,202 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protocol_test
+
+import (
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+)
+
+const content = "package a\n\n// ½ 𝄞 x\nvar s = \"𝄞𝄞\" // y\n"
+
+var encodings = []protocol.PositionEncodingKind{protocol.UTF8, protocol.UTF16, protocol.UTF32}
+
+func newMapper(fset *token.FileSet, encoding protocol.PositionEncodingKind) (*protocol.ColumnMapper, *token.File) {
+	f := fset.AddFile("a.go", -1, len(content))
+	f.SetLinesForContent([]byte(content))
+	return &protocol.ColumnMapper{
+		URI:       span.FileURI("a.go"),
+		Converter: span.NewTokenConverter(fset, f),
+		Content:   []byte(content),
+		Encoding:  encoding,
+	}, f
+}
+
+func TestPositionEncodings(t *testing.T) {
+	// The x of the comment is after a rune of 2 bytes and one of 4 bytes,
+	// which is 2 UTF-16 code units.
+	offset := strings.Index(content, "x")
+	for _, tt := range []struct {
+		encoding protocol.PositionEncodingKind
+		want     protocol.Position
+	}{
+		{protocol.UTF8, protocol.Position{Line: 2, Character: 11}},
+		{protocol.UTF16, protocol.Position{Line: 2, Character: 8}},
+		{protocol.UTF32, protocol.Position{Line: 2, Character: 7}},
+	} {
+		fset := token.NewFileSet()
+		m, f := newMapper(fset, tt.encoding)
+		spn, err := span.NewRange(fset, f.Pos(offset), f.Pos(offset)).Span()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := m.Position(spn.Start())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: Position of x = %v, want %v", tt.encoding, got, tt.want)
+		}
+		p, err := m.Point(tt.want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Offset() != offset {
+			t.Errorf("%s: Point(%v) is at offset %v, want %v", tt.encoding, tt.want, p.Offset(), offset)
+		}
+	}
+}
+
+func TestRangesToUTF16(t *testing.T) {
+	for _, encoding := range encodings {
+		testRangesToUTF16(t, encoding)
+	}
+}
+
+func testRangesToUTF16(t *testing.T, encoding protocol.PositionEncodingKind) {
+	fset := token.NewFileSet()
+	m, f := newMapper(fset, encoding)
+	// Every pair of rune boundaries, in reverse, so that they are sorted.
+	var ranges []span.Range
+	for end := len(content) - 1; end >= 0; end-- {
+		for start := end; start >= 0; start-- {
+			if !isRuneStart(start) || !isRuneStart(end) {
+				continue
+			}
+			ranges = append(ranges, span.NewRange(fset, f.Pos(start), f.Pos(end)))
+		}
+	}
+	got, err := m.RangesToUTF16(ranges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range ranges {
+		spn, err := r.Span()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := m.Range(spn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[i] != want {
+			t.Errorf("%s: RangesToUTF16 of %v = %v, want %v", encoding, spn, got[i], want)
+		}
+	}
+}
+
+func TestPositionsToOffsets(t *testing.T) {
+	for _, encoding := range encodings {
+		testPositionsToOffsets(t, encoding)
+	}
+}
+
+func testPositionsToOffsets(t *testing.T, encoding protocol.PositionEncodingKind) {
+	fset := token.NewFileSet()
+	m, _ := newMapper(fset, encoding)
+	// The empty last line, then every character of the other lines and
+	// past their ends, in reverse, so that they are sorted.
+	positions := []protocol.Position{{Line: 4}}
+	for line := 3; line >= 0; line-- {
+		for chr := 20; chr >= 0; chr-- {
+			positions = append(positions, protocol.Position{Line: float64(line), Character: float64(chr)})
+		}
+	}
+	got, err := m.PositionsToOffsets(positions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []int
+	for _, p := range positions {
+		spn, err := m.PointSpan(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, spn.Start().Offset())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: PositionsToOffsets(%v) = %v, want %v", encoding, positions, got, want)
+	}
+
+	if _, err := m.PositionsToOffsets([]protocol.Position{{Line: 5}}); err == nil {
+		t.Errorf("%s: PositionsToOffsets of a line beyond the end of the file succeeded", encoding)
+	}
+}
+
+func isRuneStart(offset int) bool {
+	return offset == len(content) || content[offset]&0xC0 != 0x80
+}
+
+func TestApplyChanges(t *testing.T) {
+	m := &protocol.ColumnMapper{
+		URI:       span.FileURI("a.go"),
+		Converter: span.NewContentConverter("a.go", []byte(content)),
+		Content:   []byte(content),
+	}
+	got, err := m.ApplyChanges([]protocol.TextDocumentContentChangeEvent{
+		// Replace the 𝄞 of the comment, which is 2 UTF-16 code units.
+		{Range: &protocol.Range{
+			Start: protocol.Position{Line: 2, Character: 5},
+			End:   protocol.Position{Line: 2, Character: 7},
+		}, Text: "y\nz"},
+		{Range: &protocol.Range{
+			Start: protocol.Position{Line: 0, Character: 0},
+			End:   protocol.Position{Line: 1, Character: 0},
+		}, Text: ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "\n// ½ y\nz x\nvar s = \"𝄞𝄞\" // y\n"
+	if string(got.Content) != want {
+		t.Fatalf("ApplyChanges = %q, want %q", got.Content, want)
+	}
+	// The line table of the converter is that of the new content.
+	fresh := span.NewContentConverter("a.go", []byte(want))
+	for offset := 0; offset <= len(want); offset++ {
+		gotLine, gotCol, _ := got.Converter.ToPosition(offset)
+		wantLine, wantCol, _ := fresh.ToPosition(offset)
+		if gotLine != wantLine || gotCol != wantCol {
+			t.Errorf("offset %v is at %v:%v, want %v:%v", offset, gotLine, gotCol, wantLine, wantCol)
+		}
+	}
+}
+
+func TestClamp(t *testing.T) {
+	for _, tt := range []struct {
+		p    protocol.Position
+		want int
+	}{
+		{protocol.Position{Line: 0, Character: 40}, strings.Index(content, "\n")},
+		{protocol.Position{Line: 3, Character: 40}, len(content) - 1},
+		{protocol.Position{Line: 9, Character: 3}, len(content)},
+	} {
+		fset := token.NewFileSet()
+		m, _ := newMapper(fset, protocol.UTF16)
+		m.Clamp = true
+		spn, err := m.PointSpan(tt.p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := spn.Start().Offset(); got != tt.want {
+			t.Errorf("clamped PointSpan(%v) is at offset %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
@@ -692,6 +692,13 @@ type TextDocumentClientCapabilities struct {
 	 * Capabilities specific to `textDocument/publishDiagnostics`.
 	 */
 	PublishDiagnostics *PublishDiagnosticsClientCapabilities `json:"publishDiagnostics,omitempty"`
+
+	/*Diagnostic defined:
+	 * Capabilities specific to the diagnostic pull model.
+	 *
+	 * @since 3.17.0
+	 */
+	Diagnostic *DiagnosticClientCapabilities `json:"diagnostic,omitempty"`
 }
 
 /*InnerClientCapabilities defined:
@@ -781,6 +788,36 @@ type ClientCapabilities struct {
 	 */
 	Window interface{} `json:"window,omitempty"`
 
+	/*General defined:
+	 * General client capabilities.
+	 *
+	 * @since 3.16.0
+	 */
+	General struct {
+
+		/*PositionEncodings defined:
+		 * The position encodings supported by the client. Client and server
+		 * have to agree on the same position encoding to ensure that offsets
+		 * (e.g. character position in a line) are interpreted the same on both
+		 * sides.
+		 *
+		 * To keep the protocol backwards compatible the following applies: if
+		 * the value 'utf-16' is missing from the array of position encodings
+		 * servers can assume that the client supports UTF-16. UTF-16 is
+		 * therefore a mandatory encoding.
+		 *
+		 * If omitted it defaults to ['utf-16'].
+		 *
+		 * Implementation considerations: since the conversion from one encoding
+		 * into another requires the content of the file / line the conversion
+		 * is best done where the file is read which is usually on the server
+		 * side.
+		 *
+		 * @since 3.17.0
+		 */
+		PositionEncodings []PositionEncodingKind `json:"positionEncodings,omitempty"`
+	} `json:"general,omitempty"`
+
 	/*Experimental defined:
 	 * Experimental client capabilities.
 	 */
@@ -972,6 +1009,19 @@ type InnerServerCapabilities struct {
 // ServerCapabilities is
 type ServerCapabilities struct {
 
+	/*PositionEncoding defined:
+	 * The position encoding the server picked from the encodings offered
+	 * by the client via the client capability `general.positionEncodings`.
+	 *
+	 * If the client didn't provide any position encodings the only valid
+	 * value that a server can return is 'utf-16'.
+	 *
+	 * If omitted it defaults to 'utf-16'.
+	 *
+	 * @since 3.17.0
+	 */
+	PositionEncoding PositionEncodingKind `json:"positionEncoding,omitempty"`
+
 	/*TextDocumentSync defined:
 	 * Defines how text documents are synced. Is either a detailed structure defining each notification or
 	 * for backwards compatibility the TextDocumentSyncKind number.
@@ -1087,6 +1137,20 @@ type ServerCapabilities struct {
 	 */
 	SelectionRangeProvider bool `json:"selectionRangeProvider,omitempty"` // boolean | SelectionRangeOptions | SelectionRangeRegistrationOptions
 
+	/*DiagnosticProvider defined:
+	 * The server has support for pull model diagnostics.
+	 *
+	 * @since 3.17.0
+	 */
+	DiagnosticProvider *DiagnosticOptions `json:"diagnosticProvider,omitempty"` // DiagnosticOptions | DiagnosticRegistrationOptions
+
+	/*InlayHintProvider defined:
+	 * The server provides inlay hints.
+	 *
+	 * @since 3.17.0
+	 */
+	InlayHintProvider bool `json:"inlayHintProvider,omitempty"` // boolean | InlayHintOptions | InlayHintRegistrationOptions
+
 	/*ExecuteCommandProvider defined:
 	 * The server provides execute command support.
 	 */
@@ -1628,6 +1692,163 @@ type PublishDiagnosticsClientCapabilities struct {
 	} `json:"tagSupport,omitempty"`
 }
 
+/*DiagnosticClientCapabilities defined:
+ * Client capabilities specific to diagnostic pull requests.
+ *
+ * @since 3.17.0
+ */
+type DiagnosticClientCapabilities struct {
+
+	/*DynamicRegistration defined:
+	 * Whether implementation supports dynamic registration.
+	 */
+	DynamicRegistration bool `json:"dynamicRegistration,omitempty"`
+
+	/*RelatedDocumentSupport defined:
+	 * Whether the clients supports related documents for document diagnostic pulls.
+	 */
+	RelatedDocumentSupport bool `json:"relatedDocumentSupport,omitempty"`
+}
+
+/*DiagnosticOptions defined:
+ * Diagnostic options.
+ *
+ * @since 3.17.0
+ */
+type DiagnosticOptions struct {
+
+	/*Identifier defined:
+	 * An optional identifier under which the diagnostics are
+	 * managed by the client.
+	 */
+	Identifier string `json:"identifier,omitempty"`
+
+	/*InterFileDependencies defined:
+	 * Whether the language has inter file dependencies meaning that
+	 * editing code in one file can result in a different diagnostic
+	 * set in another file.
+	 */
+	InterFileDependencies bool `json:"interFileDependencies"`
+
+	/*WorkspaceDiagnostics defined:
+	 * The server provides support for workspace diagnostics as well.
+	 */
+	WorkspaceDiagnostics bool `json:"workspaceDiagnostics"`
+	WorkDoneProgressOptions
+}
+
+/*DocumentDiagnosticParams defined:
+ * Parameters of the document diagnostic request.
+ *
+ * @since 3.17.0
+ */
+type DocumentDiagnosticParams struct {
+
+	/*TextDocument defined:
+	 * The text document.
+	 */
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+
+	/*Identifier defined:
+	 * The additional identifier  provided during registration.
+	 */
+	Identifier string `json:"identifier,omitempty"`
+
+	/*PreviousResultID defined:
+	 * The result id of a previous response if provided.
+	 */
+	PreviousResultID string `json:"previousResultId,omitempty"`
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+/*DocumentDiagnosticReport defined:
+ * The result of a document diagnostic pull request. A report can
+ * either be a full report containing all diagnostics for the
+ * requested document or an unchanged report indicating that nothing
+ * has changed in terms of diagnostics in comparison to the last
+ * pull request.
+ *
+ * @since 3.17.0
+ */
+type DocumentDiagnosticReport struct {
+
+	/*Kind defined:
+	 * A full document diagnostic report, or an unchanged one.
+	 */
+	Kind DocumentDiagnosticReportKind `json:"kind"`
+
+	/*ResultID defined:
+	 * An optional result id. If provided it will
+	 * be sent on the next diagnostic request for the
+	 * same document.
+	 */
+	ResultID string `json:"resultId,omitempty"`
+
+	/*Items defined:
+	 * The actual items. Only set for full reports.
+	 */
+	Items []Diagnostic `json:"items,omitempty"`
+}
+
+/*InlayHintParams defined:
+ * A parameter literal used in inlay hint requests.
+ *
+ * @since 3.17.0
+ */
+type InlayHintParams struct {
+
+	/*TextDocument defined:
+	 * The text document.
+	 */
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+
+	/*Range defined:
+	 * The document range for which inlay hints should be computed.
+	 */
+	Range Range `json:"range"`
+	WorkDoneProgressParams
+}
+
+/*InlayHint defined:
+ * Inlay hint information.
+ *
+ * @since 3.17.0
+ */
+type InlayHint struct {
+
+	/*Position defined:
+	 * The position of this hint.
+	 */
+	Position Position `json:"position"`
+
+	/*Label defined:
+	 * The label of this hint.
+	 */
+	Label string `json:"label"` // string | InlayHintLabelPart[]
+
+	/*Kind defined:
+	 * The kind of this hint. Can be omitted in which case the client
+	 * should fall back to a reasonable default.
+	 */
+	Kind InlayHintKind `json:"kind,omitempty"`
+
+	/*Tooltip defined:
+	 * The tooltip text when you hover over this item.
+	 */
+	Tooltip string `json:"tooltip,omitempty"` // string | MarkupContent
+
+	/*PaddingLeft defined:
+	 * Render padding before the hint.
+	 */
+	PaddingLeft bool `json:"paddingLeft,omitempty"`
+
+	/*PaddingRight defined:
+	 * Render padding after the hint.
+	 */
+	PaddingRight bool `json:"paddingRight,omitempty"`
+}
+
 /*PublishDiagnosticsParams defined:
  * The publish diagnostic notification's parameters.
  */
@@ -3921,6 +4142,124 @@ type ProgressParams struct {
 	Value interface{} `json:"value"`
 }
 
+/*WorkDoneProgressCreateParams defined:
+ * The parameters of a `window/workDoneProgress/create` request.
+ */
+type WorkDoneProgressCreateParams struct {
+
+	/*Token defined:
+	 * The token to be used to report progress.
+	 */
+	Token ProgressToken `json:"token"`
+}
+
+/*WorkDoneProgressCancelParams defined:
+ * The parameters of a `window/workDoneProgress/cancel` notification.
+ */
+type WorkDoneProgressCancelParams struct {
+
+	/*Token defined:
+	 * The token to be used to report progress.
+	 */
+	Token ProgressToken `json:"token"`
+}
+
+/*WorkDoneProgressBegin defined:
+ * The value of a `$/progress` notification that starts progress reporting.
+ */
+type WorkDoneProgressBegin struct {
+
+	// Kind is
+	Kind string `json:"kind"` // 'begin'
+
+	/*Title defined:
+	 * Mandatory title of the progress operation. Used to briefly inform about
+	 * the kind of operation being performed.
+	 *
+	 * Examples: "Indexing" or "Linking dependencies".
+	 */
+	Title string `json:"title"`
+
+	/*Cancellable defined:
+	 * Controls if a cancel button should show to allow the user to cancel the
+	 * long running operation. Clients that don't support cancellation are allowed
+	 * to ignore the setting.
+	 */
+	Cancellable bool `json:"cancellable,omitempty"`
+
+	/*Message defined:
+	 * Optional, more detailed associated progress message. Contains
+	 * complementary information to the `title`.
+	 *
+	 * Examples: "3/25 files", "project/src/module2", "node_modules/some_dep".
+	 * If unset, the previous progress message (if any) is still valid.
+	 */
+	Message string `json:"message,omitempty"`
+
+	/*Percentage defined:
+	 * Optional progress percentage to display (value 100 is considered 100%).
+	 * If not provided infinite progress is assumed and clients are allowed
+	 * to ignore the `percentage` value in subsequent in report notifications.
+	 *
+	 * The value should be steadily rising. Clients are free to ignore values
+	 * that are not following this rule.
+	 */
+	Percentage float64 `json:"percentage,omitempty"`
+}
+
+/*WorkDoneProgressReport defined:
+ * The value of a `$/progress` notification that reports ongoing progress.
+ */
+type WorkDoneProgressReport struct {
+
+	// Kind is
+	Kind string `json:"kind"` // 'report'
+
+	/*Cancellable defined:
+	 * Controls enablement state of a cancel button. This property is only valid if a cancel
+	 * button got requested in the `WorkDoneProgressStart` payload.
+	 *
+	 * Clients that don't support cancellation or don't support control the button's
+	 * enablement state are allowed to ignore the setting.
+	 */
+	Cancellable bool `json:"cancellable,omitempty"`
+
+	/*Message defined:
+	 * Optional, more detailed associated progress message. Contains
+	 * complementary information to the `title`.
+	 *
+	 * Examples: "3/25 files", "project/src/module2", "node_modules/some_dep".
+	 * If unset, the previous progress message (if any) is still valid.
+	 */
+	Message string `json:"message,omitempty"`
+
+	/*Percentage defined:
+	 * Optional progress percentage to display (value 100 is considered 100%).
+	 * If not provided infinite progress is assumed and clients are allowed
+	 * to ignore the `percentage` value in subsequent in report notifications.
+	 *
+	 * The value should be steadily rising. Clients are free to ignore values
+	 * that are not following this rule.
+	 */
+	Percentage float64 `json:"percentage,omitempty"`
+}
+
+/*WorkDoneProgressEnd defined:
+ * The value of a `$/progress` notification that signals the end of progress
+ * reporting.
+ */
+type WorkDoneProgressEnd struct {
+
+	// Kind is
+	Kind string `json:"kind"` // 'end'
+
+	/*Message defined:
+	 * Optional, a final message indicating to for example indicate the outcome
+	 * of the operation.
+	 */
+	Message string `json:"message,omitempty"`
+}
+
 // SetTraceParams is
 type SetTraceParams struct {
 
@@ -3945,6 +4284,15 @@ type Tracer struct {
 // FoldingRangeKind defines constants
 type FoldingRangeKind string
 
+// DocumentDiagnosticReportKind defines constants
+type DocumentDiagnosticReportKind string
+
+// InlayHintKind defines constants
+type InlayHintKind float64
+
+// PositionEncodingKind defines constants
+type PositionEncodingKind string
+
 // ResourceOperationKind defines constants
 type ResourceOperationKind string
 
@@ -4037,6 +4385,50 @@ const (
 	 */
 	Region FoldingRangeKind = "region"
 
+	/*DiagnosticFull defined:
+	 * A diagnostic report with a full
+	 * set of problems.
+	 */
+	DiagnosticFull DocumentDiagnosticReportKind = "full"
+
+	/*DiagnosticUnchanged defined:
+	 * A report indicating that the last
+	 * returned report is still accurate.
+	 */
+	DiagnosticUnchanged DocumentDiagnosticReportKind = "unchanged"
+
+	/*TypeHint defined:
+	 * An inlay hint that for a type annotation.
+	 */
+	TypeHint InlayHintKind = 1
+
+	/*ParameterHint defined:
+	 * An inlay hint that is for a parameter.
+	 */
+	ParameterHint InlayHintKind = 2
+
+	/*UTF8 defined:
+	 * Character offsets count UTF-8 code units (e.g bytes).
+	 */
+	UTF8 PositionEncodingKind = "utf-8"
+
+	/*UTF16 defined:
+	 * Character offsets count UTF-16 code units.
+	 *
+	 * This is the default and must always be supported
+	 * by servers
+	 */
+	UTF16 PositionEncodingKind = "utf-16"
+
+	/*UTF32 defined:
+	 * Character offsets count UTF-32 code units.
+	 *
+	 * Implementation note: these are the same as Unicode code points,
+	 * so this `PositionEncodingKind` may also be used for an
+	 * encoding-agnostic representation of character offsets.
+	 */
+	UTF32 PositionEncodingKind = "utf-32"
+
 	/*Create defined:
 	 * Supports creating new files and folders.
 	 */
@@ -23,6 +23,7 @@ type Server interface {
 	WillSave(context.Context, *WillSaveTextDocumentParams) error
 	DidChangeWatchedFiles(context.Context, *DidChangeWatchedFilesParams) error
 	Progress(context.Context, *ProgressParams) error
+	WorkDoneProgressCancel(context.Context, *WorkDoneProgressCancelParams) error
 	SetTraceNotification(context.Context, *SetTraceParams) error
 	LogTraceNotification(context.Context, *LogTraceParams) error
 	Implementation(context.Context, *ImplementationParams) ([]Location, error)
@@ -54,7 +55,10 @@ type Server interface {
 	OnTypeFormatting(context.Context, *DocumentOnTypeFormattingParams) ([]TextEdit, error)
 	Rename(context.Context, *RenameParams) (*WorkspaceEdit, error)
 	PrepareRename(context.Context, *PrepareRenameParams) (*Range, error)
+	Diagnostic(context.Context, *DocumentDiagnosticParams) (*DocumentDiagnosticReport, error)
+	InlayHint(context.Context, *InlayHintParams) ([]InlayHint, error)
 	ExecuteCommand(context.Context, *ExecuteCommandParams) (interface{}, error)
+	NonstandardRequest(ctx context.Context, method string, params interface{}) (interface{}, error)
 }
 
 func (h serverHandler) Deliver(ctx context.Context, r *jsonrpc2.Request, delivered bool) bool {
@@ -172,6 +176,16 @@ func (h serverHandler) Deliver(ctx context.Context, r *jsonrpc2.Request, deliver
 			log.Error(ctx, "", err)
 		}
 		return true
+	case "window/workDoneProgress/cancel": // notif
+		var params WorkDoneProgressCancelParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		if err := h.server.WorkDoneProgressCancel(ctx, &params); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
 	case "$/setTraceNotification": // notif
 		var params SetTraceParams
 		if err := json.Unmarshal(*r.Params, &params); err != nil {
@@ -510,6 +524,28 @@ func (h serverHandler) Deliver(ctx context.Context, r *jsonrpc2.Request, deliver
 			log.Error(ctx, "", err)
 		}
 		return true
+	case "textDocument/diagnostic": // req
+		var params DocumentDiagnosticParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		resp, err := h.server.Diagnostic(ctx, &params)
+		if err := r.Reply(ctx, resp, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
+	case "textDocument/inlayHint": // req
+		var params InlayHintParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			sendParseError(ctx, r, err)
+			return true
+		}
+		resp, err := h.server.InlayHint(ctx, &params)
+		if err := r.Reply(ctx, resp, err); err != nil {
+			log.Error(ctx, "", err)
+		}
+		return true
 	case "workspace/executeCommand": // req
 		var params ExecuteCommandParams
 		if err := json.Unmarshal(*r.Params, &params); err != nil {
@@ -523,7 +559,7 @@ func (h serverHandler) Deliver(ctx context.Context, r *jsonrpc2.Request, deliver
 		return true
 
 	default:
-		return false
+		return h.deliverNonstandard(ctx, r)
 	}
 }
 
@@ -575,6 +611,10 @@ func (s *serverDispatcher) Progress(ctx context.Context, params *ProgressParams)
 	return s.Conn.Notify(ctx, "$/progress", params)
 }
 
+func (s *serverDispatcher) WorkDoneProgressCancel(ctx context.Context, params *WorkDoneProgressCancelParams) error {
+	return s.Conn.Notify(ctx, "window/workDoneProgress/cancel", params)
+}
+
 func (s *serverDispatcher) SetTraceNotification(ctx context.Context, params *SetTraceParams) error {
 	return s.Conn.Notify(ctx, "$/setTraceNotification", params)
 }
@@ -810,6 +850,22 @@ func (s *serverDispatcher) PrepareRename(ctx context.Context, params *PrepareRen
 	return &result, nil
 }
 
+func (s *serverDispatcher) Diagnostic(ctx context.Context, params *DocumentDiagnosticParams) (*DocumentDiagnosticReport, error) {
+	var result DocumentDiagnosticReport
+	if err := s.Conn.Call(ctx, "textDocument/diagnostic", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *serverDispatcher) InlayHint(ctx context.Context, params *InlayHintParams) ([]InlayHint, error) {
+	var result []InlayHint
+	if err := s.Conn.Call(ctx, "textDocument/inlayHint", params, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 func (s *serverDispatcher) ExecuteCommand(ctx context.Context, params *ExecuteCommandParams) (interface{}, error) {
 	var result interface{}
 	if err := s.Conn.Call(ctx, "workspace/executeCommand", params, &result); err != nil {
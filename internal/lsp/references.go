@@ -52,6 +52,13 @@ func (s *Server) references(ctx context.Context, params *protocol.ReferenceParam
 			Range: refRange,
 		})
 	}
+	// The //go:linkname directives that refer to the identifier are
+	// references too, although they are not type-checked.
+	linknames, err := ident.Linknames(ctx)
+	if err != nil {
+		log.Error(ctx, "no linknames", err, tag.Of("Identifier", ident.Name))
+	}
+	locations = append(locations, linknames...)
 	// The declaration of this identifier may not be in the
 	// scope that we search for references, so make sure
 	// it is added to the beginning of the list if IncludeDeclaration
@@ -6,10 +6,14 @@ package lsp
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
 
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/source"
 	"golang.org/x/tools/internal/span"
+	errors "golang.org/x/xerrors"
 )
 
 func (s *Server) rename(ctx context.Context, params *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
@@ -19,13 +23,32 @@ func (s *Server) rename(ctx context.Context, params *protocol.RenameParams) (*pr
 	if err != nil {
 		return nil, err
 	}
-	ident, err := source.Identifier(ctx, view, f, params.Position)
+	// The name of a package clause renames the package in all of its
+	// files and in those of its importers.
+	edits, err := source.RenamePackage(ctx, view, f, params.Position, params.NewName)
 	if err != nil {
 		return nil, err
 	}
-	edits, err := ident.Rename(ctx, view, params.NewName)
-	if err != nil {
-		return nil, err
+	// Labels are renamed within their function, which need not type-check.
+	if edits == nil {
+		edits, err = source.RenameLabel(ctx, view, f, params.Position, params.NewName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if edits == nil {
+		ident, err := source.Identifier(ctx, view, f, params.Position)
+		if err != nil {
+			return nil, err
+		}
+		edits, err = ident.Rename(ctx, view, params.NewName)
+		var conflictErr *source.RenameConflictError
+		if errors.As(err, &conflictErr) && view.Options().ConfirmRenameConflicts && s.confirmRename(ctx, ident.Name, params.NewName, conflictErr) {
+			edits, err = conflictErr.Edits, nil
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
 	changes := make(map[string][]protocol.TextEdit)
 	for uri, e := range edits {
@@ -44,10 +67,36 @@ func (s *Server) prepareRename(ctx context.Context, params *protocol.PrepareRena
 	}
 	// Do not return errors here, as it adds clutter.
 	// Returning a nil result means there is not a valid rename.
-	item, err := source.PrepareRename(ctx, view, f, params.Position)
+	item, err := source.PrepareRenamePackage(ctx, view, f, params.Position)
+	if item == nil && err == nil {
+		item, err = source.PrepareRenameLabel(ctx, view, f, params.Position)
+	}
+	if item == nil && err == nil {
+		item, err = source.PrepareRename(ctx, view, f, params.Position)
+	}
 	if err != nil {
 		return nil, nil
 	}
 	// TODO(suzmue): return ident.Name as the placeholder text.
 	return &item.Range, nil
 }
+
+// renameAnyway is the action of the prompt of a renaming with conflicts that
+// applies it regardless.
+const renameAnyway = "Rename anyway"
+
+// confirmRename lists the conflicts of the renaming of from to to, and
+// reports whether the user chose to rename anyway.
+func (s *Server) confirmRename(ctx context.Context, from, to string, conflictErr *source.RenameConflictError) bool {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "Renaming %s to %s would change the meaning of the program:", from, to)
+	for _, c := range conflictErr.Conflicts {
+		fmt.Fprintf(&msg, "\n%s:%d: %s", filepath.Base(c.URI.Filename()), c.Line, c.Message)
+	}
+	action, err := s.client.ShowMessageRequest(ctx, &protocol.ShowMessageRequestParams{
+		Type:    protocol.Warning,
+		Message: msg.String(),
+		Actions: []protocol.MessageActionItem{{Title: renameAnyway}, {Title: "Cancel"}},
+	})
+	return err == nil && action != nil && action.Title == renameAnyway
+}
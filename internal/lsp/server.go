@@ -12,6 +12,7 @@ import (
 	"sync"
 
 	"golang.org/x/tools/internal/jsonrpc2"
+	"golang.org/x/tools/internal/lsp/journal"
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/source"
 	"golang.org/x/tools/internal/span"
@@ -87,6 +88,38 @@ type Server struct {
 	// folders is only valid between initialize and initialized, and holds the
 	// set of folders to build views for when we are ready
 	pendingFolders []protocol.WorkspaceFolder
+
+	// progress tracks the work done progress reported to the client.
+	progress progressTracker
+
+	// bridge holds the language servers that the requests for the files of
+	// other languages are forwarded to.
+	bridge bridge
+
+	// changedMappers are the mappers of the contents last set by incremental
+	// changes, by URI, so that the next changes of a file adjust their line
+	// tables rather than tables computed again from its content.
+	changedMappersMu sync.Mutex
+	changedMappers   map[span.URI]changedMapper
+
+	// staleLargeFiles are the large files changed since they were last
+	// saved, whose diagnostics are only computed again once they are saved.
+	staleLargeFilesMu sync.Mutex
+	staleLargeFiles   map[span.URI]bool
+
+	// unregisterBugs unregisters the handler of the internal bugs that
+	// offers the user to create a bug report, once the server shuts down.
+	unregisterBugs func()
+
+	// journal records the open files of the client, if its forwarder
+	// asked for it, so that a restarted daemon can open them again.
+	journalMu sync.Mutex
+	journal   *journal.Journal
+
+	// watchPatterns are the glob patterns of the file watchers registered
+	// with the client for the views, or nil if none are registered.
+	watchMu       sync.Mutex
+	watchPatterns []string
 }
 
 // General
@@ -121,21 +154,37 @@ func (s *Server) DidChangeWatchedFiles(ctx context.Context, params *protocol.Did
 	return s.didChangeWatchedFiles(ctx, params)
 }
 
-func (s *Server) Symbol(context.Context, *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
-	return nil, notImplemented("Symbol")
+func (s *Server) Symbol(ctx context.Context, params *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
+	return s.symbol(ctx, params)
 }
 
 func (s *Server) ExecuteCommand(ctx context.Context, params *protocol.ExecuteCommandParams) (interface{}, error) {
 	return s.executeCommand(ctx, params)
 }
 
+func (s *Server) NonstandardRequest(ctx context.Context, method string, params interface{}) (interface{}, error) {
+	return s.nonstandardRequest(ctx, method, params)
+}
+
 // Text Synchronization
 
 func (s *Server) DidOpen(ctx context.Context, params *protocol.DidOpenTextDocumentParams) error {
+	if es, err := s.external(ctx, params.TextDocument.URI); err != nil || es != nil {
+		if err != nil {
+			return err
+		}
+		return es.DidOpen(ctx, params)
+	}
 	return s.didOpen(ctx, params)
 }
 
 func (s *Server) DidChange(ctx context.Context, params *protocol.DidChangeTextDocumentParams) error {
+	if es, err := s.external(ctx, params.TextDocument.URI); err != nil || es != nil {
+		if err != nil {
+			return err
+		}
+		return es.DidChange(ctx, params)
+	}
 	return s.didChange(ctx, params)
 }
 
@@ -148,16 +197,34 @@ func (s *Server) WillSaveWaitUntil(context.Context, *protocol.WillSaveTextDocume
 }
 
 func (s *Server) DidSave(ctx context.Context, params *protocol.DidSaveTextDocumentParams) error {
+	if es, err := s.external(ctx, params.TextDocument.URI); err != nil || es != nil {
+		if err != nil {
+			return err
+		}
+		return es.DidSave(ctx, params)
+	}
 	return s.didSave(ctx, params)
 }
 
 func (s *Server) DidClose(ctx context.Context, params *protocol.DidCloseTextDocumentParams) error {
+	if es, err := s.external(ctx, params.TextDocument.URI); err != nil || es != nil {
+		if err != nil {
+			return err
+		}
+		return es.DidClose(ctx, params)
+	}
 	return s.didClose(ctx, params)
 }
 
 // Language Features
 
 func (s *Server) Completion(ctx context.Context, params *protocol.CompletionParams) (*protocol.CompletionList, error) {
+	if es, err := s.external(ctx, params.TextDocument.URI); err != nil || es != nil {
+		if err != nil {
+			return nil, err
+		}
+		return es.Completion(ctx, params)
+	}
 	return s.completion(ctx, params)
 }
 
@@ -166,6 +233,12 @@ func (s *Server) Resolve(ctx context.Context, item *protocol.CompletionItem) (*p
 }
 
 func (s *Server) Hover(ctx context.Context, params *protocol.HoverParams) (*protocol.Hover, error) {
+	if es, err := s.external(ctx, params.TextDocument.URI); err != nil || es != nil {
+		if err != nil {
+			return nil, err
+		}
+		return es.Hover(ctx, params)
+	}
 	return s.hover(ctx, params)
 }
 
@@ -174,6 +247,12 @@ func (s *Server) SignatureHelp(ctx context.Context, params *protocol.SignatureHe
 }
 
 func (s *Server) Definition(ctx context.Context, params *protocol.DefinitionParams) ([]protocol.Location, error) {
+	if es, err := s.external(ctx, params.TextDocument.URI); err != nil || es != nil {
+		if err != nil {
+			return nil, err
+		}
+		return es.Definition(ctx, params)
+	}
 	return s.definition(ctx, params)
 }
 
@@ -186,6 +265,12 @@ func (s *Server) Implementation(context.Context, *protocol.ImplementationParams)
 }
 
 func (s *Server) References(ctx context.Context, params *protocol.ReferenceParams) ([]protocol.Location, error) {
+	if es, err := s.external(ctx, params.TextDocument.URI); err != nil || es != nil {
+		if err != nil {
+			return nil, err
+		}
+		return es.References(ctx, params)
+	}
 	return s.references(ctx, params)
 }
 
@@ -194,6 +279,12 @@ func (s *Server) DocumentHighlight(ctx context.Context, params *protocol.Documen
 }
 
 func (s *Server) DocumentSymbol(ctx context.Context, params *protocol.DocumentSymbolParams) ([]protocol.DocumentSymbol, error) {
+	if es, err := s.external(ctx, params.TextDocument.URI); err != nil || es != nil {
+		if err != nil {
+			return nil, err
+		}
+		return es.DocumentSymbol(ctx, params)
+	}
 	return s.documentSymbol(ctx, params)
 }
 
@@ -201,8 +292,8 @@ func (s *Server) CodeAction(ctx context.Context, params *protocol.CodeActionPara
 	return s.codeAction(ctx, params)
 }
 
-func (s *Server) CodeLens(context.Context, *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
-	return nil, nil // ignore
+func (s *Server) CodeLens(ctx context.Context, params *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
+	return s.codeLens(ctx, params)
 }
 
 func (s *Server) ResolveCodeLens(context.Context, *protocol.CodeLens) (*protocol.CodeLens, error) {
@@ -226,15 +317,21 @@ func (s *Server) ColorPresentation(context.Context, *protocol.ColorPresentationP
 }
 
 func (s *Server) Formatting(ctx context.Context, params *protocol.DocumentFormattingParams) ([]protocol.TextEdit, error) {
+	if es, err := s.external(ctx, params.TextDocument.URI); err != nil || es != nil {
+		if err != nil {
+			return nil, err
+		}
+		return es.Formatting(ctx, params)
+	}
 	return s.formatting(ctx, params)
 }
 
 func (s *Server) RangeFormatting(ctx context.Context, params *protocol.DocumentRangeFormattingParams) ([]protocol.TextEdit, error) {
-	return nil, notImplemented("RangeFormatting")
+	return s.rangeFormatting(ctx, params)
 }
 
-func (s *Server) OnTypeFormatting(context.Context, *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
-	return nil, notImplemented("OnTypeFormatting")
+func (s *Server) OnTypeFormatting(ctx context.Context, params *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
+	return s.onTypeFormatting(ctx, params)
 }
 
 func (s *Server) Rename(ctx context.Context, params *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
@@ -258,16 +355,33 @@ func (s *Server) PrepareRename(ctx context.Context, params *protocol.PrepareRena
 	return s.prepareRename(ctx, params)
 }
 
+func (s *Server) Diagnostic(ctx context.Context, params *protocol.DocumentDiagnosticParams) (*protocol.DocumentDiagnosticReport, error) {
+	return s.diagnostic(ctx, params)
+}
+
+func (s *Server) InlayHint(ctx context.Context, params *protocol.InlayHintParams) ([]protocol.InlayHint, error) {
+	return s.inlayHint(ctx, params)
+}
+
 func (s *Server) Progress(context.Context, *protocol.ProgressParams) error {
 	return notImplemented("Progress")
 }
 
+func (s *Server) WorkDoneProgressCancel(ctx context.Context, params *protocol.WorkDoneProgressCancelParams) error {
+	// The token is either that of a request of the client or that of
+	// work done progress created by the server.
+	if protocol.CancelWorkDone(ctx, params.Token) {
+		return nil
+	}
+	return s.progress.cancel(ctx, params.Token)
+}
+
 func (s *Server) SetTraceNotification(context.Context, *protocol.SetTraceParams) error {
 	return notImplemented("SetTraceNotification")
 }
 
-func (s *Server) SelectionRange(context.Context, *protocol.SelectionRangeParams) ([]protocol.SelectionRange, error) {
-	return nil, notImplemented("SelectionRange")
+func (s *Server) SelectionRange(ctx context.Context, params *protocol.SelectionRangeParams) ([]protocol.SelectionRange, error) {
+	return s.selectionRange(ctx, params)
 }
 
 func notImplemented(method string) *jsonrpc2.Error {
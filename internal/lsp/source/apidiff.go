@@ -0,0 +1,376 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/internal/apidiff"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/log"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// APIDiffSource is the source of the diagnostics of the incompatible changes
+// to the API of a module, which are reported after the "apidiff" command.
+const APIDiffSource = "apidiff"
+
+// APIDiffArgs are the arguments to the "apidiff" command, which compares the
+// exported API of the module of the go.mod file URI with that of a released
+// version of the module.
+type APIDiffArgs struct {
+	URI protocol.DocumentUri `json:"uri"`
+
+	// Version is the version to compare with, such as v1.2.0. If it is
+	// empty, the latest release listed by the module proxy is used.
+	Version string `json:"version,omitempty"`
+}
+
+// APIChange is an incompatible change to the API of a package.
+type APIChange struct {
+	// Decl is the name of the package-level declaration that the change
+	// is about, such as "T", or "T.M" for a method.
+	Decl string
+
+	// Message describes the change, as reported by apidiff, such as
+	// "T.M: removed".
+	Message string
+
+	// Version is the version of the module that the package is compared
+	// with.
+	Version string
+}
+
+// APIDiffResult is the result of the "apidiff" command.
+type APIDiffResult struct {
+	// Version is the version of the module that it was compared with.
+	Version string
+
+	// Changes are the incompatible changes, by package path.
+	Changes map[string][]APIChange
+
+	// Removed are the paths of the packages of the version that the
+	// module no longer has.
+	Removed []string
+
+	// Files are the Go files of the packages of the module, whose
+	// diagnostics may have changed.
+	Files []span.URI
+}
+
+// RunAPIDiff compares the exported API of the packages of the module of
+// args.URI, as saved on disk, with that of the same packages of a released
+// version, and stores the incompatible changes in the current snapshot of
+// view. Internal and main packages are not compared.
+func RunAPIDiff(ctx context.Context, view View, args APIDiffArgs) (*APIDiffResult, error) {
+	ctx, done := trace.StartSpan(ctx, "source.RunAPIDiff")
+	defer done()
+
+	gomod := span.NewURI(args.URI)
+	content, _, err := view.Session().GetFile(gomod, Mod).Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path := modulePath(content)
+	if path == "" {
+		return nil, errors.Errorf("%s has no module directive", gomod.Filename())
+	}
+	version := args.Version
+	if version == "" {
+		info, err := moduleInfo(ctx, view, path)
+		if err != nil {
+			return nil, err
+		}
+		if len(info.Versions) == 0 {
+			return nil, errors.Errorf("%s has no released versions", path)
+		}
+		version = info.Latest
+	}
+
+	env := append(append([]string{}, view.Config(ctx).Env...), "GO111MODULE=on", "GOFLAGS=-mod=mod")
+	newPkgs, err := loadAPI(ctx, filepath.Dir(gomod.Filename()), env, "./...")
+	if err != nil {
+		return nil, err
+	}
+	// The released version is loaded as a requirement of a temporary
+	// module, rather than as the main module in the read-only module cache.
+	dir, err := ioutil.TempDir("", "gopls-apidiff-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	modFile := fmt.Sprintf("module gopls-apidiff\n\nrequire %s %s\n", path, version)
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(modFile), 0644); err != nil {
+		return nil, err
+	}
+	oldPkgs, err := loadAPI(ctx, dir, env, path+"/...")
+	if err != nil {
+		return nil, errors.Errorf("loading %s@%s: %v", path, version, err)
+	}
+
+	result := &APIDiffResult{
+		Version: version,
+		Changes: make(map[string][]APIChange),
+	}
+	for _, pkg := range newPkgs {
+		for _, file := range pkg.GoFiles {
+			result.Files = append(result.Files, span.FileURI(file))
+		}
+	}
+	for pkgPath, old := range oldPkgs {
+		pkg, ok := newPkgs[pkgPath]
+		if !ok {
+			result.Removed = append(result.Removed, pkgPath)
+			continue
+		}
+		for _, c := range apidiff.Changes(old.Types, pkg.Types).Changes {
+			if c.Compatible {
+				continue
+			}
+			result.Changes[pkgPath] = append(result.Changes[pkgPath], APIChange{
+				Decl:    apiDeclName(c.Object),
+				Message: c.Message,
+				Version: version,
+			})
+		}
+	}
+	sort.Strings(result.Removed)
+	view.Snapshot().StoreAPIChanges(result.Changes)
+	return result, nil
+}
+
+// loadAPI loads the types of the packages matching pattern in dir, by package
+// path. Internal and main packages are omitted, since they are not part of
+// the API of their module.
+func loadAPI(ctx context.Context, dir string, env []string, pattern string) (map[string]*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode:    packages.LoadTypes,
+		Context: ctx,
+		Dir:     dir,
+		Env:     env,
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*packages.Package)
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, pkg.Errors[0]
+		}
+		if pkg.Name == "main" || isInternal(pkg.PkgPath) {
+			continue
+		}
+		result[pkg.PkgPath] = pkg
+	}
+	return result, nil
+}
+
+// isInternal reports whether pkgPath has an internal element.
+func isInternal(pkgPath string) bool {
+	for _, elem := range strings.Split(pkgPath, "/") {
+		if elem == "internal" {
+			return true
+		}
+	}
+	return false
+}
+
+// modulePath returns the path of the module directive of the content of a
+// go.mod file, or "" if it has none.
+func modulePath(content []byte) string {
+	for _, d := range parseModDirectives(content) {
+		if d.verb != "module" {
+			continue
+		}
+		if path, err := strconv.Unquote(d.args); err == nil {
+			return path
+		}
+		return d.args
+	}
+	return ""
+}
+
+// apiDeclName returns the name of the package-level declaration of obj, the
+// receiver type and name of a method.
+func apiDeclName(obj types.Object) string {
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			t := recv.Type()
+			if ptr, ok := t.(*types.Pointer); ok {
+				t = ptr.Elem()
+			}
+			if named, ok := t.(*types.Named); ok {
+				return named.Obj().Name() + "." + fn.Name()
+			}
+		}
+	}
+	return obj.Name()
+}
+
+// apiDiffDiagnostics reports the incompatible changes to the API of pkg that
+// are stored in the snapshot on the names of the declarations that they are
+// about. The changes to declarations that were removed are reported on the
+// package clause.
+func apiDiffDiagnostics(ctx context.Context, snapshot Snapshot, pkg Package, reports map[span.URI][]Diagnostic) error {
+	changes := snapshot.APIChanges(pkg.PkgPath())
+	if len(changes) == 0 {
+		return nil
+	}
+	view := snapshot.View()
+	fset := view.Session().Cache().FileSet()
+	decls := make(map[string]*ast.Ident)
+	var clause *ast.Ident
+	for _, file := range pkg.GetSyntax() {
+		if strings.HasSuffix(fset.Position(file.Pos()).Filename, "_test.go") {
+			continue
+		}
+		if clause == nil {
+			clause = file.Name
+		}
+		for name, id := range apiDecls(file) {
+			decls[name] = id
+		}
+	}
+	if clause == nil {
+		return nil
+	}
+	for _, c := range changes {
+		id := lookupAPIDecl(decls, c.Decl)
+		if id == nil {
+			id = clause
+		}
+		rng, err := posToMappedRange(ctx, pkg, id.Pos(), id.End())
+		if err != nil {
+			return err
+		}
+		protocolRange, err := rng.Range()
+		if err != nil {
+			return err
+		}
+		addReport(view, reports, Diagnostic{
+			URI:      rng.URI(),
+			Range:    protocolRange,
+			Message:  fmt.Sprintf("incompatible change since %s: %s", c.Version, c.Message),
+			Source:   APIDiffSource,
+			Severity: protocol.SeverityWarning,
+		})
+	}
+	return nil
+}
+
+// apiDecls returns the names of the package-level declarations of file, by
+// the name used by APIChange.
+func apiDecls(file *ast.File) map[string]*ast.Ident {
+	result := make(map[string]*ast.Ident)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name := decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) == 1 {
+				if recv := recvTypeName(decl.Recv.List[0].Type); recv != "" {
+					name = recv + "." + name
+				}
+			}
+			result[name] = decl.Name
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					result[spec.Name.Name] = spec.Name
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						result[name.Name] = name
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
+// lookupAPIDecl returns the name of the declaration of decls with the given
+// name, or nil if it is not declared. The methods of interfaces are declared
+// by their type.
+func lookupAPIDecl(decls map[string]*ast.Ident, name string) *ast.Ident {
+	if id, ok := decls[name]; ok {
+		return id
+	}
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		return decls[name[:i]]
+	}
+	return nil
+}
+
+// recvTypeName returns the name of the type of the receiver expression x,
+// such as T for *T or T[K].
+func recvTypeName(x ast.Expr) string {
+	for {
+		switch t := x.(type) {
+		case *ast.StarExpr:
+			x = t.X
+		case *ast.ParenExpr:
+			x = t.X
+		case *ast.IndexExpr:
+			x = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// ModCodeLens returns the code lenses of the go.mod file f, on its module
+// directive: one that compares the API of the module with its latest
+// release, and one that shows whether the module is tidy.
+func ModCodeLens(ctx context.Context, view View, f File) ([]protocol.CodeLens, error) {
+	ctx, done := trace.StartSpan(ctx, "source.ModCodeLens")
+	defer done()
+
+	m, err := fileMapper(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range parseModDirectives(m.Content) {
+		if d.verb != "module" {
+			continue
+		}
+		rng, err := m.Range(span.New(m.URI, span.NewPoint(0, 0, d.start), span.NewPoint(0, 0, d.end)))
+		if err != nil {
+			return nil, err
+		}
+		lenses := []protocol.CodeLens{{
+			Range: rng,
+			Command: &protocol.Command{
+				Title:     "check API against the latest release",
+				Command:   "apidiff",
+				Arguments: []interface{}{APIDiffArgs{URI: protocol.NewURI(f.URI())}},
+			},
+		}}
+		// The module may not be tidy because the go command fails on it,
+		// which its diagnostics report.
+		if lens, err := tidyLens(ctx, view, f.URI(), rng); err != nil {
+			log.Error(ctx, "failed to compute the go mod tidy lens", err, telemetry.File.Of(f.URI()))
+		} else {
+			lenses = append(lenses, lens)
+		}
+		return lenses, nil
+	}
+	return nil, nil
+}
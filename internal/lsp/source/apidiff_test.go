@@ -0,0 +1,99 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"testing"
+
+	"golang.org/x/tools/internal/apidiff"
+)
+
+func TestAPIDiffDecls(t *testing.T) {
+	const old = `package a
+
+type T struct{}
+
+func (T) Close() error { return nil }
+func (*T) Read() {}
+
+type I interface{ M() }
+
+const Max = 10
+
+func Removed() {}
+`
+	const new = `package a
+
+type T struct{}
+
+func (T) Close() {}
+func (*T) Read(n int) {}
+
+type I interface{ M(int) }
+
+const Max = "10"
+`
+	fset := token.NewFileSet()
+	check := func(src string) (*ast.File, *types.Package) {
+		file, err := parser.ParseFile(fset, "a.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf := types.Config{Importer: importer.Default()}
+		pkg, err := conf.Check("a", fset, []*ast.File{file}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file, pkg
+	}
+	_, oldPkg := check(old)
+	file, newPkg := check(new)
+
+	decls := apiDecls(file)
+	var got []string
+	for _, c := range apidiff.Changes(oldPkg, newPkg).Changes {
+		if c.Compatible {
+			continue
+		}
+		name := apiDeclName(c.Object)
+		if id := lookupAPIDecl(decls, name); id != nil {
+			name = id.Name
+		} else {
+			name += " (not found)"
+		}
+		got = append(got, name)
+	}
+	sort.Strings(got)
+	want := []string{"Close", "I", "Max", "Read", "Removed (not found)"}
+	if len(got) != len(want) {
+		t.Fatalf("got changes to %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got changes to %q, want %q", got, want)
+			break
+		}
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	for _, test := range []struct {
+		content, want string
+	}{
+		{"module example.com/m\n\ngo 1.13\n", "example.com/m"},
+		{"// comment\nmodule \"example.com/q\" // quoted\n", "example.com/q"},
+		{"go 1.13\n", ""},
+	} {
+		if got := modulePath([]byte(test.content)); got != test.want {
+			t.Errorf("modulePath(%q) = %q, want %q", test.content, got, test.want)
+		}
+	}
+}
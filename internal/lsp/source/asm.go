@@ -0,0 +1,345 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// asmSymbol is a symbol defined by a TEXT, DATA or GLOBL directive of an
+// assembly file, such as ·Add in
+//
+//	TEXT ·Add(SB), NOSPLIT, $0-24
+type asmSymbol struct {
+	directive string
+
+	// pkg is the path of the package of the symbol, or "" for the package
+	// of the assembly file.
+	pkg  string
+	name string
+
+	// start and end are the offsets of the symbol, including its package,
+	// in the content of the file.
+	start, end int
+}
+
+// parseAsm returns the symbols defined in the assembly file with the given
+// content. Only the symbols of Go packages, whose names contain a middle
+// dot, are returned.
+func parseAsm(content []byte) []asmSymbol {
+	var symbols []asmSymbol
+	offset := 0
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		lineStart := offset
+		offset += len(line)
+		if i := bytes.Index(line, []byte("//")); i >= 0 {
+			line = line[:i]
+		}
+		text := string(line)
+		trimmed := strings.TrimLeft(text, " \t")
+		fields := strings.Fields(trimmed)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "TEXT", "DATA", "GLOBL":
+		default:
+			continue
+		}
+		start := len(text) - len(trimmed) + len(fields[0])
+		for start < len(text) && (text[start] == ' ' || text[start] == '\t') {
+			start++
+		}
+		sym := text[start:]
+		if i := strings.IndexAny(sym, "(+<, \t"); i >= 0 {
+			sym = sym[:i]
+		}
+		dot := strings.Index(sym, "·")
+		if dot < 0 || dot+len("·") == len(sym) {
+			continue
+		}
+		symbols = append(symbols, asmSymbol{
+			directive: fields[0],
+			// Slashes in package paths are written as division slashes.
+			pkg:   strings.Replace(sym[:dot], "∕", "/", -1),
+			name:  sym[dot+len("·"):],
+			start: lineStart + start,
+			end:   lineStart + start + len(sym),
+		})
+	}
+	return symbols
+}
+
+// fileMapper returns the content of the file f, which is not parsed as Go,
+// with a mapper for its positions.
+func fileMapper(ctx context.Context, view View, f File) (*protocol.ColumnMapper, error) {
+	content, _, err := view.Snapshot().Handle(ctx, f).Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ColumnMapper{
+		URI:       f.URI(),
+		Converter: span.NewContentConverter(f.URI().Filename(), content),
+		Content:   content,
+		Encoding:  view.Options().PositionEncoding,
+	}, nil
+}
+
+func (s asmSymbol) rng(m *protocol.ColumnMapper) (protocol.Range, error) {
+	return m.Range(span.New(m.URI, span.NewPoint(0, 0, s.start), span.NewPoint(0, 0, s.end)))
+}
+
+// AsmSymbols returns the outline of the assembly file f: the functions
+// defined by its TEXT directives, and the variables defined by its DATA and
+// GLOBL directives.
+func AsmSymbols(ctx context.Context, view View, f File) ([]protocol.DocumentSymbol, error) {
+	ctx, done := trace.StartSpan(ctx, "source.AsmSymbols")
+	defer done()
+
+	m, err := fileMapper(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	var symbols []protocol.DocumentSymbol
+	seen := make(map[string]bool)
+	for _, sym := range parseAsm(m.Content) {
+		name := sym.name
+		if sym.pkg != "" {
+			name = sym.pkg + "." + sym.name
+		}
+		// A variable has a DATA directive for each of its parts.
+		if sym.directive != "TEXT" {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+		}
+		rng, err := sym.rng(m)
+		if err != nil {
+			return nil, err
+		}
+		kind := protocol.Variable
+		if sym.directive == "TEXT" {
+			kind = protocol.Function
+		}
+		symbols = append(symbols, protocol.DocumentSymbol{
+			Name:           name,
+			Detail:         sym.directive,
+			Kind:           kind,
+			Range:          rng,
+			SelectionRange: rng,
+		})
+	}
+	return symbols, nil
+}
+
+// AsmPackageFile returns a Go file of the package that the assembly file f
+// belongs to: a file in the same directory that is not a test.
+func AsmPackageFile(ctx context.Context, view View, f File) (File, error) {
+	gof, err := packageFileInDir(ctx, view, filepath.Dir(f.URI().Filename()))
+	if err != nil {
+		return nil, err
+	}
+	if gof == nil {
+		return nil, errors.Errorf("no Go package for %s", f.URI())
+	}
+	return gof, nil
+}
+
+// packageFileInDir returns a Go file of dir that is not a test, or nil if
+// there is none.
+func packageFileInDir(ctx context.Context, view View, dir string) (File, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		return view.GetFile(ctx, span.FileURI(filepath.Join(dir, name)))
+	}
+	return nil, nil
+}
+
+// AsmDefinition returns the location of the Go declaration of the symbol of
+// the assembly file f at pos. It returns no locations, and no error, if pos
+// is not in a symbol.
+func AsmDefinition(ctx context.Context, view View, f File, pos protocol.Position) ([]protocol.Location, error) {
+	ctx, done := trace.StartSpan(ctx, "source.AsmDefinition")
+	defer done()
+
+	m, err := fileMapper(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return nil, err
+	}
+	offset := spn.Start().Offset()
+	var sym *asmSymbol
+	for _, s := range parseAsm(m.Content) {
+		if s.start <= offset && offset <= s.end {
+			s := s
+			sym = &s
+			break
+		}
+	}
+	if sym == nil {
+		return nil, nil
+	}
+
+	gof, err := AsmPackageFile(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	_, cphs, err := view.CheckPackageHandles(ctx, gof)
+	if err != nil {
+		return nil, err
+	}
+	cph, err := WidestCheckPackageHandle(cphs)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := cph.Check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.GetTypes() == nil {
+		return nil, errors.Errorf("no types for %s", pkg.PkgPath())
+	}
+	path := sym.pkg
+	if path == "" {
+		path = pkg.PkgPath()
+	}
+	dep := findDependency(pkg.GetTypes(), path)
+	if dep == nil {
+		return nil, errors.Errorf("%s is not a dependency of %s", path, pkg.PkgPath())
+	}
+	obj := dep.Scope().Lookup(sym.name)
+	if obj == nil {
+		return nil, errors.Errorf("no Go declaration of %s in %s", sym.name, path)
+	}
+	declRange, err := objToMappedRange(ctx, pkg, obj)
+	if err != nil {
+		return nil, err
+	}
+	protocolRange, err := declRange.Range()
+	if err != nil {
+		return nil, err
+	}
+	return []protocol.Location{{
+		URI:   protocol.NewURI(declRange.URI()),
+		Range: protocolRange,
+	}}, nil
+}
+
+// asmDiagnostics reports the functions of pkg that are declared without a
+// body, and that no assembly file of the package implements. Packages with no
+// assembly files are not checked, as the compiler reports their missing
+// function bodies itself. The assembly files for all architectures are
+// considered.
+func asmDiagnostics(ctx context.Context, view View, pkg Package, reports map[span.URI][]Diagnostic) error {
+	ctx, done := trace.StartSpan(ctx, "source.asmDiagnostics")
+	defer done()
+
+	fset := view.Session().Cache().FileSet()
+	implemented := make(map[string]map[string]bool) // by directory
+	for _, file := range pkg.GetSyntax() {
+		dir := filepath.Dir(fset.Position(file.Pos()).Filename)
+		texts, ok := implemented[dir]
+		if !ok {
+			var err error
+			if texts, err = asmTexts(ctx, view, dir, pkg.PkgPath()); err != nil {
+				return err
+			}
+			implemented[dir] = texts
+		}
+		if texts == nil {
+			continue
+		}
+		for _, decl := range file.Decls {
+			decl, ok := decl.(*ast.FuncDecl)
+			if !ok || decl.Body != nil || decl.Recv != nil || texts[decl.Name.Name] || hasLinkname(file, decl.Name.Name) {
+				continue
+			}
+			rng, err := posToMappedRange(ctx, pkg, decl.Name.Pos(), decl.Name.End())
+			if err != nil {
+				return err
+			}
+			protocolRange, err := rng.Range()
+			if err != nil {
+				return err
+			}
+			addReport(view, reports, Diagnostic{
+				URI:      rng.URI(),
+				Range:    protocolRange,
+				Message:  fmt.Sprintf("missing function body: no assembly implementation of %s", decl.Name.Name),
+				Source:   "asm",
+				Severity: protocol.SeverityError,
+			})
+		}
+	}
+	return nil
+}
+
+// asmTexts returns the names of the functions of the package with the given
+// path that the assembly files in dir define, or nil if there are no
+// assembly files in dir.
+func asmTexts(ctx context.Context, view View, dir, pkgPath string) (map[string]bool, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var texts map[string]bool
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".s" {
+			continue
+		}
+		uri := span.FileURI(filepath.Join(dir, info.Name()))
+		content, _, err := view.Session().GetFile(uri, Asm).Read(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if texts == nil {
+			texts = make(map[string]bool)
+		}
+		for _, sym := range parseAsm(content) {
+			if sym.directive == "TEXT" && (sym.pkg == "" || sym.pkg == pkgPath) {
+				texts[sym.name] = true
+			}
+		}
+	}
+	return texts, nil
+}
+
+// hasLinkname reports whether file has a //go:linkname directive for the
+// local name, which provides the body of a function declared without one.
+func hasLinkname(file *ast.File, name string) bool {
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if !strings.HasPrefix(c.Text, linknameDirective+" ") {
+				continue
+			}
+			if fields := strings.Fields(c.Text[len(linknameDirective):]); len(fields) > 0 && fields[0] == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAsm(t *testing.T) {
+	const src = `#include "textflag.h"
+
+// func Add(x, y int) int
+TEXT ·Add(SB), NOSPLIT, $0-24
+	MOVQ x+0(FP), AX
+	RET
+
+TEXT	example.com∕m∕p·Sub(SB),NOSPLIT,$0-24 // comment
+	RET
+
+DATA ·table+0(SB)/8, $1
+DATA ·table+8(SB)/8, $2
+GLOBL ·table(SB), RODATA, $16
+
+TEXT local<>(SB), NOSPLIT, $0
+	RET
+`
+	var got []asmSymbol
+	for _, sym := range parseAsm([]byte(src)) {
+		if text := src[sym.start:sym.end]; text != "·"+sym.name && text != "example.com∕m∕p·"+sym.name {
+			t.Errorf("got text %q for %s", text, sym.name)
+		}
+		sym.start, sym.end = 0, 0
+		got = append(got, sym)
+	}
+	want := []asmSymbol{
+		{directive: "TEXT", name: "Add"},
+		{directive: "TEXT", pkg: "example.com/m/p", name: "Sub"},
+		{directive: "DATA", name: "table"},
+		{directive: "DATA", name: "table"},
+		{directive: "GLOBL", name: "table"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got symbols %+v, want %+v", got, want)
+	}
+}
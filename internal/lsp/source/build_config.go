@@ -0,0 +1,85 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	errors "golang.org/x/xerrors"
+)
+
+// BuildConfiguration is a target platform and a set of build tags under which
+// packages are loaded and type-checked. Empty fields keep the value of the
+// environment and build flags of the view.
+type BuildConfiguration struct {
+	GOOS   string   `json:"goos,omitempty"`
+	GOARCH string   `json:"goarch,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// BuildConfigurationArgs are the arguments to the
+// gopls.SetBuildConfiguration command, which changes the build configuration
+// of the view of the file or folder URI.
+type BuildConfigurationArgs struct {
+	URI protocol.DocumentUri `json:"uri"`
+	BuildConfiguration
+}
+
+// ParseBuildConfiguration parses a build configuration written as
+// GOOS/GOARCH, optionally followed by a comma-separated list of build tags,
+// such as linux/amd64 or windows/arm64,integration.
+func ParseBuildConfiguration(s string) (BuildConfiguration, error) {
+	fields := strings.Split(s, ",")
+	platform := strings.Split(strings.TrimSpace(fields[0]), "/")
+	if len(platform) != 2 || platform[0] == "" || platform[1] == "" {
+		return BuildConfiguration{}, errors.Errorf("invalid build configuration %q, want GOOS/GOARCH[,tag...]", s)
+	}
+	c := BuildConfiguration{GOOS: platform[0], GOARCH: platform[1]}
+	for _, tag := range fields[1:] {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			c.Tags = append(c.Tags, tag)
+		}
+	}
+	return c, nil
+}
+
+// String returns the build configuration in the form parsed by
+// ParseBuildConfiguration.
+func (c BuildConfiguration) String() string {
+	s := c.GOOS + "/" + c.GOARCH
+	if len(c.Tags) > 0 {
+		s += "," + strings.Join(c.Tags, ",")
+	}
+	return s
+}
+
+// Apply returns a copy of options whose environment and build flags select
+// the configuration c. The -tags flag of c replaces that of the build flags.
+func (c BuildConfiguration) Apply(options Options) Options {
+	options.Env = append([]string{}, options.Env...)
+	if c.GOOS != "" {
+		options.Env = append(options.Env, "GOOS="+c.GOOS)
+	}
+	if c.GOARCH != "" {
+		options.Env = append(options.Env, "GOARCH="+c.GOARCH)
+	}
+	if len(c.Tags) > 0 {
+		var flags []string
+		for i := 0; i < len(options.BuildFlags); i++ {
+			flag := options.BuildFlags[i]
+			switch {
+			case flag == "-tags" || flag == "--tags":
+				i++ // skip the value of the flag
+				continue
+			case strings.HasPrefix(flag, "-tags=") || strings.HasPrefix(flag, "--tags="):
+				continue
+			}
+			flags = append(flags, flag)
+		}
+		options.BuildFlags = append(flags, "-tags="+strings.Join(c.Tags, ","))
+	}
+	return options
+}
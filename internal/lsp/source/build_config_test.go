@@ -0,0 +1,54 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBuildConfiguration(t *testing.T) {
+	for _, tt := range []struct {
+		in      string
+		want    BuildConfiguration
+		wantErr bool
+	}{
+		{in: "linux/amd64", want: BuildConfiguration{GOOS: "linux", GOARCH: "amd64"}},
+		{in: "windows/arm64, integration,netgo", want: BuildConfiguration{GOOS: "windows", GOARCH: "arm64", Tags: []string{"integration", "netgo"}}},
+		{in: "linux", wantErr: true},
+		{in: "/amd64", wantErr: true},
+		{in: "linux/amd64/extra", wantErr: true},
+	} {
+		got, err := ParseBuildConfiguration(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBuildConfiguration(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseBuildConfiguration(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestApplyBuildConfiguration(t *testing.T) {
+	options := Options{
+		Env:        []string{"GOPATH=/gopath"},
+		BuildFlags: []string{"-tags", "old", "-mod=vendor", "-tags=older"},
+	}
+	c := BuildConfiguration{GOOS: "windows", GOARCH: "amd64", Tags: []string{"a", "b"}}
+	got := c.Apply(options)
+	if want := []string{"GOPATH=/gopath", "GOOS=windows", "GOARCH=amd64"}; !reflect.DeepEqual(got.Env, want) {
+		t.Errorf("got env %q, want %q", got.Env, want)
+	}
+	if want := []string{"-mod=vendor", "-tags=a,b"}; !reflect.DeepEqual(got.BuildFlags, want) {
+		t.Errorf("got build flags %q, want %q", got.BuildFlags, want)
+	}
+	if len(options.Env) != 1 {
+		t.Errorf("Apply modified the environment of the options: %q", options.Env)
+	}
+	if s := c.String(); s != "windows/amd64,a,b" {
+		t.Errorf("String() = %q, want windows/amd64,a,b", s)
+	}
+}
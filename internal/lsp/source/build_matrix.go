@@ -0,0 +1,85 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/log"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// matrixKey identifies an error reported under several build configurations.
+type matrixKey struct {
+	uri     span.URI
+	rng     protocol.Range
+	message string
+}
+
+// matrixDiagnostics adds to reports the errors of the package of f under the
+// build configurations of the build matrix of the view that are not already
+// reported. The message of each error is prefixed with the configurations
+// under which it occurs, such as "[windows/amd64, windows/arm64]".
+func matrixDiagnostics(ctx context.Context, view View, f File, reports map[span.URI][]Diagnostic) {
+	ctx, done := trace.StartSpan(ctx, "source.matrixDiagnostics", telemetry.File.Of(f.URI()))
+	defer done()
+
+	// The diagnostics of the other configurations must not replace those
+	// of the view as they are found.
+	ctx = context.WithValue(ctx, partialDiagnosticsKey{}, nil)
+
+	reported := make(map[matrixKey]bool)
+	for uri, diagnostics := range reports {
+		for _, d := range diagnostics {
+			reported[matrixKey{uri, d.Range, d.Message}] = true
+		}
+	}
+	var (
+		order   []matrixKey
+		found   = make(map[matrixKey]Diagnostic)
+		configs = make(map[matrixKey][]string)
+	)
+	matrix := view.Options().BuildMatrix
+	for i, mv := range view.MatrixViews() {
+		config := matrix[i].String()
+		mf, err := mv.GetFile(ctx, f.URI())
+		if err != nil {
+			log.Error(ctx, "no file for build configuration "+config, err, telemetry.File.Of(f.URI()))
+			continue
+		}
+		mreports, _, err := Diagnostics(ctx, mv, mf, view.Options().DisabledAnalyses)
+		if err != nil {
+			// The file may not be built under this configuration.
+			continue
+		}
+		for uri, diagnostics := range mreports {
+			for _, d := range diagnostics {
+				if d.Severity != protocol.SeverityError {
+					continue
+				}
+				key := matrixKey{uri, d.Range, d.Message}
+				if reported[key] {
+					continue
+				}
+				if _, ok := found[key]; !ok {
+					order = append(order, key)
+					found[key] = d
+				}
+				configs[key] = append(configs[key], config)
+			}
+		}
+	}
+	for _, key := range order {
+		d := found[key]
+		d.Message = fmt.Sprintf("[%s] %s", strings.Join(configs[key], ", "), d.Message)
+		d.SuggestedFixes = nil
+		reports[key.uri] = append(reports[key.uri], d)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// CallGraphAlgorithm is an algorithm that resolves the dynamic calls of a
+// call graph.
+type CallGraphAlgorithm string
+
+const (
+	// CHA resolves a dynamic call to every function or method of the
+	// program that the call could reach given its type.
+	CHA = CallGraphAlgorithm("cha")
+
+	// RTA resolves a dynamic call to the functions and methods of the types
+	// that are converted to interfaces in the functions reachable from the
+	// roots of the graph.
+	RTA = CallGraphAlgorithm("rta")
+)
+
+// CallGraphParams are the parameters of the gopls/callGraph request.
+type CallGraphParams struct {
+	// URI is the URI of a file in the package.
+	URI protocol.DocumentUri `json:"uri"`
+
+	// Function is the name of a function of the package, such as F or T.M.
+	// If set, the graph is restricted to the calls reachable from it, and
+	// it is the only root of RTA. Otherwise the graph has the calls made by
+	// the functions of the package.
+	Function string `json:"function,omitempty"`
+
+	// Algorithm is the algorithm of the graph, CHA if empty.
+	Algorithm CallGraphAlgorithm `json:"algorithm,omitempty"`
+}
+
+// CallGraph is a static call graph.
+type CallGraph struct {
+	Nodes []CallGraphNode `json:"nodes"`
+	Edges []CallGraphEdge `json:"edges"`
+}
+
+// CallGraphNode is a function of a CallGraph.
+type CallGraphNode struct {
+	// ID is the index of the node in the nodes of the graph.
+	ID int `json:"id"`
+
+	// Name is the name of the function, qualified by its package if it is
+	// not that of the graph, such as F, (*T).M, F$1 or fmt.Println.
+	Name string `json:"name"`
+
+	// Location is the position of the declaration of the function, if it
+	// belongs to the package of the graph.
+	Location *protocol.Location `json:"location,omitempty"`
+}
+
+// CallGraphEdge is a call of a CallGraph.
+type CallGraphEdge struct {
+	Caller int `json:"caller"`
+	Callee int `json:"callee"`
+
+	// Dynamic reports whether the call is a call of a function value or
+	// an interface method that the algorithm resolved to the callee.
+	Dynamic bool `json:"dynamic,omitempty"`
+
+	// Site is the position of the call.
+	Site *protocol.Location `json:"site,omitempty"`
+}
+
+// BuildCallGraph returns the call graph of the package of f, or of the function
+// named function in it, using the given algorithm.
+//
+// The program of the graph is built from the package as it is type-checked
+// for the view, and from the types of its dependencies, which are checked
+// without their function bodies: the calls that the functions of the
+// dependencies make are not in the graph.
+func BuildCallGraph(ctx context.Context, view View, f File, function string, algorithm CallGraphAlgorithm) (*CallGraph, error) {
+	ctx, done := trace.StartSpan(ctx, "source.BuildCallGraph")
+	defer done()
+
+	pkg, ssaPkg, funcs, err := ssaProgram(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	var root *ssa.Function
+	if function != "" {
+		for _, fn := range funcs {
+			if ssaFuncName(fn) == function {
+				root = fn
+			}
+		}
+		if root == nil {
+			return nil, errors.Errorf("no function %s in package %s", function, pkg.PkgPath())
+		}
+	}
+	fset := view.Session().Cache().FileSet()
+
+	var g *callgraph.Graph
+	switch algorithm {
+	case "", CHA:
+		g = cha.CallGraph(ssaPkg.Prog)
+	case RTA:
+		roots := funcs
+		if root != nil {
+			roots = []*ssa.Function{root}
+		}
+		if len(roots) == 0 {
+			return &CallGraph{Nodes: []CallGraphNode{}, Edges: []CallGraphEdge{}}, nil
+		}
+		g = rta.Analyze(roots, true).CallGraph
+	default:
+		return nil, errors.Errorf("unknown call graph algorithm %q", algorithm)
+	}
+	g.DeleteSyntheticNodes()
+
+	// Collect the edges of the functions of the package, or of those that
+	// the root reaches. An algorithm may record a call more than once.
+	type call struct {
+		site           ssa.CallInstruction
+		caller, callee *callgraph.Node
+	}
+	var edges []*callgraph.Edge
+	seenEdges := make(map[call]bool)
+	addEdges := func(n *callgraph.Node) {
+		for _, e := range n.Out {
+			if c := (call{e.Site, e.Caller, e.Callee}); !seenEdges[c] {
+				seenEdges[c] = true
+				edges = append(edges, e)
+			}
+		}
+	}
+	if root != nil {
+		seen := make(map[*callgraph.Node]bool)
+		queue := []*callgraph.Node{g.Nodes[root]}
+		for len(queue) > 0 {
+			n := queue[0]
+			queue = queue[1:]
+			if n == nil || seen[n] {
+				continue
+			}
+			seen[n] = true
+			addEdges(n)
+			for _, e := range n.Out {
+				queue = append(queue, e.Callee)
+			}
+		}
+	} else {
+		for fn, n := range g.Nodes {
+			if fn != nil && fn.Pkg == ssaPkg {
+				addEdges(n)
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		x, y := edges[i], edges[j]
+		if x.Caller != y.Caller {
+			return x.Caller.Func.String() < y.Caller.Func.String()
+		}
+		if x.Pos() != y.Pos() {
+			return x.Pos() < y.Pos()
+		}
+		return x.Callee.Func.String() < y.Callee.Func.String()
+	})
+
+	mappers := make(map[span.URI]*protocol.ColumnMapper)
+	for _, ph := range pkg.Files() {
+		if _, m, _, err := ph.Parse(ctx); err == nil {
+			mappers[ph.File().Identity().URI] = m
+		}
+	}
+	location := func(pos token.Pos) *protocol.Location {
+		if !pos.IsValid() {
+			return nil
+		}
+		spn, err := span.NewRange(fset, pos, pos).Span()
+		if err != nil {
+			return nil
+		}
+		m := mappers[spn.URI()]
+		if m == nil {
+			return nil
+		}
+		loc, err := m.Location(spn)
+		if err != nil {
+			return nil
+		}
+		return &loc
+	}
+
+	result := &CallGraph{
+		Nodes: []CallGraphNode{},
+		Edges: []CallGraphEdge{},
+	}
+	ids := make(map[*ssa.Function]int)
+	node := func(fn *ssa.Function) int {
+		if id, ok := ids[fn]; ok {
+			return id
+		}
+		id := len(result.Nodes)
+		ids[fn] = id
+		n := CallGraphNode{ID: id, Name: fn.RelString(pkg.GetTypes())}
+		if fn.Pkg == ssaPkg {
+			n.Location = location(fn.Pos())
+		}
+		result.Nodes = append(result.Nodes, n)
+		return id
+	}
+	if root != nil {
+		node(root)
+	}
+	for _, e := range edges {
+		edge := CallGraphEdge{
+			Caller: node(e.Caller.Func),
+			Callee: node(e.Callee.Func),
+		}
+		if e.Site != nil {
+			edge.Dynamic = e.Site.Common().StaticCallee() == nil
+			edge.Site = location(e.Site.Pos())
+		}
+		result.Edges = append(result.Edges, edge)
+	}
+	return result, nil
+}
+
+// ssaProgram builds the SSA program of the package of f, as it is type-checked
+// for the view, and of the types of its dependencies, which are checked without
+// their function bodies. It returns the package, its SSA package, and its
+// declared functions and methods in source order.
+func ssaProgram(ctx context.Context, view View, f File) (Package, *ssa.Package, []*ssa.Function, error) {
+	_, cphs, err := view.CheckPackageHandles(ctx, f)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cph, err := NarrowestCheckPackageHandle(cphs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pkg, err := cph.Check(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// The SSA builder assumes that the package is well-typed.
+	if pkg.IsIllTyped() {
+		return nil, nil, nil, errors.Errorf("package %s is ill-typed", pkg.PkgPath())
+	}
+	for _, e := range pkg.GetErrors() {
+		if e.Kind == ParseError || e.Kind == TypeError {
+			return nil, nil, nil, errors.Errorf("package %s has errors: %s", pkg.PkgPath(), e.Message)
+		}
+	}
+
+	prog := ssa.NewProgram(view.Session().Cache().FileSet(), 0)
+	createImports(prog, pkg.GetTypes().Imports(), make(map[*types.Package]bool))
+	ssaPkg := prog.CreatePackage(pkg.GetTypes(), pkg.GetSyntax(), pkg.GetTypesInfo(), false)
+	prog.Build()
+
+	var funcs []*ssa.Function
+	for fn := range ssautil.AllFunctions(prog) {
+		if fn.Pkg == ssaPkg && fn.Synthetic == "" && fn.Parent() == nil {
+			funcs = append(funcs, fn)
+		}
+	}
+	sort.Slice(funcs, func(i, j int) bool {
+		return funcs[i].Pos() < funcs[j].Pos()
+	})
+	return pkg, ssaPkg, funcs, nil
+}
+
+// createImports creates the SSA packages of the packages imports and their
+// dependencies from their types alone.
+func createImports(prog *ssa.Program, imports []*types.Package, seen map[*types.Package]bool) {
+	for _, imp := range imports {
+		if seen[imp] {
+			continue
+		}
+		seen[imp] = true
+		createImports(prog, imp.Imports(), seen)
+		prog.CreatePackage(imp, nil, nil, true)
+	}
+}
+
+// ssaFuncName returns the name of the function fn as it is written in its
+// package, such as F or T.M.
+func ssaFuncName(fn *ssa.Function) string {
+	if recv := fn.Signature.Recv(); recv != nil {
+		t := recv.Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		if named, ok := t.(*types.Named); ok {
+			return named.Obj().Name() + "." + fn.Name()
+		}
+	}
+	return fn.Name()
+}
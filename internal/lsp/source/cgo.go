@@ -0,0 +1,127 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	errors "golang.org/x/xerrors"
+)
+
+// The packages that use cgo are type-checked from the Go files that cgo
+// generates from their files, in the build cache. Before each line that it
+// copies from an original file, cgo writes a //line directive with its
+// original position, which the parser records in the token.File of the
+// generated file. The functions below use these directives to map the
+// positions of a generated file to those of the file the user edits, and
+// back. Any Go file whose //line directives refer to another Go file is
+// mapped in the same way.
+
+// CgoOriginalSpan returns the span of the original file that the positions
+// pos and end of a file generated by cgo were copied from. It reports false
+// if pos is not in a file generated by cgo, or if it has no //line directive.
+func CgoOriginalSpan(fset *token.FileSet, pos, end token.Pos) (span.Span, bool) {
+	if !pos.IsValid() {
+		return span.Span{}, false
+	}
+	raw := fset.PositionFor(pos, false)
+	start := fset.PositionFor(pos, true)
+	if start.Filename == raw.Filename || filepath.Ext(start.Filename) != ".go" {
+		return span.Span{}, false
+	}
+	stop := start
+	if end.IsValid() {
+		if p := fset.PositionFor(end, true); p.Filename == start.Filename && p.Line >= start.Line {
+			stop = p
+		}
+	}
+	return span.New(span.FileURI(start.Filename),
+		span.NewPoint(start.Line, start.Column, -1),
+		span.NewPoint(stop.Line, stop.Column, -1),
+	), true
+}
+
+// cgoOriginalURI returns the URI of the original file of ph, if it is a file
+// generated by cgo.
+func cgoOriginalURI(fset *token.FileSet, ph ParseGoHandle) (span.URI, bool) {
+	file, _, _, err := ph.Cached()
+	if err != nil || file.Name == nil {
+		return "", false
+	}
+	spn, ok := CgoOriginalSpan(fset, file.Name.Pos(), file.Name.End())
+	if !ok {
+		return "", false
+	}
+	return spn.URI(), true
+}
+
+// CgoOriginalMapper returns a mapper for the original file with the given URI
+// of a file generated by cgo. The original file is not parsed, so its
+// content is read from the file system of the view.
+func CgoOriginalMapper(ctx context.Context, view View, uri span.URI) (*protocol.ColumnMapper, error) {
+	content, _, err := view.Session().GetFile(uri, Go).Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.ColumnMapper{
+		URI:       uri,
+		Converter: span.NewContentConverter(uri.Filename(), content),
+		Content:   content,
+		Encoding:  view.Options().PositionEncoding,
+	}, nil
+}
+
+// cgoGeneratedPos returns the syntax tree of the file of pkg that cgo
+// generated from the file with the given URI, and the position in it of the
+// given position of the original file.
+func cgoGeneratedPos(ctx context.Context, view View, pkg Package, uri span.URI, pos protocol.Position) (*ast.File, token.Pos, error) {
+	m, err := CgoOriginalMapper(ctx, view, uri)
+	if err != nil {
+		return nil, token.NoPos, err
+	}
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return nil, token.NoPos, err
+	}
+	line, col := spn.Start().Line(), spn.Start().Column()
+
+	fset := view.Session().Cache().FileSet()
+	for _, ph := range pkg.Files() {
+		file, gm, _, err := ph.Cached()
+		if err != nil {
+			continue
+		}
+		tok := fset.File(file.Pos())
+		if tok == nil {
+			continue
+		}
+		if _, ok := cgoOriginalURI(fset, ph); !ok {
+			continue
+		}
+		// Find the offset of the generated file that was copied from the
+		// position, or else from the closest position before it on its line,
+		// as cgo rewrites the references to C.
+		best, bestColumn := -1, 0
+		for offset := 0; offset < len(gm.Content); offset++ {
+			p := tok.PositionFor(tok.Pos(offset), true)
+			if p.Line != line || p.Column > col || p.Column <= bestColumn || span.CompareURI(span.FileURI(p.Filename), uri) != 0 {
+				continue
+			}
+			best, bestColumn = offset, p.Column
+			if p.Column == col {
+				break
+			}
+		}
+		if best >= 0 {
+			return file, tok.Pos(best), nil
+		}
+	}
+	return nil, token.NoPos, errors.Errorf("no file generated by cgo from %s:%d:%d", uri, line, col)
+}
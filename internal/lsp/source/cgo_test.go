@@ -0,0 +1,58 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/span"
+)
+
+func TestCgoOriginalSpan(t *testing.T) {
+	const src = `// Code generated by cmd/cgo; DO NOT EDIT.
+
+//line /src/p/x.go:1:1
+package p
+
+import _ "unsafe"
+
+//line /src/p/x.go:7:1
+func f() int { return g() }
+`
+	fset := token.NewFileSet()
+	for _, test := range []struct {
+		filename string
+		text     string
+		line     int
+		col      int
+		ok       bool
+	}{
+		{"/cache/x.cgo1.go", "package", 1, 1, true},
+		{"/cache/x.cgo1.go", "g()", 7, 23, true},
+		{"/cache/_cgo_gotypes.go", "unsafe", 3, 11, true},
+		{"/cache/x.cgo1.go", "// Code", 0, 0, false},
+	} {
+		file, err := parser.ParseFile(fset, test.filename, src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tok := fset.File(file.Pos())
+		pos := tok.Pos(strings.Index(src, test.text))
+		spn, ok := CgoOriginalSpan(fset, pos, pos+token.Pos(len(test.text)))
+		if ok != test.ok {
+			t.Errorf("%s: %q: got ok %v, want %v", test.filename, test.text, ok, test.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if spn.URI() != span.FileURI("/src/p/x.go") || spn.Start().Line() != test.line || spn.Start().Column() != test.col || spn.End().Column() != test.col+len(test.text) {
+			t.Errorf("%q: got span %v, want /src/p/x.go:%d:%d", test.text, spn, test.line, test.col)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// CodeLens returns the code lenses for f.
+func CodeLens(ctx context.Context, view View, f File) ([]protocol.CodeLens, error) {
+	ctx, done := trace.StartSpan(ctx, "source.CodeLens")
+	defer done()
+
+	fh := view.Snapshot().Handle(ctx, f)
+	ph := view.Session().ParseGoHandle(fh, ParseFull)
+	file, m, _, err := ph.Parse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lenses, err := generateLenses(ctx, view, f, file, m)
+	if err != nil {
+		return nil, err
+	}
+	complexity, err := complexityLenses(ctx, view, file, m)
+	if err != nil {
+		return nil, err
+	}
+	lenses = append(lenses, complexity...)
+	if !strings.HasSuffix(f.URI().Filename(), "_test.go") {
+		return lenses, nil
+	}
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || !isFuzzTarget(fn) {
+			continue
+		}
+		rng, err := nodeToProtocolRange(ctx, view, m, fn.Name)
+		if err != nil {
+			return nil, err
+		}
+		lenses = append(lenses, protocol.CodeLens{
+			Range: rng,
+			Command: &protocol.Command{
+				Title:   "run fuzz test",
+				Command: "test",
+				Arguments: []interface{}{
+					TestArgs{
+						URI:  protocol.NewURI(f.URI()),
+						Fuzz: fn.Name.Name,
+					},
+				},
+			},
+		})
+	}
+	return lenses, nil
+}
+
+// isFuzzTarget reports whether fn is declared as a fuzz target,
+// that is, func FuzzXxx(*testing.F).
+func isFuzzTarget(fn *ast.FuncDecl) bool {
+	return isTestFunc(fn, "Fuzz", "F")
+}
+
+// isTestFunc reports whether fn is a function with the given name prefix
+// whose only parameter is a pointer to the named type from package testing,
+// such as func TestXxx(*testing.T).
+func isTestFunc(fn *ast.FuncDecl, prefix, typeName string) bool {
+	if fn.Recv != nil || !isTestName(fn.Name.Name, prefix) {
+		return false
+	}
+	if fn.Type.Results.NumFields() > 0 {
+		return false
+	}
+	params := fn.Type.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 {
+		return false
+	}
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	return ok && x.Name == "testing" && sel.Sel.Name == typeName
+}
+
+// isTestName reports whether name is a test function name with the given
+// prefix, such as "Test", "Benchmark", "Example", or "Fuzz". As in the go
+// command, the character after the prefix must not be a lower-case letter.
+func isTestName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	if len(name) == len(prefix) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return !unicode.IsLower(r)
+}
@@ -10,6 +10,7 @@ import (
 	"go/constant"
 	"go/token"
 	"go/types"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -259,13 +260,18 @@ func (c *completer) setSurrounding(ident *ast.Ident) {
 		},
 	}
 
-	if c.opts.FuzzyMatching {
-		c.matcher = fuzzy.NewMatcher(c.surrounding.Prefix())
-	} else if c.opts.CaseSensitive {
-		c.matcher = prefixMatcher(c.surrounding.Prefix())
-	} else {
-		c.matcher = insensitivePrefixMatcher(strings.ToLower(c.surrounding.Prefix()))
+	c.matcher = newMatcher(c.surrounding.Prefix(), c.opts)
+}
+
+// newMatcher returns the matcher of the candidates for prefix, as set by
+// the completion options.
+func newMatcher(prefix string, opts CompletionOptions) matcher {
+	if opts.FuzzyMatching {
+		return fuzzy.NewMatcher(prefix)
+	} else if opts.CaseSensitive {
+		return prefixMatcher(prefix)
 	}
+	return insensitivePrefixMatcher(strings.ToLower(prefix))
 }
 
 func (c *completer) getSurrounding() *Selection {
@@ -344,6 +350,9 @@ func (c *completer) found(obj types.Object, score float64, imp *imports.ImportIn
 		if !c.inDeepCompletion() || c.deepState.isHighScore(cand.score) {
 			if item, err := c.item(cand); err == nil {
 				c.items = append(c.items, item)
+				if d, ok := c.errorDestructuringItem(cand, item); ok {
+					c.items = append(c.items, d)
+				}
 			} else {
 				log.Error(c.ctx, "error generating completion item", err)
 			}
@@ -434,12 +443,29 @@ func Completion(ctx context.Context, view View, f File, pos protocol.Position, o
 	if path == nil {
 		return nil, nil, errors.Errorf("cannot find node enclosing position")
 	}
+	// Complete the paths of //go:embed directives.
+	if c := embedDirectiveAt(file, rng.Start); c != nil {
+		fset := view.Session().Cache().FileSet()
+		items, surrounding, err := embedCompletion(c, rng.Start, filepath.Dir(f.URI().Filename()), fset, m)
+		if err != nil {
+			return nil, nil, err
+		}
+		return items, surrounding, nil
+	}
 	// Skip completion inside comments.
 	for _, g := range file.Comments {
 		if g.Pos() <= rng.Start && rng.Start <= g.End() {
 			return nil, nil, nil
 		}
 	}
+	// Complete the keys and values of struct tags.
+	if lit, ok := path[0].(*ast.BasicLit); ok && len(path) > 1 {
+		if field, ok := path[1].(*ast.Field); ok && field.Tag == lit {
+			fset := view.Session().Cache().FileSet()
+			items, surrounding := structTagCompletion(field, rng.Start, view.Options().StructTagCase, fset, m)
+			return items, surrounding, nil
+		}
+	}
 	// Skip completion inside any kind of literal.
 	if _, ok := path[0].(*ast.BasicLit); ok {
 		return nil, nil, nil
@@ -498,6 +524,7 @@ func Completion(ctx context.Context, view View, f File, pos protocol.Position, o
 			if err := c.selector(sel); err != nil {
 				return nil, nil, err
 			}
+			c.postfix(sel)
 			return c.items, c.getSurrounding(), nil
 		}
 		// reject defining identifiers
@@ -541,6 +568,7 @@ func Completion(ctx context.Context, view View, f File, pos protocol.Position, o
 		if err := c.selector(n); err != nil {
 			return nil, nil, err
 		}
+		c.postfix(n)
 
 	default:
 		// fallback to lexical completions
@@ -561,6 +589,16 @@ func (c *completer) wantStructFieldCompletions() bool {
 	return clInfo.isStruct() && (clInfo.inKey || clInfo.maybeInFieldName)
 }
 
+// wantStructFieldValue reports whether the position is on the value side of
+// a key-value pair of a struct literal (e.g. "Foo{F: <>}").
+func (c *completer) wantStructFieldValue() bool {
+	clInfo := c.enclosingCompositeLiteral
+	if clInfo == nil {
+		return false
+	}
+	return clInfo.isStruct() && clInfo.kv != nil && !clInfo.inKey
+}
+
 func (c *completer) wantTypeName() bool {
 	return c.expectedType.typeName.wantTypeName
 }
@@ -712,6 +750,79 @@ func (c *completer) lexical() error {
 		}
 	}
 
+	if c.wantStructFieldValue() && c.expectedType.objType != nil {
+		// The value of a struct field is often its zero value or the
+		// result of a constructor of its type.
+		c.zeroValue(c.expectedType.objType)
+		c.constructors(c.expectedType.objType)
+	}
+
+	return nil
+}
+
+// constructors adds the functions of the package of the named type T, or of
+// the type T points to, that return a value of type T, such as "time.Now()"
+// for a time.Time. The functions of the current package are already found in
+// its scope, and deep completion finds those of imported packages through
+// the package name.
+func (c *completer) constructors(T types.Type) {
+	if c.opts.Deep {
+		return
+	}
+	named, ok := deref(T).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg() == c.pkg.GetTypes() {
+		return
+	}
+	pkgName := c.importedPkgName(named.Obj().Pkg())
+	if pkgName == nil {
+		return
+	}
+	c.deepState.push(pkgName, false)
+	defer c.deepState.pop()
+
+	scope := pkgName.Imported().Scope()
+	for _, name := range scope.Names() {
+		fn, ok := scope.Lookup(name).(*types.Func)
+		if !ok || !fn.Exported() {
+			continue
+		}
+		cand := candidate{obj: fn, score: stdScore}
+		if !c.matchingCandidate(&cand) || !cand.expandFuncCall {
+			continue
+		}
+		// Rank the constructors like the deep candidates they are.
+		cand.score *= highScore
+		cand.score -= cand.score * float64(len(c.deepState.chain)) / 10
+
+		cand.name = c.deepState.chainString(fn.Name())
+		matchScore := c.matcher.Score(cand.name)
+		if matchScore <= 0 {
+			continue
+		}
+		cand.score *= float64(matchScore)
+		if item, err := c.item(cand); err == nil {
+			c.items = append(c.items, item)
+		} else {
+			log.Error(c.ctx, "error generating completion item", err)
+		}
+	}
+}
+
+// importedPkgName returns the name under which the file imports pkg, or nil
+// if it does not import it.
+func (c *completer) importedPkgName(pkg *types.Package) *types.PkgName {
+	info := c.pkg.GetTypesInfo()
+	for _, imp := range c.file.Imports {
+		var obj types.Object
+		if imp.Name != nil {
+			obj = info.Defs[imp.Name]
+		} else {
+			obj = info.Implicits[imp]
+		}
+		if pkgName, ok := obj.(*types.PkgName); ok && pkgName.Imported() == pkg {
+			return pkgName
+		}
+	}
 	return nil
 }
 
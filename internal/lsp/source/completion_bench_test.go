@@ -0,0 +1,117 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source_test
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/cache"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/tests"
+	"golang.org/x/tools/internal/span"
+)
+
+var (
+	benchToolsDir      = flag.String("bench_tools_dir", "../../..", "the root of the x/tools repository, for completion benchmarks")
+	benchKubernetesDir = flag.String("bench_kubernetes_dir", "", "the root of a kubernetes repository, for completion benchmarks; the benchmark is skipped if it is empty")
+)
+
+// completionBenchmark describes a completion benchmark in a repository.
+// Each iteration edits file, which invalidates its package, and then
+// requests completion of the methods of typeName, which is declared in the
+// package of file.
+type completionBenchmark struct {
+	name     string
+	dir      *string
+	file     string
+	typeName string
+}
+
+var completionBenchmarks = []completionBenchmark{
+	{
+		name:     "tools",
+		dir:      benchToolsDir,
+		file:     "internal/lsp/source/completion.go",
+		typeName: "completer",
+	},
+	{
+		name:     "kubernetes",
+		dir:      benchKubernetesDir,
+		file:     "pkg/kubelet/kubelet.go",
+		typeName: "Kubelet",
+	},
+}
+
+// BenchmarkCompletionFollowingEdit measures the latency of completion
+// requested immediately after an edit to a file, as happens for each
+// keystroke while typing.
+func BenchmarkCompletionFollowingEdit(b *testing.B) {
+	for _, bench := range completionBenchmarks {
+		b.Run(bench.name, func(b *testing.B) {
+			if *bench.dir == "" {
+				b.Skipf("no directory provided for %s", bench.name)
+			}
+			benchmarkCompletionFollowingEdit(b, bench)
+		})
+	}
+}
+
+func benchmarkCompletionFollowingEdit(b *testing.B, bench completionBenchmark) {
+	ctx := context.Background()
+	dir, err := filepath.Abs(*bench.dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	filename := filepath.Join(dir, filepath.FromSlash(bench.file))
+	original, err := ioutil.ReadFile(filename)
+	if err != nil {
+		b.Fatal(err)
+	}
+	session := cache.New(nil).NewSession(ctx)
+	view := session.NewView(ctx, bench.name, span.FileURI(dir), tests.DefaultOptions())
+	uri := span.FileURI(filename)
+	if err := session.DidOpen(ctx, uri, source.Go, original); err != nil {
+		b.Fatal(err)
+	}
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		b.Fatal(err)
+	}
+	// Each edit changes a comment in a function appended to the file,
+	// and completion is requested on the line that follows it.
+	edit := func(i int) []byte {
+		return []byte(fmt.Sprintf("%s\nfunc _() {\n\tvar x *%s\n\t// edit %d\n\tx.\n}\n", original, bench.typeName, i))
+	}
+	pos := protocol.Position{
+		Line:      float64(strings.Count(string(original), "\n") + 4),
+		Character: 3,
+	}
+	complete := func(i int) {
+		if _, err := view.SetContent(ctx, uri, edit(i)); err != nil {
+			b.Fatal(err)
+		}
+		items, _, err := source.Completion(ctx, view, f, pos, tests.DefaultOptions().Completion)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(items) == 0 {
+			b.Fatalf("no completion items for %s", bench.typeName)
+		}
+	}
+	// Load the packages of the file before starting the timer.
+	complete(-1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		complete(i)
+	}
+}
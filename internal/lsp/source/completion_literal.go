@@ -340,6 +340,36 @@ func (c *completer) basicLiteral(T types.Type, typeName string, matchScore float
 	})
 }
 
+// zeroValue adds a completion item for the zero value of the numeric or
+// string type T. The zero values of the other types are found as the "false"
+// and "nil" builtins, or as composite literals.
+func (c *completer) zeroValue(T types.Type) {
+	basic, ok := T.Underlying().(*types.Basic)
+	if !ok {
+		return
+	}
+	var zero string
+	switch {
+	case basic.Info()&types.IsNumeric != 0:
+		zero = "0"
+	case basic.Info()&types.IsString != 0:
+		zero = `""`
+	default:
+		return
+	}
+	matchScore := c.matcher.Score(zero)
+	if matchScore <= 0 {
+		return
+	}
+	c.items = append(c.items, CompletionItem{
+		Label:      zero,
+		InsertText: zero,
+		Detail:     types.TypeString(T, c.qf),
+		Score:      float64(matchScore) * literalCandidateScore,
+		Kind:       protocol.VariableCompletion,
+	})
+}
+
 // makeCall adds a completion item for a "make()" call given a specific type.
 func (c *completer) makeCall(typeName string, secondArg string, matchScore float64) {
 	// Keep it simple and don't add any placeholders for optional "make()" arguments.
@@ -0,0 +1,220 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"golang.org/x/tools/internal/lsp/diff"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/snippet"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/log"
+	errors "golang.org/x/xerrors"
+)
+
+// A PostfixTemplate is a snippet that replaces an expression followed by a
+// "." and the label of the template, such as "err.wrap".
+//
+// The body of the template is a text/template executed with the expression.
+// {{.X}} is the text of the expression and {{.Type}} is its type.
+// {{.Placeholder "text"}} is a tab stop with the placeholder text, and
+// {{.Cursor}} is where the cursor ends up.
+type PostfixTemplate struct {
+	Label   string
+	Details string
+	Body    string
+
+	tmpl *template.Template
+}
+
+// builtinPostfixTemplates are the postfix templates available to all users.
+// The templates defined in the settings replace those with the same label.
+var builtinPostfixTemplates = []PostfixTemplate{
+	mustParsePostfixTemplate("var", "assign to a new variable", `{{.Placeholder "v"}} := {{.X}}`),
+	mustParsePostfixTemplate("return", "return the expression", `return {{.X}}`),
+}
+
+// ParsePostfixTemplate parses and validates the postfix template with the
+// given label, details and body.
+func ParsePostfixTemplate(label, details, body string) (PostfixTemplate, error) {
+	if !isIdentifier(label) {
+		return PostfixTemplate{}, errors.Errorf("invalid label %q of postfix template, want an identifier", label)
+	}
+	tmpl, err := template.New(label).Parse(body)
+	if err != nil {
+		return PostfixTemplate{}, errors.Errorf("invalid body of postfix template %q: %w", label, err)
+	}
+	t := PostfixTemplate{
+		Label:   label,
+		Details: details,
+		Body:    body,
+		tmpl:    tmpl,
+	}
+	// Expand the template once so that references to unknown fields and
+	// methods are reported now rather than at completion time.
+	if _, err := t.expand("x", "T", nil, false); err != nil {
+		return PostfixTemplate{}, errors.Errorf("invalid body of postfix template %q: %w", label, err)
+	}
+	return t, nil
+}
+
+func mustParsePostfixTemplate(label, details, body string) PostfixTemplate {
+	t, err := ParsePostfixTemplate(label, details, body)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// postfixTemplates returns the built-in postfix templates merged with those
+// of opts.
+func postfixTemplates(opts CompletionOptions) []PostfixTemplate {
+	var templates []PostfixTemplate
+	user := make(map[string]bool)
+	for _, t := range opts.PostfixTemplates {
+		user[t.Label] = true
+		templates = append(templates, t)
+	}
+	for _, t := range builtinPostfixTemplates {
+		if !user[t.Label] {
+			templates = append(templates, t)
+		}
+	}
+	return templates
+}
+
+// postfixData is the data with which the body of a postfix template is
+// executed.
+type postfixData struct {
+	// X is the text of the expression.
+	X string
+
+	// Type is the type of the expression.
+	Type string
+
+	// snip is the snippet being built, or nil if the template is expanded
+	// as plain text.
+	snip *snippet.Builder
+
+	// placeholders reports whether tab stops have placeholder text.
+	placeholders bool
+}
+
+// Placeholder writes a tab stop with the placeholder text s.
+func (d *postfixData) Placeholder(s string) string {
+	if d.snip == nil {
+		return s
+	}
+	d.snip.WritePlaceholder(func(b *snippet.Builder) {
+		if d.placeholders {
+			b.WriteText(s)
+		}
+	})
+	return ""
+}
+
+// Cursor marks where the cursor ends up.
+func (d *postfixData) Cursor() string {
+	if d.snip != nil {
+		d.snip.WriteFinalTabstop()
+	}
+	return ""
+}
+
+// snippetWriter writes the text of an executed template to a snippet.
+type snippetWriter struct {
+	snip *snippet.Builder
+}
+
+func (w snippetWriter) Write(p []byte) (int, error) {
+	w.snip.WriteText(string(p))
+	return len(p), nil
+}
+
+// expand returns the text of the template for the expression x of type typ,
+// and writes its snippet to snip if it is not nil.
+func (t PostfixTemplate) expand(x, typ string, snip *snippet.Builder, placeholders bool) (string, error) {
+	var text strings.Builder
+	if err := t.tmpl.Execute(&text, &postfixData{X: x, Type: typ}); err != nil {
+		return "", err
+	}
+	if snip != nil {
+		data := &postfixData{X: x, Type: typ, snip: snip, placeholders: placeholders}
+		if err := t.tmpl.Execute(snippetWriter{snip}, data); err != nil {
+			return "", err
+		}
+	}
+	return text.String(), nil
+}
+
+// postfix adds the postfix templates as candidates for the selector sel,
+// replacing the expression it selects from.
+func (c *completer) postfix(sel *ast.SelectorExpr) {
+	if !c.opts.Postfix {
+		return
+	}
+	tv, ok := c.pkg.GetTypesInfo().Types[sel.X]
+	if !ok || !tv.IsValue() || tv.Type == nil {
+		return
+	}
+	fset := c.view.Session().Cache().FileSet()
+	start, end := fset.Position(sel.X.Pos()).Offset, fset.Position(sel.X.End()).Offset
+	if start < 0 || end > len(c.mapper.Content) || start > end {
+		return
+	}
+	x := string(c.mapper.Content[start:end])
+	typ := types.TypeString(tv.Type, c.qf)
+
+	// Remove the expression and the "." before the selector.
+	spn, err := span.Range{FileSet: fset, Start: sel.X.Pos(), End: sel.Sel.Pos()}.Span()
+	if err != nil {
+		log.Error(c.ctx, "error making span for postfix completion", err)
+		return
+	}
+	edits, err := ToProtocolEdits(c.mapper, []diff.TextEdit{{Span: spn}})
+	if err != nil {
+		log.Error(c.ctx, "error making edit for postfix completion", err)
+		return
+	}
+	for _, t := range postfixTemplates(c.opts) {
+		matchScore := c.matcher.Score(t.Label)
+		if matchScore <= 0 {
+			continue
+		}
+		snip := &snippet.Builder{}
+		text, err := t.expand(x, typ, snip, c.opts.Placeholders)
+		if err != nil {
+			log.Error(c.ctx, "error expanding postfix template "+t.Label, err)
+			continue
+		}
+		c.items = append(c.items, CompletionItem{
+			Label:               t.Label,
+			InsertText:          text,
+			Detail:              t.Details,
+			Score:               float64(matchScore) * lowScore,
+			Kind:                protocol.SnippetCompletion,
+			AdditionalTextEdits: edits,
+			snippet:             snip,
+		})
+	}
+}
+
+// isIdentifier reports whether s is a Go identifier.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/snippet"
+)
+
+func TestParsePostfixTemplate(t *testing.T) {
+	for _, tt := range []struct {
+		label, body string
+		wantErr     bool
+	}{
+		{label: "wrap", body: `fmt.Errorf("{{.Placeholder "msg"}}: %w", {{.X}})`},
+		{label: "", body: `{{.X}}`, wantErr: true},
+		{label: "1st", body: `{{.X}}`, wantErr: true},
+		{label: "wrap", body: `{{.X`, wantErr: true},
+		{label: "wrap", body: `{{.Y}}`, wantErr: true},
+	} {
+		_, err := ParsePostfixTemplate(tt.label, "", tt.body)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePostfixTemplate(%q, %q) error = %v, want error %v", tt.label, tt.body, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExpandPostfixTemplate(t *testing.T) {
+	tmpl, err := ParsePostfixTemplate("wrap", "", `fmt.Errorf("{{.Placeholder "msg"}}: %w", {{.X}}){{.Cursor}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		placeholders bool
+		want         string
+	}{
+		{true, `fmt.Errorf("${1:msg}: %w", m[\}]{.err\})$0`},
+		{false, `fmt.Errorf("${1:}: %w", m[\}]{.err\})$0`},
+	} {
+		snip := &snippet.Builder{}
+		text, err := tmpl.expand("m[}]{.err}", "error", snip, tt.placeholders)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `fmt.Errorf("msg: %w", m[}]{.err})`; text != want {
+			t.Errorf("got text %q, want %q", text, want)
+		}
+		if got := snip.String(); got != tt.want {
+			t.Errorf("got snippet %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestMergePostfixTemplates(t *testing.T) {
+	user, err := ParsePostfixTemplate("var", "mine", `{{.X}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates := postfixTemplates(CompletionOptions{PostfixTemplates: []PostfixTemplate{user}})
+	if len(templates) != len(builtinPostfixTemplates) {
+		t.Fatalf("got %d templates, want %d", len(templates), len(builtinPostfixTemplates))
+	}
+	for _, tmpl := range templates {
+		if tmpl.Label == "var" && tmpl.Details != "mine" {
+			t.Errorf("the built-in var template was not replaced by that of the user")
+		}
+	}
+}
@@ -6,8 +6,13 @@ package source
 
 import (
 	"go/ast"
+	"go/types"
+	"strings"
 
+	"golang.org/x/tools/internal/lsp/diff"
+	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/snippet"
+	"golang.org/x/tools/internal/span"
 )
 
 // structFieldSnippets calculates the snippet for struct literal field names.
@@ -77,6 +82,13 @@ func (c *completer) functionCallSnippet(name string, params []string) *snippet.B
 		}
 	}
 	snip := &snippet.Builder{}
+	c.writeFunctionCall(snip, name, params)
+	return snip
+}
+
+// writeFunctionCall writes the call of the function name with the parameters
+// params to snip.
+func (c *completer) writeFunctionCall(snip *snippet.Builder, name string, params []string) {
 	snip.WriteText(name + "(")
 
 	if c.opts.Placeholders {
@@ -97,6 +109,159 @@ func (c *completer) functionCallSnippet(name string, params []string) *snippet.B
 	}
 
 	snip.WriteText(")")
+}
 
-	return snip
+// errorDestructuringItem returns a secondary completion item for the call of
+// a function that returns a value and an error, completed at the start of a
+// statement. It assigns the results of the call to new variables and checks
+// the error, turning "os.Op<>" into
+//
+//	f, err := os.Open(<*name string*>)
+//	if err != nil {
+//		return <zero values>, err
+//	}
+func (c *completer) errorDestructuringItem(cand candidate, item CompletionItem) (CompletionItem, bool) {
+	if !c.opts.ErrorDestructuring || !cand.expandFuncCall {
+		return CompletionItem{}, false
+	}
+	sig, ok := cand.obj.Type().Underlying().(*types.Signature)
+	if !ok || sig.Results().Len() != 2 || !isError(sig.Results().At(1).Type()) {
+		return CompletionItem{}, false
+	}
+	stmt := c.enclosingExprStmt()
+	if stmt == nil {
+		return CompletionItem{}, false
+	}
+
+	name := sig.Results().At(0).Name()
+	if name == "" || name == "_" || name == "err" {
+		name = "v"
+		if named, ok := deref(sig.Results().At(0).Type()).(*types.Named); ok {
+			if abbr := abbreviateCamel(named.Obj().Name()); abbr != "err" {
+				name = abbr
+			}
+		}
+	}
+	lhs := name + ", err := "
+
+	var (
+		snip   = &snippet.Builder{}
+		insert = cand.name + "()"
+		edits  []protocol.TextEdit
+	)
+	if stmt.Pos() == c.path[0].Pos() {
+		// The statement is the identifier being completed, so the new
+		// variable is part of the snippet.
+		snip.WritePlaceholder(func(b *snippet.Builder) {
+			b.WriteText(name)
+		})
+		snip.WriteText(", err := ")
+		insert = lhs + insert
+	} else {
+		// Insert the variables before the selector being completed.
+		fset := c.view.Session().Cache().FileSet()
+		spn, err := span.Range{FileSet: fset, Start: stmt.Pos(), End: stmt.Pos()}.Span()
+		if err != nil {
+			return CompletionItem{}, false
+		}
+		edits, err = ToProtocolEdits(c.mapper, []diff.TextEdit{{Span: spn, NewText: lhs}})
+		if err != nil {
+			return CompletionItem{}, false
+		}
+	}
+	c.writeFunctionCall(snip, cand.name, formatParams(sig.Params(), sig.Variadic(), c.qf))
+
+	ret := c.errorReturn()
+	snip.WriteText("\nif err != nil {\n\t")
+	if ret != "" {
+		snip.WriteText(ret)
+	} else {
+		snip.WriteFinalTabstop()
+	}
+	snip.WriteText("\n}")
+	insert += "\nif err != nil {\n\t" + ret + "\n}"
+
+	return CompletionItem{
+		Label:               item.Label,
+		InsertText:          insert,
+		Detail:              lhs + cand.name + "(...)",
+		Kind:                item.Kind,
+		Score:               item.Score * errorDestructuringScore,
+		Depth:               item.Depth,
+		AdditionalTextEdits: append(edits, item.AdditionalTextEdits...),
+		snippet:             snip,
+	}, true
+}
+
+// errorDestructuringScore scales down the score of the error destructuring
+// item of a call so that it follows the item of the call itself.
+const errorDestructuringScore = 0.99
+
+// enclosingExprStmt returns the expression statement that consists of the
+// identifier being completed, or of the selector it is the Sel of, if any.
+func (c *completer) enclosingExprStmt() *ast.ExprStmt {
+	ident, ok := c.path[0].(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	var expr ast.Expr = ident
+	i := 1
+	if i < len(c.path) {
+		if sel, ok := c.path[i].(*ast.SelectorExpr); ok && sel.Sel == ident {
+			expr = sel
+			i++
+		}
+	}
+	if i < len(c.path) {
+		if stmt, ok := c.path[i].(*ast.ExprStmt); ok && stmt.X == expr {
+			return stmt
+		}
+	}
+	return nil
+}
+
+// errorReturn returns the statement that returns err from the enclosing
+// function with the zero values of its other results, or "" if the function
+// does not return an error last.
+func (c *completer) errorReturn() string {
+	if c.enclosingFunc == nil {
+		return ""
+	}
+	results := c.enclosingFunc.sig.Results()
+	if results.Len() == 0 || !isError(results.At(results.Len()-1).Type()) {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("return ")
+	for i := 0; i < results.Len()-1; i++ {
+		b.WriteString(formatZeroValue(results.At(i).Type(), c.qf))
+		b.WriteString(", ")
+	}
+	b.WriteString("err")
+	return b.String()
+}
+
+// formatZeroValue returns the zero value of T in Go syntax.
+func formatZeroValue(T types.Type, qf types.Qualifier) string {
+	switch u := T.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsNumeric != 0:
+			return "0"
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		}
+		return "nil"
+	case *types.Struct, *types.Array:
+		return types.TypeString(T, qf) + "{}"
+	default:
+		return "nil"
+	}
+}
+
+// isError reports whether T is the error type.
+func isError(T types.Type) bool {
+	return types.Identical(T, types.Universe.Lookup("error").Type())
 }
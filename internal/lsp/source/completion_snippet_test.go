@@ -0,0 +1,35 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+func TestFormatZeroValue(t *testing.T) {
+	pkg := types.NewPackage("example.com/p", "p")
+	point := types.NewNamed(types.NewTypeName(token.NoPos, pkg, "Point", nil), types.NewStruct(nil, nil), nil)
+	qf := types.RelativeTo(pkg)
+	for _, tt := range []struct {
+		typ  types.Type
+		want string
+	}{
+		{types.Typ[types.Int], "0"},
+		{types.Typ[types.Float64], "0"},
+		{types.Typ[types.String], `""`},
+		{types.Typ[types.Bool], "false"},
+		{types.NewPointer(point), "nil"},
+		{types.NewSlice(types.Typ[types.Int]), "nil"},
+		{types.Universe.Lookup("error").Type(), "nil"},
+		{point, "Point{}"},
+		{types.NewArray(types.Typ[types.Int], 2), "[2]int{}"},
+	} {
+		if got := formatZeroValue(tt.typ, qf); got != tt.want {
+			t.Errorf("formatZeroValue(%v) = %q, want %q", tt.typ, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+)
+
+// complexityLenses returns the code lenses that show the cyclomatic
+// complexity and the number of statements of the functions of file that
+// reach one of the thresholds of the options of the view.
+func complexityLenses(ctx context.Context, view View, file *ast.File, m *protocol.ColumnMapper) ([]protocol.CodeLens, error) {
+	opts := view.Options()
+	if opts.ComplexityLensThreshold <= 0 && opts.SizeLensThreshold <= 0 {
+		return nil, nil
+	}
+	var lenses []protocol.CodeLens
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		complexity, size := cyclomaticComplexity(fn.Body), statementCount(fn.Body)
+		if (opts.ComplexityLensThreshold <= 0 || complexity < opts.ComplexityLensThreshold) &&
+			(opts.SizeLensThreshold <= 0 || size < opts.SizeLensThreshold) {
+			continue
+		}
+		rng, err := nodeToProtocolRange(ctx, view, m, fn.Name)
+		if err != nil {
+			return nil, err
+		}
+		lenses = append(lenses, protocol.CodeLens{
+			Range: rng,
+			// The lens only informs, so it has a title but no command.
+			Command: &protocol.Command{
+				Title: fmt.Sprintf("complexity %d, %d statements", complexity, size),
+			},
+		})
+	}
+	return lenses, nil
+}
+
+// cyclomaticComplexity returns the cyclomatic complexity of the function with
+// the given body: one more than the number of its decision points, which are
+// its if and for statements, its non-default case clauses, and its && and ||
+// operators, including those of its function literals.
+func cyclomaticComplexity(body *ast.BlockStmt) int {
+	complexity := 1
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// statementCount returns the number of statements in body, including those
+// of its function literals, but not the blocks that group statements, the
+// clauses of switch and select statements, or the labels of statements.
+func statementCount(body *ast.BlockStmt) int {
+	count := 0
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause, *ast.LabeledStmt, *ast.EmptyStmt:
+		case ast.Stmt:
+			count++
+		}
+		return true
+	})
+	return count
+}
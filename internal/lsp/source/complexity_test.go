@@ -0,0 +1,79 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestComplexity(t *testing.T) {
+	const src = `package a
+
+func empty() {}
+
+func straight(x int) int {
+	y := x + 1
+	return y
+}
+
+func branches(xs []int, ch chan int) int {
+	n := 0
+	for _, x := range xs {
+		if x > 0 && x < 10 || x == 100 {
+			n++
+		} else {
+			n--
+		}
+	}
+	switch {
+	case n > 0:
+		n = 1
+	default:
+		n = 0
+	}
+	select {
+	case v := <-ch:
+		n += v
+	default:
+	}
+	f := func() bool { return n == 0 || n == 1 }
+L:
+	for f() {
+		break L
+	}
+	return n
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "a.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name             string
+		complexity, size int
+	}{
+		{"empty", 1, 0},
+		{"straight", 1, 2},
+		// for, if, &&, ||, case, case, ||, for.
+		{"branches", 9, 16},
+	} {
+		var fn *ast.FuncDecl
+		for _, decl := range file.Decls {
+			if d, ok := decl.(*ast.FuncDecl); ok && d.Name.Name == test.name {
+				fn = d
+			}
+		}
+		if got := cyclomaticComplexity(fn.Body); got != test.complexity {
+			t.Errorf("%s: got complexity %d, want %d", test.name, got, test.complexity)
+		}
+		if got := statementCount(fn.Body); got != test.size {
+			t.Errorf("%s: got %d statements, want %d", test.name, got, test.size)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/cover"
+	"golang.org/x/tools/internal/lsp/diff/myers"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// FileCoverage is the test coverage of a file, as reported by
+// "go test -coverprofile".
+type FileCoverage struct {
+	URI span.URI
+
+	// Content is the content of the file on disk when the tests were run.
+	// The blocks refer to positions in this content.
+	Content []byte
+
+	Blocks []CoverageBlock
+}
+
+// CoverageBlock is a block of statements and whether it was executed.
+// Lines and columns are 1-based, and columns are measured in bytes.
+type CoverageBlock struct {
+	StartLine, StartCol int
+	EndLine, EndCol     int
+	Covered             bool
+}
+
+// CoverageRanges are the covered and uncovered ranges of a file. It is the
+// result of the "coverage" command and the gopls/coverage request.
+type CoverageRanges struct {
+	URI       protocol.DocumentUri `json:"uri"`
+	Covered   []protocol.Range     `json:"covered"`
+	Uncovered []protocol.Range     `json:"uncovered"`
+}
+
+// CoverageParams are the parameters of the gopls/coverage request.
+type CoverageParams struct {
+	// URI is the URI of the file whose coverage is returned.
+	URI protocol.DocumentUri `json:"uri"`
+}
+
+// RunCoverage runs the tests of the package containing args.URI with
+// "go test -coverprofile", and stores the resulting coverage of the files in
+// the package in the current snapshot of view.
+func RunCoverage(ctx context.Context, view View, args TestArgs) ([]*FileCoverage, error) {
+	ctx, done := trace.StartSpan(ctx, "source.RunCoverage")
+	defer done()
+
+	profile, err := ioutil.TempFile("", "gopls-coverage-")
+	if err != nil {
+		return nil, err
+	}
+	profile.Close()
+	defer os.Remove(profile.Name())
+
+	dir := filepath.Dir(span.NewURI(args.URI).Filename())
+	goArgs := goTestArgs(args)
+	// Insert the flag before the package pattern.
+	goArgs = append(goArgs[:len(goArgs)-1], "-coverprofile="+profile.Name(), goArgs[len(goArgs)-1])
+	out := &bytes.Buffer{}
+	if err := runGoTest(ctx, view, dir, goArgs, args, out); err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, errors.Errorf("%v\n%s", err, out)
+	}
+	profiles, err := cover.ParseProfiles(profile.Name())
+	if err != nil {
+		return nil, err
+	}
+	var result []*FileCoverage
+	for _, p := range profiles {
+		// Profiles name files by import path, and only cover the files of
+		// the package under test.
+		uri := span.FileURI(filepath.Join(dir, path.Base(p.FileName)))
+		content, _, err := view.Session().Cache().GetFile(uri, Go).Read(ctx)
+		if err != nil {
+			return nil, err
+		}
+		fc := &FileCoverage{
+			URI:     uri,
+			Content: content,
+		}
+		for _, b := range p.Blocks {
+			fc.Blocks = append(fc.Blocks, CoverageBlock{
+				StartLine: b.StartLine,
+				StartCol:  b.StartCol,
+				EndLine:   b.EndLine,
+				EndCol:    b.EndCol,
+				Covered:   b.Count > 0,
+			})
+		}
+		result = append(result, fc)
+	}
+	view.Snapshot().StoreCoverage(result)
+	return result, nil
+}
+
+// Coverage returns the coverage ranges of f, based on the coverage most
+// recently stored for it. If f has changed since the tests were run, blocks
+// that were not edited are moved to their new positions, and blocks that
+// were edited are omitted. It returns nil if there is no coverage for f.
+func Coverage(ctx context.Context, view View, f File) (*CoverageRanges, error) {
+	ctx, done := trace.StartSpan(ctx, "source.Coverage")
+	defer done()
+
+	snapshot := view.Snapshot()
+	fc := snapshot.Coverage(f.URI())
+	if fc == nil {
+		return nil, nil
+	}
+	content, _, err := snapshot.Handle(ctx, f).Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return coverageRanges(fc, content, view.Options().PositionEncoding)
+}
+
+// coverageRanges returns the ranges of the blocks of fc in content, in the
+// position encoding encoding.
+func coverageRanges(fc *FileCoverage, content []byte, encoding protocol.PositionEncodingKind) (*CoverageRanges, error) {
+	blocks := fc.Blocks
+	if !bytes.Equal(fc.Content, content) {
+		blocks = moveCoverageBlocks(fc, content)
+	}
+	m := &protocol.ColumnMapper{
+		URI:       fc.URI,
+		Converter: span.NewContentConverter(fc.URI.Filename(), content),
+		Content:   content,
+		Encoding:  encoding,
+	}
+	result := &CoverageRanges{
+		URI:       protocol.NewURI(fc.URI),
+		Covered:   []protocol.Range{},
+		Uncovered: []protocol.Range{},
+	}
+	for _, b := range blocks {
+		rng, err := m.Range(span.New(fc.URI, span.NewPoint(b.StartLine, b.StartCol, -1), span.NewPoint(b.EndLine, b.EndCol, -1)))
+		if err != nil {
+			return nil, err
+		}
+		if b.Covered {
+			result.Covered = append(result.Covered, rng)
+		} else {
+			result.Uncovered = append(result.Uncovered, rng)
+		}
+	}
+	return result, nil
+}
+
+// moveCoverageBlocks maps the blocks of fc through the changes from
+// fc.Content to content. The line-based diff never changes the columns of
+// the lines it keeps, so each block is either moved by whole lines or
+// dropped because one of its lines was edited.
+func moveCoverageBlocks(fc *FileCoverage, content []byte) []CoverageBlock {
+	edits := myers.ComputeEdits(fc.URI, string(fc.Content), string(content))
+	var result []CoverageBlock
+outer:
+	for _, b := range fc.Blocks {
+		delta := 0
+		for _, edit := range edits {
+			// The edit replaces the lines [start, end) with the lines of
+			// its new text.
+			start, end := edit.Span.Start().Line(), edit.Span.End().Line()
+			inserted := strings.Count(edit.NewText, "\n")
+			switch {
+			case end <= b.StartLine:
+				delta += inserted - (end - start)
+			case start > b.EndLine:
+			default:
+				continue outer
+			}
+		}
+		b.StartLine += delta
+		b.EndLine += delta
+		result = append(result, b)
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+)
+
+const coverageContent = `package a
+
+func f(x int) int {
+	if x > 0 {
+		return x
+	}
+	return -x
+}
+`
+
+var coverageBlocks = []CoverageBlock{
+	{StartLine: 3, StartCol: 19, EndLine: 4, EndCol: 11, Covered: true},
+	{StartLine: 4, StartCol: 11, EndLine: 6, EndCol: 3, Covered: true},
+	{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 11, Covered: false},
+}
+
+func TestMoveCoverageBlocks(t *testing.T) {
+	fc := &FileCoverage{
+		URI:     span.FileURI("/a/a.go"),
+		Content: []byte(coverageContent),
+		Blocks:  coverageBlocks,
+	}
+	for _, test := range []struct {
+		name    string
+		content string
+		want    []CoverageBlock
+	}{
+		{
+			name:    "unchanged",
+			content: coverageContent,
+			want:    coverageBlocks,
+		},
+		{
+			name: "inserted before",
+			content: `package a
+
+// f returns the absolute value of x.
+func f(x int) int {
+	if x > 0 {
+		return x
+	}
+	return -x
+}
+`,
+			want: []CoverageBlock{
+				{StartLine: 4, StartCol: 19, EndLine: 5, EndCol: 11, Covered: true},
+				{StartLine: 5, StartCol: 11, EndLine: 7, EndCol: 3, Covered: true},
+				{StartLine: 8, StartCol: 2, EndLine: 8, EndCol: 11, Covered: false},
+			},
+		},
+		{
+			name: "edited inside",
+			content: `package a
+
+func f(x int) int {
+	if x > 0 {
+		return x + 0
+	}
+	return -x
+}
+`,
+			want: []CoverageBlock{
+				{StartLine: 3, StartCol: 19, EndLine: 4, EndCol: 11, Covered: true},
+				{StartLine: 7, StartCol: 2, EndLine: 7, EndCol: 11, Covered: false},
+			},
+		},
+		{
+			name: "deleted before",
+			content: `package a
+func f(x int) int {
+	if x > 0 {
+		return x
+	}
+	return -x
+}
+`,
+			want: []CoverageBlock{
+				{StartLine: 2, StartCol: 19, EndLine: 3, EndCol: 11, Covered: true},
+				{StartLine: 3, StartCol: 11, EndLine: 5, EndCol: 3, Covered: true},
+				{StartLine: 6, StartCol: 2, EndLine: 6, EndCol: 11, Covered: false},
+			},
+		},
+	} {
+		got := moveCoverageBlocks(fc, []byte(test.content))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: moveCoverageBlocks() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCoverageRanges(t *testing.T) {
+	fc := &FileCoverage{
+		URI:     span.FileURI("/a/a.go"),
+		Content: []byte(coverageContent),
+		Blocks:  coverageBlocks,
+	}
+	got, err := coverageRanges(fc, []byte(coverageContent), protocol.UTF16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := func(startLine, startChar, endLine, endChar float64) protocol.Range {
+		return protocol.Range{
+			Start: protocol.Position{Line: startLine, Character: startChar},
+			End:   protocol.Position{Line: endLine, Character: endChar},
+		}
+	}
+	want := &CoverageRanges{
+		URI:       protocol.NewURI(fc.URI),
+		Covered:   []protocol.Range{rng(2, 18, 3, 10), rng(3, 10, 5, 2)},
+		Uncovered: []protocol.Range{rng(6, 1, 6, 10)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("coverageRanges() = %+v, want %+v", got, want)
+	}
+}
@@ -7,13 +7,16 @@ package source
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/internal/lsp/analysis/spellcheck"
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/telemetry"
 	"golang.org/x/tools/internal/span"
 	"golang.org/x/tools/internal/telemetry/log"
 	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
 )
 
 type Diagnostic struct {
@@ -45,6 +48,27 @@ func Diagnostics(ctx context.Context, view View, f File, disabledAnalyses map[st
 
 	snapshot, cphs, err := view.CheckPackageHandles(ctx, f)
 	if err != nil {
+		// A vendor directory that is out of sync with go.mod prevents
+		// loading any package of the module, which the user can fix.
+		var vendorErr *InconsistentVendoringError
+		if errors.As(err, &vendorErr) {
+			return vendorDiagnostics(f.URI(), vendorErr), "", nil
+		}
+		// Likewise for missing or mismatched go.sum entries, which are
+		// also reported on the require lines of go.mod.
+		var sumErr *GoSumError
+		if errors.As(err, &sumErr) {
+			return goSumErrorDiagnostics(ctx, view, f.URI(), sumErr), "", nil
+		}
+		// The file may only be built under the other configurations of
+		// the build matrix.
+		if len(view.Options().BuildMatrix) > 0 {
+			reports := make(map[span.URI][]Diagnostic)
+			matrixDiagnostics(ctx, view, f, reports)
+			if len(reports) > 0 {
+				return reports, "", nil
+			}
+		}
 		return nil, "", err
 	}
 	cph, err := WidestCheckPackageHandle(cphs)
@@ -71,6 +95,9 @@ func Diagnostics(ctx context.Context, view View, f File, disabledAnalyses map[st
 	reports := make(map[span.URI][]Diagnostic)
 	for _, fh := range pkg.Files() {
 		clearReports(view, reports, fh.File().Identity().URI)
+		if uri, ok := cgoOriginalURI(view.Session().Cache().FileSet(), fh); ok {
+			clearReports(view, reports, uri)
+		}
 	}
 
 	// Prepare any additional reports for the errors in this package.
@@ -81,12 +108,47 @@ func Diagnostics(ctx context.Context, view View, f File, disabledAnalyses map[st
 		clearReports(view, reports, err.URI)
 	}
 
+	// Report the go.sum entries that the dependencies of the package miss
+	// on the require lines of go.mod.
+	if err := goSumReports(ctx, view, f.URI(), reports); err != nil {
+		log.Error(ctx, "failed to report go.sum problems", err, telemetry.File.Of(f.URI()))
+	}
+
+	// Warn about the edits to generated files, which are often what causes
+	// the errors of the package.
+	if err := generatedDiagnostics(ctx, view, pkg, reports); err != nil {
+		log.Error(ctx, "failed to check generated files", err, telemetry.File.Of(f.URI()))
+	}
+
 	// Run diagnostics for the package that this URI belongs to.
 	if !diagnostics(ctx, view, pkg, reports) {
-		// If we don't have any list, parse, or type errors, run analyses.
-		if err := analyses(ctx, snapshot, cph, disabledAnalyses, reports); err != nil {
+		// If we don't have any list, parse, or type errors, run analyses,
+		// unless the client no longer needs the result.
+		if deadline, ok := protocol.SoftDeadline(ctx); ok && time.Now().After(deadline) {
+			log.Print(ctx, "skipping analyses past the deadline of the client", telemetry.File.Of(f.URI()))
+		} else if err := analyses(ctx, snapshot, cph, disabledAnalyses, reports); err != nil {
 			log.Error(ctx, "failed to run analyses", err, telemetry.File.Of(f.URI()))
 		}
+		if err := embedDiagnostics(ctx, view, pkg, reports); err != nil {
+			log.Error(ctx, "failed to check //go:embed directives", err, telemetry.File.Of(f.URI()))
+		}
+		if err := goVersionDiagnostics(ctx, view, pkg, reports); err != nil {
+			log.Error(ctx, "failed to check the go version", err, telemetry.File.Of(f.URI()))
+		}
+		if err := asmDiagnostics(ctx, view, pkg, reports); err != nil {
+			log.Error(ctx, "failed to check assembly implementations", err, telemetry.File.Of(f.URI()))
+		}
+		if err := generateDiagnostics(ctx, view, pkg, reports); err != nil {
+			log.Error(ctx, "failed to check //go:generate tools", err, telemetry.File.Of(f.URI()))
+		}
+		if view.Options().UnusedExports {
+			if err := unusedExports(ctx, snapshot, pkg, reports); err != nil {
+				log.Error(ctx, "failed to find unused exports", err, telemetry.File.Of(f.URI()))
+			}
+		}
+		if err := apiDiffDiagnostics(ctx, snapshot, pkg, reports); err != nil {
+			log.Error(ctx, "failed to report API changes", err, telemetry.File.Of(f.URI()))
+		}
 	}
 	// Updates to the diagnostics for this package may need to be propagated.
 	revDeps := view.GetActiveReverseDeps(ctx, f)
@@ -100,6 +162,9 @@ func Diagnostics(ctx context.Context, view View, f File, disabledAnalyses map[st
 		}
 		diagnostics(ctx, view, pkg, reports)
 	}
+	if len(view.Options().BuildMatrix) > 0 {
+		matrixDiagnostics(ctx, view, f, reports)
+	}
 	return reports, warningMsg, nil
 }
 
@@ -124,16 +189,14 @@ func diagnostics(ctx context.Context, view View, pkg Package, reports map[span.U
 			set = &diagnosticSet{}
 			diagSets[diag.URI] = set
 		}
+		diag.Source = errorSource(err.Kind)
 		switch err.Kind {
 		case ParseError:
 			set.parseErrors = append(set.parseErrors, diag)
-			diag.Source = "syntax"
 		case TypeError:
 			set.typeErrors = append(set.typeErrors, diag)
-			diag.Source = "compiler"
 		case ListError:
 			set.listErrors = append(set.listErrors, diag)
-			diag.Source = "go list"
 		}
 	}
 	var nonEmptyDiagnostics bool // track if we actually send non-empty diagnostics
@@ -157,9 +220,70 @@ func diagnostics(ctx context.Context, view View, pkg Package, reports map[span.U
 	return nonEmptyDiagnostics
 }
 
+// errorSource returns the source of the diagnostics for errors of the
+// given kind.
+func errorSource(kind ErrorKind) string {
+	switch kind {
+	case ParseError:
+		return "syntax"
+	case TypeError:
+		return "compiler"
+	case ListError:
+		return "go list"
+	}
+	return ""
+}
+
+type partialDiagnosticsKey struct{}
+
+// WithPartialDiagnostics returns a context in which the type-checking of a
+// package parsed in full calls publish with the diagnostics of each of its
+// files as soon as they are known, rather than once the whole package is
+// checked. The parse errors of a file are published once it is parsed. The
+// type errors found so far in a file without parse errors are published
+// whenever the type checker moves on to another file. The diagnostics
+// published for a file replace those published for it before, and are all
+// replaced by the diagnostics of the package once it is checked.
+func WithPartialDiagnostics(ctx context.Context, publish func(uri span.URI, diagnostics []Diagnostic)) context.Context {
+	return context.WithValue(ctx, partialDiagnosticsKey{}, publish)
+}
+
+// StreamingDiagnostics reports whether ctx was returned by
+// WithPartialDiagnostics.
+func StreamingDiagnostics(ctx context.Context) bool {
+	_, ok := ctx.Value(partialDiagnosticsKey{}).(func(span.URI, []Diagnostic))
+	return ok
+}
+
+// ReportPartialErrors publishes errs, the parse or type errors found so far
+// in the file uri of a package that is being type-checked, if ctx was
+// returned by WithPartialDiagnostics.
+func ReportPartialErrors(ctx context.Context, uri span.URI, errs []*Error) {
+	publish, ok := ctx.Value(partialDiagnosticsKey{}).(func(span.URI, []Diagnostic))
+	if !ok {
+		return
+	}
+	diagnostics := make([]Diagnostic, 0, len(errs))
+	for _, err := range errs {
+		diagnostics = append(diagnostics, Diagnostic{
+			URI:      err.URI,
+			Message:  err.Message,
+			Range:    err.Range,
+			Severity: protocol.SeverityError,
+			Source:   errorSource(err.Kind),
+		})
+	}
+	publish(uri, diagnostics)
+}
+
 func analyses(ctx context.Context, snapshot Snapshot, cph CheckPackageHandle, disabledAnalyses map[string]struct{}, reports map[span.URI][]Diagnostic) error {
+	options := snapshot.View().Options()
+	all := options.Analyzers
+	if options.SpellCheck {
+		all = append(all[:len(all):len(all)], spellcheck.Analyzer)
+	}
 	var analyzers []*analysis.Analyzer
-	for _, a := range snapshot.View().Options().Analyzers {
+	for _, a := range all {
 		if _, ok := disabledAnalyses[a.Name]; ok {
 			continue
 		}
@@ -170,9 +294,18 @@ func analyses(ctx context.Context, snapshot Snapshot, cph CheckPackageHandle, di
 	if err != nil {
 		return err
 	}
+	pkg, err := cph.Check(ctx)
+	if err != nil {
+		return err
+	}
+	fset := snapshot.View().Session().Cache().FileSet()
+	sups := make(map[span.URI]suppressions)
 
 	// Report diagnostics and errors from root analyzers.
 	for _, e := range diagnostics {
+		if suppressedDiagnostic(ctx, fset, pkg, sups, e) {
+			continue
+		}
 		// This is a bit of a hack, but clients > 3.15 will be able to grey out unnecessary code.
 		// If we are deleting code as part of all of our suggested fixes, assume that this is dead code.
 		// TODO(golang/go/#34508): Return these codes from the diagnostics themselves.
@@ -180,12 +313,17 @@ func analyses(ctx context.Context, snapshot Snapshot, cph CheckPackageHandle, di
 		if onlyDeletions(e.SuggestedFixes) {
 			tags = append(tags, protocol.Unnecessary)
 		}
+		// Misspellings are not problems of the code.
+		severity := protocol.SeverityWarning
+		if e.Category == SpellCheckSource {
+			severity = protocol.SeverityHint
+		}
 		addReport(snapshot.View(), reports, Diagnostic{
 			URI:            e.URI,
 			Range:          e.Range,
 			Message:        e.Message,
 			Source:         e.Category,
-			Severity:       protocol.SeverityWarning,
+			Severity:       severity,
 			Tags:           tags,
 			SuggestedFixes: e.SuggestedFixes,
 			Related:        e.Related,
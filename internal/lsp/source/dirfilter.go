@@ -0,0 +1,195 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	errors "golang.org/x/xerrors"
+)
+
+// DirectoryFilter decides which directories of the folder of a view are
+// excluded from the view: their packages are not loaded, their files are not
+// watched, and their symbols are not workspace symbols.
+//
+// The rules of the filter come from the ignore files at the root of the
+// folder, such as .gitignore, followed by the DirectoryFilters option. The
+// last rule that matches a directory or one of its ancestors decides whether
+// it is excluded, so that the directories of an excluded directory are
+// excluded too, unless a later rule includes them again.
+type DirectoryFilter struct {
+	folder string
+	rules  []filterRule
+}
+
+// filterRule is a rule of a DirectoryFilter.
+type filterRule struct {
+	// pattern is the slash-separated pattern of the rule. If anchored is
+	// set, it matches the path of a directory relative to the folder, and
+	// otherwise the name of a directory.
+	pattern  string
+	anchored bool
+
+	// include is set for the rules that include the directories they match
+	// again, written with a "!" or "+" prefix.
+	include bool
+}
+
+// NewDirectoryFilter returns the directory filter of folder for the given
+// options. It reads the ignore files of options.IgnoreFiles at the root of
+// folder, skipping those that do not exist. An invalid directory filter is
+// skipped too, and the first one is returned as the error along with the
+// filter of the other rules.
+func NewDirectoryFilter(folder string, options Options) (*DirectoryFilter, error) {
+	f := &DirectoryFilter{folder: folder}
+	var firstErr error
+	for _, name := range options.IgnoreFiles {
+		data, err := ioutil.ReadFile(filepath.Join(folder, name))
+		if err != nil {
+			continue
+		}
+		f.rules = append(f.rules, parseIgnoreFile(data)...)
+	}
+	for _, filter := range options.DirectoryFilters {
+		rule, err := parseDirectoryFilter(filter)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		f.rules = append(f.rules, rule)
+	}
+	return f, firstErr
+}
+
+// parseDirectoryFilter parses an entry of the DirectoryFilters option: a
+// path relative to the folder of the view, which may contain glob patterns
+// and "**" for any number of directories, prefixed with "-" to exclude the
+// directories it matches or "+" to include them again.
+func parseDirectoryFilter(filter string) (filterRule, error) {
+	if len(filter) < 2 || filter[0] != '-' && filter[0] != '+' {
+		return filterRule{}, errors.Errorf("invalid directory filter %q: it must be a path prefixed with - or +", filter)
+	}
+	pattern := strings.Trim(filepath.ToSlash(filter[1:]), "/")
+	if pattern == "" {
+		return filterRule{}, errors.Errorf("invalid directory filter %q: it must be a path prefixed with - or +", filter)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return filterRule{}, errors.Errorf("invalid directory filter %q: %v", filter, err)
+	}
+	return filterRule{pattern: pattern, anchored: true, include: filter[0] == '+'}, nil
+}
+
+// parseIgnoreFile returns the rules of the lines of an ignore file, in the
+// syntax of .gitignore. Only directories are filtered, so a rule for files,
+// such as "*.pb.go", has no effect unless a directory matches it.
+func parseIgnoreFile(data []byte) []filterRule {
+	var rules []filterRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var rule filterRule
+		if line[0] == '!' {
+			rule.include = true
+			line = line[1:]
+		}
+		line = strings.TrimSuffix(line, "/")
+		if strings.HasPrefix(line, "**/") {
+			line = line[len("**/"):]
+		} else if strings.Contains(line, "/") {
+			rule.anchored = true
+		}
+		rule.pattern = strings.TrimPrefix(line, "/")
+		if rule.pattern == "" {
+			continue
+		}
+		if _, err := path.Match(rule.pattern, ""); err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Excluded reports whether the directory dir is excluded by the filter. A
+// nil filter excludes no directory, and directories outside of the folder
+// are never excluded.
+func (f *DirectoryFilter) Excluded(dir string) bool {
+	if f == nil || len(f.rules) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(f.folder, dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	var dirs []string
+	for i := 0; i <= len(rel); i++ {
+		if i == len(rel) || rel[i] == '/' {
+			dirs = append(dirs, rel[:i])
+		}
+	}
+	for i := len(f.rules) - 1; i >= 0; i-- {
+		rule := f.rules[i]
+		for _, d := range dirs {
+			if rule.match(d) {
+				return !rule.include
+			}
+		}
+	}
+	return false
+}
+
+// ExcludedFile reports whether the file named filename is in a directory
+// excluded by the filter.
+func (f *DirectoryFilter) ExcludedFile(filename string) bool {
+	return f.Excluded(filepath.Dir(filename))
+}
+
+// match reports whether the rule matches the directory of the relative
+// path rel, regardless of its ancestors.
+func (r filterRule) match(rel string) bool {
+	if r.anchored {
+		return matchPath(r.pattern, rel)
+	}
+	ok, _ := path.Match(r.pattern, path.Base(rel))
+	return ok
+}
+
+// matchPath reports whether the slash-separated path name matches pattern,
+// in which an element "**" matches any number of elements.
+func matchPath(pattern, name string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchElems(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchElems(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirectoryFilter(t *testing.T) {
+	folder, err := ioutil.TempDir("", "gopls-dirfilter-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	for name, content := range map[string]string{
+		".gitignore": `# build output
+/bin/
+node_modules
+*.pb.go
+gen/**/testdata
+!keep
+`,
+		".goplsignore": "third_party/\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(folder, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var options Options
+	options.IgnoreFiles = []string{".gitignore", ".goplsignore", ".missingignore"}
+	options.DirectoryFilters = []string{"-internal/big", "+third_party/ours", "-**/old"}
+	filter, err := NewDirectoryFilter(folder, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		dir  string
+		want bool
+	}{
+		{"", false},
+		{"bin", true},
+		{"bin/tools", true},
+		{"cmd/bin", false},
+		{"node_modules", true},
+		{"web/node_modules/x", true},
+		{"gen/testdata", true},
+		{"gen/a/b/testdata", true},
+		{"testdata", false},
+		{"third_party", true},
+		{"third_party/ours", false},
+		{"internal/big", true},
+		{"internal/big/sub", true},
+		{"internal/bigger", false},
+		{"a/b/old", true},
+		{"old", true},
+		{"keep", false},
+		{"../other", false},
+	} {
+		dir := filepath.Join(folder, filepath.FromSlash(test.dir))
+		if got := filter.Excluded(dir); got != test.want {
+			t.Errorf("Excluded(%q) = %v, want %v", test.dir, got, test.want)
+		}
+	}
+	if !filter.ExcludedFile(filepath.Join(folder, "bin", "main.go")) {
+		t.Errorf("ExcludedFile(bin/main.go) = false, want true")
+	}
+	if filter.ExcludedFile(filepath.Join(folder, "main.go")) {
+		t.Errorf("ExcludedFile(main.go) = true, want false")
+	}
+
+	var nilFilter *DirectoryFilter
+	if nilFilter.Excluded(filepath.Join(folder, "bin")) {
+		t.Errorf("a nil filter excludes bin")
+	}
+}
+
+func TestDirectoryFilterInvalid(t *testing.T) {
+	folder, err := ioutil.TempDir("", "gopls-dirfilter-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+	if err := ioutil.WriteFile(filepath.Join(folder, ".gitignore"), []byte("/bin/\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rules of the ignore files and of the valid filters still apply
+	// when a filter is invalid.
+	options := DefaultOptions
+	options.DirectoryFilters = []string{"-[a", "-node_modules"}
+	filter, err := NewDirectoryFilter(folder, options)
+	if err == nil {
+		t.Error("NewDirectoryFilter succeeded with an invalid filter, want an error")
+	}
+	for _, dir := range []string{"bin", "node_modules"} {
+		if !filter.Excluded(filepath.Join(folder, dir)) {
+			t.Errorf("Excluded(%q) = false, want true", dir)
+		}
+	}
+}
+
+func TestParseDirectoryFilter(t *testing.T) {
+	for _, filter := range []string{"-a", "+a/b", "-**/c", "-/d/"} {
+		if _, err := parseDirectoryFilter(filter); err != nil {
+			t.Errorf("parseDirectoryFilter(%q) failed: %v", filter, err)
+		}
+	}
+	for _, filter := range []string{"", "-", "a", "-/", "-[a"} {
+		if _, err := parseDirectoryFilter(filter); err == nil {
+			t.Errorf("parseDirectoryFilter(%q) succeeded, want an error", filter)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	errors "golang.org/x/xerrors"
+)
+
+// AddDocCommentTitle is the title of the code action that adds a doc comment
+// to an exported declaration.
+const AddDocCommentTitle = "Add doc comment"
+
+// AddDocComment returns a fix that adds the skeleton of a doc comment to the
+// exported declaration at the start of rng in f, or nil if there is no
+// exported declaration without a doc comment there. The comment starts with
+// the name of the declaration and, for a function, has a placeholder for the
+// description of each of its named parameters.
+func AddDocComment(ctx context.Context, view View, f File, rng protocol.Range) (*SuggestedFix, error) {
+	fh := view.Snapshot().Handle(ctx, f)
+	ph := view.Session().ParseGoHandle(fh, ParseFull)
+	file, m, _, err := ph.Parse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	spn, err := m.RangeSpan(rng)
+	if err != nil {
+		return nil, err
+	}
+	r, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, err
+	}
+	node, text := undocumentedDecl(file, r.Start)
+	if node == nil {
+		return nil, nil
+	}
+	tok := view.Session().Cache().FileSet().File(node.Pos())
+	if tok == nil {
+		return nil, errors.Errorf("no file for %s", f.URI())
+	}
+	line := tok.Line(node.Pos())
+	offset := tok.Offset(tok.LineStart(line))
+	content := m.Content[offset:]
+	indent := content[:len(content)-len(bytes.TrimLeft(content, " \t"))]
+
+	var b strings.Builder
+	for _, l := range text {
+		b.Write(indent)
+		if l == "" {
+			b.WriteString("//\n")
+		} else {
+			b.WriteString("// " + l + "\n")
+		}
+	}
+	start, err := m.Position(span.NewPoint(line, 1, offset))
+	if err != nil {
+		return nil, err
+	}
+	return &SuggestedFix{
+		Title: AddDocCommentTitle,
+		Edits: map[span.URI][]protocol.TextEdit{
+			f.URI(): {{
+				Range:   protocol.Range{Start: start, End: start},
+				NewText: b.String(),
+			}},
+		},
+	}, nil
+}
+
+// undocumentedDecl returns the exported declaration of file at pos that has
+// no doc comment, along with the lines of the skeleton of its doc comment.
+// The declaration is a function, or the only specification of a declaration,
+// or a specification of a group of declarations.
+func undocumentedDecl(file *ast.File, pos token.Pos) (ast.Node, []string) {
+	for _, decl := range file.Decls {
+		if pos < decl.Pos() || pos > decl.End() {
+			continue
+		}
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			// Only the signature of a function is offered the action, not
+			// every line of its body.
+			if decl.Doc != nil || !decl.Name.IsExported() || decl.Body != nil && pos > decl.Body.Lbrace {
+				return nil, nil
+			}
+			if decl.Recv != nil && (len(decl.Recv.List) == 0 || !exportedType(decl.Recv.List[0].Type)) {
+				return nil, nil
+			}
+			text := []string{decl.Name.Name + " ..."}
+			var params []string
+			for _, field := range decl.Type.Params.List {
+				for _, name := range field.Names {
+					if name.Name != "_" {
+						params = append(params, "  - "+name.Name+" ...")
+					}
+				}
+			}
+			if len(params) > 0 {
+				text = append(append(text, ""), params...)
+			}
+			return decl, text
+		case *ast.GenDecl:
+			if decl.Doc != nil && (!decl.Lparen.IsValid() || len(decl.Specs) == 1) {
+				return nil, nil
+			}
+			for _, spec := range decl.Specs {
+				if pos < spec.Pos() || pos > spec.End() {
+					continue
+				}
+				// The comment of the only specification of a declaration
+				// goes above the keyword.
+				var node ast.Node = spec
+				if !decl.Lparen.IsValid() {
+					node = decl
+				}
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Doc == nil && spec.Name.IsExported() {
+						return node, []string{spec.Name.Name + " ..."}
+					}
+				case *ast.ValueSpec:
+					if spec.Doc == nil && len(spec.Names) == 1 && spec.Names[0].IsExported() {
+						return node, []string{spec.Names[0].Name + " ..."}
+					}
+				}
+			}
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// exportedType reports whether the type of a method receiver is exported.
+func exportedType(typ ast.Expr) bool {
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+		case *ast.ParenExpr:
+			typ = t.X
+		case *ast.Ident:
+			return t.IsExported()
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUndocumentedDecl(t *testing.T) {
+	const src = `package a
+
+func Read(ctx context.Context, name string, _ int) error {
+	return nil
+}
+
+// Write writes.
+func Write() {}
+
+func (r *reader) Close() {}
+
+type (
+	Option int
+	// Mode is documented.
+	Mode int
+)
+
+const Max = 10
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "a.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := fset.File(file.Pos())
+	for _, test := range []struct {
+		at   string
+		want []string
+	}{
+		{"Read(", []string{"Read ...", "", "  - ctx ...", "  - name ..."}},
+		{"return nil", nil},
+		{"Write()", nil},
+		{"Close()", nil},
+		{"Option int", []string{"Option ..."}},
+		{"Mode int", nil},
+		{"Max =", []string{"Max ..."}},
+	} {
+		pos := tok.Pos(strings.Index(src, test.at))
+		_, got := undocumentedDecl(file, pos)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("at %q: got %q, want %q", test.at, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// DynamicTypes returns the locations of the declarations of the named types
+// that may be the dynamic type of the interface-typed variable at pos in f.
+//
+// The types are found by rapid type analysis from the functions of the package
+// of f: they are the types that implement the interface of the variable, and
+// that the functions reachable from those of the package convert to an
+// interface. As the program is built without the function bodies of the
+// dependencies, the conversions that they make are not found, so the result
+// is neither sound nor complete, but is bounded by the size of the package.
+func DynamicTypes(ctx context.Context, view View, f File, pos protocol.Position) ([]protocol.Location, error) {
+	ctx, done := trace.StartSpan(ctx, "source.DynamicTypes")
+	defer done()
+
+	pkg, _, funcs, err := ssaProgram(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	ph, err := pkg.File(f.URI())
+	if err != nil {
+		return nil, err
+	}
+	file, m, _, err := ph.Cached()
+	if err != nil {
+		return nil, err
+	}
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return nil, err
+	}
+	rng, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, err
+	}
+	path, _ := astutil.PathEnclosingInterval(file, rng.Start, rng.Start)
+	if len(path) == 0 {
+		return nil, errors.Errorf("no identifier found")
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return nil, errors.Errorf("no identifier found")
+	}
+	v, ok := pkg.GetTypesInfo().ObjectOf(id).(*types.Var)
+	if !ok {
+		return nil, errors.Errorf("%s is not a variable", id.Name)
+	}
+	iface, ok := v.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, errors.Errorf("%s is not of an interface type", id.Name)
+	}
+
+	// The roots of the analysis are not among the functions it reaches.
+	reachable := append([]*ssa.Function{}, funcs...)
+	if len(funcs) > 0 {
+		for fn := range rta.Analyze(funcs, false).Reachable {
+			reachable = append(reachable, fn)
+		}
+	}
+	seen := make(map[*types.TypeName]bool)
+	var names []*types.TypeName
+	for _, fn := range reachable {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				mi, ok := instr.(*ssa.MakeInterface)
+				if !ok {
+					continue
+				}
+				T := mi.X.Type()
+				if types.IsInterface(T) || !types.Implements(T, iface) {
+					continue
+				}
+				if ptr, ok := T.(*types.Pointer); ok {
+					T = ptr.Elem()
+				}
+				named, ok := T.(*types.Named)
+				if !ok || seen[named.Obj()] {
+					continue
+				}
+				seen[named.Obj()] = true
+				names = append(names, named.Obj())
+			}
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		x, y := names[i], names[j]
+		// The only named types of the universe are interfaces.
+		if x.Pkg() != y.Pkg() {
+			return x.Pkg().Path() < y.Pkg().Path()
+		}
+		return x.Name() < y.Name()
+	})
+
+	locations := []protocol.Location{}
+	for _, obj := range names {
+		if !obj.Pos().IsValid() {
+			continue
+		}
+		mrng, err := objToMappedRange(ctx, pkg, obj)
+		if err != nil {
+			continue
+		}
+		rng, err := mrng.Range()
+		if err != nil {
+			continue
+		}
+		locations = append(locations, protocol.Location{
+			URI:   protocol.NewURI(mrng.URI()),
+			Range: rng,
+		})
+	}
+	return locations, nil
+}
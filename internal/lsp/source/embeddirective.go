@@ -0,0 +1,324 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// embedDirective is the prefix of the comments that embed files in a
+// variable.
+const embedDirective = "//go:embed"
+
+// embedPattern is a pattern of a //go:embed directive.
+type embedPattern struct {
+	pattern string
+
+	// pos and end delimit the pattern in the directive, including its
+	// quotes, if any.
+	pos, end token.Pos
+}
+
+// isEmbedDirective reports whether c is a //go:embed directive.
+func isEmbedDirective(c *ast.Comment) bool {
+	if !strings.HasPrefix(c.Text, embedDirective) {
+		return false
+	}
+	rest := c.Text[len(embedDirective):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t'
+}
+
+// embedPatterns returns the patterns of the //go:embed directive c. Like the
+// go command, it accepts patterns that are separated by spaces, and patterns
+// quoted as Go strings.
+func embedPatterns(c *ast.Comment) ([]embedPattern, error) {
+	var (
+		text     = c.Text
+		patterns []embedPattern
+	)
+	for i := len(embedDirective); ; {
+		for i < len(text) && (text[i] == ' ' || text[i] == '\t') {
+			i++
+		}
+		if i == len(text) {
+			return patterns, nil
+		}
+		start := i
+		var pattern string
+		switch quote := text[i]; quote {
+		case '"', '`':
+			i++
+			for i < len(text) && text[i] != quote {
+				if quote == '"' && text[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(text) {
+				return nil, errors.Errorf("invalid quoted string in //go:embed: %s", text[start:])
+			}
+			i++
+			var err error
+			if pattern, err = strconv.Unquote(text[start:i]); err != nil {
+				return nil, errors.Errorf("invalid quoted string in //go:embed: %s", text[start:i])
+			}
+		default:
+			for i < len(text) && text[i] != ' ' && text[i] != '\t' {
+				i++
+			}
+			pattern = text[start:i]
+		}
+		patterns = append(patterns, embedPattern{
+			pattern: pattern,
+			pos:     c.Slash + token.Pos(start),
+			end:     c.Slash + token.Pos(i),
+		})
+	}
+}
+
+// embedMatches returns the files and directories in dir that pattern
+// matches, or an error that explains why it cannot be embedded.
+func embedMatches(dir, pattern string) ([]string, error) {
+	if !validEmbedPattern(pattern) {
+		return nil, errors.Errorf("pattern %s: invalid pattern syntax", pattern)
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+	if err != nil {
+		return nil, errors.Errorf("pattern %s: %v", pattern, err)
+	}
+	if len(matches) == 0 {
+		return nil, errors.Errorf("pattern %s: no matching files found", pattern)
+	}
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, errors.Errorf("pattern %s: %v", pattern, err)
+		}
+		if info.IsDir() && !hasEmbeddableFiles(match) {
+			return nil, errors.Errorf("pattern %s: cannot embed directory %s: contains no embeddable files", pattern, filepath.Base(match))
+		}
+	}
+	return matches, nil
+}
+
+// validEmbedPattern reports whether pattern is a valid path.Match pattern
+// for files within the directory of the package: an unrooted, slash-separated
+// path with no "." or ".." elements and no empty ones.
+func validEmbedPattern(pattern string) bool {
+	if pattern == "" || strings.HasPrefix(pattern, "/") {
+		return false
+	}
+	for _, elem := range strings.Split(pattern, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+	}
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
+
+// hasEmbeddableFiles reports whether the directory dir contains a file, at
+// any depth, that embedding the directory includes: one whose path has no
+// element that starts with '.' or '_'.
+func hasEmbeddableFiles(dir string) bool {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), ".") || strings.HasPrefix(info.Name(), "_") {
+			continue
+		}
+		if !info.IsDir() || hasEmbeddableFiles(filepath.Join(dir, info.Name())) {
+			return true
+		}
+	}
+	return false
+}
+
+// embedDiagnostics reports the patterns of the //go:embed directives of pkg
+// that cannot be embedded, such as those that match no files.
+func embedDiagnostics(ctx context.Context, view View, pkg Package, reports map[span.URI][]Diagnostic) error {
+	ctx, done := trace.StartSpan(ctx, "source.embedDiagnostics")
+	defer done()
+
+	fset := view.Session().Cache().FileSet()
+	for _, file := range pkg.GetSyntax() {
+		dir := filepath.Dir(fset.Position(file.Pos()).Filename)
+		for _, group := range file.Comments {
+			for _, c := range group.List {
+				if !isEmbedDirective(c) {
+					continue
+				}
+				patterns, err := embedPatterns(c)
+				if err != nil {
+					if err := addEmbedReport(ctx, view, pkg, reports, c.Pos(), c.End(), err); err != nil {
+						return err
+					}
+					continue
+				}
+				for _, p := range patterns {
+					if _, err := embedMatches(dir, p.pattern); err != nil {
+						if err := addEmbedReport(ctx, view, pkg, reports, p.pos, p.end, err); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func addEmbedReport(ctx context.Context, view View, pkg Package, reports map[span.URI][]Diagnostic, pos, end token.Pos, err error) error {
+	rng, rerr := posToMappedRange(ctx, pkg, pos, end)
+	if rerr != nil {
+		return rerr
+	}
+	protocolRange, rerr := rng.Range()
+	if rerr != nil {
+		return rerr
+	}
+	addReport(view, reports, Diagnostic{
+		URI:      rng.URI(),
+		Range:    protocolRange,
+		Message:  err.Error(),
+		Source:   "go:embed",
+		Severity: protocol.SeverityError,
+	})
+	return nil
+}
+
+// EmbedDefinition returns the locations of the files and directories that
+// the pattern of a //go:embed directive at pos matches. It returns no
+// locations, and no error, if pos is not in the pattern of a directive.
+func EmbedDefinition(ctx context.Context, view View, f File, pos protocol.Position) ([]protocol.Location, error) {
+	ctx, done := trace.StartSpan(ctx, "source.EmbedDefinition")
+	defer done()
+
+	fh := view.Snapshot().Handle(ctx, f)
+	file, m, _, err := view.Session().ParseGoHandle(fh, ParseFull).Parse(ctx)
+	if file == nil {
+		return nil, err
+	}
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return nil, err
+	}
+	rng, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, err
+	}
+	c := embedDirectiveAt(file, rng.Start)
+	if c == nil {
+		return nil, nil
+	}
+	patterns, err := embedPatterns(c)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range patterns {
+		if rng.Start < p.pos || p.end < rng.Start {
+			continue
+		}
+		matches, err := embedMatches(filepath.Dir(f.URI().Filename()), p.pattern)
+		if err != nil {
+			return nil, err
+		}
+		locations := make([]protocol.Location, 0, len(matches))
+		for _, match := range matches {
+			locations = append(locations, protocol.Location{
+				URI: protocol.NewURI(span.FileURI(match)),
+			})
+		}
+		return locations, nil
+	}
+	return nil, nil
+}
+
+// embedDirectiveAt returns the //go:embed directive of file that contains
+// pos, if any.
+func embedDirectiveAt(file *ast.File, pos token.Pos) *ast.Comment {
+	for _, group := range file.Comments {
+		if pos < group.Pos() || group.End() < pos {
+			continue
+		}
+		for _, c := range group.List {
+			if c.Pos() <= pos && pos <= c.End() && isEmbedDirective(c) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// embedCompletion returns the completions of the pattern of the //go:embed
+// directive c at pos: the files and directories whose names start with what
+// has been typed of the last element of the pattern, in the directory named
+// by the elements before it.
+func embedCompletion(c *ast.Comment, pos token.Pos, dir string, fset *token.FileSet, m *protocol.ColumnMapper) ([]CompletionItem, *Selection, error) {
+	offset := int(pos - c.Slash)
+	if offset < len(embedDirective) {
+		return nil, nil, nil
+	}
+	typed := c.Text[len(embedDirective):offset]
+	if i := strings.LastIndexAny(typed, " \t"); i >= 0 {
+		typed = typed[i+1:]
+	}
+	typed = strings.TrimLeft(typed, "\"`")
+	elemDir, prefix := "", typed
+	if i := strings.LastIndex(typed, "/"); i >= 0 {
+		elemDir, prefix = typed[:i], typed[i+1:]
+	}
+
+	infos, err := ioutil.ReadDir(filepath.Join(dir, filepath.FromSlash(elemDir)))
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []CompletionItem
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasPrefix(name, prefix) || strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		item := CompletionItem{
+			Label:      name,
+			InsertText: name,
+			Kind:       protocol.FileCompletion,
+			Score:      stdScore,
+		}
+		if info.IsDir() {
+			item.Label += "/"
+			item.InsertText += "/"
+			item.Kind = protocol.FolderCompletion
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items, &Selection{
+		content: prefix,
+		cursor:  pos,
+		mappedRange: mappedRange{
+			spanRange: span.NewRange(fset, pos-token.Pos(len(prefix)), pos),
+			m:         m,
+		},
+	}, nil
+}
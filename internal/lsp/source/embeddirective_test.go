@@ -0,0 +1,98 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+)
+
+func TestEmbedDirective(t *testing.T) {
+	dir, err := ioutil.TempDir("", "embeddirective")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.txt", "b.txt", "static/index.html", "static/style.css", "hidden/.keep"} {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const src = "package p\n\n//go:embed *.txt \"static/index.html\" missing hidden ../x\nvar files string\n\n//go:embed static/s\nvar prefix string\n"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(dir, "p.go"), src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The patterns of the first directive, and whether they can be embedded.
+	c := file.Comments[0].List[0]
+	patterns, err := embedPatterns(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []struct {
+		pattern string
+		text    string
+		err     string
+	}{
+		{"*.txt", "*.txt", ""},
+		{"static/index.html", `"static/index.html"`, ""},
+		{"missing", "missing", "no matching files found"},
+		{"hidden", "hidden", "contains no embeddable files"},
+		{"../x", "../x", "invalid pattern syntax"},
+	} {
+		p := patterns[i]
+		if p.pattern != want.pattern || src[fset.Position(p.pos).Offset:fset.Position(p.end).Offset] != want.text {
+			t.Errorf("pattern %d: got %q at %q, want %q at %q", i, p.pattern, src[fset.Position(p.pos).Offset:fset.Position(p.end).Offset], want.pattern, want.text)
+		}
+		_, err := embedMatches(dir, p.pattern)
+		if (err == nil) != (want.err == "") || err != nil && !strings.Contains(err.Error(), want.err) {
+			t.Errorf("pattern %s: got error %v, want %q", p.pattern, err, want.err)
+		}
+	}
+	if matches, _ := embedMatches(dir, "*.txt"); len(matches) != 2 {
+		t.Errorf("got matches %v for *.txt, want a.txt and b.txt", matches)
+	}
+
+	// The completion of the pattern of the second directive.
+	c = file.Comments[1].List[0]
+	if embedDirectiveAt(file, c.End()) != c {
+		t.Fatalf("no directive at the end of %q", c.Text)
+	}
+	m := &protocol.ColumnMapper{
+		URI:       span.FileURI(filepath.Join(dir, "p.go")),
+		Converter: span.NewTokenConverter(fset, fset.File(file.Pos())),
+		Content:   []byte(src),
+	}
+	items, surrounding, err := embedCompletion(c, c.End(), dir, fset, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	if want := []string{"style.css"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("got completions %v, want %v", labels, want)
+	}
+	if surrounding.Prefix() != "s" {
+		t.Errorf("got prefix %q, want %q", surrounding.Prefix(), "s")
+	}
+}
@@ -26,6 +26,11 @@ func checkCommonErrors(ctx context.Context, view View, uri span.URI) (string, er
 		return "", nil
 	}
 
+	// A standalone file can only import the standard library.
+	if view.Standalone(uri) {
+		return fmt.Sprintf("%s is neither in a module nor in your GOPATH, so only its imports from the standard library are found. See %s for information on how to set up your Go project.", filepath.Base(uri.Filename()), modulesWiki), nil
+	}
+
 	// Some cases we should be able to detect:
 	//
 	//  1. The user is in GOPATH mode and is working outside their GOPATH
@@ -21,7 +21,7 @@ func FoldingRange(ctx context.Context, view View, f File, lineFoldingOnly bool)
 	// implement a way to prioritize folding ranges in that case.
 	s := view.Snapshot()
 	fh := s.Handle(ctx, f)
-	ph := view.Session().Cache().ParseGoHandle(fh, ParseFull)
+	ph := view.Session().ParseGoHandle(fh, ParseFull)
 	file, m, _, err := ph.Parse(ctx)
 	if err != nil {
 		return nil, err
@@ -30,16 +30,56 @@ func FoldingRange(ctx context.Context, view View, f File, lineFoldingOnly bool)
 	// Get folding ranges for comments separately as they are not walked by ast.Inspect.
 	ranges = append(ranges, commentsFoldingRange(view, m, file)...)
 
+	// The build constraints of the file delimit the section that they
+	// constrain, up to the end of the file.
+	if end, err := ph.BuildConstraintEnd(ctx); err == nil && end.IsValid() {
+		if rng := constraintFoldingRange(view, m, file, end); rng != nil {
+			ranges = append(ranges, rng)
+		}
+	}
+
 	foldingFunc := foldingRange
 	if lineFoldingOnly {
 		foldingFunc = lineFoldingRange
 	}
 
+	// Track the nesting depth of folding ranges as we walk the AST, so that
+	// ranges nested more deeply than the configured maximum can be dropped.
+	maxDepth := view.Options().FoldingRangeMaxDepth
+	var (
+		folded       []bool // for each node on the stack, whether it was folded
+		depth        int
+		structFields *ast.FieldList
+	)
 	visit := func(n ast.Node) bool {
+		if n == nil {
+			if folded[len(folded)-1] {
+				depth--
+			}
+			folded = folded[:len(folded)-1]
+			return false
+		}
 		rng := foldingFunc(view, m, n)
+		switch n := n.(type) {
+		case *ast.StructType:
+			// Fold the fields of a struct, along with their tags, as a region.
+			// The field list itself is skipped when it is visited next.
+			if rng = foldingFunc(view, m, n.Fields); rng != nil {
+				rng.Kind = protocol.Region
+			}
+			structFields = n.Fields
+		case *ast.FieldList:
+			if n == structFields {
+				rng = nil
+			}
+		}
 		if rng != nil {
-			ranges = append(ranges, rng)
+			depth++
+			if maxDepth <= 0 || depth <= maxDepth {
+				ranges = append(ranges, rng)
+			}
 		}
+		folded = append(folded, rng != nil)
 		return true
 	}
 	// Walk the ast and collect folding ranges.
@@ -73,10 +113,7 @@ func foldingRange(view View, m *protocol.ColumnMapper, n ast.Node) *FoldingRange
 		// closing parenthesis/brace.
 		start, end = n.Opening+1, n.Closing
 	case *ast.GenDecl:
-		// If this is an import declaration, set the kind to be protocol.Imports.
-		if n.Tok == token.IMPORT {
-			kind = protocol.Imports
-		}
+		kind = genDeclFoldingRangeKind(n)
 		start, end = n.Lparen+1, n.Rparen
 	}
 	if !start.IsValid() || !end.IsValid() {
@@ -140,10 +177,7 @@ func lineFoldingRange(view View, m *protocol.ColumnMapper, n ast.Node) *FoldingR
 		}
 		start, end = n.Opening+1, n.List[nFields-1].End()
 	case *ast.GenDecl:
-		// If this is an import declaration, set the kind to be protocol.Imports.
-		if n.Tok == token.IMPORT {
-			kind = protocol.Imports
-		}
+		kind = genDeclFoldingRangeKind(n)
 		// Fold from position of "(" to position of ")".
 		if !n.Lparen.IsValid() || !n.Rparen.IsValid() {
 			break
@@ -180,6 +214,19 @@ func lineFoldingRange(view View, m *protocol.ColumnMapper, n ast.Node) *FoldingR
 	}
 }
 
+// genDeclFoldingRangeKind returns the kind of the folding range for a grouped
+// declaration: imports are protocol.Imports, and grouped constants and
+// variables are protocol.Region.
+func genDeclFoldingRangeKind(n *ast.GenDecl) protocol.FoldingRangeKind {
+	switch n.Tok {
+	case token.IMPORT:
+		return protocol.Imports
+	case token.CONST, token.VAR:
+		return protocol.Region
+	}
+	return ""
+}
+
 // commentsFoldingRange returns the folding ranges for all comment blocks in file.
 // The folding range starts at the end of the first comment, and ends at the end of the
 // comment block and has kind protocol.Comment.
@@ -200,3 +247,20 @@ func commentsFoldingRange(view View, m *protocol.ColumnMapper, file *ast.File) (
 	}
 	return comments
 }
+
+// constraintFoldingRange returns the folding range, of kind protocol.Region,
+// of the section of file constrained by the build constraints that end at
+// end, or nil if the section is on the line of the constraints.
+func constraintFoldingRange(view View, m *protocol.ColumnMapper, file *ast.File, end token.Pos) *FoldingRangeInfo {
+	fset := view.Session().Cache().FileSet()
+	if fset.Position(end).Line == fset.Position(file.End()).Line {
+		return nil
+	}
+	return &FoldingRangeInfo{
+		mappedRange: mappedRange{
+			m:         m,
+			spanRange: span.NewRange(fset, end, file.End()),
+		},
+		Kind: protocol.Region,
+	}
+}
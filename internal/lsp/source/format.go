@@ -57,6 +57,11 @@ func Format(ctx context.Context, view View, f File) ([]protocol.TextEdit, error)
 		if err != nil {
 			return nil, err
 		}
+		if formatter := view.Options().Formatter; formatter != nil {
+			if formatted, err = runFormatter(ctx, formatter, f.URI().Filename(), formatted); err != nil {
+				return nil, err
+			}
+		}
 		return computeTextEdits(ctx, view, ph.File(), m, string(formatted))
 	}
 
@@ -70,6 +75,13 @@ func Format(ctx context.Context, view View, f File) ([]protocol.TextEdit, error)
 	if err := format.Node(buf, fset, file); err != nil {
 		return nil, err
 	}
+	if formatter := view.Options().Formatter; formatter != nil {
+		formatted, err := runFormatter(ctx, formatter, f.URI().Filename(), buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		return computeTextEdits(ctx, view, ph.File(), m, string(formatted))
+	}
 	return computeTextEdits(ctx, view, ph.File(), m, buf.String())
 }
 
@@ -155,6 +167,9 @@ func AllImportsFixes(ctx context.Context, view View, f File) (edits []protocol.T
 	ctx, done := trace.StartSpan(ctx, "source.AllImportsFixes")
 	defer done()
 
+	if protectGenerated(ctx, view, f.URI()) {
+		return nil, nil, nil
+	}
 	_, cphs, err := view.CheckPackageHandles(ctx, f)
 	if err != nil {
 		return nil, nil, err
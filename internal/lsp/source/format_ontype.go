@@ -0,0 +1,153 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// OnTypeTriggers are the characters that trigger on-type formatting.
+var OnTypeTriggers = []string{"}", ";", "\n"}
+
+// OnTypeFormat returns the edits that realign the innermost composite literal
+// or struct type of f around pos after ch was typed before it: the values of
+// the keys of the literal, or the types, tags and comments of the fields of
+// the struct, as gofmt aligns them. A closing brace triggers the formatting
+// only if it closes the literal or the struct.
+//
+// Only the literal or the struct is printed, not the whole file, and only the
+// spaces of its lines are edited: lines are neither added nor removed, and the
+// line of pos is left as it is after a newline, so that the indentation the
+// editor inserted is kept.
+func OnTypeFormat(ctx context.Context, view View, f File, pos protocol.Position, ch string) ([]protocol.TextEdit, error) {
+	ctx, done := trace.StartSpan(ctx, "source.OnTypeFormat")
+	defer done()
+
+	fh := view.Snapshot().Handle(ctx, f)
+	ph := view.Session().ParseGoHandle(fh, ParseFull)
+	file, m, parseErr, err := ph.Parse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return nil, err
+	}
+	rng, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, err
+	}
+	fset := view.Session().Cache().FileSet()
+	tok := fset.File(file.Pos())
+	if tok == nil {
+		return nil, errors.Errorf("no file for %s", f.URI())
+	}
+	formatted, err := formatOnType(fset, tok, file, m.Content, parseErr, rng.Start, ch)
+	if err != nil {
+		return nil, err
+	}
+	var edits []protocol.TextEdit
+	for _, e := range formatted {
+		rng, err := m.Range(span.New(m.URI, span.NewPoint(0, 0, e.start), span.NewPoint(0, 0, e.end)))
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, protocol.TextEdit{Range: rng, NewText: e.text})
+	}
+	return edits, nil
+}
+
+// formatOnType returns the edits of OnTypeFormat to the content of the parsed
+// file tok.
+func formatOnType(fset *token.FileSet, tok *token.File, file *ast.File, content []byte, parseErr error, pos token.Pos, ch string) ([]offsetEdit, error) {
+	at := pos
+	if ch != "\n" && pos > file.Pos() {
+		at = pos - 1 // the character that was typed
+	}
+	path, _ := astutil.PathEnclosingInterval(file, at, at)
+	var n ast.Node
+	for _, p := range path {
+		switch p.(type) {
+		case *ast.CompositeLit, *ast.StructType:
+			n = p
+		default:
+			continue
+		}
+		break
+	}
+	if n == nil || ch == "}" && n.End() != pos {
+		return nil, nil
+	}
+	// The AST of a node with syntax errors is not all of its source.
+	if hasParseErrorIn(tok, parseErr, n) {
+		return nil, nil
+	}
+	start, end := tok.Offset(n.Pos()), tok.Offset(n.End())
+	lineStart := tok.Offset(tok.LineStart(tok.Line(n.Pos())))
+	lineEnd := end
+	for lineEnd < len(content) && content[lineEnd] != '\n' {
+		lineEnd++
+	}
+	indent := content[lineStart:start]
+	depth := len(indent) - len(strings.TrimLeft(string(indent), "\t"))
+	printed, err := printNode(fset, file, n, depth)
+	if err != nil {
+		return nil, err
+	}
+	before := strings.Split(string(content[lineStart:lineEnd]), "\n")
+	after := strings.Split(string(content[lineStart:start])+strings.TrimLeft(printed, "\t")+string(content[end:lineEnd]), "\n")
+
+	// Pair the lines that only differ in spaces, skipping the blank lines
+	// that gofmt removes.
+	cursorLine := -1
+	if ch == "\n" {
+		cursorLine = tok.Line(pos) - tok.Line(n.Pos())
+	}
+	var edits []offsetEdit
+	offset, j := lineStart, 0
+	for i := 0; i < len(before); i++ {
+		line := before[i]
+		lineOffset := offset
+		offset += len(line) + len("\n")
+		if j < len(after) && withoutSpaces(line) == withoutSpaces(after[j]) {
+			if line != after[j] && i != cursorLine {
+				edits = append(edits, offsetEdit{start: lineOffset, end: lineOffset + len(line), text: after[j]})
+			}
+			j++
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			// gofmt changed more than the spaces, which is left to
+			// formatting the file.
+			return nil, nil
+		}
+	}
+	for ; j < len(after); j++ {
+		if strings.TrimSpace(after[j]) != "" {
+			return nil, nil
+		}
+	}
+	return edits, nil
+}
+
+// withoutSpaces returns s without its white space.
+func withoutSpaces(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestFormatOnType(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		src  string // the position is at the first ^, which is removed
+		ch   string
+		want string
+	}{
+		{
+			name: "closing brace of literal",
+			src: `package a
+
+var x = T{
+	A: 1,
+	Long: 2,
+}^
+`,
+			ch: "}",
+			want: `package a
+
+var x = T{
+	A:    1,
+	Long: 2,
+}
+`,
+		},
+		{
+			name: "nested literal",
+			src: `package a
+
+func f() {
+	x := []T{{
+		A: 1,
+		Long: 2,
+	}^}
+}
+`,
+			ch: "}",
+			want: `package a
+
+func f() {
+	x := []T{{
+		A:    1,
+		Long: 2,
+	}}
+}
+`,
+		},
+		{
+			name: "closing brace of block",
+			src: `package a
+
+func f() {
+	x := T{
+		A: 1,
+		Long: 2,
+	}
+	if true {
+	}^
+}
+`,
+			ch: "}",
+		},
+		{
+			name: "struct tags",
+			src: `package a
+
+type T struct {
+	A int ` + "`json:\"a\"`" + `
+	Long string ` + "`json:\"long\"`" + `
+}^
+`,
+			ch: "}",
+			want: `package a
+
+type T struct {
+	A    int    ` + "`json:\"a\"`" + `
+	Long string ` + "`json:\"long\"`" + `
+}
+`,
+		},
+		{
+			name: "newline keeps the new line",
+			src: `package a
+
+var x = T{
+	A: 1,
+	Long: 2,
+	^
+}
+`,
+			ch: "\n",
+			want: `package a
+
+var x = T{
+	A:    1,
+	Long: 2,
+` + "\t" + `
+}
+`,
+		},
+		{
+			name: "more than spaces",
+			src: `package a
+
+var x = T{A: 1,
+	Long: 2}^
+`,
+			ch: "}",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			offset := strings.Index(test.src, "^")
+			src := test.src[:offset] + test.src[offset+1:]
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "a.go", src, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tok := fset.File(file.Pos())
+			edits, err := formatOnType(fset, tok, file, []byte(src), nil, tok.Pos(offset), test.ch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := src
+			for i := len(edits) - 1; i >= 0; i-- {
+				e := edits[i]
+				got = got[:e.start] + e.text + got[e.end:]
+			}
+			want := test.want
+			if want == "" {
+				want = src
+			}
+			if got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"go/ast"
+	"go/printer"
+	"go/scanner"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// RangeFormat formats the statements or declarations of f that overlap rng as
+// gofmt would, and leaves the rest of the file unchanged. The statements are
+// those of the innermost block or case clause that contains rng, and each is
+// printed on its own at the indentation of its first line. The formatter of
+// the options is not run, since it formats whole files.
+func RangeFormat(ctx context.Context, view View, f File, rng protocol.Range) ([]protocol.TextEdit, error) {
+	ctx, done := trace.StartSpan(ctx, "source.RangeFormat")
+	defer done()
+
+	fh := view.Snapshot().Handle(ctx, f)
+	ph := view.Session().ParseGoHandle(fh, ParseFull)
+	file, m, parseErr, err := ph.Parse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	spn, err := m.RangeSpan(rng)
+	if err != nil {
+		return nil, err
+	}
+	r, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, err
+	}
+	fset := view.Session().Cache().FileSet()
+	tok := fset.File(file.Pos())
+	if tok == nil {
+		return nil, errors.Errorf("no file for %s", f.URI())
+	}
+	formatted, err := formatRange(fset, tok, file, m.Content, parseErr, r.Start, r.End)
+	if err != nil {
+		return nil, errors.Errorf("%s: %v", f.URI(), err)
+	}
+	var edits []protocol.TextEdit
+	for _, e := range formatted {
+		rng, err := m.Range(span.New(m.URI, span.NewPoint(0, 0, e.start), span.NewPoint(0, 0, e.end)))
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, protocol.TextEdit{Range: rng, NewText: e.text})
+	}
+	return edits, nil
+}
+
+// offsetEdit replaces the bytes from start to end of a file with text.
+type offsetEdit struct {
+	start, end int
+	text       string
+}
+
+// formatRange returns the edits to the content of the parsed file tok that
+// format the nodes of rangeFormatNodes. It fails if the nodes have syntax
+// errors, since their source is then not all in the AST.
+func formatRange(fset *token.FileSet, tok *token.File, file *ast.File, content []byte, parseErr error, start, end token.Pos) ([]offsetEdit, error) {
+	var edits []offsetEdit
+	for _, n := range rangeFormatNodes(file, start, end) {
+		if hasParseErrorIn(tok, parseErr, n) {
+			return nil, errors.New("syntax errors in the range")
+		}
+		start, end := tok.Offset(n.Pos()), tok.Offset(n.End())
+		lineStart := tok.Offset(tok.LineStart(tok.Line(n.Pos())))
+		indent := content[lineStart:start]
+		first := len(bytes.TrimLeft(indent, " \t")) == 0
+		depth := len(indent) - len(bytes.TrimLeft(indent, "\t"))
+
+		printed, err := printNode(fset, file, n, depth)
+		if err != nil {
+			return nil, err
+		}
+		// A node that does not start its line keeps what precedes it.
+		if !first {
+			lineStart, printed = start, strings.TrimLeft(printed, "\t")
+		}
+		if printed != string(content[lineStart:end]) {
+			edits = append(edits, offsetEdit{start: lineStart, end: end, text: printed})
+		}
+	}
+	return edits, nil
+}
+
+// rangeFormatNodes returns the statements of the innermost block or case
+// clause of file that contains [start, end) and overlap it, or the
+// declarations of file that do.
+func rangeFormatNodes(file *ast.File, start, end token.Pos) []ast.Node {
+	path, _ := astutil.PathEnclosingInterval(file, start, end)
+	for _, n := range path {
+		var list []ast.Node
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			list = stmtNodes(n.List)
+		case *ast.CaseClause:
+			list = stmtNodes(n.Body)
+		case *ast.CommClause:
+			list = stmtNodes(n.Body)
+		case *ast.File:
+			for _, decl := range n.Decls {
+				list = append(list, decl)
+			}
+		default:
+			continue
+		}
+		var result []ast.Node
+		for _, item := range list {
+			if item.Pos() < end && item.End() > start || start == end && item.Pos() <= start && start <= item.End() {
+				result = append(result, item)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func stmtNodes(stmts []ast.Stmt) []ast.Node {
+	result := make([]ast.Node, len(stmts))
+	for i, stmt := range stmts {
+		result[i] = stmt
+	}
+	return result
+}
+
+// printNode prints n as gofmt would, along with the comments of file inside
+// it, indented by depth tabs. The doc comments of declarations, which precede
+// them, are not printed.
+func printNode(fset *token.FileSet, file *ast.File, n ast.Node, depth int) (string, error) {
+	node := n
+	switch n := n.(type) {
+	case *ast.FuncDecl:
+		decl := *n
+		decl.Doc = nil
+		node = &decl
+	case *ast.GenDecl:
+		decl := *n
+		decl.Doc = nil
+		node = &decl
+	}
+	var comments []*ast.CommentGroup
+	for _, group := range file.Comments {
+		if group.Pos() >= n.Pos() && group.End() <= n.End() {
+			comments = append(comments, group)
+		}
+	}
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8, Indent: depth}
+	if err := cfg.Fprint(&buf, fset, &printer.CommentedNode{Node: node, Comments: comments}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// hasParseErrorIn reports whether the parse error err of the file tok is in
+// the node n. Errors that are not syntax errors with positions are in every
+// node.
+func hasParseErrorIn(tok *token.File, err error, n ast.Node) bool {
+	if err == nil {
+		return false
+	}
+	list, ok := err.(scanner.ErrorList)
+	if !ok {
+		return true
+	}
+	start, end := tok.Offset(n.Pos()), tok.Offset(n.End())
+	for _, e := range list {
+		if start <= e.Pos.Offset && e.Pos.Offset <= end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestFormatRange(t *testing.T) {
+	const src = `package a
+
+import "fmt"
+
+func f(xs []int) {
+	a:=1
+	if a>0 {
+		// Keep this comment.
+		fmt.Println( a,
+			"x" )
+	}
+	b:=2 ;c:=3
+	switch  a {
+	case 1:
+		fmt.Println(  b )
+	}
+	fmt.Println(  c )
+	s := ` + "`raw\n  string`" + `
+}
+
+var  unformatted   =  1
+`
+	for _, test := range []struct {
+		name     string
+		from, to string // the range is from the start of from to the end of to
+		want     string
+	}{
+		{
+			name: "statement",
+			from: "a:=1", to: "a:=1",
+			want: strings.Replace(src, "a:=1", "a := 1", 1),
+		},
+		{
+			name: "block",
+			from: "fmt.Println( a", to: `"x" )`,
+			want: strings.Replace(src, "fmt.Println( a,\n\t\t\t\"x\" )", "fmt.Println(a,\n\t\t\t\"x\")", 1),
+		},
+		{
+			name: "nested",
+			from: "if a>0", to: "if a>0",
+			want: strings.Replace(strings.Replace(src, "if a>0", "if a > 0", 1), "fmt.Println( a,\n\t\t\t\"x\" )", "fmt.Println(a,\n\t\t\t\"x\")", 1),
+		},
+		{
+			name: "same line",
+			from: "c:=3", to: "c:=3",
+			want: strings.Replace(src, ";c:=3", ";c := 3", 1),
+		},
+		{
+			name: "case clause",
+			from: "fmt.Println(  b )", to: "fmt.Println(  b )",
+			want: strings.Replace(src, "fmt.Println(  b )", "fmt.Println(b)", 1),
+		},
+		{
+			name: "raw string",
+			from: "s :=", to: "string`",
+			want: src,
+		},
+		{
+			name: "declaration",
+			from: "var  unformatted", to: "var  unformatted",
+			want: strings.Replace(src, "var  unformatted   =  1", "var unformatted = 1", 1),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "a.go", src, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tok := fset.File(file.Pos())
+			start := tok.Pos(strings.Index(src, test.from))
+			end := tok.Pos(strings.Index(src, test.to) + len(test.to))
+			edits, err := formatRange(fset, tok, file, []byte(src), nil, start, end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := src
+			for i := len(edits) - 1; i >= 0; i-- {
+				e := edits[i]
+				got = got[:e.start] + e.text + got[e.end:]
+			}
+			if got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatRangeSyntaxError(t *testing.T) {
+	const src = `package a
+
+func f() {
+	a:=1
+	b := )
+}
+`
+	fset := token.NewFileSet()
+	file, parseErr := parser.ParseFile(fset, "a.go", src, parser.ParseComments)
+	if parseErr == nil {
+		t.Fatal("no syntax error")
+	}
+	tok := fset.File(file.Pos())
+	at := func(s string) token.Pos { return tok.Pos(strings.Index(src, s)) }
+
+	edits, err := formatRange(fset, tok, file, []byte(src), parseErr, at("a:=1"), at("a:=1"))
+	if err != nil || len(edits) != 1 {
+		t.Errorf("formatting the statement before the error: got %v, %v", edits, err)
+	}
+	if _, err := formatRange(fset, tok, file, []byte(src), parseErr, at("b :="), at("b :=")); err == nil {
+		t.Error("formatting the statement with the error: got no error")
+	}
+}
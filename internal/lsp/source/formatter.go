@@ -0,0 +1,106 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	errors "golang.org/x/xerrors"
+)
+
+// A Formatter formats the source of a Go file further after gofmt, such as
+// gofumpt does. The source it is given is formatted by gofmt.
+type Formatter interface {
+	Format(ctx context.Context, filename string, src []byte) ([]byte, error)
+}
+
+// FormatterFunc adapts a function to a Formatter.
+type FormatterFunc func(ctx context.Context, filename string, src []byte) ([]byte, error)
+
+func (f FormatterFunc) Format(ctx context.Context, filename string, src []byte) ([]byte, error) {
+	return f(ctx, filename, src)
+}
+
+var formatters = struct {
+	mu     sync.Mutex
+	byName map[string]Formatter
+}{byName: make(map[string]Formatter)}
+
+// RegisterFormatter registers the formatter f under name, so that programs
+// that embed gopls can provide formatters, such as gofumpt, that the
+// "formatter" setting selects by name without running another program.
+func RegisterFormatter(name string, f Formatter) {
+	formatters.mu.Lock()
+	defer formatters.mu.Unlock()
+	formatters.byName[name] = f
+}
+
+// namedFormatter returns the formatter registered under name, or the command
+// of that name if there is none.
+func namedFormatter(name string) Formatter {
+	formatters.mu.Lock()
+	defer formatters.mu.Unlock()
+	if f, ok := formatters.byName[name]; ok {
+		return f
+	}
+	return CommandFormatter{name}
+}
+
+// CommandFormatter is a Formatter that runs a command line, which reads the
+// source on its standard input and writes the formatted source to its
+// standard output, in the directory of the file. A WebAssembly module is run
+// by the command of its runtime, such as "wasmtime run fmt.wasm".
+type CommandFormatter []string
+
+// formatterTimeout bounds the run of the command of a formatter, so that a
+// command that hangs does not block formatting.
+const formatterTimeout = 10 * time.Second
+
+func (c CommandFormatter) Format(ctx context.Context, filename string, src []byte) ([]byte, error) {
+	if len(c) == 0 {
+		return src, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, formatterTimeout)
+	defer cancel()
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, c[0], c[1:]...)
+	cmd.Dir = filepath.Dir(filename)
+	cmd.Stdin = bytes.NewReader(src)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Errorf("%s: %v: %s", c[0], err, msg)
+		}
+		return nil, errors.Errorf("%s: %v", c[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// runFormatter formats the gofmt output src of the file filename with the
+// formatter f. The result must be a valid Go file, so that a broken formatter
+// cannot replace the content of the file with something that is not Go.
+func runFormatter(ctx context.Context, f Formatter, filename string, src []byte) ([]byte, error) {
+	formatted, err := f.Format(ctx, filename, src)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(formatted)) == 0 && len(bytes.TrimSpace(src)) != 0 {
+		return nil, errors.Errorf("formatter returned no output for %s", filename)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), filename, formatted, parser.ParseComments); err != nil {
+		return nil, errors.Errorf("formatter returned invalid Go for %s: %v", filename, err)
+	}
+	return formatted, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRunFormatter(t *testing.T) {
+	const src = "package a\n\nvar x = 1\n"
+	const filename = "a.go"
+	ctx := context.Background()
+
+	upper := FormatterFunc(func(ctx context.Context, filename string, src []byte) ([]byte, error) {
+		return bytes.Replace(src, []byte("var x"), []byte("var X"), 1), nil
+	})
+	got, err := runFormatter(ctx, upper, filename, []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "package a\n\nvar X = 1\n"; string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for name, f := range map[string]FormatterFunc{
+		"invalid": func(ctx context.Context, filename string, src []byte) ([]byte, error) {
+			return append(src, "func {"...), nil
+		},
+		"empty": func(ctx context.Context, filename string, src []byte) ([]byte, error) {
+			return nil, nil
+		},
+	} {
+		if _, err := runFormatter(ctx, f, filename, []byte(src)); err == nil {
+			t.Errorf("%s formatter: got no error", name)
+		}
+	}
+}
+
+func TestCommandFormatter(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat is not installed")
+	}
+	const src = "package a\n"
+	const filename = "a.go"
+	got, err := runFormatter(context.Background(), CommandFormatter{"cat"}, filename, []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != src {
+		t.Errorf("got %q, want %q", got, src)
+	}
+	_, err = runFormatter(context.Background(), CommandFormatter{"sh", "-c", "echo oops >&2; exit 1"}, filename, []byte(src))
+	if err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("got error %v, want the standard error of the command", err)
+	}
+}
+
+func TestFormatterOption(t *testing.T) {
+	RegisterFormatter("test-upper", FormatterFunc(func(ctx context.Context, filename string, src []byte) ([]byte, error) {
+		return src, nil
+	}))
+	for _, test := range []struct {
+		value interface{}
+		want  string
+	}{
+		{"test-upper", "source.FormatterFunc"},
+		{"gofumpt", "source.CommandFormatter"},
+		{[]interface{}{"wasmtime", "run", "fmt.wasm"}, "source.CommandFormatter"},
+		{"", "<nil>"},
+	} {
+		var o Options
+		if result := o.set("formatter", test.value); result.Error != nil {
+			t.Fatalf("formatter %v: %v", test.value, result.Error)
+		}
+		if got := fmt.Sprintf("%T", o.Formatter); got != test.want {
+			t.Errorf("formatter %v: got %s, want %s", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// GenerateSource is the source of the diagnostics of //go:generate
+// directives whose tool is not installed.
+const GenerateSource = "go generate"
+
+const generateDirective = "//go:generate"
+
+// GenerateArgs are the arguments to the "generate" command, which runs the
+// //go:generate directives of the file URI.
+type GenerateArgs struct {
+	URI protocol.DocumentUri `json:"uri"`
+
+	// Command is the command of the directive to run, as written after
+	// //go:generate. All the directives of the file are run if it is empty.
+	Command string `json:"command,omitempty"`
+
+	// Install is the package, with its version, that is installed with
+	// "go install" before the directives are run, such as
+	// golang.org/x/tools/cmd/stringer@latest.
+	Install string `json:"install,omitempty"`
+}
+
+// knownGenerators are the packages of the tools commonly run by
+// //go:generate directives, by the name of their command.
+var knownGenerators = map[string]string{
+	"easyjson": "github.com/mailru/easyjson/easyjson",
+	"enumer":   "github.com/dmarkham/enumer",
+	"goyacc":   "golang.org/x/tools/cmd/goyacc",
+	"mockery":  "github.com/vektra/mockery/v2",
+	"mockgen":  "github.com/golang/mock/mockgen",
+	"moq":      "github.com/matryer/moq",
+	"stringer": "golang.org/x/tools/cmd/stringer",
+	"wire":     "github.com/google/wire/cmd/wire",
+}
+
+// goGenerate is a //go:generate directive.
+type goGenerate struct {
+	// command is the text of the directive after //go:generate.
+	command string
+
+	// args are the words of the command. The first is the tool that it
+	// runs.
+	args []string
+
+	pos, end token.Pos
+}
+
+// generateDirectives returns the //go:generate directives of file.
+func generateDirectives(file *ast.File) []goGenerate {
+	var directives []goGenerate
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if !strings.HasPrefix(c.Text, generateDirective+" ") && !strings.HasPrefix(c.Text, generateDirective+"\t") {
+				continue
+			}
+			command := strings.TrimSpace(c.Text[len(generateDirective):])
+			args := splitGenerateCommand(command)
+			if len(args) == 0 {
+				continue
+			}
+			directives = append(directives, goGenerate{
+				command: command,
+				args:    args,
+				pos:     c.Pos(),
+				end:     c.End(),
+			})
+		}
+	}
+	return directives
+}
+
+// splitGenerateCommand splits the command of a //go:generate directive into
+// words, as the go command does: the words are separated by spaces, and a
+// double-quoted string is a single word.
+func splitGenerateCommand(command string) []string {
+	var words []string
+	for command = strings.TrimLeft(command, " \t"); command != ""; command = strings.TrimLeft(command, " \t") {
+		if command[0] == '"' {
+			end := 1
+			for end < len(command) && command[end] != '"' {
+				if command[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(command) {
+				if word, err := strconv.Unquote(command[:end+1]); err == nil {
+					words = append(words, word)
+					command = command[end+1:]
+					continue
+				}
+			}
+		}
+		i := strings.IndexAny(command, " \t")
+		if i < 0 {
+			i = len(command)
+		}
+		words = append(words, command[:i])
+		command = command[i:]
+	}
+	return words
+}
+
+// tool returns the name of the command that the directive runs, or "" if it
+// is the go command, as in "go run", which builds what it runs, or a
+// variable.
+func (g goGenerate) tool() string {
+	tool := g.args[0]
+	if tool == "go" || strings.HasPrefix(tool, "$") || strings.ContainsAny(tool, `/\`) {
+		return ""
+	}
+	return tool
+}
+
+// getenv returns the value of the variable key in env. The last value of a
+// variable takes precedence.
+func getenv(env []string, key string) string {
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], key+"=") {
+			return env[i][len(key)+1:]
+		}
+	}
+	return ""
+}
+
+// gopath returns the GOPATH of env, or the default one if it is not set.
+func gopath(env []string) string {
+	if gopath := getenv(env, "GOPATH"); gopath != "" {
+		return gopath
+	}
+	return build.Default.GOPATH
+}
+
+// toolInstalled reports whether the command tool is in one of the
+// directories of the PATH of env, or in the directory where "go install"
+// installs commands.
+func toolInstalled(env []string, tool string) bool {
+	dirs := filepath.SplitList(getenv(env, "PATH"))
+	if gobin := getenv(env, "GOBIN"); gobin != "" {
+		dirs = append(dirs, gobin)
+	} else {
+		for _, dir := range filepath.SplitList(gopath(env)) {
+			dirs = append(dirs, filepath.Join(dir, "bin"))
+		}
+	}
+	if runtime.GOOS == "windows" {
+		tool += ".exe"
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(dir, tool))
+		if err == nil && info.Mode().IsRegular() && (runtime.GOOS == "windows" || info.Mode()&0111 != 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// installPackage returns the package that provides the command tool, with
+// the version to install it at: the version of its module that the go.mod
+// file of the module containing filename requires, or else the latest one.
+// It returns "" if the package of tool is unknown.
+func installPackage(ctx context.Context, view View, filename, tool string) (string, error) {
+	pkgPath, ok := knownGenerators[tool]
+	if !ok {
+		return "", nil
+	}
+	version := "latest"
+	if gomod := findGoMod(filepath.Dir(filename)); gomod != "" {
+		data, _, err := view.Session().GetFile(span.FileURI(gomod), Mod).Read(ctx)
+		if err != nil {
+			return "", err
+		}
+		if v := requiredVersion(data, pkgPath); v != "" {
+			version = v
+		}
+	}
+	return pkgPath + "@" + version, nil
+}
+
+// requireRx matches a requirement of a go.mod file, in a require block or
+// not.
+var requireRx = regexp.MustCompile(`^\s*(?:require\s+)?([^\s()]+)\s+(v[^\s]+)`)
+
+// requiredVersion returns the version of the module providing the package
+// with the given path that the go.mod file with the given content requires,
+// or "" if it requires none. The module with the longest path wins.
+func requiredVersion(gomod []byte, pkgPath string) string {
+	var mod, version string
+	for _, line := range bytes.Split(gomod, []byte("\n")) {
+		if i := bytes.Index(line, []byte("//")); i >= 0 {
+			line = line[:i]
+		}
+		match := requireRx.FindSubmatch(line)
+		if match == nil {
+			continue
+		}
+		path := string(match[1])
+		if (pkgPath == path || strings.HasPrefix(pkgPath, path+"/")) && len(path) > len(mod) {
+			mod, version = path, string(match[2])
+		}
+	}
+	return version
+}
+
+// generateDiagnostics reports the //go:generate directives of the files of
+// pkg whose tool is not installed.
+func generateDiagnostics(ctx context.Context, view View, pkg Package, reports map[span.URI][]Diagnostic) error {
+	ctx, done := trace.StartSpan(ctx, "source.generateDiagnostics")
+	defer done()
+
+	env := view.Config(ctx).Env
+	fset := view.Session().Cache().FileSet()
+	for _, file := range pkg.GetSyntax() {
+		for _, g := range generateDirectives(file) {
+			tool := g.tool()
+			if tool == "" || toolInstalled(env, tool) {
+				continue
+			}
+			rng, err := posToMappedRange(ctx, pkg, g.pos, g.end)
+			if err != nil {
+				return err
+			}
+			protocolRange, err := rng.Range()
+			if err != nil {
+				return err
+			}
+			message := fmt.Sprintf("%s is not installed", tool)
+			install, err := installPackage(ctx, view, fset.Position(g.pos).Filename, tool)
+			if err != nil {
+				return err
+			}
+			if install != "" {
+				message += fmt.Sprintf(": install it with go install %s", install)
+			}
+			addReport(view, reports, Diagnostic{
+				URI:      rng.URI(),
+				Range:    protocolRange,
+				Message:  message,
+				Source:   GenerateSource,
+				Severity: protocol.SeverityWarning,
+			})
+		}
+	}
+	return nil
+}
+
+// generateLenses returns the code lenses that run the //go:generate
+// directives of file. The lens of a directive whose tool is not installed
+// installs it first, if its package is known.
+func generateLenses(ctx context.Context, view View, f File, file *ast.File, m *protocol.ColumnMapper) ([]protocol.CodeLens, error) {
+	env := view.Config(ctx).Env
+	var lenses []protocol.CodeLens
+	for _, g := range generateDirectives(file) {
+		rng, err := posToRange(ctx, view, m, g.pos, g.end)
+		if err != nil {
+			return nil, err
+		}
+		protocolRange, err := rng.Range()
+		if err != nil {
+			return nil, err
+		}
+		args := GenerateArgs{
+			URI:     protocol.NewURI(f.URI()),
+			Command: g.command,
+		}
+		title := "run go generate"
+		if tool := g.tool(); tool != "" && !toolInstalled(env, tool) {
+			if args.Install, err = installPackage(ctx, view, f.URI().Filename(), tool); err != nil {
+				return nil, err
+			}
+			if args.Install != "" {
+				title = fmt.Sprintf("install %s and run go generate", tool)
+			}
+		}
+		lenses = append(lenses, protocol.CodeLens{
+			Range: protocolRange,
+			Command: &protocol.Command{
+				Title:     title,
+				Command:   "generate",
+				Arguments: []interface{}{args},
+			},
+		})
+	}
+	return lenses, nil
+}
+
+// GenerateFix returns the arguments to the "generate" command that installs
+// the tool of the //go:generate directive of f that diag was reported for,
+// and then runs the directive.
+func GenerateFix(ctx context.Context, view View, f File, diag protocol.Diagnostic) (string, GenerateArgs, error) {
+	fh := view.Snapshot().Handle(ctx, f)
+	file, m, _, err := view.Session().ParseGoHandle(fh, ParseFull).Parse(ctx)
+	if file == nil {
+		return "", GenerateArgs{}, err
+	}
+	for _, g := range generateDirectives(file) {
+		rng, err := posToRange(ctx, view, m, g.pos, g.end)
+		if err != nil {
+			return "", GenerateArgs{}, err
+		}
+		if protocolRange, err := rng.Range(); err != nil || protocol.CompareRange(protocolRange, diag.Range) != 0 {
+			continue
+		}
+		tool := g.tool()
+		if tool == "" {
+			break
+		}
+		install, err := installPackage(ctx, view, f.URI().Filename(), tool)
+		if err != nil {
+			return "", GenerateArgs{}, err
+		}
+		if install == "" {
+			return "", GenerateArgs{}, errors.Errorf("unknown package for %s", tool)
+		}
+		title := fmt.Sprintf("Install %s with go install %s and run go generate", tool, install)
+		return title, GenerateArgs{
+			URI:     protocol.NewURI(f.URI()),
+			Command: g.command,
+			Install: install,
+		}, nil
+	}
+	return "", GenerateArgs{}, errors.Errorf("no //go:generate directive at %v in %s", diag.Range, f.URI())
+}
+
+// RunGenerate installs the tool of args, if any, and runs the
+// //go:generate directives of args in the directory of its file, writing the
+// combined output of the go command to out.
+func RunGenerate(ctx context.Context, view View, args GenerateArgs, out io.Writer) error {
+	ctx, done := trace.StartSpan(ctx, "source.RunGenerate")
+	defer done()
+
+	filename := span.NewURI(args.URI).Filename()
+	dir := filepath.Dir(filename)
+	// The go command is run as for the tests, with the environment of the
+	// view.
+	if args.Install != "" {
+		if err := runGoTest(ctx, view, dir, []string{"install", args.Install}, TestArgs{}, out); err != nil {
+			return err
+		}
+	}
+	goArgs := []string{"generate"}
+	if args.Command != "" {
+		// The expression of -run matches the whole line of a directive.
+		goArgs = append(goArgs, fmt.Sprintf(`-run=^%s[ \t]+%s$`, generateDirective, regexp.QuoteMeta(args.Command)))
+	}
+	goArgs = append(goArgs, filepath.Base(filename))
+	return runGoTest(ctx, view, dir, goArgs, TestArgs{}, out)
+}
+
+// findGoMod returns the go.mod file of the module that contains dir, or ""
+// if dir is not in a module.
+func findGoMod(dir string) string {
+	for {
+		gomod := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(gomod); err == nil {
+			return gomod
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestGenerateDirectives(t *testing.T) {
+	const src = `package p
+
+//go:generate stringer -type=Pill
+//go:generate go run example.com/gen@v1.0.0 -out "a b.go"
+//go:generate	$GOROOT/bin/tool
+//go:generated not a directive
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type directive struct {
+		Command string
+		Args    []string
+		Tool    string
+	}
+	var got []directive
+	for _, g := range generateDirectives(file) {
+		got = append(got, directive{g.command, g.args, g.tool()})
+	}
+	want := []directive{
+		{"stringer -type=Pill", []string{"stringer", "-type=Pill"}, "stringer"},
+		{`go run example.com/gen@v1.0.0 -out "a b.go"`, []string{"go", "run", "example.com/gen@v1.0.0", "-out", "a b.go"}, ""},
+		{"$GOROOT/bin/tool", []string{"$GOROOT/bin/tool"}, ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got directives\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestRequiredVersion(t *testing.T) {
+	const gomod = `module example.com/m
+
+go 1.13
+
+require golang.org/x/text v0.3.2
+
+require (
+	github.com/golang/mock v1.4.0 // indirect
+	golang.org/x/tools v0.0.0-20191108193012-7d206e10da11
+)
+`
+	for pkg, want := range map[string]string{
+		"github.com/golang/mock/mockgen":  "v1.4.0",
+		"golang.org/x/tools/cmd/stringer": "v0.0.0-20191108193012-7d206e10da11",
+		"golang.org/x/toolsx/cmd/x":       "",
+		"github.com/matryer/moq":          "",
+	} {
+		if got := requiredVersion([]byte(gomod), pkg); got != want {
+			t.Errorf("requiredVersion(%s) = %q, want %q", pkg, got, want)
+		}
+	}
+}
+
+func TestToolInstalled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("tools are found by their .exe extension on windows")
+	}
+	dir, err := ioutil.TempDir("", "generate_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, mode := range map[string]os.FileMode{
+		"path/stringer":   0755,
+		"path/notexec":    0644,
+		"gopath/bin/moq":  0755,
+		"gobin/mockgen":   0755,
+		"other/bin/wire":  0755,
+		"gopath/bin/wire": 0644,
+	} {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := []string{
+		"PATH=/nonexistent",
+		"PATH=" + filepath.Join(dir, "path"),
+		"GOPATH=" + filepath.Join(dir, "gopath"),
+	}
+	for tool, want := range map[string]bool{
+		"stringer": true,
+		"notexec":  false,
+		"moq":      true,
+		"mockgen":  false,
+		"wire":     false,
+	} {
+		if got := toolInstalled(env, tool); got != want {
+			t.Errorf("toolInstalled(%s) = %v, want %v", tool, got, want)
+		}
+	}
+	// GOBIN replaces the bin directory of GOPATH.
+	env = append(env, "GOBIN="+filepath.Join(dir, "gobin"))
+	if !toolInstalled(env, "mockgen") || toolInstalled(env, "moq") {
+		t.Errorf("with GOBIN, got mockgen %v and moq %v, want only mockgen", toolInstalled(env, "mockgen"), toolInstalled(env, "moq"))
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// GeneratedSource is the source of the diagnostics of generated files that
+// have been edited.
+const GeneratedSource = "generated"
+
+// protectGenerated reports whether the file with the given URI is a
+// generated file that gopls must not edit, which is the case unless the
+// EditGeneratedFiles option is set.
+func protectGenerated(ctx context.Context, view View, uri span.URI) bool {
+	return !view.Options().EditGeneratedFiles && IsGenerated(ctx, view, uri)
+}
+
+// generatedDiagnostics reports the generated files of pkg whose content in
+// the editor differs from that on disk, at the comment that marks them as
+// generated, as the edits will be lost when they are generated again.
+func generatedDiagnostics(ctx context.Context, view View, pkg Package, reports map[span.URI][]Diagnostic) error {
+	ctx, done := trace.StartSpan(ctx, "source.generatedDiagnostics")
+	defer done()
+
+	if view.Options().EditGeneratedFiles {
+		return nil
+	}
+	fset := view.Session().Cache().FileSet()
+	for _, file := range pkg.GetSyntax() {
+		comment := generatedComment(fset, file)
+		if comment == nil {
+			continue
+		}
+		uri := span.FileURI(fset.Position(file.Pos()).Filename)
+		if !view.Session().IsOpen(uri) {
+			continue
+		}
+		edited, _, err := view.Session().GetFile(uri, Go).Read(ctx)
+		if err != nil {
+			return err
+		}
+		saved, _, err := view.Session().Cache().GetFile(uri, Go).Read(ctx)
+		if err != nil || bytes.Equal(edited, saved) {
+			continue
+		}
+		rng, err := posToMappedRange(ctx, pkg, comment.Pos(), comment.End())
+		if err != nil {
+			return err
+		}
+		protocolRange, err := rng.Range()
+		if err != nil {
+			return err
+		}
+		addReport(view, reports, Diagnostic{
+			URI:      rng.URI(),
+			Range:    protocolRange,
+			Message:  fmt.Sprintf("%s is a generated file: the edits will be lost when it is generated again", uri.Filename()),
+			Source:   GeneratedSource,
+			Severity: protocol.SeverityWarning,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,564 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/internal/lsp/analysis/tagname"
+	"golang.org/x/tools/internal/lsp/diff"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// GenerateTypeCommand is the command that inserts the Go types of a JSON or
+// YAML document into a Go file.
+const GenerateTypeCommand = "gopls.GenerateTypeFromJSON"
+
+// GenerateTypeArgs are the arguments to GenerateTypeCommand. The document is
+// Content, such as the content of the clipboard of the client, or otherwise
+// the content of File.
+type GenerateTypeArgs struct {
+	// URI is the Go file the types are inserted into, after the declaration
+	// that contains or precedes Position.
+	URI      protocol.DocumentUri `json:"uri"`
+	Position protocol.Position    `json:"position"`
+
+	// Name is the name of the type of the document, Generated by default.
+	// The types of the nested objects are named after their fields.
+	Name string `json:"name,omitempty"`
+
+	Content string `json:"content,omitempty"`
+	File    string `json:"file,omitempty"`
+
+	// Format is "json" or "yaml". By default, it is that of the extension of
+	// File, or otherwise JSON if the document starts with { or [.
+	Format string `json:"format,omitempty"`
+
+	// Pointers makes the fields of the objects that are missing or null in
+	// some of the values of the document pointers.
+	Pointers bool `json:"pointers,omitempty"`
+
+	// DetectTime makes the fields whose values are all RFC 3339 times, such
+	// as "2019-10-12T07:20:50Z", time.Time.
+	DetectTime bool `json:"detectTime,omitempty"`
+}
+
+// GenerateType returns a fix that inserts the declarations of the Go types of
+// the JSON or YAML document of args into f, with a tag for each of their
+// fields, and the import of the time package if they use it.
+func GenerateType(ctx context.Context, view View, f File, args GenerateTypeArgs) (*SuggestedFix, error) {
+	ctx, done := trace.StartSpan(ctx, "source.GenerateType")
+	defer done()
+
+	data := []byte(args.Content)
+	if args.Content == "" {
+		if args.File == "" {
+			return nil, errors.Errorf("no content or file to generate a type from")
+		}
+		var err error
+		if data, err = ioutil.ReadFile(args.File); err != nil {
+			return nil, err
+		}
+	}
+	opts := genTypeOptions{
+		format:     args.Format,
+		pointers:   args.Pointers,
+		detectTime: args.DetectTime,
+	}
+	if opts.format == "" {
+		switch filepath.Ext(args.File) {
+		case ".json":
+			opts.format = "json"
+		case ".yaml", ".yml":
+			opts.format = "yaml"
+		}
+	}
+
+	fh := view.Snapshot().Handle(ctx, f)
+	ph := view.Session().ParseGoHandle(fh, ParseFull)
+	file, m, _, err := ph.Parse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	spn, err := m.PointSpan(args.Position)
+	if err != nil {
+		return nil, err
+	}
+	rng, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, err
+	}
+	name := args.Name
+	if name == "" {
+		name = "Generated"
+	}
+	fset := view.Session().Cache().FileSet()
+	return generateTypeFix(fset, file, m, rng.Start, data, name, opts)
+}
+
+// GenerateTypeFromLiteral returns a fix that inserts the declarations of the
+// Go types of the JSON or YAML object, or array of objects, of the string
+// literal at the start of rng in f, as GenerateType does, or nil if there is
+// none. The type of the document is named after the variable that the
+// literal is assigned to.
+func GenerateTypeFromLiteral(ctx context.Context, view View, f File, rng protocol.Range) (*SuggestedFix, error) {
+	fh := view.Snapshot().Handle(ctx, f)
+	ph := view.Session().ParseGoHandle(fh, ParseFull)
+	file, m, _, err := ph.Parse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	spn, err := m.RangeSpan(rng)
+	if err != nil {
+		return nil, err
+	}
+	r, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, err
+	}
+	path, _ := astutil.PathEnclosingInterval(file, r.Start, r.Start)
+	if len(path) < 2 {
+		return nil, nil
+	}
+	lit, ok := path[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return nil, nil
+	}
+	content, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return nil, nil
+	}
+	name := "Generated"
+	switch n := path[1].(type) {
+	case *ast.ValueSpec:
+		for i, v := range n.Values {
+			if v == lit && i < len(n.Names) {
+				name = exportedName(n.Names[i].Name)
+			}
+		}
+	case *ast.AssignStmt:
+		for i, v := range n.Rhs {
+			if v != lit || i >= len(n.Lhs) {
+				continue
+			}
+			if id, ok := n.Lhs[i].(*ast.Ident); ok {
+				name = exportedName(id.Name)
+			}
+		}
+	}
+	for i := 2; file.Scope != nil && file.Scope.Lookup(name) != nil; i++ {
+		name = fmt.Sprintf("%s%d", strings.TrimRight(name, "0123456789"), i)
+	}
+	fset := view.Session().Cache().FileSet()
+	fix, err := generateTypeFix(fset, file, m, r.Start, []byte(content), name, genTypeOptions{detectTime: true})
+	if err != nil {
+		// The literal is not a document.
+		return nil, nil
+	}
+	return fix, nil
+}
+
+// generateTypeFix returns the fix of GenerateType for the document data.
+func generateTypeFix(fset *token.FileSet, file *ast.File, m *protocol.ColumnMapper, pos token.Pos, data []byte, name string, opts genTypeOptions) (*SuggestedFix, error) {
+	if opts.format == "" {
+		opts.format = "yaml"
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+			opts.format = "json"
+		}
+	}
+	decls, usesTime, err := generateType(data, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Insert the declarations after the declaration that contains or
+	// precedes pos, and after the imports.
+	anchor := file.Name.End()
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT || decl.Pos() <= pos {
+			anchor = decl.End()
+		}
+	}
+	tok := fset.File(file.Pos())
+	if tok == nil {
+		return nil, errors.Errorf("no file for %s", m.URI)
+	}
+	offset := tok.Offset(anchor)
+	edits := []diff.TextEdit{{
+		Span:    span.New(m.URI, span.NewPoint(0, 0, offset), span.NewPoint(0, 0, offset)),
+		NewText: "\n\n" + decls,
+	}}
+	if usesTime {
+		importEdits, err := addNamedImport(fset, file, "", "time")
+		if err != nil {
+			return nil, err
+		}
+		edits = append(importEdits, edits...)
+	}
+	protocolEdits, err := ToProtocolEdits(m, edits)
+	if err != nil {
+		return nil, err
+	}
+	title := "Generate Go type from JSON"
+	if opts.format == "yaml" {
+		title = "Generate Go type from YAML"
+	}
+	return &SuggestedFix{
+		Title: title,
+		Edits: map[span.URI][]protocol.TextEdit{m.URI: protocolEdits},
+	}, nil
+}
+
+type genTypeOptions struct {
+	format     string // "json" or "yaml"
+	pointers   bool
+	detectTime bool
+}
+
+// genValue is a value of a JSON or YAML document, with the members of its
+// objects in order.
+type genValue struct {
+	kind    genKind
+	str     string // of a string
+	elems   []*genValue
+	members []genMember
+}
+
+type genMember struct {
+	key   string
+	value *genValue
+}
+
+type genKind int
+
+const (
+	genNull genKind = iota
+	genBool
+	genInt
+	genFloat
+	genString
+	genArray
+	genObject
+)
+
+// generateType returns the formatted declarations of the Go types of the
+// JSON or YAML object, or array of objects, data, the first of which is named
+// name, and whether they use time.Time.
+func generateType(data []byte, name string, opts genTypeOptions) (string, bool, error) {
+	var (
+		v   *genValue
+		err error
+	)
+	if opts.format == "yaml" {
+		v, err = parseYAML(string(data))
+	} else {
+		v, err = parseJSON(data)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	s := &genShape{}
+	switch v.kind {
+	case genObject:
+		s.add(v)
+	case genArray:
+		for _, elem := range v.elems {
+			if elem.kind != genObject {
+				return "", false, errors.Errorf("the document is not an array of objects")
+			}
+			s.add(elem)
+		}
+	default:
+		return "", false, errors.Errorf("the document is not an object or an array of objects")
+	}
+	if s.object == nil {
+		return "", false, errors.Errorf("the document has no objects")
+	}
+	tagKey := "json"
+	if opts.format == "yaml" {
+		tagKey = "yaml"
+	}
+	g := &typeGenerator{opts: opts, tagKey: tagKey, names: make(map[string]bool)}
+	g.structType(s.object, name)
+
+	src, err := format.Source([]byte("package p\n\n" + strings.Join(g.decls, "\n")))
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSpace(strings.TrimPrefix(string(src), "package p\n")), g.usesTime, nil
+}
+
+// parseJSON parses a JSON document.
+func parseJSON(data []byte) (*genValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.Errorf("invalid JSON after the document")
+	}
+	return v, nil
+}
+
+func decodeJSONValue(dec *json.Decoder) (*genValue, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := t.(type) {
+	case json.Delim:
+		v := &genValue{kind: genArray}
+		if t == '{' {
+			v.kind = genObject
+		}
+		for dec.More() {
+			var key string
+			if v.kind == genObject {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key, _ = k.(string)
+			}
+			elem, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if v.kind == genObject {
+				v.members = append(v.members, genMember{key, elem})
+			} else {
+				v.elems = append(v.elems, elem)
+			}
+		}
+		if _, err := dec.Token(); err != nil { // the closing delimiter
+			return nil, err
+		}
+		return v, nil
+	case bool:
+		return &genValue{kind: genBool}, nil
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return &genValue{kind: genInt}, nil
+		}
+		return &genValue{kind: genFloat}, nil
+	case string:
+		return &genValue{kind: genString, str: t}, nil
+	}
+	return &genValue{kind: genNull}, nil
+}
+
+// genShape is the union of the values at a place of a document, such as the
+// elements of an array, or the values of a key of several objects.
+type genShape struct {
+	kinds   map[genKind]bool
+	null    bool
+	strings []string
+	elem    *genShape // of arrays, nil if they are empty
+	object  *genObjectShape
+}
+
+type genObjectShape struct {
+	count  int // of objects
+	keys   []string
+	fields map[string]*genFieldShape
+}
+
+type genFieldShape struct {
+	count int // of objects with the key
+	shape *genShape
+}
+
+func (s *genShape) add(v *genValue) {
+	if v.kind == genNull {
+		s.null = true
+		return
+	}
+	if s.kinds == nil {
+		s.kinds = make(map[genKind]bool)
+	}
+	s.kinds[v.kind] = true
+	switch v.kind {
+	case genString:
+		s.strings = append(s.strings, v.str)
+	case genArray:
+		for _, elem := range v.elems {
+			if s.elem == nil {
+				s.elem = &genShape{}
+			}
+			s.elem.add(elem)
+		}
+	case genObject:
+		if s.object == nil {
+			s.object = &genObjectShape{fields: make(map[string]*genFieldShape)}
+		}
+		o := s.object
+		o.count++
+		for _, m := range v.members {
+			f, ok := o.fields[m.key]
+			if !ok {
+				f = &genFieldShape{shape: &genShape{}}
+				o.fields[m.key] = f
+				o.keys = append(o.keys, m.key)
+			}
+			f.count++
+			f.shape.add(m.value)
+		}
+	}
+}
+
+type typeGenerator struct {
+	opts     genTypeOptions
+	tagKey   string
+	decls    []string        // of the types, each after its enclosing type
+	names    map[string]bool // of the types
+	usesTime bool
+}
+
+// structType writes the declaration of the struct type named name, or a
+// name derived from it, for o and the types of its fields, and returns the
+// name of the type.
+func (g *typeGenerator) structType(o *genObjectShape, name string) string {
+	base := name
+	for i := 2; g.names[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	g.names[name] = true
+	decl := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var fields bytes.Buffer
+	fieldNames := make(map[string]bool)
+	for _, key := range o.keys {
+		f := o.fields[key]
+		fieldName := exportedName(key)
+		for i, base := 2, fieldName; fieldNames[fieldName]; i++ {
+			fieldName = fmt.Sprintf("%s%d", base, i)
+		}
+		fieldNames[fieldName] = true
+
+		optional := f.count < o.count || f.shape.null
+		typ := g.goType(f.shape, fieldName)
+		if optional && g.opts.pointers && !strings.HasPrefix(typ, "[]") && typ != "interface{}" {
+			typ = "*" + typ
+		}
+		tag := key
+		if optional {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&fields, "\t%s %s `%s:%s`\n", fieldName, typ, g.tagKey, strconv.Quote(tag))
+	}
+	g.decls[decl] = fmt.Sprintf("type %s struct {\n%s}\n", name, fields.String())
+	return name
+}
+
+// goType returns the Go type of the values of s, declaring the struct types
+// of their objects after name.
+func (g *typeGenerator) goType(s *genShape, name string) string {
+	if len(s.kinds) == 2 && s.kinds[genInt] && s.kinds[genFloat] {
+		return "float64"
+	}
+	if len(s.kinds) != 1 {
+		return "interface{}"
+	}
+	switch {
+	case s.kinds[genBool]:
+		return "bool"
+	case s.kinds[genInt]:
+		return "int"
+	case s.kinds[genFloat]:
+		return "float64"
+	case s.kinds[genString]:
+		if g.opts.detectTime && allTimes(s.strings) {
+			g.usesTime = true
+			return "time.Time"
+		}
+		return "string"
+	case s.kinds[genArray]:
+		if s.elem == nil {
+			return "[]interface{}"
+		}
+		return "[]" + g.goType(s.elem, singular(name))
+	}
+	return g.structType(s.object, name)
+}
+
+// allTimes reports whether strs are all RFC 3339 times.
+func allTimes(strs []string) bool {
+	for _, s := range strs {
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return false
+		}
+	}
+	return len(strs) > 0
+}
+
+// commonInitialisms are the words that are written in upper case in Go
+// names, as golint suggests.
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true,
+	"DNS": true, "EOF": true, "GUID": true, "HTML": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true,
+	"QPS": true, "RAM": true, "RHS": true, "RPC": true, "SLA": true,
+	"SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true,
+	"URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true,
+	"XMPP": true, "XSRF": true, "XSS": true, "YAML": true,
+}
+
+// exportedName returns the exported Go name of the key of an object, such
+// as UserID for user_id.
+func exportedName(key string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		for _, w := range tagname.Words(part) {
+			if upper := strings.ToUpper(w); commonInitialisms[upper] {
+				b.WriteString(upper)
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(w)
+			b.WriteString(string(unicode.ToUpper(r)) + w[size:])
+		}
+	}
+	name := b.String()
+	if r, _ := utf8.DecodeRuneInString(name); name == "" || unicode.IsDigit(r) {
+		name = "X" + name
+	}
+	return name
+}
+
+// singular returns the singular of the plural English noun name, or name if
+// it is not plural.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "ss"):
+		return name
+	case strings.HasSuffix(name, "s") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name
+}
@@ -0,0 +1,159 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"testing"
+)
+
+func TestGenerateType(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		src      string
+		opts     genTypeOptions
+		want     string
+		usesTime bool
+	}{
+		{
+			name: "json",
+			src: `{
+	"user_id": 1,
+	"name": "gopher",
+	"score": 1.5,
+	"admin": true,
+	"tags": ["a", "b"],
+	"home_url": null,
+	"address": {"street": "x", "zip": 12345},
+	"friends": [{"id": 2, "name": "a"}, {"id": 3}],
+	"extra": [],
+	"2fa": false
+}`,
+			opts: genTypeOptions{format: "json"},
+			want: "type User struct {\n" +
+				"\tUserID  int           `json:\"user_id\"`\n" +
+				"\tName    string        `json:\"name\"`\n" +
+				"\tScore   float64       `json:\"score\"`\n" +
+				"\tAdmin   bool          `json:\"admin\"`\n" +
+				"\tTags    []string      `json:\"tags\"`\n" +
+				"\tHomeURL interface{}   `json:\"home_url,omitempty\"`\n" +
+				"\tAddress Address       `json:\"address\"`\n" +
+				"\tFriends []Friend      `json:\"friends\"`\n" +
+				"\tExtra   []interface{} `json:\"extra\"`\n" +
+				"\tX2fa    bool          `json:\"2fa\"`\n" +
+				"}\n\n" +
+				"type Address struct {\n" +
+				"\tStreet string `json:\"street\"`\n" +
+				"\tZip    int    `json:\"zip\"`\n" +
+				"}\n\n" +
+				"type Friend struct {\n" +
+				"\tID   int    `json:\"id\"`\n" +
+				"\tName string `json:\"name,omitempty\"`\n" +
+				"}",
+		},
+		{
+			name: "pointers and times",
+			src:  `[{"at": "2019-10-12T07:20:50Z", "n": 1}, {"at": "2019-10-12T07:20:50.52+02:00", "n": 1.5, "opt": "x"}]`,
+			opts: genTypeOptions{format: "json", pointers: true, detectTime: true},
+			want: "type User struct {\n" +
+				"\tAt  time.Time `json:\"at\"`\n" +
+				"\tN   float64   `json:\"n\"`\n" +
+				"\tOpt *string   `json:\"opt,omitempty\"`\n" +
+				"}",
+			usesTime: true,
+		},
+		{
+			name: "yaml",
+			src: `# A service.
+name: api
+replicas: 3
+labels:
+  app: api
+ports:
+- name: http
+  port: 80
+- name: https # secure
+  port: 443
+  tls: true
+env: [1, 2]
+`,
+			opts: genTypeOptions{format: "yaml"},
+			want: "type User struct {\n" +
+				"\tName     string `yaml:\"name\"`\n" +
+				"\tReplicas int    `yaml:\"replicas\"`\n" +
+				"\tLabels   Labels `yaml:\"labels\"`\n" +
+				"\tPorts    []Port `yaml:\"ports\"`\n" +
+				"\tEnv      []int  `yaml:\"env\"`\n" +
+				"}\n\n" +
+				"type Labels struct {\n" +
+				"\tApp string `yaml:\"app\"`\n" +
+				"}\n\n" +
+				"type Port struct {\n" +
+				"\tName string `yaml:\"name\"`\n" +
+				"\tPort int    `yaml:\"port\"`\n" +
+				"\tTLS  bool   `yaml:\"tls,omitempty\"`\n" +
+				"}",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, usesTime, err := generateType([]byte(test.src), "User", test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+			if usesTime != test.usesTime {
+				t.Errorf("got usesTime %v, want %v", usesTime, test.usesTime)
+			}
+		})
+	}
+}
+
+func TestGenerateTypeErrors(t *testing.T) {
+	for _, src := range []string{`1`, `[1, 2]`, `{"a": 1`, `{} {}`} {
+		if _, _, err := generateType([]byte(src), "T", genTypeOptions{format: "json"}); err == nil {
+			t.Errorf("%s: got no error", src)
+		}
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	const src = `
+---
+description: |
+  two
+  lines
+items:
+  - - 1
+    - 2
+  - []
+quoted: "a # b"
+'key': 'it''s'
+empty:
+`
+	v, err := parseYAML(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.kind != genObject || len(v.members) != 5 {
+		t.Fatalf("got %+v, want an object with 5 members", v)
+	}
+	if got := v.members[0].value.str; got != "two\nlines" {
+		t.Errorf("got block scalar %q", got)
+	}
+	items := v.members[1].value
+	if items.kind != genArray || len(items.elems) != 2 || len(items.elems[0].elems) != 2 || items.elems[1].kind != genArray {
+		t.Errorf("got items %+v", items)
+	}
+	if got := v.members[2].value.str; got != "a # b" {
+		t.Errorf("got quoted %q", got)
+	}
+	if m := v.members[3]; m.key != "key" || m.value.str != "it's" {
+		t.Errorf("got %q: %q", m.key, m.value.str)
+	}
+	if v.members[4].value.kind != genNull {
+		t.Errorf("got empty %+v", v.members[4].value)
+	}
+}
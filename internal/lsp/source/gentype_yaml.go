@@ -0,0 +1,227 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"strconv"
+	"strings"
+
+	errors "golang.org/x/xerrors"
+)
+
+// yamlLine is a line of a YAML document that is not blank or a comment.
+type yamlLine struct {
+	num    int // 1-based
+	indent int
+	text   string // without its indentation and comment
+}
+
+// yamlParser parses the subset of YAML that configuration files commonly
+// use: block mappings and sequences, plain and quoted scalars, literal and
+// folded block scalars, and flow collections that are valid JSON. Anchors,
+// aliases, tags and multiple documents are not supported.
+type yamlParser struct {
+	lines []yamlLine
+	next  int
+}
+
+// parseYAML parses a YAML document.
+func parseYAML(src string) (*genValue, error) {
+	p := &yamlParser{}
+	for i, line := range strings.Split(src, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		text := strings.TrimLeft(line, " ")
+		if text == "" || text[0] == '#' || line == "---" || line == "..." {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(line) - len(text), text: text})
+	}
+	if len(p.lines) == 0 {
+		return nil, errors.Errorf("empty YAML document")
+	}
+	v, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.next < len(p.lines) {
+		return nil, errors.Errorf("line %d: unexpected indentation", p.lines[p.next].num)
+	}
+	return v, nil
+}
+
+// parseBlock parses the mapping, sequence or scalar at the current line,
+// which is indented by indent.
+func (p *yamlParser) parseBlock(indent int) (*genValue, error) {
+	line := p.lines[p.next]
+	if isSequenceItem(line.text) {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitMappingEntry(line.text); ok {
+		return p.parseMapping(indent)
+	}
+	p.next++
+	return yamlScalar(stripComment(line.text)), nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (*genValue, error) {
+	v := &genValue{kind: genArray}
+	for p.next < len(p.lines) {
+		line := p.lines[p.next]
+		if line.indent != indent || !isSequenceItem(line.text) {
+			break
+		}
+		rest := strings.TrimLeft(line.text[1:], " ")
+		var (
+			elem *genValue
+			err  error
+		)
+		if rest == "" {
+			p.next++
+			elem, err = p.parseNested(indent)
+		} else {
+			// The rest of the line starts a block, as if it were on a line
+			// of its own.
+			p.lines[p.next] = yamlLine{num: line.num, indent: line.indent + len(line.text) - len(rest), text: rest}
+			elem, err = p.parseBlock(p.lines[p.next].indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		v.elems = append(v.elems, elem)
+	}
+	return v, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (*genValue, error) {
+	v := &genValue{kind: genObject}
+	for p.next < len(p.lines) {
+		line := p.lines[p.next]
+		if line.indent != indent || isSequenceItem(line.text) {
+			break
+		}
+		key, value, ok := splitMappingEntry(line.text)
+		if !ok {
+			return nil, errors.Errorf("line %d: expected a key", line.num)
+		}
+		p.next++
+		var (
+			elem *genValue
+			err  error
+		)
+		switch value = stripComment(value); {
+		case value == "":
+			// A sequence may have the indentation of its key.
+			if p.next < len(p.lines) && p.lines[p.next].indent == indent && isSequenceItem(p.lines[p.next].text) {
+				elem, err = p.parseSequence(indent)
+			} else {
+				elem, err = p.parseNested(indent)
+			}
+		case value[0] == '|' || value[0] == '>':
+			elem = &genValue{kind: genString, str: p.blockScalar(indent)}
+		default:
+			elem = yamlScalar(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		v.members = append(v.members, genMember{key, elem})
+	}
+	return v, nil
+}
+
+// parseNested parses the block that is indented more than indent at the
+// current line, or returns null if there is none.
+func (p *yamlParser) parseNested(indent int) (*genValue, error) {
+	if p.next < len(p.lines) && p.lines[p.next].indent > indent {
+		return p.parseBlock(p.lines[p.next].indent)
+	}
+	return &genValue{kind: genNull}, nil
+}
+
+// blockScalar returns the lines that are indented more than indent, from the
+// current line, joined by newlines.
+func (p *yamlParser) blockScalar(indent int) string {
+	var lines []string
+	for p.next < len(p.lines) && p.lines[p.next].indent > indent {
+		lines = append(lines, p.lines[p.next].text)
+		p.next++
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitMappingEntry splits the text of a "key: value" line. The key may be
+// quoted.
+func splitMappingEntry(text string) (key, value string, ok bool) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", false
+		}
+		key, rest := text[:end+2], text[end+2:]
+		if rest != ":" && !strings.HasPrefix(rest, ": ") {
+			return "", "", false
+		}
+		return yamlScalar(key).str, strings.TrimSpace(rest[1:]), true
+	}
+	if text[0] == '[' || text[0] == '{' {
+		return "", "", false
+	}
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false
+		}
+		i = len(text) - 1
+	}
+	return text[:i], strings.TrimSpace(text[i+1:]), true
+}
+
+// stripComment returns the value of a line without its comment, unless the
+// value is quoted.
+func stripComment(value string) string {
+	if value != "" && (value[0] == '"' || value[0] == '\'') {
+		return value
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// yamlScalar returns the value of a scalar, or of a flow collection that is
+// valid JSON.
+func yamlScalar(text string) *genValue {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return &genValue{kind: genNull}
+	case "true", "True", "TRUE", "false", "False", "FALSE":
+		return &genValue{kind: genBool}
+	}
+	switch text[0] {
+	case '"':
+		if s, err := strconv.Unquote(text); err == nil {
+			return &genValue{kind: genString, str: s}
+		}
+	case '\'':
+		if len(text) >= 2 && text[len(text)-1] == '\'' {
+			return &genValue{kind: genString, str: strings.Replace(text[1:len(text)-1], "''", "'", -1)}
+		}
+	case '[', '{':
+		if v, err := parseJSON([]byte(text)); err == nil {
+			return v
+		}
+	}
+	if _, err := strconv.ParseInt(text, 0, 64); err == nil {
+		return &genValue{kind: genInt}
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil && strings.ContainsAny(text, "0123456789") {
+		return &genValue{kind: genFloat}
+	}
+	return &genValue{kind: genString, str: text}
+}
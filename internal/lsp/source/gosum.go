@@ -0,0 +1,290 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/log"
+	errors "golang.org/x/xerrors"
+)
+
+// GoSumSource is the source of the diagnostics of the requirements of a
+// go.mod file whose go.sum entries are missing or do not match.
+const GoSumSource = "go.sum"
+
+// GoSumProblem is a module whose go.sum entries are missing or do not match
+// what the go command downloaded.
+type GoSumProblem struct {
+	// Module and Version are those of the module, if the go command names
+	// it. Otherwise, Package is a package that it provides.
+	Module, Version string
+	Package         string
+
+	// Mismatch reports whether the go.sum entry does not match, rather than
+	// being missing.
+	Mismatch bool
+}
+
+// GoSumError is the error of go/packages.Load when no package of the module
+// in Dir can be loaded because of the entries of its go.sum file.
+type GoSumError struct {
+	Dir      string
+	Problems []GoSumProblem
+	Msg      string
+}
+
+func (e *GoSumError) Error() string {
+	return e.Msg
+}
+
+var (
+	// missingGoSumModuleRegexp matches the error of the go command about a
+	// module whose go.sum entries are missing, capturing its path and
+	// version.
+	missingGoSumModuleRegexp = regexp.MustCompile(`(\S+)@(\S+): missing go\.sum entry`)
+
+	// missingGoSumPackageRegexp matches the error of the go command about an
+	// imported package whose module has no go.sum entry, capturing the
+	// package path.
+	missingGoSumPackageRegexp = regexp.MustCompile(`missing go\.sum entry for module providing package (\S+)`)
+
+	// goSumMismatchRegexp matches the error of the go command about a module
+	// that does not match its go.sum entry, capturing its path and version.
+	goSumMismatchRegexp = regexp.MustCompile(`verifying (\S+)@([^\s/:]+)(?:/go\.mod)?: checksum mismatch`)
+)
+
+// ParseGoSumProblems returns the modules whose go.sum entries the error
+// message of the go command reports as missing or mismatched, in order and
+// without duplicates.
+func ParseGoSumProblems(msg string) []GoSumProblem {
+	var problems []GoSumProblem
+	seen := make(map[GoSumProblem]bool)
+	add := func(p GoSumProblem) {
+		if !seen[p] {
+			seen[p] = true
+			problems = append(problems, p)
+		}
+	}
+	for _, line := range strings.Split(msg, "\n") {
+		if m := goSumMismatchRegexp.FindStringSubmatch(line); m != nil {
+			add(GoSumProblem{Module: m[1], Version: m[2], Mismatch: true})
+		} else if m := missingGoSumModuleRegexp.FindStringSubmatch(line); m != nil {
+			add(GoSumProblem{Module: m[1], Version: m[2]})
+		} else if m := missingGoSumPackageRegexp.FindStringSubmatch(line); m != nil {
+			add(GoSumProblem{Package: m[1]})
+		}
+	}
+	return problems
+}
+
+// goSumDiagnostics returns the diagnostics of the problems on the require
+// lines of the modules in the go.mod file with the given URI, or on its
+// module directive for the modules that it does not require.
+func goSumDiagnostics(ctx context.Context, view View, gomod span.URI, problems []GoSumProblem) ([]Diagnostic, error) {
+	if len(problems) == 0 {
+		return nil, nil
+	}
+	content, _, err := view.Session().GetFile(gomod, Mod).Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m := &protocol.ColumnMapper{
+		URI:       gomod,
+		Converter: span.NewContentConverter(gomod.Filename(), content),
+		Content:   content,
+		Encoding:  view.Options().PositionEncoding,
+	}
+	var diagnostics []Diagnostic
+	for _, p := range problems {
+		start, end := requireLine(content, p)
+		rng, err := offsetRange(m, start, end)
+		if err != nil {
+			return nil, err
+		}
+		var msg string
+		switch {
+		case p.Mismatch:
+			msg = fmt.Sprintf("%s@%s does not match its go.sum entry; check that it was not tampered with before updating go.sum", p.Module, p.Version)
+		case p.Module != "":
+			msg = fmt.Sprintf("missing go.sum entry for %s@%s", p.Module, p.Version)
+		default:
+			msg = fmt.Sprintf("missing go.sum entry for the module providing package %s", p.Package)
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			URI:      gomod,
+			Range:    rng,
+			Message:  msg,
+			Source:   GoSumSource,
+			Severity: protocol.SeverityError,
+		})
+	}
+	return diagnostics, nil
+}
+
+// requireLine returns the offsets of the module path of the requirement of
+// the module of p in the go.mod file with the given content, or those of its
+// module directive if it has no such requirement.
+func requireLine(content []byte, p GoSumProblem) (start, end int) {
+	found := ""
+	for _, d := range parseModDirectives(content) {
+		switch d.verb {
+		case "module":
+			if found == "" {
+				start, end = d.start, d.end
+			}
+		case "require":
+			for _, e := range d.entries {
+				var match bool
+				if p.Module != "" {
+					match = e.name == p.Module
+				} else {
+					// The longest module path that is a prefix of the
+					// package path.
+					match = (p.Package == e.name || strings.HasPrefix(p.Package, e.name+"/")) && len(e.name) > len(found)
+				}
+				if match {
+					found, start, end = e.name, e.start, e.nameEnd
+				}
+			}
+		}
+	}
+	return start, end
+}
+
+// goModDiagnostics returns the diagnostics of the go.mod file with the given
+// URI: those of its replace directives, and those of the go.sum problems that
+// the snapshot of view found when it last loaded the packages of its module.
+func goModDiagnostics(ctx context.Context, view View, gomod span.URI) ([]Diagnostic, error) {
+	diagnostics, err := replaceDiagnostics(ctx, view, gomod)
+	if err != nil {
+		return nil, err
+	}
+	problems, _ := view.Snapshot().GoSumProblems(gomod)
+	sums, err := goSumDiagnostics(ctx, view, gomod, problems)
+	if err != nil {
+		return nil, err
+	}
+	return append(diagnostics, sums...), nil
+}
+
+// goSumReports adds the diagnostics of the go.mod file of the module that
+// contains the file with the given URI to reports, if the snapshot of view
+// found go.sum problems when it loaded the packages of the module.
+func goSumReports(ctx context.Context, view View, uri span.URI, reports map[span.URI][]Diagnostic) error {
+	path := findGoMod(filepath.Dir(uri.Filename()))
+	if path == "" {
+		return nil
+	}
+	gomod := span.FileURI(path)
+	if problems, _ := view.Snapshot().GoSumProblems(gomod); len(problems) == 0 {
+		return nil
+	}
+	diagnostics, err := goModDiagnostics(ctx, view, gomod)
+	if err != nil {
+		return err
+	}
+	reports[gomod] = diagnostics
+	return nil
+}
+
+// goSumErrorDiagnostics returns the diagnostic of the file with the given URI
+// when no package of its module could be loaded because of its go.sum file,
+// along with those of the go.mod file of the module.
+func goSumErrorDiagnostics(ctx context.Context, view View, uri span.URI, err *GoSumError) map[span.URI][]Diagnostic {
+	reports := map[span.URI][]Diagnostic{
+		uri: {{
+			URI:      uri,
+			Range:    protocol.Range{},
+			Message:  err.Msg,
+			Source:   GoSumSource,
+			Severity: protocol.SeverityError,
+		}},
+	}
+	if err := goSumReports(ctx, view, uri, reports); err != nil {
+		log.Error(ctx, "failed to report go.sum problems", err, telemetry.File.Of(uri))
+	}
+	return reports
+}
+
+// GoSumCommands returns the commands that fix the go.sum problem of the
+// diagnostic of the go.mod file f: one that runs "go mod tidy", and one that
+// runs "go mod download" for the module of the require line of the
+// diagnostic. A module that does not match its go.sum entry is not fixed, as
+// it may have been tampered with.
+func GoSumCommands(ctx context.Context, view View, f File, diag protocol.Diagnostic) ([]protocol.Command, error) {
+	m, err := fileMapper(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	problems, _ := view.Snapshot().GoSumProblems(f.URI())
+	diagnostics, err := goSumDiagnostics(ctx, view, f.URI(), problems)
+	if err != nil {
+		return nil, err
+	}
+	// There is a diagnostic for each problem.
+	for i, d := range diagnostics {
+		if protocol.CompareRange(d.Range, diag.Range) != 0 || d.Message != diag.Message {
+			continue
+		}
+		if problems[i].Mismatch {
+			return nil, nil
+		}
+		commands := []protocol.Command{{
+			Title:     "Run go mod tidy",
+			Command:   "tidy",
+			Arguments: []interface{}{f.URI()},
+		}}
+		for _, dir := range parseModDirectives(m.Content) {
+			for _, e := range dir.entries {
+				if dir.verb != "require" {
+					continue
+				}
+				if rng, err := offsetRange(m, e.start, e.nameEnd); err == nil && protocol.CompareRange(rng, d.Range) == 0 {
+					commands = append(commands, protocol.Command{
+						Title:     fmt.Sprintf("Run go mod download %s", e.name),
+						Command:   "download",
+						Arguments: []interface{}{DownloadArgs{URI: protocol.NewURI(f.URI()), Module: e.name}},
+					})
+				}
+			}
+		}
+		return commands, nil
+	}
+	return nil, errors.Errorf("no go.sum problem at %v in %s", diag.Range, f.URI())
+}
+
+// DownloadArgs are the arguments of the "download" command.
+type DownloadArgs struct {
+	// URI is the URI of the go.mod file of the main module.
+	URI protocol.DocumentURI `json:"uri"`
+
+	// Module is the path of the module to download.
+	Module string `json:"module"`
+}
+
+// ModDownload runs "go mod download" for the module of args in the main
+// module of the go.mod file of args, which adds its missing go.sum entries.
+func ModDownload(ctx context.Context, view View, args DownloadArgs) error {
+	gomod := span.NewURI(args.URI)
+	if filepath.Base(gomod.Filename()) != "go.mod" {
+		return errors.Errorf("%s is not a go.mod file", gomod.Filename())
+	}
+	if args.Module == "" {
+		return errors.Errorf("no module to download")
+	}
+	// Like `go mod tidy`, `go mod download` modifies the files on disk directly.
+	if _, err := invokeGo(ctx, filepath.Dir(gomod.Filename()), view.Config(ctx).Env, "mod", "download", args.Module); err != nil {
+		return err
+	}
+	return nil
+}
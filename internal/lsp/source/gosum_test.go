@@ -0,0 +1,60 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGoSumProblems(t *testing.T) {
+	const msg = `go [list -e -json -compiled=true -test=true -export=false -deps=true -find=false -- file=/tmp/m/a.go]: exit status 1: go: golang.org/x/sync@v0.1.0: missing go.sum entry for go.mod file; to add it:
+	go mod download golang.org/x/sync
+verifying golang.org/x/mod@v0.3.0/go.mod: checksum mismatch
+	downloaded: h1:AAAA
+	go.sum:     h1:BBBB
+missing go.sum entry for module providing package golang.org/x/text/language (imported by example.com/m); to add:
+	go get example.com/m
+go: golang.org/x/sync@v0.1.0: missing go.sum entry for go.mod file; to add it:
+`
+	want := []GoSumProblem{
+		{Module: "golang.org/x/sync", Version: "v0.1.0"},
+		{Module: "golang.org/x/mod", Version: "v0.3.0", Mismatch: true},
+		{Package: "golang.org/x/text/language"},
+	}
+	if got := ParseGoSumProblems(msg); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := ParseGoSumProblems("go: cannot find main module"); got != nil {
+		t.Errorf("got %+v for an unrelated error", got)
+	}
+}
+
+func TestRequireLine(t *testing.T) {
+	const content = `module example.com/m
+
+require golang.org/x/sync v0.1.0
+
+require (
+	golang.org/x/text v0.3.0
+	golang.org/x/text/language/sub v0.1.0 // indirect
+)
+`
+	for _, test := range []struct {
+		problem GoSumProblem
+		want    string
+	}{
+		{GoSumProblem{Module: "golang.org/x/sync", Version: "v0.1.0"}, "golang.org/x/sync"},
+		{GoSumProblem{Package: "golang.org/x/text/language"}, "golang.org/x/text"},
+		{GoSumProblem{Package: "golang.org/x/text/language/sub/x"}, "golang.org/x/text/language/sub"},
+		// A module that is not required is reported on the module directive.
+		{GoSumProblem{Module: "golang.org/x/mod", Version: "v0.3.0"}, "module example.com/m"},
+	} {
+		start, end := requireLine([]byte(content), test.problem)
+		if got := content[start:end]; got != test.want {
+			t.Errorf("%+v: got %q, want %q", test.problem, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !go1.18
+
+package source
+
+import "go/ast"
+
+// typeParams returns nil, as the syntax trees of Go versions before 1.18
+// have no type parameters.
+func typeParams(n ast.Node) *ast.FieldList {
+	return nil
+}
@@ -0,0 +1,21 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build go1.18
+
+package source
+
+import "go/ast"
+
+// typeParams returns the type parameters declared by n, a function type or
+// a type specification.
+func typeParams(n ast.Node) *ast.FieldList {
+	switch n := n.(type) {
+	case *ast.FuncType:
+		return n.TypeParams
+	case *ast.TypeSpec:
+		return n.TypeParams
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// GoVersionSource is the source of the diagnostics of language features that
+// are newer than the go directive of the module.
+const GoVersionSource = "go version"
+
+// goDirective is the go directive of a go.mod file.
+type goDirective struct {
+	uri span.URI
+
+	// minor is the minor version of the directive, as in go 1.minor.
+	minor int
+
+	// rng is the range of the version in the directive.
+	rng protocol.Range
+}
+
+// languageFeature is a use of a language feature that was added to Go in
+// version 1.minor.
+type languageFeature struct {
+	name     string
+	minor    int
+	pos, end token.Pos
+}
+
+// moduleGoDirective returns the go directive of the go.mod file of the module
+// that contains filename. It returns nil if the file is not in a module, or if
+// the go.mod file has no go directive.
+func moduleGoDirective(ctx context.Context, view View, filename string) (*goDirective, error) {
+	gomod := findGoMod(filepath.Dir(filename))
+	if gomod == "" {
+		return nil, nil
+	}
+	uri := span.FileURI(gomod)
+	data, _, err := view.Session().GetFile(uri, Mod).Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseGoDirective(uri, data), nil
+}
+
+// parseGoDirective returns the go directive of the go.mod file with the
+// given contents, or nil if it has none.
+func parseGoDirective(uri span.URI, data []byte) *goDirective {
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if j := bytes.Index(line, []byte("//")); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(string(line))
+		if len(fields) != 2 || fields[0] != "go" {
+			continue
+		}
+		minor, ok := minorVersion(fields[1])
+		if !ok {
+			return nil
+		}
+		start := bytes.Index(line, []byte(fields[1]))
+		return &goDirective{
+			uri:   uri,
+			minor: minor,
+			rng: protocol.Range{
+				Start: protocol.Position{Line: float64(i), Character: float64(start)},
+				End:   protocol.Position{Line: float64(i), Character: float64(start + len(fields[1]))},
+			},
+		}
+	}
+	return nil
+}
+
+// minorVersion returns the minor version of a Go version such as 1.13 or
+// 1.21.0.
+func minorVersion(version string) (int, bool) {
+	if !strings.HasPrefix(version, "1.") {
+		return 0, false
+	}
+	version = version[len("1."):]
+	if i := strings.Index(version, "."); i >= 0 {
+		version = version[:i]
+	}
+	minor, err := strconv.Atoi(version)
+	return minor, err == nil
+}
+
+// languageFeatures returns the uses in file of the language features that
+// were added to Go after its first module-aware release.
+func languageFeatures(file *ast.File, info *types.Info) []languageFeature {
+	var features []languageFeature
+	add := func(name string, minor int, n ast.Node) {
+		features = append(features, languageFeature{name: name, minor: minor, pos: n.Pos(), end: n.End()})
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BasicLit:
+			if name := numberLiteralFeature(n); name != "" {
+				add(name, 13, n)
+			}
+		case *ast.FuncType, *ast.TypeSpec:
+			if tparams := typeParams(n); tparams != nil && len(tparams.List) > 0 {
+				add("type parameters", 18, tparams)
+			}
+		case *ast.Ident:
+			if b, ok := info.Uses[n].(*types.Builtin); ok {
+				switch b.Name() {
+				case "clear", "max", "min":
+					add(fmt.Sprintf("predeclared %s", b.Name()), 21, n)
+				}
+			}
+		case *ast.RangeStmt:
+			tv, ok := info.Types[n.X]
+			if !ok || tv.Type == nil {
+				break
+			}
+			switch t := tv.Type.Underlying().(type) {
+			case *types.Basic:
+				if t.Info()&types.IsInteger != 0 {
+					add("range over int", 22, n.X)
+				}
+			case *types.Signature:
+				add("range over function", 23, n.X)
+			}
+		}
+		return true
+	})
+	return features
+}
+
+// numberLiteralFeature returns the name of the Go 1.13 number literal
+// feature that lit uses, if any.
+func numberLiteralFeature(lit *ast.BasicLit) string {
+	if lit.Kind != token.INT && lit.Kind != token.FLOAT && lit.Kind != token.IMAG {
+		return ""
+	}
+	value := lit.Value
+	switch {
+	case strings.HasPrefix(value, "0b"), strings.HasPrefix(value, "0B"):
+		return "binary literals"
+	case strings.HasPrefix(value, "0o"), strings.HasPrefix(value, "0O"):
+		return "0o/0O-style octal literals"
+	case (strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0X")) && lit.Kind != token.INT:
+		return "hexadecimal floating-point literals"
+	case strings.Contains(value, "_"):
+		return "'_' in numeric literals"
+	}
+	return ""
+}
+
+// goVersionDiagnostics reports the uses of language features in the files of
+// pkg that are newer than the go directive of their module.
+func goVersionDiagnostics(ctx context.Context, view View, pkg Package, reports map[span.URI][]Diagnostic) error {
+	ctx, done := trace.StartSpan(ctx, "source.goVersionDiagnostics")
+	defer done()
+
+	fset := view.Session().Cache().FileSet()
+	for _, file := range pkg.GetSyntax() {
+		directive, err := moduleGoDirective(ctx, view, fset.Position(file.Pos()).Filename)
+		if err != nil {
+			return err
+		}
+		if directive == nil {
+			continue
+		}
+		for _, feature := range languageFeatures(file, pkg.GetTypesInfo()) {
+			if feature.minor <= directive.minor {
+				continue
+			}
+			rng, err := posToMappedRange(ctx, pkg, feature.pos, feature.end)
+			if err != nil {
+				return err
+			}
+			protocolRange, err := rng.Range()
+			if err != nil {
+				return err
+			}
+			addReport(view, reports, Diagnostic{
+				URI:            rng.URI(),
+				Range:          protocolRange,
+				Message:        fmt.Sprintf("%s requires go1.%d or later (go.mod declares go 1.%d)", feature.name, feature.minor, directive.minor),
+				Source:         GoVersionSource,
+				Severity:       protocol.SeverityError,
+				SuggestedFixes: []SuggestedFix{upgradeGoDirective(directive, feature.minor)},
+			})
+		}
+	}
+	return nil
+}
+
+// upgradeGoDirective returns a fix that sets the version of the go directive
+// to go 1.minor.
+func upgradeGoDirective(directive *goDirective, minor int) SuggestedFix {
+	return SuggestedFix{
+		Title: fmt.Sprintf("Upgrade go directive to go 1.%d", minor),
+		Edits: map[span.URI][]protocol.TextEdit{
+			directive.uri: {{
+				Range:   directive.rng,
+				NewText: fmt.Sprintf("1.%d", minor),
+			}},
+		},
+	}
+}
+
+// GoVersionFixes returns the fixes of a diagnostic reported for a language
+// feature of f that is newer than the go directive of its module: the upgrade
+// of the directive.
+func GoVersionFixes(ctx context.Context, snapshot Snapshot, f File, diag protocol.Diagnostic) ([]SuggestedFix, error) {
+	cphs, err := snapshot.CheckPackageHandles(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	cph, err := WidestCheckPackageHandle(cphs)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := cph.Check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ph, err := pkg.File(f.URI())
+	if err != nil {
+		return nil, err
+	}
+	file, m, _, err := ph.Parse(ctx)
+	if file == nil {
+		return nil, err
+	}
+	directive, err := moduleGoDirective(ctx, snapshot.View(), f.URI().Filename())
+	if err != nil {
+		return nil, err
+	}
+	if directive == nil {
+		return nil, errors.Errorf("no go directive for %s", f.URI())
+	}
+	for _, feature := range languageFeatures(file, pkg.GetTypesInfo()) {
+		rng, err := posToRange(ctx, snapshot.View(), m, feature.pos, feature.end)
+		if err != nil {
+			return nil, err
+		}
+		if protocolRange, err := rng.Range(); err != nil || protocol.CompareRange(protocolRange, diag.Range) != 0 {
+			continue
+		}
+		return []SuggestedFix{upgradeGoDirective(directive, feature.minor)}, nil
+	}
+	return nil, errors.Errorf("no language feature at %v in %s", diag.Range, f.URI())
+}
@@ -0,0 +1,84 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+)
+
+func TestParseGoDirective(t *testing.T) {
+	for _, test := range []struct {
+		content string
+		minor   int
+		rng     protocol.Range
+	}{
+		{"module m\n\ngo 1.13\n", 13, protocol.Range{
+			Start: protocol.Position{Line: 2, Character: 3},
+			End:   protocol.Position{Line: 2, Character: 7},
+		}},
+		{"module m\ngo 1.21.0 // comment\n", 21, protocol.Range{
+			Start: protocol.Position{Line: 1, Character: 3},
+			End:   protocol.Position{Line: 1, Character: 9},
+		}},
+		{"module m\n", -1, protocol.Range{}},
+		{"module m\ngo one\n", -1, protocol.Range{}},
+	} {
+		directive := parseGoDirective("file:///go.mod", []byte(test.content))
+		if directive == nil {
+			if test.minor >= 0 {
+				t.Errorf("%q: got no directive, want go 1.%d", test.content, test.minor)
+			}
+			continue
+		}
+		if directive.minor != test.minor || directive.rng != test.rng {
+			t.Errorf("%q: got go 1.%d at %v, want go 1.%d at %v", test.content, directive.minor, directive.rng, test.minor, test.rng)
+		}
+	}
+}
+
+func TestLanguageFeatures(t *testing.T) {
+	const src = `package p
+
+const (
+	a = 0b101
+	b = 0o17
+	c = 0x1p-2
+	d = 1_000
+	e = 0x1F
+)
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	if _, err := (&types.Config{}).Check("p", fset, []*ast.File{file}, info); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, feature := range languageFeatures(file, info) {
+		got = append(got, feature.name+" "+src[fset.Position(feature.pos).Offset:fset.Position(feature.end).Offset])
+	}
+	want := []string{
+		"binary literals 0b101",
+		"0o/0O-style octal literals 0o17",
+		"hexadecimal floating-point literals 0x1p-2",
+		"'_' in numeric literals 1_000",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got features %q, want %q", got, want)
+	}
+}
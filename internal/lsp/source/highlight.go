@@ -7,6 +7,8 @@ package source
 import (
 	"context"
 	"go/ast"
+	"go/token"
+	"sort"
 
 	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/internal/lsp/protocol"
@@ -15,7 +17,11 @@ import (
 	errors "golang.org/x/xerrors"
 )
 
-func Highlight(ctx context.Context, view View, uri span.URI, pos protocol.Position) ([]protocol.Range, error) {
+// Highlight returns the highlights of the identifier or control-flow keyword at
+// pos in the file uri. The declarations and assignments of an identifier and
+// the uses that take its address are highlighted as writes, and its other uses
+// as reads; control flow is highlighted as text.
+func Highlight(ctx context.Context, view View, uri span.URI, pos protocol.Position) ([]protocol.DocumentHighlight, error) {
 	ctx, done := trace.StartSpan(ctx, "source.Highlight")
 	defer done()
 
@@ -24,7 +30,7 @@ func Highlight(ctx context.Context, view View, uri span.URI, pos protocol.Positi
 		return nil, err
 	}
 	fh := view.Snapshot().Handle(ctx, f)
-	ph := view.Session().Cache().ParseGoHandle(fh, ParseFull)
+	ph := view.Session().ParseGoHandle(fh, ParseFull)
 	file, m, _, err := ph.Parse(ctx)
 	if err != nil {
 		return nil, err
@@ -41,22 +47,274 @@ func Highlight(ctx context.Context, view View, uri span.URI, pos protocol.Positi
 	if len(path) == 0 {
 		return nil, errors.Errorf("no enclosing position found for %v:%v", int(pos.Line), int(pos.Character))
 	}
-	id, ok := path[0].(*ast.Ident)
-	if !ok {
-		// If the cursor is not within an identifier, return empty results.
+	switch node := path[0].(type) {
+	case *ast.Ident:
+		return highlightIdentifier(ctx, view, m, node, path)
+	case *ast.ReturnStmt:
+		return textHighlights(highlightFuncControlFlow(ctx, view, m, path))
+	case *ast.FuncDecl:
+		// Treat the "func" keyword of a declaration like a return.
+		if rng.Start < node.Type.Func+token.Pos(len("func")) {
+			return textHighlights(highlightFuncControlFlow(ctx, view, m, path))
+		}
+	case *ast.FuncType:
+		// Treat the "func" keyword of a function literal like a return.
+		if _, ok := path[1].(*ast.FuncLit); ok && rng.Start < node.Func+token.Pos(len("func")) {
+			return textHighlights(highlightFuncControlFlow(ctx, view, m, path[1:]))
+		}
+	case *ast.BranchStmt:
+		return textHighlights(highlightBranchControlFlow(ctx, view, m, node, path))
+	case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+		// Treat the keyword of a loop, switch, or select like a branch
+		// statement that targets it.
+		if rng.Start < node.Pos()+token.Pos(len(stmtKeyword(node))) {
+			return textHighlights(highlightBranchTarget(ctx, view, m, node, node))
+		}
+	}
+	// If the cursor is not within an identifier or a control-flow keyword,
+	// return empty results.
+	return []protocol.DocumentHighlight{}, nil
+}
+
+func highlightIdentifier(ctx context.Context, view View, m *protocol.ColumnMapper, id *ast.Ident, path []ast.Node) ([]protocol.DocumentHighlight, error) {
+	var result []protocol.DocumentHighlight
+	if id.Obj == nil {
+		return result, nil
+	}
+	// The stack holds the ancestors of the current node, outermost first.
+	var stack []ast.Node
+	ast.Inspect(path[len(path)-1], func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if n, ok := n.(*ast.Ident); ok && n.Obj == id.Obj {
+			rng, err := nodeToProtocolRange(ctx, view, m, n)
+			if err == nil {
+				parents := make([]ast.Node, len(stack))
+				for i := range stack {
+					parents[i] = stack[len(stack)-1-i]
+				}
+				result = append(result, protocol.DocumentHighlight{
+					Range: rng,
+					Kind:  highlightKind(ClassifyReference(n, parents)),
+				})
+			}
+		}
+		stack = append(stack, n)
+		return true
+	})
+	return result, nil
+}
+
+// highlightKind returns the kind of the highlight of a reference of the given
+// kind.
+func highlightKind(kind ReferenceKind) *protocol.DocumentHighlightKind {
+	k := protocol.Read
+	switch kind {
+	case ReferenceDeclaration, ReferenceWrite, ReferenceAddress:
+		k = protocol.Write
+	case ReferenceOther:
+		k = protocol.Text
+	}
+	return &k
+}
+
+// textHighlights returns the ranges rngs as text highlights.
+func textHighlights(rngs []protocol.Range, err error) ([]protocol.DocumentHighlight, error) {
+	if err != nil {
+		return nil, err
+	}
+	result := make([]protocol.DocumentHighlight, 0, len(rngs))
+	for _, rng := range rngs {
+		kind := protocol.Text
+		result = append(result, protocol.DocumentHighlight{Range: rng, Kind: &kind})
+	}
+	return result, nil
+}
+
+// highlightFuncControlFlow highlights the signature of the innermost function
+// in path, along with every return statement of that function.
+func highlightFuncControlFlow(ctx context.Context, view View, m *protocol.ColumnMapper, path []ast.Node) ([]protocol.Range, error) {
+	var (
+		typ  *ast.FuncType
+		body *ast.BlockStmt
+	)
+	switch fn := enclosingFunc(path).(type) {
+	case *ast.FuncDecl:
+		typ, body = fn.Type, fn.Body
+	case *ast.FuncLit:
+		typ, body = fn.Type, fn.Body
+	}
+	if typ == nil || body == nil {
 		return []protocol.Range{}, nil
 	}
 	var result []protocol.Range
-	if id.Obj != nil {
-		ast.Inspect(path[len(path)-1], func(n ast.Node) bool {
-			if n, ok := n.(*ast.Ident); ok && n.Obj == id.Obj {
-				rng, err := nodeToProtocolRange(ctx, view, m, n)
-				if err == nil {
-					result = append(result, rng)
+	// Highlight the "func" keyword and the result list, if any.
+	if typ.Func.IsValid() {
+		result = appendRange(ctx, view, m, result, typ.Func, typ.Func+token.Pos(len("func")))
+	}
+	if typ.Results != nil {
+		for _, field := range typ.Results.List {
+			result = appendRange(ctx, view, m, result, field.Pos(), field.End())
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// Return statements in nested function literals
+			// belong to a different function.
+			return false
+		case *ast.ReturnStmt:
+			result = appendRange(ctx, view, m, result, n.Return, n.Return+token.Pos(len("return")))
+		}
+		return true
+	})
+	return result, nil
+}
+
+// highlightBranchControlFlow highlights the statement targeted by a break,
+// continue, or goto statement, along with every branch statement that
+// targets the same statement.
+func highlightBranchControlFlow(ctx context.Context, view View, m *protocol.ColumnMapper, branch *ast.BranchStmt, path []ast.Node) ([]protocol.Range, error) {
+	target := branchTarget(branch, path[1:])
+	if target == nil {
+		return []protocol.Range{}, nil
+	}
+	scope := target
+	if branch.Tok == token.GOTO {
+		// A goto may jump to a label from anywhere in the function,
+		// so search the entire enclosing function.
+		scope = enclosingFunc(path)
+	}
+	return highlightBranchTarget(ctx, view, m, target, scope)
+}
+
+// highlightBranchTarget highlights the keyword or label of target, along with
+// every branch statement in scope that jumps to it.
+func highlightBranchTarget(ctx context.Context, view View, m *protocol.ColumnMapper, target, scope ast.Node) ([]protocol.Range, error) {
+	var result []protocol.Range
+	switch target := target.(type) {
+	case *ast.LabeledStmt:
+		result = appendRange(ctx, view, m, result, target.Label.Pos(), target.Label.End())
+	default:
+		result = appendRange(ctx, view, m, result, target.Pos(), target.Pos()+token.Pos(len(stmtKeyword(target))))
+	}
+	// Collect all of the branch statements that jump to the same target.
+	// The stack holds the ancestors of the current node, outermost first.
+	var stack []ast.Node
+	ast.Inspect(scope, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		if _, ok := n.(*ast.FuncLit); ok && n != scope {
+			return false
+		}
+		if b, ok := n.(*ast.BranchStmt); ok {
+			ancestors := make([]ast.Node, len(stack))
+			for i := range stack {
+				ancestors[i] = stack[len(stack)-1-i]
+			}
+			if branchTarget(b, ancestors) == target {
+				result = appendRange(ctx, view, m, result, b.Pos(), b.Pos()+token.Pos(len(b.Tok.String())))
+			}
+		}
+		stack = append(stack, n)
+		return true
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return protocol.CompareRange(result[i], result[j]) < 0
+	})
+	return result, nil
+}
+
+// appendRange appends the range between pos and end to rngs, unless it
+// cannot be mapped.
+func appendRange(ctx context.Context, view View, m *protocol.ColumnMapper, rngs []protocol.Range, pos, end token.Pos) []protocol.Range {
+	if mrng, err := posToRange(ctx, view, m, pos, end); err == nil {
+		if rng, err := mrng.Range(); err == nil {
+			rngs = append(rngs, rng)
+		}
+	}
+	return rngs
+}
+
+// branchTarget returns the statement that the given branch statement jumps
+// to, or nil if it cannot be determined. The ancestors of the branch
+// statement must be ordered from innermost to outermost.
+// For labeled branches, the target is the *ast.LabeledStmt.
+func branchTarget(branch *ast.BranchStmt, ancestors []ast.Node) ast.Node {
+	if branch.Tok == token.FALLTHROUGH {
+		return nil
+	}
+	for _, n := range ancestors {
+		switch n := n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			if branch.Label != nil && branch.Tok == token.GOTO {
+				if l := findLabeledStmt(n, branch.Label.Name); l != nil {
+					return l
 				}
 			}
-			return true
-		})
+			return nil
+		case *ast.LabeledStmt:
+			if branch.Label != nil && branch.Label.Name == n.Label.Name {
+				return n
+			}
+		case *ast.ForStmt, *ast.RangeStmt:
+			if branch.Label == nil && (branch.Tok == token.BREAK || branch.Tok == token.CONTINUE) {
+				return n
+			}
+		case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			if branch.Label == nil && branch.Tok == token.BREAK {
+				return n
+			}
+		}
 	}
-	return result, nil
+	return nil
+}
+
+// findLabeledStmt returns the statement with the given label in the body of
+// fn, ignoring nested function literals.
+func findLabeledStmt(fn ast.Node, name string) *ast.LabeledStmt {
+	var result *ast.LabeledStmt
+	ast.Inspect(fn, func(n ast.Node) bool {
+		if result != nil {
+			return false
+		}
+		if lit, ok := n.(*ast.FuncLit); ok && lit != fn {
+			return false
+		}
+		if l, ok := n.(*ast.LabeledStmt); ok && l.Label.Name == name {
+			result = l
+			return false
+		}
+		return true
+	})
+	return result
+}
+
+// enclosingFunc returns the innermost *ast.FuncDecl or *ast.FuncLit in path,
+// or nil if there is none.
+func enclosingFunc(path []ast.Node) ast.Node {
+	for _, n := range path {
+		switch n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			return n
+		}
+	}
+	return nil
+}
+
+// stmtKeyword returns the keyword that begins the given statement.
+func stmtKeyword(n ast.Node) string {
+	switch n.(type) {
+	case *ast.ForStmt, *ast.RangeStmt:
+		return "for"
+	case *ast.SwitchStmt, *ast.TypeSwitchStmt:
+		return "switch"
+	case *ast.SelectStmt:
+		return "select"
+	}
+	return ""
 }
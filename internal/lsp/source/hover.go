@@ -10,7 +10,9 @@ import (
 	"go/ast"
 	"go/doc"
 	"go/format"
+	"go/token"
 	"go/types"
+	"path/filepath"
 	"strings"
 
 	"golang.org/x/tools/internal/telemetry/trace"
@@ -31,10 +33,31 @@ type HoverInformation struct {
 	// FullDocumentation is the symbol's full documentation.
 	FullDocumentation string `json:"fullDocumentation"`
 
+	// Module is the module of the package imported by an import spec, if
+	// the package is in the module cache.
+	Module *ModuleMetadata `json:"module,omitempty"`
+
+	// Links are the links to the documentation of the symbol, and of the
+	// named types that its signature refers to.
+	Links []HoverLink `json:"links,omitempty"`
+
+	// Implements are the interfaces of the workspace that a named type
+	// implements, or the types that implement an interface, if the
+	// HoverImplements option is set.
+	Implements *HoverImplements `json:"implements,omitempty"`
+
 	source  interface{}
 	comment *ast.CommentGroup
 }
 
+// HoverLink is a link to the documentation of a symbol or package.
+type HoverLink struct {
+	// Name is the symbol qualified by the name of its package, such as
+	// io.Reader, or the path of the package.
+	Name   string `json:"name"`
+	Target string `json:"target"`
+}
+
 func (i *IdentifierInfo) Hover(ctx context.Context) (*HoverInformation, error) {
 	ctx, done := trace.StartSpan(ctx, "source.Hover")
 	defer done()
@@ -63,9 +86,173 @@ func (i *IdentifierInfo) Hover(ctx context.Context) (*HoverInformation, error) {
 		h.FullDocumentation = h.comment.Text()
 		h.Synopsis = doc.Synopsis(h.FullDocumentation)
 	}
+	if imported := i.Declaration.importedPkg; imported != nil {
+		if pkg := imported.GetTypes(); pkg != nil {
+			h.SingleLine = fmt.Sprintf("package %s (%q)", pkg.Name(), pkg.Path())
+		}
+		h.Module = importedModule(ctx, i.Snapshot.View(), imported)
+	}
+	if options := i.Snapshot.View().Options(); options.LinksInHover && options.LinkTarget != "" {
+		view := i.Snapshot.View()
+		cache, env := view.Session().Cache(), view.Config(ctx).Env
+		l := &hoverLinker{
+			target: options.LinkTarget,
+			module: func(pos token.Pos) *ModuleMetadata {
+				return moduleMetadata(cache, env, cache.FileSet().Position(pos).Filename)
+			},
+			seen: make(map[types.Object]bool),
+		}
+		if imported := i.Declaration.importedPkg; imported != nil {
+			if pkg, syntax := imported.GetTypes(), imported.GetSyntax(); pkg != nil && len(syntax) > 0 {
+				l.links = append(l.links, HoverLink{
+					Name:   pkg.Path(),
+					Target: l.packageURL(pkg, syntax[0].Pos()),
+				})
+			}
+		} else if obj := i.Declaration.obj; obj != nil {
+			l.addObject(obj)
+			// The declaration of a type is its signature, so the types
+			// it refers to are those of its underlying type.
+			if _, ok := obj.(*types.TypeName); ok {
+				l.addType(obj.Type().Underlying())
+			} else {
+				l.addType(obj.Type())
+			}
+		}
+		h.Links = l.links
+	}
+	if obj, ok := i.Declaration.obj.(*types.TypeName); ok && i.Snapshot.View().Options().HoverImplements {
+		h.Implements = hoverImplements(ctx, i.Snapshot, obj)
+	}
 	return h, nil
 }
 
+// hoverLinker collects the links to the documentation of a symbol and of
+// the named types its signature refers to.
+type hoverLinker struct {
+	// target is the host of the documentation site.
+	target string
+
+	// module returns the metadata of the module in the module cache that
+	// declares the symbol at pos, if any.
+	module func(pos token.Pos) *ModuleMetadata
+
+	seen  map[types.Object]bool
+	links []HoverLink
+}
+
+// addObject adds the link to the documentation of obj, if it has one: it
+// must be exported, and declared at package level or be a method.
+func (l *hoverLinker) addObject(obj types.Object) {
+	pkg := obj.Pkg()
+	if pkg == nil || pkg.Name() == "main" || strings.HasSuffix(pkg.Path(), "_test") || !obj.Exported() || l.seen[obj] {
+		return
+	}
+	l.seen[obj] = true
+	fragment := obj.Name()
+	if obj.Parent() != pkg.Scope() {
+		fn, ok := obj.(*types.Func)
+		if !ok {
+			return
+		}
+		recv := fn.Type().(*types.Signature).Recv()
+		if recv == nil {
+			return
+		}
+		named, ok := deref(recv.Type()).(*types.Named)
+		if !ok {
+			return
+		}
+		fragment = named.Obj().Name() + "." + fragment
+	}
+	l.links = append(l.links, HoverLink{
+		Name:   pkg.Name() + "." + fragment,
+		Target: l.packageURL(pkg, obj.Pos()) + "#" + fragment,
+	})
+}
+
+// addType adds the links to the named types that typ refers to, without
+// looking into their underlying types.
+func (l *hoverLinker) addType(typ types.Type) {
+	switch typ := typ.(type) {
+	case *types.Named:
+		l.addObject(typ.Obj())
+	case *types.Pointer:
+		l.addType(typ.Elem())
+	case *types.Slice:
+		l.addType(typ.Elem())
+	case *types.Array:
+		l.addType(typ.Elem())
+	case *types.Chan:
+		l.addType(typ.Elem())
+	case *types.Map:
+		l.addType(typ.Key())
+		l.addType(typ.Elem())
+	case *types.Signature:
+		for i := 0; i < typ.Params().Len(); i++ {
+			l.addType(typ.Params().At(i).Type())
+		}
+		for i := 0; i < typ.Results().Len(); i++ {
+			l.addType(typ.Results().At(i).Type())
+		}
+	case *types.Struct:
+		for i := 0; i < typ.NumFields(); i++ {
+			l.addType(typ.Field(i).Type())
+		}
+	case *types.Interface:
+		for i := 0; i < typ.NumEmbeddeds(); i++ {
+			l.addType(typ.EmbeddedType(i))
+		}
+		for i := 0; i < typ.NumExplicitMethods(); i++ {
+			l.addType(typ.ExplicitMethod(i).Type())
+		}
+	}
+}
+
+// packageURL returns the URL of the documentation of pkg, whose symbol at
+// pos is linked to. On pkg.go.dev, the documentation of the packages in the
+// module cache is that of the version of their module.
+func (l *hoverLinker) packageURL(pkg *types.Package, pos token.Pos) string {
+	path := pkg.Path()
+	if l.target == "pkg.go.dev" && pos.IsValid() {
+		if m := l.module(pos); m != nil && strings.HasPrefix(path, m.Path) {
+			path = m.Path + "@" + m.Version + strings.TrimPrefix(path, m.Path)
+		}
+	}
+	return "https://" + l.target + "/" + path
+}
+
+// packageDoc returns the package comment of pkg, preferring that of its
+// doc.go file, as go doc does.
+func packageDoc(pkg Package) *ast.CommentGroup {
+	var comment *ast.CommentGroup
+	for _, ph := range pkg.Files() {
+		file, _, _, err := ph.Cached()
+		if err != nil || file == nil || file.Doc == nil {
+			continue
+		}
+		if filepath.Base(ph.File().Identity().URI.Filename()) == "doc.go" {
+			return file.Doc
+		}
+		if comment == nil {
+			comment = file.Doc
+		}
+	}
+	return comment
+}
+
+// importedModule returns the metadata of the module of the imported package
+// pkg, or nil if it is not in the module cache, as for the packages of the
+// standard library and of the workspace.
+func importedModule(ctx context.Context, view View, pkg Package) *ModuleMetadata {
+	files := pkg.Files()
+	if len(files) == 0 {
+		return nil
+	}
+	filename := files[0].File().Identity().URI.Filename()
+	return moduleMetadata(view.Session().Cache(), view.Config(ctx).Env, filename)
+}
+
 // objectString is a wrapper around the types.ObjectString function.
 // It handles adding more information to the object string.
 func objectString(obj types.Object, qf types.Qualifier) string {
@@ -84,7 +271,11 @@ func (d Declaration) hover(ctx context.Context) (*HoverInformation, error) {
 	obj := d.obj
 	switch node := d.node.(type) {
 	case *ast.ImportSpec:
-		return &HoverInformation{source: node}, nil
+		h := &HoverInformation{source: node}
+		if d.importedPkg != nil {
+			h.comment = packageDoc(d.importedPkg)
+		}
+		return h, nil
 	case *ast.GenDecl:
 		switch obj := obj.(type) {
 		case *types.TypeName, *types.Var, *types.Const, *types.Func:
@@ -0,0 +1,92 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+func TestHoverLinks(t *testing.T) {
+	fset := token.NewFileSet()
+	check := func(path, src string, imports map[string]*types.Package) *types.Package {
+		f, err := parser.ParseFile(fset, path+".go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf := types.Config{Importer: importerFunc(func(path string) (*types.Package, error) {
+			return imports[path], nil
+		})}
+		pkg, err := conf.Check(path, fset, []*ast.File{f}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkg
+	}
+	dep := check("example.com/mod/dep", `package dep
+type Reader interface{ Read() }
+type Option int
+type unexported int
+`, nil)
+	pkg := check("example.com/app/a", `package a
+import "example.com/mod/dep"
+type T struct {
+	dep.Reader
+	opts []dep.Option
+	u    *U
+}
+type U int
+func (t *T) Do(r dep.Reader, f func(dep.Option) error) (map[string]*U, error) { return nil, nil }
+`, map[string]*types.Package{"example.com/mod/dep": dep})
+
+	links := func(obj types.Object) []HoverLink {
+		l := &hoverLinker{
+			target: "pkg.go.dev",
+			module: func(pos token.Pos) *ModuleMetadata {
+				if fset.Position(pos).Filename == "example.com/mod/dep.go" {
+					return &ModuleMetadata{Path: "example.com/mod", Version: "v1.2.0"}
+				}
+				return nil
+			},
+			seen: make(map[types.Object]bool),
+		}
+		l.addObject(obj)
+		if _, ok := obj.(*types.TypeName); ok {
+			l.addType(obj.Type().Underlying())
+		} else {
+			l.addType(obj.Type())
+		}
+		return l.links
+	}
+	typ := pkg.Scope().Lookup("T")
+	if got, want := links(typ), []HoverLink{
+		{Name: "a.T", Target: "https://pkg.go.dev/example.com/app/a#T"},
+		{Name: "dep.Reader", Target: "https://pkg.go.dev/example.com/mod@v1.2.0/dep#Reader"},
+		{Name: "dep.Option", Target: "https://pkg.go.dev/example.com/mod@v1.2.0/dep#Option"},
+		{Name: "a.U", Target: "https://pkg.go.dev/example.com/app/a#U"},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("links of T: got %v, want %v", got, want)
+	}
+	method, _, _ := types.LookupFieldOrMethod(typ.Type(), true, pkg, "Do")
+	if got, want := links(method), []HoverLink{
+		{Name: "a.T.Do", Target: "https://pkg.go.dev/example.com/app/a#T.Do"},
+		{Name: "dep.Reader", Target: "https://pkg.go.dev/example.com/mod@v1.2.0/dep#Reader"},
+		{Name: "dep.Option", Target: "https://pkg.go.dev/example.com/mod@v1.2.0/dep#Option"},
+		{Name: "a.U", Target: "https://pkg.go.dev/example.com/app/a#U"},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("links of T.Do: got %v, want %v", got, want)
+	}
+	if got := links(dep.Scope().Lookup("unexported")); len(got) != 0 {
+		t.Errorf("got links %v for an unexported type", got)
+	}
+}
@@ -34,6 +34,7 @@ type IdentifierInfo struct {
 
 	pkg              Package
 	ident            *ast.Ident
+	selection        *types.Selection // if ident is the name of a selector
 	wasEmbeddedField bool
 	qf               types.Qualifier
 }
@@ -43,6 +44,9 @@ type Declaration struct {
 	node        ast.Node
 	obj         types.Object
 	wasImplicit bool
+
+	// importedPkg is the package imported by node, if it is an import spec.
+	importedPkg Package
 }
 
 // Identifier returns identifier information for a position
@@ -62,7 +66,13 @@ func Identifier(ctx context.Context, view View, f File, pos protocol.Position) (
 	}
 	ph, err := pkg.File(f.URI())
 	if err != nil {
-		return nil, err
+		// The files that use cgo are type-checked from the files that cgo
+		// generates from them.
+		file, p, cgoErr := cgoGeneratedPos(ctx, view, pkg, f.URI(), pos)
+		if cgoErr != nil {
+			return nil, err
+		}
+		return findIdentifier(ctx, snapshot, pkg, file, p)
 	}
 	file, m, _, err := ph.Cached()
 	if err != nil {
@@ -109,7 +119,7 @@ func identifier(ctx context.Context, snapshot Snapshot, pkg Package, file *ast.F
 		return nil, errors.Errorf("can't find node enclosing position")
 	}
 	view := pkg.View()
-	uri := span.FileURI(view.Session().Cache().FileSet().Position(pos).Filename)
+	uri := span.FileURI(view.Session().Cache().FileSet().PositionFor(pos, false).Filename)
 	var ph ParseGoHandle
 	for _, h := range pkg.Files() {
 		if h.File().Identity().URI == uri {
@@ -134,6 +144,11 @@ func identifier(ctx context.Context, snapshot Snapshot, pkg Package, file *ast.F
 		}
 	}
 	result.Name = result.ident.Name
+	for _, n := range path[:2] {
+		if sel, ok := n.(*ast.SelectorExpr); ok && sel.Sel == result.ident {
+			result.selection = pkg.GetTypesInfo().Selections[sel]
+		}
+	}
 	if result.mappedRange, err = posToMappedRange(ctx, pkg, result.ident.Pos(), result.ident.End()); err != nil {
 		return nil, err
 	}
@@ -243,7 +258,7 @@ func hasErrorType(obj types.Object) bool {
 
 func objToNode(ctx context.Context, pkg Package, obj types.Object) (ast.Decl, error) {
 	view := pkg.View()
-	uri := span.FileURI(view.Session().Cache().FileSet().Position(obj.Pos()).Filename)
+	uri := span.FileURI(view.Session().Cache().FileSet().PositionFor(obj.Pos(), false).Filename)
 	ph, _, err := pkg.FindFile(ctx, uri)
 	if err != nil {
 		return nil, err
@@ -292,7 +307,7 @@ func importSpec(ctx context.Context, snapshot Snapshot, pkg Package, file *ast.F
 	if err != nil {
 		return nil, errors.Errorf("import path not quoted: %s (%v)", imp.Path.Value, err)
 	}
-	uri := span.FileURI(pkg.View().Session().Cache().FileSet().Position(pos).Filename)
+	uri := span.FileURI(pkg.View().Session().Cache().FileSet().PositionFor(pos, false).Filename)
 	var ph ParseGoHandle
 	for _, h := range pkg.Files() {
 		if h.File().Identity().URI == uri {
@@ -330,6 +345,7 @@ func importSpec(ctx context.Context, snapshot Snapshot, pkg Package, file *ast.F
 		return nil, err
 	}
 	result.Declaration.node = imp
+	result.Declaration.importedPkg = importedPkg
 	return result, nil
 }
 
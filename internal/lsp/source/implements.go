@@ -0,0 +1,124 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"go/types"
+	"sort"
+	"time"
+)
+
+// HoverImplements lists the interfaces of the workspace that a named type
+// implements or, for an interface, the types of the workspace that implement
+// it.
+type HoverImplements struct {
+	// Interface reports whether the type is an interface.
+	Interface bool `json:"interface"`
+
+	// Count is the number of interfaces or types, of which Types are the
+	// first few, linked to their declarations.
+	Count int         `json:"count"`
+	Types []HoverLink `json:"types"`
+}
+
+// maxHoverImplements is the number of interfaces or types that hover lists.
+const maxHoverImplements = 5
+
+// hoverImplementsBudget bounds the time that hover waits for the method sets
+// of the workspace. The files that were indexed before the budget ran out
+// are not indexed again, so a later hover may succeed.
+const hoverImplementsBudget = 100 * time.Millisecond
+
+// hoverImplements returns the implementations of the named type obj in the
+// workspace, or nil if there are none or they could not be found in time.
+// The method sets are matched by the fingerprints of their methods, so an
+// interface is listed if a type has methods with the same names and numbers
+// of parameters and results.
+func hoverImplements(ctx context.Context, snapshot Snapshot, obj *types.TypeName) *HoverImplements {
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, hoverImplementsBudget)
+	defer cancel()
+	sets, err := snapshot.WorkspaceMethodSets(ctx)
+	if err != nil {
+		return nil
+	}
+
+	var methods []string
+	iface, isInterface := named.Underlying().(*types.Interface)
+	if isInterface {
+		for i := 0; i < iface.NumMethods(); i++ {
+			methods = append(methods, signatureFingerprint(iface.Method(i)))
+		}
+		if len(methods) == 0 {
+			// Every type implements the empty interface.
+			return nil
+		}
+	} else {
+		mset := types.NewMethodSet(types.NewPointer(named))
+		for i := 0; i < mset.Len(); i++ {
+			methods = append(methods, signatureFingerprint(mset.At(i).Obj().(*types.Func)))
+		}
+	}
+	have := make(map[string]bool)
+	for _, m := range methods {
+		have[m] = true
+	}
+
+	var matches []MethodSet
+	for _, set := range sets {
+		if set.Interface == isInterface {
+			continue
+		}
+		if isInterface && subset(methods, set.Methods) || !isInterface && len(set.Methods) > 0 && subsetOf(set.Methods, have) {
+			matches = append(matches, set)
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Type < matches[j].Type
+	})
+	h := &HoverImplements{Interface: isInterface, Count: len(matches)}
+	for i, set := range matches {
+		if i == maxHoverImplements {
+			break
+		}
+		h.Types = append(h.Types, HoverLink{
+			Name:   set.Type,
+			Target: fmt.Sprintf("%s#L%d", set.Location.URI, int(set.Location.Range.Start.Line)+1),
+		})
+	}
+	return h
+}
+
+// signatureFingerprint returns the fingerprint of the method fn.
+func signatureFingerprint(fn *types.Func) string {
+	sig := fn.Type().(*types.Signature)
+	return MethodFingerprint(fn.Name(), sig.Params().Len(), sig.Results().Len())
+}
+
+// subset reports whether the fingerprints a are all in b.
+func subset(a, b []string) bool {
+	in := make(map[string]bool, len(b))
+	for _, s := range b {
+		in[s] = true
+	}
+	return subsetOf(a, in)
+}
+
+func subsetOf(a []string, in map[string]bool) bool {
+	for _, s := range a {
+		if !in[s] {
+			return false
+		}
+	}
+	return true
+}
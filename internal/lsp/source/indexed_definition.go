@@ -0,0 +1,128 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// IndexedDefinition returns the location of the declaration of the
+// package-level object that the qualified identifier at pos in f refers to,
+// such as Println in fmt.Println, from the declarations of the imported
+// package, without type-checking either package. It returns nil if pos is not
+// in such an identifier, or if the declaration could not be found that way,
+// in which case the definition is that of Identifier.
+func IndexedDefinition(ctx context.Context, view View, f File, pos protocol.Position) ([]protocol.Location, error) {
+	ctx, done := trace.StartSpan(ctx, "source.IndexedDefinition")
+	defer done()
+
+	snapshot := view.Snapshot()
+	fh := snapshot.Handle(ctx, f)
+	file, m, _, err := view.Session().ParseGoHandle(fh, ParseFull).Parse(ctx)
+	if file == nil {
+		return nil, err
+	}
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return nil, err
+	}
+	rng, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, err
+	}
+	pkgName, name := qualifiedIdentAt(file, rng.Start)
+	if pkgName == "" {
+		return nil, nil
+	}
+	importPath := importPathOf(file, pkgName)
+	if importPath == "" {
+		return nil, nil
+	}
+	decls, err := snapshot.ImportedDeclarations(ctx, f.URI(), importPath)
+	if err != nil || decls == nil || decls.Name != pkgName && !importedAs(file, importPath, pkgName) {
+		return nil, err
+	}
+	location, ok := decls.Decls[name]
+	if !ok {
+		return nil, nil
+	}
+	return []protocol.Location{location}, nil
+}
+
+// qualifiedIdentAt returns the name of the package and of the selected object
+// of the qualified identifier whose selected name is at pos in file, or ""
+// if there is none. The package name must not be declared in the scope of
+// the identifier, which the parser resolves within file.
+func qualifiedIdentAt(file *ast.File, pos token.Pos) (pkgName, name string) {
+	path, _ := astutil.PathEnclosingInterval(file, pos, pos)
+	if len(path) < 2 {
+		return "", ""
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return "", ""
+	}
+	sel, ok := path[1].(*ast.SelectorExpr)
+	if !ok || sel.Sel != id {
+		return "", ""
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok || x.Obj != nil {
+		return "", ""
+	}
+	return x.Name, id.Name
+}
+
+// importPathOf returns the path of the import of file that is likely to
+// declare pkgName: the import named pkgName, or an unnamed import whose path
+// ends in it, possibly followed by a major version, or "" if there is none.
+func importPathOf(file *ast.File, pkgName string) string {
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if spec.Name != nil {
+			if spec.Name.Name == pkgName {
+				return importPath
+			}
+			continue
+		}
+		if assumedImportName(importPath) == pkgName {
+			return importPath
+		}
+	}
+	return ""
+}
+
+// assumedImportName returns the name of the package with the given import
+// path, as guessed from the path alone: its last element that does not look
+// like a major version, without a "go-" prefix.
+func assumedImportName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") && strings.Trim(base[1:], "0123456789") == "" && len(base) > 1 {
+		base = path.Base(path.Dir(importPath))
+	}
+	return strings.TrimPrefix(base, "go-")
+}
+
+// importedAs reports whether file imports importPath with the name pkgName.
+func importedAs(file *ast.File, importPath, pkgName string) bool {
+	for _, spec := range file.Imports {
+		if spec.Name != nil && spec.Name.Name == pkgName && spec.Path.Value == strconv.Quote(importPath) {
+			return true
+		}
+	}
+	return false
+}
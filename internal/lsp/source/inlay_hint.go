@@ -0,0 +1,453 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// The kinds of inlay hints, each enabled by the entry of the same name of
+// the hints setting.
+const (
+	// ImplicitConversionHints are the conversions of the arguments of a
+	// call to the interface types of the parameters.
+	ImplicitConversionHints = "implicitConversions"
+
+	// HeapAllocationHints are the values that are likely to be allocated
+	// on the heap, by the heuristics of the escape analysis of gc.
+	HeapAllocationHints = "heapAllocations"
+
+	// ClosureCaptureHints are the variables captured by function literals,
+	// and whether they are captured by reference.
+	ClosureCaptureHints = "closureCaptures"
+)
+
+// InlayHintKinds are the names of the kinds of inlay hints.
+var InlayHintKinds = []string{
+	ImplicitConversionHints,
+	HeapAllocationHints,
+	ClosureCaptureHints,
+}
+
+func isInlayHintKind(kind string) bool {
+	for _, k := range InlayHintKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// InlayHintInfo is an inlay hint shown at the start of its range.
+type InlayHintInfo struct {
+	mappedRange
+	Label        string
+	Tooltip      string
+	Kind         protocol.InlayHintKind
+	PaddingLeft  bool
+	PaddingRight bool
+}
+
+// inlayHint is an inlay hint at pos.
+type inlayHint struct {
+	pos                       token.Pos
+	label, tooltip            string
+	kind                      protocol.InlayHintKind
+	paddingLeft, paddingRight bool
+}
+
+// InlayHints returns the inlay hints of the kinds enabled in the options of
+// the view in the range rng of f.
+func InlayHints(ctx context.Context, view View, f File, rng protocol.Range) ([]*InlayHintInfo, error) {
+	ctx, done := trace.StartSpan(ctx, "source.InlayHints", telemetry.File.Of(f.URI()))
+	defer done()
+
+	enabled := view.Options().Hints
+	if len(enabled) == 0 {
+		return nil, nil
+	}
+	_, cphs, err := view.CheckPackageHandles(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	cph, err := NarrowestCheckPackageHandle(cphs)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := cph.Check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ph, err := pkg.File(f.URI())
+	if err != nil {
+		return nil, err
+	}
+	file, m, _, err := ph.Cached()
+	if err != nil {
+		return nil, err
+	}
+	spn, err := m.RangeSpan(rng)
+	if err != nil {
+		return nil, err
+	}
+	r, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, err
+	}
+	qf := qualifier(file, pkg.GetTypes(), pkg.GetTypesInfo())
+
+	fset := view.Session().Cache().FileSet()
+	var infos []*InlayHintInfo
+	for _, h := range inlayHints(file, pkg.GetTypesInfo(), qf, r.Start, r.End, enabled) {
+		infos = append(infos, &InlayHintInfo{
+			mappedRange: mappedRange{
+				m:         m,
+				spanRange: span.NewRange(fset, h.pos, h.pos),
+			},
+			Label:        h.label,
+			Tooltip:      h.tooltip,
+			Kind:         h.kind,
+			PaddingLeft:  h.paddingLeft,
+			PaddingRight: h.paddingRight,
+		})
+	}
+	return infos, nil
+}
+
+// inlayHints returns the inlay hints of the kinds enabled in the nodes of
+// file that overlap the range from start to end.
+func inlayHints(file *ast.File, info *types.Info, qf types.Qualifier, start, end token.Pos, enabled map[string]bool) []inlayHint {
+	var (
+		hints    []inlayHint
+		stack    []ast.Node
+		assigned map[*types.Var]bool
+	)
+	if enabled[ClosureCaptureHints] {
+		assigned = assignedVars(file, info)
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		if n.End() < start || end < n.Pos() {
+			return false
+		}
+		var parent ast.Node
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		stack = append(stack, n)
+
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if enabled[ImplicitConversionHints] || enabled[HeapAllocationHints] {
+				hints = append(hints, conversionHints(n, info, qf, enabled)...)
+			}
+			if enabled[HeapAllocationHints] {
+				if reason := allocationReason(n, parent, info); reason != "" {
+					hints = append(hints, heapHint(n.Pos(), reason))
+				}
+			}
+		case *ast.UnaryExpr:
+			if enabled[HeapAllocationHints] {
+				if reason := allocationReason(n, parent, info); reason != "" {
+					hints = append(hints, heapHint(n.Pos(), reason))
+				}
+			}
+		case *ast.FuncLit:
+			if enabled[ClosureCaptureHints] {
+				if h, ok := captureHint(n, info, assigned); ok {
+					hints = append(hints, h)
+				}
+			}
+		}
+		return true
+	})
+	return hints
+}
+
+// conversionHints returns the hints of the arguments of call that are
+// converted implicitly to the interface types of its parameters.
+func conversionHints(call *ast.CallExpr, info *types.Info, qf types.Qualifier, enabled map[string]bool) []inlayHint {
+	sig := callSignature(call, info)
+	if sig == nil {
+		return nil
+	}
+	var hints []inlayHint
+	for i, arg := range call.Args {
+		param := paramType(sig, i, call.Ellipsis.IsValid())
+		if param == nil || !types.IsInterface(param) {
+			continue
+		}
+		tv, ok := info.Types[arg]
+		if !ok || tv.Type == nil || types.IsInterface(tv.Type) || tv.IsNil() {
+			continue
+		}
+		iface := types.TypeString(param, qf)
+		if enabled[ImplicitConversionHints] {
+			hints = append(hints, inlayHint{
+				pos:         arg.End(),
+				label:       "as " + iface,
+				tooltip:     fmt.Sprintf("%s is converted to %s", types.TypeString(tv.Type, qf), iface),
+				kind:        protocol.TypeHint,
+				paddingLeft: true,
+			})
+		}
+		// Values that do not fit in the data word of an interface are
+		// copied to the heap, unless they are constants.
+		if enabled[HeapAllocationHints] && tv.Value == nil && !isPointerShaped(tv.Type) {
+			hints = append(hints, heapHint(arg.Pos(), "converted to "+iface))
+		}
+	}
+	return hints
+}
+
+// allocationReason returns why the value of the "&T{...}" composite literal,
+// "new(T)" call or "make([]T, n)" call n is likely to be allocated on the
+// heap, given the node it is a child of, or "" if it is not.
+func allocationReason(n, parent ast.Node, info *types.Info) string {
+	switch n := n.(type) {
+	case *ast.UnaryExpr:
+		if _, ok := n.X.(*ast.CompositeLit); !ok || n.Op != token.AND {
+			return ""
+		}
+		return escapeReason(n, parent, info)
+	case *ast.CallExpr:
+		switch builtinName(n, info) {
+		case "new":
+			return escapeReason(n, parent, info)
+		case "make":
+			if len(n.Args) < 2 {
+				return ""
+			}
+			if _, ok := info.TypeOf(n.Args[0]).Underlying().(*types.Slice); !ok {
+				return ""
+			}
+			for _, size := range n.Args[1:] {
+				if tv, ok := info.Types[size]; ok && tv.Value == nil {
+					return "slice of non-constant size"
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// escapeReason returns how the pointer ptr escapes the function it is
+// created in, given the node it is a child of, or "" if it does not
+// obviously escape.
+func escapeReason(ptr ast.Expr, parent ast.Node, info *types.Info) string {
+	switch parent := parent.(type) {
+	case *ast.ReturnStmt:
+		return "returned"
+	case *ast.SendStmt:
+		if parent.Value == ptr {
+			return "sent on a channel"
+		}
+	case *ast.AssignStmt:
+		for i, rhs := range parent.Rhs {
+			if rhs != ptr || i >= len(parent.Lhs) {
+				continue
+			}
+			switch lhs := parent.Lhs[i].(type) {
+			case *ast.SelectorExpr, *ast.IndexExpr, *ast.StarExpr:
+				return "stored through a pointer, field or element"
+			case *ast.Ident:
+				if v, ok := info.ObjectOf(lhs).(*types.Var); ok && v.Parent() == v.Pkg().Scope() {
+					return "stored in a package-level variable"
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// heapHint returns a hint at pos of a value likely allocated on the heap.
+func heapHint(pos token.Pos, reason string) inlayHint {
+	return inlayHint{
+		pos:          pos,
+		label:        "heap",
+		tooltip:      "likely allocated on the heap: " + reason,
+		paddingRight: true,
+	}
+}
+
+// captureHint returns the hint at the func keyword of lit that lists the
+// variables it captures, in the order of their first use. The variables
+// that are assigned after their declaration or whose address is taken are
+// captured by reference, and are prefixed with "&".
+func captureHint(lit *ast.FuncLit, info *types.Info, assigned map[*types.Var]bool) (inlayHint, bool) {
+	var (
+		seen                   = make(map[*types.Var]bool)
+		labels, byValue, byRef []string
+	)
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		v, ok := info.Uses[ident].(*types.Var)
+		if !ok || seen[v] || !isLocalVar(v) {
+			return true
+		}
+		// Variables declared in lit are not captured.
+		if lit.Pos() <= v.Pos() && v.Pos() < lit.End() {
+			return true
+		}
+		seen[v] = true
+		if assigned[v] {
+			labels = append(labels, "&"+v.Name())
+			byRef = append(byRef, v.Name())
+		} else {
+			labels = append(labels, v.Name())
+			byValue = append(byValue, v.Name())
+		}
+		return true
+	})
+	if len(labels) == 0 {
+		return inlayHint{}, false
+	}
+	var tooltip []string
+	if len(byValue) > 0 {
+		tooltip = append(tooltip, "captures "+strings.Join(byValue, ", ")+" by value")
+	}
+	if len(byRef) > 0 {
+		tooltip = append(tooltip, "captures "+strings.Join(byRef, ", ")+" by reference")
+	}
+	return inlayHint{
+		pos:          lit.Pos(),
+		label:        "[" + strings.Join(labels, ", ") + "]",
+		tooltip:      strings.Join(tooltip, "; "),
+		paddingRight: true,
+	}, true
+}
+
+// assignedVars returns the local variables of file that are assigned after
+// their declaration, or whose address is taken.
+func assignedVars(file *ast.File, info *types.Info) map[*types.Var]bool {
+	assigned := make(map[*types.Var]bool)
+	mark := func(e ast.Expr) {
+		for {
+			p, ok := e.(*ast.ParenExpr)
+			if !ok {
+				break
+			}
+			e = p.X
+		}
+		if ident, ok := e.(*ast.Ident); ok {
+			// The variables declared by ":=" are in Defs, not Uses.
+			if v, ok := info.Uses[ident].(*types.Var); ok && isLocalVar(v) {
+				assigned[v] = true
+			}
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				mark(lhs)
+			}
+		case *ast.IncDecStmt:
+			mark(n.X)
+		case *ast.RangeStmt:
+			if n.Tok == token.ASSIGN {
+				if n.Key != nil {
+					mark(n.Key)
+				}
+				if n.Value != nil {
+					mark(n.Value)
+				}
+			}
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				mark(n.X)
+			}
+		}
+		return true
+	})
+	return assigned
+}
+
+// isLocalVar reports whether v is a variable, or parameter, declared in a
+// function.
+func isLocalVar(v *types.Var) bool {
+	return !v.IsField() && v.Pkg() != nil && v.Parent() != nil && v.Parent() != v.Pkg().Scope()
+}
+
+// callSignature returns the signature of the function called by call, or
+// nil if call is a conversion or calls a builtin.
+func callSignature(call *ast.CallExpr, info *types.Info) *types.Signature {
+	tv, ok := info.Types[call.Fun]
+	if !ok || tv.IsType() || tv.IsBuiltin() || tv.Type == nil {
+		return nil
+	}
+	sig, _ := tv.Type.Underlying().(*types.Signature)
+	return sig
+}
+
+// builtinName returns the name of the builtin function called by call, or
+// "" if it does not call a builtin.
+func builtinName(call *ast.CallExpr, info *types.Info) string {
+	fun := call.Fun
+	for {
+		p, ok := fun.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		fun = p.X
+	}
+	ident, ok := fun.(*ast.Ident)
+	if !ok {
+		return ""
+	}
+	if b, ok := info.ObjectOf(ident).(*types.Builtin); ok {
+		return b.Name()
+	}
+	return ""
+}
+
+// paramType returns the type of the parameter of sig that the argument i of
+// a call is passed to, or nil if there is none. The arguments of a variadic
+// parameter are passed to its elements, unless they are spread with "...".
+func paramType(sig *types.Signature, i int, spread bool) types.Type {
+	n := sig.Params().Len()
+	if sig.Variadic() && i >= n-1 {
+		T := sig.Params().At(n - 1).Type()
+		if spread {
+			return T
+		}
+		if s, ok := T.Underlying().(*types.Slice); ok {
+			return s.Elem()
+		}
+		return nil
+	}
+	if i >= n {
+		return nil
+	}
+	return sig.Params().At(i).Type()
+}
+
+// isPointerShaped reports whether the values of T are stored directly in
+// the data word of an interface.
+func isPointerShaped(T types.Type) bool {
+	switch u := T.Underlying().(type) {
+	case *types.Pointer, *types.Map, *types.Chan, *types.Signature:
+		return true
+	case *types.Basic:
+		return u.Kind() == types.UnsafePointer
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+const inlayHintSrc = `package p
+
+type I interface{ M() }
+
+type T struct{ n int }
+
+func (*T) M() {}
+
+type V int
+
+func (V) M() {}
+
+func g(i I, is ...I) {}
+
+var global *T
+
+func f(n int, ch chan *T, ts []*T) *T {
+	g(V(n), &T{}, V(1))
+	var i I = nil
+	g(i, nil)
+	global = &T{}
+	ts[0] = new(T)
+	ch <- &T{}
+	local := &T{}
+	_ = make([]int, n)
+	_ = make([]int, 10)
+	_ = local
+	return &T{n: n}
+}
+
+func h(n int) func() int {
+	sum := 0
+	return func() int {
+		for i := 0; i < n; i++ {
+			sum += i
+		}
+		return sum + global.n
+	}
+}
+`
+
+func TestInlayHints(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", inlayHintSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	pkg, err := (&types.Config{}).Check("p", fset, []*ast.File{file}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qf := types.RelativeTo(pkg)
+
+	type hint struct {
+		Line          int
+		Label, Reason string
+	}
+	for _, tt := range []struct {
+		enabled map[string]bool
+		want    []hint
+	}{
+		{
+			enabled: map[string]bool{ImplicitConversionHints: true},
+			want: []hint{
+				{18, "as I", "V is converted to I"},
+				{18, "as I", "*T is converted to I"},
+				{18, "as I", "V is converted to I"},
+			},
+		},
+		{
+			enabled: map[string]bool{HeapAllocationHints: true},
+			want: []hint{
+				{18, "heap", "likely allocated on the heap: converted to I"},
+				{21, "heap", "likely allocated on the heap: stored in a package-level variable"},
+				{22, "heap", "likely allocated on the heap: stored through a pointer, field or element"},
+				{23, "heap", "likely allocated on the heap: sent on a channel"},
+				{25, "heap", "likely allocated on the heap: slice of non-constant size"},
+				{28, "heap", "likely allocated on the heap: returned"},
+			},
+		},
+		{
+			enabled: map[string]bool{ClosureCaptureHints: true},
+			want: []hint{
+				{33, "[n, &sum]", "captures n by value; captures sum by reference"},
+			},
+		},
+	} {
+		var got []hint
+		for _, h := range inlayHints(file, info, qf, file.Pos(), file.End(), tt.enabled) {
+			got = append(got, hint{fset.Position(h.pos).Line, h.label, h.tooltip})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("inlayHints(%v) = %v, want %v", tt.enabled, got, tt.want)
+		}
+	}
+}
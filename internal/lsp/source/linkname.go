@@ -0,0 +1,164 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// linknameDirective is the prefix of the comments that give a local name to
+// an object of another package.
+const linknameDirective = "//go:linkname"
+
+// LinknameTarget returns the object that the //go:linkname directive c refers
+// to, and the position of its name in the directive. It reports false if c is
+// not a directive of the form
+//
+//	//go:linkname localname importpath.name
+func LinknameTarget(c *ast.Comment) (target Xref, pos, end token.Pos, ok bool) {
+	if !strings.HasPrefix(c.Text, linknameDirective+" ") {
+		return Xref{}, token.NoPos, token.NoPos, false
+	}
+	fields := strings.Fields(c.Text[len(linknameDirective):])
+	if len(fields) != 2 {
+		return Xref{}, token.NoPos, token.NoPos, false
+	}
+	// The package path ends at the first dot after its last slash, as the
+	// name may be that of a method, such as pkg.(*T).m.
+	name := fields[1]
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return Xref{}, token.NoPos, token.NoPos, false
+	}
+	dot += slash + 1
+	target = Xref{PkgPath: name[:dot], Name: name[dot+1:]}
+	if target.Name == "" {
+		return Xref{}, token.NoPos, token.NoPos, false
+	}
+	offset := strings.LastIndex(c.Text, name)
+	pos = c.Slash + token.Pos(offset)
+	return target, pos, pos + token.Pos(len(name)), true
+}
+
+// LinknameDefinition returns the location of the object that the
+// //go:linkname directive at pos refers to. It returns no locations, and no
+// error, if pos is not in the target of a directive.
+func LinknameDefinition(ctx context.Context, view View, f File, pos protocol.Position) ([]protocol.Location, error) {
+	ctx, done := trace.StartSpan(ctx, "source.LinknameDefinition")
+	defer done()
+
+	fh := view.Snapshot().Handle(ctx, f)
+	file, m, _, err := view.Session().ParseGoHandle(fh, ParseFull).Parse(ctx)
+	if file == nil {
+		return nil, err
+	}
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return nil, err
+	}
+	rng, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, err
+	}
+	var target Xref
+	found := false
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			t, start, end, ok := LinknameTarget(c)
+			if ok && start <= rng.Start && rng.Start <= end {
+				target, found = t, true
+			}
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+
+	// The object must be in a dependency of the package of the file, as the
+	// declarations of other packages are not type-checked.
+	_, cphs, err := view.CheckPackageHandles(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	cph, err := WidestCheckPackageHandle(cphs)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := cph.Check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.GetTypes() == nil {
+		return nil, errors.Errorf("no types for %s", pkg.PkgPath())
+	}
+	dep := findDependency(pkg.GetTypes(), target.PkgPath)
+	if dep == nil {
+		return nil, errors.Errorf("%s is not a dependency of %s", target.PkgPath, pkg.PkgPath())
+	}
+	obj := dep.Scope().Lookup(target.Name)
+	if obj == nil {
+		return nil, errors.Errorf("no object %s in %s", target.Name, target.PkgPath)
+	}
+	declRange, err := objToMappedRange(ctx, pkg, obj)
+	if err != nil {
+		return nil, err
+	}
+	protocolRange, err := declRange.Range()
+	if err != nil {
+		return nil, err
+	}
+	return []protocol.Location{{
+		URI:   protocol.NewURI(declRange.URI()),
+		Range: protocolRange,
+	}}, nil
+}
+
+// findDependency returns the package with the given path among pkg and the
+// packages it imports, directly or indirectly.
+func findDependency(pkg *types.Package, path string) *types.Package {
+	seen := make(map[*types.Package]bool)
+	queue := []*types.Package{pkg}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if p.Path() == path {
+			return p
+		}
+		for _, imp := range p.Imports() {
+			if !seen[imp] {
+				seen[imp] = true
+				queue = append(queue, imp)
+			}
+		}
+	}
+	return nil
+}
+
+// Linknames returns the locations of the //go:linkname directives in the
+// workspace that refer to the object declared by the identifier, if it is a
+// package-level object.
+func (i *IdentifierInfo) Linknames(ctx context.Context) ([]protocol.Location, error) {
+	ctx, done := trace.StartSpan(ctx, "source.Linknames")
+	defer done()
+
+	obj := i.Declaration.obj
+	if obj == nil || obj.Pkg() == nil || obj.Pkg().Scope().Lookup(obj.Name()) != obj {
+		return nil, nil
+	}
+	linknames, err := i.Snapshot.WorkspaceLinknames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return linknames[Xref{PkgPath: obj.Pkg().Path(), Name: obj.Name()}], nil
+}
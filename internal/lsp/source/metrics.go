@@ -0,0 +1,251 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"golang.org/x/tools/internal/span"
+)
+
+// Metrics describes the packages of a workspace and the import graph among
+// them.
+type Metrics struct {
+	Packages []PackageMetrics `json:"packages"`
+
+	// Cycles are the import cycles among the packages of the workspace,
+	// each sorted by package path.
+	Cycles [][]string `json:"cycles"`
+
+	// Chains are the longest chains of imports among the packages of the
+	// workspace, longest first. Each chain starts with the importing
+	// package.
+	Chains [][]string `json:"chains"`
+}
+
+// PackageMetrics describes a package of a workspace.
+type PackageMetrics struct {
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	Lines int    `json:"lines"`
+
+	// Imports is the number of packages that the package imports directly,
+	// and Deps the number that it depends on, directly or not.
+	Imports int `json:"imports"`
+	Deps    int `json:"deps"`
+
+	// Importers is the number of packages of the workspace that import the
+	// package directly.
+	Importers int `json:"importers"`
+
+	// Depth is the number of packages in the longest chain of imports
+	// among the packages of the workspace that starts with the package.
+	Depth int `json:"depth"`
+}
+
+// metricsChains is the number of chains listed in the Chains field of a
+// Metrics.
+const metricsChains = 5
+
+// WorkspaceMetrics returns the metrics of the packages of graph whose IDs are
+// in workspace, reading their files from fs. The test variants of packages
+// are left out.
+func WorkspaceMetrics(ctx context.Context, fs FileSystem, graph []PackageMetadata, workspace []string) (*Metrics, error) {
+	lines := make(map[span.URI]int)
+	inWorkspace := make(map[string]bool)
+	for _, id := range workspace {
+		inWorkspace[id] = true
+	}
+	for _, m := range graph {
+		if !inWorkspace[m.ID] {
+			continue
+		}
+		for _, uri := range m.Files {
+			data, _, err := fs.GetFile(uri, Go).Read(ctx)
+			if err != nil {
+				return nil, err
+			}
+			lines[uri] = bytes.Count(data, []byte("\n"))
+		}
+	}
+	return graphMetrics(graph, inWorkspace, lines), nil
+}
+
+// graphMetrics returns the metrics of the packages of graph that are in
+// workspace, given the number of lines of their files.
+func graphMetrics(graph []PackageMetadata, workspace map[string]bool, lines map[span.URI]int) *Metrics {
+	byID := make(map[string]PackageMetadata)
+	for _, m := range graph {
+		byID[m.ID] = m
+	}
+	var ids []string
+	for _, m := range graph {
+		if workspace[m.ID] && m.ID == m.PkgPath {
+			ids = append(ids, m.ID)
+		}
+	}
+	sort.Strings(ids)
+	isWorkspace := func(id string) bool {
+		m, ok := byID[id]
+		return ok && workspace[id] && m.ID == m.PkgPath
+	}
+
+	// internal returns the imports of id that are in the workspace.
+	internal := func(id string) []string {
+		var deps []string
+		for _, dep := range byID[id].Deps {
+			if isWorkspace(dep) {
+				deps = append(deps, dep)
+			}
+		}
+		sort.Strings(deps)
+		return deps
+	}
+
+	importers := make(map[string]int)
+	for _, id := range ids {
+		for _, dep := range internal(id) {
+			importers[dep]++
+		}
+	}
+
+	// depths and next record the longest chain that starts with a package:
+	// its number of packages, and the package that follows. The imports
+	// that close a cycle are ignored.
+	depths := make(map[string]int)
+	next := make(map[string]string)
+	onStack := make(map[string]bool)
+	var depth func(id string) int
+	depth = func(id string) int {
+		if d, ok := depths[id]; ok {
+			return d
+		}
+		onStack[id] = true
+		d := 1
+		for _, dep := range internal(id) {
+			if onStack[dep] {
+				continue
+			}
+			if dd := depth(dep) + 1; dd > d {
+				d, next[id] = dd, dep
+			}
+		}
+		delete(onStack, id)
+		depths[id] = d
+		return d
+	}
+
+	metrics := &Metrics{
+		Packages: []PackageMetrics{},
+		Cycles:   importCycles(ids, internal),
+		Chains:   [][]string{},
+	}
+	for _, id := range ids {
+		m := byID[id]
+		pm := PackageMetrics{
+			Path:      m.PkgPath,
+			Files:     len(m.Files),
+			Imports:   len(m.Deps),
+			Deps:      len(transitiveDeps(byID, id)),
+			Importers: importers[id],
+			Depth:     depth(id),
+		}
+		for _, uri := range m.Files {
+			pm.Lines += lines[uri]
+		}
+		metrics.Packages = append(metrics.Packages, pm)
+	}
+
+	starts := append([]string{}, ids...)
+	sort.SliceStable(starts, func(i, j int) bool { return depths[starts[i]] > depths[starts[j]] })
+	for _, id := range starts {
+		if len(metrics.Chains) == metricsChains || depths[id] < 2 {
+			break
+		}
+		chain := []string{byID[id].PkgPath}
+		for dep, ok := next[id]; ok; dep, ok = next[dep] {
+			chain = append(chain, byID[dep].PkgPath)
+		}
+		metrics.Chains = append(metrics.Chains, chain)
+	}
+	return metrics
+}
+
+// transitiveDeps returns the set of the IDs of the packages that the package
+// with the given ID depends on, directly or not.
+func transitiveDeps(byID map[string]PackageMetadata, id string) map[string]bool {
+	seen := make(map[string]bool)
+	var visit func(id string)
+	visit = func(id string) {
+		for _, dep := range byID[id].Deps {
+			if !seen[dep] {
+				seen[dep] = true
+				visit(dep)
+			}
+		}
+	}
+	visit(id)
+	delete(seen, id)
+	return seen
+}
+
+// importCycles returns the strongly connected components of the graph of ids
+// and their edges that contain a cycle, found by Tarjan's algorithm.
+func importCycles(ids []string, edges func(string) []string) [][]string {
+	var (
+		index   = make(map[string]int)
+		lowlink = make(map[string]int)
+		onStack = make(map[string]bool)
+		stack   []string
+		cycles  = [][]string{}
+	)
+	var connect func(id string)
+	connect = func(id string) {
+		index[id] = len(index)
+		lowlink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		selfImport := false
+		for _, dep := range edges(id) {
+			if dep == id {
+				selfImport = true
+			}
+			if _, ok := index[dep]; !ok {
+				connect(dep)
+				if lowlink[dep] < lowlink[id] {
+					lowlink[id] = lowlink[dep]
+				}
+			} else if onStack[dep] && index[dep] < lowlink[id] {
+				lowlink[id] = index[dep]
+			}
+		}
+		if lowlink[id] != index[id] {
+			return
+		}
+		var scc []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			scc = append(scc, top)
+			if top == id {
+				break
+			}
+		}
+		if len(scc) > 1 || selfImport {
+			sort.Strings(scc)
+			cycles = append(cycles, scc)
+		}
+	}
+	for _, id := range ids {
+		if _, ok := index[id]; !ok {
+			connect(id)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/internal/span"
+)
+
+func TestGraphMetrics(t *testing.T) {
+	graph := []PackageMetadata{
+		{ID: "m/a", PkgPath: "m/a", Files: []span.URI{"file:///a/a.go", "file:///a/b.go"}, Deps: []string{"m/b", "fmt"}},
+		{ID: "m/a [m/a.test]", PkgPath: "m/a", Deps: []string{"m/b", "testing"}},
+		{ID: "m/b", PkgPath: "m/b", Files: []span.URI{"file:///b/b.go"}, Deps: []string{"m/c"}},
+		{ID: "m/c", PkgPath: "m/c", Files: []span.URI{"file:///c/c.go"}, Deps: []string{"m/d", "fmt"}},
+		{ID: "m/d", PkgPath: "m/d", Deps: []string{"m/c"}},
+		{ID: "fmt", PkgPath: "fmt", Deps: []string{"io"}},
+		{ID: "io", PkgPath: "io"},
+		{ID: "testing", PkgPath: "testing"},
+	}
+	workspace := map[string]bool{"m/a": true, "m/a [m/a.test]": true, "m/b": true, "m/c": true, "m/d": true}
+	lines := map[span.URI]int{"file:///a/a.go": 10, "file:///a/b.go": 5, "file:///b/b.go": 7, "file:///c/c.go": 1}
+
+	// The import of m/c by m/d closes a cycle, so it is not part of the
+	// chains.
+	got := graphMetrics(graph, workspace, lines)
+	wantPackages := []PackageMetrics{
+		{Path: "m/a", Files: 2, Lines: 15, Imports: 2, Deps: 5, Importers: 0, Depth: 4},
+		{Path: "m/b", Files: 1, Lines: 7, Imports: 1, Deps: 4, Importers: 1, Depth: 3},
+		{Path: "m/c", Files: 1, Lines: 1, Imports: 2, Deps: 3, Importers: 2, Depth: 2},
+		{Path: "m/d", Files: 0, Lines: 0, Imports: 1, Deps: 3, Importers: 1, Depth: 1},
+	}
+	if !reflect.DeepEqual(got.Packages, wantPackages) {
+		t.Errorf("got packages %+v, want %+v", got.Packages, wantPackages)
+	}
+	if want := [][]string{{"m/c", "m/d"}}; !reflect.DeepEqual(got.Cycles, want) {
+		t.Errorf("got cycles %q, want %q", got.Cycles, want)
+	}
+	wantChains := [][]string{
+		{"m/a", "m/b", "m/c", "m/d"},
+		{"m/b", "m/c", "m/d"},
+		{"m/c", "m/d"},
+	}
+	if !reflect.DeepEqual(got.Chains, wantChains) {
+		t.Errorf("got chains %q, want %q", got.Chains, wantChains)
+	}
+}
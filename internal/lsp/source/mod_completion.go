@@ -0,0 +1,204 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/token"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/semver"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/log"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// modCompletionSite is the field of a require or replace directive of a
+// go.mod file that the cursor is in.
+type modCompletionSite struct {
+	// path is the module path of the version at the cursor, or "" if the
+	// cursor is in a module path.
+	path string
+
+	// start is the offset of the field, and prefix its text before the
+	// cursor.
+	start  int
+	prefix string
+}
+
+// modSite returns the field of a require or replace directive of the go.mod
+// file with the given content that contains offset, or false if offset is
+// not in a module path or version of one.
+func modSite(content []byte, offset int) (modCompletionSite, bool) {
+	lineStart := strings.LastIndexByte(string(content[:offset]), '\n') + 1
+	line := string(content[lineStart:offset])
+	if strings.Contains(line, "//") {
+		return modCompletionSite{}, false
+	}
+	fields := strings.Fields(line)
+	if len(fields) > 0 && (fields[0] == "require" || fields[0] == "replace") {
+		// The cursor must be after the verb.
+		if len(fields) == 1 && !strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\t") {
+			return modCompletionSite{}, false
+		}
+		if len(fields) > 1 && fields[1] == "(" {
+			return modCompletionSite{}, false
+		}
+	} else {
+		verb := modBlockVerb(content, lineStart)
+		if verb != "require" && verb != "replace" {
+			return modCompletionSite{}, false
+		}
+		fields = append([]string{verb}, fields...)
+	}
+	verb, args := fields[0], fields[1:]
+	site := modCompletionSite{start: offset}
+	if strings.HasSuffix(line, " ") || strings.HasSuffix(line, "\t") || len(fields) == 1 {
+		// The cursor starts a new field.
+		args = append(args, "")
+	} else {
+		site.prefix = args[len(args)-1]
+		site.start = offset - len(site.prefix)
+	}
+	// The index of the field of the cursor in the module path and version
+	// pairs of the directive.
+	field := len(args) - 1
+	if verb == "replace" {
+		for i, arg := range args[:len(args)-1] {
+			if arg == "=>" {
+				field -= i + 1
+				args = args[i+1:]
+				break
+			}
+		}
+	}
+	switch field {
+	case 0:
+		return site, true
+	case 1:
+		if args[0] == "=>" || strings.HasPrefix(site.prefix, "=") {
+			return modCompletionSite{}, false
+		}
+		site.path = args[0]
+		return site, true
+	}
+	return modCompletionSite{}, false
+}
+
+// modBlockVerb returns the verb of the block of the go.mod file with the
+// given content that the line at offset lineStart is in, or "" if it is not
+// in a block.
+func modBlockVerb(content []byte, lineStart int) string {
+	for _, d := range parseModDirectives(content) {
+		if d.block && d.start < lineStart && (lineStart <= d.end || d.open) {
+			return d.verb
+		}
+	}
+	return ""
+}
+
+// ModCompletion returns the completions of the module path or version at
+// pos in a require or replace directive of the go.mod file f. The module
+// paths are those of the module cache, and the versions those of the module
+// cache and, if opts.ProxyVersions is set, of the module proxy.
+func ModCompletion(ctx context.Context, view View, f File, pos protocol.Position, opts CompletionOptions) ([]CompletionItem, *Selection, error) {
+	ctx, done := trace.StartSpan(ctx, "source.ModCompletion")
+	defer done()
+
+	m, err := fileMapper(ctx, view, f)
+	if err != nil {
+		return nil, nil, err
+	}
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return nil, nil, err
+	}
+	if spn, err = spn.WithOffset(m.Converter); err != nil {
+		return nil, nil, err
+	}
+	offset := spn.Start().Offset()
+	site, ok := modSite(m.Content, offset)
+	if !ok {
+		return nil, nil, nil
+	}
+	fset := token.NewFileSet()
+	tok := fset.AddFile(f.URI().Filename(), -1, len(m.Content))
+	tok.SetLinesForContent(m.Content)
+	surrounding := &Selection{
+		content: site.prefix,
+		cursor:  tok.Pos(offset),
+		mappedRange: mappedRange{
+			// Overwrite the prefix only.
+			spanRange: span.NewRange(fset, tok.Pos(site.start), tok.Pos(offset)),
+			m:         m,
+		},
+	}
+	env := view.Config(ctx).Env
+	var items []CompletionItem
+	matcher := newMatcher(site.prefix, opts)
+	if site.path == "" {
+		for _, path := range moduleIndex(env) {
+			if score := matcher.Score(path); score > 0 {
+				items = append(items, CompletionItem{
+					Label:      path,
+					InsertText: path,
+					Kind:       protocol.ModuleCompletion,
+					Score:      stdScore * float64(score),
+				})
+			}
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Label < items[j].Label
+		})
+		return items, surrounding, nil
+	}
+	versions := cachedVersions(env, site.path)
+	if opts.ProxyVersions {
+		info, err := moduleInfo(ctx, view, site.path)
+		if err != nil {
+			log.Error(ctx, "listing module versions", err, telemetry.File.Of(f.URI()))
+		} else {
+			versions = append(versions, info.Versions...)
+		}
+	}
+	versions = sortVersions(versions)
+	for i, v := range versions {
+		score := matcher.Score(v)
+		if score <= 0 {
+			continue
+		}
+		// The later the version, the more relevant it is.
+		items = append(items, CompletionItem{
+			Label:      v,
+			InsertText: v,
+			Kind:       protocol.ValueCompletion,
+			Score:      stdScore * float64(score) * float64(len(versions)-i) / float64(len(versions)),
+		})
+	}
+	return items, surrounding, nil
+}
+
+// sortVersions returns the valid versions, without duplicates, in decreasing
+// semantic version order.
+func sortVersions(versions []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, v := range versions {
+		if semver.IsValid(v) && !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if c := semver.Compare(result[i], result[j]); c != 0 {
+			return c > 0
+		}
+		return result[i] > result[j]
+	})
+	return result
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestModSite(t *testing.T) {
+	for _, test := range []struct {
+		src    string // the cursor is at the first |
+		ok     bool
+		path   string
+		prefix string
+	}{
+		{"module m\n\nrequire golang.org/x/te|", true, "", "golang.org/x/te"},
+		{"module m\n\nrequire |", true, "", ""},
+		{"module m\n\nrequire golang.org/x/text v0.3|", true, "golang.org/x/text", "v0.3"},
+		{"module m\n\nrequire golang.org/x/text |", true, "golang.org/x/text", ""},
+		{"module m\n\nrequire golang.org/x/text v0.3.2 |", false, "", ""},
+		{"module m\n\nrequire (\n\tgolang.org/x/te|\n)\n", true, "", "golang.org/x/te"},
+		{"module m\n\nrequire (\n\t|\n)\n", true, "", ""},
+		{"module m\n\nrequire (\n\tgolang.org/x/text v|\n", true, "golang.org/x/text", "v"},
+		{"module m\n\nrequire (\n\tgolang.org/x/text v0.3.2 // ind|\n)\n", false, "", ""},
+		{"module m\n\nreplace golang.org/x/text => example.com/te|", true, "", "example.com/te"},
+		{"module m\n\nreplace golang.org/x/text => example.com/text v1|", true, "example.com/text", "v1"},
+		{"module m\n\nreplace golang.org/x/text =|", false, "", ""},
+		{"module m\n\nrequire|", false, "", ""},
+		{"module m\n\nexclude golang.org/x/te|", false, "", ""},
+		{"module ex|", false, "", ""},
+		{"module m\n\nrequire (\n\tgolang.org/x/text v0.3.2\n)\n\n|", false, "", ""},
+	} {
+		offset := strings.Index(test.src, "|")
+		content := strings.Replace(test.src, "|", "", 1)
+		site, ok := modSite([]byte(content), offset)
+		if ok != test.ok || site.path != test.path || site.prefix != test.prefix {
+			t.Errorf("%q: got site %+v, %v, want path %q, prefix %q, %v", test.src, site, ok, test.path, test.prefix, test.ok)
+			continue
+		}
+		if ok && site.start+len(site.prefix) != offset {
+			t.Errorf("%q: got start %d for prefix %q, want %d", test.src, site.start, site.prefix, offset-len(site.prefix))
+		}
+	}
+}
+
+func TestModuleIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mod_completion_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"modcache/cache/download/github.com/!burnt!sushi/toml/@v/list":       "v0.3.1\n",
+		"modcache/cache/download/github.com/!burnt!sushi/toml/@v/v0.3.1.mod": "",
+		"modcache/cache/download/golang.org/x/text/@v/list":                  "v0.3.0\nv0.3.2\n",
+		"modcache/cache/download/golang.org/x/text/@v/v0.3.1.info":           "{}",
+		"modcache/cache/download/golang.org/x/text/@v/v0.3.2.info":           "{}",
+		"modcache/cache/download/sumdb/sum.golang.org/lookup/a/@v/list":      "",
+		"proxy/golang.org/x/text/@v/list":                                    "v0.3.3\nv0.2.0\n",
+	} {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := []string{
+		"GOMODCACHE=" + filepath.Join(dir, "modcache"),
+		"GOPROXY=file://" + filepath.ToSlash(filepath.Join(dir, "proxy")) + ",direct",
+	}
+	paths := moduleIndex(env)
+	sort.Strings(paths)
+	if want := []string{"github.com/BurntSushi/toml", "golang.org/x/text"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got modules %q, want %q", paths, want)
+	}
+	versions := sortVersions(cachedVersions(env, "golang.org/x/text"))
+	if want := []string{"v0.3.2", "v0.3.1", "v0.3.0"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("got cached versions %q, want %q", versions, want)
+	}
+	listed, err := proxyVersions(context.Background(), env, "golang.org/x/text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions = sortVersions(append(versions, listed...))
+	if want := []string{"v0.3.3", "v0.3.2", "v0.3.1", "v0.3.0", "v0.2.0"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("got versions %q, want %q", versions, want)
+	}
+	if _, err := proxyVersions(context.Background(), []string{"GOPROXY=direct"}, "golang.org/x/text"); err == nil {
+		t.Error("got no error listing versions without a proxy")
+	}
+}
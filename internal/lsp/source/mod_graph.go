@@ -0,0 +1,141 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// ModGraphParams are the parameters of the gopls/modGraph request.
+type ModGraphParams struct {
+	// URI is the URI of the go.mod file of the main module.
+	URI protocol.DocumentURI `json:"uri"`
+
+	// Module is the path, or path@version, of a module whose requirement
+	// paths from the main module are wanted, if any.
+	Module string `json:"module,omitempty"`
+}
+
+// ModGraph is the module requirement graph of a main module, as printed by
+// "go mod graph". The main module is named by its path, and the other modules
+// by their path@version.
+type ModGraph struct {
+	Main string `json:"main"`
+
+	// Requires maps each module to the modules that it requires, sorted.
+	Requires map[string][]string `json:"requires"`
+
+	// Why are the shortest requirement paths from the main module to the
+	// versions of the module of the request, if any. Each path starts with
+	// the main module.
+	Why [][]string `json:"why,omitempty"`
+}
+
+// ModuleGraph returns the requirement graph of the main module of the go.mod
+// file with the given URI, with the requirement paths to module if it is not
+// empty. The graph is cached in the snapshot of view until the go.mod file
+// changes.
+func ModuleGraph(ctx context.Context, view View, gomod span.URI, module string) (*ModGraph, error) {
+	ctx, done := trace.StartSpan(ctx, "source.ModuleGraph")
+	defer done()
+
+	if filepath.Base(gomod.Filename()) != "go.mod" {
+		return nil, errors.Errorf("%s is not a go.mod file", gomod.Filename())
+	}
+	graph, err := view.Snapshot().ModGraph(ctx, gomod, func(ctx context.Context) (*ModGraph, error) {
+		stdout, err := invokeGo(ctx, filepath.Dir(gomod.Filename()), view.Config(ctx).Env, "mod", "graph")
+		if err != nil {
+			return nil, err
+		}
+		return parseModGraph(stdout.Bytes())
+	})
+	if err != nil {
+		return nil, err
+	}
+	if module == "" {
+		return graph, nil
+	}
+	// The cached graph is shared, so the paths are added to a copy.
+	result := *graph
+	result.Why = graph.why(module)
+	if len(result.Why) == 0 {
+		return nil, errors.Errorf("%s does not require %s", graph.Main, module)
+	}
+	return &result, nil
+}
+
+// parseModGraph parses the output of "go mod graph", in which each line is a
+// module and one of its requirements.
+func parseModGraph(data []byte) (*ModGraph, error) {
+	graph := &ModGraph{Requires: make(map[string][]string)}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		from, to := fields[0], fields[1]
+		if !strings.Contains(from, "@") {
+			graph.Main = from
+		}
+		graph.Requires[from] = append(graph.Requires[from], to)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if graph.Main == "" {
+		return nil, errors.Errorf("go mod graph printed no requirements of the main module")
+	}
+	for _, reqs := range graph.Requires {
+		sort.Strings(reqs)
+	}
+	return graph, nil
+}
+
+// why returns the shortest requirement paths from the main module to the
+// versions of module, which is a module path or a path@version, found by a
+// breadth-first search.
+func (g *ModGraph) why(module string) [][]string {
+	matches := func(m string) bool {
+		return m == module || strings.HasPrefix(m, module+"@")
+	}
+	parent := map[string]string{g.Main: ""}
+	queue := []string{g.Main}
+	var found []string
+	for len(queue) > 0 {
+		m := queue[0]
+		queue = queue[1:]
+		if m != g.Main && matches(m) {
+			found = append(found, m)
+			continue
+		}
+		for _, req := range g.Requires[m] {
+			if _, ok := parent[req]; !ok {
+				parent[req] = m
+				queue = append(queue, req)
+			}
+		}
+	}
+	sort.Strings(found)
+	var paths [][]string
+	for _, m := range found {
+		var path []string
+		for ; m != ""; m = parent[m] {
+			path = append([]string{m}, path...)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModGraphWhy(t *testing.T) {
+	const out = `example.com/m golang.org/x/tools@v0.1.0
+example.com/m golang.org/x/sync@v0.2.0
+golang.org/x/tools@v0.1.0 golang.org/x/sync@v0.1.0
+golang.org/x/tools@v0.1.0 golang.org/x/mod@v0.3.0
+golang.org/x/mod@v0.3.0 golang.org/x/xerrors@v0.0.1
+`
+	graph, err := parseModGraph([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graph.Main != "example.com/m" {
+		t.Errorf("got main module %q, want example.com/m", graph.Main)
+	}
+	if got, want := graph.Requires["golang.org/x/tools@v0.1.0"], []string{"golang.org/x/mod@v0.3.0", "golang.org/x/sync@v0.1.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got requirements %q, want %q", got, want)
+	}
+	for _, test := range []struct {
+		module string
+		want   [][]string
+	}{
+		{"golang.org/x/xerrors", [][]string{
+			{"example.com/m", "golang.org/x/tools@v0.1.0", "golang.org/x/mod@v0.3.0", "golang.org/x/xerrors@v0.0.1"},
+		}},
+		{"golang.org/x/sync", [][]string{
+			{"example.com/m", "golang.org/x/tools@v0.1.0", "golang.org/x/sync@v0.1.0"},
+			{"example.com/m", "golang.org/x/sync@v0.2.0"},
+		}},
+		{"golang.org/x/sync@v0.2.0", [][]string{
+			{"example.com/m", "golang.org/x/sync@v0.2.0"},
+		}},
+		{"golang.org/x/sys", nil},
+	} {
+		if got := graph.why(test.module); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("why %s: got %q, want %q", test.module, got, test.want)
+		}
+	}
+
+	if _, err := parseModGraph(nil); err == nil {
+		t.Errorf("got a graph without a main module")
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/telemetry"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/log"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// ModHover returns the hover of the requirement at pos in the go.mod file f:
+// the latest version of the module, and whether it is deprecated or its
+// required version retracted, as reported by the module proxy. It returns
+// nil if pos is not in a requirement.
+func ModHover(ctx context.Context, view View, f File, pos protocol.Position, kind protocol.MarkupKind) (*protocol.Hover, error) {
+	ctx, done := trace.StartSpan(ctx, "source.ModHover")
+	defer done()
+
+	m, err := fileMapper(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return nil, err
+	}
+	if spn, err = spn.WithOffset(m.Converter); err != nil {
+		return nil, err
+	}
+	offset := spn.Start().Offset()
+	var req *modEntry
+	for _, d := range parseModDirectives(m.Content) {
+		if d.verb != "require" {
+			continue
+		}
+		for i, e := range d.entries {
+			if e.start <= offset && offset <= e.end {
+				req = &d.entries[i]
+			}
+		}
+	}
+	if req == nil {
+		return nil, nil
+	}
+	version := strings.Fields(req.detail + " ")[0]
+	info, err := moduleInfo(ctx, view, req.name)
+	if err != nil {
+		// The proxy may be off or unreachable, which is not an error of
+		// the request.
+		log.Error(ctx, "fetching module information", err, telemetry.File.Of(f.URI()))
+		return nil, nil
+	}
+	rng, err := m.Range(span.New(m.URI, span.NewPoint(0, 0, req.start), span.NewPoint(0, 0, req.end)))
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.Hover{
+		Contents: protocol.MarkupContent{
+			Kind:  kind,
+			Value: modHoverText(info, version, kind == protocol.Markdown),
+		},
+		Range: &rng,
+	}, nil
+}
+
+// modHoverText returns the hover text of the requirement of version of the
+// module of info.
+func modHoverText(info *ModuleInfo, version string, markdown bool) string {
+	code := func(s string) string {
+		if markdown {
+			return "`" + s + "`"
+		}
+		return s
+	}
+	var lines []string
+	lines = append(lines, fmt.Sprintf("%s %s", code(info.Path), code(version)))
+	switch {
+	case info.Latest == "":
+	case info.Latest == version:
+		lines = append(lines, "This is the latest version.")
+	default:
+		lines = append(lines, fmt.Sprintf("Latest version: %s", code(info.Latest)))
+	}
+	if info.Deprecated != "" {
+		lines = append(lines, fmt.Sprintf("Deprecated: %s", info.Deprecated))
+	}
+	if r := info.Retracted(version); r != nil {
+		retracted := fmt.Sprintf("Retracted: %s", code(version))
+		if r.Low != r.High {
+			retracted += fmt.Sprintf(" is in the retracted versions [%s, %s]", code(r.Low), code(r.High))
+		} else {
+			retracted += " is retracted"
+		}
+		if r.Rationale != "" {
+			retracted += ": " + r.Rationale
+		}
+		lines = append(lines, retracted)
+	}
+	sep := "\n"
+	if markdown {
+		// Markdown joins lines that are not separated by a blank line.
+		sep = "\n\n"
+	}
+	return strings.Join(lines, sep)
+}
@@ -0,0 +1,125 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/internal/module"
+)
+
+// ModuleMetadata is what the module cache tells of a module version.
+type ModuleMetadata struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+
+	// License is the name of the license of the module, such as
+	// "BSD-3-Clause", or "" if it has no license file or its license is
+	// not recognized.
+	License string `json:"license,omitempty"`
+}
+
+// licenseFiles are the names of the files at the root of a module that may
+// hold its license, in the order in which they are tried.
+var licenseFiles = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "LICENCE", "COPYING"}
+
+// moduleMetadata returns the metadata of the module version in the module
+// cache of env that holds filename, or nil if filename is not in the module
+// cache. Since the module cache is read-only, the metadata of each module
+// version is read once per cache.
+func moduleMetadata(cache Cache, env []string, filename string) *ModuleMetadata {
+	path, version, dir, ok := moduleCacheModule(moduleCacheDir(env), filename)
+	if !ok {
+		return nil
+	}
+	return cache.ModuleMetadata(dir, func() *ModuleMetadata {
+		return readModuleMetadata(path, version, dir)
+	})
+}
+
+// moduleCacheModule returns the path, version and directory of the module
+// version in the module cache at root that holds filename, if any.
+func moduleCacheModule(root, filename string) (path, version, dir string, ok bool) {
+	rel, err := filepath.Rel(root, filename)
+	if err != nil {
+		return "", "", "", false
+	}
+	elems := strings.Split(filepath.ToSlash(rel), "/")
+	if elems[0] == ".." || elems[0] == "cache" {
+		return "", "", "", false
+	}
+	for i, elem := range elems[:len(elems)-1] {
+		at := strings.Index(elem, "@")
+		if at < 0 {
+			continue
+		}
+		encodedPath := strings.Join(append(elems[:i:i], elem[:at]), "/")
+		if path, err = module.DecodePath(encodedPath); err != nil {
+			return "", "", "", false
+		}
+		if version, err = module.DecodeVersion(elem[at+1:]); err != nil {
+			return "", "", "", false
+		}
+		dir = filepath.Join(root, filepath.FromSlash(strings.Join(elems[:i+1], "/")))
+		return path, version, dir, true
+	}
+	return "", "", "", false
+}
+
+// readModuleMetadata reads the metadata of the module version extracted in
+// dir.
+func readModuleMetadata(path, version, dir string) *ModuleMetadata {
+	m := &ModuleMetadata{Path: path, Version: version}
+	for _, name := range licenseFiles {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		m.License = licenseName(content)
+		break
+	}
+	return m
+}
+
+// licenseName returns the name of the license of the given text, by the
+// phrases that distinguish the most common licenses, or "" if none of them
+// matches.
+func licenseName(text []byte) string {
+	text = bytes.Join(bytes.Fields(text), []byte(" "))
+	has := func(phrase string) bool {
+		return bytes.Contains(text, []byte(phrase))
+	}
+	switch {
+	case has("Apache License") && has("Version 2.0"):
+		return "Apache-2.0"
+	case has("Mozilla Public License Version 2.0") || has("Mozilla Public License, version 2.0"):
+		return "MPL-2.0"
+	case has("GNU LESSER GENERAL PUBLIC LICENSE") && has("Version 3"):
+		return "LGPL-3.0"
+	case has("GNU LESSER GENERAL PUBLIC LICENSE") || has("GNU Lesser General Public License"):
+		return "LGPL-2.1"
+	case has("GNU AFFERO GENERAL PUBLIC LICENSE"):
+		return "AGPL-3.0"
+	case has("GNU GENERAL PUBLIC LICENSE") && has("Version 3"):
+		return "GPL-3.0"
+	case has("GNU GENERAL PUBLIC LICENSE"):
+		return "GPL-2.0"
+	case has("Permission is hereby granted, free of charge"):
+		return "MIT"
+	case has("Permission to use, copy, modify, and/or distribute this software for any purpose"):
+		return "ISC"
+	case has("Redistribution and use in source and binary forms"):
+		if has("Neither the name") || has("names of its contributors") {
+			return "BSD-3-Clause"
+		}
+		return "BSD-2-Clause"
+	case has("This is free and unencumbered software released into the public domain"):
+		return "Unlicense"
+	}
+	return ""
+}
@@ -0,0 +1,75 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestModuleCacheModule(t *testing.T) {
+	root := filepath.FromSlash("/gopath/pkg/mod")
+	for _, test := range []struct {
+		filename      string
+		path, version string
+		dir           string
+	}{
+		{"github.com/!burnt!sushi/toml@v0.3.1/decode.go", "github.com/BurntSushi/toml", "v0.3.1", "github.com/!burnt!sushi/toml@v0.3.1"},
+		{"golang.org/x/tools@v0.0.0-20191108193012-7d206e10da11/go/packages/packages.go", "golang.org/x/tools", "v0.0.0-20191108193012-7d206e10da11", "golang.org/x/tools@v0.0.0-20191108193012-7d206e10da11"},
+		{"cache/download/golang.org/x/tools/@v/v0.1.0.zip", "", "", ""},
+		{"../../src/fmt/print.go", "", "", ""},
+		{"example.com/nover/a.go", "", "", ""},
+	} {
+		path, version, dir, ok := moduleCacheModule(root, filepath.Join(root, filepath.FromSlash(test.filename)))
+		if ok != (test.path != "") {
+			t.Errorf("%s: got ok=%v", test.filename, ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if wantDir := filepath.Join(root, filepath.FromSlash(test.dir)); path != test.path || version != test.version || dir != wantDir {
+			t.Errorf("%s: got %s %s in %s, want %s %s in %s", test.filename, path, version, dir, test.path, test.version, wantDir)
+		}
+	}
+}
+
+func TestReadModuleMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mod_metadata_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	license := `Copyright (c) 2009 The Go Authors. All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are
+met:
+...
+   * Neither the name of Google Inc. nor the names of its
+contributors may be used to endorse or promote products derived from
+this software without specific prior written permission.
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "LICENSE"), []byte(license), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := readModuleMetadata("golang.org/x/tools", "v0.1.0", dir)
+	if m.Path != "golang.org/x/tools" || m.Version != "v0.1.0" || m.License != "BSD-3-Clause" {
+		t.Errorf("got %+v, want golang.org/x/tools v0.1.0 under BSD-3-Clause", m)
+	}
+
+	for text, want := range map[string]string{
+		"Apache License\n  Version 2.0, January 2004":                          "Apache-2.0",
+		"MIT License\n\nPermission is hereby granted, free of\ncharge, to any": "MIT",
+		"GNU GENERAL PUBLIC LICENSE\n Version 3, 29 June 2007":                 "GPL-3.0",
+		"All rights reserved.": "",
+	} {
+		if got := licenseName([]byte(text)); got != want {
+			t.Errorf("licenseName(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/internal/module"
+	"golang.org/x/tools/internal/semver"
+	errors "golang.org/x/xerrors"
+)
+
+// defaultProxy is the module proxy used when GOPROXY is not set.
+const defaultProxy = "https://proxy.golang.org"
+
+// moduleProxy returns the URL of the first module proxy of the GOPROXY of
+// env, or "" if it only lists direct or off.
+func moduleProxy(env []string) string {
+	proxies := getenv(env, "GOPROXY")
+	if proxies == "" {
+		return defaultProxy
+	}
+	for _, proxy := range strings.FieldsFunc(proxies, func(r rune) bool { return r == ',' || r == '|' }) {
+		if proxy = strings.TrimSpace(proxy); proxy != "direct" && proxy != "off" && proxy != "" {
+			return strings.TrimSuffix(proxy, "/")
+		}
+	}
+	return ""
+}
+
+// proxyGet returns the content of the file of the module path at the module
+// proxy of env, such as "@v/list" for the list of its versions. Proxies with
+// file:// URLs are read from disk.
+func proxyGet(ctx context.Context, env []string, path, file string) ([]byte, error) {
+	proxy := moduleProxy(env)
+	if proxy == "" {
+		return nil, errors.Errorf("no module proxy for %s", path)
+	}
+	encoded, err := module.EncodePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(proxy, "file://") {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadFile(filepath.Join(filepath.FromSlash(u.Path), filepath.FromSlash(encoded), filepath.FromSlash(file)))
+	}
+	u := proxy + "/" + encoded + "/" + file
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("reading %s: %s", u, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// proxyVersions returns the versions of the module path that the module
+// proxy of env lists.
+func proxyVersions(ctx context.Context, env []string, path string) ([]string, error) {
+	data, err := proxyGet(ctx, env, path, "@v/list")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// moduleCacheDir returns the root of the module cache of env, where the go
+// command extracts the modules it downloads.
+func moduleCacheDir(env []string) string {
+	if dir := getenv(env, "GOMODCACHE"); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.SplitList(gopath(env))[0], "pkg", "mod")
+}
+
+// moduleCacheDownloadDir returns the directory of the module cache of env
+// where the go command stores the modules it downloads.
+func moduleCacheDownloadDir(env []string) string {
+	return filepath.Join(moduleCacheDir(env), "cache", "download")
+}
+
+// moduleIndex returns the paths of the modules in the module cache of env,
+// which are those that the go command has downloaded.
+func moduleIndex(env []string) []string {
+	root := moduleCacheDownloadDir(env)
+	var paths []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if path == filepath.Join(root, "sumdb") {
+			return filepath.SkipDir
+		}
+		if info.Name() != "@v" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return filepath.SkipDir
+		}
+		if modPath, err := module.DecodePath(filepath.ToSlash(rel)); err == nil {
+			paths = append(paths, modPath)
+		}
+		return filepath.SkipDir
+	})
+	return paths
+}
+
+// cachedVersions returns the versions of the module path in the module
+// cache of env: those of its list file, and those it has information on.
+func cachedVersions(env []string, path string) []string {
+	encoded, err := module.EncodePath(path)
+	if err != nil {
+		return nil
+	}
+	dir := filepath.Join(moduleCacheDownloadDir(env), filepath.FromSlash(encoded), "@v")
+	seen := make(map[string]bool)
+	var versions []string
+	add := func(v string) {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			versions = append(versions, v)
+		}
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "list")); err == nil {
+		s := bufio.NewScanner(bytes.NewReader(data))
+		for s.Scan() {
+			add(strings.TrimSpace(s.Text()))
+		}
+	}
+	infos, _ := ioutil.ReadDir(dir)
+	for _, info := range infos {
+		if name := info.Name(); strings.HasSuffix(name, ".info") {
+			if v, err := module.DecodeVersion(strings.TrimSuffix(name, ".info")); err == nil {
+				add(v)
+			}
+		}
+	}
+	return versions
+}
+
+// ModuleInfo is the information of the module proxy on a module.
+type ModuleInfo struct {
+	Path string
+
+	// Versions are the versions of the module, in decreasing order.
+	Versions []string
+
+	// Latest is the latest version of the module that is not retracted,
+	// preferring releases to pre-releases.
+	Latest string
+
+	// Deprecated is the deprecation message of the module, from the go.mod
+	// file of its latest version, or "" if it is not deprecated.
+	Deprecated string
+
+	// Retractions are the versions retracted by the go.mod file of its
+	// latest version.
+	Retractions []Retraction
+}
+
+// Retraction is a version interval retracted by a retract directive.
+type Retraction struct {
+	Low, High string
+	Rationale string
+}
+
+// Retracted returns the retraction of version, or nil if it is not
+// retracted.
+func (info *ModuleInfo) Retracted(version string) *Retraction {
+	for i, r := range info.Retractions {
+		if semver.Compare(r.Low, version) <= 0 && semver.Compare(version, r.High) <= 0 {
+			return &info.Retractions[i]
+		}
+	}
+	return nil
+}
+
+// proxyTimeout bounds the requests to the module proxy, whose results are
+// shared by the requests of a snapshot.
+const proxyTimeout = 30 * time.Second
+
+// moduleInfo returns the information of the module proxy of the view on the
+// module path. It is fetched at most once per snapshot.
+func moduleInfo(ctx context.Context, view View, path string) (*ModuleInfo, error) {
+	env := view.Config(ctx).Env
+	return view.Snapshot().ModuleInfo(ctx, path, func(ctx context.Context) (*ModuleInfo, error) {
+		ctx, cancel := context.WithTimeout(ctx, proxyTimeout)
+		defer cancel()
+		return fetchModuleInfo(ctx, env, path)
+	})
+}
+
+// fetchModuleInfo fetches the information of the module proxy of env on the
+// module path. The deprecation and retractions are those of the go.mod file
+// of its highest version, as for the go command.
+func fetchModuleInfo(ctx context.Context, env []string, path string) (*ModuleInfo, error) {
+	listed, err := proxyVersions(ctx, env, path)
+	if err != nil {
+		return nil, err
+	}
+	info := &ModuleInfo{Path: path, Versions: sortVersions(listed)}
+	if len(info.Versions) == 0 {
+		// The module has no tagged versions, only pseudo-versions.
+		data, err := proxyGet(ctx, env, path, "@latest")
+		if err != nil {
+			return nil, err
+		}
+		var latest struct{ Version string }
+		if err := json.Unmarshal(data, &latest); err != nil {
+			return nil, errors.Errorf("reading the latest version of %s: %v", path, err)
+		}
+		info.Latest = latest.Version
+		return info, nil
+	}
+	highest := latestVersion(info.Versions, nil)
+	encoded, err := module.EncodeVersion(highest)
+	if err != nil {
+		return nil, err
+	}
+	gomod, err := proxyGet(ctx, env, path, "@v/"+encoded+".mod")
+	if err != nil {
+		return nil, err
+	}
+	info.Deprecated = moduleDeprecation(gomod)
+	info.Retractions = modRetractions(gomod)
+	info.Latest = latestVersion(info.Versions, info)
+	return info, nil
+}
+
+// latestVersion returns the highest of versions, which are in decreasing
+// order, that is not retracted by info, if any, preferring releases.
+func latestVersion(versions []string, info *ModuleInfo) string {
+	latest := ""
+	for _, v := range versions {
+		if info != nil && info.Retracted(v) != nil {
+			continue
+		}
+		if semver.Prerelease(v) == "" {
+			return v
+		}
+		if latest == "" {
+			latest = v
+		}
+	}
+	return latest
+}
+
+// moduleDeprecation returns the deprecation message of the go.mod file with
+// the given content: the paragraph starting with "Deprecated:" of the
+// comment before its module directive, or of the comment on its line.
+func moduleDeprecation(content []byte) string {
+	var comment []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "//") {
+			comment = append(comment, strings.TrimSpace(strings.TrimPrefix(line, "//")))
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "module" {
+			comment = nil
+			continue
+		}
+		if i := strings.Index(line, "//"); i >= 0 {
+			comment = []string{strings.TrimSpace(line[i+len("//"):])}
+		}
+		break
+	}
+	for i, line := range comment {
+		if !strings.HasPrefix(line, "Deprecated:") {
+			continue
+		}
+		paragraph := []string{strings.TrimSpace(strings.TrimPrefix(line, "Deprecated:"))}
+		for _, line := range comment[i+1:] {
+			if line == "" {
+				break
+			}
+			paragraph = append(paragraph, line)
+		}
+		return strings.Join(paragraph, " ")
+	}
+	return ""
+}
+
+// modRetractions returns the versions retracted by the retract directives
+// of the go.mod file with the given content, such as "retract v1.0.0" or
+// "retract [v1.0.0, v1.0.5]", with the comments on their lines as rationale.
+func modRetractions(content []byte) []Retraction {
+	var retractions []Retraction
+	for _, d := range parseModDirectives(content) {
+		if d.verb != "retract" {
+			continue
+		}
+		for _, e := range d.entries {
+			spec := strings.TrimSpace(e.name + " " + strings.TrimSuffix(e.detail, "// indirect"))
+			r := Retraction{Low: spec, High: spec, Rationale: e.comment}
+			if strings.HasPrefix(spec, "[") && strings.HasSuffix(spec, "]") {
+				bounds := strings.Split(spec[1:len(spec)-1], ",")
+				if len(bounds) != 2 {
+					continue
+				}
+				r.Low, r.High = strings.TrimSpace(bounds[0]), strings.TrimSpace(bounds[1])
+			}
+			if !semver.IsValid(r.Low) || !semver.IsValid(r.High) {
+				continue
+			}
+			retractions = append(retractions, r)
+		}
+	}
+	return retractions
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFetchModuleInfo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mod_proxy_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"example.com/a/@v/list": "v1.0.0\nv1.1.0\nv1.2.0\nv1.3.0-rc.1\n",
+		"example.com/a/@v/v1.2.0.mod": `// Package a does things.
+//
+// Deprecated: use example.com/b
+// instead.
+module example.com/a
+
+retract v1.2.0 // published by mistake
+
+retract (
+	[v1.0.0, v1.0.5] // broken
+)
+`,
+		"example.com/b/@v/list":       "",
+		"example.com/b/@latest":       `{"Version":"v0.0.0-20191108193012-7d206e10da11"}`,
+		"example.com/c/@v/list":       "v0.1.0\n",
+		"example.com/c/@v/v0.1.0.mod": "module example.com/c // Deprecated: gone\n",
+	} {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := []string{"GOPROXY=file://" + filepath.ToSlash(dir)}
+	for _, want := range []*ModuleInfo{
+		{
+			Path:       "example.com/a",
+			Versions:   []string{"v1.3.0-rc.1", "v1.2.0", "v1.1.0", "v1.0.0"},
+			Latest:     "v1.1.0",
+			Deprecated: "use example.com/b instead.",
+			Retractions: []Retraction{
+				{Low: "v1.2.0", High: "v1.2.0", Rationale: "published by mistake"},
+				{Low: "v1.0.0", High: "v1.0.5", Rationale: "broken"},
+			},
+		},
+		{
+			Path:   "example.com/b",
+			Latest: "v0.0.0-20191108193012-7d206e10da11",
+		},
+		{
+			Path:       "example.com/c",
+			Versions:   []string{"v0.1.0"},
+			Latest:     "v0.1.0",
+			Deprecated: "gone",
+		},
+	} {
+		got, err := fetchModuleInfo(context.Background(), env, want.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got info\n%+v\nwant\n%+v", got, want)
+		}
+	}
+	if _, err := fetchModuleInfo(context.Background(), env, "example.com/missing"); err == nil {
+		t.Error("got no error for a missing module")
+	}
+}
+
+func TestModHoverText(t *testing.T) {
+	info := &ModuleInfo{
+		Path:        "example.com/a",
+		Latest:      "v1.1.0",
+		Deprecated:  "use example.com/b instead.",
+		Retractions: []Retraction{{Low: "v1.0.0", High: "v1.0.5", Rationale: "broken"}},
+	}
+	for _, test := range []struct {
+		version  string
+		markdown bool
+		want     string
+	}{
+		{"v1.1.0", false, "example.com/a v1.1.0\nThis is the latest version.\nDeprecated: use example.com/b instead."},
+		{"v1.0.1", true, "`example.com/a` `v1.0.1`\n\nLatest version: `v1.1.0`\n\nDeprecated: use example.com/b instead.\n\nRetracted: `v1.0.1` is in the retracted versions [`v1.0.0`, `v1.0.5`]: broken"},
+	} {
+		if got := modHoverText(info, test.version, test.markdown); got != test.want {
+			t.Errorf("hover of %s:\ngot  %q\nwant %q", test.version, got, test.want)
+		}
+	}
+}
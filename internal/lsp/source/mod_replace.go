@@ -0,0 +1,341 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// ReplaceSource is the source of the diagnostics of replace directives whose
+// replacement is a directory that does not hold a module.
+const ReplaceSource = "go.mod replace"
+
+// DropReplaceTitle is the title of the fix that removes a replace directive.
+const DropReplaceTitle = "Drop this replace"
+
+// modReplace is a replacement of a module by a replace directive, such as
+// "golang.org/x/text => ../text". The offsets are those of the content of
+// the go.mod file.
+type modReplace struct {
+	// path is the module path that is replaced, and newPath and newVersion
+	// its replacement, a directory when newVersion is empty.
+	path, newPath, newVersion string
+
+	// start and end are the offsets of the line of the replacement,
+	// including its newline, and newStart and newEnd those of newPath.
+	start, end       int
+	newStart, newEnd int
+}
+
+// modReplaces returns the replacements of the replace directives of the
+// go.mod file with the given content.
+func modReplaces(content []byte) []modReplace {
+	var replaces []modReplace
+	for _, d := range parseModDirectives(content) {
+		if d.verb != "replace" {
+			continue
+		}
+		for _, e := range d.entries {
+			text := string(content[e.start:e.end])
+			arrow := strings.Index(text, "=>")
+			if arrow < 0 {
+				continue
+			}
+			fields := strings.Fields(text[arrow+len("=>"):])
+			if len(fields) == 0 {
+				continue
+			}
+			r := modReplace{path: e.name, newPath: fields[0]}
+			if len(fields) > 1 {
+				r.newVersion = fields[1]
+			}
+			r.newStart = e.start + arrow + strings.Index(text[arrow:], fields[0])
+			r.newEnd = r.newStart + len(fields[0])
+			// A directive on a single line is removed along with its verb.
+			r.start, r.end = lineBounds(content, e.start)
+			replaces = append(replaces, r)
+		}
+	}
+	return replaces
+}
+
+// lineBounds returns the offsets of the start of the line of content that
+// holds offset, and of the end of the line after its newline.
+func lineBounds(content []byte, offset int) (start, end int) {
+	start = strings.LastIndex(string(content[:offset]), "\n") + 1
+	end = len(content)
+	if i := strings.Index(string(content[offset:]), "\n"); i >= 0 {
+		end = offset + i + 1
+	}
+	return start, end
+}
+
+// isLocalReplacement reports whether the replacement of a replace directive
+// is a directory, which the go command requires to start with ./ or ../, or
+// to be absolute.
+func isLocalReplacement(path string) bool {
+	return strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") || filepath.IsAbs(path)
+}
+
+// ModDiagnostics returns the diagnostics of the go.mod files of the modules in
+// the folder of view: those of the replace directives whose replacement is a
+// directory that does not exist or that has no go.mod file, and those of the
+// requirements whose go.sum entries are missing or do not match. Every go.mod
+// file has an entry, so that its former diagnostics are cleared.
+func ModDiagnostics(ctx context.Context, view View) (map[span.URI][]Diagnostic, error) {
+	ctx, done := trace.StartSpan(ctx, "source.ModDiagnostics")
+	defer done()
+
+	folder := view.Folder().Filename()
+	dirs, err := WorkModules(folder)
+	if err != nil {
+		return nil, err
+	}
+	reports := make(map[span.URI][]Diagnostic)
+	for _, dir := range dirs {
+		uri := span.FileURI(filepath.Join(folder, filepath.FromSlash(dir), "go.mod"))
+		diagnostics, err := goModDiagnostics(ctx, view, uri)
+		if err != nil {
+			return nil, err
+		}
+		reports[uri] = diagnostics
+	}
+	return reports, nil
+}
+
+// replaceDiagnostics returns the diagnostics of the replace directives of
+// the go.mod file with the given URI, with their fixes.
+func replaceDiagnostics(ctx context.Context, view View, uri span.URI) ([]Diagnostic, error) {
+	content, _, err := view.Session().GetFile(uri, Mod).Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m := &protocol.ColumnMapper{
+		URI:       uri,
+		Converter: span.NewContentConverter(uri.Filename(), content),
+		Content:   content,
+		Encoding:  view.Options().PositionEncoding,
+	}
+	modDir := filepath.Dir(uri.Filename())
+	var diagnostics []Diagnostic
+	for _, r := range modReplaces(content) {
+		if r.newVersion != "" || !isLocalReplacement(r.newPath) {
+			continue
+		}
+		dir := r.newPath
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(modDir, filepath.FromSlash(dir))
+		}
+		var msg string
+		if _, err := os.Stat(dir); err != nil {
+			msg = fmt.Sprintf("replacement directory %s does not exist", r.newPath)
+		} else if _, err := os.Stat(filepath.Join(dir, "go.mod")); err != nil {
+			msg = fmt.Sprintf("replacement directory %s has no go.mod file", r.newPath)
+		} else {
+			continue
+		}
+		rng, err := offsetRange(m, r.newStart, r.newEnd)
+		if err != nil {
+			return nil, err
+		}
+		drop, err := dropReplaceFix(m, r)
+		if err != nil {
+			return nil, err
+		}
+		fixes := []SuggestedFix{drop}
+		for _, local := range localModuleDirs(view, modDir, r.path) {
+			fixes = append(fixes, SuggestedFix{
+				Title: replaceWithLocalTitle(local),
+				Edits: map[span.URI][]protocol.TextEdit{
+					uri: {{Range: rng, NewText: local}},
+				},
+			})
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			URI:            uri,
+			Range:          rng,
+			Message:        msg,
+			Source:         ReplaceSource,
+			Severity:       protocol.SeverityError,
+			SuggestedFixes: fixes,
+		})
+	}
+	return diagnostics, nil
+}
+
+// ReplaceFixes returns the fixes of the diagnostic of a replace directive of
+// the go.mod file f.
+func ReplaceFixes(ctx context.Context, view View, f File, diag protocol.Diagnostic) ([]SuggestedFix, error) {
+	diagnostics, err := replaceDiagnostics(ctx, view, f.URI())
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range diagnostics {
+		if protocol.CompareRange(d.Range, diag.Range) == 0 && d.Message == diag.Message {
+			return d.SuggestedFixes, nil
+		}
+	}
+	return nil, errors.Errorf("no replace directive at %v in %s", diag.Range, f.URI())
+}
+
+// ReplaceActions returns the edits that manage the replace directives of the
+// go.mod file f on the lines of rng: one that drops each replace directive,
+// and for each required module that is not replaced, one that replaces it by
+// each of the directories of the workspace, or next to the module of f, that
+// hold it.
+func ReplaceActions(ctx context.Context, view View, f File, rng protocol.Range) ([]SuggestedFix, error) {
+	ctx, done := trace.StartSpan(ctx, "source.ReplaceActions")
+	defer done()
+
+	m, err := fileMapper(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	onLines := func(offset int) bool {
+		pos, err := offsetRange(m, offset, offset)
+		if err != nil {
+			return false
+		}
+		return pos.Start.Line >= rng.Start.Line && pos.Start.Line <= rng.End.Line
+	}
+	var fixes []SuggestedFix
+	replaced := make(map[string]bool)
+	for _, r := range modReplaces(m.Content) {
+		replaced[r.path] = true
+		if !onLines(r.newStart) {
+			continue
+		}
+		drop, err := dropReplaceFix(m, r)
+		if err != nil {
+			return nil, err
+		}
+		fixes = append(fixes, drop)
+	}
+	modDir := filepath.Dir(f.URI().Filename())
+	for _, d := range parseModDirectives(m.Content) {
+		if d.verb != "require" {
+			continue
+		}
+		for _, e := range d.entries {
+			if replaced[e.name] || !onLines(e.start) {
+				continue
+			}
+			for _, local := range localModuleDirs(view, modDir, e.name) {
+				fix, err := addReplaceFix(m, e.name, local)
+				if err != nil {
+					return nil, err
+				}
+				fixes = append(fixes, fix)
+			}
+		}
+	}
+	return fixes, nil
+}
+
+// replaceWithLocalTitle returns the title of the fix that replaces a module
+// by the directory dir.
+func replaceWithLocalTitle(dir string) string {
+	return fmt.Sprintf("Replace with local directory %s", dir)
+}
+
+// dropReplaceFix returns the fix that removes the line of r. The block that
+// holds it, if any, is kept.
+func dropReplaceFix(m *protocol.ColumnMapper, r modReplace) (SuggestedFix, error) {
+	rng, err := offsetRange(m, r.start, r.end)
+	if err != nil {
+		return SuggestedFix{}, err
+	}
+	return SuggestedFix{
+		Title: DropReplaceTitle,
+		Edits: map[span.URI][]protocol.TextEdit{
+			m.URI: {{Range: rng, NewText: ""}},
+		},
+	}, nil
+}
+
+// addReplaceFix returns the fix that replaces the module with the given path
+// by the directory dir, in the last replace block of the file if it has one,
+// and in a new directive at its end otherwise.
+func addReplaceFix(m *protocol.ColumnMapper, path, dir string) (SuggestedFix, error) {
+	offset, text := len(m.Content), fmt.Sprintf("replace %s => %s\n", path, dir)
+	if offset > 0 && m.Content[offset-1] != '\n' {
+		text = "\n" + text
+	}
+	for _, d := range parseModDirectives(m.Content) {
+		if d.verb == "replace" && d.block && !d.open {
+			// Before the closing parenthesis.
+			offset, text = d.end-len(")"), fmt.Sprintf("\t%s => %s\n", path, dir)
+		}
+	}
+	rng, err := offsetRange(m, offset, offset)
+	if err != nil {
+		return SuggestedFix{}, err
+	}
+	return SuggestedFix{
+		Title: replaceWithLocalTitle(dir),
+		Edits: map[span.URI][]protocol.TextEdit{
+			m.URI: {{Range: rng, NewText: text}},
+		},
+	}, nil
+}
+
+// localModuleDirs returns the directories of the module with the given path
+// among the modules of the folder of view and the directories next to
+// modDir, relative to modDir in the form of the replacement of a replace
+// directive, such as ../text.
+func localModuleDirs(view View, modDir, path string) []string {
+	candidates := make(map[string]bool)
+	folder := view.Folder().Filename()
+	if dirs, err := WorkModules(folder); err == nil {
+		for _, dir := range dirs {
+			candidates[filepath.Join(folder, filepath.FromSlash(dir))] = true
+		}
+	}
+	if infos, err := ioutil.ReadDir(filepath.Dir(modDir)); err == nil {
+		for _, info := range infos {
+			if info.IsDir() {
+				candidates[filepath.Join(filepath.Dir(modDir), info.Name())] = true
+			}
+		}
+	}
+	var result []string
+	for dir := range candidates {
+		if dir == modDir {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err != nil || modulePath(content) != path {
+			continue
+		}
+		rel, err := filepath.Rel(modDir, dir)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != ".." && !strings.HasPrefix(rel, "../") {
+			rel = "./" + rel
+		}
+		result = append(result, rel)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// offsetRange returns the range of the content of m between the offsets
+// start and end.
+func offsetRange(m *protocol.ColumnMapper, start, end int) (protocol.Range, error) {
+	return m.Range(span.New(m.URI, span.NewPoint(0, 0, start), span.NewPoint(0, 0, end)))
+}
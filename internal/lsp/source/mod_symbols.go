@@ -0,0 +1,203 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// modDirective is a directive of a go.mod or go.work file, such as
+// "go 1.13" or a require block. The offsets are those of its content.
+type modDirective struct {
+	verb       string
+	args       string
+	comment    string
+	start, end int
+	verbEnd    int
+
+	// block reports whether the directive is a block, and open whether the
+	// block is not closed, in which case it extends to the end of the file.
+	block, open bool
+
+	// entries are the lines of a block, or the line of a directive of a
+	// verb that has blocks, such as "require golang.org/x/text v0.3.2".
+	entries []modEntry
+}
+
+// modEntry is a line of a block of a go.mod or go.work file, such as a
+// requirement.
+type modEntry struct {
+	// name is the first field of the line, such as a module path, and
+	// detail the others.
+	name, detail string
+	comment      string
+	start, end   int
+	nameEnd      int
+}
+
+// modBlockVerbs are the verbs of the directives that can have blocks.
+var modBlockVerbs = map[string]bool{
+	"require": true,
+	"replace": true,
+	"exclude": true,
+	"retract": true,
+	"use":     true,
+	"godebug": true,
+}
+
+// parseModDirectives returns the directives of the go.mod or go.work file
+// with the given content. It only splits lines into fields, and does not
+// check that the directives are valid.
+func parseModDirectives(content []byte) []modDirective {
+	var directives []modDirective
+	var block *modDirective
+	offset := 0
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		lineStart := offset
+		offset += len(line)
+		text := string(line)
+		comment := ""
+		if i := strings.Index(text, "//"); i >= 0 {
+			comment = strings.TrimSpace(text[i+len("//"):])
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		start := lineStart + strings.Index(text, fields[0])
+		last := fields[len(fields)-1]
+		end := lineStart + strings.LastIndex(text, last) + len(last)
+		if block != nil {
+			if fields[0] == ")" {
+				block.end = start + len(")")
+				directives = append(directives, *block)
+				block = nil
+				continue
+			}
+			block.entries = append(block.entries, modLineEntry(fields, comment, start, end))
+			continue
+		}
+		d := modDirective{
+			verb:    fields[0],
+			start:   start,
+			end:     end,
+			verbEnd: start + len(fields[0]),
+		}
+		switch {
+		case modBlockVerbs[d.verb] && len(fields) == 2 && fields[1] == "(":
+			d.block = true
+			block = &d
+			continue
+		case modBlockVerbs[d.verb] && len(fields) > 1:
+			entryStart := d.verbEnd + strings.Index(text[d.verbEnd-lineStart:], fields[1])
+			d.entries = append(d.entries, modLineEntry(fields[1:], comment, entryStart, end))
+		default:
+			d.args = strings.Join(fields[1:], " ")
+			d.comment = comment
+		}
+		directives = append(directives, d)
+	}
+	// A block that is not closed ends with the file.
+	if block != nil {
+		block.end = len(bytes.TrimRight(content, " \t\r\n"))
+		block.open = true
+		directives = append(directives, *block)
+	}
+	return directives
+}
+
+// modLineEntry returns the entry of a line of a block with the given fields
+// and trailing comment, which span [start, end) of the content. An
+// "indirect" comment is also kept in its detail.
+func modLineEntry(fields []string, comment string, start, end int) modEntry {
+	detail := strings.Join(fields[1:], " ")
+	if comment == "indirect" {
+		detail = strings.TrimSpace(detail + " // indirect")
+	}
+	return modEntry{
+		name:    fields[0],
+		detail:  detail,
+		comment: comment,
+		start:   start,
+		end:     end,
+		nameEnd: start + len(fields[0]),
+	}
+}
+
+// ModSymbols returns the outline of the go.mod or go.work file f: its
+// directives, such as module, go, require, replace, exclude and use, with the
+// lines of their blocks as children.
+func ModSymbols(ctx context.Context, view View, f File) ([]protocol.DocumentSymbol, error) {
+	ctx, done := trace.StartSpan(ctx, "source.ModSymbols")
+	defer done()
+
+	m, err := fileMapper(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	rng := func(start, end int) (protocol.Range, error) {
+		return m.Range(span.New(m.URI, span.NewPoint(0, 0, start), span.NewPoint(0, 0, end)))
+	}
+	var symbols []protocol.DocumentSymbol
+	for _, d := range parseModDirectives(m.Content) {
+		fullRange, err := rng(d.start, d.end)
+		if err != nil {
+			return nil, err
+		}
+		verbRange, err := rng(d.start, d.verbEnd)
+		if err != nil {
+			return nil, err
+		}
+		symbol := protocol.DocumentSymbol{
+			Name:           d.verb,
+			Detail:         d.args,
+			Kind:           modSymbolKind(d.verb),
+			Range:          fullRange,
+			SelectionRange: verbRange,
+		}
+		for _, e := range d.entries {
+			entryRange, err := rng(e.start, e.end)
+			if err != nil {
+				return nil, err
+			}
+			nameRange, err := rng(e.start, e.nameEnd)
+			if err != nil {
+				return nil, err
+			}
+			kind := protocol.Package
+			if d.verb == "use" {
+				kind = protocol.File
+			}
+			symbol.Children = append(symbol.Children, protocol.DocumentSymbol{
+				Name:           e.name,
+				Detail:         e.detail,
+				Kind:           kind,
+				Range:          entryRange,
+				SelectionRange: nameRange,
+			})
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+// modSymbolKind returns the kind of the symbol of a directive with verb.
+func modSymbolKind(verb string) protocol.SymbolKind {
+	switch verb {
+	case "module":
+		return protocol.Module
+	case "go", "toolchain":
+		return protocol.Property
+	default:
+		return protocol.Namespace
+	}
+}
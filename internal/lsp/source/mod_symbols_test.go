@@ -0,0 +1,65 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseModDirectives(t *testing.T) {
+	const src = `module example.com/m // the module
+
+go 1.13
+
+require golang.org/x/text v0.3.2
+
+require (
+	github.com/golang/mock v1.4.0 // indirect
+	golang.org/x/tools v0.0.0-20191108193012-7d206e10da11
+)
+
+replace golang.org/x/text => ../text
+
+exclude (
+	golang.org/x/net v1.2.3
+)
+
+use ./a
+`
+	var got []string
+	for _, d := range parseModDirectives([]byte(src)) {
+		if text := src[d.start:d.verbEnd]; text != d.verb {
+			t.Errorf("got verb text %q for %s", text, d.verb)
+		}
+		got = append(got, fmt.Sprintf("%s %q: %q", d.verb, d.args, src[d.start:d.end]))
+		for _, e := range d.entries {
+			if text := src[e.start:e.nameEnd]; text != e.name {
+				t.Errorf("got name text %q for %s", text, e.name)
+			}
+			got = append(got, fmt.Sprintf("\t%s %q: %q", e.name, e.detail, src[e.start:e.end]))
+		}
+	}
+	want := []string{
+		`module "example.com/m": "module example.com/m"`,
+		`go "1.13": "go 1.13"`,
+		`require "": "require golang.org/x/text v0.3.2"`,
+		`	golang.org/x/text "v0.3.2": "golang.org/x/text v0.3.2"`,
+		`require "": "require (\n\tgithub.com/golang/mock v1.4.0 // indirect\n\tgolang.org/x/tools v0.0.0-20191108193012-7d206e10da11\n)"`,
+		`	github.com/golang/mock "v1.4.0 // indirect": "github.com/golang/mock v1.4.0"`,
+		`	golang.org/x/tools "v0.0.0-20191108193012-7d206e10da11": "golang.org/x/tools v0.0.0-20191108193012-7d206e10da11"`,
+		`replace "": "replace golang.org/x/text => ../text"`,
+		`	golang.org/x/text "=> ../text": "golang.org/x/text => ../text"`,
+		`exclude "": "exclude (\n\tgolang.org/x/net v1.2.3\n)"`,
+		`	golang.org/x/net "v1.2.3": "golang.org/x/net v1.2.3"`,
+		`use "": "use ./a"`,
+		`	./a "": "./a"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got directives\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
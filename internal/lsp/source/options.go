@@ -7,6 +7,7 @@ package source
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"golang.org/x/tools/go/analysis"
@@ -33,6 +34,9 @@ import (
 	"golang.org/x/tools/go/analysis/passes/unreachable"
 	"golang.org/x/tools/go/analysis/passes/unsafeptr"
 	"golang.org/x/tools/go/analysis/passes/unusedresult"
+	"golang.org/x/tools/internal/lsp/analysis/deadbranch"
+	"golang.org/x/tools/internal/lsp/analysis/doccomment"
+	"golang.org/x/tools/internal/lsp/analysis/tagname"
 	"golang.org/x/tools/internal/lsp/diff"
 	"golang.org/x/tools/internal/lsp/diff/myers"
 	"golang.org/x/tools/internal/lsp/protocol"
@@ -51,23 +55,53 @@ var (
 			Go: {
 				protocol.SourceOrganizeImports: true,
 				protocol.QuickFix:              true,
+				protocol.RefactorRewrite:       true,
 			},
 			Mod: {
 				protocol.SourceOrganizeImports: true,
+				protocol.Source:                true,
+				protocol.QuickFix:              true,
+				protocol.RefactorRewrite:       true,
 			},
-			Sum: {},
+			Sum:  {},
+			Asm:  {},
+			Tmpl: {},
+			Work: {},
 		},
 		SupportedCommands: []string{
-			"tidy", // for go.mod files
+			"tidy",                        // for go.mod files
+			"download",                    // for go.mod files
+			"applytidy",                   // for go.mod files
+			"creatework",                  // for go.mod files
+			"vendor",                      // for Go files
+			"test",                        // for Go test files
+			"coverage",                    // for Go files
+			"apidiff",                     // for go.mod files
+			"generate",                    // for Go files
+			"gopls.GenerateTypeFromJSON",  // for Go files
+			"gopls.SetBuildConfiguration", // for views
 		},
 		Completion: CompletionOptions{
 			Documentation: true,
 			Deep:          true,
 			FuzzyMatching: true,
+			Postfix:       true,
 			Budget:        100 * time.Millisecond,
 		},
-		ComputeEdits: myers.ComputeEdits,
-		Analyzers:    defaultAnalyzers,
+		ComputeEdits:         myers.ComputeEdits,
+		Analyzers:            defaultAnalyzers,
+		ParseCacheSize:       1000,
+		FileCache:            true,
+		FileCacheCompression: true,
+		TemplateDelims:       [2]string{"{{", "}}"},
+		IgnoreFiles:          []string{".gitignore", ".goplsignore"},
+		LargeFileThreshold:   1 << 20,
+		LinkTarget:           "pkg.go.dev",
+		LinksInHover:         true,
+		StructTagCase:        tagname.CamelCase,
+		StructTagKeys:        []string{"json"},
+
+		ConfirmRenameConflicts: true,
 	}
 )
 
@@ -78,9 +112,32 @@ type Options struct {
 	// BuildFlags is used to adjust the build flags applied to the view.
 	BuildFlags []string
 
+	// DirectoryFilters exclude directories of the folder of the view from
+	// the view, or include them again, in the syntax of
+	// parseDirectoryFilter. They apply after the rules of IgnoreFiles.
+	DirectoryFilters []string
+
+	// IgnoreFiles are the names of the files at the root of the folder of
+	// the view, such as .gitignore, whose patterns in the syntax of
+	// .gitignore exclude the directories they match from the view.
+	IgnoreFiles []string
+
 	HoverKind        HoverKind
 	DisabledAnalyses map[string]struct{}
 
+	// LinkTarget is the host of the documentation site that hover links
+	// to, such as "pkg.go.dev" or "godoc.org".
+	LinkTarget string
+
+	// LinksInHover adds to markdown hover the links to the documentation
+	// of the symbol and of the types that its signature refers to.
+	LinksInHover bool
+
+	// HoverImplements adds to the hover of a named type the interfaces of
+	// the workspace that it implements, or for an interface, the types that
+	// implement it.
+	HoverImplements bool
+
 	StaticCheck bool
 	GoDiff      bool
 
@@ -91,6 +148,143 @@ type Options struct {
 	DynamicWatchedFilesSupported  bool
 	PreferredContentFormat        protocol.MarkupKind
 	LineFoldingOnly               bool
+	WorkDoneProgressSupported     bool
+	PullDiagnosticsSupported      bool
+
+	// PositionEncoding is the encoding of the characters of the positions
+	// exchanged with the client, negotiated from those it supports.
+	PositionEncoding protocol.PositionEncodingKind
+
+	// Hints are the kinds of inlay hints that are enabled, by name. No
+	// kind is enabled by default.
+	Hints map[string]bool
+
+	// FoldingRangeMaxDepth is the maximum nesting depth of the folding ranges
+	// returned for a file. A value of 0 means that there is no limit.
+	FoldingRangeMaxDepth int
+
+	// ComplexityLensThreshold is the cyclomatic complexity from which a
+	// function has a code lens with its complexity and size. A value of 0
+	// disables it.
+	ComplexityLensThreshold int
+
+	// SizeLensThreshold is the number of statements from which a function
+	// has a code lens with its complexity and size. A value of 0 disables
+	// it.
+	SizeLensThreshold int
+
+	// MemoryLimit is the heap size, in bytes, above which the packages
+	// type-checked in full for open files are evicted, least recently used
+	// first. A value of 0 means that there is no limit.
+	MemoryLimit int64
+
+	// LargeFileThreshold is the size, in bytes, above which the inlay hints
+	// and code lenses of a file are disabled, and its diagnostics are only
+	// computed when it is opened or saved rather than as it changes. A value
+	// of 0 means that there is no limit.
+	LargeFileThreshold int64
+
+	// StreamDiagnostics publishes the parse and type errors of each file of
+	// a package as soon as they are found, rather than once the whole
+	// package is type-checked.
+	StreamDiagnostics bool
+
+	// UnusedExports reports the exported functions and types of the packages
+	// in the folder of a view that no Go file in the folder refers to.
+	UnusedExports bool
+
+	// SpellCheck reports, as hints, the common misspellings in the comments,
+	// string literals and names of declarations of the packages of open
+	// files.
+	SpellCheck bool
+
+	// ParseCacheSize is the number of recently used parsed files that are
+	// kept in memory after no snapshot refers to them. The parse cache is
+	// shared by all views, so the most recently set value applies.
+	ParseCacheSize int
+
+	// FileCache stores the indexes of the Go files of the folders of views,
+	// which find their references, symbols and method sets, in a cache on
+	// disk shared by the gopls processes of the user, so that the files that
+	// have not changed are not parsed again when gopls starts. The cache is
+	// shared by all views, so the most recently set value applies.
+	FileCache bool
+
+	// FileCacheCompression compresses the large values stored in the file
+	// cache, which saves space on disk at the cost of the time spent
+	// compressing and decompressing them.
+	FileCacheCompression bool
+
+	// RemoteFileCache is the URL of an experimental remote cache that the
+	// file cache downloads the values it does not have from, if not empty.
+	RemoteFileCache string
+
+	// RemoteFileCacheUpload uploads the values stored in the file cache to
+	// its remote cache.
+	RemoteFileCacheUpload bool
+
+	// TemplateDelims are the left and right delimiters of the actions of
+	// template files.
+	TemplateDelims [2]string
+
+	// TemplateExtensions are the file extensions, such as ".html", of the
+	// template files, in addition to .tmpl and .gotmpl.
+	TemplateExtensions []string
+
+	// EditGeneratedFiles lets gopls edit generated files, marked by a
+	// "Code generated ... DO NOT EDIT." comment, when it renames identifiers
+	// or organizes imports, and stops it from warning about the edits of
+	// the user to such files.
+	EditGeneratedFiles bool
+
+	// RenamePackageDirectory makes the renaming of a package whose directory
+	// has its name change the import paths of the package and of its
+	// subdirectories as if the directory were renamed too. The directory
+	// itself is renamed by the client.
+	RenamePackageDirectory bool
+
+	// ConfirmRenameConflicts makes the renamings whose new name would
+	// conflict with, shadow or be captured by another declaration ask the
+	// user whether to rename anyway, listing the conflicts, instead of
+	// failing.
+	ConfirmRenameConflicts bool
+
+	// ExternalLanguageServers are the command lines of the language servers
+	// that the requests for the files of other languages are forwarded to,
+	// by file extension, such as ".proto".
+	ExternalLanguageServers map[string][]string
+
+	// Formatter formats Go files after gofmt when they are formatted, such
+	// as gofumpt. It is nil if they are only formatted by gofmt.
+	Formatter Formatter
+
+	// StructTagCase is the case, such as camelCase or snake_case, of the
+	// names of the struct tags that are completed or added to the fields of
+	// structs.
+	StructTagCase string
+
+	// StructTagKeys are the keys, such as json, of the struct tags that are
+	// added to the fields of structs.
+	StructTagKeys []string
+
+	// SymbolMatcher is the algorithm that matches the names of workspace
+	// symbols to the query of the user.
+	SymbolMatcher SymbolMatcher
+
+	// PromotedDefinition selects the locations of the definition of a field
+	// or method selected through embedded fields.
+	PromotedDefinition PromotedDefinition
+
+	// BuildConfiguration selects the target platform and build tags of the
+	// view, overriding its environment and build flags. It can be changed
+	// while the server runs with the gopls.SetBuildConfiguration command.
+	BuildConfiguration BuildConfiguration
+
+	// BuildMatrix are the build configurations, in addition to that of the
+	// view, under which the packages of open files are type-checked. Their
+	// errors that the configuration of the view does not have are reported
+	// too, so that code that only breaks on other platforms is caught.
+	BuildMatrix []BuildConfiguration
 
 	SupportedCodeActions map[FileKind]map[protocol.CodeActionKind]bool
 
@@ -115,6 +309,22 @@ type CompletionOptions struct {
 	FullDocumentation bool
 	Placeholders      bool
 
+	// ProxyVersions adds the versions listed by the module proxy to the
+	// completions of module versions in go.mod files.
+	ProxyVersions bool
+
+	// Postfix adds the postfix templates to the completions of selectors.
+	Postfix bool
+
+	// PostfixTemplates are the postfix templates defined by the user, in
+	// addition to the built-in ones.
+	PostfixTemplates []PostfixTemplate
+
+	// ErrorDestructuring adds, for the calls of functions that return a
+	// value and an error at the start of a statement, a second completion
+	// that assigns the results to new variables and checks the error.
+	ErrorDestructuring bool
+
 	// Budget is the soft latency goal for completion requests. Most
 	// requests finish in a couple milliseconds, but in some cases deep
 	// completions can take much longer. As we use up our budget we
@@ -139,6 +349,45 @@ const (
 	Structured
 )
 
+// SymbolMatcher is an algorithm that matches the names of workspace symbols
+// to a query.
+type SymbolMatcher int
+
+const (
+	// HybridSymbols matches the names that fuzzy match the query, that
+	// contain it, or whose camelCase humps start its parts, and ranks the
+	// names that contain it, with the same case, first.
+	HybridSymbols = SymbolMatcher(iota)
+
+	// FuzzySymbols matches the names that fuzzy match the query.
+	FuzzySymbols
+
+	// CaseInsensitiveSymbols matches the names that contain the query,
+	// ignoring case.
+	CaseInsensitiveSymbols
+
+	// CaseSensitiveSymbols matches the names that contain the query.
+	CaseSensitiveSymbols
+)
+
+// PromotedDefinition selects the locations of the definition of a promoted
+// field or method, which is selected through embedded fields.
+type PromotedDefinition int
+
+const (
+	// PromotionChain locates the embedded fields that are traversed, in
+	// order, followed by the declaration of the field or method.
+	PromotionChain = PromotedDefinition(iota)
+
+	// PromotedDeclaration locates only the declaration of the field or
+	// method.
+	PromotedDeclaration
+
+	// PromotedEmbedding locates only the first embedded field that is
+	// traversed, which is a field of the type of the selector's operand.
+	PromotedEmbedding
+)
+
 type OptionResults []OptionResult
 
 type OptionResult struct {
@@ -189,10 +438,42 @@ func (o *Options) ForClientCapabilities(caps protocol.ClientCapabilities) {
 	if hover := caps.TextDocument.Hover; hover != nil && len(hover.ContentFormat) > 0 {
 		o.PreferredContentFormat = hover.ContentFormat[0]
 	}
+	// Check if the client supports server-initiated progress reporting.
+	if window, ok := caps.Window.(map[string]interface{}); ok {
+		o.WorkDoneProgressSupported, _ = window["workDoneProgress"].(bool)
+	}
 	// Check if the client supports only line folding.
 	if fr := caps.TextDocument.FoldingRange; fr != nil {
 		o.LineFoldingOnly = fr.LineFoldingOnly
 	}
+	// Check if the client pulls diagnostics rather than having them pushed.
+	o.PullDiagnosticsSupported = caps.TextDocument.Diagnostic != nil
+	// Pick the position encoding that costs the least to convert to.
+	o.PositionEncoding = negotiatePositionEncoding(caps.General.PositionEncodings)
+}
+
+// negotiatePositionEncoding returns the position encoding to use with a
+// client that supports the given encodings. UTF-8 is preferred, as the
+// columns of gopls are in bytes, then UTF-16, which all clients support,
+// then UTF-32.
+func negotiatePositionEncoding(supported []protocol.PositionEncodingKind) protocol.PositionEncodingKind {
+	has := func(encoding protocol.PositionEncodingKind) bool {
+		for _, e := range supported {
+			if e == encoding {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case has(protocol.UTF8):
+		return protocol.UTF8
+	case len(supported) == 0 || has(protocol.UTF16):
+		return protocol.UTF16
+	case has(protocol.UTF32):
+		return protocol.UTF32
+	}
+	return protocol.UTF16
 }
 
 func (o *Options) set(name string, value interface{}) OptionResult {
@@ -220,6 +501,33 @@ func (o *Options) set(name string, value interface{}) OptionResult {
 		}
 		o.BuildFlags = flags
 
+	case "directoryFilters":
+		filters, ok := value.([]interface{})
+		if !ok {
+			result.errorf("Invalid type %T for []string option %q", value, name)
+			break
+		}
+		o.DirectoryFilters = nil
+		for _, filter := range filters {
+			filter := fmt.Sprint(filter)
+			if _, err := parseDirectoryFilter(filter); err != nil {
+				result.Error = err
+				continue
+			}
+			o.DirectoryFilters = append(o.DirectoryFilters, filter)
+		}
+
+	case "ignoreFiles":
+		names, ok := value.([]interface{})
+		if !ok {
+			result.errorf("Invalid type %T for []string option %q", value, name)
+			break
+		}
+		o.IgnoreFiles = nil
+		for _, name := range names {
+			o.IgnoreFiles = append(o.IgnoreFiles, fmt.Sprint(name))
+		}
+
 	case "noIncrementalSync":
 		if v, ok := result.asBool(); ok && v {
 			o.TextDocumentSyncKind = protocol.Full
@@ -238,6 +546,36 @@ func (o *Options) set(name string, value interface{}) OptionResult {
 		result.setBool(&o.Completion.CaseSensitive)
 	case "completeUnimported":
 		result.setBool(&o.Completion.Unimported)
+	case "completeProxyVersions":
+		result.setBool(&o.Completion.ProxyVersions)
+	case "postfixCompletions":
+		result.setBool(&o.Completion.Postfix)
+	case "errorDestructuring":
+		result.setBool(&o.Completion.ErrorDestructuring)
+
+	case "postfixTemplates":
+		templates, ok := value.([]interface{})
+		if !ok {
+			result.errorf("Invalid type %T for []object option %q", value, name)
+			break
+		}
+		o.Completion.PostfixTemplates = nil
+		for _, v := range templates {
+			fields, ok := v.(map[string]interface{})
+			if !ok {
+				result.errorf("Invalid postfix template %v of option %q", v, name)
+				continue
+			}
+			label, _ := fields["label"].(string)
+			details, _ := fields["details"].(string)
+			body, _ := fields["body"].(string)
+			t, err := ParsePostfixTemplate(label, details, body)
+			if err != nil {
+				result.Error = err
+				continue
+			}
+			o.Completion.PostfixTemplates = append(o.Completion.PostfixTemplates, t)
+		}
 
 	case "hoverKind":
 		hoverKind, ok := value.(string)
@@ -260,6 +598,20 @@ func (o *Options) set(name string, value interface{}) OptionResult {
 			result.errorf("Unsupported hover kind", tag.Of("HoverKind", hoverKind))
 		}
 
+	case "linkTarget":
+		linkTarget, ok := value.(string)
+		if !ok {
+			result.errorf("Invalid type %T for string option %q", value, name)
+			break
+		}
+		o.LinkTarget = strings.TrimSuffix(linkTarget, "/")
+
+	case "linksInHover":
+		result.setBool(&o.LinksInHover)
+
+	case "hoverImplements":
+		result.setBool(&o.HoverImplements)
+
 	case "experimentalDisabledAnalyses":
 		disabledAnalyses, ok := value.([]interface{})
 		if !ok {
@@ -271,6 +623,273 @@ func (o *Options) set(name string, value interface{}) OptionResult {
 			o.DisabledAnalyses[fmt.Sprint(a)] = struct{}{}
 		}
 
+	case "hints":
+		hints, ok := value.(map[string]interface{})
+		if !ok {
+			result.errorf("Invalid type %T for map option %q", value, name)
+			break
+		}
+		o.Hints = make(map[string]bool)
+		for kind, v := range hints {
+			if !isInlayHintKind(kind) {
+				result.errorf("Unknown inlay hint %q of option %q", kind, name)
+				continue
+			}
+			enabled, ok := v.(bool)
+			if !ok {
+				result.errorf("Invalid type %T for inlay hint %q of option %q", v, kind, name)
+				continue
+			}
+			o.Hints[kind] = enabled
+		}
+
+	case "foldingRangeMaxDepth":
+		result.setInt(&o.FoldingRangeMaxDepth)
+
+	case "complexityLensThreshold":
+		result.setInt(&o.ComplexityLensThreshold)
+
+	case "sizeLensThreshold":
+		result.setInt(&o.SizeLensThreshold)
+
+	case "memoryLimit":
+		if mb, ok := result.asInt(); ok {
+			o.MemoryLimit = int64(mb) << 20
+		}
+
+	case "largeFileThreshold":
+		threshold, ok := result.asInt()
+		if !ok {
+			break
+		}
+		if threshold < 0 {
+			result.errorf("Negative value %d for option %q", threshold, name)
+			break
+		}
+		o.LargeFileThreshold = int64(threshold)
+
+	case "parseCacheSize":
+		size, ok := result.asInt()
+		if !ok {
+			break
+		}
+		if size < 0 {
+			result.errorf("Negative value %d for option %q", size, name)
+			break
+		}
+		o.ParseCacheSize = size
+
+	case "fileCache":
+		result.setBool(&o.FileCache)
+
+	case "fileCacheCompression":
+		result.setBool(&o.FileCacheCompression)
+
+	case "remoteFileCache":
+		url, ok := value.(string)
+		if !ok {
+			result.errorf("Invalid type %T for string option %q", value, name)
+			break
+		}
+		o.RemoteFileCache = url
+
+	case "remoteFileCacheUpload":
+		result.setBool(&o.RemoteFileCacheUpload)
+
+	case "streamDiagnostics":
+		result.setBool(&o.StreamDiagnostics)
+
+	case "unusedExports":
+		result.setBool(&o.UnusedExports)
+
+	case "spellCheck":
+		result.setBool(&o.SpellCheck)
+
+	case "templateDelims":
+		delims, ok := value.([]interface{})
+		if !ok || len(delims) != 2 {
+			result.errorf("Invalid type %T for [2]string option %q", value, name)
+			break
+		}
+		left, lok := delims[0].(string)
+		right, rok := delims[1].(string)
+		if !lok || !rok || left == "" || right == "" {
+			result.errorf("Invalid delimiters %v for option %q", delims, name)
+			break
+		}
+		o.TemplateDelims = [2]string{left, right}
+
+	case "templateExtensions":
+		extensions, ok := value.([]interface{})
+		if !ok {
+			result.errorf("Invalid type %T for []string option %q", value, name)
+			break
+		}
+		o.TemplateExtensions = nil
+		for _, ext := range extensions {
+			ext := fmt.Sprint(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			o.TemplateExtensions = append(o.TemplateExtensions, ext)
+		}
+
+	case "editGeneratedFiles":
+		result.setBool(&o.EditGeneratedFiles)
+
+	case "renamePackageDirectory":
+		result.setBool(&o.RenamePackageDirectory)
+
+	case "confirmRenameConflicts":
+		result.setBool(&o.ConfirmRenameConflicts)
+
+	case "externalLanguageServers":
+		servers, ok := value.(map[string]interface{})
+		if !ok {
+			result.errorf("Invalid type %T for map option %q", value, name)
+			break
+		}
+		o.ExternalLanguageServers = make(map[string][]string)
+		for ext, v := range servers {
+			args, ok := v.([]interface{})
+			if !ok || len(args) == 0 {
+				result.errorf("Invalid command %v for extension %q of option %q", v, ext, name)
+				continue
+			}
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			command := make([]string, 0, len(args))
+			for _, arg := range args {
+				command = append(command, fmt.Sprint(arg))
+			}
+			o.ExternalLanguageServers[ext] = command
+		}
+
+	case "formatter":
+		switch formatter := value.(type) {
+		case string:
+			o.Formatter = nil
+			if formatter != "" {
+				o.Formatter = namedFormatter(formatter)
+			}
+		case []interface{}:
+			o.Formatter = nil
+			if len(formatter) > 0 {
+				command := make(CommandFormatter, 0, len(formatter))
+				for _, arg := range formatter {
+					command = append(command, fmt.Sprint(arg))
+				}
+				o.Formatter = command
+			}
+		default:
+			result.errorf("Invalid type %T for string or []string option %q", value, name)
+		}
+
+	case "structTagCase":
+		casing, ok := value.(string)
+		if !ok {
+			result.errorf("Invalid type %T for string option %q", value, name)
+			break
+		}
+		switch casing {
+		case tagname.CamelCase, tagname.SnakeCase, tagname.KebabCase, tagname.PascalCase:
+			o.StructTagCase = casing
+		default:
+			result.errorf("Unsupported struct tag case %q", casing)
+		}
+
+	case "structTagKeys":
+		keys, ok := value.([]interface{})
+		if !ok {
+			result.errorf("Invalid type %T for []string option %q", value, name)
+			break
+		}
+		o.StructTagKeys = nil
+		for _, key := range keys {
+			o.StructTagKeys = append(o.StructTagKeys, fmt.Sprint(key))
+		}
+
+	case "promotedDefinition":
+		definition, ok := value.(string)
+		if !ok {
+			result.errorf("Invalid type %T for string option %q", value, name)
+			break
+		}
+		switch definition {
+		case "chain":
+			o.PromotedDefinition = PromotionChain
+		case "declaration":
+			o.PromotedDefinition = PromotedDeclaration
+		case "embedding":
+			o.PromotedDefinition = PromotedEmbedding
+		default:
+			result.errorf("Unsupported promoted definition %q", definition)
+		}
+
+	case "symbolMatcher":
+		matcher, ok := value.(string)
+		if !ok {
+			result.errorf("Invalid type %T for string option %q", value, name)
+			break
+		}
+		switch matcher {
+		case "hybrid":
+			o.SymbolMatcher = HybridSymbols
+		case "fuzzy":
+			o.SymbolMatcher = FuzzySymbols
+		case "caseInsensitive":
+			o.SymbolMatcher = CaseInsensitiveSymbols
+		case "caseSensitive":
+			o.SymbolMatcher = CaseSensitiveSymbols
+		default:
+			result.errorf("Unsupported symbol matcher", tag.Of("SymbolMatcher", matcher))
+		}
+
+	case "buildConfiguration":
+		config, ok := value.(map[string]interface{})
+		if !ok {
+			result.errorf("Invalid type %T for object option %q", value, name)
+			break
+		}
+		var c BuildConfiguration
+		for k, v := range config {
+			switch k {
+			case "goos":
+				c.GOOS = fmt.Sprint(v)
+			case "goarch":
+				c.GOARCH = fmt.Sprint(v)
+			case "tags":
+				tags, ok := v.([]interface{})
+				if !ok {
+					result.errorf("Invalid type %T for the tags of option %q", v, name)
+					continue
+				}
+				for _, tag := range tags {
+					c.Tags = append(c.Tags, fmt.Sprint(tag))
+				}
+			default:
+				result.errorf("Unexpected field %q of option %q", k, name)
+			}
+		}
+		o.BuildConfiguration = c
+
+	case "buildMatrix":
+		configs, ok := value.([]interface{})
+		if !ok {
+			result.errorf("Invalid type %T for []string option %q", value, name)
+			break
+		}
+		o.BuildMatrix = nil
+		for _, config := range configs {
+			c, err := ParseBuildConfiguration(fmt.Sprint(config))
+			if err != nil {
+				result.Error = err
+				continue
+			}
+			o.BuildMatrix = append(o.BuildMatrix, c)
+		}
+
 	case "staticcheck":
 		result.setBool(&o.StaticCheck)
 
@@ -322,6 +941,22 @@ func (r *OptionResult) setBool(b *bool) {
 	}
 }
 
+func (r *OptionResult) asInt() (int, bool) {
+	// JSON numbers are decoded as float64.
+	f, ok := r.Value.(float64)
+	if !ok || f != float64(int(f)) {
+		r.errorf("Invalid type %T for int option %q", r.Value, r.Name)
+		return 0, false
+	}
+	return int(f), true
+}
+
+func (r *OptionResult) setInt(i *int) {
+	if v, ok := r.asInt(); ok {
+		*i = v
+	}
+}
+
 var defaultAnalyzers = []*analysis.Analyzer{
 	// The traditional vet suite:
 	asmdecl.Analyzer,
@@ -348,4 +983,7 @@ var defaultAnalyzers = []*analysis.Analyzer{
 	unusedresult.Analyzer,
 	// Non-vet analyzers
 	sortslice.Analyzer,
+	deadbranch.Analyzer,
+	doccomment.Analyzer,
+	tagname.Analyzer,
 }
@@ -0,0 +1,59 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import "testing"
+
+func TestSetLargeFileThreshold(t *testing.T) {
+	for _, test := range []struct {
+		value   interface{}
+		want    int64
+		wantErr bool
+	}{
+		{float64(1000), 1000, false},
+		// An invalid value keeps the default threshold.
+		{"big", DefaultOptions.LargeFileThreshold, true},
+		{float64(1.5), DefaultOptions.LargeFileThreshold, true},
+		{float64(-1), DefaultOptions.LargeFileThreshold, true},
+	} {
+		options := DefaultOptions
+		results := SetOptions(&options, map[string]interface{}{"largeFileThreshold": test.value})
+		if len(results) != 1 {
+			t.Fatalf("%v: got %d results, want 1", test.value, len(results))
+		}
+		if gotErr := results[0].Error != nil; gotErr != test.wantErr {
+			t.Errorf("%v: got error %v, want error: %v", test.value, results[0].Error, test.wantErr)
+		}
+		if options.LargeFileThreshold != test.want {
+			t.Errorf("%v: got threshold %d, want %d", test.value, options.LargeFileThreshold, test.want)
+		}
+	}
+}
+
+func TestSetParseCacheSize(t *testing.T) {
+	for _, test := range []struct {
+		value   interface{}
+		want    int
+		wantErr bool
+	}{
+		{float64(10), 10, false},
+		{float64(0), 0, false},
+		// A negative or invalid value keeps the default size.
+		{float64(-1), DefaultOptions.ParseCacheSize, true},
+		{"big", DefaultOptions.ParseCacheSize, true},
+	} {
+		options := DefaultOptions
+		results := SetOptions(&options, map[string]interface{}{"parseCacheSize": test.value})
+		if len(results) != 1 {
+			t.Fatalf("%v: got %d results, want 1", test.value, len(results))
+		}
+		if gotErr := results[0].Error != nil; gotErr != test.wantErr {
+			t.Errorf("%v: got error %v, want error: %v", test.value, results[0].Error, test.wantErr)
+		}
+		if options.ParseCacheSize != test.want {
+			t.Errorf("%v: got size %d, want %d", test.value, options.ParseCacheSize, test.want)
+		}
+	}
+}
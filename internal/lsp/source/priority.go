@@ -0,0 +1,24 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import "context"
+
+type backgroundKey struct{}
+
+// WithBackgroundPriority returns a context for work that no request of the
+// user waits for, such as the diagnostics of changed files. The parsing and
+// hashing of files done for such a context get fewer slots than those done
+// for the requests of the user, such as hover, which are served first.
+func WithBackgroundPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, backgroundKey{}, true)
+}
+
+// BackgroundPriority reports whether ctx was returned by
+// WithBackgroundPriority.
+func BackgroundPriority(ctx context.Context) bool {
+	background, _ := ctx.Value(backgroundKey{}).(bool)
+	return background
+}
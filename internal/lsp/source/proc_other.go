@@ -0,0 +1,17 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux,!darwin,!freebsd,!openbsd,!netbsd,!dragonfly
+
+package source
+
+import "os/exec"
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the process of cmd. Processes that it started may
+// keep running.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,25 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build linux darwin freebsd openbsd netbsd dragonfly
+
+package source
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup arranges for cmd to run in a new process group, so that
+// killProcessGroup also kills any processes that it starts, such as the test
+// binaries run by "go test".
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills the process group of cmd, which must have been
+// started after a call to setProcessGroup.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/types"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// Definitions returns the locations of the definition of the identifier,
+// which are those of its declaration, unless it selects a field or method
+// promoted through embedded fields, whose locations are selected by the
+// PromotedDefinition option.
+func (i *IdentifierInfo) Definitions(ctx context.Context) ([]protocol.Location, error) {
+	ctx, done := trace.StartSpan(ctx, "source.Definitions")
+	defer done()
+
+	declaration, err := i.Declaration.Range()
+	if err != nil {
+		return nil, err
+	}
+	locations := []protocol.Location{{
+		URI:   protocol.NewURI(i.Declaration.URI()),
+		Range: declaration,
+	}}
+	option := i.Snapshot.View().Options().PromotedDefinition
+	if option == PromotedDeclaration {
+		return locations, nil
+	}
+	var chain []protocol.Location
+	for _, field := range embeddingChain(i.selection) {
+		rng, err := objToMappedRange(ctx, i.pkg, field)
+		if err != nil {
+			return nil, err
+		}
+		location, err := rng.Range()
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, protocol.Location{URI: protocol.NewURI(rng.URI()), Range: location})
+	}
+	switch {
+	case len(chain) == 0:
+		return locations, nil
+	case option == PromotedEmbedding:
+		return chain[:1], nil
+	}
+	return append(chain, locations...), nil
+}
+
+// embeddingChain returns the embedded fields through which sel selects a
+// promoted field or method, in order, or nil if sel selects no promoted
+// field or method.
+func embeddingChain(sel *types.Selection) []*types.Var {
+	if sel == nil || len(sel.Index()) < 2 {
+		return nil
+	}
+	index := sel.Index()
+	var chain []*types.Var
+	typ := sel.Recv()
+	for _, i := range index[:len(index)-1] {
+		if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		st, ok := typ.Underlying().(*types.Struct)
+		if !ok || i >= st.NumFields() {
+			return nil
+		}
+		field := st.Field(i)
+		chain = append(chain, field)
+		typ = field.Type()
+	}
+	return chain
+}
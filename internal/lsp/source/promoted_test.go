@@ -0,0 +1,77 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func TestEmbeddingChain(t *testing.T) {
+	const src = `package a
+
+type A struct{ X int }
+
+func (*A) M() {}
+
+type B struct {
+	*A
+	Y int
+}
+
+type C struct {
+	B
+	I
+}
+
+type I interface{ N() }
+
+func _(c C, p *C) {
+	_ = c.Y
+	_ = c.X
+	_ = p.X
+	c.M()
+	c.N()
+	_ = c.B
+	_ = C.N
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "a.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{Selections: make(map[*ast.SelectorExpr]*types.Selection)}
+	if _, err := (&types.Config{}).Check("a", fset, []*ast.File{file}, info); err != nil {
+		t.Fatal(err)
+	}
+	var got [][]string
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			var names []string
+			for _, field := range embeddingChain(info.Selections[sel]) {
+				names = append(names, field.Name())
+			}
+			got = append(got, names)
+		}
+		return true
+	})
+	want := [][]string{
+		{"B"},      // c.Y
+		{"B", "A"}, // c.X
+		{"B", "A"}, // p.X
+		{"B", "A"}, // c.M
+		{"I"},      // c.N
+		nil,        // c.B
+		{"I"},      // C.N
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
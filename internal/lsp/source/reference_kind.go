@@ -0,0 +1,178 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// ReferenceKind is how a reference uses the object it refers to.
+type ReferenceKind string
+
+const (
+	// ReferenceDeclaration declares the object, or redeclares it with :=.
+	ReferenceDeclaration = ReferenceKind("declaration")
+
+	// ReferenceRead reads the value of the object, or refers to a type or
+	// function without calling it.
+	ReferenceRead = ReferenceKind("read")
+
+	// ReferenceWrite assigns to the object, increments or decrements it, or
+	// sets it as a field of a composite literal.
+	ReferenceWrite = ReferenceKind("write")
+
+	// ReferenceAddress takes the address of the variable, or of a field or
+	// element of it, which may then be read or written through the pointer.
+	ReferenceAddress = ReferenceKind("address")
+
+	// ReferenceCall calls the function, method or func-typed variable, or
+	// converts to the type.
+	ReferenceCall = ReferenceKind("call")
+
+	// ReferenceOther is a reference that is not an identifier, such as the
+	// target of a //go:linkname directive.
+	ReferenceOther = ReferenceKind("other")
+)
+
+// DescribeReference returns the kind of the reference to an object at the
+// identifier at pos in file, and the name of the function declaration that
+// encloses it, such as F or T.M, or "" if it is not in a function. A
+// reference in a function literal is enclosed by the declaration of the
+// function that the literal is in. The reference is described syntactically.
+func DescribeReference(file *ast.File, pos token.Pos) (kind ReferenceKind, function string) {
+	path, _ := astutil.PathEnclosingInterval(file, pos, pos)
+	if len(path) < 2 {
+		return ReferenceOther, ""
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return ReferenceOther, ""
+	}
+	for _, n := range path[1:] {
+		if decl, ok := n.(*ast.FuncDecl); ok && decl.Name != id {
+			function = decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				if recv := recvTypeName(decl.Recv.List[0].Type); recv != "" {
+					function = recv + "." + function
+				}
+			}
+			break
+		}
+	}
+	return ClassifyReference(id, path[1:]), function
+}
+
+// ClassifyReference returns the kind of the reference id, whose ancestors are
+// parents, innermost first.
+func ClassifyReference(id *ast.Ident, parents []ast.Node) ReferenceKind {
+	if len(parents) == 0 {
+		return ReferenceRead
+	}
+	// A selected identifier is used as its selector expression is.
+	var expr ast.Node = id
+	if sel, ok := parents[0].(*ast.SelectorExpr); ok && sel.Sel == id {
+		expr, parents = sel, parents[1:]
+	}
+	for len(parents) > 0 {
+		if paren, ok := parents[0].(*ast.ParenExpr); ok {
+			expr, parents = paren, parents[1:]
+			continue
+		}
+		break
+	}
+	if len(parents) == 0 {
+		return ReferenceRead
+	}
+	if addressTaken(expr, parents) {
+		return ReferenceAddress
+	}
+	switch parent := parents[0].(type) {
+	case *ast.CallExpr:
+		if parent.Fun == expr {
+			return ReferenceCall
+		}
+	case *ast.AssignStmt:
+		for _, lhs := range parent.Lhs {
+			if lhs == expr {
+				if parent.Tok == token.DEFINE {
+					return ReferenceDeclaration
+				}
+				return ReferenceWrite
+			}
+		}
+	case *ast.IncDecStmt:
+		return ReferenceWrite
+	case *ast.RangeStmt:
+		if parent.Key == expr || parent.Value == expr {
+			if parent.Tok == token.DEFINE {
+				return ReferenceDeclaration
+			}
+			return ReferenceWrite
+		}
+	case *ast.KeyValueExpr:
+		if len(parents) < 2 {
+			break
+		}
+		if _, ok := parents[1].(*ast.CompositeLit); ok && parent.Key == expr {
+			return ReferenceWrite
+		}
+	case *ast.FuncDecl:
+		if parent.Name == id {
+			return ReferenceDeclaration
+		}
+	case *ast.TypeSpec:
+		if parent.Name == id {
+			return ReferenceDeclaration
+		}
+	case *ast.ImportSpec:
+		if parent.Name == id {
+			return ReferenceDeclaration
+		}
+	case *ast.LabeledStmt:
+		if parent.Label == id {
+			return ReferenceDeclaration
+		}
+	case *ast.ValueSpec:
+		for _, name := range parent.Names {
+			if name == id {
+				return ReferenceDeclaration
+			}
+		}
+	case *ast.Field:
+		for _, name := range parent.Names {
+			if name == id {
+				return ReferenceDeclaration
+			}
+		}
+	}
+	return ReferenceRead
+}
+
+// addressTaken reports whether the address of expr, or of a field or element
+// of it, is taken, where parents are the ancestors of expr, innermost first.
+func addressTaken(expr ast.Node, parents []ast.Node) bool {
+	for _, n := range parents {
+		switch n := n.(type) {
+		case *ast.UnaryExpr:
+			return n.Op == token.AND && n.X == expr
+		case *ast.SelectorExpr:
+			if n.X != expr {
+				return false
+			}
+		case *ast.IndexExpr:
+			if n.X != expr {
+				return false
+			}
+		case *ast.ParenExpr:
+		default:
+			return false
+		}
+		expr = n
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestDescribeReference(t *testing.T) {
+	const src = `package a
+
+import fmtpkg "fmt"
+
+type T struct{ field int }
+
+var global int
+
+func (t *T) M() {
+	t.field = 1
+	global++
+	local := T{field: global}
+	(local.M)()
+	_ = &local.field
+	func() {
+		for i := range "" {
+			_ = i
+		}
+	}()
+	fmtpkg.Println(t)
+L:
+	goto L
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "a.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := fset.File(file.Pos())
+	for _, test := range []struct {
+		at       string // the reference is at the first occurrence of at
+		kind     ReferenceKind
+		function string
+	}{
+		{"fmtpkg \"", ReferenceDeclaration, ""},
+		{"T struct", ReferenceDeclaration, ""},
+		{"field int", ReferenceDeclaration, ""},
+		{"global int", ReferenceDeclaration, ""},
+		{"M()", ReferenceDeclaration, ""},
+		{"field = 1", ReferenceWrite, "T.M"},
+		{"global++", ReferenceWrite, "T.M"},
+		{"local :=", ReferenceDeclaration, "T.M"},
+		{"field: global", ReferenceWrite, "T.M"},
+		{"global}", ReferenceRead, "T.M"},
+		{"M)()", ReferenceCall, "T.M"},
+		{"local.field", ReferenceAddress, "T.M"},
+		{"field\n", ReferenceAddress, "T.M"},
+		{"i := range", ReferenceDeclaration, "T.M"},
+		{"i\n", ReferenceRead, "T.M"},
+		{"Println", ReferenceCall, "T.M"},
+		{"fmtpkg.Println", ReferenceRead, "T.M"},
+		{"L:", ReferenceDeclaration, "T.M"},
+		{"\"fmt\"", ReferenceOther, ""},
+	} {
+		offset := strings.Index(src, test.at)
+		if offset < 0 {
+			t.Fatalf("%q is not in the source", test.at)
+		}
+		kind, function := DescribeReference(file, tok.Pos(offset))
+		if kind != test.kind || function != test.function {
+			t.Errorf("%q: got %s in %q, want %s in %q", test.at, kind, function, test.kind, test.function)
+		}
+	}
+}
@@ -29,6 +29,7 @@ type renamer struct {
 	objsToUpdate       map[types.Object]bool
 	hadConflicts       bool
 	errors             string
+	conflicts          []RenameConflict
 	from, to           string
 	satisfyConstraints map[satisfy.Constraint]bool
 	packages           map[*types.Package]Package // may include additional packages that are a rdep of pkg
@@ -36,6 +37,28 @@ type renamer struct {
 	changeMethods      bool
 }
 
+// RenameConflictError is the error of a renaming whose new name would
+// conflict with, shadow or be captured by other declarations. Its Edits
+// rename all of the references regardless, which changes the meaning of the
+// program or breaks it.
+type RenameConflictError struct {
+	Msg       string
+	Conflicts []RenameConflict
+	Edits     map[span.URI][]protocol.TextEdit
+}
+
+func (e *RenameConflictError) Error() string {
+	return e.Msg
+}
+
+// RenameConflict is a declaration or reference that a renaming conflicts
+// with, or that it conflicts at.
+type RenameConflict struct {
+	URI     span.URI
+	Line    int // 1-based
+	Message string
+}
+
 type PrepareItem struct {
 	Range protocol.Range
 	Text  string
@@ -110,6 +133,10 @@ func (i *IdentifierInfo) Rename(ctx context.Context, view View, newName string)
 		return nil, errors.Errorf("failed to rename because %q is declared in package %q", i.Name, i.Declaration.obj.Pkg().Name())
 	}
 
+	if declURI := i.Declaration.URI(); protectGenerated(ctx, view, declURI) {
+		return nil, errors.Errorf("cannot rename %q: it is declared in the generated file %s", i.Name, declURI.Filename())
+	}
+
 	refs, err := i.References(ctx)
 	if err != nil {
 		return nil, err
@@ -136,22 +163,41 @@ func (i *IdentifierInfo) Rename(ctx context.Context, view View, newName string)
 		}
 	}
 	if r.hadConflicts {
-		return nil, errors.Errorf(r.errors)
+		conflictErr := &RenameConflictError{Msg: r.errors}
+		// Check the rest of the references, so that the edits rename them
+		// all, and report all of the conflicts.
+		for _, ref := range refs {
+			r.check(ref.obj)
+		}
+		conflictErr.Conflicts = r.conflicts
+		if conflictErr.Edits, err = r.edits(ctx, view, i.Snapshot); err != nil {
+			return nil, err
+		}
+		return nil, conflictErr
 	}
+	return r.edits(ctx, view, i.Snapshot)
+}
 
+// edits returns the edits that rename the objects to update.
+func (r *renamer) edits(ctx context.Context, view View, snapshot Snapshot) (map[span.URI][]protocol.TextEdit, error) {
 	changes, err := r.update()
 	if err != nil {
 		return nil, err
 	}
 	result := make(map[span.URI][]protocol.TextEdit)
 	for uri, edits := range changes {
+		// The references in generated files are left to the tools that
+		// generate them.
+		if protectGenerated(ctx, view, uri) {
+			continue
+		}
 		// These edits should really be associated with FileHandles for maximal correctness.
 		// For now, this is good enough.
 		f, err := view.GetFile(ctx, uri)
 		if err != nil {
 			return nil, err
 		}
-		fh := i.Snapshot.Handle(ctx, f)
+		fh := snapshot.Handle(ctx, f)
 		data, _, err := fh.Read(ctx)
 		if err != nil {
 			return nil, err
@@ -161,6 +207,7 @@ func (i *IdentifierInfo) Rename(ctx context.Context, view View, newName string)
 			URI:       uri,
 			Converter: converter,
 			Content:   data,
+			Encoding:  view.Options().PositionEncoding,
 		}
 		// Sort the edits first.
 		diff.SortTextEdits(edits)
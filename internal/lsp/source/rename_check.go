@@ -18,13 +18,23 @@ import (
 	"unicode"
 
 	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/internal/span"
 	"golang.org/x/tools/refactor/satisfy"
 )
 
 // errorf reports an error (e.g. conflict) and prevents file modification.
 func (r *renamer) errorf(pos token.Pos, format string, args ...interface{}) {
 	r.hadConflicts = true
-	r.errors += fmt.Sprintf(format, args...)
+	msg := fmt.Sprintf(format, args...)
+	r.errors += msg
+	if pos.IsValid() {
+		position := r.fset.Position(pos)
+		r.conflicts = append(r.conflicts, RenameConflict{
+			URI:     span.FileURI(position.Filename),
+			Line:    position.Line,
+			Message: strings.TrimSpace(msg),
+		})
+	}
 }
 
 // check performs safety checks of the renaming of the 'from' object to r.to.
@@ -0,0 +1,150 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// PrepareRenameLabel returns the name of the label at pos in f, or nil if
+// there is none.
+func PrepareRenameLabel(ctx context.Context, view View, f File, pos protocol.Position) (*PrepareItem, error) {
+	file, tok, m, err := parseForRename(ctx, view, f.URI())
+	if err != nil {
+		return nil, err
+	}
+	p, err := protocolPos(tok, m, pos)
+	if err != nil {
+		return nil, err
+	}
+	label, _ := labelAt(file, p)
+	if label == nil {
+		return nil, nil
+	}
+	rng, err := m.Range(span.New(m.URI, span.NewPoint(0, 0, tok.Offset(label.Pos())), span.NewPoint(0, 0, tok.Offset(label.End()))))
+	if err != nil {
+		return nil, err
+	}
+	return &PrepareItem{Range: rng, Text: label.Name}, nil
+}
+
+// RenameLabel returns the edits that rename the label at pos in f, where it
+// is declared or where a break, continue or goto statement refers to it, or
+// nil if there is no label at pos. Labels are resolved within the body of
+// their function as it is parsed, so the file need not type-check.
+func RenameLabel(ctx context.Context, view View, f File, pos protocol.Position, newName string) (map[span.URI][]protocol.TextEdit, error) {
+	ctx, done := trace.StartSpan(ctx, "source.RenameLabel")
+	defer done()
+
+	file, tok, m, err := parseForRename(ctx, view, f.URI())
+	if err != nil {
+		return nil, err
+	}
+	p, err := protocolPos(tok, m, pos)
+	if err != nil {
+		return nil, err
+	}
+	label, body := labelAt(file, p)
+	if label == nil {
+		return nil, nil
+	}
+	switch {
+	case label.Name == newName:
+		return nil, errors.Errorf("old and new names are the same: %s", newName)
+	case !isValidIdentifier(newName):
+		return nil, errors.Errorf("invalid identifier to rename: %q", newName)
+	}
+	// The conflict is reported as the renamer reports it.
+	if _, decl := labelRefs(body, newName); decl != nil {
+		return nil, errors.Errorf("renaming this label %q to %q\twould conflict with this one", label.Name, newName)
+	}
+	refs, _ := labelRefs(body, label.Name)
+	var edits []protocol.TextEdit
+	for _, id := range refs {
+		rng, err := m.Range(span.New(m.URI, span.NewPoint(0, 0, tok.Offset(id.Pos())), span.NewPoint(0, 0, tok.Offset(id.End()))))
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, protocol.TextEdit{Range: rng, NewText: newName})
+	}
+	return map[span.URI][]protocol.TextEdit{f.URI(): edits}, nil
+}
+
+// protocolPos returns the position in the file tok of pos.
+func protocolPos(tok *token.File, m *protocol.ColumnMapper, pos protocol.Position) (token.Pos, error) {
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return token.NoPos, err
+	}
+	rng, err := spn.Range(m.Converter)
+	if err != nil {
+		return token.NoPos, err
+	}
+	return rng.Start, nil
+}
+
+// labelAt returns the label that is declared or referred to at pos in file,
+// and the body of the function it belongs to, or nil if there is none.
+func labelAt(file *ast.File, pos token.Pos) (*ast.Ident, *ast.BlockStmt) {
+	path, _ := astutil.PathEnclosingInterval(file, pos, pos)
+	if len(path) < 2 {
+		return nil, nil
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return nil, nil
+	}
+	switch n := path[1].(type) {
+	case *ast.LabeledStmt:
+		ok = n.Label == id
+	case *ast.BranchStmt:
+		ok = n.Label == id
+	default:
+		ok = false
+	}
+	if !ok {
+		return nil, nil
+	}
+	for _, n := range path[2:] {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return id, n.Body
+		case *ast.FuncDecl:
+			return id, n.Body
+		}
+	}
+	return nil, nil
+}
+
+// labelRefs returns the identifiers of the label named name in body, where
+// it is declared or referred to, and that of its declaration, if any. The
+// labels of the function literals in body are those of other functions.
+func labelRefs(body *ast.BlockStmt, name string) (refs []*ast.Ident, decl *ast.Ident) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.LabeledStmt:
+			if n.Label.Name == name {
+				refs = append(refs, n.Label)
+				decl = n.Label
+			}
+		case *ast.BranchStmt:
+			if n.Label != nil && n.Label.Name == name {
+				refs = append(refs, n.Label)
+			}
+		}
+		return true
+	})
+	return refs, decl
+}
@@ -0,0 +1,74 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestLabelRefs(t *testing.T) {
+	const src = `package a
+
+func f() {
+L:
+	for {
+		func() {
+		L:
+			for {
+				break L
+			}
+		}()
+		if undefined {
+			continue L
+		}
+	}
+	goto L
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "a.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := fset.File(file.Pos())
+	for _, test := range []struct {
+		at    string
+		lines []int
+	}{
+		{"\nL", []int{4, 13, 16}},
+		{"continue L", []int{4, 13, 16}},
+		{"goto L", []int{4, 13, 16}},
+		{"\t\tL", []int{7, 9}},
+		{"break L", []int{7, 9}},
+	} {
+		// The label is the last character of at.
+		label, body := labelAt(file, tok.Pos(strings.Index(src, test.at)+len(test.at)-1))
+		if label == nil {
+			t.Errorf("%q: no label", test.at)
+			continue
+		}
+		refs, decl := labelRefs(body, label.Name)
+		var lines []int
+		for _, id := range refs {
+			lines = append(lines, tok.Line(id.Pos()))
+		}
+		if len(lines) != len(test.lines) || decl == nil || tok.Line(decl.Pos()) != test.lines[0] {
+			t.Errorf("%q: got label references at lines %v, want %v", test.at, lines, test.lines)
+			continue
+		}
+		for i := range lines {
+			if lines[i] != test.lines[i] {
+				t.Errorf("%q: got label references at lines %v, want %v", test.at, lines, test.lines)
+				break
+			}
+		}
+	}
+	if label, _ := labelAt(file, tok.Pos(strings.Index(src, "undefined"))); label != nil {
+		t.Errorf("got label %s at an expression", label.Name)
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// PrepareRenamePackage returns the name of the package clause of f if pos is
+// in it, or nil if it is not.
+func PrepareRenamePackage(ctx context.Context, view View, f File, pos protocol.Position) (*PrepareItem, error) {
+	file, m, err := packageClauseAt(ctx, view, f, pos)
+	if file == nil {
+		return nil, err
+	}
+	spn, err := span.NewRange(view.Session().Cache().FileSet(), file.Name.Pos(), file.Name.End()).Span()
+	if err != nil {
+		return nil, err
+	}
+	rng, err := m.Range(spn)
+	if err != nil {
+		return nil, err
+	}
+	return &PrepareItem{Range: rng, Text: file.Name.Name}, nil
+}
+
+// RenamePackage returns the edits that rename the package whose package
+// clause in f is at pos, or nil if pos is not in the name of the package
+// clause of f.
+//
+// The package clauses of the files of the directory of f are renamed, along
+// with those of its external test package, and, in the files that import it,
+// the qualified identifiers that refer to it and the names of its imports
+// that are the same as its own. The importers are those that the metadata of
+// the view knows, along with the files of the folder of the view whose imports
+// name its path. A file that could not refer to the package by its new name,
+// as it declares or imports another object with that name, keeps referring to
+// it by its old name, which its import is given.
+//
+// If the RenamePackageDirectory option is set and the directory of the
+// package has its name, the paths of the imports of the package and of those
+// of its subdirectories are changed as if the directory had been renamed,
+// which is left to the client.
+func RenamePackage(ctx context.Context, view View, f File, pos protocol.Position, newName string) (map[span.URI][]protocol.TextEdit, error) {
+	ctx, done := trace.StartSpan(ctx, "source.RenamePackage")
+	defer done()
+
+	file, m, err := packageClauseAt(ctx, view, f, pos)
+	if file == nil {
+		return nil, err
+	}
+	from := file.Name.Name
+	switch {
+	case strings.HasSuffix(from, "_test"):
+		return nil, errors.Errorf("cannot rename the test package %s: rename the package it tests", from)
+	case from == "main" || newName == "main":
+		return nil, errors.Errorf("cannot rename package %s to %s: main packages cannot be imported", from, newName)
+	case from == newName:
+		return nil, errors.Errorf("old and new names are the same: %s", newName)
+	case !isValidIdentifier(newName):
+		return nil, errors.Errorf("invalid package name: %q", newName)
+	}
+
+	// Load the metadata of the package, so that its importers are known.
+	snapshot, _, err := view.CheckPackageHandles(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(f.URI().Filename())
+	renameDir := view.Options().RenamePackageDirectory && filepath.Base(dir) == from
+	pkgPath, importers, err := snapshot.PackageImporters(ctx, f.URI(), renameDir)
+	if err != nil {
+		return nil, err
+	}
+	newPath := ""
+	if renameDir && path.Base(pkgPath) == from {
+		newPath = path.Join(path.Dir(pkgPath), newName)
+	}
+
+	edits := make(map[span.URI][]offsetEdit)
+	mappers := map[span.URI]*protocol.ColumnMapper{f.URI(): m}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".go") {
+			continue
+		}
+		uri := span.FileURI(filepath.Join(dir, info.Name()))
+		file, tok, m, err := parseForRename(ctx, view, uri)
+		if err != nil {
+			continue
+		}
+		mappers[uri] = m
+		switch file.Name.Name {
+		case from:
+			edits[uri] = append(edits[uri], replaceNode(tok, file.Name, newName))
+		case from + "_test":
+			edits[uri] = append(edits[uri], replaceNode(tok, file.Name, newName+"_test"))
+		}
+	}
+	for _, uri := range importers {
+		file, tok, m, err := parseForRename(ctx, view, uri)
+		if err != nil {
+			continue
+		}
+		mappers[uri] = m
+		edits[uri] = append(edits[uri], importerEdits(tok, file, pkgPath, newPath, from, newName)...)
+	}
+
+	result := make(map[span.URI][]protocol.TextEdit)
+	for uri, fileEdits := range edits {
+		// The files that are generated are left to the tools that
+		// generate them.
+		if len(fileEdits) == 0 || protectGenerated(ctx, view, uri) {
+			continue
+		}
+		m := mappers[uri]
+		for _, e := range fileEdits {
+			rng, err := m.Range(span.New(uri, span.NewPoint(0, 0, e.start), span.NewPoint(0, 0, e.end)))
+			if err != nil {
+				return nil, err
+			}
+			result[uri] = append(result[uri], protocol.TextEdit{Range: rng, NewText: e.text})
+		}
+	}
+	return result, nil
+}
+
+// packageClauseAt returns f, as parsed, if pos is in the name
+// of its package clause, or nil if it is not.
+func packageClauseAt(ctx context.Context, view View, f File, pos protocol.Position) (*ast.File, *protocol.ColumnMapper, error) {
+	file, _, m, err := parseForRename(ctx, view, f.URI())
+	if err != nil {
+		return nil, nil, err
+	}
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return nil, nil, err
+	}
+	rng, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rng.Start < file.Name.Pos() || rng.Start > file.Name.End() {
+		return nil, nil, nil
+	}
+	return file, m, nil
+}
+
+// parseForRename parses the file uri of the view. The files are parsed in
+// full, as the lines of a header are all that its mapper knows of.
+func parseForRename(ctx context.Context, view View, uri span.URI) (*ast.File, *token.File, *protocol.ColumnMapper, error) {
+	f, err := view.GetFile(ctx, uri)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	fh := view.Snapshot().Handle(ctx, f)
+	file, m, _, err := view.Session().ParseGoHandle(fh, ParseFull).Parse(ctx)
+	if file == nil {
+		return nil, nil, nil, errors.Errorf("%s: %v", uri, err)
+	}
+	tok := view.Session().Cache().FileSet().File(file.Pos())
+	if tok == nil {
+		return nil, nil, nil, errors.Errorf("no file for %s", uri)
+	}
+	return file, tok, m, nil
+}
+
+// importerEdits returns the edits that rename the package with the given path
+// from one name to another in file, which imports it. If newPath is not empty,
+// the imports of the package and of the packages under its path are changed to
+// be under newPath.
+func importerEdits(tok *token.File, file *ast.File, pkgPath, newPath, from, to string) []offsetEdit {
+	var edits []offsetEdit
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if newPath != "" && (importPath == pkgPath || strings.HasPrefix(importPath, pkgPath+"/")) {
+			edits = append(edits, replaceNode(tok, spec.Path, strconv.Quote(newPath+importPath[len(pkgPath):])))
+		}
+		if importPath != pkgPath || spec.Name != nil && spec.Name.Name != from {
+			// The imports with other names do not refer to the package
+			// by its name.
+			continue
+		}
+		if declaresName(file, to) {
+			if spec.Name == nil {
+				edits = append(edits, offsetEdit{start: tok.Offset(spec.Path.Pos()), end: tok.Offset(spec.Path.Pos()), text: from + " "})
+			}
+			continue
+		}
+		if spec.Name != nil {
+			edits = append(edits, replaceNode(tok, spec.Name, to))
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok && x.Name == from && x.Obj == nil {
+					edits = append(edits, replaceNode(tok, x, to))
+				}
+			}
+			return true
+		})
+	}
+	return edits
+}
+
+// declaresName reports whether file may declare or import an object named
+// name, or refer to one of its package. Any identifier with that name that is
+// not selected from another counts.
+func declaresName(file *ast.File, name string) bool {
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if spec.Name != nil && spec.Name.Name == name || spec.Name == nil && assumedImportName(importPath) == name {
+			return true
+		}
+	}
+	found := false
+	for _, decl := range file.Decls {
+		astutil.Apply(decl, func(c *astutil.Cursor) bool {
+			if id, ok := c.Node().(*ast.Ident); ok && id.Name == name {
+				if sel, ok := c.Parent().(*ast.SelectorExpr); !ok || sel.Sel != id {
+					found = true
+				}
+			}
+			return !found
+		}, nil)
+	}
+	return found
+}
+
+// replaceNode returns the edit that replaces n in the file tok with text.
+func replaceNode(tok *token.File, n ast.Node, text string) offsetEdit {
+	return offsetEdit{start: tok.Offset(n.Pos()), end: tok.Offset(n.End()), text: text}
+}
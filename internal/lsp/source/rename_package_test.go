@@ -0,0 +1,123 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/parser"
+	"go/token"
+	"sort"
+	"testing"
+)
+
+func TestImporterEdits(t *testing.T) {
+	for _, test := range []struct {
+		name, src, newPath, want string
+	}{
+		{
+			name: "qualified",
+			src: `package a
+
+import "example.com/m/util"
+
+func f() { util.Do(util.X) }
+`,
+			want: `package a
+
+import "example.com/m/util"
+
+func f() { helper.Do(helper.X) }
+`,
+		},
+		{
+			name: "alias",
+			src: `package a
+
+import (
+	util "example.com/m/util"
+	u "example.com/m/util/sub"
+)
+
+var _ = util.X + u.Y
+`,
+			newPath: "example.com/m/helper",
+			want: `package a
+
+import (
+	helper "example.com/m/helper"
+	u "example.com/m/helper/sub"
+)
+
+var _ = helper.X + u.Y
+`,
+		},
+		{
+			name: "other alias",
+			src: `package a
+
+import u "example.com/m/util"
+
+var _ = u.X
+`,
+			want: `package a
+
+import u "example.com/m/util"
+
+var _ = u.X
+`,
+		},
+		{
+			name: "shadowed",
+			src: `package a
+
+import "example.com/m/util"
+
+func f(util int) {}
+
+var _ = util.X
+`,
+			want: `package a
+
+import "example.com/m/util"
+
+func f(util int) {}
+
+var _ = helper.X
+`,
+		},
+		{
+			name: "conflict",
+			src: `package a
+
+import "example.com/m/util"
+
+func f(helper int) { util.Do(helper) }
+`,
+			want: `package a
+
+import util "example.com/m/util"
+
+func f(helper int) { util.Do(helper) }
+`,
+		},
+	} {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "a.go", test.src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		edits := importerEdits(fset.File(file.Pos()), file, "example.com/m/util", test.newPath, "util", "helper")
+		sort.Slice(edits, func(i, j int) bool {
+			return edits[i].start < edits[j].start
+		})
+		got := test.src
+		for i := len(edits) - 1; i >= 0; i-- {
+			e := edits[i]
+			got = got[:e.start] + e.text + got[e.end:]
+		}
+		if got != test.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// SelectionRange returns the selection ranges for each of the given
+// positions in f. Each selection range expands step by step from the
+// innermost syntactic element at the position out to the whole file.
+func SelectionRange(ctx context.Context, view View, f File, positions []protocol.Position) ([]protocol.SelectionRange, error) {
+	ctx, done := trace.StartSpan(ctx, "source.SelectionRange")
+	defer done()
+
+	fh := view.Snapshot().Handle(ctx, f)
+	ph := view.Session().ParseGoHandle(fh, ParseFull)
+	file, m, _, err := ph.Parse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]protocol.SelectionRange, 0, len(positions))
+	for _, pos := range positions {
+		spn, err := m.PointSpan(pos)
+		if err != nil {
+			return nil, err
+		}
+		rng, err := spn.Range(m.Converter)
+		if err != nil {
+			return nil, err
+		}
+		var parent *protocol.SelectionRange
+		steps := selectionSteps(file, rng.Start)
+		for i := len(steps) - 1; i >= 0; i-- {
+			mrng, err := posToRange(ctx, view, m, steps[i].start, steps[i].end)
+			if err != nil {
+				return nil, err
+			}
+			prng, err := mrng.Range()
+			if err != nil {
+				return nil, err
+			}
+			parent = &protocol.SelectionRange{
+				Range:  prng,
+				Parent: parent,
+			}
+		}
+		if parent == nil {
+			parent = &protocol.SelectionRange{Range: protocol.Range{Start: pos, End: pos}}
+		}
+		result = append(result, *parent)
+	}
+	return result, nil
+}
+
+type selectionStep struct {
+	start, end token.Pos
+}
+
+// selectionSteps returns the ranges that enclose pos, ordered from innermost
+// to outermost. Each range strictly contains the previous one.
+func selectionSteps(file *ast.File, pos token.Pos) []selectionStep {
+	var steps []selectionStep
+	add := func(start, end token.Pos) {
+		if !start.IsValid() || !end.IsValid() || start > pos || pos > end {
+			return
+		}
+		if len(steps) > 0 {
+			last := steps[len(steps)-1]
+			if start > last.start || end < last.end || (start == last.start && end == last.end) {
+				return
+			}
+		}
+		steps = append(steps, selectionStep{start, end})
+	}
+	// The file extends to include any comments outside of its declarations,
+	// such as build tags.
+	fileStart, fileEnd := file.Pos(), file.End()
+	if len(file.Comments) > 0 {
+		if start := file.Comments[0].Pos(); start < fileStart {
+			fileStart = start
+		}
+		if end := file.Comments[len(file.Comments)-1].End(); end > fileEnd {
+			fileEnd = end
+		}
+	}
+	// Comments are not part of the path returned by PathEnclosingInterval,
+	// so handle them separately.
+	for _, cg := range file.Comments {
+		if pos < cg.Pos() || cg.End() < pos {
+			continue
+		}
+		for _, c := range cg.List {
+			if pos < c.Pos() || c.End() < pos {
+				continue
+			}
+			// Select the constraint of a build tag before the whole line.
+			if text := strings.TrimPrefix(c.Text, "//"); text != c.Text {
+				trimmed := strings.TrimLeft(text, " \t")
+				if strings.HasPrefix(trimmed, "+build ") {
+					start := c.Pos() + token.Pos(len(c.Text)-len(trimmed)+len("+build "))
+					add(start, c.End())
+				}
+			}
+			add(c.Pos(), c.End())
+		}
+		add(cg.Pos(), cg.End())
+		break
+	}
+	path, _ := astutil.PathEnclosingInterval(file, pos, pos)
+	for _, n := range path {
+		switch n := n.(type) {
+		case *ast.BasicLit:
+			// Select the contents of a string before its quotes.
+			if n.Kind == token.STRING && len(n.Value) >= 2 {
+				add(n.Pos()+1, n.End()-1)
+			}
+		case *ast.CompositeLit:
+			// Select all of the elements before the braces.
+			if len(n.Elts) > 0 {
+				add(n.Elts[0].Pos(), n.Elts[len(n.Elts)-1].End())
+			}
+			add(n.Lbrace, n.Rbrace+1)
+		case *ast.BlockStmt:
+			// Select the statement list before the braces.
+			if len(n.List) > 0 {
+				add(n.List[0].Pos(), n.List[len(n.List)-1].End())
+			}
+		case *ast.CaseClause:
+			if len(n.Body) > 0 {
+				add(n.Body[0].Pos(), n.Body[len(n.Body)-1].End())
+			}
+		case *ast.CommClause:
+			if len(n.Body) > 0 {
+				add(n.Body[0].Pos(), n.Body[len(n.Body)-1].End())
+			}
+		case *ast.File:
+			add(fileStart, fileEnd)
+			continue
+		}
+		add(n.Pos(), n.End())
+	}
+	return steps
+}
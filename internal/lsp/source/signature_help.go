@@ -88,7 +88,7 @@ FindCall:
 	// There is no object in certain cases such as calling a function returned by
 	// a function (e.g. "foo()()").
 	var obj types.Object
-	switch t := callExpr.Fun.(type) {
+	switch t := genericFunc(pkg.GetTypesInfo(), callExpr.Fun).(type) {
 	case *ast.Ident:
 		obj = pkg.GetTypesInfo().ObjectOf(t)
 	case *ast.SelectorExpr:
@@ -138,7 +138,7 @@ FindCall:
 		if err != nil {
 			return nil, err
 		}
-		name = obj.Name()
+		name = obj.Name() + typeArguments(pkg.GetTypesInfo(), callExpr.Fun, obj, qf)
 		comment = d.comment
 	} else {
 		name = "func"
@@ -192,23 +192,19 @@ func signatureInformation(name string, comment *ast.CommentGroup, params, result
 }
 
 func activeParameter(callExpr *ast.CallExpr, numParams int, variadic bool, pos token.Pos) int {
-	// Determine the query position relative to the number of parameters in the function.
+	// Count the arguments that end before the query position. The position
+	// is in an argument up to its end, so that the argument of a nested call
+	// stays active while the cursor is anywhere in the nested call.
 	var activeParam int
-	var start, end token.Pos
 	for _, expr := range callExpr.Args {
-		if start == token.NoPos {
-			start = expr.Pos()
-		}
-		end = expr.End()
-		if start <= pos && pos <= end {
+		if pos <= expr.End() {
 			break
 		}
-
-		// Don't advance the active parameter for the last parameter of a variadic function.
-		if !variadic || activeParam < numParams-1 {
-			activeParam++
-		}
-		start = expr.Pos() + 1 // to account for commas
+		activeParam++
+	}
+	// All the arguments of the variadic parameter are its elements.
+	if variadic && activeParam >= numParams && numParams > 0 {
+		activeParam = numParams - 1
 	}
 	return activeParam
 }
@@ -0,0 +1,22 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !go1.18
+
+package source
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// genericFunc returns fun, as there are no generic functions before Go 1.18.
+func genericFunc(info *types.Info, fun ast.Expr) ast.Expr {
+	return fun
+}
+
+// typeArguments returns "", as there are no generic functions before Go 1.18.
+func typeArguments(info *types.Info, fun ast.Expr, obj types.Object, qf types.Qualifier) string {
+	return ""
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build go1.18
+
+package source
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// genericFunc returns the expression that names the generic function that
+// fun instantiates explicitly, such as f in f[int], or fun itself.
+func genericFunc(info *types.Info, fun ast.Expr) ast.Expr {
+	var x ast.Expr
+	switch e := fun.(type) {
+	case *ast.IndexExpr:
+		x = e.X
+	case *ast.IndexListExpr:
+		x = e.X
+	default:
+		return fun
+	}
+	if obj, ok := info.ObjectOf(funcIdent(x)).(*types.Func); ok {
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.TypeParams().Len() > 0 {
+			return x
+		}
+	}
+	return fun
+}
+
+// funcIdent returns the identifier that names the function fun, if any.
+func funcIdent(fun ast.Expr) *ast.Ident {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun
+	case *ast.SelectorExpr:
+		return fun.Sel
+	}
+	return nil
+}
+
+// typeArguments formats the type parameters of the generic function obj
+// called by fun, with the type arguments known for them so far, such as
+// "[K comparable = string, V any]". The type arguments are those of the
+// instantiation recorded by the type checker, or else those given
+// explicitly in fun. It returns "" if obj is not generic.
+func typeArguments(info *types.Info, fun ast.Expr, obj types.Object, qf types.Qualifier) string {
+	if obj == nil {
+		return ""
+	}
+	sig, ok := obj.Type().(*types.Signature)
+	if !ok || sig.TypeParams().Len() == 0 {
+		return ""
+	}
+	var args []types.Type
+	if inst, ok := info.Instances[funcIdent(genericFunc(info, fun))]; ok {
+		for i := 0; i < inst.TypeArgs.Len(); i++ {
+			args = append(args, inst.TypeArgs.At(i))
+		}
+	} else {
+		var indices []ast.Expr
+		switch e := fun.(type) {
+		case *ast.IndexExpr:
+			indices = []ast.Expr{e.Index}
+		case *ast.IndexListExpr:
+			indices = e.Indices
+		}
+		for _, index := range indices {
+			args = append(args, info.TypeOf(index))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteByte('[')
+	for i := 0; i < sig.TypeParams().Len(); i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		tparam := sig.TypeParams().At(i)
+		b.WriteString(tparam.Obj().Name() + " " + types.TypeString(tparam.Constraint(), qf))
+		if i < len(args) && args[i] != nil && args[i] != types.Typ[types.Invalid] {
+			b.WriteString(" = " + types.TypeString(args[i], qf))
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
@@ -0,0 +1,42 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestActiveParameter(t *testing.T) {
+	for _, tt := range []struct {
+		src       string // the position is at the first |
+		numParams int
+		variadic  bool
+		want      int
+	}{
+		{"f(| a, b)", 2, false, 0},
+		{"f(a, |b)", 2, false, 1},
+		{"f(a, g(x, |y))", 2, false, 1},
+		{"f(a, b, |)", 3, false, 2},
+		{"f(a, b, c, |d)", 2, true, 1},
+		{"f(|)", 0, true, 0},
+	} {
+		offset := strings.Index(tt.src, "|")
+		src := tt.src[:offset] + tt.src[offset+1:]
+		fset := token.NewFileSet()
+		expr, err := parser.ParseExprFrom(fset, "", src, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.src, err)
+		}
+		call := expr.(*ast.CallExpr)
+		pos := token.Pos(fset.File(call.Pos()).Base() + offset)
+		if got := activeParameter(call, tt.numParams, tt.variadic, pos); got != tt.want {
+			t.Errorf("activeParameter(%s) = %d, want %d", tt.src, got, tt.want)
+		}
+	}
+}
@@ -303,6 +303,50 @@ func (r *runner) FoldingRange(t *testing.T, spn span.Span) {
 		return
 	}
 	r.foldingRanges(t, "foldingRange-lineFolding", uri, string(data), ranges)
+
+	// Test folding ranges with a maximum nesting depth.
+	original := r.view.Options()
+	modified := original
+	modified.FoldingRangeMaxDepth = 1
+	r.view.SetOptions(modified)
+	defer r.view.SetOptions(original)
+
+	ranges, err = source.FoldingRange(r.ctx, r.view, f, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	r.foldingRanges(t, "foldingRange-maxDepth", uri, string(data), ranges)
+}
+
+func (r *runner) SelectionRange(t *testing.T, spn span.Span) {
+	uri := spn.URI()
+	f, err := r.view.GetFile(r.ctx, uri)
+	if err != nil {
+		t.Fatalf("failed for %v: %v", spn, err)
+	}
+	m, rng, err := spanToRange(r.data, spn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ranges, err := source.SelectionRange(r.ctx, r.view, f, []protocol.Position{rng.Start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 1 {
+		t.Fatalf("got %d selection ranges, expected 1", len(ranges))
+	}
+	got, err := tests.SummarizeSelectionRange(m, &ranges[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := fmt.Sprintf("selectionRange-%d-%d", spn.Start().Line(), spn.Start().Column())
+	want := string(r.data.Golden(tag, uri.Filename(), func() ([]byte, error) {
+		return []byte(got), nil
+	}))
+	if want != got {
+		t.Errorf("%s: selection ranges do not match:\nwant:\n%s\ngot:\n%s", tag, want, got)
+	}
 }
 
 func (r *runner) foldingRanges(t *testing.T, prefix string, uri span.URI, data string, ranges []*source.FoldingRangeInfo) {
@@ -326,7 +370,7 @@ func (r *runner) foldingRanges(t *testing.T, prefix string, uri span.URI, data s
 	}
 
 	// Filter by kind.
-	kinds := []protocol.FoldingRangeKind{protocol.Imports, protocol.Comment}
+	kinds := []protocol.FoldingRangeKind{protocol.Imports, protocol.Comment, protocol.Region}
 	for _, kind := range kinds {
 		var kindOnly []*source.FoldingRangeInfo
 		for _, fRng := range ranges {
@@ -560,12 +604,21 @@ func (r *runner) Highlight(t *testing.T, name string, locations []span.Span) {
 	if len(highlights) != len(locations) {
 		t.Errorf("got %d highlights for %s, expected %d", len(highlights), name, len(locations))
 	}
-	for i, got := range highlights {
-		want, err := m.Range(locations[i])
+	// Highlights are compared in source order, since the first location is
+	// the position of the request rather than the first highlight.
+	sorted := append([]span.Span{}, locations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return span.Compare(sorted[i], sorted[j]) < 0
+	})
+	sort.Slice(highlights, func(i, j int) bool {
+		return protocol.CompareRange(highlights[i].Range, highlights[j].Range) < 0
+	})
+	for i, h := range highlights {
+		want, err := m.Range(sorted[i])
 		if err != nil {
 			t.Fatal(err)
 		}
-		if got != want {
+		if got := h.Range; got != want {
 			t.Errorf("want %v, got %v\n", want, got)
 		}
 	}
@@ -745,6 +798,26 @@ func (r *runner) Symbol(t *testing.T, uri span.URI, expectedSymbols []protocol.D
 	if err != nil {
 		t.Fatalf("failed for %v: %v", uri, err)
 	}
+	// The outline computed from the syntax alone, before the package is
+	// type-checked, is the same.
+	parsed, err := source.ParsedDocumentSymbols(ctx, r.view, f)
+	if err != nil {
+		t.Errorf("parsed symbols failed for %s: %v", uri, err)
+	}
+	if diff := r.diffSymbols(t, uri, expectedSymbols, parsed); diff != "" {
+		t.Errorf("parsed: %s", diff)
+	}
+	_, cphs, err := r.view.CheckPackageHandles(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cph, err := source.NarrowestCheckPackageHandle(cphs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cph.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
 	symbols, err := source.DocumentSymbols(ctx, r.view, f)
 	if err != nil {
 		t.Errorf("symbols failed for %s: %v", uri, err)
@@ -854,6 +927,39 @@ func (r *runner) Link(t *testing.T, uri span.URI, wantLinks []tests.Link) {
 	// This is a pure LSP feature, no source level functionality to be tested.
 }
 
+func (r *runner) CodeLens(t *testing.T, uri span.URI, want []protocol.CodeLens) {
+	f, err := r.view.GetFile(r.ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := source.CodeLens(r.ctx, r.view, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := tests.DiffCodeLens(want, got); diff != "" {
+		t.Errorf("%s: %s", uri, diff)
+	}
+}
+
+func (r *runner) PackageTests(t *testing.T, spn span.Span) {
+	uri := spn.URI()
+	f, err := r.view.GetFile(r.ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := source.PackageTests(r.ctx, r.view, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := tests.SummarizePackageTests(result)
+	want := string(r.data.Golden("packageTests", uri.Filename(), func() ([]byte, error) {
+		return []byte(got), nil
+	}))
+	if want != got {
+		t.Errorf("%s: package tests do not match:\nwant:\n%s\ngot:\n%s", uri, want, got)
+	}
+}
+
 func spanToRange(data *tests.Data, spn span.Span) (*protocol.ColumnMapper, protocol.Range, error) {
 	m, err := data.Mapper(spn.URI())
 	if err != nil {
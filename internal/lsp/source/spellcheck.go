@@ -0,0 +1,58 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+)
+
+// SpellCheckSource is the source of the diagnostics of misspellings, which
+// are reported when the SpellCheck option is set.
+const SpellCheckSource = "spellcheck"
+
+// SpellCheckFixes returns the fixes of the misspelling diagnostic diag of f.
+// The fix of a misspelled word of a comment or a string replaces it, and
+// that of a misspelled name renames the declaration and all of its uses.
+func SpellCheckFixes(ctx context.Context, snapshot Snapshot, f File, diag protocol.Diagnostic) ([]SuggestedFix, error) {
+	cphs, err := snapshot.CheckPackageHandles(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	cph, err := WidestCheckPackageHandle(cphs)
+	if err != nil {
+		return nil, err
+	}
+	srcErr, err := snapshot.FindAnalysisError(ctx, cph.ID(), diag)
+	if err != nil {
+		return nil, err
+	}
+	// The diagnostic of a misspelled name covers the name of the
+	// declaration, and its fix only edits the declaration.
+	ident, err := Identifier(ctx, snapshot.View(), f, diag.Range.Start)
+	if err != nil {
+		return srcErr.SuggestedFixes, nil
+	}
+	if rng, err := ident.mappedRange.Range(); err != nil || protocol.CompareRange(rng, diag.Range) != 0 {
+		return srcErr.SuggestedFixes, nil
+	}
+	var fixes []SuggestedFix
+	for _, fix := range srcErr.SuggestedFixes {
+		edits := fix.Edits[f.URI()]
+		if len(edits) != 1 {
+			continue
+		}
+		renames, err := ident.Rename(ctx, snapshot.View(), edits[0].NewText)
+		if err != nil {
+			return nil, err
+		}
+		fixes = append(fixes, SuggestedFix{
+			Title: fix.Title,
+			Edits: renames,
+		})
+	}
+	return fixes, nil
+}
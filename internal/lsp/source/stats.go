@@ -0,0 +1,99 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"runtime"
+	"time"
+
+	"golang.org/x/tools/internal/span"
+)
+
+// ViewStats is the resource usage attributed to a view. Work shared between
+// views through the cache is attributed to the view that first did it.
+type ViewStats struct {
+	Name   string   `json:"name"`
+	Folder span.URI `json:"folder"`
+
+	// Packages is the number of type-checked packages held by the current
+	// snapshot of the view.
+	Packages int `json:"packages"`
+
+	// SourceBytes is the size of the source of those packages. It is an
+	// estimate of the memory held on behalf of the view, as the syntax and
+	// type information of a package grow with the size of its source.
+	SourceBytes int64 `json:"sourceBytes"`
+
+	// Loads and LoadTime are the number of go/packages loads done for the
+	// view and the total time they took.
+	Loads    int64         `json:"loads"`
+	LoadTime time.Duration `json:"loadTime"`
+
+	// TypeChecks and TypeCheckTime are the number of packages type-checked
+	// for the view and the total time spent parsing and type-checking them,
+	// excluding the time spent on their dependencies.
+	TypeChecks    int64         `json:"typeChecks"`
+	TypeCheckTime time.Duration `json:"typeCheckTime"`
+
+	// Clones and CloneTime are the number of snapshots of the view created
+	// from the previous one, once per change, and the total time spent
+	// copying them. ClonedEntries is the number of map entries copied, where
+	// the files of each directory that did not change count as one.
+	Clones        int64         `json:"clones"`
+	CloneTime     time.Duration `json:"cloneTime"`
+	ClonedEntries int64         `json:"clonedEntries"`
+}
+
+// SessionStats is the resource usage of the views of a session, which
+// corresponds to a single client of the server.
+type SessionStats struct {
+	ID    string      `json:"id"`
+	Views []ViewStats `json:"views"`
+}
+
+// DaemonStats is the result of the gopls/daemonStats request.
+type DaemonStats struct {
+	// HeapAlloc is the number of bytes of heap allocated by the server
+	// process, for all sessions.
+	HeapAlloc uint64 `json:"heapAlloc"`
+
+	ParseCache ParseCacheStats `json:"parseCache"`
+
+	Sessions []SessionStats `json:"sessions"`
+}
+
+// ParseCacheStats describes the use of the parse cache, which keeps recently
+// used parsed files in memory. Hits and Misses count the requests for parsed
+// files that found them already parsed or not, and Evictions the files
+// dropped from the cache to stay within its capacity.
+type ParseCacheStats struct {
+	Capacity  int   `json:"capacity"`
+	Size      int   `json:"size"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+}
+
+// Stats returns the resource usage of the sessions of cache and their views.
+func Stats(cache Cache) *DaemonStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	result := &DaemonStats{
+		HeapAlloc:  mem.HeapAlloc,
+		ParseCache: cache.ParseCacheStats(),
+		Sessions:   []SessionStats{},
+	}
+	for _, session := range cache.Sessions() {
+		stats := SessionStats{
+			ID:    session.ID(),
+			Views: []ViewStats{},
+		}
+		for _, view := range session.Views() {
+			stats.Views = append(stats.Views, view.Stats())
+		}
+		result.Sessions = append(result.Sessions, stats)
+	}
+	return result
+}
@@ -0,0 +1,242 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/internal/lsp/analysis/tagname"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	errors "golang.org/x/xerrors"
+)
+
+// AddStructTagsTitle is the title of the code action that adds struct tags to
+// the fields of a struct.
+const AddStructTagsTitle = "Add struct tags"
+
+// tagKeys are the keys of the struct tags that are completed, in order, with
+// the options of their values.
+var tagKeys = []struct {
+	key     string
+	options []string
+}{
+	{"json", []string{"omitempty", "string"}},
+	{"yaml", []string{"omitempty", "flow", "inline"}},
+	{"xml", []string{"attr", "chardata", "innerxml", "comment", "omitempty", "any"}},
+	{"db", nil},
+}
+
+// AddStructTags returns a fix that adds the struct tags of the given keys,
+// such as json, to the exported fields of the innermost struct type at the
+// start of rng in f that do not have them, or nil if there are none. The
+// names in the tags are those of the fields in the given case.
+func AddStructTags(ctx context.Context, view View, f File, rng protocol.Range) (*SuggestedFix, error) {
+	fh := view.Snapshot().Handle(ctx, f)
+	ph := view.Session().ParseGoHandle(fh, ParseFull)
+	file, m, _, err := ph.Parse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	spn, err := m.RangeSpan(rng)
+	if err != nil {
+		return nil, err
+	}
+	r, err := spn.Range(m.Converter)
+	if err != nil {
+		return nil, err
+	}
+	tok := view.Session().Cache().FileSet().File(file.Pos())
+	if tok == nil {
+		return nil, errors.Errorf("no file for %s", f.URI())
+	}
+	opts := view.Options()
+	added := addStructTags(tok, file, r.Start, opts.StructTagKeys, opts.StructTagCase)
+	if len(added) == 0 {
+		return nil, nil
+	}
+	var edits []protocol.TextEdit
+	for _, e := range added {
+		rng, err := m.Range(span.New(m.URI, span.NewPoint(0, 0, e.start), span.NewPoint(0, 0, e.end)))
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, protocol.TextEdit{Range: rng, NewText: e.text})
+	}
+	return &SuggestedFix{
+		Title: AddStructTagsTitle,
+		Edits: map[span.URI][]protocol.TextEdit{f.URI(): edits},
+	}, nil
+}
+
+// addStructTags returns the edits of AddStructTags to the parsed file tok.
+// Embedded fields, unexported fields and fields declared with others are
+// skipped, as are the fields whose tags cannot be extended with a raw string.
+func addStructTags(tok *token.File, file *ast.File, pos token.Pos, keys []string, casing string) []offsetEdit {
+	path, _ := astutil.PathEnclosingInterval(file, pos, pos)
+	var st *ast.StructType
+	for _, n := range path {
+		if n, ok := n.(*ast.StructType); ok {
+			st = n
+			break
+		}
+	}
+	if st == nil {
+		return nil
+	}
+	var edits []offsetEdit
+	for _, field := range st.Fields.List {
+		if len(field.Names) != 1 || !field.Names[0].IsExported() {
+			continue
+		}
+		var tag string
+		if field.Tag != nil {
+			var err error
+			if tag, err = strconv.Unquote(field.Tag.Value); err != nil || strings.Contains(tag, "`") {
+				continue
+			}
+		}
+		name := tagname.Convert(field.Names[0].Name, casing)
+		var missing []string
+		for _, key := range keys {
+			if _, ok := reflect.StructTag(tag).Lookup(key); !ok {
+				missing = append(missing, key+`:"`+name+`"`)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		text := strings.Join(missing, " ")
+		if field.Tag == nil {
+			offset := tok.Offset(field.Type.End())
+			edits = append(edits, offsetEdit{start: offset, end: offset, text: " `" + text + "`"})
+			continue
+		}
+		if tag = strings.TrimSpace(tag); tag != "" {
+			text = tag + " " + text
+		}
+		edits = append(edits, offsetEdit{
+			start: tok.Offset(field.Tag.Pos()),
+			end:   tok.Offset(field.Tag.End()),
+			text:  "`" + text + "`",
+		})
+	}
+	return edits
+}
+
+// structTagCompletion returns the completions of the raw struct tag of field
+// at pos: the keys of tagKeys, with the name of the field in the given case,
+// or the name of the field and the options of the key of the value at pos.
+func structTagCompletion(field *ast.Field, pos token.Pos, casing string, fset *token.FileSet, m *protocol.ColumnMapper) ([]CompletionItem, *Selection) {
+	lit := field.Tag
+	if lit.Value[0] != '`' || pos <= lit.Pos() || pos >= lit.End() {
+		return nil, nil
+	}
+	var name string
+	if len(field.Names) == 1 {
+		name = tagname.Convert(field.Names[0].Name, casing)
+	}
+	key, value, prefix, ok := parseTagAt(lit.Value[1 : pos-lit.Pos()])
+	if !ok {
+		return nil, nil
+	}
+	tag := reflect.StructTag(strings.Trim(lit.Value, "`"))
+
+	var items []CompletionItem
+	add := func(label, insert string, kind protocol.CompletionItemKind) {
+		if strings.HasPrefix(label, prefix) {
+			items = append(items, CompletionItem{
+				Label:      label,
+				InsertText: insert,
+				Kind:       kind,
+				Score:      stdScore,
+			})
+		}
+	}
+	switch {
+	case key == "":
+		for _, k := range tagKeys {
+			if _, ok := tag.Lookup(k.key); !ok {
+				add(k.key, k.key+`:"`+name+`"`, protocol.KeywordCompletion)
+			}
+		}
+	case !strings.Contains(value, ","):
+		if name != "" {
+			add(name, name, protocol.TextCompletion)
+		}
+		add("-", "-", protocol.TextCompletion)
+	default:
+		used := strings.Split(value, ",")[1:]
+		for _, k := range tagKeys {
+			if k.key != key {
+				continue
+			}
+		options:
+			for _, opt := range k.options {
+				for _, u := range used {
+					if u == opt {
+						continue options
+					}
+				}
+				add(opt, opt, protocol.KeywordCompletion)
+			}
+		}
+	}
+	return items, &Selection{
+		content: prefix,
+		cursor:  pos,
+		mappedRange: mappedRange{
+			spanRange: span.NewRange(fset, pos-token.Pos(len(prefix)), pos),
+			m:         m,
+		},
+	}
+}
+
+// parseTagAt parses text, the start of a struct tag up to a position, in the
+// conventional key:"value" syntax. If the position is in a key, it returns the
+// start of the key as prefix. If it is in a value, it returns the key, the
+// start of the value, and the start of its last comma separated element as
+// prefix. It reports whether text is valid.
+func parseTagAt(text string) (key, value, prefix string, ok bool) {
+	for i := 0; ; {
+		for i < len(text) && text[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(text) && text[i] > ' ' && text[i] != ':' && text[i] != '"' {
+			i++
+		}
+		if i == len(text) {
+			return "", "", text[start:], true
+		}
+		key = text[start:i]
+		if i+1 >= len(text) || text[i] != ':' || text[i+1] != '"' {
+			return "", "", "", false
+		}
+		i += 2
+		start = i
+		for i < len(text) && text[i] != '"' {
+			if text[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(text) {
+			value = text[start:]
+			prefix = value
+			if j := strings.LastIndexByte(value, ','); j >= 0 {
+				prefix = value[j+1:]
+			}
+			return key, value, prefix, true
+		}
+		i++
+	}
+}
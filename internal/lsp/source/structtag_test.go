@@ -0,0 +1,101 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/internal/lsp/analysis/tagname"
+)
+
+func TestAddStructTags(t *testing.T) {
+	const src = `package a
+
+type T struct {
+	UserID int
+	Name   string ` + "`yaml:\"n\"`" + `
+	Old    string "json:\"old\""
+	Set    string ` + "`json:\"set\"`" + `
+	hidden int
+	A, B   int
+	Embedded
+}
+`
+	const want = `package a
+
+type T struct {
+	UserID int ` + "`json:\"user_id\" db:\"user_id\"`" + `
+	Name   string ` + "`yaml:\"n\" json:\"name\" db:\"name\"`" + `
+	Old    string ` + "`json:\"old\" db:\"old\"`" + `
+	Set    string ` + "`json:\"set\" db:\"set\"`" + `
+	hidden int
+	A, B   int
+	Embedded
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "a.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := fset.File(file.Pos())
+	edits := addStructTags(tok, file, tok.Pos(strings.Index(src, "Name")), []string{"json", "db"}, tagname.SnakeCase)
+	got := src
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		got = got[:e.start] + e.text + got[e.end:]
+	}
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestStructTagCompletion(t *testing.T) {
+	for _, test := range []struct {
+		tag    string // the position is at the ^, which is removed
+		want   []string
+		prefix string
+	}{
+		{tag: "^", want: []string{"json", "yaml", "xml", "db"}},
+		{tag: "y^", want: []string{"yaml"}, prefix: "y"},
+		{tag: `json:"userID" ^`, want: []string{"yaml", "xml", "db"}},
+		{tag: `json:"^"`, want: []string{"userID", "-"}},
+		{tag: `json:"userID,^"`, want: []string{"omitempty", "string"}},
+		{tag: `json:"userID,omitempty,s^"`, want: []string{"string"}, prefix: "s"},
+		{tag: `xml:"id,a^"`, want: []string{"attr", "any"}, prefix: "a"},
+		{tag: `db:"x,^"`},
+		{tag: `json^`, want: []string{"json"}, prefix: "json"},
+		{tag: `json:^`},
+	} {
+		offset := strings.Index(test.tag, "^")
+		src := "package a\n\ntype T struct {\n\tUserID int `" + test.tag[:offset] + test.tag[offset+1:] + "`\n}\n"
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "a.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tok := fset.File(file.Pos())
+		pos := tok.Pos(strings.Index(src, "`") + 1 + offset)
+		path, _ := astutil.PathEnclosingInterval(file, pos-1, pos-1)
+		field := path[1].(*ast.Field)
+		items, surrounding := structTagCompletion(field, pos, tagname.CamelCase, fset, nil)
+		var got []string
+		for _, item := range items {
+			got = append(got, item.Label)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.tag, got, test.want)
+		}
+		if surrounding != nil && surrounding.content != test.prefix {
+			t.Errorf("%s: got prefix %q, want %q", test.tag, surrounding.content, test.prefix)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	errors "golang.org/x/xerrors"
+)
+
+// The diagnostics of analyzers can be suppressed by comments in the source.
+// A comment of the form
+//
+//	//lint:ignore Check1,Check2 reason
+//
+// suppresses the given checks on the line that follows it, as it does for
+// staticcheck, and a comment of the form
+//
+//	//nolint:check1,check2
+//
+// suppresses them on its own line, as it does for golangci-lint. A //nolint
+// comment that names no checks suppresses all of them.
+const (
+	lintIgnorePrefix = "//lint:ignore "
+	nolintPrefix     = "//nolint"
+)
+
+// suppressions maps the lines of a file, counted from 0, to the checks that
+// are suppressed on them. The empty check stands for all checks.
+type suppressions map[int]map[string]bool
+
+func fileSuppressions(fset *token.FileSet, file *ast.File) suppressions {
+	result := make(suppressions)
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			line := fset.Position(c.Slash).Line - 1
+			switch {
+			case strings.HasPrefix(c.Text, lintIgnorePrefix):
+				// Like staticcheck, require a reason.
+				fields := strings.Fields(strings.TrimPrefix(c.Text, lintIgnorePrefix))
+				if len(fields) < 2 {
+					continue
+				}
+				result.add(line+1, strings.Split(fields[0], ","))
+			case strings.HasPrefix(c.Text, nolintPrefix+":"):
+				fields := strings.Fields(strings.TrimPrefix(c.Text, nolintPrefix+":"))
+				if len(fields) == 0 {
+					continue
+				}
+				result.add(line, strings.Split(fields[0], ","))
+			case c.Text == nolintPrefix || strings.HasPrefix(c.Text, nolintPrefix+" "):
+				result.add(line, []string{""})
+			}
+		}
+	}
+	return result
+}
+
+func (s suppressions) add(line int, checks []string) {
+	if s[line] == nil {
+		s[line] = make(map[string]bool)
+	}
+	for _, check := range checks {
+		s[line][check] = true
+	}
+}
+
+// suppressed reports whether the diagnostics from source are suppressed on
+// the given line.
+func (s suppressions) suppressed(line int, source string) bool {
+	checks := s[line]
+	return checks[""] || checks[diagnosticCheck(source)]
+}
+
+// diagnosticCheck returns the name of the check that reported a diagnostic
+// from source. The source of the diagnostics of an analyzer is the name of
+// the analyzer, optionally followed by a dot and a category.
+func diagnosticCheck(source string) string {
+	if i := strings.IndexByte(source, '.'); i >= 0 {
+		return source[:i]
+	}
+	return source
+}
+
+// suppressedDiagnostic reports whether the diagnostic of an analyzer for the
+// package pkg is suppressed by a comment. The suppressions of the files are
+// cached in sups.
+func suppressedDiagnostic(ctx context.Context, fset *token.FileSet, pkg Package, sups map[span.URI]suppressions, e *Error) bool {
+	s, ok := sups[e.URI]
+	if !ok {
+		if ph, err := pkg.File(e.URI); err == nil {
+			if file, _, _, err := ph.Parse(ctx); err == nil {
+				s = fileSuppressions(fset, file)
+			}
+		}
+		sups[e.URI] = s
+	}
+	return s.suppressed(int(e.Range.Start.Line), e.Category)
+}
+
+// Suppressible reports whether the diagnostics from source can be suppressed
+// by a comment. Only those of analyzers can be: errors reported by go list,
+// the parser, the type checker, or about //go:embed directives cannot.
+func Suppressible(source string) bool {
+	switch source {
+	case "", "LSP", "asm", "go:embed", GoVersionSource, GenerateSource, GeneratedSource, errorSource(ListError), errorSource(ParseError), errorSource(TypeError):
+		return false
+	}
+	return true
+}
+
+// IgnoreDiagnostics returns a fix that suppresses the diagnostics from source
+// on the given line of f. The diagnostics of staticcheck are suppressed by a
+// //lint:ignore comment inserted above the line, which staticcheck itself
+// also understands, and those of other analyzers by a //nolint comment
+// appended to the line.
+func IgnoreDiagnostics(ctx context.Context, view View, f File, line int, source string) (*SuggestedFix, error) {
+	if !Suppressible(source) {
+		return nil, errors.Errorf("diagnostics from %q cannot be ignored", source)
+	}
+	fh := view.Snapshot().Handle(ctx, f)
+	ph := view.Session().ParseGoHandle(fh, ParseFull)
+	_, m, _, err := ph.Parse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines := bytes.SplitAfter(m.Content, []byte("\n"))
+	if line < 0 || line >= len(lines) {
+		return nil, errors.Errorf("no line %d in %s", line, f.URI())
+	}
+	var offset int
+	for _, l := range lines[:line] {
+		offset += len(l)
+	}
+	text := bytes.TrimRight(lines[line], "\r\n")
+
+	check := diagnosticCheck(source)
+	var edit protocol.TextEdit
+	if isStaticcheck(check) {
+		start, err := m.Position(span.NewPoint(line+1, 1, offset))
+		if err != nil {
+			return nil, err
+		}
+		indent := text[:len(text)-len(bytes.TrimLeftFunc(text, unicode.IsSpace))]
+		edit = protocol.TextEdit{
+			Range:   protocol.Range{Start: start, End: start},
+			NewText: fmt.Sprintf("%s%s%s reason\n", indent, lintIgnorePrefix, check),
+		}
+	} else {
+		end, err := m.Position(span.NewPoint(line+1, len(text)+1, offset+len(text)))
+		if err != nil {
+			return nil, err
+		}
+		edit = protocol.TextEdit{
+			Range:   protocol.Range{Start: end, End: end},
+			NewText: fmt.Sprintf(" %s:%s", nolintPrefix, check),
+		}
+	}
+	return &SuggestedFix{
+		Title: fmt.Sprintf("Ignore this diagnostic (%s)", check),
+		Edits: map[span.URI][]protocol.TextEdit{
+			f.URI(): {edit},
+		},
+	}, nil
+}
+
+// isStaticcheck reports whether check is the name of a staticcheck analyzer,
+// such as SA4006 or ST1003.
+func isStaticcheck(check string) bool {
+	prefix := strings.TrimRightFunc(check, unicode.IsDigit)
+	switch prefix {
+	case "S", "SA", "ST", "QF":
+		return len(prefix) < len(check)
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestSuppressions(t *testing.T) {
+	const src = `package p
+
+func f() {
+	//lint:ignore SA4006,unusedresult the result is not needed
+	x := g()
+	//lint:ignore SA4006
+	y := g() //nolint:printf // the format is fine
+	z := g() //nolint
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sups := fileSuppressions(fset, file)
+	for _, test := range []struct {
+		line   int
+		source string
+		want   bool
+	}{
+		{4, "SA4006", true},
+		{4, "unusedresult", true},
+		{4, "printf", false},
+		{3, "SA4006", false},
+		// A //lint:ignore comment without a reason is ignored.
+		{6, "SA4006", false},
+		{6, "printf", true},
+		{6, "printf.format", true},
+		{7, "SA4006", true},
+		{8, "SA4006", false},
+	} {
+		if got := sups.suppressed(test.line, test.source); got != test.want {
+			t.Errorf("suppressed(%d, %q) = %v, want %v", test.line, test.source, got, test.want)
+		}
+	}
+}
+
+func TestIsStaticcheck(t *testing.T) {
+	for check, want := range map[string]bool{
+		"SA4006":       true,
+		"S1000":        true,
+		"ST1003":       true,
+		"QF1001":       true,
+		"SA":           false,
+		"printf":       false,
+		"unusedresult": false,
+	} {
+		if got := isStaticcheck(check); got != want {
+			t.Errorf("isStaticcheck(%q) = %v, want %v", check, got, want)
+		}
+	}
+}
@@ -8,16 +8,36 @@ import (
 	"context"
 	"fmt"
 	"go/ast"
+	"go/token"
 	"go/types"
+	"strings"
 
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/telemetry/trace"
+	"golang.org/x/tools/internal/xcontext"
 )
 
+// DocumentSymbols returns the outline of the Go file f. Until its package has
+// been type-checked, the outline is computed from the syntax of f alone, and
+// the package is type-checked in the background for the next requests, so
+// that the outline does not wait for the package to be loaded and checked.
 func DocumentSymbols(ctx context.Context, view View, f File) ([]protocol.DocumentSymbol, error) {
 	ctx, done := trace.StartSpan(ctx, "source.DocumentSymbols")
 	defer done()
 
+	if !typeChecked(view.Snapshot(), f) {
+		go func() {
+			ctx := xcontext.Detach(ctx)
+			_, cphs, err := view.CheckPackageHandles(ctx, f)
+			if err != nil {
+				return
+			}
+			if cph, err := NarrowestCheckPackageHandle(cphs); err == nil {
+				cph.Check(ctx)
+			}
+		}()
+		return parsedDocumentSymbols(ctx, view, f)
+	}
 	_, cphs, err := view.CheckPackageHandles(ctx, f)
 	if err != nil {
 		return nil, err
@@ -94,6 +114,193 @@ func DocumentSymbols(ctx context.Context, view View, f File) ([]protocol.Documen
 	return symbols, nil
 }
 
+// typeChecked reports whether the snapshot has type-checked a package of f.
+func typeChecked(snapshot Snapshot, f File) bool {
+	cph, err := NarrowestCheckPackageHandle(snapshot.CachedCheckPackageHandles(f))
+	if err != nil {
+		return false
+	}
+	_, err = cph.Cached()
+	return err == nil
+}
+
+// parsedDocumentSymbols returns the outline of the Go file f computed from its
+// syntax alone. It is that of DocumentSymbols, except that the details and
+// kinds of the symbols come from the types as written rather than as
+// computed, and the embedded types of interfaces are not resolved.
+func parsedDocumentSymbols(ctx context.Context, view View, f File) ([]protocol.DocumentSymbol, error) {
+	fh := view.Snapshot().Handle(ctx, f)
+	file, m, _, err := view.Session().ParseGoHandle(fh, ParseFull).Parse(ctx)
+	if file == nil {
+		return nil, err
+	}
+	symbol := func(name string, kind protocol.SymbolKind, detail string, node, selection ast.Node) protocol.DocumentSymbol {
+		s := protocol.DocumentSymbol{
+			Name:   name,
+			Kind:   kind,
+			Detail: detail,
+		}
+		if rng, err := nodeToProtocolRange(ctx, view, m, node); err == nil {
+			s.Range = rng
+		}
+		if rng, err := nodeToProtocolRange(ctx, view, m, selection); err == nil {
+			s.SelectionRange = rng
+		}
+		return s
+	}
+	specs := make(map[string]*ast.TypeSpec)
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.GenDecl); ok {
+			for _, spec := range decl.Specs {
+				if spec, ok := spec.(*ast.TypeSpec); ok {
+					specs[spec.Name.Name] = spec
+				}
+			}
+		}
+	}
+	var symbols []protocol.DocumentSymbol
+	methods := make(map[string][]protocol.DocumentSymbol)
+	typeIndex := make(map[string]int)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			var params []string
+			for _, field := range decl.Type.Params.List {
+				typ := types.ExprString(field.Type)
+				if len(field.Names) == 0 {
+					params = append(params, typ)
+				}
+				for _, name := range field.Names {
+					params = append(params, name.Name+" "+typ)
+				}
+			}
+			detail := "(" + strings.Join(params, ", ") + ")"
+			if decl.Recv == nil || len(decl.Recv.List) == 0 {
+				symbols = append(symbols, symbol(decl.Name.Name, protocol.Function, detail, decl, decl.Name))
+				break
+			}
+			recv := receiverTypeName(decl.Recv.List[0].Type)
+			methods[recv] = append(methods[recv], symbol(decl.Name.Name, protocol.Method, detail, decl, decl.Name))
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					s := symbol(spec.Name.Name, parsedTypeKind(spec.Type, specs), types.ExprString(spec.Type), spec, spec.Name)
+					switch t := spec.Type.(type) {
+					case *ast.StructType:
+						for _, field := range t.Fields.List {
+							typ := types.ExprString(field.Type)
+							if len(field.Names) == 0 {
+								s.Children = append(s.Children, symbol(embeddedName(field.Type), protocol.Field, typ, field, field.Type))
+							}
+							for _, name := range field.Names {
+								s.Children = append(s.Children, symbol(name.Name, protocol.Field, typ, field, name))
+							}
+						}
+					case *ast.InterfaceType:
+						for _, field := range t.Methods.List {
+							if len(field.Names) == 0 {
+								s.Children = append(s.Children, symbol(types.ExprString(field.Type), protocol.Interface, "", field, field.Type))
+							}
+							for _, name := range field.Names {
+								s.Children = append(s.Children, symbol(name.Name, protocol.Method, "", field, name))
+							}
+						}
+					}
+					symbols = append(symbols, s)
+					typeIndex[spec.Name.Name] = len(symbols) - 1
+				case *ast.ValueSpec:
+					kind := protocol.Variable
+					if decl.Tok == token.CONST {
+						kind = protocol.Constant
+					}
+					detail := ""
+					if spec.Type != nil {
+						detail = types.ExprString(spec.Type)
+					}
+					for _, name := range spec.Names {
+						symbols = append(symbols, symbol(name.Name, kind, detail, decl, name))
+					}
+				}
+			}
+		}
+	}
+	// Attach the methods to their receiver types, as DocumentSymbols does.
+	for recv, methods := range methods {
+		if i, ok := typeIndex[recv]; ok {
+			symbols[i].Children = append(symbols[i].Children, methods...)
+		} else {
+			symbols = append(symbols, methods...)
+		}
+	}
+	return symbols, nil
+}
+
+// parsedTypeKind returns the kind of the symbol of a type declared as expr,
+// as far as it can be told from its syntax and the type declarations of its
+// file, specs.
+func parsedTypeKind(expr ast.Expr, specs map[string]*ast.TypeSpec) protocol.SymbolKind {
+	seen := make(map[string]bool)
+	for {
+		ident, ok := expr.(*ast.Ident)
+		// Stop at cycles, which are type errors.
+		if !ok || specs[ident.Name] == nil || seen[ident.Name] {
+			break
+		}
+		seen[ident.Name] = true
+		expr = specs[ident.Name].Type
+	}
+	switch expr := expr.(type) {
+	case *ast.StructType:
+		return protocol.Struct
+	case *ast.InterfaceType:
+		return protocol.Interface
+	case *ast.FuncType:
+		return protocol.Function
+	case *ast.Ident:
+		switch expr.Name {
+		case "bool":
+			return protocol.Boolean
+		case "string":
+			return protocol.String
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+			"float32", "float64", "complex64", "complex128", "byte", "rune":
+			return protocol.Number
+		}
+		return protocol.Class
+	}
+	return protocol.Variable
+}
+
+// receiverTypeName returns the name of the type of a method receiver, such as
+// T for *T.
+func receiverTypeName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return types.ExprString(expr)
+		}
+	}
+}
+
+// embeddedName returns the name of the field of the embedded type expr, such
+// as T for *pkg.T.
+func embeddedName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if sel, ok := expr.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name
+	}
+	return types.ExprString(expr)
+}
+
 func funcSymbol(ctx context.Context, view View, m *protocol.ColumnMapper, decl *ast.FuncDecl, obj types.Object, q types.Qualifier) protocol.DocumentSymbol {
 	s := protocol.DocumentSymbol{
 		Name: obj.Name(),
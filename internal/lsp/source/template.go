@@ -0,0 +1,514 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// The fields and methods used in a template are resolved against the type of
+// the data that the template is executed with. That type is named by a
+// comment of the template such as
+//
+//	{{/* gopls:data example.com/m/p.Page */}}
+//
+// or else inferred from a call in the Go package of the template, or of one
+// of the directories above it, that executes the template by its file name:
+//
+//	t.ExecuteTemplate(w, "page.tmpl", page)
+//
+// The delimiters of the actions, {{ and }} above, are those of the
+// TemplateDelims option.
+
+// templateDataRx returns a regular expression that matches the comment that
+// names the data type of a template with the given delimiters.
+func templateDataRx(delims [2]string) *regexp.Regexp {
+	return regexp.MustCompile(regexp.QuoteMeta(delims[0]) + `-?\s*/\*\s*gopls:data\s+(\*?[^\s*]+)\s*\*/\s*-?` + regexp.QuoteMeta(delims[1]))
+}
+
+// templateField is a reference to a field or method in an action of a
+// template, such as .Title in {{.Page.Title}}.
+type templateField struct {
+	name string
+
+	// start and end are the offsets of the name, without its dot.
+	start, end int
+
+	// obj is the field or method, or nil if it is unknown. err explains why
+	// it is unknown, if the type of its receiver is known.
+	obj types.Object
+	err error
+}
+
+// templateFrame is the dot of the actions within a {{range}}, {{with}},
+// {{if}}, {{block}} or {{define}} action, and of those after it.
+type templateFrame struct {
+	dot, outer types.Type
+}
+
+// templateFields returns the references to fields and methods in the actions
+// of the template with the given content and action delimiters, resolved
+// against data, the type of the data of the template. A nil type is unknown.
+func templateFields(content []byte, delims [2]string, data types.Type) []templateField {
+	var (
+		fields []templateField
+		stack  []templateFrame
+		text   = string(content)
+		left   = delims[0]
+		right  = delims[1]
+	)
+	dot := func() types.Type {
+		if len(stack) == 0 {
+			return data
+		}
+		return stack[len(stack)-1].dot
+	}
+	for i := 0; ; {
+		start := strings.Index(text[i:], left)
+		if start < 0 {
+			break
+		}
+		start += i + len(left)
+		end := strings.Index(text[start:], right)
+		if end < 0 {
+			break
+		}
+		end += start
+		i = end + len(right)
+
+		// Skip the trim markers and the spaces around the action.
+		for start < end && (text[start] == '-' || isTemplateSpace(text[start])) {
+			start++
+		}
+		for end > start && (text[end-1] == '-' || isTemplateSpace(text[end-1])) {
+			end--
+		}
+		action := text[start:end]
+		if strings.HasPrefix(action, "/*") {
+			continue // a comment
+		}
+		keyword := action
+		if j := strings.IndexFunc(action, isTemplateSpaceRune); j >= 0 {
+			keyword = action[:j]
+		}
+		switch keyword {
+		case "end":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case "range", "with", "block":
+			outer := dot()
+			chains := templateChains(text, start+len(keyword), end)
+			fields = append(fields, resolveTemplateChains(chains, outer, data)...)
+			inner := pipelineType(chains, outer, data)
+			if keyword == "range" {
+				inner = rangeElem(inner)
+			}
+			stack = append(stack, templateFrame{dot: inner, outer: outer})
+		case "if":
+			chains := templateChains(text, start+len(keyword), end)
+			fields = append(fields, resolveTemplateChains(chains, dot(), data)...)
+			stack = append(stack, templateFrame{dot: dot(), outer: dot()})
+		case "define":
+			// The data of a template definition is that of its invocations.
+			stack = append(stack, templateFrame{})
+		case "else":
+			// The dot of an else branch is that outside of the action, or
+			// the value of its pipeline in an else with.
+			outer := dot()
+			if len(stack) > 0 {
+				outer = stack[len(stack)-1].outer
+			}
+			rest := strings.TrimLeftFunc(action[len(keyword):], isTemplateSpaceRune)
+			chains := templateChains(text, end-len(rest), end)
+			fields = append(fields, resolveTemplateChains(chains, outer, data)...)
+			inner := outer
+			if strings.HasPrefix(rest, "with") {
+				inner = pipelineType(chains, outer, data)
+			}
+			if len(stack) > 0 {
+				stack[len(stack)-1].dot = inner
+			}
+		default:
+			chains := templateChains(text, start, end)
+			fields = append(fields, resolveTemplateChains(chains, dot(), data)...)
+		}
+	}
+	return fields
+}
+
+// templateChain is a chain of fields in an action, such as $.A.B or .C.
+type templateChain struct {
+	// root is "." for the dot, "$" for the data of the template, and the
+	// name of the variable otherwise.
+	root string
+
+	// decl reports whether the chain follows the declaration of a variable,
+	// as in $x := .A.
+	decl   bool
+	fields []templateField
+}
+
+// templateChains returns the chains of fields in text[start:end], the text of
+// an action. The chains in string and character literals are skipped.
+func templateChains(text string, start, end int) []templateChain {
+	var chains []templateChain
+	decl := false
+	for i := start; i < end; {
+		switch c := text[i]; {
+		case c == '"' || c == '`' || c == '\'':
+			i++
+			for i < end && text[i] != c {
+				if c != '`' && text[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+		case c == ':' && i+1 < end && text[i+1] == '=', c == '=':
+			decl = true
+			i++
+		case c == '$' || c == '.' && (i == start || !isTemplateIdentByte(text[i-1]) && text[i-1] != ')'):
+			chain := templateChain{root: ".", decl: decl}
+			if c == '$' {
+				j := i + 1
+				for j < end && isTemplateIdentByte(text[j]) {
+					j++
+				}
+				chain.root = text[i:j]
+				i = j
+			}
+			for i < end && text[i] == '.' {
+				j := i + 1
+				for j < end && isTemplateIdentByte(text[j]) {
+					j++
+				}
+				if j == i+1 {
+					break
+				}
+				chain.fields = append(chain.fields, templateField{name: text[i+1 : j], start: i + 1, end: j})
+				i = j
+			}
+			chains = append(chains, chain)
+		default:
+			i++
+		}
+	}
+	return chains
+}
+
+// resolveTemplateChains resolves the fields of chains against the types of
+// the dot and of the data of the template.
+func resolveTemplateChains(chains []templateChain, dot, data types.Type) []templateField {
+	var result []templateField
+	for _, chain := range chains {
+		var t types.Type
+		switch chain.root {
+		case ".":
+			t = dot
+		case "$":
+			t = data
+		}
+		for _, f := range chain.fields {
+			if t != nil {
+				f.obj, t, f.err = templateLookup(t, f.name)
+			}
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// pipelineType returns the type of the value of a pipeline made of chains,
+// if it is a single chain of fields, and nil otherwise.
+func pipelineType(chains []templateChain, dot, data types.Type) types.Type {
+	var value []templateChain
+	for _, chain := range chains {
+		if chain.decl {
+			value = append(value, chain)
+		}
+	}
+	if len(value) == 0 {
+		value = chains
+	}
+	if len(value) != 1 {
+		return nil
+	}
+	fields := resolveTemplateChains(value, dot, data)
+	if len(fields) == 0 {
+		if value[0].root == "." {
+			return dot
+		}
+		if value[0].root == "$" {
+			return data
+		}
+		return nil
+	}
+	last := fields[len(fields)-1]
+	if last.obj == nil {
+		return nil
+	}
+	return templateValueType(last.obj)
+}
+
+// templateLookup returns the field or method name of a value of type t, and
+// the type of its value in a template. The field is unknown, without error,
+// if t is an interface without such a method, as the dynamic type of the
+// value may have it.
+func templateLookup(t types.Type, name string) (types.Object, types.Type, error) {
+	under := t.Underlying()
+	if ptr, ok := under.(*types.Pointer); ok {
+		under = ptr.Elem().Underlying()
+	}
+	if m, ok := under.(*types.Map); ok {
+		if b, ok := m.Key().Underlying().(*types.Basic); ok && b.Info()&types.IsString != 0 {
+			return nil, m.Elem(), nil
+		}
+	}
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name)
+	if obj == nil || !obj.Exported() {
+		if _, ok := under.(*types.Interface); ok {
+			return nil, nil, nil
+		}
+		return nil, nil, errors.Errorf("can't evaluate field %s in type %s", name, types.TypeString(t, (*types.Package).Name))
+	}
+	return obj, templateValueType(obj), nil
+}
+
+// templateValueType returns the type of the value of a field, or of the first
+// result of a method.
+func templateValueType(obj types.Object) types.Type {
+	if sig, ok := obj.Type().(*types.Signature); ok {
+		if sig.Results().Len() == 0 {
+			return nil
+		}
+		return sig.Results().At(0).Type()
+	}
+	return obj.Type()
+}
+
+// rangeElem returns the type of the elements that {{range}} iterates over in
+// a value of type t, or nil if it is unknown.
+func rangeElem(t types.Type) types.Type {
+	if t == nil {
+		return nil
+	}
+	switch t := t.Underlying().(type) {
+	case *types.Slice:
+		return t.Elem()
+	case *types.Array:
+		return t.Elem()
+	case *types.Map:
+		return t.Elem()
+	case *types.Chan:
+		return t.Elem()
+	case *types.Pointer:
+		if a, ok := t.Elem().Underlying().(*types.Array); ok {
+			return a.Elem()
+		}
+	}
+	return nil
+}
+
+func isTemplateSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func isTemplateSpaceRune(r rune) bool {
+	return r < unicode.MaxASCII && isTemplateSpace(byte(r))
+}
+
+func isTemplateIdentByte(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// templateData returns the type of the data of the template f, and the Go
+// package it was resolved in. The type is nil if it is unknown.
+func templateData(ctx context.Context, view View, f File, content []byte, delims [2]string) (types.Type, Package, error) {
+	// Find the Go package of the template's directory, or of the closest
+	// directory above it within the folder of the view.
+	folder := view.Folder().Filename()
+	var gof File
+	for dir := filepath.Dir(f.URI().Filename()); ; dir = filepath.Dir(dir) {
+		var err error
+		if gof, err = packageFileInDir(ctx, view, dir); err != nil {
+			return nil, nil, err
+		}
+		if gof != nil || dir == folder || !strings.HasPrefix(dir, folder) || filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if gof == nil {
+		return nil, nil, nil
+	}
+	_, cphs, err := view.CheckPackageHandles(ctx, gof)
+	if err != nil {
+		return nil, nil, err
+	}
+	cph, err := WidestCheckPackageHandle(cphs)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkg, err := cph.Check(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pkg.GetTypes() == nil {
+		return nil, pkg, nil
+	}
+
+	if match := templateDataRx(delims).FindSubmatch(content); match != nil {
+		t, err := lookupTemplateData(pkg.GetTypes(), string(match[1]))
+		return t, pkg, err
+	}
+	return inferTemplateData(pkg, filepath.Base(f.URI().Filename())), pkg, nil
+}
+
+// lookupTemplateData returns the type named by a gopls:data comment, such as
+// Page, *Page or example.com/m/p.Page, in pkg or one of its dependencies.
+func lookupTemplateData(pkg *types.Package, name string) (types.Type, error) {
+	pointer := strings.HasPrefix(name, "*")
+	name = strings.TrimPrefix(name, "*")
+	path := pkg.Path()
+	if dot := strings.LastIndex(name, "."); dot >= 0 && dot > strings.LastIndex(name, "/") {
+		path, name = name[:dot], name[dot+1:]
+	}
+	dep := findDependency(pkg, path)
+	if dep == nil {
+		return nil, errors.Errorf("%s is not a dependency of %s", path, pkg.Path())
+	}
+	obj, ok := dep.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, errors.Errorf("no type %s in %s", name, path)
+	}
+	if pointer {
+		return types.NewPointer(obj.Type()), nil
+	}
+	return obj.Type(), nil
+}
+
+// inferTemplateData returns the type of the data that pkg executes the
+// template with the given file name with, in a call to ExecuteTemplate, or
+// nil if there is no such call.
+func inferTemplateData(pkg Package, name string) types.Type {
+	info := pkg.GetTypesInfo()
+	var data types.Type
+	for _, file := range pkg.GetSyntax() {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || data != nil || len(call.Args) != 3 {
+				return data == nil
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "ExecuteTemplate" {
+				return true
+			}
+			tv, ok := info.Types[call.Args[1]]
+			if !ok || tv.Value == nil || tv.Value.Kind() != constant.String || constant.StringVal(tv.Value) != name {
+				return true
+			}
+			if t := info.TypeOf(call.Args[2]); t != nil && !types.IsInterface(t) {
+				data = t
+			}
+			return data == nil
+		})
+	}
+	return data
+}
+
+func templateFieldRange(m *protocol.ColumnMapper, f templateField) (protocol.Range, error) {
+	return m.Range(span.New(m.URI, span.NewPoint(0, 0, f.start), span.NewPoint(0, 0, f.end)))
+}
+
+// TemplateDiagnostics reports the fields and methods of the template f that
+// the type of its data does not have.
+func TemplateDiagnostics(ctx context.Context, view View, f File) (map[span.URI][]Diagnostic, error) {
+	ctx, done := trace.StartSpan(ctx, "source.TemplateDiagnostics")
+	defer done()
+
+	m, err := fileMapper(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	reports := map[span.URI][]Diagnostic{f.URI(): {}}
+	delims := view.Options().TemplateDelims
+	data, _, err := templateData(ctx, view, f, m.Content, delims)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return reports, nil
+	}
+	for _, field := range templateFields(m.Content, delims, data) {
+		if field.err == nil {
+			continue
+		}
+		rng, err := templateFieldRange(m, field)
+		if err != nil {
+			return nil, err
+		}
+		reports[f.URI()] = append(reports[f.URI()], Diagnostic{
+			URI:      f.URI(),
+			Range:    rng,
+			Message:  field.err.Error(),
+			Source:   "template",
+			Severity: protocol.SeverityWarning,
+		})
+	}
+	return reports, nil
+}
+
+// TemplateDefinition returns the location of the Go declaration of the field
+// or method of the template f at pos. It returns no locations, and no error,
+// if pos is not in a known field or method.
+func TemplateDefinition(ctx context.Context, view View, f File, pos protocol.Position) ([]protocol.Location, error) {
+	ctx, done := trace.StartSpan(ctx, "source.TemplateDefinition")
+	defer done()
+
+	m, err := fileMapper(ctx, view, f)
+	if err != nil {
+		return nil, err
+	}
+	spn, err := m.PointSpan(pos)
+	if err != nil {
+		return nil, err
+	}
+	offset := spn.Start().Offset()
+	delims := view.Options().TemplateDelims
+	data, pkg, err := templateData(ctx, view, f, m.Content, delims)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	for _, field := range templateFields(m.Content, delims, data) {
+		if offset < field.start || field.end < offset || field.obj == nil {
+			continue
+		}
+		declRange, err := objToMappedRange(ctx, pkg, field.obj)
+		if err != nil {
+			return nil, err
+		}
+		protocolRange, err := declRange.Range()
+		if err != nil {
+			return nil, err
+		}
+		return []protocol.Location{{
+			URI:   protocol.NewURI(declRange.URI()),
+			Range: protocolRange,
+		}}, nil
+	}
+	return nil, nil
+}
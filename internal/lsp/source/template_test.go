@@ -0,0 +1,144 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func TestTemplateFields(t *testing.T) {
+	const src = `package p
+
+type Page struct {
+	Title  string
+	Items  []Item
+	Meta   map[string]string
+	Author *Person
+	Any    interface{}
+}
+
+type Item struct{ Name string }
+
+type Person struct{ Name string }
+
+func (p *Person) Greeting() string { return "hello " + p.Name }
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := (&types.Config{}).Check("example.com/p", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const tmpl = `{{- /* gopls:data Page */ -}}
+<h1>{{.Title}}</h1>{{.Missing}}
+{{range $i, $item := .Items}}{{.Name}}{{.Title}}{{$.Title}}{{$item.Name}}{{else}}{{.Title}}{{end}}
+{{with .Author}}{{.Greeting | printf "%s"}}{{.Age}}{{end}}
+{{if eq .Title "x.Y"}}{{.Meta.anything}}{{.Any.Whatever}}{{end}}
+{{define "x"}}{{.Unknown}}{{end}}
+`
+	delims := [2]string{"{{", "}}"}
+	match := templateDataRx(delims).FindStringSubmatch(tmpl)
+	if match == nil {
+		t.Fatal("no data comment")
+	}
+	data, err := lookupTemplateData(pkg, match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range templateFields([]byte(tmpl), delims, data) {
+		if tmpl[f.start:f.end] != f.name {
+			t.Errorf("got text %q for field %s", tmpl[f.start:f.end], f.name)
+		}
+		switch {
+		case f.err != nil:
+			got = append(got, f.name+": "+f.err.Error())
+		case f.obj != nil:
+			got = append(got, f.name+": ok")
+		default:
+			got = append(got, f.name+": unknown")
+		}
+	}
+	want := []string{
+		"Title: ok",
+		"Missing: can't evaluate field Missing in type p.Page",
+		"Items: ok",
+		"Name: ok",
+		"Title: can't evaluate field Title in type p.Item",
+		"Title: ok",
+		"Name: unknown",
+		"Title: ok",
+		"Author: ok",
+		"Greeting: ok",
+		"Age: can't evaluate field Age in type *p.Person",
+		"Title: ok",
+		"Meta: ok",
+		"anything: unknown",
+		"Any: ok",
+		"Whatever: unknown",
+		"Unknown: unknown",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got fields\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestTemplateFieldsDelims(t *testing.T) {
+	pkg := types.NewPackage("example.com/p", "p")
+	fields := types.NewStruct([]*types.Var{
+		types.NewField(token.NoPos, pkg, "Title", types.Typ[types.String], false),
+	}, nil)
+	pkg.Scope().Insert(types.NewTypeName(token.NoPos, pkg, "Page", fields))
+
+	const tmpl = `[[/* gopls:data Page */]]{{.Ignored}}[[ .Title ]][[- .Missing -]]`
+	delims := [2]string{"[[", "]]"}
+	match := templateDataRx(delims).FindStringSubmatch(tmpl)
+	if match == nil {
+		t.Fatal("no data comment")
+	}
+	data, err := lookupTemplateData(pkg, match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range templateFields([]byte(tmpl), delims, data) {
+		got = append(got, tmpl[f.start:f.end])
+		if (f.err != nil) != (f.name == "Missing") {
+			t.Errorf("field %s: got error %v", f.name, f.err)
+		}
+	}
+	if want := []string{"Title", "Missing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got fields %q, want %q", got, want)
+	}
+}
+
+func TestDetectTemplateExtensions(t *testing.T) {
+	var o Options
+	if res := o.set("templateExtensions", []interface{}{"html", ".txt"}); res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	for filename, want := range map[string]FileKind{
+		"a.html":  Tmpl,
+		"a.txt":   Tmpl,
+		"a.tmpl":  Tmpl,
+		"a.go":    Go,
+		"a.s":     Asm,
+		"go.mod":  Mod,
+		"a.htmlx": Go,
+	} {
+		if got := o.DetectLanguage("", filename); got != want {
+			t.Errorf("DetectLanguage(%q) = %v, want %v", filename, got, want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// TestArgs are the arguments to the "test" command, which runs the tests of
+// the package containing URI.
+type TestArgs struct {
+	// URI is the URI of a file in the package to test.
+	URI protocol.DocumentUri `json:"uri"`
+
+	// Run is a regular expression that selects the tests to run, as for
+	// "go test -run".
+	Run string `json:"run,omitempty"`
+
+	// Fuzz is the name of a fuzz target to run with "go test -fuzz".
+	Fuzz string `json:"fuzz,omitempty"`
+
+	// Count is the number of times to run each test, as for "go test -count".
+	Count int `json:"count,omitempty"`
+
+	// Race enables the race detector.
+	Race bool `json:"race,omitempty"`
+
+	// Tags are additional build tags to consider satisfied.
+	Tags []string `json:"tags,omitempty"`
+
+	// Env holds environment variables for the go command, which override
+	// those of the view.
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// goTestArgs returns the arguments to the go command for the given test
+// arguments.
+func goTestArgs(args TestArgs) []string {
+	goArgs := []string{"test"}
+	if len(args.Tags) > 0 {
+		goArgs = append(goArgs, "-tags="+strings.Join(args.Tags, ","))
+	}
+	if args.Race {
+		goArgs = append(goArgs, "-race")
+	}
+	if args.Count > 0 {
+		goArgs = append(goArgs, fmt.Sprintf("-count=%d", args.Count))
+	}
+	run := args.Run
+	if args.Fuzz != "" && run == "" {
+		// Only run the fuzz target, not the other tests in the package.
+		run = "^$"
+	}
+	if run != "" {
+		goArgs = append(goArgs, "-run="+run)
+	}
+	if args.Fuzz != "" {
+		goArgs = append(goArgs, fmt.Sprintf("-fuzz=^%s$", regexp.QuoteMeta(args.Fuzz)))
+	}
+	return append(goArgs, ".")
+}
+
+// goTestEnv returns the environment for the go command, given the
+// environment of the view.
+func goTestEnv(env []string, args TestArgs) []string {
+	env = append([]string{}, env...)
+	keys := make([]string, 0, len(args.Env))
+	for k := range args.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, fmt.Sprintf("%s=%s", k, args.Env[k]))
+	}
+	return env
+}
+
+// RunTests runs "go test" in the package directory of args.URI, writing the
+// combined output of the command to out as it is produced. It returns an error if the tests
+// could not be run or failed.
+func RunTests(ctx context.Context, view View, args TestArgs, out io.Writer) error {
+	ctx, done := trace.StartSpan(ctx, "source.RunTests")
+	defer done()
+
+	dir := filepath.Dir(span.NewURI(args.URI).Filename())
+	return runGoTest(ctx, view, dir, goTestArgs(args), args, out)
+}
+
+// runGoTest runs the go command with the given arguments in dir, using the
+// environment of view as overridden by args. If ctx is cancelled, the go
+// command and any test binaries that it started are killed.
+func runGoTest(ctx context.Context, view View, dir string, goArgs []string, args TestArgs, out io.Writer) error {
+	cmd := exec.Command("go", goArgs...)
+	// Set PWD for the same reason as invokeGo.
+	cmd.Env = append(goTestEnv(view.Config(ctx).Env, args), "PWD="+dir)
+	cmd.Dir = dir
+	cmd.Stdout = out
+	cmd.Stderr = out
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return errors.Errorf("go %v: %w", goArgs, err)
+	}
+	// Killing only the go command would leave the test binary running, and
+	// Wait would not return until it exits and closes its output.
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return errors.Errorf("go %v: %w", goArgs, err)
+		}
+		return nil
+	case <-ctx.Done():
+		if err := killProcessGroup(cmd); err != nil {
+			return errors.Errorf("killing go %v: %w", goArgs, err)
+		}
+		<-done
+		return ctx.Err()
+	}
+}
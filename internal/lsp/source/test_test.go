@@ -0,0 +1,40 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGoTestArgs(t *testing.T) {
+	for _, test := range []struct {
+		args TestArgs
+		want []string
+	}{
+		{TestArgs{}, []string{"test", "."}},
+		{TestArgs{Run: "^TestFoo$"}, []string{"test", "-run=^TestFoo$", "."}},
+		{TestArgs{Fuzz: "FuzzFoo"}, []string{"test", "-run=^$", "-fuzz=^FuzzFoo$", "."}},
+		{TestArgs{Fuzz: "FuzzFoo", Run: "FuzzFoo"}, []string{"test", "-run=FuzzFoo", "-fuzz=^FuzzFoo$", "."}},
+		{
+			TestArgs{Count: 1, Race: true, Tags: []string{"integration", "linux"}},
+			[]string{"test", "-tags=integration,linux", "-race", "-count=1", "."},
+		},
+	} {
+		if got := goTestArgs(test.args); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("goTestArgs(%+v) = %q, want %q", test.args, got, test.want)
+		}
+	}
+}
+
+func TestGoTestEnv(t *testing.T) {
+	env := []string{"GOPATH=/gopath", "GOFLAGS=-mod=vendor"}
+	args := TestArgs{Env: map[string]string{"GOFLAGS": "", "CGO_ENABLED": "0"}}
+	got := goTestEnv(env, args)
+	want := []string{"GOPATH=/gopath", "GOFLAGS=-mod=vendor", "CGO_ENABLED=0", "GOFLAGS="}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("goTestEnv(%q, %v) = %q, want %q", env, args.Env, got, want)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+)
+
+// TestFuncKind is the kind of a test function.
+type TestFuncKind string
+
+const (
+	TestKind      = TestFuncKind("test")
+	BenchmarkKind = TestFuncKind("benchmark")
+	FuzzKind      = TestFuncKind("fuzz")
+	ExampleKind   = TestFuncKind("example")
+)
+
+// PackageTestsParams are the parameters of the gopls/tests request.
+type PackageTestsParams struct {
+	// URI is the URI of a file in the package.
+	URI protocol.DocumentUri `json:"uri"`
+}
+
+// TestFunc describes a test, benchmark, fuzz target, or example declared in
+// a package, or a subtest run by one of them.
+type TestFunc struct {
+	// Name is the name of the function, or for a subtest, the name that
+	// "go test -run" matches against, relative to its parent.
+	Name string `json:"name"`
+
+	// Kind is the kind of the test function. Subtests have the kind of the
+	// function that runs them.
+	Kind TestFuncKind `json:"kind"`
+
+	// Location is the location of the function name or, for a subtest,
+	// the string literal that names it.
+	Location protocol.Location `json:"location"`
+
+	// Subtests are the subtests started by calls to t.Run or b.Run with a
+	// string literal name.
+	Subtests []TestFunc `json:"subtests,omitempty"`
+}
+
+// PackageTests returns the test functions declared in the _test.go files of
+// the packages containing f, ordered by file and then by position.
+func PackageTests(ctx context.Context, view View, f File) ([]TestFunc, error) {
+	ctx, done := trace.StartSpan(ctx, "source.PackageTests")
+	defer done()
+
+	_, cphs, err := view.CheckPackageHandles(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[span.URI]bool)
+	var fhs []FileHandle
+	for _, cph := range cphs {
+		for _, ph := range cph.Files() {
+			uri := ph.File().Identity().URI
+			if seen[uri] || !strings.HasSuffix(uri.Filename(), "_test.go") {
+				continue
+			}
+			seen[uri] = true
+			fhs = append(fhs, ph.File())
+		}
+	}
+	sort.Slice(fhs, func(i, j int) bool {
+		return fhs[i].Identity().URI < fhs[j].Identity().URI
+	})
+	var result []TestFunc
+	for _, fh := range fhs {
+		// The package handles may only have exported declarations, so
+		// parse the files in full.
+		ph := view.Session().ParseGoHandle(fh, ParseFull)
+		file, m, _, err := ph.Parse(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			kind, ok := testFuncKind(fn)
+			if !ok {
+				continue
+			}
+			tf := TestFunc{
+				Name: fn.Name.Name,
+				Kind: kind,
+			}
+			if tf.Location, err = nodeToLocation(ctx, view, m, fn.Name); err != nil {
+				return nil, err
+			}
+			if kind == TestKind || kind == BenchmarkKind {
+				param := fn.Type.Params.List[0]
+				if len(param.Names) == 1 {
+					if tf.Subtests, err = subtests(ctx, view, m, kind, param.Names[0].Name, fn.Body); err != nil {
+						return nil, err
+					}
+				}
+			}
+			result = append(result, tf)
+		}
+	}
+	return result, nil
+}
+
+// testFuncKind returns the kind of test function that fn is declared as, if
+// any.
+func testFuncKind(fn *ast.FuncDecl) (TestFuncKind, bool) {
+	switch {
+	case isTestFunc(fn, "Test", "T"):
+		return TestKind, true
+	case isTestFunc(fn, "Benchmark", "B"):
+		return BenchmarkKind, true
+	case isFuzzTarget(fn):
+		return FuzzKind, true
+	case fn.Recv == nil && isTestName(fn.Name.Name, "Example") &&
+		fn.Type.Params.NumFields() == 0 && fn.Type.Results.NumFields() == 0:
+		return ExampleKind, true
+	}
+	return "", false
+}
+
+// subtests returns the subtests started in body by calls to the Run method
+// of the parameter named param, recursing into subtests declared with
+// function literals.
+func subtests(ctx context.Context, view View, m *protocol.ColumnMapper, kind TestFuncKind, param string, body *ast.BlockStmt) ([]TestFunc, error) {
+	if body == nil || param == "_" {
+		return nil, nil
+	}
+	var (
+		result []TestFunc
+		err    error
+	)
+	ast.Inspect(body, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Run" {
+			return true
+		}
+		if x, ok := sel.X.(*ast.Ident); !ok || x.Name != param {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		name, uerr := strconv.Unquote(lit.Value)
+		if uerr != nil {
+			return true
+		}
+		tf := TestFunc{
+			// The go command replaces spaces in subtest names.
+			Name: strings.Replace(name, " ", "_", -1),
+			Kind: kind,
+		}
+		if tf.Location, err = nodeToLocation(ctx, view, m, lit); err != nil {
+			return false
+		}
+		if fn, ok := call.Args[1].(*ast.FuncLit); ok {
+			params := fn.Type.Params.List
+			if len(params) == 1 && len(params[0].Names) == 1 {
+				if tf.Subtests, err = subtests(ctx, view, m, kind, params[0].Names[0].Name, fn.Body); err != nil {
+					return false
+				}
+			}
+		}
+		result = append(result, tf)
+		return false
+	})
+	return result, err
+}
+
+func nodeToLocation(ctx context.Context, view View, m *protocol.ColumnMapper, n ast.Node) (protocol.Location, error) {
+	rng, err := nodeToProtocolRange(ctx, view, m, n)
+	if err != nil {
+		return protocol.Location{}, err
+	}
+	return protocol.Location{
+		URI:   protocol.NewURI(m.URI),
+		Range: rng,
+	}, nil
+}
@@ -1,7 +1,20 @@
 package source
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
 )
 
 func ModTidy(ctx context.Context, view View) error {
@@ -17,3 +30,174 @@ func ModTidy(ctx context.Context, view View) error {
 	}
 	return nil
 }
+
+// ApplyTidyTitle is the title of the edit of the "applytidy" command.
+const ApplyTidyTitle = "Apply go mod tidy"
+
+// TidyPendingTitle is the title of the code lens of a go.mod file while
+// gopls checks whether the module is tidy.
+const TidyPendingTitle = "checking whether go.mod and go.sum are tidy..."
+
+// TidyDiff is the difference that "go mod tidy" would make to the go.mod and
+// go.sum files of a module.
+type TidyDiff struct {
+	// Edits are the edits of the files that would change, keyed by their
+	// URIs. It is empty if the module is tidy.
+	Edits map[span.URI][]protocol.TextEdit
+}
+
+// ModTidyDiff returns the difference that "go mod tidy" would make to the
+// go.mod file with the given URI, with its unsaved edits, and to the go.sum
+// file next to it. The go command runs on copies of the files, so that they
+// are left untouched. The difference is cached in the snapshot of view until
+// either file, or the imports of the module, change.
+func ModTidyDiff(ctx context.Context, view View, gomod span.URI) (*TidyDiff, error) {
+	return modTidyDiff(ctx, view, gomod, true)
+}
+
+// modTidyDiff is ModTidyDiff, but if wait is not set it returns nil rather
+// than hurry a difference that is still being computed, once ctx is done.
+func modTidyDiff(ctx context.Context, view View, gomod span.URI, wait bool) (*TidyDiff, error) {
+	ctx, done := trace.StartSpan(ctx, "source.ModTidyDiff")
+	defer done()
+
+	if filepath.Base(gomod.Filename()) != "go.mod" {
+		return nil, errors.Errorf("%s is not a go.mod file", gomod.Filename())
+	}
+	return view.Snapshot().TidyDiff(ctx, gomod, wait, func(ctx context.Context) (*TidyDiff, error) {
+		return computeTidyDiff(ctx, view, gomod)
+	})
+}
+
+func computeTidyDiff(ctx context.Context, view View, gomod span.URI) (*TidyDiff, error) {
+	gosum := span.FileURI(filepath.Join(filepath.Dir(gomod.Filename()), "go.sum"))
+	modContent, _, err := view.Session().GetFile(gomod, Mod).Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// A module without dependencies has no go.sum file.
+	sumContent, _, err := view.Session().GetFile(gosum, Mod).Read(ctx)
+	if err != nil {
+		sumContent = nil
+	}
+
+	dir, err := ioutil.TempDir("", "gopls-tidy-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	// The go command reads and writes the go.sum file next to the file of
+	// the -modfile flag.
+	modFile, sumFile := filepath.Join(dir, "go.mod"), filepath.Join(dir, "go.sum")
+	if err := ioutil.WriteFile(modFile, modContent, 0666); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(sumFile, sumContent, 0666); err != nil {
+		return nil, err
+	}
+	if err := runGoModTidy(ctx, filepath.Dir(gomod.Filename()), view.Config(ctx).Env, modFile); err != nil {
+		return nil, err
+	}
+
+	diff := &TidyDiff{Edits: make(map[span.URI][]protocol.TextEdit)}
+	for _, f := range []struct {
+		uri    span.URI
+		before []byte
+		tidy   string
+	}{
+		{gomod, modContent, modFile},
+		{gosum, sumContent, sumFile},
+	} {
+		after, err := ioutil.ReadFile(f.tidy)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(f.before, after) {
+			continue
+		}
+		// A missing or empty file, such as a new go.sum file, is written
+		// as a whole.
+		if len(f.before) == 0 {
+			diff.Edits[f.uri] = []protocol.TextEdit{{NewText: string(after)}}
+			continue
+		}
+		m := &protocol.ColumnMapper{
+			URI:       f.uri,
+			Converter: span.NewContentConverter(f.uri.Filename(), f.before),
+			Content:   f.before,
+			Encoding:  view.Options().PositionEncoding,
+		}
+		edits, err := ToProtocolEdits(m, view.Options().ComputeEdits(f.uri, string(f.before), string(after)))
+		if err != nil {
+			return nil, err
+		}
+		diff.Edits[f.uri] = edits
+	}
+	return diff, nil
+}
+
+// runGoModTidy runs "go mod tidy" in the module in dir on the go.mod file
+// modFile rather than on its own. Unlike invokeGo, it fails if the go command
+// does, as the files would otherwise seem tidy.
+func runGoModTidy(ctx context.Context, dir string, env []string, modFile string) error {
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, "go", "mod", "tidy", "-modfile="+modFile)
+	// See invokeGo.
+	cmd.Env = append(append([]string{}, env...), "PWD="+dir)
+	cmd.Dir = dir
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.Errorf("go mod tidy: %v: %s", err, msg)
+		}
+		return errors.Errorf("go mod tidy: %v", err)
+	}
+	return nil
+}
+
+// tidyLensTimeout is the time for which the code lenses of a go.mod file
+// wait for "go mod tidy" to finish in the background. Nothing tells the
+// client to ask for the lenses again once it is done, so the lens would
+// otherwise show that it is running until the file is next edited.
+var tidyLensTimeout = 5 * time.Second
+
+// tidyLens returns the code lens on rng, the module directive of the go.mod
+// file with the given URI, that shows whether the module is tidy and, if it
+// is not, applies the edits of "go mod tidy". "go mod tidy" runs in the
+// background, and the lens shows that it is running if it is not done within
+// tidyLensTimeout.
+func tidyLens(ctx context.Context, view View, gomod span.URI, rng protocol.Range) (protocol.CodeLens, error) {
+	ctx, cancel := context.WithTimeout(ctx, tidyLensTimeout)
+	defer cancel()
+	diff, err := modTidyDiff(ctx, view, gomod, false)
+	if err != nil {
+		return protocol.CodeLens{}, err
+	}
+	if diff == nil {
+		return protocol.CodeLens{
+			Range:   rng,
+			Command: &protocol.Command{Title: TidyPendingTitle},
+		}, nil
+	}
+	if len(diff.Edits) == 0 {
+		// A command without a name only shows its title.
+		return protocol.CodeLens{
+			Range:   rng,
+			Command: &protocol.Command{Title: "go.mod and go.sum are tidy"},
+		}, nil
+	}
+	var names []string
+	for _, uri := range []span.URI{gomod, span.FileURI(filepath.Join(filepath.Dir(gomod.Filename()), "go.sum"))} {
+		if len(diff.Edits[uri]) > 0 {
+			names = append(names, filepath.Base(uri.Filename()))
+		}
+	}
+	return protocol.CodeLens{
+		Range: rng,
+		Command: &protocol.Command{
+			Title:     fmt.Sprintf("go mod tidy would change %s: apply", strings.Join(names, " and ")),
+			Command:   "applytidy",
+			Arguments: []interface{}{gomod},
+		},
+	}, nil
+}
@@ -0,0 +1,220 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// UnusedExportsSource is the source of the diagnostics of unused exports.
+const UnusedExportsSource = "unusedexports"
+
+// unusedDecl is the declaration of an exported function or type.
+type unusedDecl struct {
+	name *ast.Ident
+	kind string
+
+	// start and end delimit the text that declares it, including its
+	// documentation.
+	start, end token.Pos
+}
+
+// unusedExports reports a hint for each exported function or type of pkg
+// that is referred to neither in pkg itself nor by any other Go file in the
+// folder of the view. Only the packages in the folder are checked, and not
+// main packages, whose exports cannot be imported.
+func unusedExports(ctx context.Context, snapshot Snapshot, pkg Package, reports map[span.URI][]Diagnostic) error {
+	ctx, done := trace.StartSpan(ctx, "source.unusedExports")
+	defer done()
+
+	view := snapshot.View()
+	if pkg.GetTypes() == nil || pkg.GetTypes().Name() == "main" {
+		return nil
+	}
+	folder := view.Folder().Filename() + "/"
+	for _, ph := range pkg.Files() {
+		if !strings.HasPrefix(ph.File().Identity().URI.Filename(), folder) {
+			return nil
+		}
+	}
+	xrefs, err := snapshot.WorkspaceXrefs(ctx)
+	if err != nil {
+		return err
+	}
+	used := make(map[types.Object]bool)
+	for _, obj := range pkg.GetTypesInfo().Uses {
+		if obj.Pkg() == pkg.GetTypes() {
+			used[obj] = true
+		}
+	}
+	fset := view.Session().Cache().FileSet()
+	for _, file := range pkg.GetSyntax() {
+		if strings.HasSuffix(fset.Position(file.Pos()).Filename, "_test.go") {
+			continue
+		}
+		sups := fileSuppressions(fset, file)
+		for _, decl := range exportedDecls(file) {
+			if used[pkg.GetTypesInfo().Defs[decl.name]] {
+				continue
+			}
+			if _, ok := xrefs[Xref{PkgPath: pkg.PkgPath(), Name: decl.name.Name}]; ok {
+				continue
+			}
+			if sups.suppressed(fset.Position(decl.name.Pos()).Line-1, UnusedExportsSource) {
+				continue
+			}
+			rng, err := posToMappedRange(ctx, pkg, decl.name.Pos(), decl.name.End())
+			if err != nil {
+				return err
+			}
+			protocolRange, err := rng.Range()
+			if err != nil {
+				return err
+			}
+			fix, err := deleteDecl(ctx, view, pkg, decl)
+			if err != nil {
+				return err
+			}
+			addReport(view, reports, Diagnostic{
+				URI:            rng.URI(),
+				Range:          protocolRange,
+				Message:        fmt.Sprintf("exported %s %s is unused in the workspace", decl.kind, decl.name.Name),
+				Source:         UnusedExportsSource,
+				Severity:       protocol.SeverityHint,
+				Tags:           []protocol.DiagnosticTag{protocol.Unnecessary},
+				SuggestedFixes: []SuggestedFix{*fix},
+			})
+		}
+	}
+	return nil
+}
+
+// exportedDecls returns the declarations of the exported functions and types
+// of file.
+func exportedDecls(file *ast.File) []unusedDecl {
+	var result []unusedDecl
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil || !decl.Name.IsExported() {
+				continue
+			}
+			result = append(result, unusedDecl{
+				name:  decl.Name,
+				kind:  "function",
+				start: withDoc(decl.Doc, decl.Pos()),
+				end:   decl.End(),
+			})
+		case *ast.GenDecl:
+			if decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				spec := spec.(*ast.TypeSpec)
+				if !spec.Name.IsExported() {
+					continue
+				}
+				d := unusedDecl{
+					name:  spec.Name,
+					kind:  "type",
+					start: withDoc(spec.Doc, spec.Pos()),
+					end:   spec.End(),
+				}
+				// Delete the whole declaration of a type declared on its own.
+				if !decl.Lparen.IsValid() {
+					d.start, d.end = withDoc(decl.Doc, decl.Pos()), decl.End()
+				}
+				result = append(result, d)
+			}
+		}
+	}
+	return result
+}
+
+func withDoc(doc *ast.CommentGroup, pos token.Pos) token.Pos {
+	if doc != nil {
+		return doc.Pos()
+	}
+	return pos
+}
+
+// deleteDecl returns a fix that deletes decl, along with the line break that
+// ends it.
+func deleteDecl(ctx context.Context, view View, pkg Package, decl unusedDecl) (*SuggestedFix, error) {
+	m, err := posToMapper(ctx, pkg, decl.start)
+	if err != nil {
+		return nil, err
+	}
+	end := decl.end
+	if tok := view.Session().Cache().FileSet().File(end); tok != nil {
+		if offset := tok.Offset(end); offset < len(m.Content) && m.Content[offset] == '\n' {
+			end = tok.Pos(offset + 1)
+		}
+	}
+	rng, err := posToRange(ctx, view, m, decl.start, end)
+	if err != nil {
+		return nil, err
+	}
+	protocolRange, err := rng.Range()
+	if err != nil {
+		return nil, err
+	}
+	return &SuggestedFix{
+		Title: fmt.Sprintf("Delete declaration of %s", decl.name.Name),
+		Edits: map[span.URI][]protocol.TextEdit{
+			rng.URI(): {{Range: protocolRange}},
+		},
+	}, nil
+}
+
+// UnusedExportFixes returns the fixes of a diagnostic reported for an unused
+// export of f: the deletion of its declaration.
+func UnusedExportFixes(ctx context.Context, snapshot Snapshot, f File, diag protocol.Diagnostic) ([]SuggestedFix, error) {
+	cphs, err := snapshot.CheckPackageHandles(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	cph, err := WidestCheckPackageHandle(cphs)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := cph.Check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ph, err := pkg.File(f.URI())
+	if err != nil {
+		return nil, err
+	}
+	file, m, _, err := ph.Parse(ctx)
+	if file == nil {
+		return nil, err
+	}
+	for _, decl := range exportedDecls(file) {
+		rng, err := posToRange(ctx, snapshot.View(), m, decl.name.Pos(), decl.name.End())
+		if err != nil {
+			return nil, err
+		}
+		if protocolRange, err := rng.Range(); err != nil || protocol.CompareRange(protocolRange, diag.Range) != 0 {
+			continue
+		}
+		fix, err := deleteDecl(ctx, snapshot.View(), pkg, decl)
+		if err != nil {
+			return nil, err
+		}
+		return []SuggestedFix{*fix}, nil
+	}
+	return nil, errors.Errorf("no exported declaration at %v in %s", diag.Range, f.URI())
+}
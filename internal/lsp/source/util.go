@@ -23,6 +23,11 @@ type mappedRange struct {
 	spanRange span.Range
 	m         *protocol.ColumnMapper
 
+	// original is the span of the original file of a file generated by cgo
+	// that spanRange was copied from, if any. In that case, m maps the
+	// original file.
+	original *span.Span
+
 	// protocolRange is the result of converting the spanRange using the mapper.
 	// It is computed on-demand.
 	protocolRange *protocol.Range
@@ -30,7 +35,7 @@ type mappedRange struct {
 
 func (s mappedRange) Range() (protocol.Range, error) {
 	if s.protocolRange == nil {
-		spn, err := s.spanRange.Span()
+		spn, err := s.Span()
 		if err != nil {
 			return protocol.Range{}, err
 		}
@@ -44,6 +49,9 @@ func (s mappedRange) Range() (protocol.Range, error) {
 }
 
 func (s mappedRange) Span() (span.Span, error) {
+	if s.original != nil {
+		return *s.original, nil
+	}
 	return s.spanRange.Span()
 }
 
@@ -97,26 +105,32 @@ func IsGenerated(ctx context.Context, view View, uri span.URI) bool {
 	if err != nil {
 		return false
 	}
-	ph := view.Session().Cache().ParseGoHandle(view.Snapshot().Handle(ctx, f), ParseHeader)
+	ph := view.Session().ParseGoHandle(view.Snapshot().Handle(ctx, f), ParseHeader)
 	parsed, _, _, err := ph.Parse(ctx)
 	if err != nil {
 		return false
 	}
-	tok := view.Session().Cache().FileSet().File(parsed.Pos())
+	return generatedComment(view.Session().Cache().FileSet(), parsed) != nil
+}
+
+// generatedComment returns the comment that marks file as generated, such as
+// "// Code generated by stringer; DO NOT EDIT.", or nil if there is none.
+func generatedComment(fset *token.FileSet, file *ast.File) *ast.Comment {
+	tok := fset.File(file.Pos())
 	if tok == nil {
-		return false
+		return nil
 	}
-	for _, commentGroup := range parsed.Comments {
+	for _, commentGroup := range file.Comments {
 		for _, comment := range commentGroup.List {
 			if matched := generatedRx.MatchString(comment.Text); matched {
 				// Check if comment is at the beginning of the line in source.
 				if pos := tok.Position(comment.Slash); pos.Column == 1 {
-					return true
+					return comment
 				}
 			}
 		}
 	}
-	return false
+	return nil
 }
 
 func nodeToProtocolRange(ctx context.Context, view View, m *protocol.ColumnMapper, n ast.Node) (protocol.Range, error) {
@@ -169,14 +183,25 @@ func posToRange(ctx context.Context, view View, m *protocol.ColumnMapper, pos, e
 	if !end.IsValid() {
 		return mappedRange{}, errors.Errorf("invalid position for %v", end)
 	}
-	return mappedRange{
+	fset := view.Session().Cache().FileSet()
+	rng := mappedRange{
 		m:         m,
-		spanRange: span.NewRange(view.Session().Cache().FileSet(), pos, end),
-	}, nil
+		spanRange: span.NewRange(fset, pos, end),
+	}
+	// Report the positions of the files generated by cgo in the files that
+	// the user edits.
+	if original, ok := CgoOriginalSpan(fset, pos, end); ok {
+		if m, err := CgoOriginalMapper(ctx, view, original.URI()); err == nil {
+			rng.m, rng.original = m, &original
+		}
+	}
+	return rng, nil
 }
 
 func posToMapper(ctx context.Context, pkg Package, pos token.Pos) (*protocol.ColumnMapper, error) {
-	posn := pkg.View().Session().Cache().FileSet().Position(pos)
+	// The position is not adjusted by //line directives, so that it is in
+	// the file that was parsed.
+	posn := pkg.View().Session().Cache().FileSet().PositionFor(pos, false)
 	ph, _, err := pkg.FindFile(ctx, span.FileURI(posn.Filename))
 	if err != nil {
 		return nil, err
@@ -197,6 +222,8 @@ func DetectLanguage(langID, filename string) FileKind {
 		return Mod
 	case "go.sum":
 		return Sum
+	case "go.work":
+		return Work
 	}
 	// Fallback to detecting the language based on the file extension.
 	switch filepath.Ext(filename) {
@@ -204,17 +231,41 @@ func DetectLanguage(langID, filename string) FileKind {
 		return Mod
 	case ".sum":
 		return Sum
+	case ".work":
+		return Work
+	case ".s":
+		return Asm
+	case ".tmpl", ".gotmpl":
+		return Tmpl
 	default: // fallback to Go
 		return Go
 	}
 }
 
+// DetectLanguage is like the DetectLanguage function, but it also detects
+// the template files with the extensions of o.TemplateExtensions.
+func (o Options) DetectLanguage(langID, filename string) FileKind {
+	ext := filepath.Ext(filename)
+	for _, e := range o.TemplateExtensions {
+		if ext == e {
+			return Tmpl
+		}
+	}
+	return DetectLanguage(langID, filename)
+}
+
 func (k FileKind) String() string {
 	switch k {
 	case Mod:
 		return "go.mod"
 	case Sum:
 		return "go.sum"
+	case Asm:
+		return "asm"
+	case Tmpl:
+		return "tmpl"
+	case Work:
+		return "go.work"
 	default:
 		return "go"
 	}
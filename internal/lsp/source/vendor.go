@@ -0,0 +1,61 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"path/filepath"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	errors "golang.org/x/xerrors"
+)
+
+// VendorSource is the source of the diagnostics of modules whose vendor
+// directory is out of sync with their go.mod file.
+const VendorSource = "go mod vendor"
+
+// VendorTitle is the title of the quick fix that runs the "vendor" command.
+const VendorTitle = "Run go mod vendor"
+
+// InconsistentVendoringError is the error of the go command when the
+// vendor/modules.txt file of the module in Dir disagrees with its go.mod
+// file, which prevents packages from being loaded in -mod=vendor mode.
+type InconsistentVendoringError struct {
+	Dir string
+	Msg string
+}
+
+func (e *InconsistentVendoringError) Error() string {
+	return e.Msg
+}
+
+// vendorDiagnostics returns the diagnostic of the file with the given URI
+// when its package could not be loaded because of inconsistent vendoring.
+func vendorDiagnostics(uri span.URI, err *InconsistentVendoringError) map[span.URI][]Diagnostic {
+	return map[span.URI][]Diagnostic{
+		uri: {{
+			URI:      uri,
+			Range:    protocol.Range{},
+			Message:  err.Msg,
+			Source:   VendorSource,
+			Severity: protocol.SeverityError,
+		}},
+	}
+}
+
+// ModVendor runs "go mod vendor" in the module that contains the file with
+// the given URI, to bring its vendor directory in sync with its go.mod file.
+func ModVendor(ctx context.Context, view View, uri span.URI) error {
+	gomod := findGoMod(filepath.Dir(uri.Filename()))
+	if gomod == "" {
+		return errors.Errorf("%s is not in a module", uri.Filename())
+	}
+	// Like `go mod tidy`, `go mod vendor` modifies the files on disk directly.
+	if _, err := invokeGo(ctx, filepath.Dir(gomod), view.Config(ctx).Env, "mod", "vendor"); err != nil {
+		return err
+	}
+	return nil
+}
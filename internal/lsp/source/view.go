@@ -50,13 +50,16 @@ type FileSystem interface {
 }
 
 // FileKind describes the kind of the file in question.
-// It can be one of Go, mod, or sum.
+// It can be one of Go, mod, sum, asm, tmpl, or work.
 type FileKind int
 
 const (
 	Go = FileKind(iota)
 	Mod
 	Sum
+	Asm
+	Tmpl
+	Work
 	UnknownKind
 )
 
@@ -74,6 +77,10 @@ type ParseGoHandle interface {
 
 	// Cached returns the AST for this handle, if it has already been stored.
 	Cached() (*ast.File, *protocol.ColumnMapper, error, error)
+
+	// BuildConstraintEnd returns the end of the build constraint lines of
+	// the file, found when it was parsed, or token.NoPos if it has none.
+	BuildConstraintEnd(ctx context.Context) (token.Pos, error)
 }
 
 // ParseMode controls the content of the AST produced when parsing a source file.
@@ -134,6 +141,18 @@ type Cache interface {
 
 	// ParseGoHandle returns a ParseGoHandle for the given file handle.
 	ParseGoHandle(fh FileHandle, mode ParseMode) ParseGoHandle
+
+	// Sessions returns the sessions created by this cache that have not
+	// been shut down.
+	Sessions() []Session
+
+	// ParseCacheStats returns the use of the cache's parse cache so far.
+	ParseCacheStats() ParseCacheStats
+
+	// ModuleMetadata returns the metadata of the module version extracted
+	// in dir in the module cache, as returned by read. It calls read at
+	// most once per directory, whose content does not change.
+	ModuleMetadata(dir string, read func() *ModuleMetadata) *ModuleMetadata
 }
 
 // Session represents a single connection from a client.
@@ -141,6 +160,9 @@ type Cache interface {
 // of the client.
 // A session may have many active views at any given time.
 type Session interface {
+	// ID returns the unique identifier of this session.
+	ID() string
+
 	// NewView creates a new View and returns it.
 	NewView(ctx context.Context, name string, folder span.URI, options Options) View
 
@@ -187,6 +209,11 @@ type Session interface {
 
 	// SetOptions sets the options of this session to new values.
 	SetOptions(Options)
+
+	// ParseGoHandle returns a ParseGoHandle for the given file handle, as
+	// Cache.ParseGoHandle does, whose mappers use the position encoding
+	// negotiated with the client of the session.
+	ParseGoHandle(fh FileHandle, mode ParseMode) ParseGoHandle
 }
 
 // View represents a single workspace.
@@ -226,6 +253,26 @@ type View interface {
 	// Ignore returns true if this file should be ignored by this view.
 	Ignore(span.URI) bool
 
+	// DirectoryFilter returns the filter of the directories of the folder
+	// that are excluded from the view, computed from its options when the
+	// view is created or its options change.
+	DirectoryFilter() *DirectoryFilter
+
+	// Standalone reports whether the Go file is in neither a module, a
+	// GOPATH, nor GOROOT, and is therefore type-checked on its own, with
+	// only its imports from the standard library.
+	Standalone(span.URI) bool
+
+	// SetBuildConfiguration changes the target platform and build tags of
+	// the view, so that its packages are loaded and type-checked again. It
+	// returns the URIs of the open files whose diagnostics may have changed.
+	SetBuildConfiguration(ctx context.Context, c BuildConfiguration) []span.URI
+
+	// MatrixViews returns the views that load the files of this view under
+	// each build configuration of its build matrix, in the order of its
+	// BuildMatrix option.
+	MatrixViews() []View
+
 	// Config returns the configuration for the view.
 	Config(ctx context.Context) *packages.Config
 
@@ -251,6 +298,9 @@ type View interface {
 
 	// Snapshot returns the current snapshot for the view.
 	Snapshot() Snapshot
+
+	// Stats returns the resources used by this view so far.
+	Stats() ViewStats
 }
 
 // Snapshot represents the current state for the given view.
@@ -271,8 +321,167 @@ type Snapshot interface {
 	// CheckPackageHandles returns the CheckPackageHandles for the packages
 	// that this file belongs to.
 	CheckPackageHandles(ctx context.Context, f File) ([]CheckPackageHandle, error)
+
+	// CachedCheckPackageHandles returns the CheckPackageHandles that the
+	// snapshot already has for the packages of f, without loading their
+	// metadata. Their packages may not have been type-checked yet.
+	CachedCheckPackageHandles(f File) []CheckPackageHandle
+
+	// StoreCoverage records the test coverage of the given files, replacing
+	// any coverage previously stored for them.
+	StoreCoverage(coverage []*FileCoverage)
+
+	// Coverage returns the test coverage most recently stored for the file
+	// with the given URI, or nil if there is none.
+	Coverage(uri span.URI) *FileCoverage
+
+	// StoreAPIChanges records the incompatible changes to the API of the
+	// packages of a module, by package path, replacing those previously
+	// stored.
+	StoreAPIChanges(changes map[string][]APIChange)
+
+	// APIChanges returns the incompatible changes to the API of the package
+	// with the given path most recently stored, if any.
+	APIChanges(pkgPath string) []APIChange
+
+	// ModuleInfo returns the information of the module proxy on the module
+	// path, as returned by fetch. It calls fetch at most once per path in
+	// the snapshot, so that the proxy is not queried again until the next
+	// change.
+	ModuleInfo(ctx context.Context, path string, fetch func(context.Context) (*ModuleInfo, error)) (*ModuleInfo, error)
+
+	// ModGraph returns the module requirement graph of the go.mod file with
+	// the given URI, as returned by fetch. It calls fetch again only once
+	// the go.mod file has changed.
+	ModGraph(ctx context.Context, gomod span.URI, fetch func(context.Context) (*ModGraph, error)) (*ModGraph, error)
+
+	// TidyDiff returns the difference that "go mod tidy" would make to the
+	// go.mod file with the given URI and its go.sum file, as returned by
+	// fetch. It calls fetch in the background, at most once per version of
+	// the files and of the imports of the module, which also change it. If
+	// the difference is not computed yet, TidyDiff computes it right away
+	// and waits for it if wait is set. Otherwise it waits for the
+	// computation in the background until ctx is done, and returns nil
+	// then.
+	TidyDiff(ctx context.Context, gomod span.URI, wait bool, fetch func(context.Context) (*TidyDiff, error)) (*TidyDiff, error)
+
+	// GoSumProblems returns the go.sum problems that the snapshot found when
+	// it last loaded the packages of the files of the module of the go.mod
+	// file with the given URI, and the URIs of those files.
+	GoSumProblems(gomod span.URI) ([]GoSumProblem, []span.URI)
+
+	// WorkspaceXrefs returns the package-level objects that the Go files in
+	// the folder of the view refer to from outside of their packages, with
+	// the ways in which they use them.
+	WorkspaceXrefs(ctx context.Context) (map[Xref]XrefUse, error)
+
+	// WorkspaceLinknames returns the locations of the //go:linkname
+	// directives of the Go files in the folder of the view, by the
+	// package-level object that they refer to.
+	WorkspaceLinknames(ctx context.Context) (map[Xref][]protocol.Location, error)
+
+	// WorkspaceSymbols returns the symbols declared by the Go files in the
+	// folder of the view.
+	WorkspaceSymbols(ctx context.Context) ([]Symbol, error)
+
+	// WorkspaceMethodSets returns the method sets of the named types
+	// declared by the Go files in the folder of the view.
+	WorkspaceMethodSets(ctx context.Context) ([]MethodSet, error)
+
+	// ImportedDeclarations returns the declarations of the package that the
+	// package of the file uri imports with the given path, without
+	// type-checking them, or nil if the metadata of the snapshot does not
+	// know of it.
+	ImportedDeclarations(ctx context.Context, uri span.URI, importPath string) (*PackageDeclarations, error)
+
+	// PackageImporters returns the import path of the package of the file
+	// uri and the files that import it, or, if subpackages is set, that
+	// import the packages of the subdirectories of its directory.
+	PackageImporters(ctx context.Context, uri span.URI, subpackages bool) (string, []span.URI, error)
+
+	// MetadataGraph returns the metadata of the packages that the snapshot
+	// has loaded and of their dependencies, sorted by ID.
+	MetadataGraph() []PackageMetadata
+}
+
+// PackageMetadata is the metadata of a package, as loaded by go/packages.
+type PackageMetadata struct {
+	// ID is the ID of the package, which is its path unless it is a test
+	// variant.
+	ID      string
+	PkgPath string
+	Files   []span.URI
+
+	// Deps are the IDs of the packages that the package imports.
+	Deps []string
+}
+
+// PackageDeclarations are the locations of the package-level declarations
+// of a package, by name, along with those of the methods and fields of its
+// types, by their names qualified by that of the type, as in T.M.
+type PackageDeclarations struct {
+	Name  string
+	Decls map[string]protocol.Location
 }
 
+// Symbol is a declaration of a Go file in the folder of a view, as indexed
+// for workspace symbol requests.
+type Symbol struct {
+	Name string
+
+	// Container is the name of the package of the symbol, qualified by the
+	// name of the type of a method or field, as in pkg.T.
+	Container string
+
+	Kind     protocol.SymbolKind
+	Location protocol.Location
+}
+
+// MethodSet is the method set of a named type declared in the folder of a
+// view, as indexed syntactically for the implementations shown by hover. The
+// methods promoted from the embedded fields of a struct are not included, and
+// the interfaces that embed interfaces of other packages are not indexed.
+type MethodSet struct {
+	// Type is the name of the type qualified by the name of its package,
+	// as in pkg.T, and Dir is the directory of the package.
+	Type string
+	Dir  string
+
+	Interface bool
+
+	// Methods are the fingerprints of the methods of the type, as returned
+	// by MethodFingerprint, in order.
+	Methods []string
+
+	Location protocol.Location
+}
+
+// MethodFingerprint returns the fingerprint of a method by which method sets
+// are matched: its name and its numbers of parameters and results.
+func MethodFingerprint(name string, params, results int) string {
+	return fmt.Sprintf("%s/%d/%d", name, params, results)
+}
+
+// Xref identifies a package-level object referred to from another package.
+type Xref struct {
+	PkgPath string
+	Name    string
+}
+
+// XrefUse is a set of the ways in which an Xref is used.
+type XrefUse uint8
+
+const (
+	// XrefRead reads the object, calls it, or refers to it as a type.
+	XrefRead XrefUse = 1 << iota
+
+	// XrefWrite assigns to the object, or increments or decrements it.
+	XrefWrite
+
+	// XrefAddress takes the address of the object.
+	XrefAddress
+)
+
 // File represents a source file of any type.
 type File interface {
 	URI() span.URI
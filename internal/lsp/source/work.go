@@ -0,0 +1,122 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// CreateWorkTitle is the title of the code action that runs the "creatework"
+// command.
+const CreateWorkTitle = "Create go.work covering open modules"
+
+// minWorkMinor is the minor version of the first Go release that supports
+// go.work files.
+const minWorkMinor = 18
+
+// WorkModules returns the directories of the modules in folder, relative to
+// it, in the form of the use directives of a go.work file, such as ./a. Like
+// the go command, it skips the vendor and testdata directories, and those
+// whose names start with . or _.
+func WorkModules(folder string) ([]string, error) {
+	var dirs []string
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path == folder {
+				return nil
+			}
+			switch name := info.Name(); {
+			case name == "vendor", name == "testdata", strings.HasPrefix(name, "."), strings.HasPrefix(name, "_"):
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() != "go.mod" {
+			return nil
+		}
+		rel, err := filepath.Rel(folder, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, workDir(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// workDir returns the argument of the use directive of the module in the
+// directory rel, relative to the go.work file.
+func workDir(rel string) string {
+	if rel == "." {
+		return "."
+	}
+	return "./" + filepath.ToSlash(rel)
+}
+
+// workContent returns the content of a go.work file that uses the modules in
+// dirs, with the go version of the newest of them.
+func workContent(minor int, dirs []string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "go 1.%d\n\nuse (\n", minor)
+	for _, dir := range dirs {
+		fmt.Fprintf(&buf, "\t%s\n", dir)
+	}
+	buf.WriteString(")\n")
+	return buf.Bytes()
+}
+
+// CreateWorkFile writes a go.work file in folder that uses all of the modules
+// it contains, and returns its URI. Its go version is the newest of those of
+// the modules, and at least the first one that supports go.work files. It
+// fails if folder already has a go.work file, or if it contains no module.
+func CreateWorkFile(ctx context.Context, view View, folder string) (span.URI, error) {
+	ctx, done := trace.StartSpan(ctx, "source.CreateWorkFile")
+	defer done()
+
+	gowork := filepath.Join(folder, "go.work")
+	if _, err := os.Stat(gowork); err == nil {
+		return "", errors.Errorf("%s already exists", gowork)
+	}
+	dirs, err := WorkModules(folder)
+	if err != nil {
+		return "", err
+	}
+	if len(dirs) == 0 {
+		return "", errors.Errorf("no modules found in %s", folder)
+	}
+	minor := minWorkMinor
+	for _, dir := range dirs {
+		uri := span.FileURI(filepath.Join(folder, filepath.FromSlash(dir), "go.mod"))
+		data, _, err := view.Session().GetFile(uri, Mod).Read(ctx)
+		if err != nil {
+			return "", err
+		}
+		if d := parseGoDirective(uri, data); d != nil && d.minor > minor {
+			minor = d.minor
+		}
+	}
+	if err := ioutil.WriteFile(gowork, workContent(minor, dirs), 0666); err != nil {
+		return "", err
+	}
+	return span.FileURI(gowork), nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWorkModules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopls-work-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{
+		"go.mod",
+		"a/go.mod",
+		"b/c/go.mod",
+		"b/c/vendor/example.com/v/go.mod",
+		"testdata/go.mod",
+		".git/go.mod",
+		"_old/go.mod",
+		"d/d.go",
+	} {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := WorkModules(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".", "./a", "./b/c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WorkModules() = %q, want %q", got, want)
+	}
+
+	const content = "go 1.21\n\nuse (\n\t.\n\t./a\n\t./b/c\n)\n"
+	if got := string(workContent(21, want)); got != content {
+		t.Errorf("workContent() = %q, want %q", got, content)
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/internal/lsp/fuzzy"
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+	"golang.org/x/tools/internal/telemetry/trace"
+	errors "golang.org/x/xerrors"
+)
+
+// maxSymbols is the maximum number of workspace symbols returned for a query.
+const maxSymbols = 100
+
+// WorkspaceSymbols returns the symbols declared in the folders of views that
+// match query, best matches first, as scored by matcher, omitting those of
+// the directories excluded by the directory filters of the views. The syntax of the
+// query is that of parseSymbolQuery.
+func WorkspaceSymbols(ctx context.Context, matcher SymbolMatcher, views []View, query string) ([]protocol.SymbolInformation, error) {
+	ctx, done := trace.StartSpan(ctx, "source.WorkspaceSymbols")
+	defer done()
+
+	q, err := parseSymbolQuery(matcher, query)
+	if err != nil {
+		return nil, err
+	}
+	type scoredSymbol struct {
+		Symbol
+		score float64
+	}
+	var scored []scoredSymbol
+	for _, view := range views {
+		symbols, err := view.Snapshot().WorkspaceSymbols(ctx)
+		if err != nil {
+			return nil, err
+		}
+		folder := view.Folder().Filename()
+		filter := view.DirectoryFilter()
+		for _, sym := range symbols {
+			if !q.inScope(folder, sym) {
+				continue
+			}
+			if filter.ExcludedFile(span.NewURI(sym.Location.URI).Filename()) {
+				continue
+			}
+			if s := q.score(sym.Name); s > 0 {
+				scored = append(scored, scoredSymbol{sym, s})
+			}
+		}
+	}
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		if scored[i].Name != scored[j].Name {
+			return scored[i].Name < scored[j].Name
+		}
+		return scored[i].Container < scored[j].Container
+	})
+	if len(scored) > maxSymbols {
+		scored = scored[:maxSymbols]
+	}
+	result := make([]protocol.SymbolInformation, 0, len(scored))
+	for _, sym := range scored {
+		result = append(result, protocol.SymbolInformation{
+			Name:          sym.Name,
+			Kind:          sym.Kind,
+			Location:      sym.Location,
+			ContainerName: sym.Container,
+		})
+	}
+	return result, nil
+}
+
+// symbolQuery is a parsed workspace symbol query.
+type symbolQuery struct {
+	// terms score a symbol name against each term of the query. A name
+	// matches the query if it matches all of them.
+	terms []func(name string) float64
+
+	// pkg, if set, matches the name or the directory of the package of the
+	// symbols in scope.
+	pkg *regexp.Regexp
+
+	// noTests excludes the symbols of test files.
+	noTests bool
+}
+
+// parseSymbolQuery parses a workspace symbol query: its terms, separated by
+// spaces, are matched to the symbol names with matcher, except for:
+//
+//	'text       names that contain text
+//	^text       names that start with text
+//	pkg:regexp  symbols of the packages whose name or directory, relative
+//	            to the folder, matches regexp
+//	-test       symbols of the files that are not test files
+func parseSymbolQuery(matcher SymbolMatcher, query string) (*symbolQuery, error) {
+	q := &symbolQuery{}
+	for _, term := range strings.Fields(query) {
+		switch {
+		case term == "-test":
+			q.noTests = true
+		case strings.HasPrefix(term, "pkg:"):
+			rx, err := regexp.Compile(strings.TrimPrefix(term, "pkg:"))
+			if err != nil {
+				return nil, errors.Errorf("invalid package pattern in %q: %v", term, err)
+			}
+			q.pkg = rx
+		case len(term) > 1 && term[0] == '\'':
+			text := term[1:]
+			q.terms = append(q.terms, func(name string) float64 {
+				return substringScore(name, text)
+			})
+		case len(term) > 1 && term[0] == '^':
+			text := term[1:]
+			q.terms = append(q.terms, func(name string) float64 {
+				if !strings.HasPrefix(name, text) {
+					return 0
+				}
+				return substringScore(name, text)
+			})
+		default:
+			q.terms = append(q.terms, symbolScorer(matcher, term))
+		}
+	}
+	return q, nil
+}
+
+// inScope reports whether sym, declared in the given folder, is in the scope
+// of the query.
+func (q *symbolQuery) inScope(folder string, sym Symbol) bool {
+	filename := span.NewURI(sym.Location.URI).Filename()
+	if q.noTests && strings.HasSuffix(filename, "_test.go") {
+		return false
+	}
+	if q.pkg == nil {
+		return true
+	}
+	pkg := sym.Container
+	if i := strings.IndexByte(pkg, '.'); i >= 0 {
+		pkg = pkg[:i]
+	}
+	if q.pkg.MatchString(pkg) {
+		return true
+	}
+	dir, err := filepath.Rel(folder, filepath.Dir(filename))
+	return err == nil && q.pkg.MatchString(filepath.ToSlash(dir))
+}
+
+// score returns the sum of the scores of name for the terms of the query, or
+// 0 if it does not match one of them. All names match a query without terms.
+func (q *symbolQuery) score(name string) float64 {
+	if len(q.terms) == 0 {
+		return 1
+	}
+	var total float64
+	for _, term := range q.terms {
+		s := term(name)
+		if s <= 0 {
+			return 0
+		}
+		total += s
+	}
+	return total
+}
+
+// symbolScorer returns a function that scores a symbol name against query,
+// returning a positive score for the names that match it.
+func symbolScorer(matcher SymbolMatcher, query string) func(name string) float64 {
+	lowerQuery := strings.ToLower(query)
+	switch matcher {
+	case CaseSensitiveSymbols:
+		return func(name string) float64 {
+			return substringScore(name, query)
+		}
+	case CaseInsensitiveSymbols:
+		return func(name string) float64 {
+			return substringScore(strings.ToLower(name), lowerQuery)
+		}
+	}
+	m := fuzzy.NewMatcher(query)
+	if matcher == FuzzySymbols {
+		return func(name string) float64 {
+			return float64(m.Score(name))
+		}
+	}
+	// The hybrid matcher adds to the fuzzy score a boost for the names whose
+	// camelCase humps start the parts of the query, and boosts for the names
+	// that contain the query, more so at their start and with the same case,
+	// so that "WSym" finds WorkspaceSymbols and "sym" ranks Symbol above
+	// WorkspaceSymbols.
+	return func(name string) float64 {
+		if name == query {
+			return 10
+		}
+		score := float64(m.Score(name))
+		if score < 0 {
+			score = 0
+		}
+		if camelCaseMatch(query, name) {
+			score += 1
+		}
+		if s := substringScore(strings.ToLower(name), lowerQuery); s > 0 {
+			score += 2 * s
+			if strings.Contains(name, query) {
+				score += 0.5
+			}
+		}
+		return score
+	}
+}
+
+// substringScore scores the names that contain query: 1 if it is the whole
+// name, 0.75 if it starts the name, 0.5 elsewhere, and 0 if it does not
+// contain it.
+func substringScore(name, query string) float64 {
+	switch i := strings.Index(name, query); {
+	case i < 0:
+		return 0
+	case len(name) == len(query):
+		return 1
+	case i == 0:
+		return 0.75
+	default:
+		return 0.5
+	}
+}
+
+// camelCaseMatch reports whether query can be split into parts that each
+// start one of the camelCase humps of name, in order, ignoring case, as "WSym"
+// does for the humps "Workspace" and "Symbols" of WorkspaceSymbols.
+func camelCaseMatch(query, name string) bool {
+	q := []rune(query)
+	if len(q) == 0 {
+		return false
+	}
+	humps := camelCaseHumps(name)
+	// match[i][j] records whether q[i:] matches humps[j:], once known.
+	const unknown, yes, no = 0, 1, 2
+	match := make([][]int, len(q))
+	for i := range match {
+		match[i] = make([]int, len(humps)+1)
+	}
+	var matches func(i, j int) bool
+	matches = func(i, j int) bool {
+		if i == len(q) {
+			return true
+		}
+		if match[i][j] != unknown {
+			return match[i][j] == yes
+		}
+		match[i][j] = no
+		for k := j; k < len(humps); k++ {
+			hump := humps[k]
+			for n := 0; n < len(hump) && i+n < len(q) && unicode.ToLower(hump[n]) == unicode.ToLower(q[i+n]); n++ {
+				if matches(i+n+1, k+1) {
+					match[i][j] = yes
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return matches(0, 0)
+}
+
+// camelCaseHumps splits name into its camelCase humps, such as "HTTP",
+// "Server" and "2" for HTTPServer2. Underscores separate humps.
+func camelCaseHumps(name string) [][]rune {
+	var humps [][]rune
+	runes := []rune(name)
+	start := 0
+	for i, r := range runes {
+		if r == '_' {
+			if start < i {
+				humps = append(humps, runes[start:i])
+			}
+			start = i + 1
+			continue
+		}
+		if i == start {
+			continue
+		}
+		prev := runes[i-1]
+		if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)) ||
+			unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(prev) ||
+			unicode.IsDigit(r) != unicode.IsDigit(prev) {
+			humps = append(humps, runes[start:i])
+			start = i
+		}
+	}
+	if start < len(runes) {
+		humps = append(humps, runes[start:])
+	}
+	return humps
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package source
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/span"
+)
+
+func TestCamelCaseMatch(t *testing.T) {
+	for _, test := range []struct {
+		query, name string
+		want        bool
+	}{
+		{"WSym", "WorkspaceSymbols", true},
+		{"wsym", "WorkspaceSymbols", true},
+		{"ws", "WorkspaceSymbols", true},
+		{"sym", "WorkspaceSymbols", true},
+		{"HTTPS", "HTTPServer", true},
+		{"hs2", "HTTPServer2", true},
+		{"nc", "new_conn", true},
+		{"ws", "Workspace", false},
+		{"SW", "WorkspaceSymbols", false},
+		{"", "WorkspaceSymbols", false},
+	} {
+		if got := camelCaseMatch(test.query, test.name); got != test.want {
+			t.Errorf("camelCaseMatch(%q, %q) = %v, want %v", test.query, test.name, got, test.want)
+		}
+	}
+}
+
+func TestSymbolScorer(t *testing.T) {
+	names := []string{"WorkspaceSymbols", "Symbol", "symbolScore", "MySymbol", "SymbolKind", "Unrelated", "SYM"}
+	for _, test := range []struct {
+		matcher SymbolMatcher
+		query   string
+		want    []string
+	}{
+		// Exact matches first, then prefixes, then substrings, with the
+		// same case first.
+		{HybridSymbols, "Symbol", []string{"Symbol", "SymbolKind", "symbolScore", "MySymbol", "WorkspaceSymbols"}},
+		{HybridSymbols, "WSym", []string{"WorkspaceSymbols"}},
+		{CaseInsensitiveSymbols, "sym", []string{"SYM", "Symbol", "SymbolKind", "symbolScore", "MySymbol", "WorkspaceSymbols"}},
+		{CaseSensitiveSymbols, "Symbol", []string{"Symbol", "SymbolKind", "MySymbol", "WorkspaceSymbols"}},
+	} {
+		score := symbolScorer(test.matcher, test.query)
+		var got []string
+		for _, name := range names {
+			if score(name) > 0 {
+				got = append(got, name)
+			}
+		}
+		sort.SliceStable(got, func(i, j int) bool {
+			si, sj := score(got[i]), score(got[j])
+			if si != sj {
+				return si > sj
+			}
+			return got[i] < got[j]
+		})
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("matcher %v, query %q: got %q, want %q", test.matcher, test.query, got, test.want)
+		}
+	}
+}
+
+func TestSymbolQuery(t *testing.T) {
+	const folder = "/ws"
+	symbol := func(name, container, filename string) Symbol {
+		return Symbol{
+			Name:      name,
+			Container: container,
+			Location:  protocol.Location{URI: protocol.NewURI(span.FileURI(folder + filename))},
+		}
+	}
+	symbols := []Symbol{
+		symbol("Server", "lsp", "/lsp/server.go"),
+		symbol("NewServer", "lsp", "/lsp/server.go"),
+		symbol("ServerTest", "lsp", "/lsp/server_test.go"),
+		symbol("Server", "cache", "/internal/cache/cache.go"),
+		symbol("serve", "cache.T", "/internal/cache/cache.go"),
+	}
+	for _, test := range []struct {
+		query string
+		want  []string
+	}{
+		{"Server", []string{"cache.Server", "lsp.NewServer", "lsp.Server", "lsp.ServerTest"}},
+		{"'Server", []string{"cache.Server", "lsp.NewServer", "lsp.Server", "lsp.ServerTest"}},
+		{"^Serve", []string{"cache.Server", "lsp.Server", "lsp.ServerTest"}},
+		{"^Serve -test", []string{"cache.Server", "lsp.Server"}},
+		{"Server pkg:^lsp$", []string{"lsp.NewServer", "lsp.Server", "lsp.ServerTest"}},
+		{"serve pkg:internal/", []string{"cache.Server", "cache.T.serve"}},
+		{"'Serv ^New", []string{"lsp.NewServer"}},
+		{"pkg:cache", []string{"cache.Server", "cache.T.serve"}},
+	} {
+		q, err := parseSymbolQuery(CaseInsensitiveSymbols, test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, sym := range symbols {
+			if q.inScope(folder, sym) && q.score(sym.Name) > 0 {
+				got = append(got, sym.Container+"."+sym.Name)
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("query %q: got %q, want %q", test.query, got, test.want)
+		}
+	}
+	if _, err := parseSymbolQuery(HybridSymbols, "pkg:("); err == nil {
+		t.Error("got no error for an invalid package pattern")
+	}
+}
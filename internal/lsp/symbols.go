@@ -23,5 +23,22 @@ func (s *Server) documentSymbol(ctx context.Context, params *protocol.DocumentSy
 	if err != nil {
 		return nil, err
 	}
+	switch view.Options().DetectLanguage("", uri.Filename()) {
+	case source.Asm:
+		return source.AsmSymbols(ctx, view, f)
+	case source.Mod, source.Work:
+		return source.ModSymbols(ctx, view, f)
+	}
 	return source.DocumentSymbols(ctx, view, f)
 }
+
+func (s *Server) symbol(ctx context.Context, params *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
+	ctx, done := trace.StartSpan(ctx, "lsp.Server.symbol")
+	defer done()
+
+	symbols, err := source.WorkspaceSymbols(ctx, s.session.Options().SymbolMatcher, s.session.Views(), params.Query)
+	if err != nil {
+		return nil, err
+	}
+	return append(symbols, s.externalSymbols(ctx, params)...), nil
+}
@@ -23,6 +23,8 @@ const (
 	URI           = tag.Key("URI")
 	Package       = tag.Key("package")
 	PackagePath   = tag.Key("package_path")
+	ParseMode     = tag.Key("parse_mode")
+	FileCount     = tag.Key("file_count")
 )
 
 var (
@@ -31,6 +33,10 @@ var (
 	ReceivedBytes = stats.Int64("received_bytes", "Bytes received.", unit.Bytes)
 	SentBytes     = stats.Int64("sent_bytes", "Bytes sent.", unit.Bytes)
 	Latency       = stats.Float64("latency_ms", "Elapsed time in milliseconds", unit.Milliseconds)
+
+	ParseCacheHits      = stats.Int64("parse_cache_hits", "Count of parsed files found in the parse cache.", unit.Dimensionless)
+	ParseCacheMisses    = stats.Int64("parse_cache_misses", "Count of files parsed because they were not in the parse cache.", unit.Dimensionless)
+	ParseCacheEvictions = stats.Int64("parse_cache_evictions", "Count of parsed files evicted from the parse cache.", unit.Dimensionless)
 )
 
 const (
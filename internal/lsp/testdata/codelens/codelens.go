@@ -0,0 +1,5 @@
+package codelens
+
+func Parse(s string) string {
+	return s
+}
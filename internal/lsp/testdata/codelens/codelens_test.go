@@ -0,0 +1,15 @@
+package codelens
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	Parse("")
+}
+
+func FuzzParse(f *testing.F) { //@codelens("FuzzParse", "run fuzz test", "test")
+	f.Fuzz(func(t *testing.T, s string) {
+		Parse(s)
+	})
+}
+
+func Fuzzy(f *testing.F) {}
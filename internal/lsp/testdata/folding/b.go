@@ -0,0 +1,26 @@
+//go:build linux && amd64
+
+package folding //@fold("package")
+
+const (
+	a = 1
+	b = 2
+)
+
+var (
+	c = "c"
+	d = "d"
+)
+
+type point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+func baz() {
+	if true {
+		for {
+			break
+		}
+	}
+}
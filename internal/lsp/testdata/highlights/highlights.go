@@ -13,3 +13,32 @@ func Print() {
 func (x *F) Inc() { //@highlight("x", "x")
 	x.bar++ //@highlight("x", "x")
 }
+
+func testReturn(s string) (int, error) { //@highlight("return", "func"),highlight("return", "int"),highlight("return", "error")
+	if s == "" {
+		return 0, nil //@highlight("return", "return")
+	}
+	f := func() int {
+		return 1
+	}
+	return f(), nil //@highlight("return", "return")
+}
+
+func testBranch(xs []int) {
+	for _, x := range xs { //@highlight("range", "for")
+		switch { //@highlight("switch", "switch")
+		case x == 0:
+			continue //@highlight("range", "continue")
+		case x == 1:
+			break //@highlight("switch", "break")
+		}
+		if x == 2 {
+			break //@highlight("range", "break")
+		}
+	}
+}
+
+func testGoto() {
+	goto End //@highlight("goto", "goto")
+End: //@highlight("goto", "End")
+}
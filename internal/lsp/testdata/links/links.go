@@ -11,6 +11,12 @@ var (
 	_ foo.StructFoo
 )
 
+//go:generate go run golang.org/x/tools/cmd/stringer@v0.1.0 -type=Kind //@link("golang.org/x/tools/cmd/stringer@v0.1.0","https://godoc.org/golang.org/x/tools/cmd/stringer")
+
+type Message struct {
+	Name string `protobuf:"bytes,1,opt,name=name" proto:"go_package=github.com/example/pb"` //@link("github.com/example/pb","https://godoc.org/github.com/example/pb")
+}
+
 // Foo function
 func Foo() string {
 	/*https://example.com/comment */ //@link("https://example.com/comment","https://example.com/comment")
@@ -0,0 +1,5 @@
+package packagetests
+
+func Add(x, y int) int {
+	return x + y
+}
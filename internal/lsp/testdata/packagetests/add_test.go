@@ -0,0 +1,42 @@
+package packagetests //@packagetests("package")
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	t.Run("positive", func(t *testing.T) {
+		t.Run("small numbers", func(t *testing.T) {
+			Add(1, 2)
+		})
+	})
+	t.Run("negative", func(u *testing.T) {
+		Add(-1, -2)
+	})
+	name := "dynamic"
+	t.Run(name, func(t *testing.T) {})
+}
+
+func BenchmarkAdd(b *testing.B) {
+	b.Run("loop", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Add(i, i)
+		}
+	})
+}
+
+func FuzzAdd(f *testing.F) {
+	f.Fuzz(func(t *testing.T, x, y int) {
+		Add(x, y)
+	})
+}
+
+func ExampleAdd() {
+	fmt.Println(Add(1, 2))
+	// Output: 3
+}
+
+func Testable(t *testing.T) {}
+
+func TestHelper(t *testing.T) int { return 0 }
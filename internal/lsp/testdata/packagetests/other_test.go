@@ -0,0 +1,9 @@
+package packagetests
+
+import "testing"
+
+func TestOther(t *testing.T) {}
+
+func helper(t *testing.T) {
+	t.Run("not a test", func(t *testing.T) {})
+}
@@ -0,0 +1,27 @@
+package labels
+
+func _() {
+Outer: //@rename("Outer", "Loop")
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			switch {
+			case i == j:
+				continue Outer
+			case j > 5:
+				break Outer
+			}
+		}
+	}
+	goto Outer //@rename("Outer", "Next")
+}
+
+func _() {
+Outer:
+	for {
+		break Outer
+	}
+Inner: //@rename("Inner", "Outer")
+	for {
+		break Inner //@rename("Inner", "Last")
+	}
+}
@@ -0,0 +1,19 @@
+// +build linux darwin //@selection("darwin")
+
+package selectionrange
+
+type point struct {
+	x, y int
+}
+
+func f(s string) []point {
+	msg := "hello, world" //@selection("world")
+	pts := []point{
+		{x: 1, y: 2},
+		{x: 3, y: 4}, //@selection("3")
+	}
+	if s == msg {
+		return pts //@selection("pts")
+	}
+	return nil
+}
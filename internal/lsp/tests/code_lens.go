@@ -0,0 +1,43 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tests
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+)
+
+// DiffCodeLens returns a description of the differences between the wanted
+// and actual code lenses, comparing only their ranges, titles, and commands.
+// It returns the empty string if they match.
+func DiffCodeLens(want, got []protocol.CodeLens) string {
+	sortCodeLens(want)
+	sortCodeLens(got)
+	if len(got) != len(want) {
+		return fmt.Sprintf("got %d code lenses, expected %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if w.Range != g.Range {
+			return fmt.Sprintf("code lens %d: got range %v, expected %v", i, g.Range, w.Range)
+		}
+		if g.Command == nil {
+			return fmt.Sprintf("code lens %d: missing command", i)
+		}
+		if w.Command.Title != g.Command.Title || w.Command.Command != g.Command.Command {
+			return fmt.Sprintf("code lens %d: got command %q (%s), expected %q (%s)", i,
+				g.Command.Title, g.Command.Command, w.Command.Title, w.Command.Command)
+		}
+	}
+	return ""
+}
+
+func sortCodeLens(lenses []protocol.CodeLens) {
+	sort.Slice(lenses, func(i, j int) bool {
+		return protocol.CompareRange(lenses[i].Range, lenses[j].Range) < 0
+	})
+}
@@ -0,0 +1,33 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/span"
+)
+
+// SummarizePackageTests returns a description of the given test functions,
+// one per line, with subtests indented beneath the test that runs them.
+func SummarizePackageTests(tests []source.TestFunc) string {
+	var buf bytes.Buffer
+	summarizePackageTests(&buf, tests, 0)
+	return buf.String()
+}
+
+func summarizePackageTests(buf *bytes.Buffer, tests []source.TestFunc, depth int) {
+	for _, tf := range tests {
+		rng := tf.Location.Range
+		filename := filepath.Base(span.NewURI(tf.Location.URI).Filename())
+		fmt.Fprintf(buf, "%s%s %s %s:%v:%v-%v:%v\n", strings.Repeat("\t", depth), tf.Kind, tf.Name,
+			filename, rng.Start.Line+1, rng.Start.Character+1, rng.End.Line+1, rng.End.Character+1)
+		summarizePackageTests(buf, tf.Subtests, depth+1)
+	}
+}
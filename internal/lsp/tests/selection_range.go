@@ -0,0 +1,34 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/internal/lsp/protocol"
+)
+
+// SummarizeSelectionRange returns a description of each step of the given
+// selection range, from innermost to outermost. Single-line steps include
+// the selected text.
+func SummarizeSelectionRange(m *protocol.ColumnMapper, sr *protocol.SelectionRange) (string, error) {
+	var buf bytes.Buffer
+	for ; sr != nil; sr = sr.Parent {
+		rng := sr.Range
+		fmt.Fprintf(&buf, "%v:%v-%v:%v", rng.Start.Line+1, rng.Start.Character+1, rng.End.Line+1, rng.End.Character+1)
+		if rng.Start.Line == rng.End.Line {
+			spn, err := m.RangeSpan(rng)
+			if err != nil {
+				return "", err
+			}
+			text := string(m.Content[spn.Start().Offset():spn.End().Offset()])
+			fmt.Fprintf(&buf, " %q", strings.TrimSpace(text))
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String(), nil
+}
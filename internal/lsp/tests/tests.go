@@ -47,6 +47,7 @@ type FuzzyCompletions map[span.Span]Completion
 type CaseSensitiveCompletions map[span.Span]Completion
 type RankCompletions map[span.Span]Completion
 type FoldingRanges []span.Span
+type SelectionRanges []span.Span
 type Formats []span.Span
 type Imports []span.Span
 type SuggestedFixes []span.Span
@@ -59,6 +60,8 @@ type Symbols map[span.URI][]protocol.DocumentSymbol
 type SymbolsChildren map[string][]protocol.DocumentSymbol
 type Signatures map[span.Span]*source.SignatureInformation
 type Links map[span.URI][]Link
+type CodeLens map[span.URI][]protocol.CodeLens
+type PackageTests []span.Span
 
 type Data struct {
 	Config                   packages.Config
@@ -73,6 +76,7 @@ type Data struct {
 	CaseSensitiveCompletions CaseSensitiveCompletions
 	RankCompletions          RankCompletions
 	FoldingRanges            FoldingRanges
+	SelectionRanges          SelectionRanges
 	Formats                  Formats
 	Imports                  Imports
 	SuggestedFixes           SuggestedFixes
@@ -85,6 +89,8 @@ type Data struct {
 	symbolsChildren          SymbolsChildren
 	Signatures               Signatures
 	Links                    Links
+	CodeLens                 CodeLens
+	PackageTests             PackageTests
 
 	t         testing.TB
 	fragments map[string]string
@@ -105,6 +111,7 @@ type Tests interface {
 	CaseSensitiveCompletion(*testing.T, span.Span, Completion, CompletionItems)
 	RankCompletion(*testing.T, span.Span, Completion, CompletionItems)
 	FoldingRange(*testing.T, span.Span)
+	SelectionRange(*testing.T, span.Span)
 	Format(*testing.T, span.Span)
 	Import(*testing.T, span.Span)
 	SuggestedFix(*testing.T, span.Span)
@@ -116,6 +123,8 @@ type Tests interface {
 	Symbol(*testing.T, span.URI, []protocol.DocumentSymbol)
 	SignatureHelp(*testing.T, span.Span, *source.SignatureInformation)
 	Link(*testing.T, span.URI, []Link)
+	CodeLens(*testing.T, span.URI, []protocol.CodeLens)
+	PackageTests(*testing.T, span.Span)
 }
 
 type Definition struct {
@@ -180,11 +189,18 @@ func DefaultOptions() source.Options {
 			protocol.SourceOrganizeImports: true,
 			protocol.QuickFix:              true,
 		},
-		source.Mod: {},
-		source.Sum: {},
+		source.Mod:  {},
+		source.Sum:  {},
+		source.Asm:  {},
+		source.Tmpl: {},
+		source.Work: {},
 	}
 	o.HoverKind = source.SynopsisDocumentation
 	o.InsertTextFormat = protocol.SnippetTextFormat
+	// The definitions of the tests are declarations.
+	o.PromotedDefinition = source.PromotedDeclaration
+	// The tests have no client to confirm the renamings with conflicts.
+	o.ConfirmRenameConflicts = false
 	return o
 }
 
@@ -210,6 +226,7 @@ func Load(t testing.TB, exporter packagestest.Exporter, dir string) *Data {
 		symbolsChildren:          make(SymbolsChildren),
 		Signatures:               make(Signatures),
 		Links:                    make(Links),
+		CodeLens:                 make(CodeLens),
 
 		t:         t,
 		dir:       dir,
@@ -297,6 +314,7 @@ func Load(t testing.TB, exporter packagestest.Exporter, dir string) *Data {
 		"rank":          data.collectCompletions(CompletionRank),
 		"snippet":       data.collectCompletionSnippets,
 		"fold":          data.collectFoldingRanges,
+		"selection":     data.collectSelectionRanges,
 		"format":        data.collectFormats,
 		"import":        data.collectImports,
 		"godef":         data.collectDefinitions,
@@ -310,6 +328,8 @@ func Load(t testing.TB, exporter packagestest.Exporter, dir string) *Data {
 		"signature":     data.collectSignatures,
 		"link":          data.collectLinks,
 		"suggestedfix":  data.collectSuggestedFixes,
+		"codelens":      data.collectCodeLens,
+		"packagetests":  data.collectPackageTests,
 	}); err != nil {
 		t.Fatal(err)
 	}
@@ -429,6 +449,16 @@ func Run(t *testing.T, tests Tests, data *Data) {
 		}
 	})
 
+	t.Run("SelectionRange", func(t *testing.T) {
+		t.Helper()
+		for _, spn := range data.SelectionRanges {
+			t.Run(spanName(spn), func(t *testing.T) {
+				t.Helper()
+				tests.SelectionRange(t, spn)
+			})
+		}
+	})
+
 	t.Run("Format", func(t *testing.T) {
 		t.Helper()
 		for _, spn := range data.Formats {
@@ -539,6 +569,26 @@ func Run(t *testing.T, tests Tests, data *Data) {
 		}
 	})
 
+	t.Run("CodeLens", func(t *testing.T) {
+		t.Helper()
+		for uri, want := range data.CodeLens {
+			t.Run(uriName(uri), func(t *testing.T) {
+				t.Helper()
+				tests.CodeLens(t, uri, want)
+			})
+		}
+	})
+
+	t.Run("PackageTests", func(t *testing.T) {
+		t.Helper()
+		for _, spn := range data.PackageTests {
+			t.Run(uriName(spn.URI()), func(t *testing.T) {
+				t.Helper()
+				tests.PackageTests(t, spn)
+			})
+		}
+	})
+
 	if *UpdateGolden {
 		for _, golden := range data.golden {
 			if !golden.Modified {
@@ -574,6 +624,11 @@ func checkData(t *testing.T, data *Data) {
 		}
 	}
 
+	codeLensCount := 0
+	for _, want := range data.CodeLens {
+		codeLensCount += len(want)
+	}
+
 	fmt.Fprintf(buf, "CompletionsCount = %v\n", len(data.Completions))
 	fmt.Fprintf(buf, "CompletionSnippetCount = %v\n", len(data.CompletionSnippets))
 	fmt.Fprintf(buf, "UnimportedCompletionsCount = %v\n", len(data.UnimportedCompletions))
@@ -583,6 +638,7 @@ func checkData(t *testing.T, data *Data) {
 	fmt.Fprintf(buf, "CaseSensitiveCompletionsCount = %v\n", len(data.CaseSensitiveCompletions))
 	fmt.Fprintf(buf, "DiagnosticsCount = %v\n", diagnosticsCount)
 	fmt.Fprintf(buf, "FoldingRangesCount = %v\n", len(data.FoldingRanges))
+	fmt.Fprintf(buf, "SelectionRangesCount = %v\n", len(data.SelectionRanges))
 	fmt.Fprintf(buf, "FormatCount = %v\n", len(data.Formats))
 	fmt.Fprintf(buf, "ImportCount = %v\n", len(data.Imports))
 	fmt.Fprintf(buf, "SuggestedFixCount = %v\n", len(data.SuggestedFixes))
@@ -595,6 +651,8 @@ func checkData(t *testing.T, data *Data) {
 	fmt.Fprintf(buf, "SymbolsCount = %v\n", len(data.Symbols))
 	fmt.Fprintf(buf, "SignaturesCount = %v\n", len(data.Signatures))
 	fmt.Fprintf(buf, "LinksCount = %v\n", linksCount)
+	fmt.Fprintf(buf, "CodeLensCount = %v\n", codeLensCount)
+	fmt.Fprintf(buf, "PackageTestsCount = %v\n", len(data.PackageTests))
 
 	want := string(data.Golden("summary", "summary.txt", func() ([]byte, error) {
 		return buf.Bytes(), nil
@@ -757,6 +815,10 @@ func (data *Data) collectFoldingRanges(spn span.Span) {
 	data.FoldingRanges = append(data.FoldingRanges, spn)
 }
 
+func (data *Data) collectSelectionRanges(spn span.Span) {
+	data.SelectionRanges = append(data.SelectionRanges, spn)
+}
+
 func (data *Data) collectFormats(spn span.Span) {
 	data.Formats = append(data.Formats, spn)
 }
@@ -885,6 +947,28 @@ func (data *Data) collectLinks(spn span.Span, link string, note *expect.Note, fs
 	})
 }
 
+func (data *Data) collectCodeLens(spn span.Span, title, cmd string) {
+	m, err := data.Mapper(spn.URI())
+	if err != nil {
+		data.t.Fatal(err)
+	}
+	rng, err := m.Range(spn)
+	if err != nil {
+		data.t.Fatal(err)
+	}
+	data.CodeLens[spn.URI()] = append(data.CodeLens[spn.URI()], protocol.CodeLens{
+		Range: rng,
+		Command: &protocol.Command{
+			Title:   title,
+			Command: cmd,
+		},
+	})
+}
+
+func (data *Data) collectPackageTests(spn span.Span) {
+	data.PackageTests = append(data.PackageTests, spn)
+}
+
 func uriName(uri span.URI) string {
 	return filepath.Base(strings.TrimSuffix(uri.Filename(), ".go"))
 }
@@ -5,9 +5,7 @@
 package lsp
 
 import (
-	"bytes"
 	"context"
-	"fmt"
 
 	"golang.org/x/tools/internal/jsonrpc2"
 	"golang.org/x/tools/internal/lsp/protocol"
@@ -23,16 +21,19 @@ func (s *Server) didOpen(ctx context.Context, params *protocol.DidOpenTextDocume
 	text := []byte(params.TextDocument.Text)
 
 	// Confirm that the file's language ID is related to Go.
-	fileKind := source.DetectLanguage(params.TextDocument.LanguageID, uri.Filename())
+	view := s.session.ViewOf(uri)
+	fileKind := view.Options().DetectLanguage(params.TextDocument.LanguageID, uri.Filename())
 
 	// Open the file.
 	s.session.DidOpen(ctx, uri, fileKind, text)
-
-	view := s.session.ViewOf(uri)
+	s.journalOpened(ctx, params.TextDocument)
 
 	// Run diagnostics on the newly-changed file.
 	go s.diagnostics(view, uri)
 
+	if s.isLargeFile(ctx, view, uri) {
+		s.warnLargeFile(ctx, view, uri)
+	}
 	return nil
 }
 
@@ -49,6 +50,7 @@ func (s *Server) didChange(ctx context.Context, params *protocol.DidChangeTextDo
 	text, isFullChange := fullChange(params.ContentChanges)
 
 	// We only accept an incremental change if the server expected it.
+	var m *protocol.ColumnMapper
 	if !isFullChange {
 		switch options.TextDocumentSyncKind {
 		case protocol.Full:
@@ -56,29 +58,30 @@ func (s *Server) didChange(ctx context.Context, params *protocol.DidChangeTextDo
 		case protocol.Incremental:
 			// Determine the new file content.
 			var err error
-			text, err = s.applyChanges(ctx, uri, params.ContentChanges)
+			m, err = s.applyChanges(ctx, uri, params.ContentChanges)
 			if err != nil {
 				return err
 			}
+			text = string(m.Content)
 		}
 	}
 	// Cache the new file content and send fresh diagnostics.
 	view := s.session.ViewOf(uri)
-	wasFirstChange, err := view.SetContent(ctx, uri, []byte(text))
-	if err != nil {
+	if _, err := view.SetContent(ctx, uri, []byte(text)); err != nil {
 		return err
 	}
+	s.setChangedMapper(ctx, uri, m)
+	s.journalChanged(ctx, params.TextDocument.URI, params.TextDocument.Version, text)
 
-	// TODO: Ideally, we should be able to specify that a generated file should be opened as read-only.
-	// Tell the user that they should not be editing a generated file.
-	if source.IsGenerated(ctx, view, uri) && wasFirstChange {
-		s.client.ShowMessage(ctx, &protocol.ShowMessageParams{
-			Message: fmt.Sprintf("Do not edit this file! %s is a generated file.", uri.Filename()),
-			Type:    protocol.Warning,
-		})
+	// The diagnostics of a large file are only computed again once it is
+	// saved, as computing them for each change would hang the server.
+	if s.isLargeFile(ctx, view, uri) {
+		s.setStaleLargeFile(uri, true)
+		return nil
 	}
 
-	// Run diagnostics on the newly-changed file.
+	// Run diagnostics on the newly-changed file. Those of a generated file
+	// warn the user that they should not be editing it.
 	go s.diagnostics(view, uri)
 
 	return nil
@@ -95,42 +98,67 @@ func fullChange(changes []protocol.TextDocumentContentChangeEvent) (string, bool
 	return "", false
 }
 
-func (s *Server) applyChanges(ctx context.Context, uri span.URI, changes []protocol.TextDocumentContentChangeEvent) (string, error) {
-	content, _, err := s.session.GetFile(uri, source.UnknownKind).Read(ctx)
+// changedMapper is the mapper of the content of a file last set by
+// incremental changes.
+type changedMapper struct {
+	// hash is the hash of the content, which tells whether it is still that
+	// of the file.
+	hash   string
+	mapper *protocol.ColumnMapper
+}
+
+func (s *Server) applyChanges(ctx context.Context, uri span.URI, changes []protocol.TextDocumentContentChangeEvent) (*protocol.ColumnMapper, error) {
+	content, hash, err := s.session.GetFile(uri, source.UnknownKind).Read(ctx)
 	if err != nil {
-		return "", jsonrpc2.NewErrorf(jsonrpc2.CodeInternalError, "file not found (%v)", err)
+		return nil, jsonrpc2.NewErrorf(jsonrpc2.CodeInternalError, "file not found (%v)", err)
 	}
-	for _, change := range changes {
-		// Update column mapper along with the content.
-		converter := span.NewContentConverter(uri.Filename(), content)
-		m := &protocol.ColumnMapper{
+	// Reuse the mapper of the last changes of the file, unless its content
+	// has changed in another way since.
+	s.changedMappersMu.Lock()
+	cm, ok := s.changedMappers[uri]
+	s.changedMappersMu.Unlock()
+	m := cm.mapper
+	if !ok || cm.hash != hash {
+		m = &protocol.ColumnMapper{
 			URI:       uri,
-			Converter: converter,
+			Converter: span.NewContentConverter(uri.Filename(), content),
 			Content:   content,
+			Encoding:  s.session.Options().PositionEncoding,
 		}
+	}
+	m, err = m.ApplyChanges(changes)
+	if err != nil {
+		return nil, jsonrpc2.NewErrorf(jsonrpc2.CodeInternalError, "%v", err)
+	}
+	return m, nil
+}
 
-		spn, err := m.RangeSpan(*change.Range)
-		if err != nil {
-			return "", err
-		}
-		if !spn.HasOffset() {
-			return "", jsonrpc2.NewErrorf(jsonrpc2.CodeInternalError, "invalid range for content change")
-		}
-		start, end := spn.Start().Offset(), spn.End().Offset()
-		if end < start {
-			return "", jsonrpc2.NewErrorf(jsonrpc2.CodeInternalError, "invalid range for content change")
-		}
-		var buf bytes.Buffer
-		buf.Write(content[:start])
-		buf.WriteString(change.Text)
-		buf.Write(content[end:])
-		content = buf.Bytes()
+// setChangedMapper records m as the mapper of the content of the file uri,
+// or forgets the mapper of the file if m is nil.
+func (s *Server) setChangedMapper(ctx context.Context, uri span.URI, m *protocol.ColumnMapper) {
+	s.changedMappersMu.Lock()
+	defer s.changedMappersMu.Unlock()
+	if m == nil {
+		delete(s.changedMappers, uri)
+		return
 	}
-	return string(content), nil
+	_, hash, err := s.session.GetFile(uri, source.UnknownKind).Read(ctx)
+	if err != nil {
+		delete(s.changedMappers, uri)
+		return
+	}
+	if s.changedMappers == nil {
+		s.changedMappers = make(map[span.URI]changedMapper)
+	}
+	s.changedMappers[uri] = changedMapper{hash: hash, mapper: m}
 }
 
 func (s *Server) didSave(ctx context.Context, params *protocol.DidSaveTextDocumentParams) error {
-	s.session.DidSave(span.NewURI(params.TextDocument.URI))
+	uri := span.NewURI(params.TextDocument.URI)
+	s.session.DidSave(uri)
+	if s.setStaleLargeFile(uri, false) {
+		go s.diagnostics(s.session.ViewOf(uri), uri)
+	}
 	return nil
 }
 
@@ -138,6 +166,9 @@ func (s *Server) didClose(ctx context.Context, params *protocol.DidCloseTextDocu
 	uri := span.NewURI(params.TextDocument.URI)
 	ctx = telemetry.URI.With(ctx, uri)
 	s.session.DidClose(uri)
+	s.setChangedMapper(ctx, uri, nil)
+	s.setStaleLargeFile(uri, false)
+	s.journalClosed(ctx, params.TextDocument.URI)
 	view := s.session.ViewOf(uri)
 	if _, err := view.SetContent(ctx, uri, nil); err != nil {
 		return err
@@ -5,6 +5,11 @@ package lsp
 
 import (
 	"context"
+	"go/build"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"golang.org/x/tools/internal/lsp/protocol"
 	"golang.org/x/tools/internal/lsp/source"
@@ -81,3 +86,148 @@ func (s *Server) didChangeWatchedFiles(ctx context.Context, params *protocol.Did
 	}
 	return nil
 }
+
+// watchedFilesRegistration is the ID of the registration of the watchers of
+// the Go files of the views.
+const watchedFilesRegistration = "workspace/didChangeWatchedFiles"
+
+// updateWatchedFiles registers watchers for the Go files of the current
+// views with the client, in place of those registered before, if the views
+// changed so that they need different watchers.
+func (s *Server) updateWatchedFiles(ctx context.Context) error {
+	options := s.session.Options()
+	if !options.WatchFileChanges || !options.DynamicWatchedFilesSupported {
+		return nil
+	}
+	views := s.session.Views()
+	var folders []string
+	for _, view := range views {
+		folders = append(folders, view.Folder().Filename())
+	}
+	filtered := func(dir string) bool {
+		for _, view := range views {
+			if view.DirectoryFilter().Excluded(dir) {
+				return true
+			}
+		}
+		return false
+	}
+	patterns := watchPatterns(folders, moduleCaches(options.Env), filtered)
+
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	if equalStrings(patterns, s.watchPatterns) {
+		return nil
+	}
+	if s.watchPatterns != nil {
+		if err := s.client.UnregisterCapability(ctx, &protocol.UnregistrationParams{
+			Unregisterations: []protocol.Unregistration{{
+				ID:     watchedFilesRegistration,
+				Method: "workspace/didChangeWatchedFiles",
+			}},
+		}); err != nil {
+			return err
+		}
+		s.watchPatterns = nil
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+	var watchers []protocol.FileSystemWatcher
+	for _, pattern := range patterns {
+		watchers = append(watchers, protocol.FileSystemWatcher{
+			GlobPattern: pattern,
+			Kind:        float64(protocol.WatchChange + protocol.WatchDelete + protocol.WatchCreate),
+		})
+	}
+	if err := s.client.RegisterCapability(ctx, &protocol.RegistrationParams{
+		Registrations: []protocol.Registration{{
+			ID:              watchedFilesRegistration,
+			Method:          "workspace/didChangeWatchedFiles",
+			RegisterOptions: protocol.DidChangeWatchedFilesRegistrationOptions{Watchers: watchers},
+		}},
+	}); err != nil {
+		return err
+	}
+	s.watchPatterns = patterns
+	return nil
+}
+
+// watchPatterns returns the glob patterns of the Go files of the given
+// folders. The folders nested in another folder, such as those of nested
+// modules, are covered by its patterns, and the folders in one of the
+// excluded directories are not watched. Within a folder, the top-level
+// vendor directory and the directories that the go command ignores, whose
+// names start with "." or "_", are not watched, nor are those for which
+// filtered, if not nil, reports true. A top-level directory created
+// later is only watched once the watchers are updated for a change of the
+// folders.
+func watchPatterns(folders, excluded []string, filtered func(dir string) bool) []string {
+	sort.Strings(folders)
+	var patterns, roots []string
+	for _, folder := range folders {
+		if inAnyDir(roots, folder) || inAnyDir(excluded, folder) {
+			continue
+		}
+		roots = append(roots, folder)
+		glob := filepath.ToSlash(folder)
+		patterns = append(patterns, glob+"/*.go")
+		infos, err := ioutil.ReadDir(folder)
+		if err != nil {
+			// Watch the whole folder rather than nothing.
+			patterns = append(patterns, glob+"/**/*.go")
+			continue
+		}
+		for _, info := range infos {
+			name := info.Name()
+			if !info.IsDir() || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				continue
+			}
+			if filtered != nil && filtered(filepath.Join(folder, name)) {
+				continue
+			}
+			patterns = append(patterns, glob+"/"+name+"/**/*.go")
+		}
+	}
+	return patterns
+}
+
+// moduleCaches returns the module caches of the GOPATH directories in env,
+// or in the default GOPATH, whose files are never edited.
+func moduleCaches(env []string) []string {
+	gopath := build.Default.GOPATH
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "GOPATH=") {
+			gopath = kv[len("GOPATH="):]
+		}
+	}
+	var dirs []string
+	for _, dir := range filepath.SplitList(gopath) {
+		if dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "pkg", "mod"))
+		}
+	}
+	return dirs
+}
+
+// inAnyDir reports whether path is one of dirs or is in one of them.
+func inAnyDir(dirs []string, path string) bool {
+	for _, dir := range dirs {
+		if path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/internal/lsp/cache"
+	"golang.org/x/tools/internal/lsp/source"
+	"golang.org/x/tools/internal/lsp/tests"
+	"golang.org/x/tools/internal/span"
+)
+
+// TestUnreliableWatcher checks how the server's view of files that are not
+// open in the editor depends on the notifications of the client's file
+// watcher, when those notifications are delayed or dropped.
+func TestUnreliableWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopls-watcher-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"go.mod": "module example.com/a\n",
+		"a.go":   "package a\n\nconst C = 1\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := tests.Context(t)
+	session := cache.New(nil).NewSession(ctx)
+	options := tests.DefaultOptions()
+	options.WatchFileChanges = true
+	session.SetOptions(options)
+	view := session.NewView(ctx, "watcher_test", span.FileURI(dir), options)
+	s := &Server{
+		client:      newFakeClient(),
+		session:     session,
+		undelivered: make(map[span.URI][]source.Diagnostic),
+	}
+	w := &fakeWatcher{server: s, dir: dir}
+
+	f, err := view.GetFile(ctx, span.FileURI(filepath.Join(dir, "a.go")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Load the package of the file, as diagnostics would, since changes to
+	// files that do not belong to a known package are not tracked.
+	if _, _, err := view.CheckPackageHandles(ctx, f); err != nil {
+		t.Fatal(err)
+	}
+	content := func() string {
+		data, _, err := view.Snapshot().Handle(ctx, f).Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	const (
+		v1 = "package a\n\nconst C = 1\n"
+		v2 = "package a\n\nconst C = 2\n"
+		v3 = "package a\n\nconst C = 3\n"
+		v4 = "package a\n\nconst C = 4\n"
+	)
+	if got := content(); got != v1 {
+		t.Fatalf("initial content = %q, want %q", got, v1)
+	}
+
+	// Without a notification, the server keeps the old content.
+	w.Drop = func(string) bool { return true }
+	w.WriteFile(ctx, t, "a.go", v2)
+	if got := content(); got != v1 {
+		t.Errorf("content after dropped notification = %q, want %q", got, v1)
+	}
+
+	// A later notification brings the server up to date.
+	w.Drop = nil
+	w.Delay = 50 * time.Millisecond
+	w.WriteFile(ctx, t, "a.go", v3)
+	if got := content(); got != v1 {
+		t.Errorf("content before delayed notification = %q, want %q", got, v1)
+	}
+	w.Await()
+	if got := content(); got != v3 {
+		t.Errorf("content after delayed notification = %q, want %q", got, v3)
+	}
+
+	// Changes to open files are ignored, since the editor's content is the
+	// source of truth.
+	w.Delay = 0
+	if err := session.DidOpen(ctx, f.URI(), source.Go, []byte(v3)); err != nil {
+		t.Fatal(err)
+	}
+	w.WriteFile(ctx, t, "a.go", v4)
+	if got := content(); got != v3 {
+		t.Errorf("content of open file after notification = %q, want %q", got, v3)
+	}
+}
+
+func TestWatchPatterns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopls-watch-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, sub := range []string{
+		"a/b",
+		"vendor/example.com/v",
+		".git",
+		"_tools",
+		"nested/c",
+		"gopath/pkg/mod/example.com/m",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(sub)), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	folders := []string{
+		filepath.Join(dir, "nested"),
+		dir,
+		filepath.Join(dir, "gopath", "pkg", "mod", "example.com", "m"),
+		filepath.Join(dir, "missing"),
+	}
+	excluded := moduleCaches([]string{"GOPATH=" + filepath.Join(dir, "gopath")})
+	got := watchPatterns(folders, excluded, nil)
+	root := filepath.ToSlash(dir)
+	want := []string{
+		root + "/*.go",
+		root + "/a/**/*.go",
+		root + "/gopath/**/*.go",
+		root + "/nested/**/*.go",
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("watchPatterns(%v) = %v, want %v", folders, got, want)
+	}
+
+	// The directories excluded by the directory filters are not watched.
+	filtered := func(d string) bool { return d == filepath.Join(dir, "a") }
+	got = watchPatterns([]string{dir}, nil, filtered)
+	want = []string{
+		root + "/*.go",
+		root + "/gopath/**/*.go",
+		root + "/nested/**/*.go",
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("watchPatterns(%v) with a filter = %v, want %v", dir, got, want)
+	}
+
+	// A folder in a module cache is not watched.
+	modFolder := filepath.Join(dir, "gopath", "pkg", "mod", "example.com", "m")
+	if got := watchPatterns([]string{modFolder}, excluded, nil); len(got) != 0 {
+		t.Errorf("watchPatterns(%v) = %v, want none", modFolder, got)
+	}
+
+	// A folder that cannot be read is watched entirely.
+	missing := filepath.Join(dir, "missing")
+	got = watchPatterns([]string{missing}, nil, nil)
+	want = []string{
+		filepath.ToSlash(missing) + "/*.go",
+		filepath.ToSlash(missing) + "/**/*.go",
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("watchPatterns(%v) = %v, want %v", missing, got, want)
+	}
+}
@@ -6,8 +6,12 @@ package lsp
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"golang.org/x/tools/internal/lsp/protocol"
+	"golang.org/x/tools/internal/lsp/source"
 	"golang.org/x/tools/internal/span"
 	errors "golang.org/x/xerrors"
 )
@@ -27,7 +31,7 @@ func (s *Server) changeFolders(ctx context.Context, event protocol.WorkspaceFold
 			return err
 		}
 	}
-	return nil
+	return s.updateWatchedFiles(ctx)
 }
 
 func (s *Server) addView(ctx context.Context, name string, uri span.URI) error {
@@ -43,3 +47,48 @@ func (s *Server) addView(ctx context.Context, name string, uri span.URI) error {
 	s.session.NewView(ctx, name, uri, options)
 	return nil
 }
+
+// workspaceFolderView returns the view of the workspace folder that contains
+// the file with the given URI: of the views whose folders contain it, the one
+// with the shortest folder, as the views of the modules nested in a
+// workspace folder are deeper.
+func (s *Server) workspaceFolderView(uri span.URI) source.View {
+	var folder source.View
+	for _, view := range s.session.Views() {
+		if !strings.HasPrefix(string(uri), string(view.Folder())) {
+			continue
+		}
+		if folder == nil || len(view.Folder()) < len(folder.Folder()) {
+			folder = view
+		}
+	}
+	return folder
+}
+
+// createWorkFile writes a go.work file that uses the modules of the workspace
+// folder, and reloads its view, dropping the views of its nested modules.
+func (s *Server) createWorkFile(ctx context.Context, folder span.URI) error {
+	var view source.View
+	for _, v := range s.session.Views() {
+		if v.Folder() == folder {
+			view = v
+		}
+	}
+	if view == nil {
+		return errors.Errorf("no view for workspace folder %v", folder)
+	}
+	uri, err := source.CreateWorkFile(ctx, view, folder.Filename())
+	if err != nil {
+		return err
+	}
+	s.session.DidChangeOutOfBand(ctx, uri, protocol.Created)
+	name := view.Name()
+	view.Shutdown(ctx)
+	return s.addView(ctx, name, folder)
+}
+
+// hasWorkFile reports whether the workspace folder has a go.work file.
+func hasWorkFile(folder span.URI) bool {
+	_, err := os.Stat(filepath.Join(folder.Filename(), "go.work"))
+	return err == nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package persistent provides data structures that are cheap to copy, so
+// that each version of a changing collection, such as the files of a
+// snapshot, can be kept without copying all of its entries.
+//
+// A change to a collection copies only the parts of its structure on the path
+// to the changed entry, and shares the rest with the copies made before.
+package persistent
+
+import (
+	"math/rand"
+)
+
+// Map is an ordered map whose Clone method takes constant time. It is
+// implemented as a treap whose nodes are never modified once they are
+// shared, so a Set or Delete copies the nodes on the path to its key.
+//
+// The zero value is not usable: use NewMap. A Map is not safe for concurrent
+// use, but clones are independent of each other.
+type Map struct {
+	root *mapNode
+	less func(a, b interface{}) bool
+}
+
+type mapNode struct {
+	key, value  interface{}
+	weight      uint64
+	size        int
+	left, right *mapNode
+}
+
+// NewMap returns an empty map whose keys are ordered by less, which must be
+// a strict weak ordering.
+func NewMap(less func(a, b interface{}) bool) *Map {
+	return &Map{less: less}
+}
+
+// Clone returns a copy of m, which shares its structure.
+func (m *Map) Clone() *Map {
+	return &Map{root: m.root, less: m.less}
+}
+
+// Len returns the number of entries of m.
+func (m *Map) Len() int {
+	return m.root.len()
+}
+
+// Get returns the value of key in m, and whether it is in m.
+func (m *Map) Get(key interface{}) (interface{}, bool) {
+	n := m.root
+	for n != nil {
+		switch {
+		case m.less(key, n.key):
+			n = n.left
+		case m.less(n.key, key):
+			n = n.right
+		default:
+			return n.value, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets the value of key in m to value.
+func (m *Map) Set(key, value interface{}) {
+	left, _, right := m.split(m.root, key)
+	n := &mapNode{key: key, value: value, weight: rand.Uint64(), size: 1}
+	m.root = merge(merge(left, n), right)
+}
+
+// Delete removes key from m, if it is in m.
+func (m *Map) Delete(key interface{}) {
+	left, mid, right := m.split(m.root, key)
+	if mid != nil {
+		m.root = merge(left, right)
+	}
+}
+
+// Range calls f with each entry of m, in the order of their keys.
+func (m *Map) Range(f func(key, value interface{})) {
+	m.root.rangeFrom(nil, nil, func(key, value interface{}) bool {
+		f(key, value)
+		return true
+	})
+}
+
+// RangeFrom calls f with each entry of m whose key is not less than start,
+// in the order of their keys, until f returns false. The entries before
+// start are skipped without being visited, so a scan of the keys with a
+// common prefix, such as the URIs of a directory, takes time proportional
+// to the number of entries it visits rather than to the size of m.
+func (m *Map) RangeFrom(start interface{}, f func(key, value interface{}) bool) {
+	m.root.rangeFrom(start, m.less, f)
+}
+
+func (n *mapNode) len() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// rangeFrom calls f with the entries of the tree of n whose key is not less
+// than start, or all of them if less is nil, until f returns false. It
+// reports whether f never returned false.
+func (n *mapNode) rangeFrom(start interface{}, less func(a, b interface{}) bool, f func(key, value interface{}) bool) bool {
+	if n == nil {
+		return true
+	}
+	if less == nil || !less(n.key, start) {
+		if !n.left.rangeFrom(start, less, f) || !f(n.key, n.value) {
+			return false
+		}
+	}
+	return n.right.rangeFrom(start, less, f)
+}
+
+// split returns the trees of the keys of the tree of n that are less than
+// key and greater than key, and the node of key, if any. The nodes on the
+// path to key are copied, so the tree of n is unchanged.
+func (m *Map) split(n *mapNode, key interface{}) (left, mid, right *mapNode) {
+	if n == nil {
+		return nil, nil, nil
+	}
+	switch {
+	case m.less(key, n.key):
+		left, mid, r := m.split(n.left, key)
+		c := n.copy()
+		c.left = r
+		c.update()
+		return left, mid, c
+	case m.less(n.key, key):
+		l, mid, right := m.split(n.right, key)
+		c := n.copy()
+		c.right = l
+		c.update()
+		return c, mid, right
+	default:
+		return n.left, n, n.right
+	}
+}
+
+// merge returns the tree of the keys of the trees of a and b, whose keys
+// are all less than those of b. The nodes on the paths of the merge are
+// copied, so the trees of a and b are unchanged.
+func merge(a, b *mapNode) *mapNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if a.weight > b.weight {
+		c := a.copy()
+		c.right = merge(a.right, b)
+		c.update()
+		return c
+	}
+	c := b.copy()
+	c.left = merge(a, b.left)
+	c.update()
+	return c
+}
+
+func (n *mapNode) copy() *mapNode {
+	c := *n
+	return &c
+}
+
+func (n *mapNode) update() {
+	n.size = 1 + n.left.len() + n.right.len()
+}
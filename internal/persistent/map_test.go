@@ -0,0 +1,112 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package persistent
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func intLess(a, b interface{}) bool {
+	return a.(int) < b.(int)
+}
+
+// entries returns the keys and values of m, in order.
+func entries(m *Map) [][2]int {
+	var result [][2]int
+	m.Range(func(key, value interface{}) {
+		result = append(result, [2]int{key.(int), value.(int)})
+	})
+	return result
+}
+
+// sortedEntries returns the keys and values of m, ordered by key.
+func sortedEntries(m map[int]int) [][2]int {
+	var result [][2]int
+	for k, v := range m {
+		result = append(result, [2]int{k, v})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i][0] < result[j][0] })
+	return result
+}
+
+func TestMap(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var (
+		maps   []*Map
+		wants  []map[int]int
+		m      = NewMap(intLess)
+		want   = make(map[int]int)
+		nextOp = func() {
+			key := rnd.Intn(100)
+			if rnd.Intn(3) == 0 {
+				m.Delete(key)
+				delete(want, key)
+			} else {
+				value := rnd.Int()
+				m.Set(key, value)
+				want[key] = value
+			}
+		}
+	)
+	for i := 0; i < 1000; i++ {
+		nextOp()
+		if i%100 == 0 {
+			// Keep a clone and a copy of the map, which the following
+			// operations must not change.
+			maps = append(maps, m.Clone())
+			saved := make(map[int]int)
+			for k, v := range want {
+				saved[k] = v
+			}
+			wants = append(wants, saved)
+		}
+	}
+	maps = append(maps, m)
+	wants = append(wants, want)
+	for i, m := range maps {
+		if got, want := entries(m), sortedEntries(wants[i]); !reflect.DeepEqual(got, want) {
+			t.Errorf("map %d has entries %v, want %v", i, got, want)
+		}
+		if m.Len() != len(wants[i]) {
+			t.Errorf("map %d has length %d, want %d", i, m.Len(), len(wants[i]))
+		}
+		for k := 0; k < 100; k++ {
+			v, ok := m.Get(k)
+			wantV, wantOK := wants[i][k]
+			if ok != wantOK || ok && v.(int) != wantV {
+				t.Errorf("map %d: Get(%d) = %v, %v, want %v, %v", i, k, v, ok, wantV, wantOK)
+			}
+		}
+	}
+}
+
+func TestRangeFrom(t *testing.T) {
+	m := NewMap(intLess)
+	for i := 0; i < 20; i += 2 {
+		m.Set(i, i)
+	}
+	for _, tt := range []struct {
+		start, stop int
+		want        []int
+	}{
+		{start: 5, stop: 12, want: []int{6, 8, 10, 12}},
+		{start: 6, stop: 6, want: []int{6}},
+		{start: -1, stop: 2, want: []int{0, 2}},
+		{start: 18, stop: 100, want: []int{18}},
+		{start: 19, stop: 100, want: nil},
+	} {
+		var got []int
+		m.RangeFrom(tt.start, func(key, value interface{}) bool {
+			got = append(got, key.(int))
+			return key.(int) < tt.stop
+		})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("RangeFrom(%d) until %d = %v, want %v", tt.start, tt.stop, got, tt.want)
+		}
+	}
+}
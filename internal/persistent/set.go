@@ -0,0 +1,88 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package persistent
+
+// Set is an ordered set whose Clone method takes constant time. It is a Map
+// whose values are ignored.
+//
+// The zero value is not usable: use NewSet.
+type Set struct {
+	m *Map
+}
+
+// NewSet returns an empty set whose elements are ordered by less, which must
+// be a strict weak ordering.
+func NewSet(less func(a, b interface{}) bool) *Set {
+	return &Set{m: NewMap(less)}
+}
+
+// Clone returns a copy of s, which shares its structure.
+func (s *Set) Clone() *Set {
+	return &Set{m: s.m.Clone()}
+}
+
+// Len returns the number of elements of s.
+func (s *Set) Len() int {
+	return s.m.Len()
+}
+
+// Contains reports whether elem is in s.
+func (s *Set) Contains(elem interface{}) bool {
+	_, ok := s.m.Get(elem)
+	return ok
+}
+
+// Add adds elem to s.
+func (s *Set) Add(elem interface{}) {
+	s.m.Set(elem, nil)
+}
+
+// Remove removes elem from s, if it is in s.
+func (s *Set) Remove(elem interface{}) {
+	s.m.Delete(elem)
+}
+
+// Range calls f with each element of s, in order.
+func (s *Set) Range(f func(elem interface{})) {
+	s.m.Range(func(key, _ interface{}) {
+		f(key)
+	})
+}
+
+// Union returns the set of the elements of s or other, which must have the
+// same order. The elements of the larger set are not copied.
+func (s *Set) Union(other *Set) *Set {
+	large, small := s, other
+	if large.Len() < small.Len() {
+		large, small = small, large
+	}
+	result := large.Clone()
+	small.Range(result.Add)
+	return result
+}
+
+// Intersect returns the set of the elements of both s and other, which must
+// have the same order.
+func (s *Set) Intersect(other *Set) *Set {
+	large, small := s, other
+	if large.Len() < small.Len() {
+		large, small = small, large
+	}
+	result := &Set{m: NewMap(s.m.less)}
+	small.Range(func(elem interface{}) {
+		if large.Contains(elem) {
+			result.Add(elem)
+		}
+	})
+	return result
+}
+
+// Diff returns the set of the elements of s that are not in other, which
+// must have the same order.
+func (s *Set) Diff(other *Set) *Set {
+	result := s.Clone()
+	other.Range(result.Remove)
+	return result
+}
@@ -0,0 +1,81 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package persistent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newIntSet(elems ...int) *Set {
+	s := NewSet(intLess)
+	for _, e := range elems {
+		s.Add(e)
+	}
+	return s
+}
+
+func elements(s *Set) []int {
+	var result []int
+	s.Range(func(elem interface{}) {
+		result = append(result, elem.(int))
+	})
+	return result
+}
+
+func TestSetOperations(t *testing.T) {
+	a := newIntSet(1, 2, 3, 5, 8)
+	b := newIntSet(2, 4, 8, 16)
+	for _, tt := range []struct {
+		name string
+		got  *Set
+		want []int
+	}{
+		{"Union", a.Union(b), []int{1, 2, 3, 4, 5, 8, 16}},
+		{"Intersect", a.Intersect(b), []int{2, 8}},
+		{"Diff", a.Diff(b), []int{1, 3, 5}},
+		{"Diff", b.Diff(a), []int{4, 16}},
+	} {
+		if got := elements(tt.got); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	// The operations must not change their operands.
+	if got, want := elements(a), []int{1, 2, 3, 5, 8}; !reflect.DeepEqual(got, want) {
+		t.Errorf("a = %v, want %v", got, want)
+	}
+	if got, want := elements(b), []int{2, 4, 8, 16}; !reflect.DeepEqual(got, want) {
+		t.Errorf("b = %v, want %v", got, want)
+	}
+}
+
+func TestSlice(t *testing.T) {
+	var s Slice
+	var want []interface{}
+	for i := 0; i < 100; i++ {
+		s.Append(i)
+		want = append(want, i)
+	}
+	clone := s.Clone()
+	for i := 0; i < 100; i += 3 {
+		s.Set(i, -i)
+		want[i] = -i
+	}
+	var got []interface{}
+	s.Range(func(i int, value interface{}) {
+		if v := s.At(i); v != value {
+			t.Errorf("At(%d) = %v, want %v", i, v, value)
+		}
+		got = append(got, value)
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("slice = %v, want %v", got, want)
+	}
+	for i := 0; i < clone.Len(); i++ {
+		if v := clone.At(i); v != i {
+			t.Errorf("clone.At(%d) = %v, want %v", i, v, i)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package persistent
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Slice is a sequence of values whose Clone method takes constant time. It
+// is implemented as a treap ordered by the index of its nodes, which is the
+// number of nodes before them, so At, Set and Append take logarithmic time.
+//
+// The zero value is an empty slice, ready to use.
+type Slice struct {
+	root *mapNode
+}
+
+// Clone returns a copy of s, which shares its structure.
+func (s *Slice) Clone() *Slice {
+	return &Slice{root: s.root}
+}
+
+// Len returns the number of values of s.
+func (s *Slice) Len() int {
+	return s.root.len()
+}
+
+// At returns the value at index i of s. It panics if i is out of range.
+func (s *Slice) At(i int) interface{} {
+	s.check(i)
+	n := s.root
+	for {
+		switch l := n.left.len(); {
+		case i < l:
+			n = n.left
+		case i > l:
+			i -= l + 1
+			n = n.right
+		default:
+			return n.value
+		}
+	}
+}
+
+// Set sets the value at index i of s to value. It panics if i is out of
+// range.
+func (s *Slice) Set(i int, value interface{}) {
+	s.check(i)
+	s.root = s.root.setAt(i, value)
+}
+
+// Append appends values to s.
+func (s *Slice) Append(values ...interface{}) {
+	for _, v := range values {
+		s.root = merge(s.root, &mapNode{value: v, weight: rand.Uint64(), size: 1})
+	}
+}
+
+// Range calls f with the index and value of each value of s, in order.
+func (s *Slice) Range(f func(i int, value interface{})) {
+	i := 0
+	s.root.rangeFrom(nil, nil, func(_, value interface{}) bool {
+		f(i, value)
+		i++
+		return true
+	})
+}
+
+func (s *Slice) check(i int) {
+	if i < 0 || i >= s.Len() {
+		panic(fmt.Sprintf("persistent.Slice: index %d out of range [0:%d]", i, s.Len()))
+	}
+}
+
+// setAt returns a copy of the tree of n in which the value at index i is
+// value.
+func (n *mapNode) setAt(i int, value interface{}) *mapNode {
+	c := n.copy()
+	switch l := n.left.len(); {
+	case i < l:
+		c.left = n.left.setAt(i, value)
+	case i > l:
+		c.right = n.right.setAt(i-l-1, value)
+	default:
+		c.value = value
+	}
+	return c
+}
@@ -5,8 +5,10 @@
 package span
 
 import (
+	"bytes"
 	"fmt"
 	"go/token"
+	"sort"
 )
 
 // Range represents a source code range in token.Pos form.
@@ -24,6 +26,15 @@ type Range struct {
 type TokenConverter struct {
 	fset *token.FileSet
 	file *token.File
+
+	// lines are the offsets of the starts of the lines of the content of
+	// a converter created by NewContentConverter, including that after a
+	// final newline, so that Edit can adjust them.
+	lines []int
+
+	// clamp reports whether ToOffset clamps the positions beyond the end
+	// of their line or of the file.
+	clamp bool
 }
 
 // NewRange creates a new Range from a FileSet and two positions.
@@ -45,10 +56,67 @@ func NewTokenConverter(fset *token.FileSet, f *token.File) *TokenConverter {
 // NewContentConverter returns an implementation of Converter for the
 // given file content.
 func NewContentConverter(filename string, content []byte) *TokenConverter {
+	lines := []int{0}
+	for i, b := range content {
+		if b == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return newLinesConverter(filename, len(content), lines)
+}
+
+// newLinesConverter returns a converter for content of the given size whose
+// lines start at the given offsets.
+func newLinesConverter(filename string, size int, lines []int) *TokenConverter {
 	fset := token.NewFileSet()
-	f := fset.AddFile(filename, -1, len(content))
-	f.SetLinesForContent(content)
-	return &TokenConverter{fset: fset, file: f}
+	f := fset.AddFile(filename, -1, size)
+	// A token.File has no line that starts at its end.
+	fileLines := lines
+	if n := len(fileLines); n > 1 && fileLines[n-1] == size {
+		fileLines = fileLines[:n-1]
+	}
+	f.SetLines(fileLines)
+	return &TokenConverter{fset: fset, file: f, lines: lines}
+}
+
+// WithClamping returns a copy of l that converts the lines beyond the end of
+// the file to its end, and the columns beyond the end of their line to the
+// end of the line, rather than failing or converting them to an offset on
+// another line. This suits the callers whose positions may be slightly out of
+// date, such as command-line tools.
+func (l *TokenConverter) WithClamping() *TokenConverter {
+	c := *l
+	c.clamp = true
+	return &c
+}
+
+// Edit returns a converter for the content of l with the bytes from the
+// offset start to end replaced by text. Rather than scanning the whole new
+// content for lines, it adjusts the line table of l, which must have been
+// created by NewContentConverter or Edit.
+func (l *TokenConverter) Edit(start, end int, text []byte) (*TokenConverter, error) {
+	if l.lines == nil {
+		return nil, fmt.Errorf("converter of %s has no line table", l.file.Name())
+	}
+	if start < 0 || end < start || end > l.file.Size() {
+		return nil, fmt.Errorf("invalid edit %v-%v of file of size %v", start, end, l.file.Size())
+	}
+	// The lines that start inside the replaced bytes are removed.
+	before := sort.SearchInts(l.lines, start+1)
+	after := sort.SearchInts(l.lines, end+1)
+	delta := len(text) - (end - start)
+
+	lines := make([]int, 0, len(l.lines)+bytes.Count(text, []byte("\n")))
+	lines = append(lines, l.lines[:before]...)
+	for i, b := range text {
+		if b == '\n' {
+			lines = append(lines, start+i+1)
+		}
+	}
+	for _, offset := range l.lines[after:] {
+		lines = append(lines, offset+delta)
+	}
+	return newLinesConverter(l.file.Name(), l.file.Size()+delta, lines), nil
 }
 
 // IsPoint returns true if the range represents a single point.
@@ -132,9 +200,12 @@ func (l *TokenConverter) ToOffset(line, col int) (int, error) {
 	}
 	lineMax := l.file.LineCount() + 1
 	if line > lineMax {
+		if l.clamp {
+			return l.file.Size(), nil
+		}
 		return -1, fmt.Errorf("line is beyond end of file %v", lineMax)
 	} else if line == lineMax {
-		if col > 1 {
+		if col > 1 && !l.clamp {
 			return -1, fmt.Errorf("column is beyond end of file")
 		}
 		// at the end of the file, allowing for a trailing eol
@@ -147,5 +218,19 @@ func (l *TokenConverter) ToOffset(line, col int) (int, error) {
 	// we assume that column is in bytes here, and that the first byte of a
 	// line is at column 1
 	pos += token.Pos(col - 1)
+	if l.clamp {
+		if end := l.lineEnd(line); pos > end {
+			pos = end
+		}
+	}
 	return offset(l.file, pos)
 }
+
+// lineEnd returns the position of the newline at the end of line, or of the
+// end of the file if it is the last line.
+func (l *TokenConverter) lineEnd(line int) token.Pos {
+	if line < l.file.LineCount() {
+		return lineStart(l.file, line+1) - 1
+	}
+	return token.Pos(l.file.Base() + l.file.Size())
+}
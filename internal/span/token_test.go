@@ -76,3 +76,69 @@ func checkToken(t *testing.T, c *span.TokenConverter, in, expect span.Span) {
 		t.Errorf("For %v expected %q got %q", in, expected, got)
 	}
 }
+
+func TestEdit(t *testing.T) {
+	const content = "package a\n\nfunc f() {\n}\n"
+	for _, edit := range []struct {
+		start, end int
+		text       string
+	}{
+		{0, 0, "// a\n"},
+		{len(content), len(content), "var x int"},
+		{len(content), len(content), "\n\n"},
+		{11, 24, ""},
+		{9, 11, "\n\n\n"},
+		{8, 13, "b\nc"},
+		{0, len(content), "package b\n"},
+	} {
+		c, err := span.NewContentConverter("a.go", []byte(content)).Edit(edit.start, edit.end, []byte(edit.text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		edited := content[:edit.start] + edit.text + content[edit.end:]
+		want := span.NewContentConverter("a.go", []byte(edited))
+		for offset := 0; offset <= len(edited); offset++ {
+			gotLine, gotCol, err := c.ToPosition(offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantLine, wantCol, err := want.ToPosition(offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotLine != wantLine || gotCol != wantCol {
+				t.Errorf("%q: offset %v is at %v:%v, want %v:%v", edited, offset, gotLine, gotCol, wantLine, wantCol)
+			}
+		}
+	}
+}
+
+func TestClamping(t *testing.T) {
+	const content = "ab\ncd"
+	c := span.NewContentConverter("a.go", []byte(content))
+	clamping := c.WithClamping()
+	for _, tt := range []struct {
+		line, col int
+		want      int
+		wantErr   bool // without clamping
+	}{
+		{1, 2, 1, false},
+		{1, 4, 2, false}, // converted to an offset on the next line without clamping
+		{1, 9, 2, true},
+		{2, 9, 5, true},
+		{3, 1, 5, false},
+		{3, 4, 5, true},
+		{7, 1, 5, true},
+	} {
+		got, err := clamping.ToOffset(tt.line, tt.col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("clamped ToOffset(%v, %v) = %v, want %v", tt.line, tt.col, got, tt.want)
+		}
+		if _, err := c.ToOffset(tt.line, tt.col); (err != nil) != tt.wantErr {
+			t.Errorf("ToOffset(%v, %v) error = %v, want error %v", tt.line, tt.col, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package otlp
+
+import (
+	"strconv"
+	"time"
+
+	"golang.org/x/tools/internal/telemetry"
+	"golang.org/x/tools/internal/telemetry/export/otlp/wire"
+	"golang.org/x/tools/internal/telemetry/metric"
+)
+
+// convertMetric returns a *wire.Metric holding one data point for each row
+// of data, or nil if data is not of a known type. Scalar metrics that track
+// values become gauges, the others cumulative sums.
+func convertMetric(data telemetry.MetricData, start time.Time) *wire.Metric {
+	groups := data.Groups()
+	startTime := convertTimestamp(start)
+	switch d := data.(type) {
+	case *metric.Int64Data:
+		points := make([]*wire.NumberDataPoint, len(d.Rows))
+		for i, v := range d.Rows {
+			s := strconv.FormatInt(v, 10)
+			points[i] = numberPoint(groups, i, startTime, d.EndTime)
+			points[i].AsInt = &s
+		}
+		return scalarMetric(d.Info, d.IsGauge, points)
+
+	case *metric.Float64Data:
+		points := make([]*wire.NumberDataPoint, len(d.Rows))
+		for i := range d.Rows {
+			points[i] = numberPoint(groups, i, startTime, d.EndTime)
+			points[i].AsDouble = &d.Rows[i]
+		}
+		return scalarMetric(d.Info, d.IsGauge, points)
+
+	case *metric.HistogramInt64Data:
+		bounds := make([]float64, len(d.Info.Buckets))
+		for i, b := range d.Info.Buckets {
+			bounds[i] = float64(b)
+		}
+		points := make([]*wire.HistogramDataPoint, len(d.Rows))
+		for i, row := range d.Rows {
+			min, max := float64(row.Min), float64(row.Max)
+			points[i] = histogramPoint(groups, i, startTime, d.EndTime, row.Values, row.Count, bounds)
+			points[i].Sum = float64(row.Sum)
+			points[i].Min, points[i].Max = &min, &max
+		}
+		return histogramMetric(d.Info.Name, d.Info.Description, points)
+
+	case *metric.HistogramFloat64Data:
+		points := make([]*wire.HistogramDataPoint, len(d.Rows))
+		for i, row := range d.Rows {
+			min, max := row.Min, row.Max
+			points[i] = histogramPoint(groups, i, startTime, d.EndTime, row.Values, row.Count, d.Info.Buckets)
+			points[i].Sum = row.Sum
+			points[i].Min, points[i].Max = &min, &max
+		}
+		return histogramMetric(d.Info.Name, d.Info.Description, points)
+	}
+
+	return nil
+}
+
+func scalarMetric(info *metric.Scalar, isGauge bool, points []*wire.NumberDataPoint) *wire.Metric {
+	result := &wire.Metric{
+		Name:        info.Name,
+		Description: info.Description,
+	}
+	if isGauge {
+		// Gauges have no start time.
+		for _, p := range points {
+			p.StartTimeUnixNano = ""
+		}
+		result.Gauge = &wire.Gauge{DataPoints: points}
+	} else {
+		result.Sum = &wire.Sum{
+			DataPoints:             points,
+			AggregationTemporality: wire.AggregationTemporalityCumulative,
+			IsMonotonic:            true,
+		}
+	}
+	return result
+}
+
+func histogramMetric(name, description string, points []*wire.HistogramDataPoint) *wire.Metric {
+	return &wire.Metric{
+		Name:        name,
+		Description: description,
+		Histogram: &wire.Histogram{
+			DataPoints:             points,
+			AggregationTemporality: wire.AggregationTemporalityCumulative,
+		},
+	}
+}
+
+func numberPoint(groups []telemetry.TagList, i int, start string, end *time.Time) *wire.NumberDataPoint {
+	return &wire.NumberDataPoint{
+		Attributes:        rowAttributes(groups, i),
+		StartTimeUnixNano: start,
+		TimeUnixNano:      endTimestamp(end),
+	}
+}
+
+// histogramPoint returns a data point for a histogram row. The values of the
+// row count the values at or below each bound, while OTLP wants the count of
+// the values in each bucket, with an extra bucket for the values above the
+// last bound.
+func histogramPoint(groups []telemetry.TagList, i int, start string, end *time.Time, values []int64, count int64, bounds []float64) *wire.HistogramDataPoint {
+	counts := make([]string, len(values)+1)
+	var below int64
+	for j, v := range values {
+		counts[j] = strconv.FormatInt(v-below, 10)
+		below = v
+	}
+	counts[len(values)] = strconv.FormatInt(count-below, 10)
+	return &wire.HistogramDataPoint{
+		Attributes:        rowAttributes(groups, i),
+		StartTimeUnixNano: start,
+		TimeUnixNano:      endTimestamp(end),
+		Count:             strconv.FormatInt(count, 10),
+		BucketCounts:      counts,
+		ExplicitBounds:    bounds,
+	}
+}
+
+// rowAttributes returns the attributes of the tags that identify row i.
+func rowAttributes(groups []telemetry.TagList, i int) []wire.KeyValue {
+	if i >= len(groups) {
+		return nil
+	}
+	return convertAttributes(groups[i])
+}
+
+func endTimestamp(end *time.Time) string {
+	if end == nil {
+		return convertTimestamp(time.Now())
+	}
+	return convertTimestamp(*end)
+}
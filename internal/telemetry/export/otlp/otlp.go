@@ -0,0 +1,325 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package otlp adds the ability to export all telemetry to a collector that
+// accepts the OpenTelemetry protocol (OTLP) over HTTP, using its JSON
+// encoding. Like the ocagent exporter, this keeps the compile time
+// dependencies to zero.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/internal/telemetry"
+	"golang.org/x/tools/internal/telemetry/export"
+	"golang.org/x/tools/internal/telemetry/export/otlp/wire"
+	"golang.org/x/tools/internal/telemetry/tag"
+)
+
+// The environment variables that configure the exporter, as defined by the
+// OpenTelemetry specification.
+const (
+	EndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	ServiceEnv  = "OTEL_SERVICE_NAME"
+)
+
+type Config struct {
+	Start   time.Time
+	Host    string
+	Process uint32
+	Client  *http.Client
+	Service string
+	// Endpoint is the base URL of the collector, to which the exporter
+	// appends /v1/traces and /v1/metrics.
+	Endpoint string
+	Rate     time.Duration
+}
+
+// Discover returns the configuration given by the standard OpenTelemetry
+// environment variables. The endpoint is empty if none is configured.
+func Discover() *Config {
+	return &Config{
+		Service:  os.Getenv(ServiceEnv),
+		Endpoint: os.Getenv(EndpointEnv),
+	}
+}
+
+// sendTimeout is the time that the collector has to accept a message, which
+// the default client of the exporter waits for. A collector that hangs would
+// otherwise accumulate the flushes waiting on it.
+const sendTimeout = 10 * time.Second
+
+type exporter struct {
+	mu       sync.Mutex
+	config   Config
+	resource *wire.Resource
+	scope    *wire.InstrumentationScope
+	spans    []*wire.Span
+	metrics  map[string]*wire.Metric
+}
+
+// Connect creates a process specific exporter that uploads its telemetry to
+// the collector at the endpoint of config. It returns nil if there is no
+// endpoint, or the endpoint is "off".
+func Connect(config *Config) export.Exporter {
+	if config == nil || config.Endpoint == "" || config.Endpoint == "off" {
+		return nil
+	}
+	exporter := &exporter{
+		config:  *config,
+		metrics: make(map[string]*wire.Metric),
+	}
+	exporter.config.Endpoint = strings.TrimSuffix(exporter.config.Endpoint, "/")
+	if exporter.config.Start.IsZero() {
+		exporter.config.Start = time.Now()
+	}
+	if exporter.config.Host == "" {
+		hostname, _ := os.Hostname()
+		exporter.config.Host = hostname
+	}
+	if exporter.config.Process == 0 {
+		exporter.config.Process = uint32(os.Getpid())
+	}
+	if exporter.config.Client == nil {
+		exporter.config.Client = &http.Client{Timeout: sendTimeout}
+	}
+	if exporter.config.Service == "" {
+		exporter.config.Service = filepath.Base(os.Args[0])
+	}
+	if exporter.config.Rate == 0 {
+		exporter.config.Rate = 2 * time.Second
+	}
+	exporter.resource = &wire.Resource{
+		Attributes: []wire.KeyValue{
+			stringKeyValue("service.name", exporter.config.Service),
+			stringKeyValue("host.name", exporter.config.Host),
+			intKeyValue("process.pid", int64(exporter.config.Process)),
+			stringKeyValue("telemetry.sdk.language", "go"),
+			stringKeyValue("telemetry.sdk.name", "x/tools"),
+		},
+	}
+	exporter.scope = &wire.InstrumentationScope{
+		Name:    "golang.org/x/tools/internal/telemetry",
+		Version: "0.0.1",
+	}
+	go func() {
+		for range time.Tick(exporter.config.Rate) {
+			exporter.Flush()
+		}
+	}()
+	return exporter
+}
+
+func (e *exporter) StartSpan(ctx context.Context, span *telemetry.Span) {}
+
+func (e *exporter) FinishSpan(ctx context.Context, span *telemetry.Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, convertSpan(span))
+}
+
+func (e *exporter) Log(context.Context, telemetry.Event) {}
+
+// Metric records the latest value of the metric. The values of metrics are
+// cumulative, so only the last one received before a flush is sent.
+func (e *exporter) Metric(ctx context.Context, data telemetry.MetricData) {
+	metric := convertMetric(data, e.config.Start)
+	if metric == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.metrics[metric.Name] = metric
+}
+
+// Flush sends the spans finished and the metrics recorded since the previous
+// flush. The messages are sent without holding the lock, so that recording
+// does not wait for the collector.
+func (e *exporter) Flush() {
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	metrics := make([]*wire.Metric, 0, len(e.metrics))
+	for _, m := range e.metrics {
+		metrics = append(metrics, m)
+	}
+	e.metrics = make(map[string]*wire.Metric)
+	e.mu.Unlock()
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].Name < metrics[j].Name })
+
+	if len(spans) > 0 {
+		e.send("/v1/traces", &wire.ExportTraceServiceRequest{
+			ResourceSpans: []*wire.ResourceSpans{{
+				Resource: e.resource,
+				ScopeSpans: []*wire.ScopeSpans{{
+					Scope: e.scope,
+					Spans: spans,
+				}},
+			}},
+		})
+	}
+	if len(metrics) > 0 {
+		e.send("/v1/metrics", &wire.ExportMetricsServiceRequest{
+			ResourceMetrics: []*wire.ResourceMetrics{{
+				Resource: e.resource,
+				ScopeMetrics: []*wire.ScopeMetrics{{
+					Scope:   e.scope,
+					Metrics: metrics,
+				}},
+			}},
+		})
+	}
+}
+
+func (e *exporter) send(endpoint string, message interface{}) {
+	blob, err := json.Marshal(message)
+	if err != nil {
+		errorInExport("otlp failed to marshal message for %v: %v", endpoint, err)
+		return
+	}
+	uri := e.config.Endpoint + endpoint
+	req, err := http.NewRequest("POST", uri, bytes.NewReader(blob))
+	if err != nil {
+		errorInExport("otlp failed to build request for %v: %v", uri, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := e.config.Client.Do(req)
+	if err != nil {
+		errorInExport("otlp failed to send message: %v \n", err)
+		return
+	}
+	if res.Body != nil {
+		res.Body.Close()
+	}
+}
+
+func errorInExport(message string, args ...interface{}) {
+	// This function is useful when debugging the exporter, but in general we
+	// want to just drop any export
+}
+
+func convertTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func convertSpan(span *telemetry.Span) *wire.Span {
+	result := &wire.Span{
+		TraceID:           span.ID.TraceID.String(),
+		SpanID:            span.ID.SpanID.String(),
+		Name:              span.Name,
+		Kind:              wire.SpanKindInternal,
+		StartTimeUnixNano: convertTimestamp(span.Start),
+		EndTimeUnixNano:   convertTimestamp(span.Finish),
+		Attributes:        convertAttributes(span.Tags),
+	}
+	if span.ParentID.IsValid() {
+		result.ParentSpanID = span.ParentID.String()
+	}
+	for _, event := range span.Events {
+		result.Events = append(result.Events, convertEvent(event))
+		if event.Error != nil {
+			result.Status = &wire.Status{
+				Code:    wire.StatusCodeError,
+				Message: event.Error.Error(),
+			}
+		}
+	}
+	return result
+}
+
+func convertEvent(event telemetry.Event) *wire.Event {
+	name := event.Message
+	if name == "" && event.Error != nil {
+		name = event.Error.Error()
+		event.Error = nil
+	}
+	tags := event.Tags
+	if event.Error != nil {
+		tags = append(tags, tag.Of("Error", event.Error))
+	}
+	return &wire.Event{
+		TimeUnixNano: convertTimestamp(event.At),
+		Name:         name,
+		Attributes:   convertAttributes(tags),
+	}
+}
+
+func convertAttributes(tags telemetry.TagList) []wire.KeyValue {
+	if len(tags) == 0 {
+		return nil
+	}
+	attributes := make([]wire.KeyValue, 0, len(tags))
+	for _, tag := range tags {
+		attributes = append(attributes, wire.KeyValue{
+			Key:   fmt.Sprint(tag.Key),
+			Value: convertValue(tag.Value),
+		})
+	}
+	return attributes
+}
+
+func convertValue(v interface{}) wire.AnyValue {
+	switch v := v.(type) {
+	case int8:
+		return intValue(int64(v))
+	case int16:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case int:
+		return intValue(int64(v))
+	case uint8:
+		return intValue(int64(v))
+	case uint16:
+		return intValue(int64(v))
+	case uint32:
+		return intValue(int64(v))
+	case uint64:
+		return intValue(int64(v))
+	case uint:
+		return intValue(int64(v))
+	case float32:
+		f := float64(v)
+		return wire.AnyValue{DoubleValue: &f}
+	case float64:
+		return wire.AnyValue{DoubleValue: &v}
+	case bool:
+		return wire.AnyValue{BoolValue: &v}
+	case string:
+		return wire.AnyValue{StringValue: &v}
+	default:
+		s := fmt.Sprint(v)
+		return wire.AnyValue{StringValue: &s}
+	}
+}
+
+func intValue(v int64) wire.AnyValue {
+	s := strconv.FormatInt(v, 10)
+	return wire.AnyValue{IntValue: &s}
+}
+
+func stringKeyValue(key, value string) wire.KeyValue {
+	return wire.KeyValue{Key: key, Value: wire.AnyValue{StringValue: &value}}
+}
+
+func intKeyValue(key string, value int64) wire.KeyValue {
+	return wire.KeyValue{Key: key, Value: intValue(value)}
+}
@@ -0,0 +1,177 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/internal/telemetry"
+	"golang.org/x/tools/internal/telemetry/export/otlp/wire"
+	"golang.org/x/tools/internal/telemetry/metric"
+	"golang.org/x/tools/internal/telemetry/tag"
+)
+
+func TestConnectOff(t *testing.T) {
+	for _, endpoint := range []string{"", "off"} {
+		if e := Connect(&Config{Endpoint: endpoint}); e != nil {
+			t.Errorf("Connect with endpoint %q returned an exporter", endpoint)
+		}
+	}
+	if e := Connect(nil); e != nil {
+		t.Errorf("Connect with no config returned an exporter")
+	}
+}
+
+func TestExport(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received = make(map[string][]byte)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: got content type %q, want application/json", r.URL.Path, ct)
+		}
+		mu.Lock()
+		received[r.URL.Path] = body
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	start := time.Unix(100, 0)
+	e := Connect(&Config{
+		Start:    start,
+		Host:     "host",
+		Process:  1,
+		Service:  "gopls",
+		Endpoint: server.URL + "/",
+		Rate:     time.Hour,
+	})
+	ctx := context.Background()
+	span := &telemetry.Span{
+		Name:     "check",
+		ID:       telemetry.SpanContext{TraceID: telemetry.TraceID{1}, SpanID: telemetry.SpanID{2}},
+		ParentID: telemetry.SpanID{3},
+		Start:    start,
+		Finish:   start.Add(time.Second),
+		Tags:     telemetry.TagList{tag.Of("package", "a")},
+		Events: []telemetry.Event{
+			{At: start, Error: errors.New("failed")},
+		},
+	}
+	e.FinishSpan(ctx, span)
+	end := start.Add(2 * time.Second)
+	for _, v := range []int64{1, 5} {
+		e.Metric(ctx, &metric.Int64Data{
+			Info:    &metric.Scalar{Name: "count", Description: "a count"},
+			Rows:    []int64{v},
+			EndTime: &end,
+		})
+	}
+	e.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	var traces wire.ExportTraceServiceRequest
+	if err := json.Unmarshal(received["/v1/traces"], &traces); err != nil {
+		t.Fatalf("decoding traces: %v", err)
+	}
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	got := spans[0]
+	if got.TraceID != "01000000000000000000000000000000" || got.SpanID != "0200000000000000" || got.ParentSpanID != "0300000000000000" {
+		t.Errorf("got ids %s/%s/%s", got.TraceID, got.SpanID, got.ParentSpanID)
+	}
+	if got.StartTimeUnixNano != "100000000000" || got.EndTimeUnixNano != "101000000000" {
+		t.Errorf("got times %s-%s, want 100000000000-101000000000", got.StartTimeUnixNano, got.EndTimeUnixNano)
+	}
+	if len(got.Attributes) != 1 || got.Attributes[0].Key != "package" || *got.Attributes[0].Value.StringValue != "a" {
+		t.Errorf("got attributes %+v, want package=a", got.Attributes)
+	}
+	if got.Status == nil || got.Status.Code != wire.StatusCodeError || got.Status.Message != "failed" {
+		t.Errorf("got status %+v, want an error", got.Status)
+	}
+
+	var metrics wire.ExportMetricsServiceRequest
+	if err := json.Unmarshal(received["/v1/metrics"], &metrics); err != nil {
+		t.Fatalf("decoding metrics: %v", err)
+	}
+	ms := metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(ms) != 1 || ms[0].Sum == nil || len(ms[0].Sum.DataPoints) != 1 {
+		t.Fatalf("got metrics %+v, want a single sum with one point", ms)
+	}
+	if v := *ms[0].Sum.DataPoints[0].AsInt; v != "5" {
+		t.Errorf("got count %s, want the latest value 5", v)
+	}
+}
+
+func TestFlushHangingCollector(t *testing.T) {
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	e := Connect(&Config{Endpoint: server.URL, Rate: time.Hour})
+	ctx := context.Background()
+	e.FinishSpan(ctx, &telemetry.Span{Name: "check"})
+	go e.Flush()
+	<-received
+
+	// The spans are still recorded while the collector hangs.
+	done := make(chan struct{})
+	go func() {
+		e.FinishSpan(ctx, &telemetry.Span{Name: "check"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("FinishSpan waited for the collector")
+	}
+}
+
+func TestConvertHistogram(t *testing.T) {
+	end := time.Unix(1, 0)
+	data := &metric.HistogramInt64Data{
+		Info: &metric.HistogramInt64{Name: "latency", Buckets: []int64{10, 100}},
+		Rows: []*metric.HistogramInt64Row{
+			// 3 values at or below 10, 1 between 10 and 100, 2 above 100.
+			{Values: []int64{3, 4}, Count: 6, Sum: 500, Min: 1, Max: 200},
+		},
+		EndTime: &end,
+	}
+	got := convertMetric(data, time.Unix(0, 0))
+	if got == nil || got.Histogram == nil || len(got.Histogram.DataPoints) != 1 {
+		t.Fatalf("got %+v, want a histogram with one point", got)
+	}
+	point := got.Histogram.DataPoints[0]
+	if want := []string{"3", "1", "2"}; !reflect.DeepEqual(point.BucketCounts, want) {
+		t.Errorf("got bucket counts %v, want %v", point.BucketCounts, want)
+	}
+	if want := []float64{10, 100}; !reflect.DeepEqual(point.ExplicitBounds, want) {
+		t.Errorf("got bounds %v, want %v", point.ExplicitBounds, want)
+	}
+	if point.Count != "6" || point.Sum != 500 {
+		t.Errorf("got count %s and sum %v, want 6 and 500", point.Count, point.Sum)
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2019 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package wire holds the types of the JSON encoding of the OpenTelemetry
+// protocol (OTLP) messages used to export traces and metrics.
+package wire
+
+// This file holds the subset of the OTLP types used by the exporter.
+// 64 bit integers are encoded as decimal strings, and ids as hex strings,
+// as required by the JSON encoding of the protocol.
+
+type ExportTraceServiceRequest struct {
+	ResourceSpans []*ResourceSpans `json:"resourceSpans,omitempty"`
+}
+
+type ExportMetricsServiceRequest struct {
+	ResourceMetrics []*ResourceMetrics `json:"resourceMetrics,omitempty"`
+}
+
+type Resource struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+type InstrumentationScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue holds exactly one of its fields.
+type AnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type ResourceSpans struct {
+	Resource   *Resource     `json:"resource,omitempty"`
+	ScopeSpans []*ScopeSpans `json:"scopeSpans,omitempty"`
+}
+
+type ScopeSpans struct {
+	Scope *InstrumentationScope `json:"scope,omitempty"`
+	Spans []*Span               `json:"spans,omitempty"`
+}
+
+type SpanKind int32
+
+const (
+	SpanKindUnspecified SpanKind = 0
+	SpanKindInternal    SpanKind = 1
+)
+
+type Span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              SpanKind   `json:"kind,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	Events            []*Event   `json:"events,omitempty"`
+	Status            *Status    `json:"status,omitempty"`
+}
+
+type Event struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name,omitempty"`
+	Attributes   []KeyValue `json:"attributes,omitempty"`
+}
+
+type StatusCode int32
+
+const (
+	StatusCodeUnset StatusCode = 0
+	StatusCodeOK    StatusCode = 1
+	StatusCodeError StatusCode = 2
+)
+
+type Status struct {
+	Message string     `json:"message,omitempty"`
+	Code    StatusCode `json:"code,omitempty"`
+}
+
+type ResourceMetrics struct {
+	Resource     *Resource       `json:"resource,omitempty"`
+	ScopeMetrics []*ScopeMetrics `json:"scopeMetrics,omitempty"`
+}
+
+type ScopeMetrics struct {
+	Scope   *InstrumentationScope `json:"scope,omitempty"`
+	Metrics []*Metric             `json:"metrics,omitempty"`
+}
+
+// Metric holds exactly one of Gauge, Sum and Histogram.
+type Metric struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Unit        string     `json:"unit,omitempty"`
+	Gauge       *Gauge     `json:"gauge,omitempty"`
+	Sum         *Sum       `json:"sum,omitempty"`
+	Histogram   *Histogram `json:"histogram,omitempty"`
+}
+
+type AggregationTemporality int32
+
+const (
+	AggregationTemporalityUnspecified AggregationTemporality = 0
+	AggregationTemporalityDelta       AggregationTemporality = 1
+	AggregationTemporalityCumulative  AggregationTemporality = 2
+)
+
+type Gauge struct {
+	DataPoints []*NumberDataPoint `json:"dataPoints,omitempty"`
+}
+
+type Sum struct {
+	DataPoints             []*NumberDataPoint     `json:"dataPoints,omitempty"`
+	AggregationTemporality AggregationTemporality `json:"aggregationTemporality,omitempty"`
+	IsMonotonic            bool                   `json:"isMonotonic,omitempty"`
+}
+
+type Histogram struct {
+	DataPoints             []*HistogramDataPoint  `json:"dataPoints,omitempty"`
+	AggregationTemporality AggregationTemporality `json:"aggregationTemporality,omitempty"`
+}
+
+// NumberDataPoint holds exactly one of AsInt and AsDouble.
+type NumberDataPoint struct {
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsInt             *string    `json:"asInt,omitempty"`
+	AsDouble          *float64   `json:"asDouble,omitempty"`
+}
+
+// HistogramDataPoint has one more bucket count than explicit bounds, the
+// last bucket counting the values above the highest bound.
+type HistogramDataPoint struct {
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	Count             string     `json:"count"`
+	Sum               float64    `json:"sum"`
+	BucketCounts      []string   `json:"bucketCounts,omitempty"`
+	ExplicitBounds    []float64  `json:"explicitBounds,omitempty"`
+	Min               *float64   `json:"min,omitempty"`
+	Max               *float64   `json:"max,omitempty"`
+}